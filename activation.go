@@ -0,0 +1,156 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	activationPollInterval = 30 * time.Second
+	activationWatchTimeout = 72 * time.Hour
+)
+
+const eventParticipantActivated = "participant.activated"
+
+const firstEntityQuery = `{
+	"@context": {
+		"@vocab": "https://w3id.org/edc/v0.0.1/ns/"
+	},
+	"@type": "QuerySpec",
+	"limit": 1
+}`
+
+// Namespace annotation recording when the participant.activated event was sent
+const activatedAnnotation = "aruba-provisioner/activated-at"
+
+// Namespaces with a running activation watch
+var activationWatches = struct {
+	sync.Mutex
+	running map[string]bool
+}{running: make(map[string]bool)}
+
+// startActivationWatch starts watchActivation in the background unless a watch for the participant is already running.
+func startActivationWatch(ctx context.Context, kubeClient client.Client, definition ParticipantDefinition, notifier Notifier, clients seedingClients) {
+	namespace := definition.ParticipantName
+	activationWatches.Lock()
+	defer activationWatches.Unlock()
+	if activationWatches.running[namespace] {
+		return
+	}
+	activationWatches.running[namespace] = true
+	go func() {
+		defer func() {
+			activationWatches.Lock()
+			delete(activationWatches.running, namespace)
+			activationWatches.Unlock()
+		}()
+		watchActivation(ctx, kubeClient, definition, notifier, clients)
+	}()
+}
+
+// watchActivation polls the participant's management API until its first contract agreement or transfer process
+// shows up and emits a participant.activated event. Participants are only announced once, the namespace is
+// annotated when the event was sent. It gives up after activationWatchTimeout or when the namespace is gone.
+func watchActivation(ctx context.Context, kubeClient client.Client, definition ParticipantDefinition, notifier Notifier, clients seedingClients) {
+	ctx, cancel := context.WithTimeout(ctx, activationWatchTimeout)
+	defer cancel()
+
+	namespace := definition.ParticipantName
+	httpClient := clients.client(targetManagement)
+	httpClient.Timeout = 30 * time.Second
+	ticker := time.NewTicker(activationPollInterval)
+	defer ticker.Stop()
+	for {
+		activated, err := isActivated(kubeClient, ctx, namespace)
+		if apierrors.IsNotFound(err) || activated {
+			return
+		}
+		if err != nil {
+			fmt.Printf("activation check for %s failed: %v\n", namespace, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("activation watch for %s ended without activity\n", namespace)
+			return
+		case <-ticker.C:
+		}
+
+		creds, err := loadCredentials(kubeClient, ctx, namespace)
+		if err != nil {
+			fmt.Printf("activation check for %s failed: %v\n", namespace, err)
+			continue
+		}
+		mgmtApi := api.ApiClient{
+			BaseUrl:    definition.routeUrl("/cp/api/management/v3"),
+			ApiKey:     creds.ManagementApiKey,
+			HttpClient: httpClient,
+		}
+
+		details := make(map[string]string)
+		if id, err := firstId(mgmtApi.QueryContractAgreements(ctx, firstEntityQuery)); err != nil {
+			fmt.Printf("activation check for %s: query contract agreements failed: %v\n", namespace, err)
+		} else if id != "" {
+			details["contractAgreementId"] = id
+		}
+		if id, err := firstId(mgmtApi.QueryTransferProcesses(ctx, firstEntityQuery)); err != nil {
+			fmt.Printf("activation check for %s: query transfer processes failed: %v\n", namespace, err)
+		} else if id != "" {
+			details["transferProcessId"] = id
+		}
+		if len(details) == 0 {
+			continue
+		}
+
+		if err := notifier.Notify(ctx, newLifecycleEvent(eventParticipantActivated, namespace, details)); err != nil {
+			fmt.Printf("activation notification for %s failed: %v\n", namespace, err)
+			continue
+		}
+		if err := markActivated(kubeClient, ctx, namespace); err != nil {
+			fmt.Printf("recording activation of %s failed: %v\n", namespace, err)
+		}
+		return
+	}
+}
+
+func isActivated(c client.Client, ctx context.Context, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return false, err
+	}
+	_, ok := namespace.Annotations[activatedAnnotation]
+	return ok, nil
+}
+
+func markActivated(c client.Client, ctx context.Context, name string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, activatedAnnotation, time.Now().UTC().Format(time.RFC3339))
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	return c.Patch(ctx, namespace, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
+
+// firstId returns the @id of the first entity in a management API query response.
+func firstId(body string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	var entities []struct {
+		Id string `json:"@id"`
+	}
+	if err := json.Unmarshal([]byte(body), &entities); err != nil {
+		return "", err
+	}
+	if len(entities) == 0 {
+		return "", nil
+	}
+	return entities[0].Id, nil
+}
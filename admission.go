@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultAdmissionTimeout = 5 * time.Second
+
+// Largest decision the admission policy may answer with
+const maxAdmissionResponseSize = 1 << 20
+
+// Operations a definition is reviewed for
+const (
+	admissionCreate = "create"
+	admissionUpdate = "update"
+	admissionImport = "import"
+)
+
+// admissionPolicy lets an external policy accept or reject every participant definition before it is provisioned,
+// e.g. to allow DIDs of certain domains only or to enforce naming conventions. The policy is a webhook, or the data
+// API of an Open Policy Agent, which is POSTed an admissionInput as {"input": ...} and answers with an
+// admissionDecision, for OPA wrapped in {"result": ...}.
+type admissionPolicy struct {
+	url        string
+	httpClient http.Client
+}
+
+// admissionInput is what the policy decides on.
+type admissionInput struct {
+	Operation string `json:"operation"`
+	// Tenant is the tenant of the caller, empty for unscoped callers
+	Tenant     string                `json:"tenant,omitempty"`
+	Definition ParticipantDefinition `json:"definition"`
+}
+
+// admissionDecision is the answer of the policy. Definitions are rejected unless it allows them, also when it gives no
+// decision.
+type admissionDecision struct {
+	Allowed bool              `json:"allowed"`
+	Reasons []admissionReason `json:"reasons,omitempty"`
+}
+
+// admissionReason explains a rejection, either as a plain message, as Rego deny rules usually produce them, or as a
+// message about a field of the definition.
+type admissionReason fieldError
+
+func (r *admissionReason) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		r.Message = message
+		return nil
+	}
+	return json.Unmarshal(data, (*fieldError)(r))
+}
+
+// policyRejection is returned for definitions the admission policy rejected and answered with a 403 listing its
+// reasons.
+type policyRejection struct {
+	Reasons []fieldError
+}
+
+func (e *policyRejection) Error() string {
+	messages := make([]string, len(e.Reasons))
+	for i, reason := range e.Reasons {
+		messages[i] = reason.Message
+		if reason.Field != "" {
+			messages[i] = reason.Field + ": " + reason.Message
+		}
+	}
+	return "rejected by the admission policy: " + strings.Join(messages, "; ")
+}
+
+// newAdmissionPolicy returns the policy at the URL, nil if there is none.
+func newAdmissionPolicy(url string, timeout time.Duration) *admissionPolicy {
+	if url == "" {
+		return nil
+	}
+	return &admissionPolicy{url: url, httpClient: http.Client{Timeout: timeout}}
+}
+
+// review asks the policy to decide on the definition. Rejected definitions fail with a policyRejection, definitions
+// the policy could not decide on with 503, so that nothing is provisioned unchecked.
+func (p *admissionPolicy) review(ctx context.Context, operation string, tenant string, definition ParticipantDefinition) error {
+	if p == nil {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"input": admissionInput{Operation: operation, Tenant: tenant, Definition: definition.redacted()}})
+	if err != nil {
+		return err
+	}
+	unavailable := func(format string, args ...any) error {
+		return withCode(codePolicyUnavailable, fiber.StatusServiceUnavailable, fmt.Errorf("admission policy: "+format, args...))
+	}
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	resp, err := p.httpClient.Do(rq)
+	if err != nil {
+		return unavailable("%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return unavailable("responded with %s", resp.Status)
+	}
+	var answer struct {
+		admissionDecision
+		Result *admissionDecision `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAdmissionResponseSize)).Decode(&answer); err != nil {
+		return unavailable("invalid decision: %v", err)
+	}
+	decision := answer.admissionDecision
+	if answer.Result != nil {
+		decision = *answer.Result
+	}
+	if decision.Allowed {
+		return nil
+	}
+	rejection := &policyRejection{}
+	for _, reason := range decision.Reasons {
+		rejection.Reasons = append(rejection.Reasons, fieldError(reason))
+	}
+	if len(rejection.Reasons) == 0 {
+		rejection.Reasons = []fieldError{{Message: "the definition is not allowed"}}
+	}
+	return rejection
+}
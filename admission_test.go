@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAdmissionPolicy(t *testing.T) {
+	var answer string
+	var input admissionInput
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input admissionInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		input = body.Input
+		_, _ = w.Write([]byte(answer))
+	}))
+	defer server.Close()
+	policy := newAdmissionPolicy(server.URL, time.Second)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:example.com:alice", ApiKeys: &ApiKeyOverrides{ManagementApiKey: "secret"}}
+	review := func() error {
+		return policy.review(context.Background(), admissionCreate, "acme", definition)
+	}
+
+	answer = `{"allowed": true}`
+	if err := review(); err != nil {
+		t.Errorf("expected the definition to be allowed, got %v", err)
+	}
+	if input.Operation != admissionCreate || input.Tenant != "acme" || input.Definition.ParticipantName != "alice" {
+		t.Errorf("unexpected input %+v", input)
+	}
+	if input.Definition.ApiKeys.ManagementApiKey == "secret" {
+		t.Error("expected the keys of the definition to be redacted")
+	}
+
+	// the answer of an OPA data API, with reasons of a deny rule and of a field
+	answer = `{"result": {"allowed": false, "reasons": ["participants of acme must be named acme-*", {"field": "did", "message": "example.com is not allowed"}]}}`
+	var rejection *policyRejection
+	if err := review(); !errors.As(err, &rejection) || len(rejection.Reasons) != 2 || rejection.Reasons[1].Field != "did" {
+		t.Fatalf("expected a rejection with two reasons, got %v", err)
+	}
+	if p := problemOf(rejection); p.Status != fiber.StatusForbidden || p.Code != codePolicyRejected || len(p.Errors) != 2 {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	answer = `{"result": {}}`
+	if err := review(); !errors.As(err, &rejection) || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected definitions without a decision to be rejected, got %v", err)
+	}
+
+	answer = `not json`
+	var coded *codedError
+	if err := review(); !errors.As(err, &coded) || coded.code != codePolicyUnavailable {
+		t.Errorf("expected an unavailable policy, got %v", err)
+	}
+
+	if err := (*admissionPolicy)(nil).review(context.Background(), admissionCreate, "", definition); err != nil {
+		t.Errorf("expected definitions to be allowed without a policy, got %v", err)
+	}
+}
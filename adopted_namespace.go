@@ -0,0 +1,132 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// adoptedNamespaceAnnotation marks a participant namespace created by other tooling, e.g. with its quotas and RBAC,
+// that the provisioner deploys into. Deleting the participant deletes its objects and leaves the namespace.
+const adoptedNamespaceAnnotation = "aruba-provisioner/adopted-namespace"
+
+// adoptsNamespace reports whether the participant was provisioned into an existing namespace. The namespace stays
+// adopted when the participant is upgraded without adoptNamespace.
+func adoptsNamespace(c client.Client, ctx context.Context, definition ParticipantDefinition) (bool, error) {
+	if definition.AdoptNamespace {
+		return true, nil
+	}
+	return isAdoptedNamespace(c, ctx, definition.ParticipantName)
+}
+
+// claimAdoptedNamespace rejects adopting a namespace that doesn't exist, as the provisioner would create it.
+func claimAdoptedNamespace(c client.Client, ctx context.Context, name string) error {
+	err := c.Get(ctx, client.ObjectKey{Name: name}, &corev1.Namespace{})
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err != nil {
+		return &conflictError{code: codeNameConflict, resource: conflictingResource{Kind: "Namespace", Name: name},
+			message: fmt.Sprintf("namespace %s doesn't exist, adoptNamespace requires it to be created first", name)}
+	}
+	return nil
+}
+
+// adoptedNamespaceMutator records the adoption on the namespace, whose labels and annotations are merged with those
+// of its creator.
+func adoptedNamespaceMutator(obj *unstructured.Unstructured) error {
+	if obj.GetKind() == "Namespace" {
+		addAnnotations(obj, map[string]string{adoptedNamespaceAnnotation: "true"})
+	}
+	return nil
+}
+
+// isAdoptedNamespace reports whether the namespace of the participant was adopted rather than created.
+func isAdoptedNamespace(c client.Client, ctx context.Context, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return namespace.Annotations[adoptedNamespaceAnnotation] == "true", nil
+}
+
+// ownedObjects lists the objects of the participant in its namespace: those labelled with the participant and those
+// the provisioner applied, like its credentials.
+func ownedObjects(c client.Client, ctx context.Context, namespace string) ([]*unstructured.Unstructured, error) {
+	var owned []*unstructured.Unstructured
+	for _, gvk := range sharedNamespaceKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(ctx, list, client.InNamespace(namespace))
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if obj.GetLabels()[status.ParticipantLabel] == namespace || appliedBy(obj, fieldOwner) {
+				obj.SetGroupVersionKind(gvk)
+				owned = append(owned, obj)
+			}
+		}
+	}
+	return owned, nil
+}
+
+// releaseNamespace deletes the participant's objects from its adopted namespace and removes the provisioner's labels
+// and annotations from it, leaving the namespace as its creator set it up.
+func releaseNamespace(c client.Client, ctx context.Context, namespace string, remove action) error {
+	owned, err := ownedObjects(c, ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, obj := range owned {
+		if err := client.IgnoreNotFound(remove(c, ctx, obj)); err != nil {
+			return fmt.Errorf("delete %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	for key := range ns.Labels {
+		if reservedMetadataKey(key) {
+			delete(ns.Labels, key)
+		}
+	}
+	for key := range ns.Annotations {
+		if reservedMetadataKey(key) {
+			delete(ns.Annotations, key)
+		}
+	}
+	return c.Update(ctx, ns)
+}
+
+// verifyRelease waits until the objects of the participant are gone from its adopted namespace.
+func verifyRelease(c client.Client, ctx context.Context, namespace string) error {
+	for {
+		owned, err := ownedObjects(c, ctx, namespace)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if err == nil && len(owned) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			remaining := make([]string, 0, len(owned))
+			for _, obj := range owned {
+				remaining = append(remaining, obj.GetKind()+"/"+obj.GetName())
+			}
+			return fmt.Errorf("objects of the participant remain in its namespace: %s", strings.Join(remaining, ", "))
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
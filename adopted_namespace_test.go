@@ -0,0 +1,84 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClaimAdoptedNamespace(t *testing.T) {
+	c := newNamespaceClient(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme-edc", Labels: map[string]string{"platform/team": "acme"}}})
+	if err := claimParticipant(c, context.Background(), "", "acme-edc", false); problemOf(err).Code != codeNameConflict {
+		t.Errorf("expected the namespace to be refused without adoption, got %v", err)
+	}
+	if err := claimParticipant(c, context.Background(), "", "acme-edc", true); err != nil {
+		t.Errorf("expected the namespace to be adopted, got %v", err)
+	}
+	if err := claimParticipant(c, context.Background(), "", "globex-edc", true); problemOf(err).Code != codeNameConflict {
+		t.Errorf("expected adopting a missing namespace to be refused, got %v", err)
+	}
+}
+
+func TestAdoptedNamespaceMutator(t *testing.T) {
+	namespace := &unstructured.Unstructured{}
+	namespace.SetKind("Namespace")
+	definition := ParticipantDefinition{ParticipantName: "acme-edc", Did: "did:web:acme", AdoptNamespace: true}
+	for _, mutator := range definition.mutators() {
+		if err := mutator(namespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if namespace.GetAnnotations()[adoptedNamespaceAnnotation] != "true" || namespace.GetLabels()[status.ManagedByLabel] != status.ManagedByValue {
+		t.Errorf("expected the namespace to be recorded as adopted participant namespace, got %v %v", namespace.GetLabels(), namespace.GetAnnotations())
+	}
+}
+
+func TestReleaseNamespace(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "acme-edc",
+		Labels:      map[string]string{"platform/team": "acme", status.ManagedByLabel: status.ManagedByValue, status.ParticipantLabel: "acme-edc"},
+		Annotations: map[string]string{adoptedNamespaceAnnotation: "true", didAnnotation: "did:web:acme", "platform/owner": "team-acme"},
+	}}
+	owned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-config", Namespace: "acme-edc",
+		Labels: map[string]string{status.ParticipantLabel: "acme-edc"}}}
+	platform := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "platform-settings", Namespace: "acme-edc"}}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(namespace, owned, platform).Build()
+	ctx := context.Background()
+
+	adopted, err := isAdoptedNamespace(kube, ctx, "acme-edc")
+	if err != nil || !adopted {
+		t.Fatalf("expected the namespace to be adopted, got %v %v", adopted, err)
+	}
+	if err := releaseNamespace(kube, ctx, "acme-edc", deleteResource); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyRelease(kube, ctx, "acme-edc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(owned), &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the participant's ConfigMap to be deleted, got %v", err)
+	}
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(platform), &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the platform's ConfigMap to remain, got %v", err)
+	}
+	released := &corev1.Namespace{}
+	if err := kube.Get(ctx, client.ObjectKey{Name: "acme-edc"}, released); err != nil {
+		t.Fatalf("expected the namespace to remain, got %v", err)
+	}
+	if released.Labels["platform/team"] != "acme" || released.Annotations["platform/owner"] != "team-acme" {
+		t.Errorf("expected the platform's metadata to remain, got %v %v", released.Labels, released.Annotations)
+	}
+	if _, ok := released.Labels[status.ManagedByLabel]; ok || released.Annotations[adoptedNamespaceAnnotation] != "" {
+		t.Errorf("expected the provisioner's metadata to be removed, got %v %v", released.Labels, released.Annotations)
+	}
+}
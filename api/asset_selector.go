@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	edcNamespace           = "https://w3id.org/edc/v0.0.1/ns/"
+	managementContext      = "https://w3id.org/edc/connector/management/v0.0.1"
+	AssetIdProperty        = edcNamespace + "id"
+	OperatorEquals         = "="
+	OperatorNotEquals      = "!="
+	OperatorIn             = "in"
+	OperatorLike           = "like"
+	contractDefinitionType = "ContractDefinition"
+)
+
+var SupportedOperators = map[string]bool{OperatorEquals: true, OperatorNotEquals: true, OperatorIn: true, OperatorLike: true}
+
+type Criterion struct {
+	Type         string `json:"@type"`
+	OperandLeft  string `json:"operandLeft"`
+	Operator     string `json:"operator"`
+	OperandRight any    `json:"operandRight"`
+}
+
+type ContractDefinition struct {
+	Context          []string    `json:"@context"`
+	Id               string      `json:"@id"`
+	Type             string      `json:"@type"`
+	AccessPolicyId   string      `json:"accessPolicyId"`
+	ContractPolicyId string      `json:"contractPolicyId"`
+	AssetsSelector   []Criterion `json:"assetsSelector"`
+}
+
+// AssetSelectorBuilder assembles the criteria of a contract definition's asset selector. All criteria must match.
+type AssetSelectorBuilder struct {
+	criteria []Criterion
+}
+
+func NewAssetSelector() *AssetSelectorBuilder {
+	return &AssetSelectorBuilder{}
+}
+
+// AssetIds selects the assets with the given IDs.
+func (b *AssetSelectorBuilder) AssetIds(ids ...string) *AssetSelectorBuilder {
+	switch len(ids) {
+	case 0:
+		return b
+	case 1:
+		return b.Property(AssetIdProperty, OperatorEquals, ids[0])
+	default:
+		return b.Property(AssetIdProperty, OperatorIn, ids)
+	}
+}
+
+// Property selects assets by a property value. Property names that are not IRIs are resolved against the EDC namespace.
+func (b *AssetSelectorBuilder) Property(name string, operator string, value any) *AssetSelectorBuilder {
+	if !strings.Contains(name, ":") {
+		name = edcNamespace + name
+	}
+	b.criteria = append(b.criteria, Criterion{
+		Type:         "Criterion",
+		OperandLeft:  name,
+		Operator:     operator,
+		OperandRight: value,
+	})
+	return b
+}
+
+func (b *AssetSelectorBuilder) Build() []Criterion {
+	return b.criteria
+}
+
+func NewContractDefinition(id string, accessPolicyId string, contractPolicyId string, selector []Criterion) ContractDefinition {
+	if selector == nil {
+		selector = []Criterion{}
+	}
+	return ContractDefinition{
+		Context:          []string{managementContext},
+		Id:               id,
+		Type:             contractDefinitionType,
+		AccessPolicyId:   accessPolicyId,
+		ContractPolicyId: contractPolicyId,
+		AssetsSelector:   selector,
+	}
+}
+
+// Json renders the contract definition as request body for CreateContractDefinition.
+func (d ContractDefinition) Json() (string, error) {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
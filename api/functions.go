@@ -1,33 +1,106 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"strings"
+	"time"
 )
 
 type ApiClient struct {
 	HttpClient http.Client
 	BaseUrl    string
 	ApiKey     string
+	// Debug logs every request with its status and duration
+	Debug bool
+	// Retry re-sends requests failing transiently, see IsTransient. Requests are sent once when it is nil, e.g. by
+	// callers retrying whole sequences of requests themselves.
+	Retry *RetryPolicy
 }
 
-func sendRequest(client http.Client, apiKey string, body string, url string) (string, error) {
-	payload := strings.NewReader(body)
+// RetryPolicy is how often and after which delays failing requests are sent again.
+type RetryPolicy struct {
+	// Attempts is the number of times a request is sent at most
+	Attempts int
+	// Initial is the delay before the first retry, it doubles with every retry up to Max
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// DefaultRetry retries requests for about 10 seconds, enough to ride out a restarting component.
+var DefaultRetry = &RetryPolicy{Attempts: 4, Initial: time.Second, Max: 5 * time.Second}
+
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	delay := p.Initial
+	for i := 1; i < retry && delay < p.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, p.Max)
+}
+
+// Request describes a call relative to the client's BaseUrl.
+type Request struct {
+	Method string
+	Path   string
+	// Headers are sent in addition to the content type and API key
+	Headers map[string]string
+	// Body is sent as is if it is a string or byte slice, other values are encoded as JSON
+	Body any
+	// IgnoreConflict treats 409 responses as success, for creating entities that may already exist
+	IgnoreConflict bool
+}
 
-	rq, err := http.NewRequest("POST", url, payload)
+// Do sends the request and returns the raw response body. Non-2xx responses yield a *StatusError. Transient failures
+// are retried according to the client's retry policy until the context is done.
+func (i *ApiClient) Do(ctx context.Context, request Request) ([]byte, error) {
+	payload, err := encodeBody(request.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	rq.Header.Add("Content-Type", "application/json")
-	rq.Header.Add("x-api-key", apiKey)
+	attempts := 1
+	if i.Retry != nil && i.Retry.Attempts > 1 {
+		attempts = i.Retry.Attempts
+	}
+	for attempt := 1; ; attempt++ {
+		response, err := i.do(ctx, request, payload)
+		if err == nil || attempt >= attempts || !IsTransient(err) {
+			return response, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(i.Retry.delay(attempt)):
+		}
+	}
+}
+
+func (i *ApiClient) do(ctx context.Context, request Request, payload []byte) ([]byte, error) {
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := i.BaseUrl + request.Path
 
-	resp, err := client.Do(rq)
+	rq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	rq.Header.Add("Content-Type", "application/json")
+	rq.Header.Add("x-api-key", i.ApiKey)
+	for name, value := range request.Headers {
+		rq.Header.Set(name, value)
 	}
 
+	start := time.Now()
+	resp, err := i.HttpClient.Do(rq)
+	if err != nil {
+		return nil, err
+	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
@@ -37,11 +110,96 @@ func sendRequest(client http.Client, apiKey string, body string, url string) (st
 
 	response, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if i.Debug {
+		fmt.Printf("%s %s -> %s (%s)\n", method, url, resp.Status, time.Since(start).Round(time.Millisecond))
+	}
+	if request.IgnoreConflict && resp.StatusCode == http.StatusConflict {
+		return response, nil
 	}
-	if resp.StatusCode != 409 && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		fmt.Println("Error sending request: ", resp.Status, " ", string(response))
-		return "", fmt.Errorf("error sending request: %s", resp.Status)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(response)}
 	}
-	return string(response), nil
+	return response, nil
+}
+
+// DoJson sends the request and decodes the JSON response into a value of type T.
+func DoJson[T any](ctx context.Context, client *ApiClient, request Request) (T, error) {
+	var result T
+	response, err := client.Do(ctx, request)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return result, fmt.Errorf("decode response of %s %s: %w", request.Method, request.Path, err)
+	}
+	return result, nil
+}
+
+// send is the shorthand for calls that pass and return JSON documents as strings.
+func (i *ApiClient) send(ctx context.Context, method string, path string, body string) (string, error) {
+	response, err := i.Do(ctx, Request{Method: method, Path: path, Body: body, IgnoreConflict: method == http.MethodPost})
+	return string(response), err
+}
+
+func encodeBody(body any) ([]byte, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(b), nil
+	case []byte:
+		return b, nil
+	default:
+		return json.Marshal(b)
+	}
+}
+
+// StatusError is returned when an API responds with a non-successful status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// Body is the response body, usually describing the problem
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error sending request: %s", e.Status)
+}
+
+// IsNotFound reports whether the error is a 404 response.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether the error is a 401 or 403 response.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict reports whether the error is a 409 response.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsTransient reports whether a request failed in a way that may not happen again: a connection error or timeout, a
+// 5xx response, or a 408 or 429 response.
+func IsTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusRequestTimeout || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func hasStatus(err error, statusCode int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == statusCode
 }
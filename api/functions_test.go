@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			if r.Header.Get("x-api-key") != "key" || r.Header.Get("X-Trace") != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write(body)
+		case "/conflict":
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`[{"message":"not found"}]`))
+		}
+	}))
+	defer server.Close()
+	client := &ApiClient{BaseUrl: server.URL, ApiKey: "key"}
+
+	echoed, err := DoJson[holder](context.Background(), client, Request{
+		Method:  http.MethodPost,
+		Path:    "/echo",
+		Headers: map[string]string{"X-Trace": "abc"},
+		Body:    holder{Did: "did:web:a", HolderId: "a", Name: "A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if echoed.Did != "did:web:a" || echoed.Name != "A" {
+		t.Errorf("unexpected echo %+v", echoed)
+	}
+
+	if _, err := client.Do(context.Background(), Request{Method: http.MethodPost, Path: "/conflict", IgnoreConflict: true}); err != nil {
+		t.Errorf("ignored conflict returned %v", err)
+	}
+	if _, err := client.Do(context.Background(), Request{Method: http.MethodPost, Path: "/conflict"}); !IsConflict(err) {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	_, err = client.Do(context.Background(), Request{Path: "/missing"})
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if statusErr := err.(*StatusError); statusErr.Body != `[{"message":"not found"}]` {
+		t.Errorf("unexpected error body %q", statusErr.Body)
+	}
+}
+
+func TestDoRetriesTransientFailures(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch {
+		case r.URL.Path == "/invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case attempts < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`ok`))
+		}
+	}))
+	defer server.Close()
+	client := &ApiClient{BaseUrl: server.URL, Retry: &RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond}}
+
+	response, err := client.Do(context.Background(), Request{Path: "/flaky"})
+	if err != nil || string(response) != "ok" || attempts != 3 {
+		t.Fatalf("expected success on the third attempt, got %q, %v after %d attempts", response, err, attempts)
+	}
+
+	attempts = 0
+	if _, err := client.Do(context.Background(), Request{Path: "/invalid"}); IsTransient(err) || attempts != 1 {
+		t.Errorf("expected a single attempt of a rejected request, got %v after %d attempts", err, attempts)
+	}
+
+	attempts = -10
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Do(ctx, Request{Path: "/flaky"}); err == nil || IsTransient(err) {
+		t.Errorf("expected a cancelled request to fail without retries, got %v", err)
+	}
+}
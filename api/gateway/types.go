@@ -0,0 +1,131 @@
+// Package gateway registers the Gateway API kinds the provisioner reads with a typed client. It holds the subset of
+// gateway.networking.k8s.io/v1 the provisioner needs, objects are created from unstructured manifests and must not be
+// updated through these types, which drop the fields they don't know.
+package gateway
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion of the Gateway API kinds
+var GroupVersion = schema.GroupVersion{Group: "gateway.networking.k8s.io", Version: "v1"}
+
+// Listener protocols of a Gateway
+const (
+	ProtocolHTTP  = "HTTP"
+	ProtocolHTTPS = "HTTPS"
+)
+
+// AddToScheme registers the kinds with the scheme of a controller-runtime client.
+func AddToScheme(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion, &Gateway{}, &GatewayList{}, &HTTPRoute{}, &HTTPRouteList{})
+	metav1.AddToGroupVersion(scheme, GroupVersion)
+	return nil
+}
+
+// Gateway accepts traffic on its listeners for the routes attached to it.
+type Gateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GatewaySpec `json:"spec"`
+}
+
+type GatewaySpec struct {
+	GatewayClassName string     `json:"gatewayClassName"`
+	Listeners        []Listener `json:"listeners"`
+}
+
+type Listener struct {
+	Name     string  `json:"name"`
+	Hostname *string `json:"hostname,omitempty"`
+	Port     int32   `json:"port"`
+	Protocol string  `json:"protocol"`
+}
+
+type GatewayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Gateway `json:"items"`
+}
+
+// HTTPRoute routes HTTP requests of the hostnames from the Gateways it is attached to to backends.
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              HTTPRouteSpec `json:"spec"`
+}
+
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+}
+
+// ParentReference names the Gateway a route is attached to, in the namespace of the route if Namespace is nil.
+type ParentReference struct {
+	Name        string  `json:"name"`
+	Namespace   *string `json:"namespace,omitempty"`
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+type HTTPRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HTTPRoute `json:"items"`
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func (in *Gateway) DeepCopy() *Gateway {
+	out := &Gateway{TypeMeta: in.TypeMeta, Spec: GatewaySpec{GatewayClassName: in.Spec.GatewayClassName}}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	for _, listener := range in.Spec.Listeners {
+		listener.Hostname = copyString(listener.Hostname)
+		out.Spec.Listeners = append(out.Spec.Listeners, listener)
+	}
+	return out
+}
+
+func (in *Gateway) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *GatewayList) DeepCopyObject() runtime.Object {
+	out := &GatewayList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopy())
+	}
+	return out
+}
+
+func (in *HTTPRoute) DeepCopy() *HTTPRoute {
+	out := &HTTPRoute{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	for _, ref := range in.Spec.ParentRefs {
+		ref.Namespace, ref.SectionName = copyString(ref.Namespace), copyString(ref.SectionName)
+		out.Spec.ParentRefs = append(out.Spec.ParentRefs, ref)
+	}
+	out.Spec.Hostnames = append([]string(nil), in.Spec.Hostnames...)
+	return out
+}
+
+func (in *HTTPRoute) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *HTTPRouteList) DeepCopyObject() runtime.Object {
+	out := &HTTPRouteList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	for i := range in.Items {
+		out.Items = append(out.Items, *in.Items[i].DeepCopy())
+	}
+	return out
+}
@@ -1,11 +1,29 @@
 package api
 
 import (
-	"k8s.io/apimachinery/pkg/util/json"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type IdentityApi interface {
-	CreateParticipant(body string) (string, error)
+	CreateParticipant(ctx context.Context, body string) (*ParticipantResponse, error)
+	GetParticipant(ctx context.Context, participantContextId string) (*ParticipantContext, error)
+	ListParticipants(ctx context.Context) ([]ParticipantContext, error)
+	DeleteParticipant(ctx context.Context, participantContextId string) error
+	RegenerateClientSecret(ctx context.Context, accountId string, alias string, secret string) error
+	RegenerateToken(ctx context.Context, participantContextId string) (string, error)
+	RequestCredentials(ctx context.Context, participantContextId string, request CredentialRequest) error
+	GetCredentialRequest(ctx context.Context, participantContextId string, holderPid string) (*CredentialRequestStatus, error)
+	HasCredential(ctx context.Context, participantContextId string, credentialType string) (bool, error)
+	QueryCredentials(ctx context.Context, participantContextId string, credentialType string) ([]CredentialResource, error)
+	AddCredential(ctx context.Context, participantContextId string, manifest CredentialManifest) error
+	DeleteCredential(ctx context.Context, participantContextId string, credentialId string) error
+	QueryDidDocuments(ctx context.Context, participantContextId string) ([]json.RawMessage, error)
 }
 
 type ParticipantResponse struct {
@@ -14,17 +32,222 @@ type ParticipantResponse struct {
 	ApiKey       string `json:"apiKey"`
 }
 
-func (i *ApiClient) CreateParticipant(body string) (*ParticipantResponse, error) {
-	jsonBody, err := sendRequest(i.HttpClient, i.ApiKey, body, i.BaseUrl+"/participants")
+// ParticipantContext is a participant known to the identity hub. State is 0 when created, 1 when activated and 2 when
+// deactivated.
+type ParticipantContext struct {
+	ParticipantContextId string   `json:"participantContextId"`
+	Did                  string   `json:"did"`
+	State                int      `json:"state"`
+	Roles                []string `json:"roles,omitempty"`
+}
+
+// CreateParticipant creates a participant context and returns its credentials, or nil if it already exists.
+func (i *ApiClient) CreateParticipant(ctx context.Context, body string) (*ParticipantResponse, error) {
+	p, err := DoJson[ParticipantResponse](ctx, i, Request{Method: http.MethodPost, Path: "/participants", Body: body})
+	if IsConflict(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
 
+// GetParticipant returns the participant context, or nil if the identity hub doesn't know it. The participant context
+// ID is passed base64 encoded.
+func (i *ApiClient) GetParticipant(ctx context.Context, participantContextId string) (*ParticipantContext, error) {
+	participant, err := DoJson[ParticipantContext](ctx, i, Request{Path: "/participants/" + participantContextId})
+	if IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	return &participant, nil
+}
+
+// ListParticipants returns the participant contexts of the identity hub, which requires the super-user key.
+func (i *ApiClient) ListParticipants(ctx context.Context) ([]ParticipantContext, error) {
+	return DoJson[[]ParticipantContext](ctx, i, Request{Path: "/participants"})
+}
+
+// DeleteParticipant deletes the participant context with its keys and credentials. Unknown participants are ignored.
+func (i *ApiClient) DeleteParticipant(ctx context.Context, participantContextId string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodDelete, Path: "/participants/" + participantContextId})
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
 
-	var p ParticipantResponse
-	err = json.Unmarshal([]byte(jsonBody), &p)
+// QueryDidDocuments returns the DID documents of the participant context, with the public keys and service
+// endpoints the identity hub publishes.
+func (i *ApiClient) QueryDidDocuments(ctx context.Context, participantContextId string) ([]json.RawMessage, error) {
+	return DoJson[[]json.RawMessage](ctx, i, Request{Method: http.MethodPost, Path: "/participants/" + participantContextId + "/dids/query", Body: map[string]any{}})
+}
+
+// stsSecretRotation replaces the client secret of an STS account, the secret is stored in the vault under the alias.
+type stsSecretRotation struct {
+	NewAlias  string `json:"newAlias"`
+	NewSecret string `json:"newSecret"`
+}
+
+// RegenerateClientSecret replaces the client secret the connector of a participant authenticates with at the STS. The
+// account ID is the client ID returned on creation of the participant; the connector reads the secret from the vault
+// under the alias, so callers store it there as well.
+func (i *ApiClient) RegenerateClientSecret(ctx context.Context, accountId string, alias string, secret string) error {
+	_, err := i.Do(ctx, Request{
+		Method: http.MethodPost,
+		Path:   "/sts/accounts/" + url.PathEscape(accountId) + "/secret",
+		Body:   stsSecretRotation{NewAlias: alias, NewSecret: secret},
+	})
+	return err
+}
+
+// RegenerateToken replaces the API key of a participant context and returns the new key. The participant context ID
+// is passed base64 encoded, e.g. "c3VwZXItdXNlcg==" for the super-user.
+func (i *ApiClient) RegenerateToken(ctx context.Context, participantContextId string) (string, error) {
+	token, err := i.Do(ctx, Request{Method: http.MethodPost, Path: "/participants/" + participantContextId + "/token"})
 	if err != nil {
+		return "", err
+	}
+	return strings.Trim(string(token), "\" \r\n"), nil
+}
+
+// CredentialRequest asks an issuer to issue verifiable credentials to a participant context. HolderPid identifies the
+// request, it is chosen by the holder.
+type CredentialRequest struct {
+	IssuerDid   string                 `json:"issuerDid"`
+	HolderPid   string                 `json:"holderPid"`
+	Credentials []CredentialDescriptor `json:"credentials"`
+}
+
+type CredentialDescriptor struct {
+	Format         string `json:"format"`
+	CredentialType string `json:"credentialType"`
+}
+
+// CredentialRequestStatus is the state of a credential request as tracked by the identity hub, e.g. REQUESTED,
+// ISSUED or ERROR.
+type CredentialRequestStatus struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// RequestCredentials sends a credential request to the issuer on behalf of the participant context. Requests that
+// were already sent are left alone.
+func (i *ApiClient) RequestCredentials(ctx context.Context, participantContextId string, request CredentialRequest) error {
+	_, err := i.Do(ctx, Request{
+		Method:         http.MethodPost,
+		Path:           "/participants/" + participantContextId + "/credentials/request",
+		Body:           request,
+		IgnoreConflict: true,
+	})
+	return err
+}
+
+// GetCredentialRequest returns the state of the credential request, or nil if the identity hub doesn't know it.
+func (i *ApiClient) GetCredentialRequest(ctx context.Context, participantContextId string, holderPid string) (*CredentialRequestStatus, error) {
+	request, err := DoJson[CredentialRequestStatus](ctx, i, Request{Path: "/participants/" + participantContextId + "/credentials/request/" + url.PathEscape(holderPid)})
+	if IsNotFound(err) {
 		return nil, nil
 	}
-	return &p, err
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CredentialContainer holds a verifiable credential in its raw form, e.g. a JWT, and as parsed JSON.
+type CredentialContainer struct {
+	RawVc      string          `json:"rawVc"`
+	Format     string          `json:"format"`
+	Credential json.RawMessage `json:"credential,omitempty"`
+}
+
+// CredentialResource is a verifiable credential held by a participant context.
+type CredentialResource struct {
+	Id       string `json:"id"`
+	IssuerId string `json:"issuerId"`
+	HolderId string `json:"holderId"`
+	// State is the numeric state of the credential, see CredentialStateName
+	State                int                 `json:"state"`
+	VerifiableCredential CredentialContainer `json:"verifiableCredential"`
+}
+
+// CredentialManifest adds a verifiable credential obtained elsewhere to a participant context.
+type CredentialManifest struct {
+	Id                            string              `json:"id"`
+	ParticipantContextId          string              `json:"participantContextId"`
+	VerifiableCredentialContainer CredentialContainer `json:"verifiableCredentialContainer"`
+}
+
+// Credential is the part of a parsed verifiable credential the provisioner reports.
+type Credential struct {
+	Types          []string   `json:"type"`
+	IssuanceDate   *time.Time `json:"issuanceDate,omitempty"`
+	ExpirationDate *time.Time `json:"expirationDate,omitempty"`
+}
+
+// Parsed returns the parsed credential, empty if the identity hub didn't include it.
+func (r CredentialResource) Parsed() Credential {
+	var credential Credential
+	if len(r.VerifiableCredential.Credential) > 0 {
+		_ = json.Unmarshal(r.VerifiableCredential.Credential, &credential)
+	}
+	return credential
+}
+
+// Names of the states of held credentials
+var credentialStates = map[int]string{
+	100: "INITIAL",
+	200: "ISSUANCE_REQUESTED",
+	300: "ISSUED",
+	400: "REISSUE_REQUESTED",
+	500: "REVOKED",
+	600: "SUSPENDED",
+	700: "EXPIRED",
+	800: "NOT_YET_VALID",
+}
+
+// CredentialStateName returns the name of a credential state, or its number if it is unknown.
+func CredentialStateName(state int) string {
+	if name, ok := credentialStates[state]; ok {
+		return name
+	}
+	return strconv.Itoa(state)
+}
+
+// HasCredential reports whether the participant context holds a credential of the type.
+func (i *ApiClient) HasCredential(ctx context.Context, participantContextId string, credentialType string) (bool, error) {
+	credentials, err := i.QueryCredentials(ctx, participantContextId, credentialType)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
+
+// QueryCredentials returns the credentials the participant context holds, only those of the type unless it is empty.
+func (i *ApiClient) QueryCredentials(ctx context.Context, participantContextId string, credentialType string) ([]CredentialResource, error) {
+	path := "/participants/" + participantContextId + "/credentials"
+	if credentialType != "" {
+		path += "?type=" + url.QueryEscape(credentialType)
+	}
+	return DoJson[[]CredentialResource](ctx, i, Request{Path: path})
+}
+
+// AddCredential stores a credential in the participant context, e.g. one issued outside the issuance flow.
+func (i *ApiClient) AddCredential(ctx context.Context, participantContextId string, manifest CredentialManifest) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodPost, Path: "/participants/" + participantContextId + "/credentials", Body: manifest})
+	return err
+}
+
+// DeleteCredential removes a credential from the participant context. This doesn't revoke it, only the issuer can,
+// see IssuerApi.RevokeCredential. Unknown credentials are ignored.
+func (i *ApiClient) DeleteCredential(ctx context.Context, participantContextId string, credentialId string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodDelete, Path: "/participants/" + participantContextId + "/credentials/" + url.PathEscape(credentialId)})
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
 }
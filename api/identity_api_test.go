@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIdentityApiParticipants(t *testing.T) {
+	var rotation stsSecretRotation
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /participants":
+			_, _ = w.Write([]byte(`[{"participantContextId":"did:web:alice","did":"did:web:alice","state":1}]`))
+		case "GET /participants/ZGlkOndlYjphbGljZQ==":
+			_, _ = w.Write([]byte(`{"participantContextId":"did:web:alice","did":"did:web:alice","state":1}`))
+		case "POST /sts/accounts/did:web:alice/secret":
+			_ = json.NewDecoder(r.Body).Decode(&rotation)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	var identityApi IdentityApi = &ApiClient{BaseUrl: server.URL}
+	ctx := context.Background()
+
+	participants, err := identityApi.ListParticipants(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(participants) != 1 || participants[0].State != 1 {
+		t.Errorf("unexpected participants %+v", participants)
+	}
+	alice, err := identityApi.GetParticipant(ctx, "ZGlkOndlYjphbGljZQ==")
+	if err != nil || alice == nil || alice.Did != "did:web:alice" {
+		t.Errorf("expected alice, got %+v, %v", alice, err)
+	}
+	bob, err := identityApi.GetParticipant(ctx, "ZGlkOndlYjpib2I=")
+	if err != nil || bob != nil {
+		t.Errorf("expected no participant for an unknown context, got %+v, %v", bob, err)
+	}
+	if err := identityApi.DeleteParticipant(ctx, "ZGlkOndlYjpib2I="); err != nil {
+		t.Errorf("expected deleting an unknown participant to succeed, got %v", err)
+	}
+	if err := identityApi.RegenerateClientSecret(ctx, "did:web:alice", "did:web:alice-sts-client-secret", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if rotation.NewAlias != "did:web:alice-sts-client-secret" || rotation.NewSecret != "s3cret" {
+		t.Errorf("unexpected rotation %+v", rotation)
+	}
+}
+
+func TestIdentityApiCredentials(t *testing.T) {
+	var added CredentialManifest
+	var deleted, revoked string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /participants/alice/credentials":
+			if r.URL.Query().Get("type") == "DataProcessorCredential" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"id":"membership","issuerId":"did:web:issuer","state":500,"verifiableCredential":{"format":"VC1_0_JWT","credential":{"type":["VerifiableCredential","MembershipCredential"],"expirationDate":"2030-01-01T00:00:00Z"}}}]`))
+		case "POST /participants/alice/credentials":
+			_ = json.NewDecoder(r.Body).Decode(&added)
+		case "DELETE /participants/alice/credentials/membership":
+			deleted = "membership"
+		case "POST /credentials/membership/revoke":
+			revoked = "membership"
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := &ApiClient{BaseUrl: server.URL}
+	var identityApi IdentityApi = client
+	ctx := context.Background()
+
+	credentials, err := identityApi.QueryCredentials(ctx, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 1 || CredentialStateName(credentials[0].State) != "REVOKED" {
+		t.Fatalf("unexpected credentials %+v", credentials)
+	}
+	parsed := credentials[0].Parsed()
+	if len(parsed.Types) != 2 || parsed.ExpirationDate == nil || parsed.IssuanceDate != nil {
+		t.Errorf("unexpected parsed credential %+v", parsed)
+	}
+	if held, err := identityApi.HasCredential(ctx, "alice", "DataProcessorCredential"); err != nil || held {
+		t.Errorf("expected no DataProcessorCredential, got %v, %v", held, err)
+	}
+	manifest := CredentialManifest{Id: "imported", ParticipantContextId: "alice", VerifiableCredentialContainer: CredentialContainer{RawVc: "eyJ", Format: "VC1_0_JWT"}}
+	if err := identityApi.AddCredential(ctx, "alice", manifest); err != nil {
+		t.Fatal(err)
+	}
+	if added.Id != "imported" || added.VerifiableCredentialContainer.RawVc != "eyJ" {
+		t.Errorf("unexpected manifest %+v", added)
+	}
+	if err := identityApi.DeleteCredential(ctx, "alice", "membership"); err != nil || deleted != "membership" {
+		t.Errorf("expected the credential to be deleted, got %v", err)
+	}
+	if err := identityApi.DeleteCredential(ctx, "alice", "unknown"); err != nil {
+		t.Errorf("expected deleting an unknown credential to succeed, got %v", err)
+	}
+	var issuerApi IssuerApi = client
+	if err := issuerApi.RevokeCredential(ctx, "membership"); err != nil || revoked != "membership" {
+		t.Errorf("expected the credential to be revoked, got %v", err)
+	}
+	if name := CredentialStateName(42); name != "42" {
+		t.Errorf("expected unknown states to be reported by number, got %s", name)
+	}
+}
@@ -1,17 +1,35 @@
 package api
 
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
 type IssuerApi interface {
-	CreateHolder(did string, holderId string, name string) (string, error)
+	CreateHolder(ctx context.Context, did string, holderId string, name string) error
+	RevokeCredential(ctx context.Context, credentialId string) error
+}
+
+type holder struct {
+	Did      string `json:"did"`
+	HolderId string `json:"holderId"`
+	Name     string `json:"name"`
 }
 
-func (i *ApiClient) CreateHolder(did string, holderId string, name string) error {
-	url := i.BaseUrl + "/holders"
+func (i *ApiClient) CreateHolder(ctx context.Context, did string, holderId string, name string) error {
+	_, err := i.Do(ctx, Request{
+		Method:         http.MethodPost,
+		Path:           "/holders",
+		Body:           holder{Did: did, HolderId: holderId, Name: name},
+		IgnoreConflict: true,
+	})
+	return err
+}
 
-	body := `{
-				"did": "` + did + `",
-    			"holderId": "` + holderId + `",
- 				"name": "` + name + `"
-			}`
-	_, err := sendRequest(i.HttpClient, i.ApiKey, body, url)
+// RevokeCredential revokes a credential the issuer issued, holders and verifiers learn about it from the issuer's
+// status list.
+func (i *ApiClient) RevokeCredential(ctx context.Context, credentialId string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodPost, Path: "/credentials/" + url.PathEscape(credentialId) + "/revoke"})
 	return err
 }
@@ -1,24 +1,122 @@
 package api
 
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
 type ManagementApi interface {
-	CreateAsset(body string) (string, error)
-	CreatePolicy(body string) (string, error)
-	CreateContractDefinition(body string) (string, error)
-	CreateSecret(body string) (string, error)
+	CreateAsset(ctx context.Context, body string) (string, error)
+	CreatePolicy(ctx context.Context, body string) (string, error)
+	CreateContractDefinition(ctx context.Context, body string) (string, error)
+	CreateSecret(ctx context.Context, body string) (string, error)
+	QueryAssets(ctx context.Context, body string) (string, error)
+	QueryPolicies(ctx context.Context, body string) (string, error)
+	QueryContractDefinitions(ctx context.Context, body string) (string, error)
+	GetAsset(ctx context.Context, id string) (string, error)
+	GetPolicy(ctx context.Context, id string) (string, error)
+	GetContractDefinition(ctx context.Context, id string) (string, error)
+	DeleteAsset(ctx context.Context, id string) error
+	DeletePolicy(ctx context.Context, id string) error
+	DeleteContractDefinition(ctx context.Context, id string) error
+	QueryContractAgreements(ctx context.Context, body string) (string, error)
+	QueryTransferProcesses(ctx context.Context, body string) (string, error)
+	RequestCatalog(ctx context.Context, body string) (string, error)
+	InitiateNegotiation(ctx context.Context, body string) (string, error)
+	GetNegotiation(ctx context.Context, id string) (string, error)
+	InitiateTransfer(ctx context.Context, body string) (string, error)
+	GetTransferProcess(ctx context.Context, id string) (string, error)
+	TerminateTransfer(ctx context.Context, id string, body string) error
+}
+
+func (i *ApiClient) CreateAsset(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/assets", body)
+}
+func (i *ApiClient) CreatePolicy(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/policydefinitions", body)
+}
+
+func (i *ApiClient) CreateContractDefinition(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/contractdefinitions", body)
+}
+
+func (i *ApiClient) CreateSecret(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/secrets", body)
+}
+
+func (i *ApiClient) QueryAssets(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/assets/request", body)
+}
+
+func (i *ApiClient) QueryPolicies(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/policydefinitions/request", body)
+}
+
+func (i *ApiClient) QueryContractDefinitions(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/contractdefinitions/request", body)
+}
+
+func (i *ApiClient) GetAsset(ctx context.Context, id string) (string, error) {
+	return i.send(ctx, http.MethodGet, "/assets/"+url.PathEscape(id), "")
+}
+
+func (i *ApiClient) GetPolicy(ctx context.Context, id string) (string, error) {
+	return i.send(ctx, http.MethodGet, "/policydefinitions/"+url.PathEscape(id), "")
 }
 
-func (i *ApiClient) CreateAsset(body string) (string, error) {
-	return sendRequest(i.HttpClient, i.ApiKey, body, i.BaseUrl+"/assets")
+func (i *ApiClient) GetContractDefinition(ctx context.Context, id string) (string, error) {
+	return i.send(ctx, http.MethodGet, "/contractdefinitions/"+url.PathEscape(id), "")
 }
-func (i *ApiClient) CreatePolicy(body string) (string, error) {
-	return sendRequest(i.HttpClient, i.ApiKey, body, i.BaseUrl+"/policydefinitions")
+
+// DeleteAsset deletes the asset. The connector refuses with 409 while a contract agreement references it.
+func (i *ApiClient) DeleteAsset(ctx context.Context, id string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodDelete, Path: "/assets/" + url.PathEscape(id)})
+	return err
+}
+
+// DeletePolicy deletes the policy definition. The connector refuses with 409 while a contract definition references
+// it, so contract definitions have to be deleted first.
+func (i *ApiClient) DeletePolicy(ctx context.Context, id string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodDelete, Path: "/policydefinitions/" + url.PathEscape(id)})
+	return err
+}
+
+func (i *ApiClient) DeleteContractDefinition(ctx context.Context, id string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodDelete, Path: "/contractdefinitions/" + url.PathEscape(id)})
+	return err
+}
+
+func (i *ApiClient) QueryContractAgreements(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/contractagreements/request", body)
+}
+
+func (i *ApiClient) QueryTransferProcesses(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/transferprocesses/request", body)
+}
+
+// RequestCatalog requests the catalog of the counterparty named in the body via DSP.
+func (i *ApiClient) RequestCatalog(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/catalog/request", body)
+}
+
+func (i *ApiClient) InitiateNegotiation(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/contractnegotiations", body)
+}
+
+func (i *ApiClient) GetNegotiation(ctx context.Context, id string) (string, error) {
+	return i.send(ctx, http.MethodGet, "/contractnegotiations/"+url.PathEscape(id), "")
+}
+
+func (i *ApiClient) InitiateTransfer(ctx context.Context, body string) (string, error) {
+	return i.send(ctx, http.MethodPost, "/transferprocesses", body)
 }
 
-func (i *ApiClient) CreateContractDefinition(body string) (string, error) {
-	url := i.BaseUrl + "/contractdefinitions"
-	return sendRequest(i.HttpClient, i.ApiKey, body, url)
+func (i *ApiClient) GetTransferProcess(ctx context.Context, id string) (string, error) {
+	return i.send(ctx, http.MethodGet, "/transferprocesses/"+url.PathEscape(id), "")
 }
 
-func (i *ApiClient) CreateSecret(body string) (string, error) {
-	return sendRequest(i.HttpClient, i.ApiKey, body, i.BaseUrl+"/secrets")
+func (i *ApiClient) TerminateTransfer(ctx context.Context, id string, body string) error {
+	_, err := i.Do(ctx, Request{Method: http.MethodPost, Path: "/transferprocesses/" + url.PathEscape(id) + "/terminate", Body: body})
+	return err
 }
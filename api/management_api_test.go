@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestManagementApiCatalogOperations(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		if r.URL.Path == "/assets/in-use" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	var mgmtApi ManagementApi = &ApiClient{BaseUrl: server.URL}
+	ctx := context.Background()
+
+	if _, err := mgmtApi.QueryPolicies(ctx, `{}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgmtApi.QueryContractDefinitions(ctx, `{}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgmtApi.GetContractDefinition(ctx, "cd/1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgmtApi.DeleteContractDefinition(ctx, "cd-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgmtApi.DeletePolicy(ctx, "policy-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgmtApi.DeleteAsset(ctx, "in-use"); !IsConflict(err) {
+		t.Errorf("expected the conflict deleting a referenced asset, got %v", err)
+	}
+
+	want := []string{
+		"POST /policydefinitions/request",
+		"POST /contractdefinitions/request",
+		"GET /contractdefinitions/cd%2F1",
+		"DELETE /contractdefinitions/cd-1",
+		"DELETE /policydefinitions/policy-1",
+		"DELETE /assets/in-use",
+	}
+	if !slices.Equal(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
@@ -0,0 +1,136 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	status  ParticipantStatus
+	fields  []Field
+	expires time.Time
+}
+
+// statusCache keeps recently evaluated participant statuses for a fixed TTL. Every invalidation bumps the version of
+// the participant, so evaluations that started before an invalidation can't overwrite it with stale results.
+type statusCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	entries  map[string]cacheEntry
+	versions map[string]uint64
+	// lastKnown keeps the latest evaluated status of every participant beyond the TTL, to be reported while the
+	// cluster can't be reached
+	lastKnown map[string]cacheEntry
+}
+
+func newStatusCache(ttl time.Duration) *statusCache {
+	return &statusCache{
+		ttl:       ttl,
+		entries:   make(map[string]cacheEntry),
+		versions:  make(map[string]uint64),
+		lastKnown: make(map[string]cacheEntry),
+	}
+}
+
+// version returns the current version of the participant's entry, to be passed to set.
+func (c *statusCache) version(name string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.versions[name]
+}
+
+// get returns the cached status if it is still fresh and contains all requested fields.
+func (c *statusCache) get(name string, fields []Field) (ParticipantStatus, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[name]
+	if !ok || time.Now().After(entry.expires) {
+		return ParticipantStatus{}, false
+	}
+	for _, field := range fields {
+		if !hasField(entry.fields, field) {
+			return ParticipantStatus{}, false
+		}
+	}
+	return entry.status, true
+}
+
+// set caches a status evaluated at the given version, unless the entry was invalidated in the meantime or caching is
+// disabled.
+func (c *statusCache) set(name string, status ParticipantStatus, fields []Field, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions[name] != version {
+		return
+	}
+	c.lastKnown[name] = cacheEntry{status: status, fields: fields}
+	if c.ttl <= 0 {
+		return
+	}
+	c.entries[name] = cacheEntry{
+		status:  status,
+		fields:  fields,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// last returns the latest status evaluated for the participant, however old, if it contains all requested fields.
+func (c *statusCache) last(name string, fields []Field) (ParticipantStatus, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.lastKnown[name]
+	if !ok {
+		return ParticipantStatus{}, false
+	}
+	for _, field := range fields {
+		if !hasField(entry.fields, field) {
+			return ParticipantStatus{}, false
+		}
+	}
+	return entry.status, true
+}
+
+// forget drops the latest status of a participant that was deleted or provisioned again.
+func (c *statusCache) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lastKnown, name)
+}
+
+func (c *statusCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+	c.versions[name]++
+}
+
+// cleanupLoop periodically evicts expired entries until the context is cancelled.
+func (c *statusCache) cleanupLoop(ctx context.Context) {
+	if c.ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for name, entry := range c.entries {
+				if now.After(entry.expires) {
+					delete(c.entries, name)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *statusCache) has(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.entries[name]
+	return ok
+}
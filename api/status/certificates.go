@@ -0,0 +1,52 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var certificateListKind = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "CertificateList"}
+
+// getCertificateStatuses reports the cert-manager Certificates in the namespace. Clusters without cert-manager have
+// none.
+func (s *StatusChecker) getCertificateStatuses(ctx context.Context, namespace string) []CertificateStatus {
+	certificates := &unstructured.UnstructuredList{}
+	certificates.SetGroupVersionKind(certificateListKind)
+	if err := s.client.List(ctx, certificates, client.InNamespace(namespace)); err != nil {
+		if !meta.IsNoMatchError(err) {
+			fmt.Printf("listing certificates of %s failed: %v\n", namespace, err)
+		}
+		return nil
+	}
+	statuses := make([]CertificateStatus, 0, len(certificates.Items))
+	for _, certificate := range certificates.Items {
+		statuses = append(statuses, certificateStatusOf(certificate))
+	}
+	return statuses
+}
+
+// certificateStatusOf reads the Ready condition and expiry of a Certificate.
+func certificateStatusOf(certificate unstructured.Unstructured) CertificateStatus {
+	result := CertificateStatus{Name: certificate.GetName(), Message: "waiting for cert-manager"}
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, condition := range conditions {
+		condition, ok := condition.(map[string]any)
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		result.Ready = condition["status"] == "True"
+		result.Message, _ = condition["message"].(string)
+	}
+	if notAfter, _, _ := unstructured.NestedString(certificate.Object, "status", "notAfter"); notAfter != "" {
+		if parsed, err := time.Parse(time.RFC3339, notAfter); err == nil {
+			result.NotAfter = &parsed
+		}
+	}
+	return result
+}
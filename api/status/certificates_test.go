@@ -0,0 +1,29 @@
+package status
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCertificateStatusOf(t *testing.T) {
+	certificate := unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "ingress-tls"},
+		"status": map[string]any{
+			"notAfter": "2026-01-01T00:00:00Z",
+			"conditions": []any{
+				map[string]any{"type": "Issuing", "status": "False"},
+				map[string]any{"type": "Ready", "status": "True", "message": "Certificate is up to date and has not expired"},
+			},
+		},
+	}}
+	status := certificateStatusOf(certificate)
+	if !status.Ready || status.Name != "ingress-tls" || status.NotAfter == nil || status.NotAfter.Year() != 2026 {
+		t.Errorf("expected a ready certificate expiring 2026, got %+v", status)
+	}
+
+	pending := certificateStatusOf(unstructured.Unstructured{Object: map[string]any{"metadata": map[string]any{"name": "ingress-tls"}}})
+	if pending.Ready || pending.Message == "" {
+		t.Errorf("expected a pending certificate, got %+v", pending)
+	}
+}
@@ -0,0 +1,731 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultCacheTTL is how long evaluated participant statuses are served from the cache by default
+const DefaultCacheTTL = 10 * time.Second
+
+const (
+	recentEventsLimit  = 10
+	recentEventsWindow = 30 * time.Minute
+)
+
+// Components that are not ready after this period are reported as degraded rather than starting.
+const provisioningGracePeriod = 10 * time.Minute
+
+// CriticalDeployments are the deployments a participant stack consists of, set with --critical-deployments. Those a
+// participant wasn't provisioned with aren't required of it, e.g. postgres of stacks using an external database.
+var CriticalDeployments = []string{"controlplane", "dataplane", "identityhub", "postgres"}
+
+// SelectableComponents are the components a participant stack may be provisioned without. The other critical
+// deployments are shared by them.
+var SelectableComponents = []string{"controlplane", "dataplane", "identityhub"}
+
+// OptionalComponents are only part of a participant stack when they were selected, next to the selectable ones.
+var OptionalComponents = []string{"federatedcatalog"}
+
+// ComponentsAnnotation on the namespace of a participant with a partial stack, or with optional components, lists its
+// selectable and optional components, comma separated.
+const ComponentsAnnotation = "aruba-provisioner/components"
+
+// DeploymentsAnnotation on the namespace of a participant lists the deployments its manifests were last applied with,
+// comma separated.
+const DeploymentsAnnotation = "aruba-provisioner/deployments"
+
+// MinReplicasAnnotation on an autoscaled deployment is the number of replicas it needs at least to be ready, its
+// autoscaler may be adding more.
+const MinReplicasAnnotation = "aruba-provisioner/min-replicas"
+
+// Containers injected by service meshes, their readiness doesn't reflect the health of the component itself
+var sidecarContainers = map[string]bool{"istio-proxy": true, "linkerd-proxy": true}
+
+// StatusChecker evaluates the status of participants from the live cluster state and caches the result.
+type StatusChecker struct {
+	client    client.Client
+	cache     *statusCache
+	evaluator StatusEvaluator
+
+	mu              sync.RWMutex
+	seeding         map[string]SeedingStatus
+	connectorEvents map[string][]Event
+	operations      map[string]operation
+	deleted         map[string]time.Time
+	failures        map[string]Failure
+	provisioning    map[string]ProvisioningDurations
+	progress        map[string][]ProgressStep
+	didRotations    map[string]DidRotationStatus
+	waiters         deploymentWaiters
+	// prober requests the participant's ingress routes, nil unless probes are enabled
+	prober *routeProber
+	// health requests the components' health endpoints, nil unless health checks are enabled
+	health *healthChecker
+	// credentials looks up the credentials held by a participant, nil unless enabled
+	credentials CredentialLookup
+	// onTransition is called with notable status transitions, reported holds the last status reported per participant
+	onTransition func(Transition)
+	reported     map[string]ProvisioningStatus
+	// history persists the status changes of the participants, nil unless enabled
+	history *statusHistory
+	// sharedOperations looks up the operations other replicas run, nil unless enabled
+	sharedOperations SharedOperations
+
+	// loops tracks the background loops of the checker
+	loops sync.WaitGroup
+}
+
+// NewStatusChecker creates a checker caching statuses for cacheTTL, whose cache cleanup runs until the context is
+// cancelled.
+func NewStatusChecker(ctx context.Context, c client.Client, cacheTTL time.Duration) *StatusChecker {
+	checker := &StatusChecker{
+		client:          c,
+		cache:           newStatusCache(cacheTTL),
+		seeding:         make(map[string]SeedingStatus),
+		connectorEvents: make(map[string][]Event),
+		operations:      make(map[string]operation),
+		deleted:         make(map[string]time.Time),
+		failures:        make(map[string]Failure),
+		provisioning:    make(map[string]ProvisioningDurations),
+		progress:        make(map[string][]ProgressStep),
+		didRotations:    make(map[string]DidRotationStatus),
+	}
+	checker.loops.Add(1)
+	go func() {
+		defer checker.loops.Done()
+		checker.cache.cleanupLoop(ctx)
+	}()
+	return checker
+}
+
+// Wait blocks until the cache cleanup and event watch of the checker returned after their context was cancelled.
+func (s *StatusChecker) Wait() {
+	s.loops.Wait()
+}
+
+// GetStatus returns the status of a participant containing the requested optional fields.
+func (s *StatusChecker) GetStatus(ctx context.Context, name string, fields []Field) (ParticipantStatus, error) {
+	if cached, ok := s.cache.get(name, fields); ok {
+		reported := withRemainingLifetime(s.applyOperation(cached, s.sharedOperation(ctx, name)))
+		s.observe(reported)
+		return reported.Project(fields), nil
+	}
+
+	version := s.cache.version(name)
+	participantStatus, err := s.evaluate(ctx, name, fields)
+	if err != nil {
+		return ParticipantStatus{}, err
+	}
+	loaded := []Field{FieldComponents, FieldSeeding, FieldEndpoints, FieldCertificates, FieldSteps}
+	for _, field := range []Field{FieldEvents, FieldProbes, FieldCredentials} {
+		if hasField(fields, field) {
+			loaded = append(loaded, field)
+		}
+	}
+	s.cache.set(name, participantStatus, loaded, version)
+	reported := withRemainingLifetime(s.applyOperation(participantStatus, s.sharedOperation(ctx, name)))
+	s.observe(reported)
+	return reported.Project(fields), nil
+}
+
+// Invalidate drops the cached status of a participant, e.g. after it was provisioned or deleted.
+func (s *StatusChecker) Invalidate(name string) {
+	s.cache.invalidate(name)
+}
+
+// Reset forgets the seeding progress and connector events of a participant, e.g. when it is re-provisioned or deleted,
+// so a recreated participant doesn't report the state of a previous run.
+func (s *StatusChecker) Reset(name string) {
+	s.mu.Lock()
+	delete(s.seeding, name)
+	delete(s.connectorEvents, name)
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+	s.cache.forget(name)
+}
+
+// LastKnown returns the latest status evaluated for the participant, marked as stale, to be reported while the cluster
+// can't be reached. It is false if the participant's status with the fields wasn't evaluated since the start.
+func (s *StatusChecker) LastKnown(name string, fields []Field) (ParticipantStatus, bool) {
+	last, ok := s.cache.last(name, fields)
+	if !ok {
+		return ParticipantStatus{}, false
+	}
+	last.Stale = true
+	return withRemainingLifetime(last).Project(fields), true
+}
+
+// SetSeeding records the progress of the data seeding for a participant. The progress of its steps is kept.
+func (s *StatusChecker) SetSeeding(name string, state string, message string) {
+	s.mu.Lock()
+	s.seeding[name] = SeedingStatus{
+		State:       state,
+		Message:     message,
+		UpdatedAt:   time.Now(),
+		Steps:       s.seeding[name].Steps,
+		Credentials: s.seeding[name].Credentials,
+		Resources:   s.seeding[name].Resources,
+		// a completed run is verified again, the outcome of the previous run is kept until then
+		Verification: s.seeding[name].Verification,
+	}
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetSeedingStep records the progress of a seed step, replacing the previous progress of the step.
+func (s *StatusChecker) SetSeedingStep(name string, step SeedingStep) {
+	step.UpdatedAt = time.Now()
+	s.mu.Lock()
+	seeding := s.seeding[name]
+	steps := make([]SeedingStep, 0, len(seeding.Steps)+1)
+	replaced := false
+	for _, existing := range seeding.Steps {
+		if existing.Name == step.Name {
+			existing, replaced = step, true
+		}
+		steps = append(steps, existing)
+	}
+	if !replaced {
+		steps = append(steps, step)
+	}
+	seeding.Steps = steps
+	s.seeding[name] = seeding
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetSeededResources records the entities seeding created so far, replacing the previously recorded ones.
+func (s *StatusChecker) SetSeededResources(name string, resources SeededResources) {
+	s.mu.Lock()
+	seeding := s.seeding[name]
+	seeding.Resources = &resources
+	s.seeding[name] = seeding
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetVerification records the outcome of verifying the seeded entities of a participant.
+func (s *StatusChecker) SetVerification(name string, verification CatalogVerification) {
+	s.mu.Lock()
+	seeding := s.seeding[name]
+	seeding.Verification = &verification
+	s.seeding[name] = seeding
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetCredential records the issuance state of a credential, replacing the previous state of the credential type.
+func (s *StatusChecker) SetCredential(name string, credential CredentialStatus) {
+	credential.UpdatedAt = time.Now()
+	s.mu.Lock()
+	seeding := s.seeding[name]
+	credentials := make([]CredentialStatus, 0, len(seeding.Credentials)+1)
+	replaced := false
+	for _, existing := range seeding.Credentials {
+		if existing.Type == credential.Type {
+			existing, replaced = credential, true
+		}
+		credentials = append(credentials, existing)
+	}
+	if !replaced {
+		credentials = append(credentials, credential)
+	}
+	seeding.Credentials = credentials
+	s.seeding[name] = seeding
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// GetSeeding returns the seeding progress recorded for the participant, nil if it wasn't seeded since the start.
+func (s *StatusChecker) GetSeeding(name string) *SeedingStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if seeding, ok := s.seeding[name]; ok {
+		return &seeding
+	}
+	return nil
+}
+
+// evaluate determines the status of the participant from the cluster. Events, probes and credentials, which are costly,
+// are only loaded when among the fields.
+func (s *StatusChecker) evaluate(ctx context.Context, name string, fields []Field) (ParticipantStatus, error) {
+	result := ParticipantStatus{
+		Name:        name,
+		LastUpdated: time.Now(),
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := s.client.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			result.Status = StatusNotFound
+			return result, nil
+		}
+		return result, err
+	}
+
+	result.Metadata = metadataOf(namespace)
+	result.ExpiresAt = ExpiryOf(namespace)
+	critical := criticalDeploymentsOf(namespace)
+	components, err := s.getComponentStatuses(ctx, name, critical)
+	if err != nil {
+		return result, err
+	}
+	result.Components = components
+	result.Status, result.Message = s.evaluator.Evaluate(components)
+	result.Seeding = s.GetSeeding(name)
+	result.Status, result.Message = seedingStatus(result.Status, result.Message, result.Seeding)
+	result.Endpoints = endpointsFor(name, critical)
+	result.Certificates = s.getCertificateStatuses(ctx, name)
+
+	switch {
+	case namespace.DeletionTimestamp != nil:
+		result.Status = StatusTerminating
+		result.Message = "namespace is being deleted"
+		result.Endpoints = nil
+	case result.Status == StatusNotFound:
+		result.Status = StatusOrphaned
+		result.Message = "namespace exists but no participant components were found"
+		result.Endpoints = nil
+	}
+	if result.Status == StatusTerminating || result.Status == StatusOrphaned {
+		if result.Remaining, err = s.remainingResources(ctx, name); err != nil {
+			return result, err
+		}
+	}
+
+	if hasField(fields, FieldEvents) {
+		events, err := s.GetRecentEvents(ctx, name)
+		if err != nil {
+			return result, err
+		}
+		result.Events = events
+	}
+	if hasField(fields, FieldProbes) && s.prober != nil && result.Endpoints != nil {
+		result.Probes = s.probeRoutes(ctx, name)
+	}
+	if hasField(fields, FieldCredentials) && s.credentials != nil && result.Endpoints["credentials"] != "" {
+		result.Credentials = s.heldCredentials(ctx, name)
+	}
+	return result, nil
+}
+
+// seedingStatus reports ready participants as SEEDING or SEED_FAILED until their data was seeded, as their APIs are
+// up but the assets, policies and participant records may not exist yet, and as DEGRADED when the seeded entities
+// couldn't be verified afterwards. Unhealthy components take precedence.
+func seedingStatus(status ProvisioningStatus, message string, seeding *SeedingStatus) (ProvisioningStatus, string) {
+	if status != StatusReady || seeding == nil {
+		return status, message
+	}
+	switch seeding.State {
+	case SeedingRunning:
+		return StatusSeeding, "seeding data"
+	case SeedingFailed:
+		return StatusSeedFailed, "seeding failed: " + seeding.Message
+	}
+	if seeding.Verification != nil && !seeding.Verification.Verified {
+		return StatusDegraded, "catalog verification failed: " + seeding.Verification.Message
+	}
+	return status, message
+}
+
+// remainingResources lists the workloads, services and config maps still present in the namespace.
+func (s *StatusChecker) remainingResources(ctx context.Context, namespace string) ([]string, error) {
+	var remaining []string
+	deployments := &appsv1.DeploymentList{}
+	if err := s.client.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments.Items {
+		remaining = append(remaining, "Deployment/"+deployment.Name)
+	}
+	pods := &corev1.PodList{}
+	if err := s.client.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		remaining = append(remaining, "Pod/"+pod.Name)
+	}
+	services := &corev1.ServiceList{}
+	if err := s.client.List(ctx, services, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, service := range services.Items {
+		remaining = append(remaining, "Service/"+service.Name)
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := s.client.List(ctx, configMaps, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, configMap := range configMaps.Items {
+		// created by Kubernetes in every namespace
+		if configMap.Name != "kube-root-ca.crt" {
+			remaining = append(remaining, "ConfigMap/"+configMap.Name)
+		}
+	}
+	return remaining, nil
+}
+
+// criticalDeploymentsOf returns the critical deployments of the participant, those of the selected components for
+// partial stacks, and those of the optional components it includes. Of these, only the deployments it was provisioned
+// with count, or all of those if it was provisioned with none of them, e.g. by a chart naming them differently.
+func criticalDeploymentsOf(namespace *corev1.Namespace) []string {
+	deployments := CriticalDeployments
+	if selected, ok := namespace.Annotations[ComponentsAnnotation]; ok {
+		components := strings.Split(selected, ",")
+		deployments = nil
+		for _, name := range CriticalDeployments {
+			if slices.Contains(components, name) || !slices.Contains(SelectableComponents, name) {
+				deployments = append(deployments, name)
+			}
+		}
+		for _, name := range OptionalComponents {
+			if slices.Contains(components, name) {
+				deployments = append(deployments, name)
+			}
+		}
+	}
+	applied, ok := namespace.Annotations[DeploymentsAnnotation]
+	if !ok || applied == "" {
+		return deployments
+	}
+	provisioned := strings.Split(applied, ",")
+	if critical := slices.DeleteFunc(slices.Clone(deployments), func(name string) bool {
+		return !slices.Contains(provisioned, name)
+	}); len(critical) > 0 {
+		return critical
+	}
+	return provisioned
+}
+
+// getComponentStatuses reports the status of the participant's critical deployments, followed by its Services and
+// Ingresses. Deployments are selected by the participant label; participants provisioned before the label existed fall
+// back to all deployments in the namespace.
+func (s *StatusChecker) getComponentStatuses(ctx context.Context, namespace string, critical []string) ([]ComponentStatus, error) {
+	deployments := &appsv1.DeploymentList{}
+	if err := s.client.List(ctx, deployments, client.InNamespace(namespace), client.MatchingLabels{ParticipantLabel: namespace}); err != nil {
+		return nil, err
+	}
+	if len(deployments.Items) == 0 {
+		if err := s.client.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+	}
+	byName := make(map[string]*appsv1.Deployment, len(deployments.Items))
+	for i := range deployments.Items {
+		byName[deployments.Items[i].Name] = &deployments.Items[i]
+	}
+
+	pods := &corev1.PodList{}
+	if err := s.client.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		fmt.Printf("Listing pods of %s failed: %v\n", namespace, err)
+	}
+
+	now := time.Now()
+	components := make([]ComponentStatus, 0, len(critical))
+	for _, name := range critical {
+		deployment, ok := byName[name]
+		if !ok {
+			components = append(components, ComponentStatus{
+				Name:    name,
+				Status:  ComponentMissing,
+				Message: "deployment not found",
+			})
+			continue
+		}
+		component := componentStatusOf(deployment, now)
+		owned := podsOf(deployment, pods.Items)
+		component.Pods = make([]PodStatus, 0, len(owned))
+		for _, pod := range owned {
+			component.Pods = append(component.Pods, podStatusOf(pod))
+		}
+		if component.Ready && s.health != nil {
+			s.health.apply(ctx, deployment, owned, &component)
+		}
+		if !component.Ready {
+			applySidecarReadiness(owned, &component)
+		}
+		if !applyPodFailure(&component) && !component.Ready {
+			if reason := waitingReason(component.Pods); reason != "" {
+				component.Message += " (" + reason + ")"
+			}
+		}
+		components = append(components, component)
+	}
+	// without any deployment, the namespace holds no participant whose reachability matters
+	if slices.ContainsFunc(components, func(component ComponentStatus) bool { return component.Status != ComponentMissing }) {
+		components = append(components, s.getNetworkStatuses(ctx, namespace, now)...)
+	}
+	return components, nil
+}
+
+// podsOf returns the pods selected by the deployment, except those being deleted.
+func podsOf(deployment *appsv1.Deployment, pods []corev1.Pod) []corev1.Pod {
+	if deployment.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	var owned []corev1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && selector.Matches(labels.Set(pod.Labels)) {
+			owned = append(owned, pod)
+		}
+	}
+	return owned
+}
+
+// podStatusOf summarizes a pod from the state of its main container, the first one.
+func podStatusOf(pod corev1.Pod) PodStatus {
+	status := PodStatus{Name: pod.Name, Phase: string(pod.Status.Phase), Ready: true}
+	for _, container := range pod.Status.ContainerStatuses {
+		status.Restarts += container.RestartCount
+		if !container.Ready {
+			status.Ready = false
+		}
+		if waiting := container.State.Waiting; waiting != nil && status.Reason == "" {
+			status.Reason = waiting.Reason
+		}
+		if terminated := container.LastTerminationState.Terminated; terminated != nil && status.LastTerminationReason == "" {
+			status.LastTerminationReason = terminated.Reason
+		}
+	}
+	if len(pod.Status.ContainerStatuses) == 0 {
+		status.Ready = false
+	}
+	if len(pod.Spec.Containers) > 0 {
+		status.Image = pod.Spec.Containers[0].Image
+	}
+	return status
+}
+
+// waitingReason returns the first reason a pod's container is waiting for, e.g. ImagePullBackOff.
+func waitingReason(pods []PodStatus) string {
+	for _, pod := range pods {
+		if pod.Reason != "" {
+			return pod.Reason
+		}
+	}
+	return ""
+}
+
+// Reasons of waiting containers that persist until the deployment or the cluster is changed. Crash loops are only
+// reported once a pod restarted crashLoopRestarts times, components often crash while their database starts.
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+const crashLoopRestarts = 3
+
+// podFailure returns why a pod of the component can't run, e.g. CrashLoopBackOff after OOMKilled, and the pod.
+func podFailure(pods []PodStatus) (string, string) {
+	for _, pod := range pods {
+		if pod.Ready {
+			continue
+		}
+		crashing := pod.Restarts >= crashLoopRestarts
+		reason := pod.Reason
+		switch {
+		case reason == "CrashLoopBackOff" && !crashing:
+			continue
+		case failedWaitingReasons[reason] && pod.LastTerminationReason == "OOMKilled":
+			reason += " after OOMKilled"
+		case failedWaitingReasons[reason]:
+		case pod.LastTerminationReason == "OOMKilled" && crashing:
+			reason = "OOMKilled"
+		default:
+			continue
+		}
+		return reason, pod.Name
+	}
+	return "", ""
+}
+
+// applyPodFailure reports a component with a pod that crash loops, can't pull its image or runs out of memory as
+// failed, or as degraded while enough replicas are ready, e.g. when a rollout is stuck. It reports whether it did.
+func applyPodFailure(component *ComponentStatus) bool {
+	reason, pod := podFailure(component.Pods)
+	if reason == "" {
+		return false
+	}
+	component.Reason = reason
+	if component.Ready {
+		component.Status = ComponentDegraded
+		component.Message = fmt.Sprintf("Degraded: pod %s is in %s", pod, reason)
+	} else {
+		component.Status = ComponentFailed
+		component.Message = fmt.Sprintf("Failed: %d of %d replicas ready, pod %s is in %s", component.ReadyReplicas, component.DesiredReplicas, pod, reason)
+	}
+	return true
+}
+
+// applySidecarReadiness marks a component as running when all its application containers are ready and only
+// injected mesh sidecars are holding back pod readiness.
+func applySidecarReadiness(pods []corev1.Pod, component *ComponentStatus) {
+	var ready int32
+	for _, pod := range pods {
+		if applicationReady(pod) {
+			ready++
+		}
+	}
+	if ready >= component.DesiredReplicas {
+		component.Ready = true
+		component.ReadyReplicas = ready
+		component.Status = ComponentRunning
+		component.Message = "application containers ready, mesh sidecar not ready"
+	}
+}
+
+// applicationReady reports whether all containers of a meshed pod except the sidecars are ready.
+func applicationReady(pod corev1.Pod) bool {
+	meshed := false
+	for _, container := range pod.Status.ContainerStatuses {
+		if sidecarContainers[container.Name] {
+			meshed = true
+			continue
+		}
+		if !container.Ready {
+			return false
+		}
+	}
+	return meshed
+}
+
+func componentStatusOf(deployment *appsv1.Deployment, now time.Time) ComponentStatus {
+	desired := DesiredReplicas(deployment)
+	ready := deployment.Status.ReadyReplicas
+	component := ComponentStatus{
+		Name:            deployment.Name,
+		ReadyReplicas:   ready,
+		DesiredReplicas: desired,
+	}
+	if image := mainImage(deployment); image != "" {
+		component.Image = image
+		component.Version = ImageTag(image)
+	}
+
+	switch {
+	case ready >= RequiredReplicas(deployment):
+		component.Ready = true
+		component.Status = ComponentRunning
+	case progressDeadlineExceeded(deployment):
+		component.Status = ComponentFailed
+		component.Message = fmt.Sprintf("Failed: progress deadline exceeded with %d of %d replicas ready", ready, desired)
+	case now.Sub(deployment.CreationTimestamp.Time) > provisioningGracePeriod:
+		component.Status = ComponentDegraded
+		component.Message = fmt.Sprintf("Degraded: %d of %d replicas ready", ready, desired)
+	default:
+		component.Status = ComponentStarting
+		component.Message = fmt.Sprintf("Starting: %d of %d replicas ready", ready, desired)
+	}
+	return component
+}
+
+// DesiredReplicas returns the replicas of the deployment, as set by the provisioner or its autoscaler.
+func DesiredReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas != nil {
+		return *deployment.Spec.Replicas
+	}
+	return 1
+}
+
+// RequiredReplicas returns the ready replicas the deployment needs to be ready: the desired ones, or the minimum of
+// its autoscaler while it is adding replicas.
+func RequiredReplicas(deployment *appsv1.Deployment) int32 {
+	desired := DesiredReplicas(deployment)
+	if minimum, err := strconv.Atoi(deployment.Annotations[MinReplicasAnnotation]); err == nil && int32(minimum) < desired {
+		return int32(minimum)
+	}
+	return desired
+}
+
+// mainImage returns the image of the container named like the deployment, or of the first container.
+func mainImage(deployment *appsv1.Deployment) string {
+	containers := deployment.Spec.Template.Spec.Containers
+	for _, container := range containers {
+		if container.Name == deployment.Name {
+			return container.Image
+		}
+	}
+	if len(containers) > 0 {
+		return containers[0].Image
+	}
+	return ""
+}
+
+// ImageTag returns the tag of an image reference, "latest" if it has none.
+func ImageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return "latest"
+}
+
+func progressDeadlineExceeded(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRecentEvents returns the latest Kubernetes and connector events of the namespace, capped to the last 30 minutes
+// and 10 entries.
+func (s *StatusChecker) GetRecentEvents(ctx context.Context, namespace string) ([]Event, error) {
+	events, err := s.GetEvents(ctx, namespace, EventFilter{Since: time.Now().Add(-recentEventsWindow)})
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > recentEventsLimit {
+		events = events[:recentEventsLimit]
+	}
+	return events, nil
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// endpointsFor returns the in-cluster URLs of the APIs of the participant's critical deployments.
+func endpointsFor(namespace string, critical []string) map[string]string {
+	endpoints := map[string]string{}
+	if slices.Contains(critical, "controlplane") {
+		endpoints["management"] = fmt.Sprintf("http://%s:8081/api/management", ServiceHost("controlplane", namespace))
+		endpoints["protocol"] = fmt.Sprintf("http://%s:8082/api/dsp", ServiceHost("controlplane", namespace))
+	}
+	if slices.Contains(critical, "identityhub") {
+		endpoints["identity"] = fmt.Sprintf("http://%s:7081/api/identity", ServiceHost("identityhub", namespace))
+		endpoints["credentials"] = fmt.Sprintf("http://%s:7082/api/credentials", ServiceHost("identityhub", namespace))
+	}
+	if slices.Contains(critical, "federatedcatalog") {
+		endpoints["federatedCatalog"] = fmt.Sprintf("http://%s:8084/api/catalog", ServiceHost("federatedcatalog", namespace))
+	}
+	return endpoints
+}
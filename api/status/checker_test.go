@@ -0,0 +1,294 @@
+package status
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deploymentClient serves List for deployments and honours label selectors.
+type deploymentClient struct {
+	client.Client
+	deployments []appsv1.Deployment
+}
+
+func (c *deploymentClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	result, ok := list.(*appsv1.DeploymentList)
+	if !ok {
+		// no pods
+		return nil
+	}
+	result.Items = nil
+	for _, deployment := range c.deployments {
+		if options.LabelSelector == nil || options.LabelSelector.Matches(labels.Set(deployment.Labels)) {
+			result.Items = append(result.Items, deployment)
+		}
+	}
+	return nil
+}
+
+func TestSetSeedingStep(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.SetSeeding("alice", SeedingRunning, "")
+	checker.SetSeedingStep("alice", SeedingStep{Name: "assets", State: SeedingRunning, Attempts: 1})
+	checker.SetSeedingStep("alice", SeedingStep{Name: "policies", State: SeedingPending})
+	checker.SetSeedingStep("alice", SeedingStep{Name: "assets", State: SeedingCompleted, Attempts: 2})
+	checker.SetSeeding("alice", SeedingCompleted, "")
+
+	seeding := checker.GetSeeding("alice")
+	if seeding.State != SeedingCompleted {
+		t.Errorf("expected seeding to be completed, got %s", seeding.State)
+	}
+	if len(seeding.Steps) != 2 || seeding.Steps[0].Name != "assets" || seeding.Steps[1].Name != "policies" {
+		t.Fatalf("expected the steps in the order they were reported, got %+v", seeding.Steps)
+	}
+	if seeding.Steps[0].State != SeedingCompleted || seeding.Steps[0].Attempts != 2 {
+		t.Errorf("step progress not replaced: %+v", seeding.Steps[0])
+	}
+}
+
+func TestSetCredential(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.SetCredential("alice", CredentialStatus{Type: "MembershipCredential", State: CredentialRequested})
+	checker.SetSeeding("alice", SeedingRunning, "")
+	checker.SetCredential("alice", CredentialStatus{Type: "MembershipCredential", State: CredentialIssued})
+
+	credentials := checker.GetSeeding("alice").Credentials
+	if len(credentials) != 1 || credentials[0].State != CredentialIssued {
+		t.Errorf("expected the issued credential, got %+v", credentials)
+	}
+}
+
+func TestSetSeededResources(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.SetSeededResources("alice", SeededResources{Assets: []string{"asset-1"}, StsClientId: "alice"})
+	checker.SetSeeding("alice", SeedingCompleted, "")
+
+	resources := checker.GetSeeding("alice").Resources
+	if resources == nil || resources.Assets[0] != "asset-1" || resources.StsClientId != "alice" {
+		t.Errorf("expected the seeded resources to be kept, got %+v", resources)
+	}
+}
+
+func TestSeedingStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   ProvisioningStatus
+		seeding  *SeedingStatus
+		expected ProvisioningStatus
+	}{
+		{"ready without seeding run", StatusReady, nil, StatusReady},
+		{"seeding", StatusReady, &SeedingStatus{State: SeedingRunning}, StatusSeeding},
+		{"seeding failed", StatusReady, &SeedingStatus{State: SeedingFailed, Message: "seed assets: 503"}, StatusSeedFailed},
+		{"seeded", StatusReady, &SeedingStatus{State: SeedingCompleted}, StatusReady},
+		{"seeded and verified", StatusReady, &SeedingStatus{State: SeedingCompleted, Verification: &CatalogVerification{Verified: true}}, StatusReady},
+		{"seeded entities missing", StatusReady, &SeedingStatus{State: SeedingCompleted, Verification: &CatalogVerification{Missing: []string{"asset asset-1"}}}, StatusDegraded},
+		{"components failing while seeding", StatusDegraded, &SeedingStatus{State: SeedingRunning}, StatusDegraded},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if status, _ := seedingStatus(c.status, "", c.seeding); status != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, status)
+			}
+		})
+	}
+}
+
+func TestComponentStatusesSelectParticipantDeployments(t *testing.T) {
+	labelled := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "alice", Labels: map[string]string{ParticipantLabel: "alice"}}}
+	foreign := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "dataplane", Namespace: "alice"}}
+	c := &deploymentClient{deployments: []appsv1.Deployment{labelled, foreign}}
+	checker := NewStatusChecker(context.Background(), c, DefaultCacheTTL)
+
+	components, err := checker.getComponentStatuses(context.Background(), "alice", CriticalDeployments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]ComponentStatus{}
+	for _, component := range components {
+		byName[component.Name] = component
+	}
+	if byName["controlplane"].Status == ComponentMissing {
+		t.Error("expected the labelled deployment to be found")
+	}
+	if byName["dataplane"].Status != ComponentMissing {
+		t.Error("expected the unlabelled deployment to be ignored")
+	}
+
+	legacy := &deploymentClient{deployments: []appsv1.Deployment{foreign}}
+	components, err = NewStatusChecker(context.Background(), legacy, DefaultCacheTTL).getComponentStatuses(context.Background(), "alice", CriticalDeployments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components[1].Name != "dataplane" || components[1].Status == ComponentMissing {
+		t.Errorf("expected unlabelled deployments to be used for legacy participants, got %+v", components)
+	}
+}
+
+func TestCriticalDeploymentsOfPartialStacks(t *testing.T) {
+	full := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+	if got := criticalDeploymentsOf(full); !slices.Equal(got, CriticalDeployments) {
+		t.Errorf("expected all critical deployments, got %v", got)
+	}
+	partial := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Annotations: map[string]string{ComponentsAnnotation: "identityhub"}}}
+	critical := criticalDeploymentsOf(partial)
+	if !slices.Equal(critical, []string{"identityhub", "postgres"}) {
+		t.Errorf("expected the identityhub and the shared postgres, got %v", critical)
+	}
+	if endpoints := endpointsFor("alice", critical); endpoints["management"] != "" || endpoints["credentials"] == "" {
+		t.Errorf("expected only the identityhub's endpoints, got %v", endpoints)
+	}
+	optional := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Annotations: map[string]string{ComponentsAnnotation: "controlplane,dataplane,identityhub,federatedcatalog"}}}
+	critical = criticalDeploymentsOf(optional)
+	if !slices.Equal(critical, append(slices.Clone(CriticalDeployments), "federatedcatalog")) {
+		t.Errorf("expected the full stack and the federated catalog, got %v", critical)
+	}
+	if endpoints := endpointsFor("alice", critical); endpoints["federatedCatalog"] == "" {
+		t.Errorf("expected the federated catalog's endpoint, got %v", endpoints)
+	}
+}
+
+func TestCriticalDeploymentsOfProvisionedStacks(t *testing.T) {
+	external := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Annotations: map[string]string{DeploymentsAnnotation: "controlplane,dataplane,identityhub,vault"}}}
+	if critical := criticalDeploymentsOf(external); !slices.Equal(critical, []string{"controlplane", "dataplane", "identityhub"}) {
+		t.Errorf("expected postgres not to be required of a stack without it, got %v", critical)
+	}
+	renamed := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Annotations: map[string]string{DeploymentsAnnotation: "edc-connector,edc-ih"}}}
+	if critical := criticalDeploymentsOf(renamed); !slices.Equal(critical, []string{"edc-connector", "edc-ih"}) {
+		t.Errorf("expected the provisioned deployments of a stack without the configured ones, got %v", critical)
+	}
+
+	configured := CriticalDeployments
+	CriticalDeployments = []string{"controlplane", "identityhub"}
+	defer func() { CriticalDeployments = configured }()
+	if critical := criticalDeploymentsOf(external); !slices.Equal(critical, []string{"controlplane", "identityhub"}) {
+		t.Errorf("expected the configured critical deployments, got %v", critical)
+	}
+}
+
+func TestMetadataOf(t *testing.T) {
+	if metadata := metadataOf(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: map[string]string{ManagedByLabel: ManagedByValue, "kubernetes.io/metadata.name": "alice"}}}); metadata != nil {
+		t.Errorf("expected no metadata, got %+v", metadata)
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "alice",
+		Labels:      map[string]string{ManagedByLabel: ManagedByValue, ParticipantLabel: "alice", TeamLabel: "alpha", "cost-center": "42"},
+		Annotations: map[string]string{OwnerAnnotation: "alice@example.com"},
+	}}
+	metadata := metadataOf(namespace)
+	if metadata == nil || metadata.Team != "alpha" || metadata.Owner != "alice@example.com" || len(metadata.Labels) != 1 || metadata.Labels["cost-center"] != "42" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+}
+
+func TestPodStatusOf(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "controlplane-7d9f"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "controlplane", Image: "ghcr.io/metaform/controlplane:0.14.0"}}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "controlplane",
+				RestartCount:         4,
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+			}},
+		},
+	}
+	want := PodStatus{Name: "controlplane-7d9f", Phase: "Running", Restarts: 4, Reason: "CrashLoopBackOff", LastTerminationReason: "OOMKilled", Image: "ghcr.io/metaform/controlplane:0.14.0"}
+	if got := podStatusOf(pod); got != want {
+		t.Errorf("podStatusOf() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPodsOf(t *testing.T) {
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "controlplane"}}}}
+	deleted := metav1.Now()
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-a", Labels: map[string]string{"app": "controlplane"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-b", Labels: map[string]string{"app": "controlplane"}, DeletionTimestamp: &deleted}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "dataplane-a", Labels: map[string]string{"app": "dataplane"}}},
+	}
+	owned := podsOf(deployment, pods)
+	if len(owned) != 1 || owned[0].Name != "controlplane-a" {
+		t.Errorf("expected only the live pod of the deployment, got %v", owned)
+	}
+}
+
+func TestGetStatusCachesAllFields(t *testing.T) {
+	c := &countingClient{}
+	checker := NewStatusChecker(context.Background(), c, DefaultCacheTTL)
+	for i := 0; i < 2; i++ {
+		if _, err := checker.GetStatus(context.Background(), "alice", AllFields); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.gets != 1 {
+		t.Errorf("expected the second request to be served from the cache, got %d evaluations", c.gets)
+	}
+}
+
+func TestGetStatusWithoutCache(t *testing.T) {
+	c := &countingClient{}
+	checker := NewStatusChecker(context.Background(), c, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := checker.GetStatus(context.Background(), "alice", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.gets != 2 {
+		t.Errorf("expected every request to be evaluated with caching disabled, got %d evaluations", c.gets)
+	}
+}
+
+// countingClient counts the evaluations of a participant whose namespace doesn't exist.
+type countingClient struct {
+	client.Client
+	gets int
+}
+
+func (c *countingClient) Get(_ context.Context, key client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	c.gets++
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, key.Name)
+}
+
+func TestApplyPodFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		ready   bool
+		pod     PodStatus
+		status  string
+		reason  string
+		applied bool
+	}{
+		{"crash loop", false, PodStatus{Name: "cp-1", Restarts: 5, Reason: "CrashLoopBackOff"}, ComponentFailed, "CrashLoopBackOff", true},
+		{"crash loop while starting", false, PodStatus{Name: "cp-1", Restarts: 1, Reason: "CrashLoopBackOff"}, ComponentStarting, "", false},
+		{"out of memory", false, PodStatus{Name: "cp-1", Restarts: 4, Reason: "CrashLoopBackOff", LastTerminationReason: "OOMKilled"}, ComponentFailed, "CrashLoopBackOff after OOMKilled", true},
+		{"image pull", false, PodStatus{Name: "cp-1", Reason: "ImagePullBackOff"}, ComponentFailed, "ImagePullBackOff", true},
+		{"stuck rollout", true, PodStatus{Name: "cp-2", Reason: "ErrImagePull"}, ComponentDegraded, "ErrImagePull", true},
+		{"creating", false, PodStatus{Name: "cp-1", Reason: "ContainerCreating"}, ComponentStarting, "", false},
+	}
+	for _, tt := range tests {
+		component := ComponentStatus{Name: "controlplane", Ready: tt.ready, Status: ComponentStarting, DesiredReplicas: 1, Pods: []PodStatus{tt.pod}}
+		if tt.ready {
+			component.Status, component.ReadyReplicas = ComponentRunning, 1
+			component.Pods = append(component.Pods, PodStatus{Name: "cp-1", Ready: true})
+		}
+		if applied := applyPodFailure(&component); applied != tt.applied {
+			t.Errorf("%s: applied = %v, want %v", tt.name, applied, tt.applied)
+		}
+		if tt.applied && (component.Status != tt.status || component.Reason != tt.reason) {
+			t.Errorf("%s: got %s (%s), want %s (%s)", tt.name, component.Status, component.Reason, tt.status, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package status
+
+import (
+	"time"
+)
+
+// Number of connector events retained per participant
+const connectorEventsLimit = 100
+
+// RecordConnectorEvent stores an event reported by the participant's connector, e.g. through an EDC callback.
+func (s *StatusChecker) RecordConnectorEvent(name string, event Event) {
+	s.mu.Lock()
+	events := append(s.connectorEvents[name], event)
+	if len(events) > connectorEventsLimit {
+		events = events[len(events)-connectorEventsLimit:]
+	}
+	s.connectorEvents[name] = events
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+func (s *StatusChecker) connectorEventsSince(name string, cutoff time.Time) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var events []Event
+	for _, event := range s.connectorEvents[name] {
+		if !event.Timestamp.Before(cutoff) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,38 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HeldCredential summarizes a verifiable credential held by the participant's identity hub.
+type HeldCredential struct {
+	Id     string   `json:"id"`
+	Types  []string `json:"types,omitempty"`
+	Issuer string   `json:"issuer,omitempty"`
+	// State is the identity hub's state of the credential, e.g. ISSUED or REVOKED
+	State     string     `json:"state"`
+	IssuedAt  *time.Time `json:"issuedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CredentialLookup returns the credentials held by a participant.
+type CredentialLookup func(ctx context.Context, name string) ([]HeldCredential, error)
+
+// EnableCredentials makes status evaluations report the credentials held by the participant, requested from its
+// identity hub with the lookup.
+func (s *StatusChecker) EnableCredentials(lookup CredentialLookup) {
+	s.credentials = lookup
+}
+
+// heldCredentials looks up the participant's credentials. The identity hub may be down while the participant is
+// degraded, which is reported by the components already, so failures only leave the credentials out.
+func (s *StatusChecker) heldCredentials(ctx context.Context, name string) []HeldCredential {
+	credentials, err := s.credentials(ctx, name)
+	if err != nil {
+		fmt.Printf("Looking up credentials of %s failed: %v\n", name, err)
+		return nil
+	}
+	return credentials
+}
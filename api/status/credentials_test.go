@@ -0,0 +1,31 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHeldCredentials(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.EnableCredentials(func(_ context.Context, name string) ([]HeldCredential, error) {
+		if name == "bob" {
+			return nil, errors.New("identity hub unavailable")
+		}
+		return []HeldCredential{{Id: "membership", Types: []string{"MembershipCredential"}, State: "ISSUED"}}, nil
+	})
+	if credentials := checker.heldCredentials(context.Background(), "alice"); len(credentials) != 1 || credentials[0].Id != "membership" {
+		t.Errorf("unexpected credentials %+v", credentials)
+	}
+	if credentials := checker.heldCredentials(context.Background(), "bob"); credentials != nil {
+		t.Errorf("expected failed lookups to report no credentials, got %+v", credentials)
+	}
+
+	participantStatus := ParticipantStatus{Name: "alice", Credentials: checker.heldCredentials(context.Background(), "alice")}
+	if projected := participantStatus.Project([]Field{FieldComponents}); projected.Credentials != nil {
+		t.Errorf("expected credentials only when requested, got %+v", projected.Credentials)
+	}
+	if projected := participantStatus.Project(AllFields); len(projected.Credentials) != 1 {
+		t.Errorf("expected credentials among all fields, got %+v", projected.Credentials)
+	}
+}
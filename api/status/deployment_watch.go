@@ -0,0 +1,124 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deploymentWaiters are the subscribers to deployment changes, by namespace.
+type deploymentWaiters struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+// DeploymentChanges subscribes to changes of the deployments in the namespace, e.g. to wait for them to become ready
+// without polling. The channel receives a value after one or more changes; call the returned function to unsubscribe.
+func (s *StatusChecker) DeploymentChanges(namespace string) (<-chan struct{}, func()) {
+	changes := make(chan struct{}, 1)
+	s.waiters.mu.Lock()
+	defer s.waiters.mu.Unlock()
+	if s.waiters.subscribers == nil {
+		s.waiters.subscribers = make(map[string]map[chan struct{}]struct{})
+	}
+	if s.waiters.subscribers[namespace] == nil {
+		s.waiters.subscribers[namespace] = make(map[chan struct{}]struct{})
+	}
+	s.waiters.subscribers[namespace][changes] = struct{}{}
+	return changes, func() {
+		s.waiters.mu.Lock()
+		defer s.waiters.mu.Unlock()
+		delete(s.waiters.subscribers[namespace], changes)
+		if len(s.waiters.subscribers[namespace]) == 0 {
+			delete(s.waiters.subscribers, namespace)
+		}
+	}
+}
+
+// WatchDeployments drops the cached status of a participant as soon as one of its deployments changes and notifies
+// the subscribers of the namespace, so neither status requests nor readiness checks have to list the deployments
+// periodically. Only deployments carrying the managed-by label are watched. It blocks until the context is
+// cancelled.
+func (s *StatusChecker) WatchDeployments(ctx context.Context, c client.WithWatch) {
+	s.loops.Add(1)
+	defer s.loops.Done()
+	resourceVersion := ""
+	for {
+		if err := s.watchDeployments(ctx, c, &resourceVersion); err != nil {
+			fmt.Println("Deployment watch failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventWatchRetryInterval):
+		}
+	}
+}
+
+func (s *StatusChecker) watchDeployments(ctx context.Context, c client.WithWatch, resourceVersion *string) error {
+	managed := client.MatchingLabels{ManagedByLabel: ManagedByValue}
+	if *resourceVersion == "" {
+		list := &appsv1.DeploymentList{}
+		if err := c.List(ctx, list, managed, client.Limit(1)); err != nil {
+			return err
+		}
+		*resourceVersion = list.ResourceVersion
+	}
+
+	watcher, err := c.Watch(ctx, &appsv1.DeploymentList{}, managed, &client.ListOptions{
+		Raw: &metav1.ListOptions{ResourceVersion: *resourceVersion, AllowWatchBookmarks: true},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch result.Type {
+			case watch.Error:
+				err := apierrors.FromObject(result.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					*resourceVersion = ""
+				}
+				return err
+			case watch.Bookmark:
+				if deployment, ok := result.Object.(*appsv1.Deployment); ok {
+					*resourceVersion = deployment.ResourceVersion
+				}
+			case watch.Added, watch.Modified, watch.Deleted:
+				if deployment, ok := result.Object.(*appsv1.Deployment); ok {
+					*resourceVersion = deployment.ResourceVersion
+					s.onDeploymentChange(deployment.Namespace)
+				}
+			}
+		}
+	}
+}
+
+// onDeploymentChange invalidates the cached status of the namespace and wakes up its subscribers.
+func (s *StatusChecker) onDeploymentChange(namespace string) {
+	s.cache.invalidate(namespace)
+	s.waiters.mu.Lock()
+	defer s.waiters.mu.Unlock()
+	for changes := range s.waiters.subscribers[namespace] {
+		select {
+		case changes <- struct{}{}:
+		default:
+			// a change is pending already
+		}
+	}
+}
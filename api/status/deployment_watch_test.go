@@ -0,0 +1,32 @@
+package status
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeploymentChanges(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	alice, unsubscribe := checker.DeploymentChanges("alice")
+	bob, _ := checker.DeploymentChanges("bob")
+
+	checker.onDeploymentChange("alice")
+	checker.onDeploymentChange("alice")
+	select {
+	case <-alice:
+	default:
+		t.Fatal("expected the subscriber of the namespace to be notified")
+	}
+	select {
+	case <-alice:
+		t.Error("expected changes to be coalesced")
+	case <-bob:
+		t.Error("expected subscribers of other namespaces not to be notified")
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := checker.waiters.subscribers["alice"]; ok {
+		t.Error("expected the namespace to have no subscribers left")
+	}
+}
@@ -0,0 +1,63 @@
+package status
+
+import "time"
+
+// States of a DID rotation reported in DidRotationStatus.State
+const (
+	DidRotationRunning   = "RUNNING"
+	DidRotationCompleted = "COMPLETED"
+	DidRotationFailed    = "FAILED"
+)
+
+// Phases of a DID rotation, in order
+const (
+	// DidRotationManifests re-renders the manifests with the new DID and waits for the restarted deployments
+	DidRotationManifests = "manifests"
+	// DidRotationParticipantContext replaces the participant context in the identity hub, which issues a new STS
+	// client secret stored for the connector
+	DidRotationParticipantContext = "participantContext"
+	// DidRotationCredentials requests the credentials for the new DID and checks they were issued
+	DidRotationCredentials = "credentials"
+)
+
+// DidRotationStatus reports the latest DID rotation of a participant, until it is rotated again or deleted.
+type DidRotationStatus struct {
+	PreviousDid string `json:"previousDid"`
+	Did         string `json:"did"`
+	State       string `json:"state"`
+	// Phase is the phase running, or the one that failed
+	Phase     string    `json:"phase"`
+	Message   string    `json:"message,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BeginDidRotation reports a DID rotation of the participant in its first phase.
+func (s *StatusChecker) BeginDidRotation(name string, previousDid string, did string) {
+	now := time.Now()
+	s.mu.Lock()
+	s.didRotations[name] = DidRotationStatus{PreviousDid: previousDid, Did: did, State: DidRotationRunning, Phase: DidRotationManifests,
+		StartedAt: now, UpdatedAt: now}
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetDidRotation reports the phase and state of the participant's DID rotation. Unknown rotations are ignored.
+func (s *StatusChecker) SetDidRotation(name string, phase string, state string, message string) {
+	s.mu.Lock()
+	rotation, ok := s.didRotations[name]
+	if ok {
+		rotation.Phase, rotation.State, rotation.Message, rotation.UpdatedAt = phase, state, message, time.Now()
+		s.didRotations[name] = rotation
+	}
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// applyDidRotation adds the participant's latest DID rotation to an evaluated status. Callers hold the lock.
+func (s *StatusChecker) applyDidRotation(participantStatus ParticipantStatus) ParticipantStatus {
+	if rotation, ok := s.didRotations[participantStatus.Name]; ok {
+		participantStatus.DidRotation = &rotation
+	}
+	return participantStatus
+}
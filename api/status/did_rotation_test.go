@@ -0,0 +1,30 @@
+package status
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDidRotation(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.SetDidRotation("p", DidRotationCredentials, DidRotationCompleted, "")
+	if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusReady}, nil); got.DidRotation != nil {
+		t.Fatalf("expected no rotation to be reported before one began, got %+v", got.DidRotation)
+	}
+
+	checker.BeginDidRotation("p", "did:web:old", "did:web:new")
+	got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusReady}, nil).DidRotation
+	if got == nil || got.State != DidRotationRunning || got.Phase != DidRotationManifests || got.PreviousDid != "did:web:old" {
+		t.Fatalf("expected the running rotation, got %+v", got)
+	}
+	checker.SetDidRotation("p", DidRotationParticipantContext, DidRotationFailed, "identity hub unavailable")
+	got = checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusReady}, nil).DidRotation
+	if got.State != DidRotationFailed || got.Phase != DidRotationParticipantContext || got.Message != "identity hub unavailable" || got.Did != "did:web:new" {
+		t.Errorf("expected the failed phase, got %+v", got)
+	}
+
+	checker.MarkDeleted("p")
+	if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusNotFound}, nil); got.DidRotation != nil {
+		t.Errorf("expected the rotation to be forgotten with the participant, got %+v", got.DidRotation)
+	}
+}
@@ -0,0 +1,37 @@
+package status
+
+import "time"
+
+// ProvisioningDurations breaks down how long the latest provisioning of a participant took, from the request until it
+// was READY. Phases the provisioning didn't run are empty.
+type ProvisioningDurations struct {
+	Total string `json:"total"`
+	// Queued is how long the provisioning waited for a free slot before it started
+	Queued      string    `json:"queued,omitempty"`
+	Apply       string    `json:"apply,omitempty"`
+	Readiness   string    `json:"readiness,omitempty"`
+	Seeding     string    `json:"seeding,omitempty"`
+	Hooks       string    `json:"hooks,omitempty"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// RecordProvisioning reports the durations of a completed provisioning with the status of the participant and on the
+// READY entry of its history, until it is provisioned again or deleted.
+func (s *StatusChecker) RecordProvisioning(name string, durations ProvisioningDurations) {
+	s.mu.Lock()
+	s.provisioning[name] = durations
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+	if s.history != nil {
+		s.history.record(name, HistoryEntry{Status: StatusReady, Timestamp: durations.CompletedAt, Provisioning: &durations})
+	}
+}
+
+// applyProvisioning adds the durations of the participant's latest provisioning to an evaluated status. Callers hold
+// the lock.
+func (s *StatusChecker) applyProvisioning(participantStatus ParticipantStatus) ParticipantStatus {
+	if durations, ok := s.provisioning[participantStatus.Name]; ok {
+		participantStatus.Provisioning = &durations
+	}
+	return participantStatus
+}
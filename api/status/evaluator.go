@@ -0,0 +1,45 @@
+package status
+
+import (
+	"strings"
+)
+
+// StatusEvaluator derives the overall participant status from the status of its components.
+type StatusEvaluator struct{}
+
+func (StatusEvaluator) Evaluate(components []ComponentStatus) (ProvisioningStatus, string) {
+	var missing, failed, degraded, starting []string
+	for _, component := range components {
+		switch component.Status {
+		case ComponentMissing:
+			missing = append(missing, component.Name)
+		case ComponentFailed:
+			failed = append(failed, withReason(component))
+		case ComponentDegraded:
+			degraded = append(degraded, withReason(component))
+		case ComponentStarting:
+			starting = append(starting, component.Name)
+		}
+	}
+
+	switch {
+	case len(missing) == len(components):
+		return StatusNotFound, "no participant components found"
+	case len(failed) > 0:
+		return StatusFailed, "failed components: " + strings.Join(failed, ", ")
+	case len(missing) > 0 || len(degraded) > 0:
+		return StatusDegraded, "degraded components: " + strings.Join(append(missing, degraded...), ", ")
+	case len(starting) > 0:
+		return StatusProvisioning, "waiting for components: " + strings.Join(starting, ", ")
+	default:
+		return StatusReady, ""
+	}
+}
+
+// withReason names the component with why its pods can't run, if known.
+func withReason(component ComponentStatus) string {
+	if component.Reason == "" {
+		return component.Name
+	}
+	return component.Name + " (" + component.Reason + ")"
+}
@@ -0,0 +1,59 @@
+package status
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	component := func(name string, state string) ComponentStatus {
+		return ComponentStatus{Name: name, Status: state}
+	}
+	tests := []struct {
+		name       string
+		components []ComponentStatus
+		want       ProvisioningStatus
+	}{
+		{"all running", []ComponentStatus{component("controlplane", ComponentRunning), component("dataplane", ComponentRunning)}, StatusReady},
+		{"all missing", []ComponentStatus{component("controlplane", ComponentMissing), component("dataplane", ComponentMissing)}, StatusNotFound},
+		{"failed wins", []ComponentStatus{component("controlplane", ComponentFailed), component("dataplane", ComponentMissing)}, StatusFailed},
+		{"partially missing", []ComponentStatus{component("controlplane", ComponentRunning), component("dataplane", ComponentMissing)}, StatusDegraded},
+		{"starting", []ComponentStatus{component("controlplane", ComponentRunning), component("dataplane", ComponentStarting)}, StatusProvisioning},
+	}
+	for _, tt := range tests {
+		if got, _ := (StatusEvaluator{}).Evaluate(tt.components); got != tt.want {
+			t.Errorf("%s: Evaluate() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	crashing := ComponentStatus{Name: "controlplane", Status: ComponentFailed, Reason: "CrashLoopBackOff"}
+	if _, message := (StatusEvaluator{}).Evaluate([]ComponentStatus{crashing}); message != "failed components: controlplane (CrashLoopBackOff)" {
+		t.Errorf("expected the reason in the message, got %q", message)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]ParticipantStatus{
+		{Name: "a", Status: StatusReady},
+		{Name: "b", Status: StatusTerminating},
+		{Name: "c", Status: StatusOrphaned},
+		{Name: "d", Status: StatusReady},
+	})
+	if summary.Total != 4 || summary.Ready != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if summary.ByStatus[StatusTerminating] != 1 || summary.ByStatus[StatusOrphaned] != 1 {
+		t.Errorf("terminating and orphaned participants not counted separately: %+v", summary.ByStatus)
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/metaform/controlplane:0.13.0":          "0.13.0",
+		"localhost:5000/controlplane":                   "latest",
+		"controlplane:0.12.1@sha256:0123456789abcdef":   "0.12.1",
+		"registry.example.com:443/team/identityhub:1.0": "1.0",
+	}
+	for image, want := range tests {
+		if got := ImageTag(image); got != want {
+			t.Errorf("ImageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package status
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventFilter selects the events of a participant. Empty fields match every event.
+type EventFilter struct {
+	// Type is Normal or Warning
+	Type   string
+	Reason string
+	// Object is the involved object as Kind/Name, or just its name
+	Object string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f EventFilter) matches(event Event) bool {
+	if f.Type != "" && event.Type != f.Type {
+		return false
+	}
+	if f.Reason != "" && event.Reason != f.Reason {
+		return false
+	}
+	if f.Object != "" && event.Object != f.Object && !hasName(event.Object, f.Object) {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && event.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// hasName reports whether the Kind/Name reference names the object.
+func hasName(object string, name string) bool {
+	return len(object) > len(name) && object[len(object)-len(name)-1:] == "/"+name
+}
+
+// GetEvents returns the Kubernetes events of the namespace and the events reported by the participant's connector
+// that match the filter, newest first. Kubernetes only retains events for a limited time, an hour by default.
+func (s *StatusChecker) GetEvents(ctx context.Context, namespace string, filter EventFilter) ([]Event, error) {
+	list := &corev1.EventList{}
+	if err := s.client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, item := range list.Items {
+		event := Event{
+			Type:      item.Type,
+			Reason:    item.Reason,
+			Object:    item.InvolvedObject.Kind + "/" + item.InvolvedObject.Name,
+			Message:   item.Message,
+			Timestamp: eventTime(item),
+		}
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	for _, event := range s.connectorEventsSince(namespace, filter.Since) {
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	return events, nil
+}
@@ -0,0 +1,30 @@
+package status
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventFilter(t *testing.T) {
+	now := time.Now()
+	event := Event{Type: "Warning", Reason: "BackOff", Object: "Pod/controlplane-7d9f", Timestamp: now}
+	tests := []struct {
+		filter EventFilter
+		want   bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{Type: "Warning", Reason: "BackOff"}, true},
+		{EventFilter{Type: "Normal"}, false},
+		{EventFilter{Object: "Pod/controlplane-7d9f"}, true},
+		{EventFilter{Object: "controlplane-7d9f"}, true},
+		{EventFilter{Object: "7d9f"}, false},
+		{EventFilter{Since: now.Add(-time.Hour), Until: now.Add(time.Minute)}, true},
+		{EventFilter{Since: now.Add(time.Minute)}, false},
+		{EventFilter{Until: now.Add(-time.Minute)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matches(event); got != tt.want {
+			t.Errorf("%+v matches = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package status
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Namespace annotations recording the lifetime of ephemeral participants: the lifetime requested in the definition
+// and when it lapses, as RFC 3339 timestamp
+const (
+	ExpiresAfterAnnotation = "aruba-provisioner/expires-after"
+	ExpiresAtAnnotation    = "aruba-provisioner/expires-at"
+)
+
+// ExpiryOf returns when the participant of the namespace expires, nil if it doesn't.
+func ExpiryOf(namespace *corev1.Namespace) *time.Time {
+	expiresAt, err := time.Parse(time.RFC3339, namespace.Annotations[ExpiresAtAnnotation])
+	if err != nil {
+		return nil
+	}
+	return &expiresAt
+}
+
+// withRemainingLifetime reports how long an ephemeral participant has left, at the time of the request rather than
+// of the cached evaluation.
+func withRemainingLifetime(participantStatus ParticipantStatus) ParticipantStatus {
+	if participantStatus.ExpiresAt == nil {
+		return participantStatus
+	}
+	remaining := max(time.Until(*participantStatus.ExpiresAt).Round(time.Second), 0)
+	participantStatus.ExpiresIn = remaining.String()
+	return participantStatus
+}
@@ -0,0 +1,27 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRemainingLifetime(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		ExpiresAtAnnotation: time.Now().Add(90 * time.Minute).UTC().Format(time.RFC3339),
+	}}}
+	participantStatus := withRemainingLifetime(ParticipantStatus{ExpiresAt: ExpiryOf(namespace)})
+	if remaining, err := time.ParseDuration(participantStatus.ExpiresIn); err != nil || remaining < 89*time.Minute || remaining > 90*time.Minute {
+		t.Errorf("unexpected remaining lifetime %q", participantStatus.ExpiresIn)
+	}
+
+	lapsed := time.Now().Add(-time.Hour)
+	if participantStatus := withRemainingLifetime(ParticipantStatus{ExpiresAt: &lapsed}); participantStatus.ExpiresIn != "0s" {
+		t.Errorf("expected no lifetime left, got %q", participantStatus.ExpiresIn)
+	}
+	if ExpiryOf(&corev1.Namespace{}) != nil {
+		t.Error("expected participants without expiry to live on")
+	}
+}
@@ -0,0 +1,89 @@
+package status
+
+import (
+	"strings"
+	"time"
+)
+
+// Failure is why the provisioning of a participant was given up, e.g. a component that didn't become ready in time.
+type Failure struct {
+	// Component is the component blocking the provisioning, empty if the components were ready
+	Component string `json:"component,omitempty"`
+	Reason    string `json:"reason"`
+	// Events are the warnings about the component's pods, newest first
+	Events   []Event   `json:"events,omitempty"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// MarkFailed reports the participant as FAILED with the failure until it becomes READY, is provisioned again or
+// deleted.
+func (s *StatusChecker) MarkFailed(name string, failure Failure) {
+	if failure.FailedAt.IsZero() {
+		failure.FailedAt = time.Now()
+	}
+	s.mu.Lock()
+	s.failures[name] = failure
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// applyFailure overlays the recorded failure of the participant onto an evaluated status. Callers hold the lock.
+func (s *StatusChecker) applyFailure(participantStatus ParticipantStatus) ParticipantStatus {
+	failure, ok := s.failures[participantStatus.Name]
+	if !ok {
+		return participantStatus
+	}
+	switch participantStatus.Status {
+	case StatusReady:
+		// the component recovered after all
+		delete(s.failures, participantStatus.Name)
+		return participantStatus
+	case StatusNotFound, StatusDeleted, StatusTerminating:
+		return participantStatus
+	}
+	participantStatus.Status = StatusFailed
+	participantStatus.Message = failure.Reason
+	participantStatus.Failure = &failure
+	return participantStatus
+}
+
+// componentEvents returns the events about the component's deployment, replica sets and pods.
+func componentEvents(events []Event, component string) []Event {
+	var matching []Event
+	for _, event := range events {
+		kind, name, _ := strings.Cut(event.Object, "/")
+		switch kind {
+		case "Deployment":
+			if name != component {
+				continue
+			}
+		case "ReplicaSet", "Pod":
+			if !strings.HasPrefix(name, component+"-") {
+				continue
+			}
+		default:
+			continue
+		}
+		matching = append(matching, event)
+	}
+	return matching
+}
+
+// FailureEvents returns the most relevant events explaining why the component of the participant isn't ready, its
+// warnings or, if there are none, its latest events.
+func FailureEvents(events []Event, component string) []Event {
+	events = componentEvents(events, component)
+	var warnings []Event
+	for _, event := range events {
+		if event.Type == "Warning" {
+			warnings = append(warnings, event)
+		}
+	}
+	if len(warnings) > 0 {
+		events = warnings
+	}
+	if len(events) > recentEventsLimit {
+		events = events[:recentEventsLimit]
+	}
+	return events
+}
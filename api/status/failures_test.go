@@ -0,0 +1,49 @@
+package status
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMarkFailed(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.BeginProvisioning("p")
+	checker.MarkFailed("p", Failure{Component: "controlplane", Reason: "controlplane did not become ready within 30m0s"})
+	checker.EndOperation("p")
+
+	got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusProvisioning}, nil)
+	if got.Status != StatusFailed || got.Failure == nil || got.Failure.Component != "controlplane" || got.Message != got.Failure.Reason {
+		t.Fatalf("expected the timed out participant to be FAILED, got %+v", got)
+	}
+	if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusReady}, nil); got.Status != StatusReady || got.Failure != nil {
+		t.Errorf("expected the recovered participant to be READY, got %+v", got)
+	}
+	if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusDegraded}, nil); got.Status != StatusDegraded {
+		t.Errorf("expected the failure to be dropped once the participant was ready, got %s", got.Status)
+	}
+
+	checker.MarkFailed("p", Failure{Reason: "timed out"})
+	checker.BeginProvisioning("p")
+	checker.EndOperation("p")
+	if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusDegraded}, nil); got.Status != StatusDegraded {
+		t.Errorf("expected provisioning again to clear the failure, got %s", got.Status)
+	}
+}
+
+func TestFailureEvents(t *testing.T) {
+	events := []Event{
+		{Type: "Normal", Reason: "Pulling", Object: "Pod/controlplane-7d9f-abcde"},
+		{Type: "Warning", Reason: "BackOff", Object: "Pod/controlplane-7d9f-abcde"},
+		{Type: "Warning", Reason: "FailedScheduling", Object: "Pod/dataplane-5c4b-fghij"},
+		{Type: "Normal", Reason: "ScalingReplicaSet", Object: "Deployment/controlplane"},
+	}
+	got := FailureEvents(events, "controlplane")
+	want := []Event{events[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the warnings about controlplane, got %+v", got)
+	}
+	if got := FailureEvents(events[:1], "controlplane"); len(got) != 1 {
+		t.Errorf("expected the latest events without warnings, got %+v", got)
+	}
+}
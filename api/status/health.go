@@ -0,0 +1,119 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Path prefix of the EDC and IdentityHub health endpoints
+const healthCheckPrefix = "/api/check/"
+
+// healthReport is the response of an EDC /api/check/health endpoint.
+type healthReport struct {
+	IsSystemHealthy  bool `json:"isSystemHealthy"`
+	ComponentResults []struct {
+		Component string `json:"component"`
+		IsHealthy bool   `json:"isHealthy"`
+		Failure   *struct {
+			Messages []string `json:"messages"`
+		} `json:"failure"`
+	} `json:"componentResults"`
+}
+
+// healthChecker requests the health endpoints of the components' pods.
+type healthChecker struct {
+	httpClient *http.Client
+}
+
+// EnableHealthChecks derives the status of running components from their /api/check/health endpoints as well, so
+// failures of the application layer such as a lost database connection show up as DEGRADED even though the pods are
+// ready.
+func (s *StatusChecker) EnableHealthChecks(timeout time.Duration) {
+	s.health = &healthChecker{httpClient: &http.Client{Timeout: timeout}}
+}
+
+// apply marks a running component as degraded when the health endpoint of one of its pods reports a failure or
+// doesn't answer. Components without an HTTP health probe are left alone.
+func (h *healthChecker) apply(ctx context.Context, deployment *appsv1.Deployment, pods []corev1.Pod, component *ComponentStatus) {
+	port := healthPort(deployment)
+	if port == 0 {
+		return
+	}
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		url := "http://" + pod.Status.PodIP + ":" + strconv.Itoa(port) + healthCheckPrefix + "health"
+		if problem := h.check(ctx, url); problem != "" {
+			component.Status = ComponentDegraded
+			component.Message = fmt.Sprintf("Degraded: health check of %s failed: %s", pod.Name, problem)
+			return
+		}
+	}
+}
+
+// check requests the health endpoint and describes why the component is unhealthy, empty if it is healthy.
+func (h *healthChecker) check(ctx context.Context, url string) string {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err.Error()
+	}
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return err.Error()
+	}
+	defer response.Body.Close()
+	var report healthReport
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		if response.StatusCode != http.StatusOK {
+			return response.Status
+		}
+		return "unreadable health report: " + err.Error()
+	}
+	if report.IsSystemHealthy {
+		return ""
+	}
+	var failures []string
+	for _, result := range report.ComponentResults {
+		if result.IsHealthy {
+			continue
+		}
+		failure := result.Component
+		if result.Failure != nil && len(result.Failure.Messages) > 0 {
+			failure += " (" + strings.Join(result.Failure.Messages, "; ") + ")"
+		}
+		failures = append(failures, failure)
+	}
+	if len(failures) == 0 {
+		return "system unhealthy"
+	}
+	return strings.Join(failures, ", ")
+}
+
+// healthPort returns the port of the deployment's HTTP readiness probe when it points at a health endpoint, zero
+// otherwise.
+func healthPort(deployment *appsv1.Deployment) int {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		probe := container.ReadinessProbe
+		if probe == nil || probe.HTTPGet == nil || !strings.HasPrefix(probe.HTTPGet.Path, healthCheckPrefix) {
+			continue
+		}
+		if port := probe.HTTPGet.Port; port.IntValue() > 0 {
+			return port.IntValue()
+		}
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == probe.HTTPGet.Port.String() {
+				return int(containerPort.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
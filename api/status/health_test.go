@@ -0,0 +1,68 @@
+package status
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestHealthChecks(t *testing.T) {
+	report := `{"isSystemHealthy":true,"componentResults":[]}`
+	component := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/check/health" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(report))
+	}))
+	defer component.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(component.URL, "http://"))
+	portNumber, _ := strconv.Atoi(port)
+
+	deployment := &appsv1.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:  "controlplane",
+		Ports: []corev1.ContainerPort{{Name: "default-port", ContainerPort: int32(portNumber)}},
+		ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{
+			Path: "/api/check/readiness", Port: intstr.FromString("default-port"),
+		}}},
+	}}
+	pods := []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-7d9f"}, Status: corev1.PodStatus{PodIP: host}}}
+	checker := &healthChecker{httpClient: &http.Client{Timeout: time.Second}}
+
+	healthy := ComponentStatus{Name: "controlplane", Ready: true, Status: ComponentRunning}
+	checker.apply(context.Background(), deployment, pods, &healthy)
+	if healthy.Status != ComponentRunning {
+		t.Errorf("expected a healthy component to keep running, got %+v", healthy)
+	}
+
+	report = `{"isSystemHealthy":false,"componentResults":[{"component":"sql","isHealthy":false,"failure":{"messages":["connection refused"]}}]}`
+	unhealthy := ComponentStatus{Name: "controlplane", Ready: true, Status: ComponentRunning}
+	checker.apply(context.Background(), deployment, pods, &unhealthy)
+	if unhealthy.Status != ComponentDegraded || !strings.Contains(unhealthy.Message, "sql (connection refused)") {
+		t.Errorf("expected the failing health check to degrade the component, got %+v", unhealthy)
+	}
+}
+
+func TestHealthPort(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	if port := healthPort(deployment); port != 0 {
+		t.Errorf("expected no health port without probes, got %d", port)
+	}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/api/check/readiness", Port: intstr.FromInt32(7080)}}},
+	}}
+	if port := healthPort(deployment); port != 7080 {
+		t.Errorf("expected the readiness probe port, got %d", port)
+	}
+}
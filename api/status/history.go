@@ -0,0 +1,193 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The status history of a participant is kept in a ConfigMap in its namespace, so it survives restarts of the
+// provisioner and is removed together with the participant. Only the latest maxHistoryEntries are kept.
+const (
+	historyConfigMap  = "provisioner-status-history"
+	historyKey        = "history.json"
+	maxHistoryEntries = 200
+	historyTimeout    = 10 * time.Second
+)
+
+// HistoryEntry is a change of the reported status of a participant.
+type HistoryEntry struct {
+	Status  ProvisioningStatus `json:"status"`
+	Message string             `json:"message,omitempty"`
+	// Component is the first unhealthy component at the time
+	Component string    `json:"component,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Duration is how long the participant stayed in the status, empty for its current status
+	Duration string `json:"duration,omitempty"`
+	// Provisioning breaks down how long the provisioning took, on the READY entry ending it
+	Provisioning *ProvisioningDurations `json:"provisioning,omitempty"`
+}
+
+// statusHistory writes status changes to the history ConfigMaps of the participants. Entries of participants whose
+// namespace doesn't exist yet, e.g. the start of a provisioning, are kept until the next change can be written.
+type statusHistory struct {
+	client client.Client
+
+	mu      sync.Mutex
+	pending map[string][]HistoryEntry
+	// writes serializes the updates of the ConfigMaps
+	writes sync.Mutex
+}
+
+// EnableHistory records the status changes of the participants in their namespaces, see GetHistory.
+func (s *StatusChecker) EnableHistory() {
+	s.history = &statusHistory{client: s.client}
+}
+
+// record queues the entry and writes it in the background, so status requests don't wait for the update.
+func (h *statusHistory) record(name string, entry HistoryEntry) {
+	h.mu.Lock()
+	switch entry.Status {
+	case StatusNotFound, StatusDeleted:
+		// the namespace and its history are gone
+		delete(h.pending, name)
+		h.mu.Unlock()
+		return
+	case StatusTerminating:
+		// objects can't be created in terminating namespaces
+		h.mu.Unlock()
+		return
+	}
+	if h.pending == nil {
+		h.pending = make(map[string][]HistoryEntry)
+	}
+	h.pending[name] = append(h.pending[name], entry)
+	h.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+		defer cancel()
+		if err := h.flush(ctx, name); err != nil {
+			fmt.Printf("Recording status history of %s failed: %v\n", name, err)
+		}
+	}()
+}
+
+// flush appends the pending entries of the participant to its history ConfigMap.
+func (h *statusHistory) flush(ctx context.Context, name string) error {
+	h.writes.Lock()
+	defer h.writes.Unlock()
+	h.mu.Lock()
+	entries := h.pending[name]
+	delete(h.pending, name)
+	h.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := h.client.Get(ctx, client.ObjectKey{Namespace: name, Name: historyConfigMap}, configMap)
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:      historyConfigMap,
+				Namespace: name,
+				Labels:    map[string]string{ManagedByLabel: ManagedByValue},
+			}}
+			data, err := json.Marshal(appendHistory(nil, entries))
+			if err != nil {
+				return err
+			}
+			configMap.Data = map[string]string{historyKey: string(data)}
+			return h.client.Create(ctx, configMap)
+		}
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(appendHistory(parseHistory(configMap), entries))
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[historyKey] = string(data)
+		return h.client.Update(ctx, configMap)
+	})
+	if apierrors.IsNotFound(err) {
+		// the namespace doesn't exist yet, the entries are written with the next change
+		h.mu.Lock()
+		if h.pending == nil {
+			h.pending = make(map[string][]HistoryEntry)
+		}
+		h.pending[name] = append(entries, h.pending[name]...)
+		h.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// appendHistory appends the entries to the history, skipping entries repeating the latest status, e.g. after a
+// restart of the provisioner, and drops the oldest entries beyond maxHistoryEntries. The provisioning durations of a
+// skipped entry are added to the latest one.
+func appendHistory(history []HistoryEntry, entries []HistoryEntry) []HistoryEntry {
+	for _, entry := range entries {
+		if last := len(history) - 1; last >= 0 && history[last].Status == entry.Status {
+			if entry.Provisioning != nil {
+				history[last].Provisioning = entry.Provisioning
+			}
+			continue
+		}
+		history = append(history, entry)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	return history
+}
+
+func parseHistory(configMap *corev1.ConfigMap) []HistoryEntry {
+	var history []HistoryEntry
+	data, ok := configMap.Data[historyKey]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		fmt.Printf("Ignoring unreadable status history of %s: %v\n", configMap.Namespace, err)
+		return nil
+	}
+	return history
+}
+
+// GetHistory returns the status changes of a participant, oldest first, with the time it stayed in each status.
+func (s *StatusChecker) GetHistory(ctx context.Context, name string) ([]HistoryEntry, error) {
+	var history []HistoryEntry
+	configMap := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: name, Name: historyConfigMap}, configMap); err == nil {
+		history = parseHistory(configMap)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if s.history != nil {
+		s.history.mu.Lock()
+		pending := s.history.pending[name]
+		s.history.mu.Unlock()
+		history = appendHistory(history, pending)
+	}
+	for i := 0; i+1 < len(history); i++ {
+		history[i].Duration = history[i+1].Timestamp.Sub(history[i].Timestamp).Round(time.Second).String()
+	}
+	return history, nil
+}
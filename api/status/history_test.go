@@ -0,0 +1,107 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configMapClient stores ConfigMaps in memory, creating them fails until the namespace exists.
+type configMapClient struct {
+	client.Client
+	namespaceExists bool
+	configMaps      map[string]*corev1.ConfigMap
+}
+
+func (c *configMapClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	configMap, ok := c.configMaps[key.Namespace+"/"+key.Name]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	*obj.(*corev1.ConfigMap) = *configMap.DeepCopy()
+	return nil
+}
+
+func (c *configMapClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	if !c.namespaceExists {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, obj.GetNamespace())
+	}
+	c.configMaps[obj.GetNamespace()+"/"+obj.GetName()] = obj.(*corev1.ConfigMap).DeepCopy()
+	return nil
+}
+
+func (c *configMapClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.configMaps[obj.GetNamespace()+"/"+obj.GetName()] = obj.(*corev1.ConfigMap).DeepCopy()
+	return nil
+}
+
+func TestHistoryKeepsEntriesUntilNamespaceExists(t *testing.T) {
+	c := &configMapClient{configMaps: make(map[string]*corev1.ConfigMap)}
+	checker := &StatusChecker{client: c}
+	checker.EnableHistory()
+	ctx := context.Background()
+	started := time.Now().Add(-5 * time.Minute)
+
+	checker.history.pending = map[string][]HistoryEntry{"alice": {{Status: StatusProvisioning, Timestamp: started}}}
+	if err := checker.history.flush(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.configMaps) != 0 || len(checker.history.pending["alice"]) != 1 {
+		t.Fatalf("expected the entry to stay pending without namespace, got %v", checker.history.pending)
+	}
+
+	c.namespaceExists = true
+	checker.history.pending["alice"] = append(checker.history.pending["alice"], HistoryEntry{Status: StatusReady, Timestamp: started.Add(3 * time.Minute)})
+	if err := checker.history.flush(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	checker.history.pending["alice"] = []HistoryEntry{
+		// repeated after a restart
+		{Status: StatusReady, Timestamp: started.Add(4 * time.Minute)},
+		{Status: StatusDegraded, Component: "dataplane", Timestamp: started.Add(5 * time.Minute)},
+	}
+	if err := checker.history.flush(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := checker.GetHistory(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Status != StatusProvisioning || history[1].Status != StatusReady || history[2].Component != "dataplane" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if history[0].Duration != "3m0s" || history[1].Duration != "2m0s" || history[2].Duration != "" {
+		t.Errorf("unexpected durations %q, %q, %q", history[0].Duration, history[1].Duration, history[2].Duration)
+	}
+}
+
+func TestAppendHistoryDropsOldestEntries(t *testing.T) {
+	var history []HistoryEntry
+	start := time.Now()
+	for i := 0; i < maxHistoryEntries+10; i++ {
+		status := StatusReady
+		if i%2 == 1 {
+			status = StatusDegraded
+		}
+		history = appendHistory(history, []HistoryEntry{{Status: status, Timestamp: start.Add(time.Duration(i) * time.Second)}})
+	}
+	if len(history) != maxHistoryEntries || !history[0].Timestamp.Equal(start.Add(10*time.Second)) {
+		t.Errorf("expected the latest %d entries, got %d starting at %v", maxHistoryEntries, len(history), history[0].Timestamp)
+	}
+}
+
+func TestAppendHistoryAddsProvisioningDurations(t *testing.T) {
+	start := time.Now()
+	history := appendHistory(nil, []HistoryEntry{{Status: StatusProvisioning, Timestamp: start}, {Status: StatusReady, Timestamp: start.Add(time.Minute)}})
+	durations := &ProvisioningDurations{Total: "1m0s", CompletedAt: start.Add(time.Minute)}
+	history = appendHistory(history, []HistoryEntry{{Status: StatusReady, Timestamp: durations.CompletedAt, Provisioning: durations}})
+	if len(history) != 2 || history[1].Provisioning != durations {
+		t.Errorf("expected the durations on the existing READY entry, got %+v", history)
+	}
+}
@@ -0,0 +1,37 @@
+package status
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Labels the provisioner puts on every object of the participants it manages
+const (
+	ManagedByLabel   = "app.kubernetes.io/managed-by"
+	ManagedByValue   = "aruba-provisioner"
+	ParticipantLabel = "aruba-provisioner/participant"
+)
+
+// Namespaces of the cluster itself, participants can't be provisioned into them or into those prefixed with kube-
+var systemNamespaces = map[string]bool{"default": true, "kube-system": true, "kube-public": true, "kube-node-lease": true}
+
+// IsSystemNamespace reports whether the namespace belongs to the cluster itself rather than to a workload.
+func IsSystemNamespace(name string) bool {
+	return systemNamespaces[name] || strings.HasPrefix(name, "kube-")
+}
+
+// IsManagedNamespace reports whether the namespace exists and carries the provisioner's managed-by label.
+func IsManagedNamespace(ctx context.Context, c client.Client, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return namespace.Labels[ManagedByLabel] == ManagedByValue, nil
+}
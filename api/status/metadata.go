@@ -0,0 +1,50 @@
+package status
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Namespace labels and annotations holding the metadata of a participant. Team and environment are labels, so
+// participants can be selected by them.
+const (
+	OwnerAnnotation       = "aruba-provisioner/owner"
+	DescriptionAnnotation = "aruba-provisioner/description"
+	TeamLabel             = "aruba-provisioner/team"
+	EnvironmentLabel      = "aruba-provisioner/environment"
+)
+
+// ParticipantMetadata describes who a participant belongs to and what it is for.
+type ParticipantMetadata struct {
+	Owner       string `json:"owner,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Labels are the labels given in the participant's definition
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// metadataOf reads the metadata of a participant from its namespace, nil if it has none. Labels of the provisioner
+// and of Kubernetes aren't reported.
+func metadataOf(namespace *corev1.Namespace) *ParticipantMetadata {
+	metadata := &ParticipantMetadata{
+		Owner:       namespace.Annotations[OwnerAnnotation],
+		Team:        namespace.Labels[TeamLabel],
+		Environment: namespace.Labels[EnvironmentLabel],
+		Description: namespace.Annotations[DescriptionAnnotation],
+	}
+	for key, value := range namespace.Labels {
+		if key == ManagedByLabel || strings.HasPrefix(key, "aruba-provisioner/") || strings.Contains(key, "kubernetes.io/") {
+			continue
+		}
+		if metadata.Labels == nil {
+			metadata.Labels = make(map[string]string)
+		}
+		metadata.Labels[key] = value
+	}
+	if metadata.Owner == "" && metadata.Team == "" && metadata.Environment == "" && metadata.Description == "" && metadata.Labels == nil {
+		return nil
+	}
+	return metadata
+}
@@ -0,0 +1,285 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ProvisioningStatus string
+
+const (
+	StatusProvisioning ProvisioningStatus = "PROVISIONING"
+	// StatusSeeding is reported while the components are ready but their data is still being seeded
+	StatusSeeding ProvisioningStatus = "SEEDING"
+	// StatusSeedFailed is reported when the components are ready but seeding their data failed
+	StatusSeedFailed ProvisioningStatus = "SEED_FAILED"
+	StatusReady      ProvisioningStatus = "READY"
+	StatusDegraded   ProvisioningStatus = "DEGRADED"
+	StatusFailed     ProvisioningStatus = "FAILED"
+	StatusNotFound   ProvisioningStatus = "NOT_FOUND"
+	// StatusDeleting is reported from the moment a deletion was requested until the namespace is gone
+	StatusDeleting ProvisioningStatus = "DELETING"
+	// StatusDeleted is reported for a while after a deletion completed, instead of NOT_FOUND
+	StatusDeleted ProvisioningStatus = "DELETED"
+	// StatusTerminating is reported while the participant namespace is being deleted
+	StatusTerminating ProvisioningStatus = "TERMINATING"
+	// StatusOrphaned is reported for namespaces whose participant components are gone
+	StatusOrphaned ProvisioningStatus = "ORPHANED"
+)
+
+// ProvisioningStatuses lists all statuses a participant can be reported in
+var ProvisioningStatuses = []ProvisioningStatus{StatusProvisioning, StatusSeeding, StatusSeedFailed, StatusReady, StatusDegraded, StatusFailed, StatusNotFound, StatusDeleting, StatusDeleted, StatusTerminating, StatusOrphaned}
+
+// Component states reported in ComponentStatus.Status
+const (
+	ComponentRunning  = "Running"
+	ComponentStarting = "Starting"
+	ComponentDegraded = "Degraded"
+	ComponentFailed   = "Failed"
+	ComponentMissing  = "Missing"
+)
+
+type ComponentStatus struct {
+	Name            string `json:"name"`
+	Ready           bool   `json:"ready"`
+	ReadyReplicas   int32  `json:"readyReplicas"`
+	DesiredReplicas int32  `json:"desiredReplicas"`
+	Status          string `json:"status"`
+	Message         string `json:"message,omitempty"`
+	// Reason is why a pod of a failed or degraded component can't run, e.g. CrashLoopBackOff or OOMKilled
+	Reason string `json:"reason,omitempty"`
+	// Image is the image reference of the component's main container, Version its tag
+	Image   string `json:"image,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Pods reports the pods of the component, e.g. whether they are crash looping or can't pull their image
+	Pods []PodStatus `json:"pods,omitempty"`
+}
+
+// PodStatus is the state of a pod of a component.
+type PodStatus struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Ready    bool   `json:"ready"`
+	Restarts int32  `json:"restarts"`
+	// Reason is why a container is waiting, e.g. CrashLoopBackOff or ImagePullBackOff
+	Reason string `json:"reason,omitempty"`
+	// LastTerminationReason is why a container was last terminated, e.g. OOMKilled or Error
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	Image                 string `json:"image,omitempty"`
+}
+
+type Event struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Object    string    `json:"object"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Seeding states reported in SeedingStatus.State and SeedingStep.State
+const (
+	SeedingPending   = "PENDING"
+	SeedingRunning   = "RUNNING"
+	SeedingCompleted = "COMPLETED"
+	SeedingFailed    = "FAILED"
+)
+
+type SeedingStatus struct {
+	State     string    `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Steps reports the progress of the individual seed steps, in the order they run
+	Steps []SeedingStep `json:"steps,omitempty"`
+	// Credentials reports the verifiable credentials requested from the issuer
+	Credentials []CredentialStatus `json:"credentials,omitempty"`
+	// Resources identifies the entities seeding created
+	Resources *SeededResources `json:"resources,omitempty"`
+	// Verification is the outcome of checking the seeded entities once seeding completed
+	Verification *CatalogVerification `json:"verification,omitempty"`
+}
+
+// CatalogVerification reports whether the entities seeding created exist in the connector and whether the
+// participant's catalog offers the seeded assets. Participants failing the verification are reported DEGRADED.
+type CatalogVerification struct {
+	Verified bool `json:"verified"`
+	// Missing lists what wasn't found, e.g. "asset asset-1", or "dataset asset-1" for assets the catalog doesn't offer
+	Missing   []string  `json:"missing,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// SeededResources identifies the entities seeding created in the participant's components, for clients that refer
+// to them, e.g. in contract negotiations.
+type SeededResources struct {
+	Assets              []string `json:"assets,omitempty"`
+	Policies            []string `json:"policies,omitempty"`
+	ContractDefinitions []string `json:"contractDefinitions,omitempty"`
+	// ParticipantContextId is the participant context in the identity hub
+	ParticipantContextId string `json:"participantContextId,omitempty"`
+	// StsClientId is the client the connector authenticates with at the identity hub's STS
+	StsClientId string `json:"stsClientId,omitempty"`
+}
+
+// SeedingStep is the progress of one seed step, e.g. the creation of the assets.
+type SeedingStep struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Attempts counts the tries of the current run, failed ones are retried with backoff
+	Attempts  int       `json:"attempts,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Credential states reported in CredentialStatus.State
+const (
+	CredentialRequested = "REQUESTED"
+	CredentialIssued    = "ISSUED"
+	CredentialFailed    = "FAILED"
+)
+
+// CredentialStatus is the issuance state of a verifiable credential, e.g. the MembershipCredential.
+type CredentialStatus struct {
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CertificateStatus is the state of a cert-manager Certificate of the participant, e.g. of its ingresses.
+type CertificateStatus struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+	// NotAfter is the expiry of the issued certificate
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+type ParticipantStatus struct {
+	Name    string             `json:"name"`
+	Status  ProvisioningStatus `json:"status"`
+	Message string             `json:"message,omitempty"`
+	// Metadata describes who the participant belongs to and what it is for
+	Metadata *ParticipantMetadata `json:"metadata,omitempty"`
+	// ExpiresAt is when an ephemeral participant is deleted, ExpiresIn the lifetime it has left
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	ExpiresIn string     `json:"expiresIn,omitempty"`
+	// QueuePosition is the position of a pending provisioning among the jobs waiting for a free slot
+	QueuePosition int               `json:"queuePosition,omitempty"`
+	Components    []ComponentStatus `json:"components,omitempty"`
+	Events        []Event           `json:"events,omitempty"`
+	Seeding       *SeedingStatus    `json:"seeding,omitempty"`
+	// Steps reports the progress of the latest provisioning step by step, from applying the manifests to seeding the
+	// contract definitions
+	Steps     []ProgressStep    `json:"steps,omitempty"`
+	Endpoints map[string]string `json:"endpoints,omitempty"`
+	// Certificates reports the TLS certificates requested for the participant
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+	// Probes reports whether the participant's ingress routes answer, when route probes are enabled
+	Probes []RouteProbe `json:"probes,omitempty"`
+	// Credentials reports the verifiable credentials held by the participant, when credential lookups are enabled
+	Credentials []HeldCredential `json:"credentials,omitempty"`
+	// Remaining lists the resources left in the namespace of a TERMINATING or ORPHANED participant
+	Remaining []string `json:"remaining,omitempty"`
+	// Failure is why the provisioning of a FAILED participant was given up
+	Failure *Failure `json:"failure,omitempty"`
+	// DeletedAt is when the deletion of a DELETED participant completed
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Provisioning breaks down how long the latest completed provisioning took
+	Provisioning *ProvisioningDurations `json:"provisioning,omitempty"`
+	// DidRotation reports the latest rotation of the participant's DID
+	DidRotation *DidRotationStatus `json:"didRotation,omitempty"`
+	LastUpdated time.Time          `json:"lastUpdated"`
+	// Stale marks the last known status reported while the Kubernetes API is unreachable, as of LastUpdated
+	Stale bool `json:"stale,omitempty"`
+}
+
+// StatusSummary aggregates the status of several participants.
+type StatusSummary struct {
+	Total    int                        `json:"total"`
+	Ready    int                        `json:"ready"`
+	ByStatus map[ProvisioningStatus]int `json:"byStatus"`
+}
+
+// Summarize counts the participants per status. Only READY participants count as ready, terminating and orphaned
+// ones are reported separately.
+func Summarize(statuses []ParticipantStatus) StatusSummary {
+	summary := StatusSummary{Total: len(statuses), ByStatus: make(map[ProvisioningStatus]int)}
+	for _, participantStatus := range statuses {
+		summary.ByStatus[participantStatus.Status]++
+		if participantStatus.Status == StatusReady {
+			summary.Ready++
+		}
+	}
+	return summary
+}
+
+// Field names an optional section of the status response that can be requested via ?fields=
+type Field string
+
+const (
+	FieldComponents   Field = "components"
+	FieldEvents       Field = "events"
+	FieldSeeding      Field = "seeding"
+	FieldEndpoints    Field = "endpoints"
+	FieldCertificates Field = "certificates"
+	FieldProbes       Field = "probes"
+	FieldCredentials  Field = "credentials"
+	FieldSteps        Field = "steps"
+)
+
+var AllFields = []Field{FieldComponents, FieldEvents, FieldSeeding, FieldEndpoints, FieldCertificates, FieldProbes, FieldCredentials, FieldSteps}
+
+// ParseFields parses a comma-separated field list. An empty list selects all fields.
+func ParseFields(value string) ([]Field, error) {
+	if strings.TrimSpace(value) == "" {
+		return AllFields, nil
+	}
+	var fields []Field
+	for _, name := range strings.Split(value, ",") {
+		field := Field(strings.TrimSpace(name))
+		if !hasField(AllFields, field) {
+			return nil, fmt.Errorf("unknown status field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func hasField(fields []Field, field Field) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Project returns a copy of the status that only contains the requested optional sections.
+func (p ParticipantStatus) Project(fields []Field) ParticipantStatus {
+	if !hasField(fields, FieldComponents) {
+		p.Components = nil
+	}
+	if !hasField(fields, FieldEvents) {
+		p.Events = nil
+	}
+	if !hasField(fields, FieldSeeding) {
+		p.Seeding = nil
+	}
+	if !hasField(fields, FieldEndpoints) {
+		p.Endpoints = nil
+	}
+	if !hasField(fields, FieldCertificates) {
+		p.Certificates = nil
+	}
+	if !hasField(fields, FieldProbes) {
+		p.Probes = nil
+	}
+	if !hasField(fields, FieldCredentials) {
+		p.Credentials = nil
+	}
+	if !hasField(fields, FieldSteps) {
+		p.Steps = nil
+	}
+	return p
+}
@@ -0,0 +1,114 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Prefixes of the names of the components reporting the Services and Ingresses of a participant, which share the
+// names of its deployments
+const (
+	serviceComponentPrefix = "service/"
+	ingressComponentPrefix = "ingress/"
+)
+
+// getNetworkStatuses reports the participant's Services and Ingresses as components, as running deployments don't
+// make the participant reachable: Services need ready endpoints and Ingresses an address assigned by their controller.
+// Like deployments, they are selected by the participant label, falling back to all of the namespace. Kinds that
+// can't be listed are left out.
+func (s *StatusChecker) getNetworkStatuses(ctx context.Context, namespace string, now time.Time) []ComponentStatus {
+	var components []ComponentStatus
+	services := &corev1.ServiceList{}
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := listParticipantObjects(ctx, s.client, services, namespace); err != nil {
+		fmt.Printf("Listing services of %s failed: %v\n", namespace, err)
+	} else if err := s.client.List(ctx, endpointSlices, client.InNamespace(namespace)); err != nil {
+		fmt.Printf("Listing endpoint slices of %s failed: %v\n", namespace, err)
+	} else {
+		ready := readyEndpoints(endpointSlices.Items)
+		for _, service := range services.Items {
+			// without a selector, the endpoints are managed by hand or point outside the cluster
+			if service.Spec.Type == corev1.ServiceTypeExternalName || len(service.Spec.Selector) == 0 {
+				continue
+			}
+			components = append(components, serviceStatusOf(&service, ready[service.Name], now))
+		}
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := listParticipantObjects(ctx, s.client, ingresses, namespace); err != nil {
+		fmt.Printf("Listing ingresses of %s failed: %v\n", namespace, err)
+		return components
+	}
+	for _, ingress := range ingresses.Items {
+		components = append(components, ingressStatusOf(&ingress, now))
+	}
+	return components
+}
+
+// listParticipantObjects lists the objects labelled with the participant, all of the namespace if none is.
+func listParticipantObjects(ctx context.Context, c client.Client, list client.ObjectList, namespace string) error {
+	if err := c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{ParticipantLabel: namespace}); err != nil {
+		return err
+	}
+	if meta.LenList(list) > 0 {
+		return nil
+	}
+	return c.List(ctx, list, client.InNamespace(namespace))
+}
+
+// readyEndpoints counts the ready endpoints of the services by their name.
+func readyEndpoints(endpointSlices []discoveryv1.EndpointSlice) map[string]int32 {
+	ready := make(map[string]int32)
+	for _, slice := range endpointSlices {
+		service := slice.Labels[discoveryv1.LabelServiceName]
+		for _, endpoint := range slice.Endpoints {
+			// nil means unknown, which consumers interpret as ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready[service]++
+			}
+		}
+	}
+	return ready
+}
+
+// serviceStatusOf reports the service as running once it has a ready endpoint.
+func serviceStatusOf(service *corev1.Service, ready int32, now time.Time) ComponentStatus {
+	component := ComponentStatus{Name: serviceComponentPrefix + service.Name, ReadyReplicas: ready}
+	if ready > 0 {
+		component.Ready = true
+		component.Status = ComponentRunning
+		return component
+	}
+	component.Status, component.Message = unavailableStatus(service.CreationTimestamp, now, "no ready endpoints")
+	return component
+}
+
+// ingressStatusOf reports the ingress as running once its controller admitted it and assigned it an address.
+func ingressStatusOf(ingress *networkingv1.Ingress, now time.Time) ComponentStatus {
+	component := ComponentStatus{Name: ingressComponentPrefix + ingress.Name}
+	if len(ingress.Status.LoadBalancer.Ingress) > 0 {
+		component.Ready = true
+		component.Status = ComponentRunning
+		return component
+	}
+	component.Status, component.Message = unavailableStatus(ingress.CreationTimestamp, now, "no address assigned by the ingress controller")
+	return component
+}
+
+// unavailableStatus reports an unavailable Service or Ingress as starting within the provisioning grace period after
+// its creation and as degraded afterwards.
+func unavailableStatus(created metav1.Time, now time.Time, reason string) (string, string) {
+	if now.Sub(created.Time) > provisioningGracePeriod {
+		return ComponentDegraded, "Degraded: " + reason
+	}
+	return ComponentStarting, "Starting: " + reason
+}
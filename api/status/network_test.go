@@ -0,0 +1,97 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// networkClient serves List for the deployments, services, endpoint slices and ingresses of a namespace.
+type networkClient struct {
+	client.Client
+	deployments    []appsv1.Deployment
+	services       []corev1.Service
+	endpointSlices []discoveryv1.EndpointSlice
+	ingresses      []networkingv1.Ingress
+}
+
+func (c *networkClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	switch list := list.(type) {
+	case *appsv1.DeploymentList:
+		list.Items = c.deployments
+	case *corev1.ServiceList:
+		list.Items = c.services
+	case *discoveryv1.EndpointSliceList:
+		list.Items = c.endpointSlices
+	case *networkingv1.IngressList:
+		list.Items = c.ingresses
+	}
+	return nil
+}
+
+func TestNetworkComponentStatuses(t *testing.T) {
+	replicas := int32(1)
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+	ready := true
+	c := &networkClient{
+		deployments: []appsv1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", CreationTimestamp: old},
+			Spec: appsv1.DeploymentSpec{Replicas: &replicas}, Status: appsv1.DeploymentStatus{ReadyReplicas: 1}}},
+		services: []corev1.Service{
+			{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", CreationTimestamp: old}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "controlplane"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "dataplane", CreationTimestamp: old}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "dataplane"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "vault"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "vault.example.com"}},
+		},
+		endpointSlices: []discoveryv1.EndpointSlice{{
+			ObjectMeta: metav1.ObjectMeta{Name: "controlplane-x7k2p", Labels: map[string]string{discoveryv1.LabelServiceName: "controlplane"}},
+			Endpoints:  []discoveryv1.Endpoint{{Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+		}},
+		ingresses: []networkingv1.Ingress{
+			{ObjectMeta: metav1.ObjectMeta{Name: "ingress-controlplane", CreationTimestamp: old},
+				Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}}}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "ingress-dataplane", CreationTimestamp: metav1.Now()}},
+		},
+	}
+	components, err := NewStatusChecker(context.Background(), c, DefaultCacheTTL).getComponentStatuses(context.Background(), "alice", []string{"controlplane"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]ComponentStatus{}
+	for _, component := range components {
+		byName[component.Name] = component
+	}
+	expected := map[string]string{
+		"controlplane":                 ComponentRunning,
+		"service/controlplane":         ComponentRunning,
+		"service/dataplane":            ComponentDegraded,
+		"ingress/ingress-controlplane": ComponentRunning,
+		"ingress/ingress-dataplane":    ComponentStarting,
+	}
+	if len(components) != len(expected) {
+		t.Errorf("expected %d components, got %+v", len(expected), components)
+	}
+	for name, state := range expected {
+		if byName[name].Status != state {
+			t.Errorf("expected %s to be %s, got %+v", name, state, byName[name])
+		}
+	}
+	if status, _ := (StatusEvaluator{}).Evaluate(components); status != StatusDegraded {
+		t.Errorf("expected a participant with an unreachable service to be degraded, got %s", status)
+	}
+
+	// a namespace without deployments holds no participant
+	c.deployments = nil
+	components, err = NewStatusChecker(context.Background(), c, DefaultCacheTTL).getComponentStatuses(context.Background(), "alice", []string{"controlplane"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := (StatusEvaluator{}).Evaluate(components); status != StatusNotFound {
+		t.Errorf("expected services and ingresses of a missing participant to be ignored, got %+v", components)
+	}
+}
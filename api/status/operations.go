@@ -0,0 +1,175 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Operations not finished within this period no longer affect the reported status
+const operationExpiry = 30 * time.Minute
+
+// Deleted participants are reported as DELETED for this period before they become NOT_FOUND
+const deletedRetention = 24 * time.Hour
+
+// operation is a create or delete that was accepted but whose effects may not be visible in the cluster yet.
+type operation struct {
+	status  ProvisioningStatus
+	started time.Time
+	// queuePosition is the position of the provisioning job in the provisioner's queue, zero once it runs
+	queuePosition int
+}
+
+// SharedOperations returns the operation another replica of the provisioner runs on the participant and when it
+// started, for replicas to report the same status behind a load balancer.
+type SharedOperations func(ctx context.Context, name string) (ProvisioningStatus, time.Time, bool)
+
+// EnableSharedOperations makes status evaluations overlay the operations of other replicas, when the participant has
+// no operation running on this one.
+func (s *StatusChecker) EnableSharedOperations(lookup SharedOperations) {
+	s.sharedOperations = lookup
+}
+
+// sharedOperation returns the operation another replica runs on the participant, nil if there is none or the
+// participant has an operation on this replica.
+func (s *StatusChecker) sharedOperation(ctx context.Context, name string) *operation {
+	if s.sharedOperations == nil {
+		return nil
+	}
+	s.mu.RLock()
+	_, local := s.operations[name]
+	s.mu.RUnlock()
+	if local {
+		return nil
+	}
+	status, started, ok := s.sharedOperations(ctx, name)
+	if !ok {
+		return nil
+	}
+	return &operation{status: status, started: started}
+}
+
+// InOperation reports whether the participant is being provisioned or deleted, on this replica or on another one.
+func (s *StatusChecker) InOperation(ctx context.Context, name string) bool {
+	s.mu.RLock()
+	op, ok := s.operations[name]
+	s.mu.RUnlock()
+	if ok && time.Since(op.started) <= operationExpiry {
+		return true
+	}
+	return s.sharedOperation(ctx, name) != nil
+}
+
+// BeginProvisioning guarantees that status calls report the participant as at least PROVISIONING, instead of
+// NOT_FOUND or a stale READY, until EndOperation is called. Call it before the create returns.
+func (s *StatusChecker) BeginProvisioning(name string) {
+	s.mu.Lock()
+	delete(s.deleted, name)
+	delete(s.failures, name)
+	s.mu.Unlock()
+	s.beginOperation(name, StatusProvisioning)
+}
+
+// BeginDeletion guarantees that status calls report the participant as DELETING until its namespace is gone.
+func (s *StatusChecker) BeginDeletion(name string) {
+	s.beginOperation(name, StatusDeleting)
+}
+
+// MarkDeleted ends the deletion of a participant whose namespace is gone, from now on it is reported as DELETED.
+func (s *StatusChecker) MarkDeleted(name string) {
+	s.mu.Lock()
+	delete(s.operations, name)
+	delete(s.failures, name)
+	delete(s.provisioning, name)
+	delete(s.progress, name)
+	delete(s.didRotations, name)
+	s.deleted[name] = time.Now()
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// EndOperation marks the participant's pending operation as finished, e.g. when seeding completed or failed.
+func (s *StatusChecker) EndOperation(name string) {
+	s.mu.Lock()
+	delete(s.operations, name)
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetQueuePosition reports the position of the participant's pending provisioning in the queue of jobs waiting for a
+// free slot, zero once it started.
+func (s *StatusChecker) SetQueuePosition(name string, position int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op, ok := s.operations[name]; ok {
+		op.queuePosition = position
+		s.operations[name] = op
+	}
+}
+
+func (s *StatusChecker) beginOperation(name string, status ProvisioningStatus) {
+	now := time.Now()
+	s.mu.Lock()
+	s.operations[name] = operation{status: status, started: now}
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+	// the history shows when the operation started rather than when its status was first requested
+	s.observe(ParticipantStatus{Name: name, Status: status, LastUpdated: now})
+}
+
+// applyOperation overlays a pending operation, a failure or a completed deletion onto an evaluated status. The
+// operation of another replica, if given, applies when the participant has none on this one.
+func (s *StatusChecker) applyOperation(participantStatus ParticipantStatus, shared *operation) ParticipantStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participantStatus = s.applyDidRotation(s.applyProgress(s.applyProvisioning(participantStatus)))
+	op, ok := s.operations[participantStatus.Name]
+	if !ok && shared != nil {
+		op, ok = *shared, true
+	}
+	if !ok {
+		return s.applyDeleted(s.applyFailure(participantStatus))
+	}
+	if time.Since(op.started) > operationExpiry {
+		delete(s.operations, participantStatus.Name)
+		return participantStatus
+	}
+
+	switch op.status {
+	case StatusProvisioning:
+		// failures and degradations are reported as they are
+		if participantStatus.Status == StatusNotFound || participantStatus.Status == StatusReady {
+			participantStatus.Status = StatusProvisioning
+			participantStatus.Message = "provisioning in progress"
+		}
+		if op.queuePosition > 0 {
+			participantStatus.QueuePosition = op.queuePosition
+			participantStatus.Message = fmt.Sprintf("queued for provisioning at position %d", op.queuePosition)
+		}
+	case StatusDeleting:
+		if participantStatus.Status == StatusNotFound {
+			delete(s.operations, participantStatus.Name)
+			s.deleted[participantStatus.Name] = time.Now()
+			return s.applyDeleted(participantStatus)
+		}
+		participantStatus.Status = StatusDeleting
+		participantStatus.Message = "deletion in progress"
+	}
+	return participantStatus
+}
+
+// applyDeleted reports a missing participant as DELETED when its deletion completed recently. Callers hold the lock.
+func (s *StatusChecker) applyDeleted(participantStatus ParticipantStatus) ParticipantStatus {
+	deletedAt, ok := s.deleted[participantStatus.Name]
+	if !ok || participantStatus.Status != StatusNotFound {
+		return participantStatus
+	}
+	if time.Since(deletedAt) > deletedRetention {
+		delete(s.deleted, participantStatus.Name)
+		return participantStatus
+	}
+	participantStatus.Status = StatusDeleted
+	participantStatus.Message = "participant was deleted"
+	participantStatus.DeletedAt = &deletedAt
+	return participantStatus
+}
@@ -0,0 +1,102 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestApplyOperation(t *testing.T) {
+	tests := []struct {
+		name      string
+		begin     func(s *StatusChecker)
+		evaluated ProvisioningStatus
+		want      ProvisioningStatus
+	}{
+		{"no operation", func(s *StatusChecker) {}, StatusNotFound, StatusNotFound},
+		{"provisioning not yet visible", func(s *StatusChecker) { s.BeginProvisioning("p") }, StatusNotFound, StatusProvisioning},
+		{"stale ready while provisioning", func(s *StatusChecker) { s.BeginProvisioning("p") }, StatusReady, StatusProvisioning},
+		{"failure while provisioning", func(s *StatusChecker) { s.BeginProvisioning("p") }, StatusFailed, StatusFailed},
+		{"ended provisioning", func(s *StatusChecker) { s.BeginProvisioning("p"); s.EndOperation("p") }, StatusReady, StatusReady},
+		{"deletion not yet visible", func(s *StatusChecker) { s.BeginDeletion("p") }, StatusReady, StatusDeleting},
+		{"deletion finished", func(s *StatusChecker) { s.BeginDeletion("p") }, StatusNotFound, StatusDeleted},
+		{"marked deleted", func(s *StatusChecker) { s.BeginDeletion("p"); s.MarkDeleted("p") }, StatusNotFound, StatusDeleted},
+		{"provisioned again", func(s *StatusChecker) { s.MarkDeleted("p"); s.BeginProvisioning("p") }, StatusNotFound, StatusProvisioning},
+	}
+	for _, tt := range tests {
+		checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+		tt.begin(checker)
+		if got := checker.applyOperation(ParticipantStatus{Name: "p", Status: tt.evaluated}, nil).Status; got != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeletedIsTerminal(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.BeginDeletion("p")
+	first := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusNotFound}, nil)
+	second := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusNotFound}, nil)
+	if second.Status != StatusDeleted || second.DeletedAt == nil || !second.DeletedAt.Equal(*first.DeletedAt) {
+		t.Errorf("deleted participant not reported as DELETED with a stable timestamp: %+v", second)
+	}
+}
+
+func TestCacheRejectsStaleWrites(t *testing.T) {
+	cache := newStatusCache(DefaultCacheTTL)
+	version := cache.version("p")
+	cache.invalidate("p")
+	cache.set("p", ParticipantStatus{Name: "p", Status: StatusReady}, AllFields, version)
+	if cache.has("p") {
+		t.Error("status evaluated before an invalidation was cached")
+	}
+	cache.set("p", ParticipantStatus{Name: "p", Status: StatusReady}, AllFields, cache.version("p"))
+	if !cache.has("p") {
+		t.Error("current status was not cached")
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.BeginProvisioning("alice")
+	checker.SetQueuePosition("alice", 3)
+	queued := checker.applyOperation(ParticipantStatus{Name: "alice", Status: StatusNotFound}, nil)
+	if queued.Status != StatusProvisioning || queued.QueuePosition != 3 {
+		t.Errorf("expected a queued provisioning, got %+v", queued)
+	}
+	checker.SetQueuePosition("alice", 0)
+	running := checker.applyOperation(ParticipantStatus{Name: "alice", Status: StatusNotFound}, nil)
+	if running.QueuePosition != 0 || running.Message != "provisioning in progress" {
+		t.Errorf("expected a running provisioning, got %+v", running)
+	}
+}
+
+func TestSharedOperation(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.EnableSharedOperations(func(_ context.Context, name string) (ProvisioningStatus, time.Time, bool) {
+		return StatusDeleting, time.Now(), name == "alice"
+	})
+	if got := checker.applyOperation(ParticipantStatus{Name: "alice", Status: StatusReady}, checker.sharedOperation(context.Background(), "alice")); got.Status != StatusDeleting {
+		t.Errorf("expected the deletion of another replica to be reported, got %s", got.Status)
+	}
+	checker.BeginProvisioning("alice")
+	if shared := checker.sharedOperation(context.Background(), "alice"); shared != nil {
+		t.Errorf("expected the operation of this replica to take precedence, got %+v", shared)
+	}
+	if got := checker.applyOperation(ParticipantStatus{Name: "bob", Status: StatusReady}, checker.sharedOperation(context.Background(), "bob")); got.Status != StatusReady {
+		t.Errorf("expected no operation for bob, got %s", got.Status)
+	}
+}
+
+func TestProvisioningDurations(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.RecordProvisioning("p", ProvisioningDurations{Total: "2m0s", Apply: "5s"})
+	reported := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusReady}, nil)
+	if reported.Provisioning == nil || reported.Provisioning.Total != "2m0s" {
+		t.Errorf("durations not reported: %+v", reported.Provisioning)
+	}
+	checker.MarkDeleted("p")
+	if reported := checker.applyOperation(ParticipantStatus{Name: "p", Status: StatusNotFound}, nil); reported.Provisioning != nil {
+		t.Errorf("durations of a deleted participant reported: %+v", reported.Provisioning)
+	}
+}
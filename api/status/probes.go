@@ -0,0 +1,192 @@
+package status
+
+import (
+	"aruba-provisioner/api/gateway"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RouteProbe is the outcome of a request to one of the participant's ingress routes.
+type RouteProbe struct {
+	Name       string `json:"name"`
+	Url        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// PathPrefixAnnotation records the path the component routes of the participant's ingresses start with, when it isn't
+// /<participant>
+const PathPrefixAnnotation = "aruba-provisioner/path-prefix"
+
+// participantRoutes are the ingress routes probed, with their path below the path prefix of the participant. The DID
+// route is always below /<participant>.
+var participantRoutes = []struct {
+	name    string
+	path    string
+	didPath bool
+}{
+	{"health", "/health/api/check/health", false},
+	{"management", "/cp/api/management/v3/assets", false},
+	{"credentials", "/cs/api/credentials/v1/participants", false},
+	{"did", "/did.json", true},
+}
+
+// routeProber requests the ingress routes of participants through the ingress controller.
+type routeProber struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+// EnableProbes makes status evaluations request the participant's ingress routes and report their reachability and
+// latency. Routes without a host are requested below baseUrl, the address of the ingress controller.
+func (s *StatusChecker) EnableProbes(baseUrl string, timeout time.Duration) {
+	s.prober = &routeProber{baseUrl: strings.TrimSuffix(baseUrl, "/"), httpClient: &http.Client{Timeout: timeout}}
+}
+
+// probeRoutes requests all routes of the participant concurrently.
+func (s *StatusChecker) probeRoutes(ctx context.Context, namespace string) []RouteProbe {
+	base := s.prober.baseUrl
+	host, tls := ingressHost(ctx, s.client, namespace)
+	if host == "" {
+		host, tls = routeHost(ctx, s.client, namespace)
+	}
+	if host != "" {
+		base = "http://" + host
+		if tls {
+			base = "https://" + host
+		}
+	}
+	prefix := ingressPathPrefix(ctx, s.client, namespace)
+	probes := make([]RouteProbe, len(participantRoutes))
+	var wg sync.WaitGroup
+	for i, route := range participantRoutes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := prefix + route.path
+			if route.didPath {
+				path = "/" + namespace + route.path
+			}
+			probes[i] = s.prober.probe(ctx, route.name, base+path)
+		}()
+	}
+	wg.Wait()
+	return probes
+}
+
+// probe requests the url. The route is reachable when a component answered: ingress controllers answer 404 for
+// unknown routes and 502 to 504 for routes without a healthy backend.
+func (p *routeProber) probe(ctx context.Context, name string, url string) RouteProbe {
+	result := RouteProbe{Name: name, Url: url}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	start := time.Now()
+	response, err := p.httpClient.Do(request)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+	result.StatusCode = response.StatusCode
+	switch response.StatusCode {
+	case http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		result.Error = fmt.Sprintf("route answered %s", response.Status)
+	default:
+		result.Reachable = true
+	}
+	return result
+}
+
+// ingressHost returns the host the participant's ingresses are served at and whether they are served over TLS, empty
+// if their rules have no host.
+func ingressHost(ctx context.Context, c client.Client, namespace string) (string, bool) {
+	ingresses := &networkingv1.IngressList{}
+	if err := c.List(ctx, ingresses, client.InNamespace(namespace)); err != nil {
+		return "", false
+	}
+	for _, ingress := range ingresses.Items {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host == "" {
+				continue
+			}
+			for _, tls := range ingress.Spec.TLS {
+				for _, host := range tls.Hosts {
+					if host == rule.Host {
+						return rule.Host, true
+					}
+				}
+			}
+			return rule.Host, false
+		}
+	}
+	return "", false
+}
+
+// routeHost returns the host the participant's HTTPRoutes are served at and whether a Gateway they are attached to
+// serves it over TLS, empty if they have no hostname or the cluster has no Gateway API.
+func routeHost(ctx context.Context, c client.Client, namespace string) (string, bool) {
+	routes := &gateway.HTTPRouteList{}
+	if err := c.List(ctx, routes, client.InNamespace(namespace)); err != nil {
+		return "", false
+	}
+	for _, route := range routes.Items {
+		if len(route.Spec.Hostnames) == 0 {
+			continue
+		}
+		host := route.Spec.Hostnames[0]
+		for _, ref := range route.Spec.ParentRefs {
+			key := client.ObjectKey{Namespace: namespace, Name: ref.Name}
+			if ref.Namespace != nil {
+				key.Namespace = *ref.Namespace
+			}
+			parent := &gateway.Gateway{}
+			if err := c.Get(ctx, key, parent); err != nil {
+				continue
+			}
+			for _, listener := range parent.Spec.Listeners {
+				if listener.Protocol == gateway.ProtocolHTTPS && (listener.Hostname == nil || *listener.Hostname == host) {
+					return host, true
+				}
+			}
+		}
+		return host, false
+	}
+	return "", false
+}
+
+// ingressPathPrefix returns the path prefix recorded on the participant's ingresses or HTTPRoutes, /<participant> if
+// none is.
+func ingressPathPrefix(ctx context.Context, c client.Client, namespace string) string {
+	ingresses := &networkingv1.IngressList{}
+	if err := c.List(ctx, ingresses, client.InNamespace(namespace)); err == nil {
+		for _, ingress := range ingresses.Items {
+			if prefix, ok := ingress.Annotations[PathPrefixAnnotation]; ok {
+				return prefix
+			}
+		}
+	}
+	routes := &gateway.HTTPRouteList{}
+	if err := c.List(ctx, routes, client.InNamespace(namespace)); err == nil {
+		for _, route := range routes.Items {
+			if prefix, ok := route.Annotations[PathPrefixAnnotation]; ok {
+				return prefix
+			}
+		}
+	}
+	return "/" + namespace
+}
@@ -0,0 +1,122 @@
+package status
+
+import (
+	"aruba-provisioner/api/gateway"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ingressClient lists the given ingresses and HTTPRoutes and gets the given Gateways.
+type ingressClient struct {
+	client.Client
+	ingresses []networkingv1.Ingress
+	routes    []gateway.HTTPRoute
+	gateways  map[client.ObjectKey]gateway.Gateway
+}
+
+func (c ingressClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	switch list := list.(type) {
+	case *networkingv1.IngressList:
+		list.Items = c.ingresses
+	case *gateway.HTTPRouteList:
+		list.Items = c.routes
+	}
+	return nil
+}
+
+func (c ingressClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	parent, ok := c.gateways[key]
+	if !ok {
+		return apierrors.NewNotFound(gateway.GroupVersion.WithResource("gateways").GroupResource(), key.Name)
+	}
+	*obj.(*gateway.Gateway) = parent
+	return nil
+}
+
+func TestProbeRoutes(t *testing.T) {
+	codes := map[string]int{
+		"/alice/health/api/check/health":            http.StatusOK,
+		"/alice/cp/api/management/v3/assets":        http.StatusUnauthorized,
+		"/alice/cs/api/credentials/v1/participants": http.StatusServiceUnavailable,
+	}
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, ok := codes[r.URL.Path]
+		if !ok {
+			code = http.StatusNotFound
+		}
+		w.WriteHeader(code)
+	}))
+	defer controller.Close()
+
+	checker := NewStatusChecker(context.Background(), ingressClient{}, DefaultCacheTTL)
+	checker.EnableProbes(controller.URL+"/", time.Second)
+	reachable := map[string]bool{}
+	for _, probe := range checker.probeRoutes(context.Background(), "alice") {
+		reachable[probe.Name] = probe.Reachable
+		if !probe.Reachable && probe.Error == "" {
+			t.Errorf("expected an error for the unreachable route %s", probe.Name)
+		}
+	}
+	want := map[string]bool{"health": true, "management": true, "credentials": false, "did": false}
+	for name, expected := range want {
+		if reachable[name] != expected {
+			t.Errorf("route %s: reachable = %v, want %v", name, reachable[name], expected)
+		}
+	}
+}
+
+func TestIngressHost(t *testing.T) {
+	ingress := networkingv1.Ingress{Spec: networkingv1.IngressSpec{
+		Rules: []networkingv1.IngressRule{{Host: "alice.example.com"}},
+		TLS:   []networkingv1.IngressTLS{{Hosts: []string{"alice.example.com"}}},
+	}}
+	if host, tls := ingressHost(context.Background(), ingressClient{ingresses: []networkingv1.Ingress{ingress}}, "alice"); host != "alice.example.com" || !tls {
+		t.Errorf("expected the TLS host, got %s %v", host, tls)
+	}
+	if host, _ := ingressHost(context.Background(), ingressClient{ingresses: []networkingv1.Ingress{{}}}, "alice"); host != "" {
+		t.Errorf("expected no host, got %s", host)
+	}
+}
+
+func TestIngressPathPrefix(t *testing.T) {
+	ingress := networkingv1.Ingress{}
+	ingress.Annotations = map[string]string{PathPrefixAnnotation: ""}
+	if prefix := ingressPathPrefix(context.Background(), ingressClient{ingresses: []networkingv1.Ingress{ingress}}, "alice"); prefix != "" {
+		t.Errorf("expected the recorded prefix, got %q", prefix)
+	}
+	if prefix := ingressPathPrefix(context.Background(), ingressClient{ingresses: []networkingv1.Ingress{{}}}, "alice"); prefix != "/alice" {
+		t.Errorf("expected the default prefix, got %q", prefix)
+	}
+}
+
+func TestRouteHost(t *testing.T) {
+	shared := "gateways"
+	route := gateway.HTTPRoute{Spec: gateway.HTTPRouteSpec{
+		ParentRefs: []gateway.ParentReference{{Name: "public", Namespace: &shared}},
+		Hostnames:  []string{"alice.example.com"},
+	}}
+	route.Annotations = map[string]string{PathPrefixAnnotation: "/connectors/alice"}
+	c := ingressClient{
+		routes: []gateway.HTTPRoute{route},
+		gateways: map[client.ObjectKey]gateway.Gateway{{Namespace: "gateways", Name: "public"}: {Spec: gateway.GatewaySpec{
+			Listeners: []gateway.Listener{{Name: "http", Port: 80, Protocol: gateway.ProtocolHTTP}, {Name: "https", Port: 443, Protocol: gateway.ProtocolHTTPS}},
+		}}},
+	}
+	if host, tls := routeHost(context.Background(), c, "alice"); host != "alice.example.com" || !tls {
+		t.Errorf("expected the TLS host of the route, got %s %v", host, tls)
+	}
+	if prefix := ingressPathPrefix(context.Background(), c, "alice"); prefix != "/connectors/alice" {
+		t.Errorf("expected the prefix recorded on the route, got %q", prefix)
+	}
+	c.gateways = nil
+	if host, tls := routeHost(context.Background(), c, "alice"); host != "alice.example.com" || tls {
+		t.Errorf("expected the plain host without a known gateway, got %s %v", host, tls)
+	}
+}
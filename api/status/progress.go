@@ -0,0 +1,107 @@
+package status
+
+import (
+	"slices"
+	"time"
+)
+
+// Steps of the provisioning of a participant reported in ParticipantStatus.Steps, in order
+const (
+	StepManifestsApplied          = "manifestsApplied"
+	StepDeploymentsReady          = "deploymentsReady"
+	StepParticipantCreated        = "identityParticipantCreated"
+	StepSecretStored              = "secretStored"
+	StepAssetsSeeded              = "assetsSeeded"
+	StepPoliciesSeeded            = "policiesSeeded"
+	StepContractDefinitionsSeeded = "contractDefinitionsSeeded"
+)
+
+var ProgressSteps = []string{StepManifestsApplied, StepDeploymentsReady, StepParticipantCreated, StepSecretStored, StepAssetsSeeded, StepPoliciesSeeded, StepContractDefinitionsSeeded}
+
+// Step states reported in ProgressStep.State
+const (
+	StepPending   = "PENDING"
+	StepRunning   = "RUNNING"
+	StepCompleted = "COMPLETED"
+	StepFailed    = "FAILED"
+	// StepSkipped is reported for steps the provisioning doesn't run, e.g. seed steps of participants without seeding
+	StepSkipped = "SKIPPED"
+)
+
+// ProgressStep is the state of one step of the latest provisioning of a participant.
+type ProgressStep struct {
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// BeginProgress reports all steps of a new provisioning of the participant as PENDING, except for the skipped ones.
+func (s *StatusChecker) BeginProgress(name string, skipped []string) {
+	steps := make([]ProgressStep, len(ProgressSteps))
+	for i, step := range ProgressSteps {
+		steps[i] = ProgressStep{Name: step, State: StepPending}
+		if slices.Contains(skipped, step) {
+			steps[i].State = StepSkipped
+		}
+	}
+	s.mu.Lock()
+	s.progress[name] = steps
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// SetProgress records the state of a step of the participant's provisioning. A RUNNING step records when it started,
+// a COMPLETED or FAILED one when it finished. Steps of runs without a BeginProgress, e.g. resumed seedings after a
+// restart of the provisioner, are added as they are reported.
+func (s *StatusChecker) SetProgress(name string, step string, state string, message string) {
+	now := time.Now()
+	s.mu.Lock()
+	steps := slices.Clone(s.progress[name])
+	i := slices.IndexFunc(steps, func(existing ProgressStep) bool { return existing.Name == step })
+	if i < 0 {
+		// keep the steps in the order they run
+		order := slices.Index(ProgressSteps, step)
+		i = len(steps)
+		for j, existing := range steps {
+			if slices.Index(ProgressSteps, existing.Name) > order {
+				i = j
+				break
+			}
+		}
+		steps = slices.Insert(steps, i, ProgressStep{Name: step})
+	}
+	current := &steps[i]
+	current.State, current.Message = state, message
+	switch state {
+	case StepRunning:
+		current.StartedAt, current.FinishedAt = &now, nil
+	case StepCompleted, StepFailed:
+		if current.StartedAt == nil {
+			current.StartedAt = &now
+		}
+		current.FinishedAt = &now
+	case StepPending, StepSkipped:
+		current.StartedAt, current.FinishedAt = nil, nil
+	}
+	s.progress[name] = steps
+	s.mu.Unlock()
+	s.cache.invalidate(name)
+}
+
+// GetProgress returns the steps of the participant's latest provisioning, nil if it wasn't provisioned since the
+// start.
+func (s *StatusChecker) GetProgress(name string) []ProgressStep {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.progress[name])
+}
+
+// applyProgress adds the steps of the participant's latest provisioning to an evaluated status. Callers hold the lock.
+func (s *StatusChecker) applyProgress(participantStatus ParticipantStatus) ParticipantStatus {
+	if steps, ok := s.progress[participantStatus.Name]; ok {
+		participantStatus.Steps = slices.Clone(steps)
+	}
+	return participantStatus
+}
@@ -0,0 +1,40 @@
+package status
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	checker := NewStatusChecker(context.Background(), nil, DefaultCacheTTL)
+	checker.BeginProgress("alice", []string{StepAssetsSeeded})
+	checker.SetProgress("alice", StepManifestsApplied, StepRunning, "")
+	checker.SetProgress("alice", StepManifestsApplied, StepCompleted, "")
+	checker.SetProgress("alice", StepDeploymentsReady, StepFailed, "deadline exceeded")
+
+	steps := checker.GetProgress("alice")
+	if len(steps) != len(ProgressSteps) {
+		t.Fatalf("expected all steps, got %+v", steps)
+	}
+	if applied := steps[0]; applied.State != StepCompleted || applied.StartedAt == nil || applied.FinishedAt == nil {
+		t.Errorf("unexpected step %+v", applied)
+	}
+	if ready := steps[1]; ready.State != StepFailed || ready.Message != "deadline exceeded" || ready.FinishedAt == nil {
+		t.Errorf("unexpected step %+v", ready)
+	}
+	if steps[2].State != StepPending || steps[4].State != StepSkipped {
+		t.Errorf("unexpected pending and skipped steps %+v", steps)
+	}
+
+	// steps reported without a provisioning, e.g. by a resumed seeding, keep their order
+	checker.SetProgress("bob", StepPoliciesSeeded, StepRunning, "")
+	checker.SetProgress("bob", StepAssetsSeeded, StepCompleted, "")
+	if steps := checker.GetProgress("bob"); len(steps) != 2 || steps[0].Name != StepAssetsSeeded || steps[1].FinishedAt != nil {
+		t.Errorf("unexpected steps %+v", steps)
+	}
+
+	reported := checker.applyProgress(ParticipantStatus{Name: "alice"})
+	if len(reported.Steps) != len(ProgressSteps) || len(reported.Project([]Field{FieldComponents}).Steps) != 0 {
+		t.Errorf("expected the steps to be reported unless projected away, got %+v", reported.Steps)
+	}
+}
@@ -0,0 +1,24 @@
+package status
+
+import "fmt"
+
+// SharedNamespace is the namespace all participants are provisioned into when namespaces can't be created, empty when
+// every participant has a namespace of its own. The objects of a participant are named after it there, e.g.
+// controlplane-acme.
+var SharedNamespace string
+
+// ObjectName returns the name of an object of the participant in the cluster.
+func ObjectName(name string, participant string) string {
+	if SharedNamespace == "" {
+		return name
+	}
+	return name + "-" + participant
+}
+
+// ServiceHost returns the in-cluster host name of a service of the participant.
+func ServiceHost(service string, participant string) string {
+	if SharedNamespace == "" {
+		return fmt.Sprintf("%s.%s.svc.cluster.local", service, participant)
+	}
+	return fmt.Sprintf("%s.%s.svc.cluster.local", ObjectName(service, participant), SharedNamespace)
+}
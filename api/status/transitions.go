@@ -0,0 +1,121 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Transition is a change of the reported status of a participant, e.g. from READY to DEGRADED.
+type Transition struct {
+	Participant string
+	From        ProvisioningStatus
+	To          ProvisioningStatus
+	// Component is the first unhealthy component, empty when the participant recovered
+	Component string
+	Message   string
+	Timestamp time.Time
+}
+
+// notableTransitions are passed to the transition handler, all other changes are only tracked
+var notableTransitions = map[[2]ProvisioningStatus]bool{
+	{StatusReady, StatusDegraded}:      true,
+	{StatusReady, StatusFailed}:        true,
+	{StatusDegraded, StatusFailed}:     true,
+	{StatusProvisioning, StatusFailed}: true,
+	{StatusDegraded, StatusReady}:      true,
+	{StatusFailed, StatusReady}:        true,
+}
+
+// OnTransition registers the handler called with notable status transitions of the participants, e.g. to notify
+// operators of degradations. Call it before statuses are requested or watched.
+func (s *StatusChecker) OnTransition(handler func(Transition)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onTransition = handler
+}
+
+// observe compares the reported status with the previously reported one, records changes in the participant's
+// history and passes notable transitions to the handler. The first status reported for a participant after a restart
+// only sets the baseline.
+func (s *StatusChecker) observe(participantStatus ParticipantStatus) {
+	name := participantStatus.Name
+	s.mu.Lock()
+	if s.reported == nil {
+		s.reported = make(map[string]ProvisioningStatus)
+	}
+	previous, known := s.reported[name]
+	switch participantStatus.Status {
+	case StatusNotFound, StatusDeleted:
+		delete(s.reported, name)
+	default:
+		s.reported[name] = participantStatus.Status
+	}
+	handler := s.onTransition
+	s.mu.Unlock()
+
+	if known && previous == participantStatus.Status {
+		return
+	}
+	component := unhealthyComponent(participantStatus.Components)
+	if s.history != nil {
+		s.history.record(name, HistoryEntry{
+			Status:    participantStatus.Status,
+			Message:   participantStatus.Message,
+			Component: component,
+			Timestamp: participantStatus.LastUpdated,
+		})
+	}
+	if handler == nil || !known || !notableTransitions[[2]ProvisioningStatus{previous, participantStatus.Status}] {
+		return
+	}
+	handler(Transition{
+		Participant: name,
+		From:        previous,
+		To:          participantStatus.Status,
+		Component:   component,
+		Message:     participantStatus.Message,
+		Timestamp:   participantStatus.LastUpdated,
+	})
+}
+
+// unhealthyComponent returns the first component that isn't running, failed ones first.
+func unhealthyComponent(components []ComponentStatus) string {
+	for _, state := range []string{ComponentFailed, ComponentMissing, ComponentDegraded} {
+		for _, component := range components {
+			if component.Status == state {
+				return component.Name
+			}
+		}
+	}
+	return ""
+}
+
+// WatchTransitions requests the status of all managed participants at the interval, so transitions are noticed even
+// when nobody queries the status endpoints. Cached statuses are used while fresh; the deployment and event watches
+// drop them on changes. It blocks until the context is cancelled.
+func (s *StatusChecker) WatchTransitions(ctx context.Context, interval time.Duration) {
+	s.loops.Add(1)
+	defer s.loops.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		namespaces := &corev1.NamespaceList{}
+		if err := s.client.List(ctx, namespaces, client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
+			fmt.Println("Listing managed namespaces failed:", err)
+		}
+		for _, namespace := range namespaces.Items {
+			if _, err := s.GetStatus(ctx, namespace.Name, nil); err != nil && ctx.Err() == nil {
+				fmt.Printf("Checking status of %s failed: %v\n", namespace.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
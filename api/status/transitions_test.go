@@ -0,0 +1,58 @@
+package status
+
+import (
+	"testing"
+)
+
+func TestObserveTransitions(t *testing.T) {
+	checker := &StatusChecker{}
+	var transitions []Transition
+	checker.OnTransition(func(transition Transition) {
+		transitions = append(transitions, transition)
+	})
+
+	degraded := ParticipantStatus{Name: "alice", Status: StatusDegraded, Message: "degraded components: dataplane", Components: []ComponentStatus{
+		{Name: "controlplane", Status: ComponentRunning},
+		{Name: "dataplane", Status: ComponentDegraded},
+	}}
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusReady})
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusReady})
+	checker.observe(degraded)
+	checker.observe(degraded)
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusReady})
+	// the first status of a participant only sets the baseline
+	checker.observe(ParticipantStatus{Name: "bob", Status: StatusFailed})
+	checker.observe(ParticipantStatus{Name: "carol", Status: StatusProvisioning})
+	checker.observe(ParticipantStatus{Name: "carol", Status: StatusSeeding})
+
+	if len(transitions) != 2 {
+		t.Fatalf("expected the degradation and recovery, got %+v", transitions)
+	}
+	if transitions[0].From != StatusReady || transitions[0].To != StatusDegraded || transitions[0].Component != "dataplane" {
+		t.Errorf("unexpected degradation %+v", transitions[0])
+	}
+	if transitions[1].To != StatusReady || transitions[1].Component != "" {
+		t.Errorf("unexpected recovery %+v", transitions[1])
+	}
+}
+
+func TestObserveForgetsDeletedParticipants(t *testing.T) {
+	checker := &StatusChecker{}
+	var transitions []Transition
+	checker.OnTransition(func(transition Transition) {
+		transitions = append(transitions, transition)
+	})
+
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusProvisioning})
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusNotFound})
+	checker.observe(ParticipantStatus{Name: "alice", Status: StatusFailed})
+	if len(transitions) != 0 {
+		t.Errorf("expected a recreated participant to start without baseline, got %+v", transitions)
+	}
+
+	checker.observe(ParticipantStatus{Name: "bob", Status: StatusProvisioning})
+	checker.observe(ParticipantStatus{Name: "bob", Status: StatusFailed, Components: []ComponentStatus{{Name: "postgres", Status: ComponentFailed}}})
+	if len(transitions) != 1 || transitions[0].Component != "postgres" {
+		t.Errorf("expected the failed provisioning to be reported, got %+v", transitions)
+	}
+}
@@ -0,0 +1,167 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const eventWatchRetryInterval = 5 * time.Second
+
+// Warning events arriving within this interval trigger a single re-evaluation per namespace
+const refreshCoalesceInterval = 2 * time.Second
+
+// How often the set of managed namespaces is refreshed while watching
+const managedNamespacesRefreshInterval = time.Minute
+
+// eventWatch holds the state of the event watch across reconnects.
+type eventWatch struct {
+	resourceVersion string
+
+	mu        sync.Mutex
+	managed   map[string]bool
+	refreshed time.Time
+	pending   map[string]bool
+}
+
+// WatchEvents re-evaluates cached participant statuses as soon as Warning events arrive in namespaces managed by the
+// provisioner, instead of waiting for the cache entry to expire. Events are only delivered cluster-wide by the API
+// server, so events of other namespaces are dropped before any work is done. It blocks until the context is
+// cancelled.
+func (s *StatusChecker) WatchEvents(ctx context.Context, c client.WithWatch) {
+	s.loops.Add(2)
+	defer s.loops.Done()
+	w := &eventWatch{pending: make(map[string]bool)}
+	go func() {
+		defer s.loops.Done()
+		s.refreshLoop(ctx, w)
+	}()
+	for {
+		if err := s.watchEvents(ctx, c, w); err != nil {
+			fmt.Println("Event watch failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventWatchRetryInterval):
+		}
+	}
+}
+
+func (s *StatusChecker) watchEvents(ctx context.Context, c client.WithWatch, w *eventWatch) error {
+	warnings := client.MatchingFields{"type": corev1.EventTypeWarning}
+	if w.resourceVersion == "" {
+		// start from the current state instead of replaying all existing warnings
+		list := &corev1.EventList{}
+		if err := c.List(ctx, list, warnings, client.Limit(1)); err != nil {
+			return err
+		}
+		w.resourceVersion = list.ResourceVersion
+	}
+
+	watcher, err := c.Watch(ctx, &corev1.EventList{}, warnings, &client.ListOptions{
+		Raw: &metav1.ListOptions{ResourceVersion: w.resourceVersion, AllowWatchBookmarks: true},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-watcher.ResultChan():
+			if !ok {
+				// the API server closes watches periodically, the caller re-establishes it
+				return nil
+			}
+			switch result.Type {
+			case watch.Error:
+				err := apierrors.FromObject(result.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					// the resource version is too old to resume from
+					w.resourceVersion = ""
+				}
+				return err
+			case watch.Bookmark:
+				if event, ok := result.Object.(*corev1.Event); ok {
+					w.resourceVersion = event.ResourceVersion
+				}
+			case watch.Added, watch.Modified:
+				if event, ok := result.Object.(*corev1.Event); ok {
+					w.resourceVersion = event.ResourceVersion
+					s.onWarningEvent(ctx, c, w, event)
+				}
+			}
+		}
+	}
+}
+
+// onWarningEvent schedules a refresh of the cached status of the namespace the event belongs to. Namespaces without
+// a cached status are skipped, their next status request evaluates the live state anyway.
+func (s *StatusChecker) onWarningEvent(ctx context.Context, c client.Client, w *eventWatch, event *corev1.Event) {
+	name := event.Namespace
+	if !s.cache.has(name) || !w.isManaged(ctx, c, name) {
+		return
+	}
+	w.mu.Lock()
+	w.pending[name] = true
+	w.mu.Unlock()
+}
+
+// isManaged checks the namespace against the managed namespaces, listing them again when the list is stale.
+func (w *eventWatch) isManaged(ctx context.Context, c client.Client, name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.managed == nil || time.Since(w.refreshed) > managedNamespacesRefreshInterval {
+		namespaces := &corev1.NamespaceList{}
+		if err := c.List(ctx, namespaces, client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
+			fmt.Println("Listing managed namespaces failed:", err)
+			return w.managed[name]
+		}
+		w.managed = make(map[string]bool, len(namespaces.Items))
+		for _, namespace := range namespaces.Items {
+			w.managed[namespace.Name] = true
+		}
+		w.refreshed = time.Now()
+	}
+	return w.managed[name]
+}
+
+// refreshLoop re-evaluates the namespaces that received warnings, once per namespace and interval.
+func (s *StatusChecker) refreshLoop(ctx context.Context, w *eventWatch) {
+	ticker := time.NewTicker(refreshCoalesceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		pending := w.pending
+		w.pending = make(map[string]bool)
+		w.mu.Unlock()
+
+		for name := range pending {
+			version := s.cache.version(name)
+			participantStatus, err := s.evaluate(ctx, name, AllFields)
+			if err != nil {
+				fmt.Printf("Refreshing status of %s after warning event failed: %v\n", name, err)
+				s.cache.invalidate(name)
+				continue
+			}
+			s.cache.set(name, participantStatus, AllFields, version)
+			s.observe(s.applyOperation(participantStatus, s.sharedOperation(ctx, name)))
+		}
+	}
+}
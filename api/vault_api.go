@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type VaultApi interface {
+	PutSecret(ctx context.Context, key string, value string) error
+	PutSecretIn(ctx context.Context, namespace string, mount string, path string, value string) error
+}
+
+type vaultSecret struct {
+	Data struct {
+		Content string `json:"content"`
+	} `json:"data"`
+}
+
+// PutSecret writes the secret to the KV v2 engine mounted at secret/ of a HashiCorp Vault, in the content field EDC
+// components read secrets from. The client's ApiKey is the Vault token.
+func (i *ApiClient) PutSecret(ctx context.Context, key string, value string) error {
+	secret := vaultSecret{}
+	secret.Data.Content = value
+	_, err := i.vault(ctx, "", http.MethodPost, "secret/data/"+url.PathEscape(key), secret)
+	return err
+}
+
+// Requests managing a Vault, see https://developer.hashicorp.com/vault/api-docs. The namespace arguments select a
+// Vault Enterprise namespace, empty for the root namespace.
+
+func (i *ApiClient) vault(ctx context.Context, namespace string, method string, path string, body any) ([]byte, error) {
+	headers := map[string]string{"X-Vault-Token": i.ApiKey}
+	if namespace != "" {
+		headers["X-Vault-Namespace"] = namespace
+	}
+	return i.Do(ctx, Request{Method: method, Path: "/v1/" + path, Headers: headers, Body: body})
+}
+
+// PutSecretIn writes the secret to the KV v2 engine at the mount, under a path of slash separated segments.
+func (i *ApiClient) PutSecretIn(ctx context.Context, namespace string, mount string, path string, value string) error {
+	secret := vaultSecret{}
+	secret.Data.Content = value
+	_, err := i.vault(ctx, namespace, http.MethodPost, mount+"/data/"+escapeSegments(path), secret)
+	return err
+}
+
+// DeleteSecretsIn destroys all versions of the secrets directly below the folder of the KV v2 engine at the mount.
+func (i *ApiClient) DeleteSecretsIn(ctx context.Context, namespace string, mount string, folder string) error {
+	response, err := i.vault(ctx, namespace, http.MethodGet, mount+"/metadata/"+escapeSegments(folder)+"/?list=true", nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(response, &list); err != nil {
+		return err
+	}
+	for _, key := range list.Data.Keys {
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		if _, err := i.vault(ctx, namespace, http.MethodDelete, mount+"/metadata/"+escapeSegments(folder+"/"+key), nil); err != nil && !IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateVaultNamespace creates the child namespace of the parent namespace unless it exists.
+func (i *ApiClient) CreateVaultNamespace(ctx context.Context, parent string, name string) error {
+	_, err := i.vault(ctx, parent, http.MethodGet, "sys/namespaces/"+url.PathEscape(name), nil)
+	if !IsNotFound(err) {
+		return err
+	}
+	_, err = i.vault(ctx, parent, http.MethodPost, "sys/namespaces/"+url.PathEscape(name), map[string]any{})
+	return err
+}
+
+// DeleteVaultNamespace deletes the child namespace of the parent namespace with everything in it.
+func (i *ApiClient) DeleteVaultNamespace(ctx context.Context, parent string, name string) error {
+	_, err := i.vault(ctx, parent, http.MethodDelete, "sys/namespaces/"+url.PathEscape(name), nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// EnableKvEngine mounts a KV v2 engine at the path unless the path is in use.
+func (i *ApiClient) EnableKvEngine(ctx context.Context, namespace string, path string) error {
+	_, err := i.vault(ctx, namespace, http.MethodPost, "sys/mounts/"+escapeSegments(path), map[string]any{
+		"type":    "kv",
+		"options": map[string]string{"version": "2"},
+	})
+	return ignorePathInUse(err)
+}
+
+// EnableAppRoleAuth enables the AppRole auth method at auth/approle unless it is enabled.
+func (i *ApiClient) EnableAppRoleAuth(ctx context.Context, namespace string) error {
+	_, err := i.vault(ctx, namespace, http.MethodPost, "sys/auth/approle", map[string]string{"type": "approle"})
+	return ignorePathInUse(err)
+}
+
+// ignorePathInUse treats Vault's response to enabling an engine at a path in use as success.
+func ignorePathInUse(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest && strings.Contains(statusErr.Body, "already in use") {
+		return nil
+	}
+	return err
+}
+
+// PutVaultPolicy creates or replaces the ACL policy, given in HCL.
+func (i *ApiClient) PutVaultPolicy(ctx context.Context, namespace string, name string, policy string) error {
+	_, err := i.vault(ctx, namespace, http.MethodPut, "sys/policies/acl/"+url.PathEscape(name), map[string]string{"policy": policy})
+	return err
+}
+
+func (i *ApiClient) DeleteVaultPolicy(ctx context.Context, namespace string, name string) error {
+	_, err := i.vault(ctx, namespace, http.MethodDelete, "sys/policies/acl/"+url.PathEscape(name), nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// AppRole is a role of the AppRole auth method.
+type AppRole struct {
+	Policies []string `json:"token_policies"`
+	// TokenPeriod makes the issued tokens periodic, they don't expire as long as they are renewed within the period
+	TokenPeriod string `json:"token_period,omitempty"`
+}
+
+// PutAppRole creates or updates the role.
+func (i *ApiClient) PutAppRole(ctx context.Context, namespace string, name string, role AppRole) error {
+	_, err := i.vault(ctx, namespace, http.MethodPost, "auth/approle/role/"+url.PathEscape(name), role)
+	return err
+}
+
+func (i *ApiClient) DeleteAppRole(ctx context.Context, namespace string, name string) error {
+	_, err := i.vault(ctx, namespace, http.MethodDelete, "auth/approle/role/"+url.PathEscape(name), nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// AppRoleCredentials returns the role ID of the role and a newly generated secret ID.
+func (i *ApiClient) AppRoleCredentials(ctx context.Context, namespace string, name string) (string, string, error) {
+	response, err := i.vault(ctx, namespace, http.MethodGet, "auth/approle/role/"+url.PathEscape(name)+"/role-id", nil)
+	if err != nil {
+		return "", "", err
+	}
+	var roleId struct {
+		Data struct {
+			RoleId string `json:"role_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(response, &roleId); err != nil {
+		return "", "", err
+	}
+	response, err = i.vault(ctx, namespace, http.MethodPost, "auth/approle/role/"+url.PathEscape(name)+"/secret-id", map[string]any{})
+	if err != nil {
+		return "", "", err
+	}
+	var secretId struct {
+		Data struct {
+			SecretId string `json:"secret_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(response, &secretId); err != nil {
+		return "", "", err
+	}
+	return roleId.Data.RoleId, secretId.Data.SecretId, nil
+}
+
+// AppRoleLogin logs in with the role and secret ID and returns the issued token.
+func (i *ApiClient) AppRoleLogin(ctx context.Context, namespace string, roleId string, secretId string) (string, error) {
+	response, err := i.vault(ctx, namespace, http.MethodPost, "auth/approle/login", map[string]string{"role_id": roleId, "secret_id": secretId})
+	if err != nil {
+		return "", err
+	}
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.Unmarshal(response, &login); err != nil {
+		return "", err
+	}
+	if login.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault issued no token for role %s", roleId)
+	}
+	return login.Auth.ClientToken, nil
+}
+
+func escapeSegments(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Seeding fails when the participant's APIs don't answer within this period once its deployments are ready, set with
+// --api-readiness-timeout, 0 seeds right away
+var apiReadinessTimeout = 5 * time.Minute
+
+// apiProbe checks whether one of the participant's APIs answers through the ingress.
+type apiProbe struct {
+	name  string
+	url   string
+	probe func(ctx context.Context) error
+}
+
+// apiProbes returns the probes of the APIs seeding calls, those of the components the participant's stack includes.
+func apiProbes(definition ParticipantDefinition, clients seedingClients, creds participantCredentials) []apiProbe {
+	var probes []apiProbe
+	if definition.hasComponent("controlplane") {
+		mgmtApi := managementApi(definition, clients, creds)
+		probes = append(probes, apiProbe{name: "management", url: mgmtApi.BaseUrl, probe: func(ctx context.Context) error {
+			_, err := mgmtApi.QueryAssets(ctx, assetQuerySpec)
+			return err
+		}})
+	}
+	if definition.hasComponent("identityhub") {
+		identityHub := identityApi(definition, clients, creds)
+		probes = append(probes, apiProbe{name: "identity", url: identityHub.BaseUrl, probe: func(ctx context.Context) error {
+			_, err := identityHub.ListParticipants(ctx)
+			return err
+		}})
+	}
+	return probes
+}
+
+// apiAnswers reports whether the API handled the probe. Connection errors, 5xx responses and the 404 of a route whose
+// backend hasn't registered its paths yet mean it is still starting, other errors are left to the seed steps.
+func apiAnswers(err error) bool {
+	var statusErr *api.StatusError
+	if errors.As(err, &statusErr) {
+		return !api.IsNotFound(err) && !api.IsTransient(err)
+	}
+	return err == nil
+}
+
+// waitForApis waits until the management and identity APIs answer through the ingress. Deployments report ready
+// replicas before the APIs of their runtimes started, so the first seed requests would be refused.
+func waitForApis(ctx context.Context, definition ParticipantDefinition, clients seedingClients, creds participantCredentials, statusChecker *status.StatusChecker) error {
+	if apiReadinessTimeout <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, apiReadinessTimeout)
+	defer cancel()
+	for _, probe := range apiProbes(definition, clients, creds) {
+		statusChecker.SetSeeding(definition.ParticipantName, status.SeedingPending, "waiting for the "+probe.name+" API")
+		for {
+			err := probe.probe(ctx)
+			if apiAnswers(err) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				err = unreachableRemediation(definition, fmt.Errorf("%s API at %s not ready within %s: %w", probe.name, probe.url, apiReadinessTimeout, err))
+				statusChecker.SetSeeding(definition.ParticipantName, status.SeedingFailed, err.Error())
+				return err
+			case <-time.After(readinessPollInterval):
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startingApis answers like the ingress of a participant whose runtimes are starting: unavailable, then not found,
+// until the given number of requests was refused.
+type startingApis struct {
+	mu       sync.Mutex
+	refused  map[string]int
+	starting int
+}
+
+func (s *startingApis) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api := "management"
+	if strings.Contains(r.URL.Path, "/cs/") {
+		api = "identity"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refused[api] < s.starting {
+		s.refused[api]++
+		if s.refused[api] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`[]`))
+}
+
+func TestWaitForApis(t *testing.T) {
+	previous := readinessPollInterval
+	readinessPollInterval = time.Millisecond
+	t.Cleanup(func() { readinessPollInterval = previous })
+
+	apis := &startingApis{refused: map[string]int{}, starting: 3}
+	server := httptest.NewServer(apis)
+	defer server.Close()
+	definition := ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: server.URL}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+
+	if err := waitForApis(context.Background(), definition, seedingClients{}, participantCredentials{}, checker); err != nil {
+		t.Fatal(err)
+	}
+	if apis.refused["management"] != 3 || apis.refused["identity"] != 3 {
+		t.Errorf("expected both APIs to be polled until they answered, got %v", apis.refused)
+	}
+
+	// only the APIs of the selected components are awaited
+	apis.refused = map[string]int{}
+	definition.Components = []string{"identityhub"}
+	if err := waitForApis(context.Background(), definition, seedingClients{}, participantCredentials{}, checker); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := apis.refused["management"]; ok {
+		t.Errorf("management API polled without a controlplane: %v", apis.refused)
+	}
+}
+
+func TestWaitForApisTimesOut(t *testing.T) {
+	previousInterval, previousTimeout := readinessPollInterval, apiReadinessTimeout
+	readinessPollInterval, apiReadinessTimeout = time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { readinessPollInterval, apiReadinessTimeout = previousInterval, previousTimeout })
+
+	server := httptest.NewServer(&startingApis{refused: map[string]int{}, starting: 1 << 30})
+	defer server.Close()
+	definition := ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: server.URL}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+
+	err := waitForApis(context.Background(), definition, seedingClients{}, participantCredentials{}, checker)
+	if err == nil || !strings.Contains(err.Error(), "management API") {
+		t.Fatalf("expected the management API to time out, got %v", err)
+	}
+	if seeding := checker.GetSeeding("alice"); seeding.State != status.SeedingFailed {
+		t.Errorf("expected seeding to be reported failed, got %+v", seeding)
+	}
+}
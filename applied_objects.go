@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Operation of an applied object whose previous state wasn't compared, otherwise it's the action of its objectChange
+const operationApplied = "applied"
+
+// appliedObject identifies an object a job applied, with the resource version the API server returned for it.
+type appliedObject struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Operation is created, updated or unchanged, or applied when the object wasn't compared with its previous state
+	Operation string `json:"operation"`
+	// ResourceVersion is empty for objects that were only rendered, e.g. in a dry run
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// appliedObjectOf describes the object after it was applied.
+func appliedObjectOf(obj *unstructured.Unstructured) appliedObject {
+	gvk := obj.GroupVersionKind()
+	return appliedObject{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName(),
+		Operation: operationApplied, ResourceVersion: obj.GetResourceVersion()}
+}
+
+// withOperations sets the operation of the objects from the changes applying them made.
+func withOperations(objects []appliedObject, changes []objectChange) []appliedObject {
+	actions := make(map[[2]string]string, len(changes))
+	for _, change := range changes {
+		actions[[2]string{change.Kind, change.Name}] = change.Action
+	}
+	for i, object := range objects {
+		if action, ok := actions[[2]string{object.Kind, object.Name}]; ok {
+			objects[i].Operation = action
+		}
+	}
+	return objects
+}
+
+// appliedObjects lists the objects a job applied, in the order they were applied.
+type appliedObjects []appliedObject
+
+// UnmarshalJSON also reads the kinds by name that jobs shared by earlier versions list their objects as.
+func (o *appliedObjects) UnmarshalJSON(data []byte) error {
+	var kinds map[string]string
+	if err := json.Unmarshal(data, &kinds); err != nil {
+		return json.Unmarshal(data, (*[]appliedObject)(o))
+	}
+	*o = make(appliedObjects, 0, len(kinds))
+	for name, kind := range kinds {
+		*o = append(*o, appliedObject{Kind: kind, Name: name, Operation: operationApplied})
+	}
+	sort.Slice(*o, func(i, j int) bool { return (*o)[i].Name < (*o)[j].Name })
+	return nil
+}
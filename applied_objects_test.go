@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplyYamlListsAppliedObjects(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ${PARTICIPANT_NAME}
+  namespace: ${PARTICIPANT_NAME}
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ${PARTICIPANT_NAME}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+  namespace: ${PARTICIPANT_NAME}
+`
+	name := "alice"
+	apply := func(_ client.Client, _ context.Context, object client.Object) error {
+		object.SetResourceVersion("7")
+		return nil
+	}
+	objects, err := applyYaml(&name, new(string), nil, context.Background(), manifests, apply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []appliedObject{
+		{Version: "v1", Kind: "ConfigMap", Namespace: "alice", Name: "alice", Operation: operationApplied, ResourceVersion: "7"},
+		{Version: "v1", Kind: "Namespace", Name: "alice", Operation: operationApplied, ResourceVersion: "7"},
+		{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "alice", Name: "controlplane", Operation: operationApplied, ResourceVersion: "7"},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, objects)
+	}
+	for i := range expected {
+		if objects[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], objects[i])
+		}
+	}
+
+	objects = withOperations(objects, []objectChange{{Kind: "ConfigMap", Name: "alice", Action: changeUpdated}, {Kind: "Deployment", Name: "controlplane", Action: changeUnchanged}})
+	if objects[0].Operation != changeUpdated || objects[1].Operation != operationApplied || objects[2].Operation != changeUnchanged {
+		t.Errorf("expected the operations of the changes, got %+v", objects)
+	}
+}
+
+func TestAppliedObjectsReadSharedLegacyJobs(t *testing.T) {
+	job := &provisioningJob{}
+	if err := json.Unmarshal([]byte(`{"id":"42","resources":{"controlplane":"Deployment","alice":"Namespace"}}`), job); err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Resources) != 2 || job.Resources[0].Name != "alice" || job.Resources[0].Kind != "Namespace" || job.Resources[1].Kind != "Deployment" {
+		t.Errorf("unexpected resources %+v", job.Resources)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"43","resources":[{"version":"v1","kind":"Namespace","name":"bob","operation":"created"}]}`), job); err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Resources) != 1 || job.Resources[0].Name != "bob" || job.Resources[0].Operation != changeCreated {
+		t.Errorf("unexpected resources %+v", job.Resources)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Label marking the ConfigMaps holding audit entries
+	auditLabel = "aruba-provisioner/audit"
+	// Label with the participant an audit entry is about
+	auditParticipantLabel = status.ParticipantLabel
+	auditEntryKey         = "entry.json"
+)
+
+// Audited actions
+const (
+	auditCreate    = "create"
+	auditUpdate    = "update"
+	auditRollback  = "rollback"
+	auditDelete    = "delete"
+	auditSeed      = "seed"
+	auditImport    = "import"
+	auditReconcile = "reconcile"
+	auditCancel    = "cancel"
+	auditRestore   = "restore"
+	auditRotateDid = "rotate-did"
+	// auditSchedule records a provisioning deferred to its notBefore time, auditUnschedule its cancellation
+	auditSchedule   = "schedule"
+	auditUnschedule = "unschedule"
+	// auditReadCredentials records who read the API keys of a participant
+	auditReadCredentials = "read-credentials"
+)
+
+// auditEntry records who changed which participant, when and how.
+type auditEntry struct {
+	Id          string    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Action      string    `json:"action"`
+	Participant string    `json:"participant"`
+	// Actor is the subject of the caller's credentials, "anonymous" without authentication
+	Actor      string                 `json:"actor"`
+	Tenant     string                 `json:"tenant,omitempty"`
+	RemoteAddr string                 `json:"remoteAddr"`
+	JobId      string                 `json:"jobId,omitempty"`
+	Revision   int                    `json:"revision,omitempty"`
+	Definition *ParticipantDefinition `json:"definition,omitempty"`
+}
+
+// auditLog stores audit entries as immutable ConfigMaps in the provisioner's namespace, so they outlive the
+// participants they are about and can't be altered once written.
+type auditLog struct {
+	client    client.Client
+	namespace string
+}
+
+// record writes an audit entry for the request. The entry belongs to the caller's tenant unless it names the tenant
+// owning the participant. Failures are logged, as the action was already started.
+func (a *auditLog) record(c *fiber.Ctx, ctx context.Context, entry auditEntry) {
+	entry.Timestamp = time.Now().UTC()
+	entry.Actor = "anonymous"
+	if caller, ok := c.Locals(principalKey).(*principal); ok {
+		entry.Actor = caller.Subject
+		if entry.Tenant == "" {
+			entry.Tenant = caller.Tenant
+		}
+	}
+	entry.RemoteAddr = c.IP()
+	if entry.Definition != nil {
+		definition := entry.Definition.redacted()
+		entry.Definition = &definition
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		fmt.Printf("AUDIT: recording %s of %s failed: %v\n", entry.Action, entry.Participant, err)
+		return
+	}
+	entry.Id = "audit-" + strconv.FormatInt(entry.Timestamp.UnixNano(), 10) + "-" + hex.EncodeToString(suffix)
+	if err := a.write(ctx, entry); err != nil {
+		fmt.Printf("AUDIT: recording %s of %s failed: %v\n", entry.Action, entry.Participant, err)
+	}
+}
+
+func (a *auditLog) write(ctx context.Context, entry auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{auditLabel: "true", auditParticipantLabel: entry.Participant}
+	if entry.Tenant != "" {
+		labels[tenantLabel] = entry.Tenant
+	}
+	immutable := true
+	return a.client.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: entry.Id, Namespace: a.namespace, Labels: labels},
+		Immutable:  &immutable,
+		Data:       map[string]string{auditEntryKey: string(data)},
+	})
+}
+
+// query returns the audit entries of the participant, of all participants if empty, oldest first. Scoped callers
+// only see the entries of their tenant.
+func (a *auditLog) query(ctx context.Context, participant string, tenant string) ([]auditEntry, error) {
+	selector := client.MatchingLabels{auditLabel: "true"}
+	if participant != "" {
+		selector[auditParticipantLabel] = participant
+	}
+	if tenant != "" {
+		selector[tenantLabel] = tenant
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := a.client.List(ctx, configMaps, client.InNamespace(a.namespace), selector); err != nil {
+		return nil, err
+	}
+	entries := make([]auditEntry, 0, len(configMaps.Items))
+	for _, configMap := range configMaps.Items {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(configMap.Data[auditEntryKey]), &entry); err != nil {
+			fmt.Printf("skipping audit entry %s: %v\n", configMap.Name, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// Most audit entries a page holds
+const maxAuditPageSize = 500
+
+// handler lists the audit entries in the caller's scope, oldest first and a page at a time when a limit is given.
+func (a *auditLog) handler(ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 0)
+		if limit < 0 || limit > maxAuditPageSize {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditPageSize))
+		}
+		offset, err := pageOffset(c)
+		if err != nil {
+			return err
+		}
+		entries, err := a.query(ctx, c.Query("participant"), tenantOf(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(offsetPage(c, entries, offset, limit))
+	}
+}
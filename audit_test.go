@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configMapClient is an in-memory client supporting just enough ConfigMap operations for the audit log.
+type configMapClient struct {
+	client.Client
+	configMaps []corev1.ConfigMap
+}
+
+func (c *configMapClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.configMaps = append(c.configMaps, *obj.(*corev1.ConfigMap).DeepCopy())
+	return nil
+}
+
+func (c *configMapClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	configMaps := list.(*corev1.ConfigMapList)
+	for _, configMap := range c.configMaps {
+		if options.Namespace != "" && configMap.Namespace != options.Namespace {
+			continue
+		}
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labelSet(configMap.Labels)) {
+			continue
+		}
+		configMaps.Items = append(configMaps.Items, configMap)
+	}
+	return nil
+}
+
+func TestAuditLog(t *testing.T) {
+	kube := &configMapClient{}
+	audit := &auditLog{client: kube, namespace: "mvd-provisioner"}
+	// the fake keeps the labels, which would otherwise point into buffers fiber reuses
+	app := fiber.New(fiber.Config{Immutable: true})
+	app.Use(func(c *fiber.Ctx) error {
+		if tenant := c.Get("x-tenant"); tenant != "" {
+			c.Locals(principalKey, &principal{Subject: "key-" + tenant, Tenant: tenant})
+		}
+		return c.Next()
+	})
+	app.Post("/:participant/:action", func(c *fiber.Ctx) error {
+		audit.record(c, context.Background(), auditEntry{Action: c.Params("action"), Participant: c.Params("participant"), Tenant: c.Query("owner")})
+		return nil
+	})
+	app.Get("/audit", audit.handler(context.Background()))
+
+	send := func(method string, target string, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("x-tenant", tenant)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		recorder.Code = resp.StatusCode
+		_, _ = recorder.Body.ReadFrom(resp.Body)
+		return recorder
+	}
+	query := func(target string, tenant string) []auditEntry {
+		var page listPage[auditEntry]
+		if err := json.Unmarshal(send("GET", target, tenant).Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		return page.Items
+	}
+
+	send("POST", "/alice/"+auditCreate, "acme")
+	send("POST", "/bob/"+auditCreate, "")
+	// an unscoped caller deleting a participant of acme
+	send("POST", "/alice/"+auditDelete+"?owner=acme", "")
+
+	for _, configMap := range kube.configMaps {
+		if configMap.Immutable == nil || !*configMap.Immutable {
+			t.Errorf("audit entry %s is mutable", configMap.Name)
+		}
+	}
+
+	all := query("/audit", "")
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(all))
+	}
+	if all[0].Actor != "key-acme" || all[1].Actor != "anonymous" {
+		t.Errorf("unexpected actors %s, %s", all[0].Actor, all[1].Actor)
+	}
+
+	alice := query("/audit?participant=alice", "")
+	if len(alice) != 2 || alice[0].Action != auditCreate || alice[1].Action != auditDelete {
+		t.Errorf("unexpected entries of alice: %+v", alice)
+	}
+
+	paged := send("GET", "/audit?limit=2&continue=2", "")
+	var last listPage[auditEntry]
+	if err := json.Unmarshal(paged.Body.Bytes(), &last); err != nil {
+		t.Fatal(err)
+	}
+	if len(last.Items) != 1 || last.Total != 3 || last.Page != 2 || last.Continue != "" || last.Items[0].Action != auditDelete {
+		t.Errorf("expected the last page to hold the deletion, got %+v", last)
+	}
+
+	// acme sees the entries of its participants, also those of other callers
+	scoped := query("/audit", "acme")
+	if len(scoped) != 2 {
+		t.Errorf("expected 2 entries for acme, got %+v", scoped)
+	}
+	if entries := query("/audit", "other"); len(entries) != 0 {
+		t.Errorf("expected no entries for another tenant, got %+v", entries)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Key of the authenticated principal in the request locals
+const principalKey = "principal"
+
+// principal is the caller of an authenticated request.
+type principal struct {
+	// Subject is the sub claim of OIDC tokens, "api-key:" followed by the key's fingerprint or "admin" for static keys,
+	// the common name of client certificates prefixed with "cert:"
+	Subject string
+	// Tenant scopes the caller to the participants it provisioned, empty for unscoped callers
+	Tenant string
+	Claims map[string]any
+}
+
+// staticKey is a static API key, optionally scoped to a tenant by configuring it as "tenant:key".
+type staticKey struct {
+	tenant string
+	key    string
+	// subject tells the callers with different keys apart, e.g. in rate limits, without revealing the key
+	subject string
+}
+
+// apiAuth authenticates API requests with static API keys or OIDC bearer tokens. Requests carrying the admin key are
+// accepted as well. Without keys and issuer configured, authentication is disabled.
+type apiAuth struct {
+	apiKeys  []staticKey
+	adminKey string
+	oidc     *oidcVerifier
+	// tenantClaim names the OIDC claim holding the caller's tenant, tokens without it are rejected when set
+	tenantClaim string
+	// exempt holds path prefixes that are served without authentication
+	exempt []string
+	// clientCertificates accepts client certificates verified by the TLS listener
+	clientCertificates bool
+}
+
+func newApiAuth(apiKeys string, adminKey string, oidcIssuer string, oidcAudience string, tenantClaim string, exempt string) *apiAuth {
+	auth := &apiAuth{adminKey: adminKey, tenantClaim: tenantClaim, exempt: splitList(exempt)}
+	for _, entry := range splitList(apiKeys) {
+		tenant, key, scoped := strings.Cut(entry, ":")
+		if !scoped {
+			tenant, key = "", entry
+		}
+		fingerprint := sha256.Sum256([]byte(key))
+		auth.apiKeys = append(auth.apiKeys, staticKey{tenant: tenant, key: key, subject: "api-key:" + hex.EncodeToString(fingerprint[:8])})
+	}
+	if oidcIssuer != "" {
+		auth.oidc = newOidcVerifier(oidcIssuer, oidcAudience)
+	}
+	return auth
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (a *apiAuth) enabled() bool {
+	return len(a.apiKeys) > 0 || a.oidc != nil || a.clientCertificates
+}
+
+func (a *apiAuth) middleware() fiber.Handler {
+	if !a.enabled() {
+		fmt.Println("No API keys or OIDC issuer configured, the API is not authenticated")
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	return func(c *fiber.Ctx) error {
+		for _, prefix := range a.exempt {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+		caller, err := a.authenticate(c)
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="aruba-provisioner"`)
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		c.Locals(principalKey, caller)
+		return c.Next()
+	}
+}
+
+func (a *apiAuth) authenticate(c *fiber.Ctx) (*principal, error) {
+	if key := c.Get("x-api-key"); key != "" && a.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(a.adminKey)) == 1 {
+		return &principal{Subject: "admin"}, nil
+	}
+	if a.clientCertificates {
+		// the listener only accepts certificates issued by the client CA
+		if state := c.Context().TLSConnectionState(); state != nil && len(state.VerifiedChains) > 0 {
+			return &principal{Subject: "cert:" + state.VerifiedChains[0][0].Subject.CommonName}, nil
+		}
+	}
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.key)) == 1 {
+			return &principal{Subject: key.subject, Tenant: key.tenant}, nil
+		}
+	}
+	if a.oidc == nil || strings.Count(token, ".") != 2 {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	claims, err := a.oidc.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	subject, _ := claims["sub"].(string)
+	var tenant string
+	if a.tenantClaim != "" {
+		if tenant, _ = claims[a.tenantClaim].(string); tenant == "" {
+			return nil, fmt.Errorf("token has no %s claim", a.tenantClaim)
+		}
+	}
+	return &principal{Subject: subject, Tenant: tenant, Claims: claims}, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestIssuer serves the discovery document and signing key of an OIDC provider and returns a function signing
+// tokens with the given claims.
+func newTestIssuer(t *testing.T) (*httptest.Server, func(claims map[string]any) string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/jwks"})
+		case "/jwks":
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sign := func(claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	return server, sign
+}
+
+func TestApiAuth(t *testing.T) {
+	issuer, sign := newTestIssuer(t)
+	valid := map[string]any{"iss": issuer.URL, "aud": "provisioner", "sub": "ci", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := map[string]any{"iss": issuer.URL, "aud": "provisioner", "sub": "ci", "exp": time.Now().Add(-time.Hour).Unix()}
+	otherAudience := map[string]any{"iss": issuer.URL, "aud": "other", "sub": "ci", "exp": time.Now().Add(time.Hour).Unix()}
+
+	app := fiber.New()
+	app.Use("/api/v1", newApiAuth("static-key,acme:acme-key", "admin-key", issuer.URL, "provisioner", "", "/api/v1/callbacks/").middleware())
+	app.Get("/api/v1/resources", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(principalKey).(*principal).Subject)
+	})
+	app.Post("/api/v1/callbacks/acme/token", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"no credentials", "GET", "/api/v1/resources", nil, fiber.StatusUnauthorized},
+		{"static key", "GET", "/api/v1/resources", map[string]string{"Authorization": "Bearer static-key"}, fiber.StatusOK},
+		{"wrong key", "GET", "/api/v1/resources", map[string]string{"Authorization": "Bearer other-key"}, fiber.StatusUnauthorized},
+		{"admin key", "GET", "/api/v1/resources", map[string]string{"x-api-key": "admin-key"}, fiber.StatusOK},
+		{"oidc token", "GET", "/api/v1/resources", map[string]string{"Authorization": "Bearer " + sign(valid)}, fiber.StatusOK},
+		{"expired token", "GET", "/api/v1/resources", map[string]string{"Authorization": "Bearer " + sign(expired)}, fiber.StatusUnauthorized},
+		{"other audience", "GET", "/api/v1/resources", map[string]string{"Authorization": "Bearer " + sign(otherAudience)}, fiber.StatusUnauthorized},
+		{"exempt path", "POST", "/api/v1/callbacks/acme/token", nil, fiber.StatusNoContent},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest(tt.method, tt.path, nil)
+		for k, v := range tt.headers {
+			request.Header.Set(k, v)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, response.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestTamperedTokenRejected(t *testing.T) {
+	issuer, sign := newTestIssuer(t)
+	token := sign(map[string]any{"iss": issuer.URL, "sub": "ci", "exp": time.Now().Add(time.Hour).Unix()})
+	verifier := newOidcVerifier(issuer.URL, "")
+	if _, err := verifier.verify(token); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	payload, _ := json.Marshal(map[string]any{"iss": issuer.URL, "sub": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if _, err := verifier.verify(tampered); err == nil {
+		t.Error("token with modified claims accepted")
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Label marking the Secrets recording background work, which keep the definitions with their API keys
+const workLabel = "aruba-provisioner/background-work"
+
+// Keys of the Secret recording background work
+const (
+	workDefinitionKey = "definition.json"
+	workTaskKey       = "task.json"
+)
+
+const (
+	// Interval the replicas renew the records of their background work at and the leader resumes interrupted work at
+	workHeartbeatInterval = 30 * time.Second
+	// Work of another replica whose record wasn't renewed for this long is resumed
+	workHeartbeatTimeout = 3 * workHeartbeatInterval
+)
+
+// States of background tasks
+const (
+	taskRunning     = "RUNNING"
+	taskInterrupted = "INTERRUPTED"
+)
+
+// backgroundTask is the readiness wait or the seeding of a participant, the phases of its job that run after the
+// manifests were applied.
+type backgroundTask struct {
+	Participant string `json:"participant"`
+	Phase       string `json:"phase"`
+	JobId       string `json:"jobId"`
+	// Replica runs the task, Instance tells a restarted replica of the same name apart
+	Replica  string `json:"replica"`
+	Instance string `json:"instance"`
+	// Deployments are awaited by the readiness phase
+	Deployments []string  `json:"deployments,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	HeartbeatAt time.Time `json:"heartbeatAt"`
+	// Resumptions counts the times the task was resumed after it was interrupted
+	Resumptions int `json:"resumptions,omitempty"`
+	// State is RUNNING or INTERRUPTED, set when listed
+	State string `json:"state,omitempty"`
+}
+
+// trackedTask is a task of this replica with the definition of its participant.
+type trackedTask struct {
+	task       backgroundTask
+	definition []byte
+}
+
+// workTracker records the background work of the provisioning jobs in Secrets in the provisioner's namespace, so that
+// the readiness wait and the seeding of a participant survive a restart of the replica running them: the leader
+// resumes the work whose replica is gone in a new job. A single replica resumes the work of its previous process,
+// several replicas renew the records of their work and the records that weren't renewed are resumed.
+type workTracker struct {
+	client    client.Client
+	ctx       context.Context
+	namespace string
+	identity  string
+	instance  string
+	// shared is set with several replicas, whose work is only resumed once they stopped renewing its records
+	shared       bool
+	participants *provisioner
+	maintenance  *maintenanceMode
+	// leading reports whether this replica resumes interrupted work, with several replicas only the leader does
+	leading func() bool
+
+	mu    sync.Mutex
+	tasks map[string]*trackedTask
+}
+
+func newWorkTracker(ctx context.Context, c client.Client, namespace string, shared bool, participants *provisioner, maintenance *maintenanceMode, leading func() bool) *workTracker {
+	identity := replicaIdentity()
+	return &workTracker{client: c, ctx: ctx, namespace: namespace, identity: identity, instance: identity + "-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		shared: shared, participants: participants, maintenance: maintenance, leading: leading, tasks: make(map[string]*trackedTask)}
+}
+
+func workSecretName(participant string) string {
+	return "work-" + participant
+}
+
+// track records the readiness and seeding steps of the participant's job as background work while they run. The
+// returned function ends the record once the job ended, unless the replica is shutting down and the work is left to
+// be resumed. A nil tracker leaves the steps as they are.
+func (t *workTracker) track(job *provisioningJob, definition ParticipantDefinition, awaited func() []string, resumptions int, steps []jobStep) ([]jobStep, func()) {
+	if t == nil {
+		return steps, func() {}
+	}
+	tracked := make([]jobStep, len(steps))
+	for i, step := range steps {
+		tracked[i] = step
+		if step.phase != phaseReadiness && step.phase != phaseSeeding {
+			continue
+		}
+		tracked[i].run = func(ctx context.Context) error {
+			t.enter(job, definition, step.phase, awaited(), resumptions)
+			return step.run(ctx)
+		}
+	}
+	return tracked, func() { t.end(definition.ParticipantName, job.Id) }
+}
+
+// enter records the phase of the job the participant's work is in.
+func (t *workTracker) enter(job *provisioningJob, definition ParticipantDefinition, phase string, deployments []string, resumptions int) {
+	t.mu.Lock()
+	tracked, ok := t.tasks[definition.ParticipantName]
+	if !ok || tracked.task.JobId != job.Id {
+		data, err := json.Marshal(definition)
+		if err != nil {
+			t.mu.Unlock()
+			fmt.Printf("Recording the %s of %s failed: %v\n", phase, definition.ParticipantName, err)
+			return
+		}
+		now := time.Now().UTC()
+		tracked = &trackedTask{definition: data, task: backgroundTask{Participant: definition.ParticipantName, JobId: job.Id, Replica: t.identity,
+			Instance: t.instance, StartedAt: now, HeartbeatAt: now, Resumptions: resumptions}}
+		t.tasks[definition.ParticipantName] = tracked
+	}
+	tracked.task.Phase = phase
+	tracked.task.Deployments = deployments
+	record := *tracked
+	t.mu.Unlock()
+	if err := t.save(t.ctx, record); err != nil {
+		fmt.Printf("Recording the %s of %s failed: %v\n", phase, definition.ParticipantName, err)
+	}
+}
+
+// end removes the record of the participant's work done by the job.
+func (t *workTracker) end(participant string, jobId string) {
+	t.mu.Lock()
+	if tracked, ok := t.tasks[participant]; ok && tracked.task.JobId == jobId {
+		delete(t.tasks, participant)
+	}
+	t.mu.Unlock()
+	if t.ctx.Err() != nil {
+		return
+	}
+	secret := &corev1.Secret{}
+	if err := t.client.Get(t.ctx, client.ObjectKey{Namespace: t.namespace, Name: workSecretName(participant)}, secret); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			fmt.Printf("Removing the background work of %s failed: %v\n", participant, err)
+		}
+		return
+	}
+	if task, err := taskOf(*secret); err == nil && task.JobId != jobId {
+		// a later job took the work over
+		return
+	}
+	if err := t.client.Delete(t.ctx, secret); client.IgnoreNotFound(err) != nil {
+		fmt.Printf("Removing the background work of %s failed: %v\n", participant, err)
+	}
+}
+
+// save creates or replaces the record of the task.
+func (t *workTracker) save(ctx context.Context, tracked trackedTask) error {
+	data, err := json.Marshal(tracked.task)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: workSecretName(tracked.task.Participant), Namespace: t.namespace,
+			Labels: map[string]string{workLabel: "true", status.ParticipantLabel: tracked.task.Participant}},
+		Data: map[string][]byte{workDefinitionKey: tracked.definition, workTaskKey: data},
+	}
+	existing := &corev1.Secret{}
+	err = t.client.Get(ctx, client.ObjectKeyFromObject(secret), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		return t.client.Create(ctx, secret)
+	case err != nil:
+		return err
+	default:
+		secret.ResourceVersion = existing.ResourceVersion
+		return t.client.Update(ctx, secret)
+	}
+}
+
+func taskOf(secret corev1.Secret) (backgroundTask, error) {
+	var task backgroundTask
+	if err := json.Unmarshal(secret.Data[workTaskKey], &task); err != nil {
+		return task, fmt.Errorf("background work %s: %w", secret.Name, err)
+	}
+	return task, nil
+}
+
+// heartbeat renews the records of the work of this replica.
+func (t *workTracker) heartbeat(ctx context.Context, now time.Time) {
+	t.mu.Lock()
+	records := make([]trackedTask, 0, len(t.tasks))
+	for _, tracked := range t.tasks {
+		tracked.task.HeartbeatAt = now.UTC()
+		records = append(records, *tracked)
+	}
+	t.mu.Unlock()
+	for _, record := range records {
+		if err := t.save(ctx, record); err != nil {
+			fmt.Printf("Renewing the background work of %s failed: %v\n", record.task.Participant, err)
+		}
+	}
+}
+
+// interrupted reports whether the replica running the task is gone.
+func (t *workTracker) interrupted(task backgroundTask, now time.Time) bool {
+	if task.Instance == t.instance {
+		return false
+	}
+	return !t.shared || now.Sub(task.HeartbeatAt) > workHeartbeatTimeout
+}
+
+// list returns the background work of all replicas, the oldest first.
+func (t *workTracker) list(ctx context.Context, now time.Time) ([]backgroundTask, error) {
+	secrets := &corev1.SecretList{}
+	if err := t.client.List(ctx, secrets, client.InNamespace(t.namespace), client.MatchingLabels{workLabel: "true"}); err != nil {
+		return nil, err
+	}
+	tasks := make([]backgroundTask, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		task, err := taskOf(secret)
+		if err != nil {
+			return nil, err
+		}
+		task.State = taskRunning
+		if t.interrupted(task, now) {
+			task.State = taskInterrupted
+		}
+		tasks = append(tasks, task)
+	}
+	slices.SortFunc(tasks, func(a, b backgroundTask) int { return a.StartedAt.Compare(b.StartedAt) })
+	return tasks, nil
+}
+
+// run renews the records of the replica's work and resumes the interrupted work at the interval until the context is
+// cancelled, starting right away so the work of a restarted replica continues. Nothing is resumed during maintenance.
+func (t *workTracker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.heartbeat(ctx, time.Now())
+		if t.leading() && !t.maintenance.current(ctx).Enabled {
+			t.resumeInterrupted(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resumeInterrupted resumes the work whose replica is gone in new jobs. Work of participants that were deleted since
+// is dropped, work that can't be resumed now, e.g. as the participant is busy, is retried at the next check.
+func (t *workTracker) resumeInterrupted(ctx context.Context, now time.Time) {
+	secrets := &corev1.SecretList{}
+	if err := t.client.List(ctx, secrets, client.InNamespace(t.namespace), client.MatchingLabels{workLabel: "true"}); err != nil {
+		fmt.Println("Listing background work failed:", err)
+		return
+	}
+	for _, secret := range secrets.Items {
+		task, err := taskOf(secret)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if !t.interrupted(task, now) || jobs.running(task.Participant) {
+			continue
+		}
+		var definition ParticipantDefinition
+		if err := json.Unmarshal(secret.Data[workDefinitionKey], &definition); err != nil {
+			fmt.Printf("Background work %s: %v\n", secret.Name, err)
+			continue
+		}
+		managed, err := status.IsManagedNamespace(ctx, t.client, task.Participant)
+		if err != nil {
+			fmt.Printf("Resuming the %s of %s failed: %v\n", task.Phase, task.Participant, err)
+			continue
+		}
+		if !managed {
+			if err := t.client.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+				fmt.Printf("Removing the background work of %s failed: %v\n", task.Participant, err)
+			}
+			continue
+		}
+		job, err := t.participants.resumeWork(ctx, task, definition)
+		if err != nil {
+			fmt.Printf("Resuming the %s of %s failed: %v\n", task.Phase, task.Participant, err)
+			continue
+		}
+		fmt.Printf("Resumed the %s of %s, interrupted on replica %s, in job %s\n", task.Phase, task.Participant, task.Replica, job.Id)
+	}
+}
+
+// resumeWork continues the interrupted work of the participant in a new job: it waits for the deployments once more if
+// the readiness wait was interrupted, then seeds the participant and runs the dataspace's hooks.
+func (p *provisioner) resumeWork(ctx context.Context, task backgroundTask, definition ParticipantDefinition) (*provisioningJob, error) {
+	var steps []jobStep
+	switch task.Phase {
+	case phaseReadiness:
+		steps = p.readinessSteps(definition, func() []string { return task.Deployments })
+	case phaseSeeding:
+		if !definition.Seed.enabled() {
+			return nil, fmt.Errorf("seeding is disabled for %s", task.Participant)
+		}
+	default:
+		return nil, fmt.Errorf("unknown phase %q of the background work of %s", task.Phase, task.Participant)
+	}
+	if definition.Seed.enabled() {
+		creds, err := loadCredentials(p.kubeClient, ctx, definition.ParticipantName)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, p.seedingStep(definition, creds))
+	}
+	return p.runResumption(ctx, definition, steps, task.Deployments, task.Resumptions+1)
+}
+
+// listBackgroundTasks serves GET /api/v1/admin/background-tasks.
+func listBackgroundTasks(t *workTracker, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tasks, err := t.list(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		return c.JSON(tasks)
+	}
+}
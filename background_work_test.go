@@ -0,0 +1,129 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWorkTrackerRecordsJobPhases(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := newWorkTracker(ctx, kube, "provisioner", false, nil, nil, func() bool { return true })
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice"}
+
+	recorded := func() (backgroundTask, bool) {
+		secret := &corev1.Secret{}
+		err := kube.Get(ctx, client.ObjectKey{Namespace: "provisioner", Name: workSecretName("alice")}, secret)
+		if apierrors.IsNotFound(err) {
+			return backgroundTask{}, false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := taskOf(*secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task, true
+	}
+	var phases []string
+	step := func(phase string) jobStep {
+		return jobStep{phase, func(context.Context) error {
+			task, ok := recorded()
+			phases = append(phases, phase+":"+task.Phase)
+			if phase != phaseApply && !ok {
+				t.Errorf("expected the %s to be recorded", phase)
+			}
+			return nil
+		}}
+	}
+	steps, end := tracker.track(&provisioningJob{Id: "1"}, definition, func() []string { return []string{"controlplane"} }, 0,
+		[]jobStep{step(phaseApply), step(phaseReadiness), step(phaseSeeding), step(phaseHooks)})
+	for _, step := range steps {
+		if err := step.run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"apply:", "readiness:readiness", "seeding:seeding", "hooks:seeding"}
+	for i := range expected {
+		if phases[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, phases)
+		}
+	}
+	task, _ := recorded()
+	if task.JobId != "1" || task.Instance != tracker.instance || len(task.Deployments) != 1 {
+		t.Errorf("unexpected record %+v", task)
+	}
+	end()
+	if _, ok := recorded(); ok {
+		t.Error("expected the record to be removed once the job ended")
+	}
+
+	steps, end = tracker.track(&provisioningJob{Id: "2"}, definition, func() []string { return nil }, 0, []jobStep{step(phaseSeeding)})
+	if err := steps[0].run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	end()
+	if _, ok := recorded(); !ok {
+		t.Error("expected the work of a replica shutting down to be kept for resumption")
+	}
+}
+
+func TestWorkTrackerListsInterruptedWork(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	now := time.Now().UTC()
+	record := func(participant string, instance string, heartbeat time.Time) *corev1.Secret {
+		task, _ := json.Marshal(backgroundTask{Participant: participant, Phase: phaseReadiness, JobId: "1", Replica: "provisioner-0", Instance: instance,
+			StartedAt: heartbeat, HeartbeatAt: heartbeat})
+		definition, _ := json.Marshal(ParticipantDefinition{ParticipantName: participant})
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: workSecretName(participant), Namespace: "provisioner", Labels: map[string]string{workLabel: "true"}},
+			Data: map[string][]byte{workTaskKey: task, workDefinitionKey: definition}}
+	}
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		record("alice", "provisioner-0-old", now.Add(-time.Minute)),
+		record("bob", "provisioner-1-live", now),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bob", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+	).Build()
+	ctx := context.Background()
+
+	states := func(tracker *workTracker) map[string]string {
+		tasks, err := tracker.list(ctx, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		states := map[string]string{}
+		for _, task := range tasks {
+			states[task.Participant] = task.State
+		}
+		return states
+	}
+	single := newWorkTracker(ctx, kube, "provisioner", false, nil, nil, func() bool { return true })
+	if got := states(single); got["alice"] != taskInterrupted || got["bob"] != taskInterrupted {
+		t.Errorf("expected a single replica to resume the work of its previous process, got %v", got)
+	}
+	replicas := newWorkTracker(ctx, kube, "provisioner", true, nil, nil, func() bool { return true })
+	if got := states(replicas); got["alice"] != taskRunning || got["bob"] != taskRunning {
+		t.Errorf("expected the renewed work of other replicas to be running, got %v", got)
+	}
+
+	// alice was deleted while its work was interrupted
+	replicas.resumeInterrupted(ctx, now.Add(workHeartbeatTimeout))
+	if got := states(replicas); len(got) != 1 || got["bob"] == "" {
+		t.Errorf("expected the work of the deleted participant to be dropped, got %v", got)
+	}
+}
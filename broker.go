@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lifecycle events are published to message brokers with this timeout per event
+const brokerTimeout = 10 * time.Second
+
+// natsNotifier publishes events to a NATS server, on the subject prefix followed by the event type, e.g.
+// aruba.provisioner.participant.ready. It speaks the core NATS protocol over a connection kept open between events.
+type natsNotifier struct {
+	// url is nats://[user:password@|token@]host:port, tls:// upgrades the connection to TLS
+	url     string
+	subject string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (n *natsNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// a connection closed by the server is only noticed when using it, so publishing is tried on a new one once
+	for attempt := 0; ; attempt++ {
+		err = n.publish(ctx, n.subject+"."+event.Type, payload)
+		if err == nil {
+			return nil
+		}
+		n.close()
+		if attempt > 0 || ctx.Err() != nil {
+			return fmt.Errorf("publish to nats: %w", err)
+		}
+	}
+}
+
+// publish sends the message and a PING, the PONG confirms the server processed the message.
+func (n *natsNotifier) publish(ctx context.Context, subject string, payload []byte) error {
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(brokerTimeout)
+	}
+	if err := n.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := n.conn.Write([]byte(message)); err != nil {
+		return err
+	}
+	return n.awaitPong()
+}
+
+// connect opens the connection and authenticates with the credentials of the URL.
+func (n *natsNotifier) connect(ctx context.Context) error {
+	address, err := url.Parse(n.url)
+	if err != nil {
+		return err
+	}
+	host := address.Host
+	if address.Port() == "" {
+		host = net.JoinHostPort(address.Hostname(), "4222")
+	}
+	dialer := &net.Dialer{Timeout: brokerTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(brokerTimeout))
+	reader := bufio.NewReader(conn)
+	info, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(info))
+	}
+	if address.Scheme == "tls" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: address.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn, reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "aruba-provisioner", "lang": "go", "protocol": 0}
+	if address.User != nil {
+		if password, ok := address.User.Password(); ok {
+			options["user"], options["pass"] = address.User.Username(), password
+		} else {
+			options["auth_token"] = address.User.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	n.conn, n.reader = conn, reader
+	if _, err := conn.Write([]byte("CONNECT " + string(connect) + "\r\nPING\r\n")); err != nil {
+		return err
+	}
+	return n.awaitPong()
+}
+
+// awaitPong reads until the server answered the PING, answering its own PINGs. -ERR lines, e.g. for failed
+// authentication, are returned as errors.
+func (n *natsNotifier) awaitPong() error {
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats server: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (n *natsNotifier) close() {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn, n.reader = nil, nil
+	}
+}
+
+// kafkaNotifier publishes events to a Kafka topic through a Kafka REST proxy, keyed by participant so the events of a
+// participant stay in order.
+type kafkaNotifier struct {
+	// url is the address of the REST proxy, e.g. http://kafka-rest.kafka:8082
+	url        string
+	topic      string
+	httpClient http.Client
+}
+
+func (k kafkaNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{"key": event.Participant, "value": event}},
+	})
+	if err != nil {
+		return err
+	}
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(k.url, "/")+"/topics/"+url.PathEscape(k.topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	rq.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := k.httpClient.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy responded with %s", resp.Status)
+	}
+	// records are reported per offset, failed ones carry an error code
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
+		for _, offset := range result.Offsets {
+			if offset.ErrorCode != nil {
+				return fmt.Errorf("kafka rejected the event: %s", offset.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// natsServer accepts connections speaking the core NATS protocol and collects the published messages. Every
+// connection is closed after confirming closeAfter messages, if set.
+type natsServer struct {
+	listener   net.Listener
+	connects   chan string
+	messages   chan [2]string
+	closeAfter int
+}
+
+func newNatsServer(t *testing.T) *natsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &natsServer{listener: listener, connects: make(chan string, 10), messages: make(chan [2]string, 10)}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *natsServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_, _ = conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+	reader := bufio.NewReader(conn)
+	published := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			s.connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+		case "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+			if published > 0 && published == s.closeAfter {
+				return
+			}
+		case "PUB":
+			payload, _ := reader.ReadString('\n')
+			s.messages <- [2]string{fields[1], strings.TrimSpace(payload)}
+			published++
+		}
+	}
+}
+
+func TestNatsNotifier(t *testing.T) {
+	server := newNatsServer(t)
+	server.closeAfter = 1
+	notifier := &natsNotifier{url: "nats://s3cret@" + server.listener.Addr().String(), subject: "aruba.provisioner"}
+	defer notifier.close()
+
+	// the second event is published after the server closed the first connection
+	for _, eventType := range []string{eventParticipantCreated, eventParticipantReady} {
+		if err := notifier.Notify(context.Background(), newLifecycleEvent(eventType, "alice", map[string]string{"did": "did:web:alice"})); err != nil {
+			t.Fatal(err)
+		}
+		message := <-server.messages
+		if message[0] != "aruba.provisioner."+eventType {
+			t.Errorf("unexpected subject %s", message[0])
+		}
+		var event LifecycleEvent
+		if err := json.Unmarshal([]byte(message[1]), &event); err != nil || event.Participant != "alice" || event.Details["did"] != "did:web:alice" {
+			t.Errorf("unexpected payload %s", message[1])
+		}
+	}
+	var options map[string]any
+	if err := json.Unmarshal([]byte(<-server.connects), &options); err != nil || options["auth_token"] != "s3cret" {
+		t.Errorf("expected the token of the URL to authenticate, got %v", options)
+	}
+}
+
+func TestKafkaNotifier(t *testing.T) {
+	var records []struct {
+		Key   string         `json:"key"`
+		Value LifecycleEvent `json:"value"`
+	}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/provisioner-events" || r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var body struct {
+			Records json.RawMessage `json:"records"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.Unmarshal(body.Records, &records)
+		if records[0].Value.Type == eventParticipantDeleted {
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50301,"error":"topic not writable"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":7,"error_code":null,"error":null}]}`))
+	}))
+	defer proxy.Close()
+	notifier := kafkaNotifier{url: proxy.URL + "/", topic: "provisioner-events"}
+
+	if err := notifier.Notify(context.Background(), newLifecycleEvent(eventParticipantSeedFailed, "alice", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Key != "alice" || records[0].Value.Type != eventParticipantSeedFailed {
+		t.Errorf("unexpected records %+v", records)
+	}
+	if err := notifier.Notify(context.Background(), newLifecycleEvent(eventParticipantDeleted, "alice", nil)); err == nil {
+		t.Error("expected rejected records to fail the notification")
+	}
+}
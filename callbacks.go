@@ -0,0 +1,102 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EDC event types the connectors report to the provisioner
+const callbackEvents = "contract.negotiation,transfer.process"
+
+// edcEventEnvelope is the body EDC POSTs to callback addresses.
+type edcEventEnvelope struct {
+	Id      string         `json:"id"`
+	At      int64          `json:"at"`
+	Type    string         `json:"type"`
+	Payload map[string]any `json:"payload"`
+}
+
+// receiveCallback records connector events POSTed to /api/v1/callbacks/:participantName/:token. Only participants
+// managed by the provisioner are accepted, and the token has to match the one configured in their controlplane.
+func receiveCallback(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("participantName")
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid participant name: "+errs[0])
+		}
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, name)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.ErrNotFound
+		}
+		creds, err := loadCredentials(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		if creds.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(c.Params("token")), []byte(creds.CallbackToken)) != 1 {
+			return fiber.ErrUnauthorized
+		}
+		var envelope edcEventEnvelope
+		if err := c.BodyParser(&envelope); err != nil {
+			return err
+		}
+		if envelope.Type == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "event type missing")
+		}
+
+		timestamp := time.Now()
+		if envelope.At > 0 {
+			timestamp = time.UnixMilli(envelope.At)
+		}
+		statusChecker.RecordConnectorEvent(name, status.Event{
+			Type:      "Normal",
+			Reason:    envelope.Type,
+			Object:    callbackObject(envelope),
+			Message:   "reported by connector",
+			Timestamp: timestamp,
+		})
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// callbackObject identifies the negotiation or transfer process an event is about.
+func callbackObject(envelope edcEventEnvelope) string {
+	for _, key := range []string{"transferProcessId", "contractNegotiationId"} {
+		if id, ok := envelope.Payload[key].(string); ok {
+			return strings.TrimSuffix(strings.TrimSuffix(key, "Id"), "Process") + "/" + id
+		}
+	}
+	return "connector/" + envelope.Id
+}
+
+// callbackMutator configures the controlplane to send negotiation and transfer events to the provisioner, using
+// EDC's static callback settings. The token in the URI authenticates the connector.
+func callbackMutator(baseUrl string, participantName string, token string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "ConfigMap" || obj.GetName() != "controlplane-config" {
+			return nil
+		}
+		settings := map[string]string{
+			"EDC_CALLBACK_PROVISIONER_URI":           fmt.Sprintf("%s/api/v1/callbacks/%s/%s", strings.TrimSuffix(baseUrl, "/"), participantName, token),
+			"EDC_CALLBACK_PROVISIONER_EVENTS":        callbackEvents,
+			"EDC_CALLBACK_PROVISIONER_TRANSACTIONAL": "false",
+		}
+		for key, value := range settings {
+			if err := unstructured.SetNestedField(obj.Object, value, "data", key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
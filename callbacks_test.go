@@ -0,0 +1,85 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReceiveCallback(t *testing.T) {
+	managed := map[string]string{status.ManagedByLabel: status.ManagedByValue}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: managed}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "acme"},
+			Data: map[string][]byte{callbackTokenField: []byte("acme-token")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "globex", Labels: managed}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "globex"},
+			Data: map[string][]byte{managementApiKeyField: []byte("management-key")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "initech"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "initech"},
+			Data: map[string][]byte{callbackTokenField: []byte("initech-token")}},
+	).Build()
+	statusChecker := status.NewStatusChecker(context.Background(), kube, 0)
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/api/v1/callbacks/:participantName/:token", receiveCallback(kube, context.Background(), statusChecker))
+
+	event := `{"id": "event-1", "at": 1700000000000, "type": "TransferProcessStarted", "payload": {"transferProcessId": "tp-1"}}`
+	post := func(target string) int {
+		t.Helper()
+		request := httptest.NewRequest("POST", target, strings.NewReader(event))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	recorded := func(name string) []status.Event {
+		t.Helper()
+		events, err := statusChecker.GetEvents(context.Background(), name, status.EventFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	// globex has no callback token configured, initech isn't managed by the provisioner
+	for target, expected := range map[string]int{
+		"/api/v1/callbacks/acme/":                 fiber.StatusNotFound,
+		"/api/v1/callbacks/acme/wrong-token":      fiber.StatusUnauthorized,
+		"/api/v1/callbacks/globex/acme-token":     fiber.StatusUnauthorized,
+		"/api/v1/callbacks/initech/initech-token": fiber.StatusNotFound,
+		"/api/v1/callbacks/Acme/acme-token":       fiber.StatusBadRequest,
+	} {
+		if code := post(target); code != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, code)
+		}
+	}
+	for _, name := range []string{"acme", "globex", "initech"} {
+		if events := recorded(name); len(events) != 0 {
+			t.Errorf("%s: expected rejected callbacks not to be recorded, got %+v", name, events)
+		}
+	}
+
+	if code := post("/api/v1/callbacks/acme/acme-token"); code != fiber.StatusNoContent {
+		t.Fatalf("expected the event to be accepted, got %d", code)
+	}
+	events := recorded("acme")
+	if len(events) != 1 {
+		t.Fatalf("expected the event to be recorded, got %+v", events)
+	}
+	if events[0].Reason != "TransferProcessStarted" || events[0].Object != "transfer/tp-1" || !events[0].Timestamp.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
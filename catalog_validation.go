@@ -0,0 +1,119 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type contractDefinitionReferences struct {
+	Id               string `json:"@id"`
+	AccessPolicyId   string `json:"accessPolicyId"`
+	ContractPolicyId string `json:"contractPolicyId"`
+	AssetsSelector   any    `json:"assetsSelector"`
+}
+
+// validateCatalogReferences checks that every contract definition references policies and assets that are either part
+// of the seed catalog or already exist in the connector, so broken references fail before anything is created.
+func validateCatalogReferences(ctx context.Context, mgmtApi *api.ApiClient, assets []string, policies []string, contractDefinitions []string) error {
+	assetIds, err := catalogIds(assets)
+	if err != nil {
+		return err
+	}
+	policyIds, err := catalogIds(policies)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, body := range contractDefinitions {
+		var definition contractDefinitionReferences
+		if err := json.Unmarshal([]byte(body), &definition); err != nil {
+			return fmt.Errorf("parse contract definition: %w", err)
+		}
+		for _, policyId := range []string{definition.AccessPolicyId, definition.ContractPolicyId} {
+			if policyId == "" {
+				problems = append(problems, fmt.Sprintf("contract definition %s has no policy reference", definition.Id))
+				continue
+			}
+			if policyIds[policyId] {
+				continue
+			}
+			if problem := checkReference(ctx, mgmtApi.GetPolicy, "policy", policyId); problem != "" {
+				problems = append(problems, fmt.Sprintf("contract definition %s: %s", definition.Id, problem))
+			}
+		}
+		for _, assetId := range selectedAssetIds(definition.AssetsSelector) {
+			if assetIds[assetId] {
+				continue
+			}
+			if problem := checkReference(ctx, mgmtApi.GetAsset, "asset", assetId); problem != "" {
+				problems = append(problems, fmt.Sprintf("contract definition %s: %s", definition.Id, problem))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("catalog validation failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkReference looks up an entity in the connector and describes why the reference is broken, if it is.
+func checkReference(ctx context.Context, lookup func(context.Context, string) (string, error), kind string, id string) string {
+	_, err := lookup(ctx, id)
+	switch {
+	case err == nil:
+		return ""
+	case api.IsNotFound(err):
+		return fmt.Sprintf("unknown %s %s", kind, id)
+	default:
+		return fmt.Sprintf("could not verify %s %s: %v", kind, id, err)
+	}
+}
+
+func catalogIds(bodies []string) (map[string]bool, error) {
+	ids := make(map[string]bool, len(bodies))
+	for _, body := range bodies {
+		var entity struct {
+			Id string `json:"@id"`
+		}
+		if err := json.Unmarshal([]byte(body), &entity); err != nil {
+			return nil, fmt.Errorf("parse catalog entry: %w", err)
+		}
+		ids[entity.Id] = true
+	}
+	return ids, nil
+}
+
+// selectedAssetIds returns the asset IDs an asset selector matches explicitly via "=" or "in" criteria on the asset ID.
+func selectedAssetIds(selector any) []string {
+	var ids []string
+	switch s := selector.(type) {
+	case []any:
+		for _, criterion := range s {
+			ids = append(ids, selectedAssetIds(criterion)...)
+		}
+	case map[string]any:
+		left, _ := s["operandLeft"].(string)
+		if left != api.AssetIdProperty && left != "id" {
+			return nil
+		}
+		switch right := s["operandRight"].(type) {
+		case string:
+			if s["operator"] == api.OperatorEquals {
+				ids = append(ids, right)
+			}
+		case []any:
+			if s["operator"] == api.OperatorIn {
+				for _, id := range right {
+					if id, ok := id.(string); ok {
+						ids = append(ids, id)
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	changeCreated   = "created"
+	changeUpdated   = "updated"
+	changeUnchanged = "unchanged"
+)
+
+// objectChange describes how applying an object changed its live state.
+type objectChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Fields lists the paths of the changed fields of updated objects
+	Fields []string `json:"fields,omitempty"`
+}
+
+// changeLog collects the changes of the objects applied through its action.
+type changeLog struct {
+	mu      sync.Mutex
+	changes []objectChange
+}
+
+// action wraps an action, comparing every object with its live state before the wrapped action ran.
+func (l *changeLog) action(next action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		obj, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			return next(c, ctx, object)
+		}
+		before := &unstructured.Unstructured{}
+		before.SetGroupVersionKind(obj.GroupVersionKind())
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), before)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		existed := err == nil
+		if err := next(c, ctx, object); err != nil {
+			return err
+		}
+
+		change := objectChange{Kind: obj.GetKind(), Name: obj.GetName(), Action: changeCreated}
+		if existed {
+			change.Fields = diffFields(comparable(before.Object), comparable(obj.Object), "")
+			change.Action = changeUpdated
+			if len(change.Fields) == 0 {
+				change.Action = changeUnchanged
+			}
+		}
+		l.mu.Lock()
+		l.changes = append(l.changes, change)
+		l.mu.Unlock()
+		return nil
+	}
+}
+
+func (l *changeLog) list() []objectChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]objectChange(nil), l.changes...)
+}
+
+// updated reports whether an object of the kind was created or updated.
+func (l *changeLog) updated(kind string) bool {
+	for _, change := range l.list() {
+		if change.Kind == kind && change.Action != changeUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// comparable strips the status and the server maintained metadata from an object, keeping labels and annotations.
+func comparable(object map[string]any) map[string]any {
+	stripped := make(map[string]any, len(object))
+	for k, v := range object {
+		if k != "status" && k != "metadata" {
+			stripped[k] = v
+		}
+	}
+	if metadata, ok := object["metadata"].(map[string]any); ok {
+		kept := make(map[string]any)
+		for _, k := range []string{"labels", "annotations"} {
+			if v, ok := metadata[k]; ok {
+				kept[k] = v
+			}
+		}
+		stripped["metadata"] = kept
+	}
+	return stripped
+}
+
+// diffFields returns the sorted paths at which two objects differ. Lists are compared as a whole.
+func diffFields(before map[string]any, after map[string]any, prefix string) []string {
+	var fields []string
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	for k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		beforeMap, beforeIsMap := before[k].(map[string]any)
+		afterMap, afterIsMap := after[k].(map[string]any)
+		if beforeIsMap && afterIsMap {
+			fields = append(fields, diffFields(beforeMap, afterMap, path)...)
+		} else if !reflect.DeepEqual(before[k], after[k]) {
+			fields = append(fields, path)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	before := map[string]any{
+		"metadata": map[string]any{"name": "controlplane", "resourceVersion": "1", "labels": map[string]any{"app": "cp"}},
+		"spec": map[string]any{
+			"replicas": int64(1),
+			"template": map[string]any{"spec": map[string]any{"containers": []any{map[string]any{"image": "cp:0.12.0"}}}},
+		},
+		"status": map[string]any{"readyReplicas": int64(1)},
+	}
+	after := map[string]any{
+		"metadata": map[string]any{"name": "controlplane", "resourceVersion": "2", "labels": map[string]any{"app": "cp", "tier": "demo"}},
+		"spec": map[string]any{
+			"replicas": int64(1),
+			"template": map[string]any{"spec": map[string]any{"containers": []any{map[string]any{"image": "cp:0.13.0"}}}},
+		},
+		"status": map[string]any{"readyReplicas": int64(0)},
+	}
+	want := []string{"metadata.labels.tier", "spec.template.spec.containers"}
+	if got := diffFields(comparable(before), comparable(after), ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffFields() = %v, want %v", got, want)
+	}
+	if got := diffFields(comparable(before), comparable(before), ""); len(got) != 0 {
+		t.Errorf("identical objects reported as changed: %v", got)
+	}
+}
+
+func TestChangeLogUpdated(t *testing.T) {
+	log := &changeLog{changes: []objectChange{
+		{Kind: "ConfigMap", Name: "controlplane-config", Action: changeUnchanged},
+		{Kind: "Deployment", Name: "controlplane", Action: changeUpdated},
+	}}
+	if log.updated("ConfigMap") {
+		t.Error("unchanged ConfigMap reported as updated")
+	}
+	if !log.updated("Deployment") {
+		t.Error("updated Deployment not reported")
+	}
+}
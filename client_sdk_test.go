@@ -0,0 +1,19 @@
+package main
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"aruba-provisioner/pkg/client"
+)
+
+// The participant definition of the client package must stay in sync with the one accepted by the API.
+func TestClientParticipantDefinition(t *testing.T) {
+	server := slices.Sorted(maps.Keys(jsonFields(reflect.TypeOf(ParticipantDefinition{}))))
+	sdk := slices.Sorted(maps.Keys(jsonFields(reflect.TypeOf(client.ParticipantDefinition{}))))
+	if !slices.Equal(server, sdk) {
+		t.Errorf("client definition has fields %v, the API accepts %v", sdk, server)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"aruba-provisioner/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+// waitFlags adds the flags of commands that can block until their job completed.
+func waitFlags(cmd *cobra.Command) (*bool, *time.Duration) {
+	wait := cmd.Flags().Bool("wait", false, "Block until the job completed and the participant reached the target status")
+	timeout := cmd.Flags().Duration("timeout", 15*time.Minute, "How long --wait blocks at most")
+	return wait, timeout
+}
+
+func createCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create -f <definition.json>",
+		Short: "Provision a participant",
+		Args:  cobra.NoArgs,
+	}
+	file := cmd.Flags().StringP("filename", "f", "", "File with the participant definition as JSON, - reads it from stdin")
+	_ = cmd.MarkFlagRequired("filename")
+	wait, timeout := waitFlags(cmd)
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		var data []byte
+		var err error
+		if *file == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(*file)
+		}
+		if err != nil {
+			return err
+		}
+		// typos would otherwise silently drop settings
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var definition client.ParticipantDefinition
+		if err := decoder.Decode(&definition); err != nil {
+			return fmt.Errorf("read %s: %w", *file, err)
+		}
+		ctx := cmd.Context()
+		return cli.startJob(ctx, func() (client.AcceptedJob, error) {
+			return cli.api.Provision(ctx, definition)
+		}, *wait, *timeout, client.StatusReady)
+	}
+	return cmd
+}
+
+func deleteCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a participant",
+		Args:  participantArg,
+	}
+	wait, timeout := waitFlags(cmd)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		return cli.startJob(ctx, func() (client.AcceptedJob, error) {
+			return cli.api.Delete(ctx, args[0])
+		}, *wait, *timeout, client.StatusDeleted, client.StatusNotFound)
+	}
+	return cmd
+}
+
+func seedCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "seed <name>",
+		Short: "Seed the data of a participant again",
+		Args:  participantArg,
+	}
+	wait, timeout := waitFlags(cmd)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		return cli.startJob(ctx, func() (client.AcceptedJob, error) {
+			return cli.api.Seed(ctx, args[0])
+		}, *wait, *timeout, client.StatusReady)
+	}
+	return cmd
+}
+
+// participantArg accepts the name of a single participant as argument.
+func participantArg(_ *cobra.Command, args []string) error {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		return fmt.Errorf("expected the name of one participant, got %d arguments", len(args))
+	}
+	return nil
+}
+
+// startJob starts a job and, with wait, blocks until the job completed and the participant reached one of the target
+// statuses.
+func (cli *cli) startJob(ctx context.Context, start func() (client.AcceptedJob, error), wait bool, timeout time.Duration, targets ...string) error {
+	job, err := start()
+	if err != nil {
+		return err
+	}
+	if cli.output == "json" && (!wait || job.NotBefore != nil) {
+		return json.NewEncoder(cli.out).Encode(job)
+	}
+	// scheduled provisionings have no job to wait for yet
+	if job.NotBefore != nil {
+		fmt.Fprintf(cli.out, "%s: scheduled for %s\n", job.Participant, job.NotBefore.Format(time.RFC3339))
+		return nil
+	}
+	fmt.Fprintf(cli.out, "%s: job %s started\n", job.Participant, job.JobId)
+	if job.QueuePosition > 0 {
+		fmt.Fprintf(cli.out, "%s: queued at position %d\n", job.Participant, job.QueuePosition)
+	}
+	if !wait {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := cli.api.WaitForJob(ctx, job.JobId); err != nil {
+		return err
+	}
+	participant, err := cli.api.WaitForStatus(ctx, job.Participant, targets...)
+	if err != nil {
+		return err
+	}
+	return cli.printStatus(participant)
+}
+
+func statusCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status <name>",
+		Short: "Show the status of a participant",
+		Args:  participantArg,
+	}
+	fields := cmd.Flags().String("fields", "components", "Comma separated sections of the status to include, empty for all")
+	refresh := cmd.Flags().Bool("refresh", false, "Evaluate the status from the cluster instead of returning a cached one")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		options := client.StatusOptions{Refresh: *refresh}
+		if *fields != "" {
+			options.Fields = strings.Split(*fields, ",")
+		}
+		participant, err := cli.api.Status(cmd.Context(), args[0], options)
+		if err != nil {
+			return err
+		}
+		return cli.printStatus(participant)
+	}
+	return cmd
+}
+
+func (cli *cli) printStatus(participant client.ParticipantStatus) error {
+	if cli.output == "json" {
+		return json.NewEncoder(cli.out).Encode(participant)
+	}
+	fmt.Fprintf(cli.out, "Participant: %s\nStatus:      %s\n", participant.Name, participant.Status)
+	if participant.Message != "" {
+		fmt.Fprintf(cli.out, "Message:     %s\n", participant.Message)
+	}
+	if len(participant.Components) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(cli.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nCOMPONENT\tSTATUS\tREADY\tMESSAGE")
+	for _, component := range participant.Components {
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", component.Name, component.Status, component.ReadyReplicas, component.DesiredReplicas, component.Message)
+	}
+	return w.Flush()
+}
+
+func listCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the participants",
+		Args:  cobra.NoArgs,
+	}
+	sortBy := cmd.Flags().String("sort", "name", "Sort order: name, status or lastUpdated")
+	order := cmd.Flags().String("order", "asc", "asc or desc")
+	limit := cmd.Flags().Int("limit", 0, "Number of participants per page, 0 lists all")
+	continueToken := cmd.Flags().String("continue", "", "Token of the page to list, printed with the previous page")
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		if *order != "asc" && *order != "desc" {
+			return fmt.Errorf("order must be asc or desc")
+		}
+		list, err := cli.api.List(cmd.Context(), client.ListOptions{Sort: *sortBy, Descending: *order == "desc", Limit: *limit, Continue: *continueToken})
+		if err != nil {
+			return err
+		}
+		if cli.output == "json" {
+			return json.NewEncoder(cli.out).Encode(list)
+		}
+		w := tabwriter.NewWriter(cli.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tLAST UPDATED\tMESSAGE")
+		for _, participant := range list.Items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", participant.Name, participant.Status, participant.LastUpdated.Format(time.RFC3339), participant.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if list.Continue != "" {
+			fmt.Fprintf(cli.out, "\nMore participants: provisionerctl list --continue %s\n", list.Continue)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func logsCommand(cli *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs <name>",
+		Short: "Print the logs of a component of a participant, requires --admin-key",
+		Args:  participantArg,
+	}
+	component := cmd.Flags().String("component", "controlplane", "Component whose pods' logs are printed: controlplane, dataplane or identityhub")
+	container := cmd.Flags().String("container", "", "Container of the pods, defaults to the component's")
+	tail := cmd.Flags().Int("tail", 100, "Number of lines per pod")
+	previous := cmd.Flags().Bool("previous", false, "Print the logs of the previous, e.g. crashed, containers")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		logs, err := cli.api.Logs(cmd.Context(), args[0], client.LogOptions{Component: *component, Container: *container, Tail: *tail, Previous: *previous})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(cli.out, logs)
+		return err
+	}
+	return cmd
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateWait(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/resources/":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"alice"`) {
+				t.Errorf("unexpected definition %s", body)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"jobId":"42","participant":"alice"}`))
+		case "GET /api/v1/jobs/42":
+			polls++
+			if polls < 3 {
+				_, _ = w.Write([]byte(`{"id":"42","status":"RUNNING","phase":"readiness"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"42","status":"SUCCEEDED","phase":"hooks"}`))
+		case "GET /api/v1/resources/alice/status":
+			if r.URL.Query().Get("refresh") != "true" {
+				t.Error("expected --wait to bypass the status cache")
+			}
+			_, _ = w.Write([]byte(`{"name":"alice","status":"READY","components":[{"name":"controlplane","status":"Running","readyReplicas":1,"desiredReplicas":1}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	definition := filepath.Join(t.TempDir(), "alice.json")
+	if err := os.WriteFile(definition, []byte(`{"participantName":"alice","did":"did:web:alice"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	args := []string{"--server", server.URL, "--token", "secret", "--poll-interval", "1ms", "create", "-f", definition, "--wait"}
+	if err := run(context.Background(), args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 {
+		t.Errorf("expected the job to be polled until it succeeded, got %d polls", polls)
+	}
+	for _, expected := range []string{"job 42 started", "Status:      READY", "controlplane  Running  1/1"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the output:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestCreateScheduled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.Path != "POST /api/v1/resources/" {
+			t.Errorf("expected no job to be polled, got %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"participant":"alice","notBefore":"2026-10-19T22:00:00Z"}`))
+	}))
+	defer server.Close()
+	definition := filepath.Join(t.TempDir(), "alice.json")
+	if err := os.WriteFile(definition, []byte(`{"participantName":"alice","notBefore":"2026-10-19T22:00:00Z","teardown":"0 18 * * *"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := run(context.Background(), []string{"--server", server.URL, "create", "-f", definition, "--wait"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "alice: scheduled for 2026-10-19T22:00:00Z") {
+		t.Errorf("expected the scheduled time in the output:\n%s", out.String())
+	}
+}
+
+func TestWaitFailsWithJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/resources/alice/seed":
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"jobId":"7","participant":"alice"}`))
+		case "GET /api/v1/jobs/7":
+			_, _ = w.Write([]byte(`{"id":"7","status":"FAILED","phase":"seeding","error":"identity hub unavailable","code":"SEED_FAILED"}`))
+		}
+	}))
+	defer server.Close()
+
+	err := run(context.Background(), []string{"--server", server.URL, "seed", "alice", "--wait"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "identity hub unavailable") {
+		t.Errorf("expected the job failure, got %v", err)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"title":"Not Found","status":404,"detail":"participant not found","code":"NOT_FOUND"}`))
+	}))
+	defer server.Close()
+
+	err := run(context.Background(), []string{"--server", server.URL, "delete", "bob"}, io.Discard)
+	if err == nil || err.Error() != "participant not found (404 NOT_FOUND)" {
+		t.Errorf("expected the problem detail, got %v", err)
+	}
+	if err := run(context.Background(), []string{"--server", server.URL, "delete"}, io.Discard); err == nil {
+		t.Error("expected delete without a participant to be rejected")
+	}
+	if err := run(context.Background(), []string{"--server", server.URL, "provision"}, io.Discard); err == nil {
+		t.Error("expected unknown commands to be rejected")
+	}
+}
+
+func TestList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sort") != "status" || r.URL.Query().Get("limit") != "1" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"items":[{"name":"alice","status":"READY","lastUpdated":"2024-05-01T10:00:00Z"}],"continue":"abc"}`))
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	if err := run(context.Background(), []string{"--server", server.URL, "list", "--sort", "status", "--limit", "1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "alice  READY   2024-05-01T10:00:00Z") || !strings.Contains(out.String(), "--continue abc") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,83 @@
+// Command provisionerctl manages participants through the REST API of the provisioner.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"aruba-provisioner/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+// cli holds the settings shared by the commands.
+type cli struct {
+	api    *client.Client
+	output string
+	out    io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	root := rootCommand(out)
+	root.SetArgs(args)
+	root.SetOut(out)
+	root.SetErr(out)
+	return root.ExecuteContext(ctx)
+}
+
+// rootCommand returns the provisionerctl command with its subcommands, which print to out.
+func rootCommand(out io.Writer) *cobra.Command {
+	cli := &cli{out: out}
+	var server, token, adminKey string
+	var requestTimeout, pollInterval time.Duration
+	root := &cobra.Command{
+		Use:   "provisionerctl",
+		Short: "Manage participants through the REST API of the provisioner",
+		// errors are printed by main, usage only when asked for
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			if cli.output != "text" && cli.output != "json" {
+				return fmt.Errorf("output must be text or json")
+			}
+			cli.api = &client.Client{
+				BaseUrl:      server,
+				Token:        token,
+				AdminKey:     adminKey,
+				HttpClient:   &http.Client{Timeout: requestTimeout},
+				PollInterval: pollInterval,
+			}
+			return nil
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&server, "server", envOrDefault("PROVISIONERCTL_SERVER", "http://localhost:9999"), "Address of the provisioner")
+	flags.StringVar(&token, "token", os.Getenv("PROVISIONERCTL_TOKEN"), "API key or OIDC token sent as bearer token")
+	flags.StringVar(&adminKey, "admin-key", os.Getenv("PROVISIONERCTL_ADMIN_KEY"), "Admin API key sent as x-api-key header")
+	flags.StringVarP(&cli.output, "output", "o", "text", "Output format: text or json")
+	flags.DurationVar(&requestTimeout, "request-timeout", 30*time.Second, "Timeout of each API request")
+	flags.DurationVar(&pollInterval, "poll-interval", 5*time.Second, "Interval of the status polls of --wait")
+	root.AddCommand(createCommand(cli), deleteCommand(cli), statusCommand(cli), listCommand(cli), logsCommand(cli), seedCommand(cli))
+	return root
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
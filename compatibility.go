@@ -0,0 +1,199 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Version of the embedded manifest templates. Bump it whenever connector.yaml or identityhub.yaml change in a way
+// that affects which component releases they can run, and add a matching entry to compatibilityMatrix.
+const templateVersion = "1.0.0"
+
+// Namespace annotation recording the template version a participant was last provisioned with
+const templateVersionAnnotation = "aruba-provisioner/template-version"
+
+// versionRange is a half-open range of supported component versions: [Min, Below).
+type versionRange struct {
+	Min   string `json:"minVersion"`
+	Below string `json:"maxVersionExclusive"`
+}
+
+type templateCompatibility struct {
+	TemplateVersion string                  `json:"templateVersion"`
+	Components      map[string]versionRange `json:"components"`
+}
+
+var compatibilityMatrix = []templateCompatibility{
+	{
+		TemplateVersion: "1.0.0",
+		Components: map[string]versionRange{
+			"controlplane": {Min: "0.11.0", Below: "0.15.0"},
+			"dataplane":    {Min: "0.11.0", Below: "0.15.0"},
+			"identityhub":  {Min: "0.11.0", Below: "0.15.0"},
+			"postgres":     {Min: "14.0.0", Below: "18.0.0"},
+		},
+	},
+}
+
+func currentCompatibility() templateCompatibility {
+	for _, entry := range compatibilityMatrix {
+		if entry.TemplateVersion == templateVersion {
+			return entry
+		}
+	}
+	return templateCompatibility{TemplateVersion: templateVersion}
+}
+
+// checkCompatibility verifies that a component version can be run with the current templates. Tags that are not
+// semantic versions (e.g. "latest") can't be checked and are accepted.
+func checkCompatibility(component string, version string) error {
+	parsed, ok := parseVersion(version)
+	if !ok {
+		return nil
+	}
+	supported, ok := currentCompatibility().Components[component]
+	if !ok {
+		return nil
+	}
+	lower, _ := parseVersion(supported.Min)
+	upper, _ := parseVersion(supported.Below)
+	if compareVersions(parsed, lower) < 0 || compareVersions(parsed, upper) >= 0 {
+		return fmt.Errorf("%s version %s is not supported by template version %s (supported: >= %s, < %s)",
+			component, version, templateVersion, supported.Min, supported.Below)
+	}
+	return nil
+}
+
+// validateComponentVersions checks the requested component versions, and the tags of images without an explicit
+// version, against the compatibility matrix.
+func validateComponentVersions(versions map[string]string, images map[string]string) error {
+	supported := currentCompatibility().Components
+	for component, version := range versions {
+		if _, ok := supported[component]; !ok {
+			return fmt.Errorf("unknown component %q", component)
+		}
+		if err := checkCompatibility(component, version); err != nil {
+			return err
+		}
+	}
+	for component, image := range images {
+		if _, ok := supported[component]; !ok {
+			return fmt.Errorf("unknown component %q", component)
+		}
+		if image == "" || strings.ContainsAny(image, " \t\n") {
+			return fmt.Errorf("invalid image %q for component %s", image, component)
+		}
+		if _, ok := versions[component]; ok {
+			continue
+		}
+		if err := checkCompatibility(component, status.ImageTag(image)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// componentVersionMutator sets the image and image tag of the requested components and records the template version
+// on the participant namespace.
+func componentVersionMutator(versions map[string]string, images map[string]string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		switch obj.GetKind() {
+		case "Namespace":
+			addAnnotations(obj, map[string]string{templateVersionAnnotation: templateVersion})
+		case "Deployment":
+			version, hasVersion := versions[obj.GetName()]
+			replacement, hasImage := images[obj.GetName()]
+			if !hasVersion && !hasImage {
+				return nil
+			}
+			containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			if err != nil {
+				return err
+			}
+			for _, container := range containers {
+				container, ok := container.(map[string]any)
+				if !ok || container["name"] != obj.GetName() {
+					continue
+				}
+				image, _ := container["image"].(string)
+				if hasImage {
+					image = replacement
+				}
+				if hasVersion {
+					image = withTag(image, version)
+				}
+				container["image"] = image
+			}
+			return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+		}
+		return nil
+	}
+}
+
+// withTag replaces the tag of an image reference.
+func withTag(image string, tag string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
+
+// outdatedParticipants returns the managed participants provisioned with a different template version than the
+// running provisioner ships, keyed by participant name.
+func outdatedParticipants(c client.Client, ctx context.Context, tenant string) (map[string]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, tenantSelector(tenant)); err != nil {
+		return nil, err
+	}
+	outdated := make(map[string]string)
+	for _, namespace := range namespaces.Items {
+		version := namespace.Annotations[templateVersionAnnotation]
+		if version != templateVersion {
+			if version == "" {
+				version = "unknown"
+			}
+			outdated[namespace.Name] = version
+		}
+	}
+	return outdated, nil
+}
+
+// parseVersion parses "[v]major.minor.patch", ignoring any pre-release or build suffix.
+func parseVersion(version string) ([3]int, bool) {
+	var parsed [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+func compareVersions(a [3]int, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    [3]int
+		ok      bool
+	}{
+		{"0.13.2", [3]int{0, 13, 2}, true},
+		{"v1.2.3", [3]int{1, 2, 3}, true},
+		{"0.14.0-SNAPSHOT", [3]int{0, 14, 0}, true},
+		{"1.0.0+build.7", [3]int{1, 0, 0}, true},
+		{"16.3", [3]int{}, false},
+		{"latest", [3]int{}, false},
+		{"1.x.0", [3]int{}, false},
+		{"", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.version)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseVersion(%q) = %v, %v; want %v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b [3]int
+		want int
+	}{
+		{[3]int{0, 13, 0}, [3]int{0, 13, 0}, 0},
+		{[3]int{0, 12, 9}, [3]int{0, 13, 0}, -1},
+		{[3]int{1, 0, 0}, [3]int{0, 99, 99}, 1},
+		{[3]int{0, 13, 1}, [3]int{0, 13, 0}, 1},
+		{[3]int{0, 13, 0}, [3]int{0, 13, 10}, -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%v, %v) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidateComponentVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions map[string]string
+		images   map[string]string
+		wantErr  bool
+	}{
+		{"supported", map[string]string{"controlplane": "0.13.0", "postgres": "16.3.0"}, nil, false},
+		{"non-semver tag", map[string]string{"identityhub": "latest"}, nil, false},
+		{"below minimum", map[string]string{"controlplane": "0.10.4"}, nil, true},
+		{"upper bound exclusive", map[string]string{"dataplane": "0.15.0"}, nil, true},
+		{"unknown component", map[string]string{"vault": "1.15.6"}, nil, true},
+		{"image tag checked", nil, map[string]string{"controlplane": "registry.example.com/controlplane:0.10.0"}, true},
+		{"version overrides image tag", map[string]string{"controlplane": "0.13.0"}, map[string]string{"controlplane": "registry.example.com/controlplane:0.10.0"}, false},
+		{"image of unknown component", nil, map[string]string{"vault": "hashicorp/vault:1.15.6"}, true},
+		{"empty image", nil, map[string]string{"dataplane": ""}, true},
+	}
+	for _, tt := range tests {
+		if err := validateComponentVersions(tt.versions, tt.images); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateComponentVersions() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWithTag(t *testing.T) {
+	tests := []struct{ image, tag, want string }{
+		{"postgres:16.3-alpine3.20", "17.0.0", "postgres:17.0.0"},
+		{"ghcr.io/org/controlplane:latest", "0.13.0", "ghcr.io/org/controlplane:0.13.0"},
+		{"localhost:5000/controlplane", "0.13.0", "localhost:5000/controlplane:0.13.0"},
+	}
+	for _, tt := range tests {
+		if got := withTag(tt.image, tt.tag); got != tt.want {
+			t.Errorf("withTag(%q, %q) = %q; want %q", tt.image, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestComponentVersionMutator(t *testing.T) {
+	deployment := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"kind":     "Deployment",
+			"metadata": map[string]any{"name": "controlplane"},
+			"spec": map[string]any{"template": map[string]any{"spec": map[string]any{"containers": []any{
+				map[string]any{"name": "controlplane", "image": "ghcr.io/org/controlplane:latest"},
+			}}}},
+		}}
+	}
+	tests := []struct {
+		name     string
+		versions map[string]string
+		images   map[string]string
+		want     string
+	}{
+		{"untouched", nil, nil, "ghcr.io/org/controlplane:latest"},
+		{"version", map[string]string{"controlplane": "0.13.0"}, nil, "ghcr.io/org/controlplane:0.13.0"},
+		{"image", nil, map[string]string{"controlplane": "registry.example.com/edc/controlplane:0.12.0"}, "registry.example.com/edc/controlplane:0.12.0"},
+		{"image and version", map[string]string{"controlplane": "0.13.0"}, map[string]string{"controlplane": "registry.example.com/edc/controlplane"}, "registry.example.com/edc/controlplane:0.13.0"},
+	}
+	for _, tt := range tests {
+		obj := deployment()
+		if err := componentVersionMutator(tt.versions, tt.images)(obj); err != nil {
+			t.Fatal(err)
+		}
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		if got := containers[0].(map[string]any)["image"]; got != tt.want {
+			t.Errorf("%s: image = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ConfigMaps the components read their settings from as environment variables, keyed by deployment name
+var componentConfigMaps = map[string]string{
+	"controlplane":            "controlplane-config",
+	"dataplane":               "dataplane-config",
+	"identityhub":             "ih-config",
+	federatedCatalogComponent: "federatedcatalog-config",
+}
+
+var settingNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// settingName returns the environment variable of a setting given as variable, e.g. EDC_LOG_LEVEL, or as config
+// property, e.g. edc.log.level, the way the runtimes map properties to variables.
+func settingName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// managedSettings returns the settings of the component the provisioner sets itself, they can't be overridden.
+func managedSettings(configMap string) []string {
+	managed := slices.Clone(keyAliasSettings[configMap])
+	switch configMap {
+	case "controlplane-config":
+		managed = append(managed, managementApiKeySetting)
+	case "ih-config":
+		managed = append(managed, superUserKeySetting)
+	}
+	return managed
+}
+
+// validateComponentEnv checks that the settings are given for known components and name valid variables the
+// provisioner doesn't manage.
+func validateComponentEnv(env map[string]map[string]string) error {
+	for component, settings := range env {
+		configMap, ok := componentConfigMaps[component]
+		if !ok {
+			return fmt.Errorf("env: unknown component %q", component)
+		}
+		for key := range settings {
+			name := settingName(key)
+			if !settingNamePattern.MatchString(name) {
+				return fmt.Errorf("env: %s: invalid setting %q", component, key)
+			}
+			if slices.Contains(managedSettings(configMap), name) {
+				return fmt.Errorf("env: %s: %s is set by the provisioner", component, name)
+			}
+		}
+	}
+	return nil
+}
+
+// componentEnvMutator merges the settings into the ConfigMaps of the components, replacing the settings of the
+// templates.
+func componentEnvMutator(env map[string]map[string]string) objectMutator {
+	configMaps := make(map[string]map[string]string, len(env))
+	for component, settings := range env {
+		configMaps[componentConfigMaps[component]] = settings
+	}
+	return func(obj *unstructured.Unstructured) error {
+		settings, ok := configMaps[obj.GetName()]
+		if obj.GetKind() != "ConfigMap" || !ok {
+			return nil
+		}
+		for key, value := range settings {
+			if err := unstructured.SetNestedField(obj.Object, value, "data", settingName(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateComponentEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]map[string]string
+		wantErr bool
+	}{
+		{"variables", map[string]map[string]string{"controlplane": {"EDC_LOG_LEVEL": "DEBUG"}, "identityhub": {"JAVA_TOOL_OPTIONS": "-Xmx512m"}}, false},
+		{"config property", map[string]map[string]string{"dataplane": {"edc.dataplane.state-machine.iteration-wait-millis": "500"}}, false},
+		{"unknown component", map[string]map[string]string{"vault": {"VAULT_LOG_LEVEL": "debug"}}, true},
+		{"invalid name", map[string]map[string]string{"controlplane": {"EDC LOG": "DEBUG"}}, true},
+		{"managed API key", map[string]map[string]string{"controlplane": {"web.http.management.auth.key": "secret"}}, true},
+		{"managed key alias", map[string]map[string]string{"identityhub": {"EDC_IAM_STS_PRIVATEKEY_ALIAS": "other"}}, true},
+	}
+	for _, tt := range tests {
+		if err := validateComponentEnv(tt.env); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateComponentEnv() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestComponentEnvMutator(t *testing.T) {
+	config := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{"kind": "ConfigMap", "metadata": map[string]any{"name": name},
+			"data": map[string]any{"EDC_LOG_LEVEL": "INFO", "EDC_HOSTNAME": "controlplane"}}}
+	}
+	mutator := componentEnvMutator(map[string]map[string]string{"controlplane": {"edc.log.level": "DEBUG", "EDC_FEATURE": "on"}})
+
+	controlplane := config("controlplane-config")
+	if err := mutator(controlplane); err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(controlplane.Object, "data")
+	if data["EDC_LOG_LEVEL"] != "DEBUG" || data["EDC_FEATURE"] != "on" || data["EDC_HOSTNAME"] != "controlplane" {
+		t.Errorf("expected the settings to be merged into the template's, got %v", data)
+	}
+
+	dataplane := config("dataplane-config")
+	if err := mutator(dataplane); err != nil {
+		t.Fatal(err)
+	}
+	if level, _, _ := unstructured.NestedString(dataplane.Object, "data", "EDC_LOG_LEVEL"); level != "INFO" {
+		t.Errorf("expected the dataplane to keep its settings, got %s", level)
+	}
+}
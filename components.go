@@ -0,0 +1,100 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Objects of the selectable components, by kind and name. Objects of no component, like postgres and the Vault, are
+// shared by all of them.
+var componentObjects = map[string][]string{
+	"controlplane": {"Deployment/controlplane", "Service/controlplane", "ConfigMap/controlplane-config", "ConfigMap/participants", "Ingress/ingress-controlplane"},
+	"dataplane":    {"Deployment/dataplane", "Service/dataplane", "ConfigMap/dataplane-config", "Ingress/ingress-dataplane"},
+	"identityhub":  {"Deployment/identityhub", "Service/identityhub", "ConfigMap/ih-config", "Ingress/identityhub", "Ingress/did"},
+}
+
+// Components the seed steps talk to
+var seedStepComponents = map[string]string{
+	seedStepAssets:               "controlplane",
+	seedStepPolicies:             "controlplane",
+	seedStepContractDefinitions:  "controlplane",
+	seedStepParticipant:          "identityhub",
+	seedStepDidDocument:          "identityhub",
+	seedStepIssuer:               "identityhub",
+	seedStepCredentials:          "identityhub",
+	seedStepPresignedCredentials: "identityhub",
+}
+
+// validateComponents checks the components selected for a partial stack, or added to it. The dataplane is controlled
+// by the controlplane and can't run without it, the federated catalog crawls with the identity of the participant's
+// identity hub.
+func validateComponents(components []string) error {
+	known := slices.Concat(status.SelectableComponents, status.OptionalComponents)
+	for _, name := range components {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown component %q, must be one of %s", name, strings.Join(known, ", "))
+		}
+	}
+	if slices.Contains(components, "dataplane") && !slices.Contains(components, "controlplane") {
+		return fmt.Errorf("the dataplane requires the controlplane")
+	}
+	if slices.Contains(components, federatedCatalogComponent) && !selectsAll(components) && !slices.Contains(components, "identityhub") {
+		return fmt.Errorf("the federated catalog requires the identityhub")
+	}
+	return nil
+}
+
+// selectsAll reports whether the selection leaves the selectable components as they are, because it names none of
+// them.
+func selectsAll(components []string) bool {
+	return !slices.ContainsFunc(components, func(name string) bool { return slices.Contains(status.SelectableComponents, name) })
+}
+
+// hasComponent reports whether the participant's stack includes the component. All selectable components are
+// included unless some were selected, optional ones only when they were.
+func (p *ParticipantDefinition) hasComponent(name string) bool {
+	if slices.Contains(status.OptionalComponents, name) {
+		return slices.Contains(p.Components, name)
+	}
+	return selectsAll(p.Components) || slices.Contains(p.Components, name)
+}
+
+// seeds reports whether the seed step runs for the participant: it was selected and the component it talks to is
+// part of the stack.
+func (p *ParticipantDefinition) seeds(step string) bool {
+	return p.Seed.runs(step) && p.hasComponent(seedStepComponents[step])
+}
+
+// componentMutator leaves out the objects of the components that weren't selected and records the selection on the
+// participant namespace, for the status checks.
+func componentMutator(components []string) objectMutator {
+	all := selectsAll(components)
+	selected := make([]string, 0, len(components))
+	for _, name := range slices.Concat(status.SelectableComponents, status.OptionalComponents) {
+		if slices.Contains(components, name) || (all && slices.Contains(status.SelectableComponents, name)) {
+			selected = append(selected, name)
+		}
+	}
+	skipped := map[string]bool{}
+	for component, objects := range componentObjects {
+		if !slices.Contains(selected, component) {
+			for _, object := range objects {
+				skipped[object] = true
+			}
+		}
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, map[string]string{status.ComponentsAnnotation: strings.Join(selected, ",")})
+			return nil
+		}
+		if skipped[obj.GetKind()+"/"+obj.GetName()] {
+			return errSkipObject
+		}
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestComponentMutatorProvisionsPartialStack(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", Components: []string{"identityhub"}}
+	plan := provisioningPlan{
+		definition: definition,
+		templates:  manifestSet{Connector: participantYaml, IdentityHub: identityhubYaml},
+		mutators:   definition.mutators(),
+	}
+	var annotation string
+	deployments := &deploymentCollector{}
+	resources, err := plan.applyManifests(nil, context.Background(), deployments.action(func(_ client.Client, _ context.Context, object client.Object) error {
+		if object.GetObjectKind().GroupVersionKind().Kind == "Namespace" {
+			annotation = object.GetAnnotations()[status.ComponentsAnnotation]
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deployments.awaited(); !slices.Equal(got, []string{"postgres", "vault", "identityhub"}) {
+		t.Errorf("expected the identityhub and the shared deployments, got %v", got)
+	}
+	for _, name := range []string{"controlplane", "dataplane", "controlplane-config", "ingress-dataplane"} {
+		if slices.ContainsFunc(resources, func(object appliedObject) bool { return object.Name == name }) {
+			t.Errorf("expected %s to be left out", name)
+		}
+	}
+	if annotation != "identityhub" {
+		t.Errorf("expected the selection on the namespace, got %q", annotation)
+	}
+}
+
+func TestValidateComponents(t *testing.T) {
+	for _, components := range [][]string{nil, {"identityhub"}, {"controlplane", "identityhub"}, {"controlplane", "dataplane"}, {"federatedcatalog"}, {"identityhub", "federatedcatalog"}} {
+		if err := validateComponents(components); err != nil {
+			t.Errorf("%v: %v", components, err)
+		}
+	}
+	for _, components := range [][]string{{"postgres"}, {"dataplane", "identityhub"}, {"controlplane", "federatedcatalog"}} {
+		if err := validateComponents(components); err == nil {
+			t.Errorf("%v: expected an error", components)
+		}
+	}
+}
+
+func TestSeedStepsOfPartialStacks(t *testing.T) {
+	definition := ParticipantDefinition{Components: []string{"identityhub"}}
+	if definition.seeds(seedStepAssets) || !definition.seeds(seedStepDidDocument) {
+		t.Error("expected only the identityhub's seed steps to run")
+	}
+	definition.Seed = &SeedOptions{Steps: []string{seedStepParticipant}}
+	if definition.seeds(seedStepDidDocument) {
+		t.Error("expected the selected seed steps to be honoured")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// configSetting maps a key of the config file to the flag it sets and the environment variable taking precedence
+// over the file.
+type configSetting struct {
+	key  string
+	flag string
+	env  string
+}
+
+var configSettings = []configSetting{
+	{"server.listen", "listen", "PROVISIONER_LISTEN"},
+	{"server.tlsCertFile", "tls-cert-file", "PROVISIONER_TLS_CERT_FILE"},
+	{"server.tlsKeyFile", "tls-key-file", "PROVISIONER_TLS_KEY_FILE"},
+	{"server.tlsSecret", "tls-secret", "PROVISIONER_TLS_SECRET"},
+	{"server.tlsClientCaFile", "tls-client-ca-file", "PROVISIONER_TLS_CLIENT_CA_FILE"},
+	{"server.tlsRequireClientCert", "tls-require-client-cert", "PROVISIONER_TLS_REQUIRE_CLIENT_CERT"},
+	{"server.apiKeys", "api-keys", "PROVISIONER_API_KEYS"},
+	{"server.adminApiKey", "admin-api-key", "PROVISIONER_ADMIN_API_KEY"},
+	{"server.authExempt", "auth-exempt", "PROVISIONER_AUTH_EXEMPT"},
+	{"server.oidcIssuer", "oidc-issuer", "PROVISIONER_OIDC_ISSUER"},
+	{"server.oidcAudience", "oidc-audience", "PROVISIONER_OIDC_AUDIENCE"},
+	{"server.oidcTenantClaim", "oidc-tenant-claim", "PROVISIONER_OIDC_TENANT_CLAIM"},
+	{"server.rateLimit", "rate-limit", "PROVISIONER_RATE_LIMIT"},
+	{"server.idempotencyWindow", "idempotency-window", "PROVISIONER_IDEMPOTENCY_WINDOW"},
+	{"server.bodyLimit", "body-limit", "PROVISIONER_BODY_LIMIT"},
+	{"server.strictPayloads", "strict-payloads", "PROVISIONER_STRICT_PAYLOADS"},
+	{"server.shutdownTimeout", "shutdown-timeout", "PROVISIONER_SHUTDOWN_TIMEOUT"},
+	{"server.callbackBaseUrl", "callback-base-url", "PROVISIONER_CALLBACK_BASE_URL"},
+	{"server.notificationWebhook", "notification-webhook", "PROVISIONER_NOTIFICATION_WEBHOOK"},
+	{"server.notificationSlackWebhook", "notification-slack-webhook", "PROVISIONER_NOTIFICATION_SLACK_WEBHOOK"},
+	{"server.notificationSmtpServer", "notification-smtp-server", "PROVISIONER_NOTIFICATION_SMTP_SERVER"},
+	{"server.notificationSmtpUsername", "notification-smtp-username", "PROVISIONER_NOTIFICATION_SMTP_USERNAME"},
+	{"server.notificationSmtpPassword", "notification-smtp-password", "PROVISIONER_NOTIFICATION_SMTP_PASSWORD"},
+	{"server.notificationEmailFrom", "notification-email-from", "PROVISIONER_NOTIFICATION_EMAIL_FROM"},
+	{"server.notificationEmailTo", "notification-email-to", "PROVISIONER_NOTIFICATION_EMAIL_TO"},
+	{"server.notificationEvents", "notification-events", "PROVISIONER_NOTIFICATION_EVENTS"},
+	{"server.eventsNatsUrl", "events-nats-url", "PROVISIONER_EVENTS_NATS_URL"},
+	{"server.eventsNatsSubject", "events-nats-subject", "PROVISIONER_EVENTS_NATS_SUBJECT"},
+	{"server.eventsKafkaRestUrl", "events-kafka-rest-url", "PROVISIONER_EVENTS_KAFKA_REST_URL"},
+	{"server.eventsKafkaTopic", "events-kafka-topic", "PROVISIONER_EVENTS_KAFKA_TOPIC"},
+	{"server.otlpEndpoint", "otlp-endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT"},
+	{"kube.kubeconfig", "kubeconfig", "KUBECONFIG"},
+	{"kube.context", "context", "PROVISIONER_KUBE_CONTEXT"},
+	{"kube.sharedNamespace", "shared-namespace", "PROVISIONER_SHARED_NAMESPACE"},
+	{"kube.manifests", "manifests", "PROVISIONER_MANIFESTS"},
+	{"kube.flavors", "flavors", "PROVISIONER_FLAVORS"},
+	{"kube.helmBinary", "helm-binary", "PROVISIONER_HELM_BINARY"},
+	{"kube.helmChartVersion", "helm-chart-version", "PROVISIONER_HELM_CHART_VERSION"},
+	{"kube.helmValues", "helm-values", "PROVISIONER_HELM_VALUES"},
+	{"kube.auditNamespace", "audit-namespace", "PROVISIONER_AUDIT_NAMESPACE"},
+	{"kube.statusCacheTtl", "status-cache-ttl", "PROVISIONER_STATUS_CACHE_TTL"},
+	{"kube.statusProbeUrl", "status-probe-url", "PROVISIONER_STATUS_PROBE_URL"},
+	{"kube.statusHealthChecks", "status-health-checks", "PROVISIONER_STATUS_HEALTH_CHECKS"},
+	{"kube.statusProbeTimeout", "status-probe-timeout", "PROVISIONER_STATUS_PROBE_TIMEOUT"},
+	{"kube.statusWatchInterval", "status-watch-interval", "PROVISIONER_STATUS_WATCH_INTERVAL"},
+	{"kube.circuitCooldown", "kube-circuit-cooldown", "PROVISIONER_KUBE_CIRCUIT_COOLDOWN"},
+	{"kube.qps", "kube-qps", "PROVISIONER_KUBE_QPS"},
+	{"kube.burst", "kube-burst", "PROVISIONER_KUBE_BURST"},
+	{"kube.cache", "kube-cache", "PROVISIONER_KUBE_CACHE"},
+	{"kube.cacheSyncPeriod", "kube-cache-sync-period", "PROVISIONER_KUBE_CACHE_SYNC_PERIOD"},
+	{"kube.statusConcurrency", "status-concurrency", "PROVISIONER_STATUS_CONCURRENCY"},
+	{"seeding.managementApiKey", "management-api-key", "PROVISIONER_MANAGEMENT_API_KEY"},
+	{"seeding.identityApiKey", "identity-api-key", "PROVISIONER_IDENTITY_API_KEY"},
+	{"seeding.managementApiKeyFile", "management-api-key-file", "PROVISIONER_MANAGEMENT_API_KEY_FILE"},
+	{"seeding.credentialGenerator", "credential-generator", "PROVISIONER_CREDENTIAL_GENERATOR"},
+	{"seeding.identityApiKeyFile", "identity-api-key-file", "PROVISIONER_IDENTITY_API_KEY_FILE"},
+	{"seeding.secretStore", "secret-store", "PROVISIONER_SECRET_STORE"},
+	{"seeding.httpConfig", "http-config", "PROVISIONER_HTTP_CONFIG"},
+	{"seeding.dataspaceConfig", "dataspace-config", "PROVISIONER_DATASPACE_CONFIG"},
+	{"seeding.seedBundleHosts", "seed-bundle-hosts", "PROVISIONER_SEED_BUNDLE_HOSTS"},
+	{"issuer.url", "issuer-url", "PROVISIONER_ISSUER_URL"},
+	{"issuer.did", "issuer-did", "PROVISIONER_ISSUER_DID"},
+	{"issuer.apiKey", "issuer-api-key", "PROVISIONER_ISSUER_API_KEY"},
+	{"issuer.credentials", "issuer-credentials", "PROVISIONER_ISSUER_CREDENTIALS"},
+	{"issuer.disabled", "disable-issuer", "PROVISIONER_DISABLE_ISSUER"},
+	{"server.didWebHost", "did-web-host", "PROVISIONER_DID_WEB_HOST"},
+	{"vault.url", "vault-url", "PROVISIONER_VAULT_URL"},
+	{"vault.token", "vault-token", "PROVISIONER_VAULT_TOKEN"},
+	{"vault.namespace", "vault-namespace", "PROVISIONER_VAULT_NAMESPACE"},
+	{"vault.participantNamespaces", "vault-participant-namespaces", "PROVISIONER_VAULT_PARTICIPANT_NAMESPACES"},
+	{"vault.mount", "vault-mount", "PROVISIONER_VAULT_MOUNT"},
+	{"kube.routing", "routing", "PROVISIONER_ROUTING"},
+	{"gateway.name", "gateway-name", "PROVISIONER_GATEWAY_NAME"},
+	{"gateway.namespace", "gateway-namespace", "PROVISIONER_GATEWAY_NAMESPACE"},
+	{"gateway.sectionName", "gateway-section-name", "PROVISIONER_GATEWAY_SECTION_NAME"},
+	{"gateway.className", "gateway-class", "PROVISIONER_GATEWAY_CLASS"},
+	{"ha.enabled", "ha", "PROVISIONER_HA"},
+	{"ha.leaseDuration", "ha-lease-duration", "PROVISIONER_HA_LEASE_DURATION"},
+	{"ha.namespace", "ha-namespace", "PROVISIONER_HA_NAMESPACE"},
+	{"provisioning.maxConcurrent", "max-concurrent-provisionings", "PROVISIONER_MAX_CONCURRENT_PROVISIONINGS"},
+	{"provisioning.maxQueued", "max-queued-provisionings", "PROVISIONER_MAX_QUEUED_PROVISIONINGS"},
+	{"provisioning.timeout", "provisioning-timeout", "PROVISIONER_PROVISIONING_TIMEOUT"},
+	{"provisioning.deletionGracePeriod", "deletion-grace-period", "PROVISIONER_DELETION_GRACE_PERIOD"},
+	{"provisioning.participantRbac", "participant-rbac", "PROVISIONER_PARTICIPANT_RBAC"},
+	{"provisioning.ingressProbe", "ingress-probe", "PROVISIONER_INGRESS_PROBE"},
+	{"quotas.maxParticipants", "max-participants", "PROVISIONER_MAX_PARTICIPANTS"},
+	{"quotas.maxParticipantsPerTenant", "max-participants-per-tenant", "PROVISIONER_MAX_PARTICIPANTS_PER_TENANT"},
+	{"postgres.storageClass", "postgres-storage-class", "PROVISIONER_POSTGRES_STORAGE_CLASS"},
+	{"readiness.timeout", "readiness-timeout", "PROVISIONER_READINESS_TIMEOUT"},
+	{"readiness.deployments", "readiness-deployments", "PROVISIONER_READINESS_DEPLOYMENTS"},
+	{"readiness.criticalDeployments", "critical-deployments", "PROVISIONER_CRITICAL_DEPLOYMENTS"},
+	{"readiness.pollInterval", "readiness-poll-interval", "PROVISIONER_READINESS_POLL_INTERVAL"},
+	{"readiness.apiTimeout", "api-readiness-timeout", "PROVISIONER_API_READINESS_TIMEOUT"},
+}
+
+// applyConfigFile sets the flags from the YAML config file at path, e.g.
+//
+//	server:
+//	  listen: ":8080"
+//	readiness:
+//	  timeout: 20m
+//
+// Flags given on the command line and settings given as environment variables take precedence over the file.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file map[string]map[string]any
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	settings := make(map[string]configSetting, len(configSettings))
+	for _, setting := range configSettings {
+		settings[setting.key] = setting
+	}
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	// sorted, so errors are reported for the same key on every start
+	var keys []string
+	for section, values := range file {
+		for key := range values {
+			keys = append(keys, section+"."+key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		setting, ok := settings[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %s in %s", key, path)
+		}
+		if _, ok := os.LookupEnv(setting.env); ok || explicit[setting.flag] {
+			continue
+		}
+		section, name, _ := strings.Cut(key, ".")
+		if err := flags.Set(setting.flag, configValue(file[section][name])); err != nil {
+			return fmt.Errorf("invalid value for %s in %s: %w", key, path, err)
+		}
+	}
+	return nil
+}
+
+// configValue formats a YAML value the way it would be given as flag. Lists are joined with commas.
+func configValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = configValue(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	t.Setenv("PROVISIONER_RATE_LIMIT", "10")
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	listen := flags.String("listen", ":9999", "")
+	apiKeys := flags.String("api-keys", "", "")
+	rateLimit := flags.Int("rate-limit", 10, "")
+	bodyLimit := flags.Int("body-limit", defaultBodyLimit, "")
+	timeout := flags.Duration("readiness-timeout", 15*time.Minute, "")
+	strict := flags.Bool("strict-payloads", false, "")
+	if err := flags.Parse([]string{"--listen", ":7000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeConfig(t, `
+server:
+  listen: ":8080"
+  apiKeys: [one, "acme:two"]
+  rateLimit: 500
+  bodyLimit: 4194304
+  strictPayloads: true
+readiness:
+  timeout: 20m
+`)
+	if err := applyConfigFile(flags, path); err != nil {
+		t.Fatal(err)
+	}
+	if *listen != ":7000" {
+		t.Errorf("flag given on the command line was overridden: %s", *listen)
+	}
+	if *rateLimit != 10 {
+		t.Errorf("setting given as environment variable was overridden: %d", *rateLimit)
+	}
+	if *apiKeys != "one,acme:two" || *bodyLimit != 4194304 || *timeout != 20*time.Minute || !*strict {
+		t.Errorf("settings not applied: %s %d %s %t", *apiKeys, *bodyLimit, *timeout, *strict)
+	}
+}
+
+func TestApplyConfigFileRejectsInvalidSettings(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown key":   "server:\n  port: 8080\n",
+		"invalid value": "readiness:\n  timeout: soon\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.Duration("readiness-timeout", time.Minute, "")
+			if err := applyConfigFile(flags, writeConfig(t, content)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
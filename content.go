@@ -0,0 +1,62 @@
+package main
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// yamlContentType is what YAML responses are sent as. Requests may also use the other media types in yamlContentTypes.
+const yamlContentType = "application/yaml"
+
+var yamlContentTypes = []string{yamlContentType, "application/x-yaml", "text/yaml", "text/x-yaml"}
+
+// isYaml reports whether the Content-Type or media type names YAML.
+func isYaml(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, yamlType := range yamlContentTypes {
+		if mediaType == yamlType {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateContent answers requests accepting YAML rather than JSON with YAML, e.g. for GitOps pipelines keeping
+// participant definitions as YAML, and rejects requests accepting neither with 406. Responses other than JSON, like
+// event streams and the dashboard, are passed on as they are. Errors are rendered here, so that problems are
+// negotiated as well.
+func negotiateContent() fiber.Handler {
+	offers := append([]string{fiber.MIMEApplicationJSON, problemContentType}, yamlContentTypes...)
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			if err := errorHandler(c, err); err != nil {
+				return err
+			}
+		}
+		contentType := string(c.Response().Header.ContentType())
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != fiber.MIMEApplicationJSON && mediaType != problemContentType {
+			return nil
+		}
+		accepted := c.Accepts(offers...)
+		if accepted == "" {
+			c.Response().ResetBody()
+			return errorHandler(c, fiber.NewError(fiber.StatusNotAcceptable, "responses are available as application/json or "+yamlContentType))
+		}
+		if !isYaml(accepted) || strings.TrimSpace(string(c.Response().Body())) == "" {
+			return nil
+		}
+		body, err := yaml.JSONToYAML(c.Response().Body())
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, yamlContentType)
+		return c.Send(body)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestYamlPayloadsAndResponses(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(negotiateContent())
+	parser := payloadParser{strict: true}
+	app.Post("/", func(c *fiber.Ctx) error {
+		var definition ParticipantDefinition
+		if err := parser.parse(c, &definition); err != nil {
+			return err
+		}
+		return c.JSON(definition)
+	})
+	send := func(contentType string, accept string, body string) (int, string, string) {
+		rq := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(body))
+		rq.Header.Set(fiber.HeaderContentType, contentType)
+		if accept != "" {
+			rq.Header.Set(fiber.HeaderAccept, accept)
+		}
+		resp, err := app.Test(rq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), string(content)
+	}
+
+	definition := "participantName: alice\ndid: did:web:alice\nservices:\n  controlplane:\n    type: NodePort\n"
+	code, contentType, body := send("application/yaml", "", definition)
+	if code != fiber.StatusOK || !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) || !strings.Contains(body, `"participantName":"alice"`) {
+		t.Errorf("expected the YAML definition as JSON, got %d %s %s", code, contentType, body)
+	}
+
+	code, contentType, body = send("text/yaml; charset=utf-8", "application/yaml", definition)
+	if code != fiber.StatusOK || contentType != yamlContentType || !strings.Contains(body, "participantName: alice") {
+		t.Errorf("expected a YAML response, got %d %s %s", code, contentType, body)
+	}
+
+	code, contentType, body = send("application/yaml", "application/yaml", "participantName: alice\nmesh:\n  mtls: STRICT\n")
+	if code != fiber.StatusBadRequest || contentType != yamlContentType || !strings.Contains(body, "mesh.mtls") {
+		t.Errorf("expected the unknown field as a YAML problem, got %d %s %s", code, contentType, body)
+	}
+
+	if code, _, _ = send("application/yaml", "", "participantName: [alice"); code != fiber.StatusBadRequest {
+		t.Errorf("expected invalid YAML to be rejected, got %d", code)
+	}
+	if code, _, _ = send(fiber.MIMEApplicationJSON, "text/csv", `{"participantName":"alice"}`); code != fiber.StatusNotAcceptable {
+		t.Errorf("expected 406 for unsupported media types, got %d", code)
+	}
+}
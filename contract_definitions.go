@@ -0,0 +1,46 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"fmt"
+)
+
+// ContractDefinitionSpec describes a contract definition to seed instead of the embedded demo definitions.
+type ContractDefinitionSpec struct {
+	Id               string           `json:"id"`
+	AccessPolicyId   string           `json:"accessPolicyId"`
+	ContractPolicyId string           `json:"contractPolicyId"`
+	AssetIds         []string         `json:"assetIds,omitempty"`
+	PropertyFilters  []PropertyFilter `json:"propertyFilters,omitempty"`
+}
+
+// PropertyFilter selects assets by one of their properties, e.g. {"property": "contenttype", "operator": "=", "value": "application/json"}
+type PropertyFilter struct {
+	Property string `json:"property"`
+	Operator string `json:"operator"`
+	Value    any    `json:"value"`
+}
+
+func (s ContractDefinitionSpec) validate() error {
+	if s.Id == "" || s.AccessPolicyId == "" || s.ContractPolicyId == "" {
+		return fmt.Errorf("contract definition %q: id, accessPolicyId and contractPolicyId are required", s.Id)
+	}
+	for _, filter := range s.PropertyFilters {
+		if filter.Property == "" {
+			return fmt.Errorf("contract definition %s: property filter without property", s.Id)
+		}
+		if !api.SupportedOperators[filter.Operator] {
+			return fmt.Errorf("contract definition %s: unsupported operator %q", s.Id, filter.Operator)
+		}
+	}
+	return nil
+}
+
+// toJson builds the management API request body for the spec.
+func (s ContractDefinitionSpec) toJson() (string, error) {
+	selector := api.NewAssetSelector().AssetIds(s.AssetIds...)
+	for _, filter := range s.PropertyFilters {
+		selector.Property(filter.Property, filter.Operator, filter.Value)
+	}
+	return api.NewContractDefinition(s.Id, s.AccessPolicyId, s.ContractPolicyId, selector.Build()).Json()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Generators of the management API key of new participants
+const (
+	// generatorRandom gives every participant a random key of its own
+	generatorRandom = "random"
+	// generatorStatic gives every participant the key of --management-api-key, as the templates do
+	generatorStatic = "static"
+)
+
+// credentialGenerator returns the management API key of a participant provisioned the first time.
+type credentialGenerator func(participant string) (string, error)
+
+var credentialGenerators = map[string]credentialGenerator{
+	generatorRandom: func(string) (string, error) { return generateApiKey() },
+	generatorStatic: func(string) (string, error) { return defaultCredentials.ManagementApiKey, nil },
+}
+
+// credentialGeneration selects the generator of the management API keys, set with --credential-generator.
+var credentialGeneration = generatorRandom
+
+func validateCredentialGenerator(name string) error {
+	if _, ok := credentialGenerators[name]; !ok {
+		known := make([]string, 0, len(credentialGenerators))
+		for generator := range credentialGenerators {
+			known = append(known, generator)
+		}
+		slices.Sort(known)
+		return fmt.Errorf("unknown credential generator %q, expected one of %s", name, strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// generateCredentials gives a participant provisioned the first time a management API key of its own, unless the
+// definition sets one. Participants that were provisioned before keep the key they run with until it is rotated, even
+// if it was never stored. It reports whether a key was generated, which still has to be stored.
+func generateCredentials(c client.Client, ctx context.Context, definition ParticipantDefinition, creds participantCredentials) (participantCredentials, bool, error) {
+	if creds.stored || (definition.ApiKeys != nil && definition.ApiKeys.ManagementApiKey != "") {
+		return creds, false, nil
+	}
+	provisioned, err := status.IsManagedNamespace(ctx, c, definition.ParticipantName)
+	if err != nil || provisioned {
+		return creds, false, err
+	}
+	key, err := credentialGenerators[credentialGeneration](definition.ParticipantName)
+	if err != nil {
+		return creds, false, fmt.Errorf("generate management API key: %w", err)
+	}
+	creds.ManagementApiKey = key
+	return creds, true, nil
+}
+
+// participantApiKeys are the keys the owner of a participant calls its management and identity APIs with.
+type participantApiKeys struct {
+	ManagementApiKey string `json:"managementApiKey"`
+	IdentityApiKey   string `json:"identityApiKey"`
+	// RotatedAt is when the keys were last rotated, empty for keys never rotated
+	RotatedAt string `json:"rotatedAt,omitempty"`
+}
+
+// getParticipantApiKeys serves GET /api/v1/resources/:participantName/credentials. Every read is audited and the
+// response must not be cached.
+func getParticipantApiKeys(kubeClient client.Client, ctx context.Context, audit *auditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("participantName")
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, name)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		creds, err := loadCredentials(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditReadCredentials, Participant: name})
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(participantApiKeys{ManagementApiKey: creds.ManagementApiKey, IdentityApiKey: creds.IdentityApiKey, RotatedAt: creds.RotatedAt})
+	}
+}
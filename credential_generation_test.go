@@ -0,0 +1,119 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGenerateCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "rotated", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "rotated"}, Data: map[string][]byte{managementApiKeyField: []byte("rotated-key")}},
+	).Build()
+	ctx := context.Background()
+	generate := func(definition ParticipantDefinition) (participantCredentials, bool) {
+		stored, err := loadCredentials(kube, ctx, definition.ParticipantName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, _ := stored.withOverrides(definition.ApiKeys)
+		creds, generated, err := generateCredentials(kube, ctx, definition, creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return creds, generated
+	}
+
+	first, generated := generate(ParticipantDefinition{ParticipantName: "alice"})
+	second, _ := generate(ParticipantDefinition{ParticipantName: "bob"})
+	if !generated || first.ManagementApiKey == defaultCredentials.ManagementApiKey || len(first.ManagementApiKey) < 43 {
+		t.Errorf("expected a random management API key for a new participant, got %q", first.ManagementApiKey)
+	}
+	if first.ManagementApiKey == second.ManagementApiKey {
+		t.Error("expected every participant to get a key of its own")
+	}
+	if first.IdentityApiKey != defaultCredentials.IdentityApiKey {
+		t.Errorf("expected the identity hub to keep its bootstrap key, got %q", first.IdentityApiKey)
+	}
+	for name, definition := range map[string]ParticipantDefinition{
+		"stored key":       {ParticipantName: "rotated"},
+		"never stored key": {ParticipantName: "legacy"},
+		"given key":        {ParticipantName: "carol", ApiKeys: &ApiKeyOverrides{ManagementApiKey: "chosen"}},
+	} {
+		creds, generated := generate(definition)
+		if generated {
+			t.Errorf("%s: expected no key to be generated, got %q", name, creds.ManagementApiKey)
+		}
+	}
+
+	defer func(generator string) { credentialGeneration = generator }(credentialGeneration)
+	credentialGeneration = generatorStatic
+	if creds, _ := generate(ParticipantDefinition{ParticipantName: "dave"}); creds.ManagementApiKey != defaultCredentials.ManagementApiKey {
+		t.Errorf("expected the static generator to give the default key, got %q", creds.ManagementApiKey)
+	}
+}
+
+func TestValidateCredentialGenerator(t *testing.T) {
+	for _, name := range []string{generatorRandom, generatorStatic} {
+		if err := validateCredentialGenerator(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := validateCredentialGenerator("vault"); err == nil {
+		t.Error("expected an unknown generator to be rejected")
+	}
+}
+
+func TestGetParticipantApiKeys(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "alice"},
+			Data: map[string][]byte{managementApiKeyField: []byte("generated-key"), identityApiKeyField: []byte("identity-key")}},
+	).Build()
+	ctx := context.Background()
+	audit := &auditLog{client: kube, namespace: "mvd-provisioner"}
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/:participantName/credentials", getParticipantApiKeys(kube, ctx, audit))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/alice/credentials", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys participantApiKeys
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys.ManagementApiKey != "generated-key" || keys.IdentityApiKey != "identity-key" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+	if resp.Header.Get(fiber.HeaderCacheControl) != "no-store" {
+		t.Error("expected the keys not to be cached")
+	}
+	entries := &corev1.ConfigMapList{}
+	if err := kube.List(ctx, entries, client.MatchingLabels{auditLabel: "true"}); err != nil || len(entries.Items) != 1 {
+		t.Errorf("expected the read to be audited, got %d entries, %v", len(entries.Items), err)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/bob/credentials", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("expected 404 for an unknown participant, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Per-participant API keys are kept in this secret once they were rotated away from the template defaults.
+const credentialsSecretName = "provisioner-credentials"
+
+const (
+	managementApiKeyField = "management-api-key"
+	identityApiKeyField   = "identity-api-key"
+	callbackTokenField    = "callback-token"
+	rotatedAtField        = "rotated-at"
+)
+
+// defaultCredentials are the keys of participants whose keys were never rotated or generated. They are rendered into
+// the templates and can be changed with --management-api-key and --identity-api-key, or read from mounted secrets with
+// --management-api-key-file and --identity-api-key-file. New participants get a management API key of their own, see
+// generateCredentials.
+var defaultCredentials = participantCredentials{
+	ManagementApiKey: "password",
+	IdentityApiKey:   "c3VwZXItdXNlcg==.c3VwZXItc2VjcmV0LWtleQo=",
+}
+
+// Controlplane setting holding the management API key
+const managementApiKeySetting = "WEB_HTTP_MANAGEMENT_AUTH_KEY"
+
+// Identity hub setting holding the super-user key it is bootstrapped with
+const superUserKeySetting = "EDC_IH_API_SUPERUSER_KEY"
+
+type participantCredentials struct {
+	ManagementApiKey string
+	IdentityApiKey   string
+	// CallbackToken authenticates the connector's event callbacks, empty until callbacks are configured
+	CallbackToken string
+	// RotatedAt is the RFC 3339 time of the last successful key rotation, empty for keys never rotated
+	RotatedAt string
+	// stored is set when the keys were read from the participant's credentials secret
+	stored bool
+}
+
+// ApiKeyOverrides replaces the default API keys a participant's components are provisioned with.
+type ApiKeyOverrides struct {
+	ManagementApiKey string `json:"managementApiKey,omitempty"`
+	// IdentityApiKey is the super-user key the identity hub is bootstrapped with
+	IdentityApiKey string `json:"identityApiKey,omitempty"`
+}
+
+// loadKeyFiles sets the default credentials from the files, e.g. Secret volumes. Empty paths keep the keys given by
+// flags, a trailing newline is dropped.
+func loadKeyFiles(managementApiKeyFile string, identityApiKeyFile string) error {
+	for path, key := range map[string]*string{managementApiKeyFile: &defaultCredentials.ManagementApiKey, identityApiKeyFile: &defaultCredentials.IdentityApiKey} {
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if *key = strings.TrimSpace(string(content)); *key == "" {
+			return fmt.Errorf("key file %s is empty", path)
+		}
+	}
+	return nil
+}
+
+// withOverrides returns the credentials with the keys given in the definition. Keys of participants that were
+// provisioned before are only replaced if the definition gives them.
+func (creds participantCredentials) withOverrides(overrides *ApiKeyOverrides) (participantCredentials, bool) {
+	if overrides == nil {
+		return creds, false
+	}
+	changed := false
+	if overrides.ManagementApiKey != "" && overrides.ManagementApiKey != creds.ManagementApiKey {
+		creds.ManagementApiKey, changed = overrides.ManagementApiKey, true
+	}
+	if overrides.IdentityApiKey != "" && overrides.IdentityApiKey != creds.IdentityApiKey {
+		creds.IdentityApiKey, changed = overrides.IdentityApiKey, true
+	}
+	return creds, changed
+}
+
+// superUserKey returns the key the identity hub is bootstrapped with, the default unless the definition overrides it.
+func superUserKey(overrides *ApiKeyOverrides) string {
+	if overrides != nil && overrides.IdentityApiKey != "" {
+		return overrides.IdentityApiKey
+	}
+	return defaultCredentials.IdentityApiKey
+}
+
+// redacted returns a copy of the definition without its API keys, for storing it where keys must not end up, e.g.
+// the audit log. The keys themselves are kept in the participant's credentials secret.
+func (p ParticipantDefinition) redacted() ParticipantDefinition {
+	if p.ApiKeys == nil {
+		return p
+	}
+	keys := *p.ApiKeys
+	if keys.ManagementApiKey != "" {
+		keys.ManagementApiKey = redacted
+	}
+	if keys.IdentityApiKey != "" {
+		keys.IdentityApiKey = redacted
+	}
+	p.ApiKeys = &keys
+	return p
+}
+
+// unredacted drops the redacted API keys of a stored definition, so provisioning it again keeps the keys of the
+// participant's credentials secret instead of overriding them.
+func (p ParticipantDefinition) unredacted() ParticipantDefinition {
+	if p.ApiKeys == nil {
+		return p
+	}
+	keys := *p.ApiKeys
+	if keys.ManagementApiKey == redacted {
+		keys.ManagementApiKey = ""
+	}
+	if keys.IdentityApiKey == redacted {
+		keys.IdentityApiKey = ""
+	}
+	p.ApiKeys = &keys
+	if keys == (ApiKeyOverrides{}) {
+		p.ApiKeys = nil
+	}
+	return p
+}
+
+// loadCredentials returns the API keys of a participant's components, falling back to the template defaults
+// when no credentials secret exists in the namespace.
+func loadCredentials(c client.Client, ctx context.Context, namespace string) (participantCredentials, error) {
+	creds := participantCredentials{
+		ManagementApiKey: defaultCredentials.ManagementApiKey,
+		IdentityApiKey:   defaultCredentials.IdentityApiKey,
+	}
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: credentialsSecretName}, secret)
+	if apierrors.IsNotFound(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+	creds.stored = true
+	if key, ok := secret.Data[managementApiKeyField]; ok {
+		creds.ManagementApiKey = string(key)
+	}
+	if key, ok := secret.Data[identityApiKeyField]; ok {
+		creds.IdentityApiKey = string(key)
+	}
+	creds.CallbackToken = string(secret.Data[callbackTokenField])
+	creds.RotatedAt = string(secret.Data[rotatedAtField])
+	return creds, nil
+}
+
+func storeCredentials(c client.Client, ctx context.Context, namespace string, creds participantCredentials) error {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      credentialsSecretName,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			managementApiKeyField: []byte(creds.ManagementApiKey),
+			identityApiKeyField:   []byte(creds.IdentityApiKey),
+		},
+	}
+	if creds.CallbackToken != "" {
+		secret.Data[callbackTokenField] = []byte(creds.CallbackToken)
+	}
+	if creds.RotatedAt != "" {
+		secret.Data[rotatedAtField] = []byte(creds.RotatedAt)
+	}
+	return applyResource(c, ctx, secret)
+}
+
+// credentialsMutator keeps the management API key of rendered manifests in line with the stored credentials, so
+// re-applying the templates doesn't reset a rotated key. The identity hub is bootstrapped with the default super-user
+// key, rotated keys are created through its API. An empty superUserKey keeps the key of the manifests, e.g. of a
+// revision rolled back to.
+func credentialsMutator(creds participantCredentials, superUserKey string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "ConfigMap" {
+			return nil
+		}
+		switch obj.GetName() {
+		case "controlplane-config":
+			return unstructured.SetNestedField(obj.Object, creds.ManagementApiKey, "data", managementApiKeySetting)
+		case "ih-config":
+			if superUserKey == "" {
+				return nil
+			}
+			return unstructured.SetNestedField(obj.Object, superUserKey, "data", superUserKeySetting)
+		}
+		return nil
+	}
+}
+
+// generateApiKey returns a random, URL-safe key with 256 bits of entropy.
+func generateApiKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
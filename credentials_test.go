@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKeyFiles(t *testing.T) {
+	previous := defaultCredentials
+	t.Cleanup(func() { defaultCredentials = previous })
+
+	dir := t.TempDir()
+	managementKeyFile := filepath.Join(dir, "management-api-key")
+	if err := os.WriteFile(managementKeyFile, []byte("mounted-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadKeyFiles(managementKeyFile, ""); err != nil {
+		t.Fatal(err)
+	}
+	if defaultCredentials.ManagementApiKey != "mounted-key" {
+		t.Errorf("expected the key of the file, got %q", defaultCredentials.ManagementApiKey)
+	}
+	if defaultCredentials.IdentityApiKey != previous.IdentityApiKey {
+		t.Errorf("identity key changed without a file: %q", defaultCredentials.IdentityApiKey)
+	}
+
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadKeyFiles("", emptyFile); err == nil {
+		t.Error("expected empty key files to be rejected")
+	}
+}
+
+func TestCredentialOverrides(t *testing.T) {
+	stored := participantCredentials{ManagementApiKey: "rotated", IdentityApiKey: "identity", CallbackToken: "token"}
+
+	if creds, changed := stored.withOverrides(nil); changed || creds != stored {
+		t.Errorf("expected the stored credentials, got %+v", creds)
+	}
+	if _, changed := stored.withOverrides(&ApiKeyOverrides{ManagementApiKey: "rotated"}); changed {
+		t.Error("unchanged key reported as changed")
+	}
+	creds, changed := stored.withOverrides(&ApiKeyOverrides{IdentityApiKey: "staging-identity"})
+	if !changed || creds.IdentityApiKey != "staging-identity" || creds.ManagementApiKey != "rotated" || creds.CallbackToken != "token" {
+		t.Errorf("expected only the identity key to be replaced, got %+v", creds)
+	}
+
+	if key := superUserKey(&ApiKeyOverrides{IdentityApiKey: "staging-identity"}); key != "staging-identity" {
+		t.Errorf("expected the overridden super-user key, got %q", key)
+	}
+	if key := superUserKey(&ApiKeyOverrides{ManagementApiKey: "staging"}); key != defaultCredentials.IdentityApiKey {
+		t.Errorf("expected the default super-user key, got %q", key)
+	}
+}
+
+func TestRedactedDefinition(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "alice", ApiKeys: &ApiKeyOverrides{ManagementApiKey: "secret"}}
+	redactedDefinition := definition.redacted()
+	if redactedDefinition.ApiKeys.ManagementApiKey != redacted || redactedDefinition.ApiKeys.IdentityApiKey != "" {
+		t.Errorf("expected the management key to be redacted, got %+v", redactedDefinition.ApiKeys)
+	}
+	if definition.ApiKeys.ManagementApiKey != "secret" {
+		t.Error("redacting changed the definition")
+	}
+}
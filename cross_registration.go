@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Namespace annotation tracking the registration of a participant with the other members of its dataspace: pending
+// until the participant is ready, then when it was registered
+const registrationAnnotation = "aruba-provisioner/registration"
+
+const registrationPending = "pending"
+
+// RegistrationConfig registers the participants of a dataspace with its other members once they become ready, so
+// they can discover each other without follow-up calls. Until then, federated catalogs don't crawl them.
+type RegistrationConfig struct {
+	// Url is the registration service of the dataspace the participants are announced to, if it has one
+	Url string `json:"url,omitempty"`
+	// ApiKey is sent in the X-Api-Key header to the registration service
+	ApiKey string `json:"apiKey,omitempty"`
+}
+
+// validate checks that the registration service is an http or https URL.
+func (r *RegistrationConfig) validate() error {
+	if r == nil || r.Url == "" {
+		return nil
+	}
+	if u, err := url.Parse(r.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("registration url %q must be an http or https URL", r.Url)
+	}
+	return nil
+}
+
+// registrationRequest announces a participant to the registration service of its dataspace.
+type registrationRequest struct {
+	ParticipantName string `json:"participantName"`
+	Did             string `json:"did"`
+	Dataspace       string `json:"dataspace"`
+	// ProtocolUrl is the dataspace protocol endpoint counterparts request the participant's catalog from
+	ProtocolUrl string `json:"protocolUrl"`
+}
+
+// isRegistered tells whether the participant was registered with its dataspace.
+func isRegistered(c client.Client, ctx context.Context, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	registration := namespace.Annotations[registrationAnnotation]
+	return registration != "" && registration != registrationPending, nil
+}
+
+// registrationMutator marks the namespace of a participant that isn't registered yet, leaving it out of the
+// participant lists of federated catalogs until it is ready.
+func registrationMutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, map[string]string{registrationAnnotation: registrationPending})
+		}
+		return nil
+	}
+}
+
+// registerWithDataspace registers a ready participant with the other members of its dataspace: the registration
+// service learns its protocol endpoint and the federated catalogs of the dataspace start crawling it. Participants
+// that fail to register stay pending and are registered again when they are updated.
+func registerWithDataspace(ctx context.Context, c client.Client, definition ParticipantDefinition, registration RegistrationConfig, httpClient http.Client) error {
+	if registration.Url != "" {
+		body, err := json.Marshal(registrationRequest{
+			ParticipantName: definition.ParticipantName,
+			Did:             definition.Did,
+			Dataspace:       dataspaceOf(definition),
+			ProtocolUrl:     protocolEndpointUrl(definition),
+		})
+		if err != nil {
+			return err
+		}
+		rq, err := http.NewRequestWithContext(ctx, http.MethodPost, registration.Url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		rq.Header.Set("Content-Type", "application/json")
+		if registration.ApiKey != "" {
+			rq.Header.Set("X-Api-Key", registration.ApiKey)
+		}
+		resp, err := httpClient.Do(rq)
+		if err != nil {
+			return fmt.Errorf("registration service: %w", err)
+		}
+		_ = resp.Body.Close()
+		// a participant registered before is known to the service already
+		if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusConflict {
+			return fmt.Errorf("registration service responded with %s", resp.Status)
+		}
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, registrationAnnotation, time.Now().UTC().Format(time.RFC3339))
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: definition.ParticipantName}}
+	if err := c.Patch(ctx, namespace, client.RawPatch(types.MergePatchType, []byte(patch))); err != nil {
+		return err
+	}
+	return refreshFederatedCatalogs(c, ctx)
+}
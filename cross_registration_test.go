@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegisterWithDataspace(t *testing.T) {
+	var announced registrationRequest
+	var apiKey string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-Api-Key")
+		_ = json.NewDecoder(r.Body).Decode(&announced)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer service.Close()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	list := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        catalogParticipantsConfigMapName,
+			Namespace:   "acme",
+			Labels:      map[string]string{catalogParticipantsLabel: "true"},
+			Annotations: map[string]string{dataspaceAnnotation: "catena", catalogIdentityAnnotation: "acme"},
+		},
+		Data: map[string]string{catalogParticipantsKey: "{}\n"},
+	}
+	beta := participantNamespace("beta", "catena")
+	beta.Annotations[registrationAnnotation] = registrationPending
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(participantNamespace("acme", "catena"), beta, list).Build()
+	ctx := context.Background()
+
+	participants, err := dataspaceParticipants(kube, ctx, "catena", "acme")
+	if err != nil || len(participants) != 0 {
+		t.Fatalf("expected the pending participant not to be crawled, got %v %v", participants, err)
+	}
+	if registered, err := isRegistered(kube, ctx, "beta"); err != nil || registered {
+		t.Fatalf("expected beta to wait for its registration, got %v %v", registered, err)
+	}
+
+	definition := ParticipantDefinition{ParticipantName: "beta", Did: "did:web:beta", Dataspace: "catena"}
+	if err := registerWithDataspace(ctx, kube, definition, RegistrationConfig{Url: service.URL, ApiKey: "secret"}, http.Client{}); err != nil {
+		t.Fatal(err)
+	}
+	if announced.Did != "did:web:beta" || announced.Dataspace != "catena" || announced.ProtocolUrl != protocolEndpointUrl(definition) || apiKey != "secret" {
+		t.Errorf("unexpected registration %+v with key %q", announced, apiKey)
+	}
+	if registered, err := isRegistered(kube, ctx, "beta"); err != nil || !registered {
+		t.Errorf("expected beta to be registered, got %v %v", registered, err)
+	}
+	refreshed := &corev1.ConfigMap{}
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(list), refreshed); err != nil {
+		t.Fatal(err)
+	}
+	if crawled := catalogParticipants(t, refreshed.Data[catalogParticipantsKey]); crawled["beta"] != "did:web:beta" {
+		t.Errorf("expected the catalog of acme to crawl beta, got %v", crawled)
+	}
+
+	// a rejected registration leaves the participant pending
+	service.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	if err := registerWithDataspace(ctx, kube, ParticipantDefinition{ParticipantName: "acme"}, RegistrationConfig{Url: service.URL}, http.Client{}); err == nil {
+		t.Error("expected the rejected registration to fail")
+	}
+	if err := (&RegistrationConfig{Url: "registry.catena"}).validate(); err == nil {
+		t.Error("expected a registration URL without scheme to be rejected")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	_ "embed"
+)
+
+//go:embed ui/index.html
+var dashboardHtml string
+
+//go:embed ui/participant.html
+var participantDetailHtml string
+
+//go:embed ui/signin.html
+var signInHtml string
+
+const dashboardRefreshInterval = 5 * time.Second
+
+const (
+	// Cookie holding the bearer token the browser signed in to the dashboard with
+	sessionCookie = "provisioner_session"
+	// Browsers drop the session cookie after this period, tokens expiring earlier end the session with them
+	sessionLifetime = 8 * time.Hour
+)
+
+// registerDashboard serves the embedded web dashboard at /ui and the status stream feeding it. The dashboard is
+// authenticated like the API and shows the participants in the caller's scope only. Browsers can't add the
+// Authorization header to page loads and EventSource requests, they sign in once and send the token in a session
+// cookie instead. Pages requested without a valid session show the sign-in form.
+func registerDashboard(app *fiber.App, authenticate fiber.Handler, kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) {
+	ui := app.Group("/ui", withSession)
+	ui.Post("/session", authenticate, startSession)
+	ui.Get("/", signIn(authenticate), func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.SendString(dashboardHtml)
+	})
+	ui.Get("/participants/:participantName", signIn(authenticate), requireScope(kubeClient, ctx), func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.SendString(participantDetailHtml)
+	})
+	ui.Get("/participants/:participantName/badge.svg", authenticate, requireScope(kubeClient, ctx), statusBadge(ctx, statusChecker))
+	ui.Get("/stream", authenticate, func(c *fiber.Ctx) error {
+		participant := strings.Clone(c.Query("participant"))
+		tenant := strings.Clone(tenantOf(c))
+		if participant != "" {
+			ok, err := inScope(kubeClient, ctx, tenant, participant)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fiber.NewError(fiber.StatusNotFound, "participant not found")
+			}
+		}
+		return streamJson(c, ctx, dashboardRefreshInterval, nil, func() (any, error) {
+			if participant != "" {
+				return statusChecker.GetStatus(ctx, participant, status.AllFields)
+			}
+			return participantOverview(kubeClient, ctx, statusChecker, tenant)
+		})
+	})
+}
+
+// withSession authenticates requests without an Authorization header with the token of the session cookie.
+func withSession(c *fiber.Ctx) error {
+	if token := c.Cookies(sessionCookie); token != "" && c.Get(fiber.HeaderAuthorization) == "" {
+		c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	return c.Next()
+}
+
+// startSession stores the bearer token the request was authenticated with in the session cookie. It can only be read
+// by the provisioner and isn't sent with requests of other sites.
+func startSession(c *fiber.Ctx) error {
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok && token != "" {
+		c.Cookie(&fiber.Cookie{
+			Name:     sessionCookie,
+			Value:    token,
+			MaxAge:   int(sessionLifetime.Seconds()),
+			Secure:   c.Protocol() == "https",
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteStrictMode,
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// signIn serves the sign-in form instead of the page to callers that aren't authenticated.
+func signIn(authenticate fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := authenticate(c)
+		var rejected *fiber.Error
+		if errors.As(err, &rejected) && rejected.Code == fiber.StatusUnauthorized {
+			c.Type("html")
+			return c.Status(fiber.StatusUnauthorized).SendString(signInHtml)
+		}
+		return err
+	}
+}
+
+// participantOverview returns the statuses of the participants of the tenant, of all participants if empty.
+func participantOverview(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker, tenant string) ([]status.ParticipantStatus, error) {
+	names, err := discoverParticipants(kubeClient, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if names, err = filterScope(kubeClient, ctx, tenant, names); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	statuses := make([]status.ParticipantStatus, 0, len(names))
+	for _, name := range names {
+		participantStatus, err := statusChecker.GetStatus(ctx, name, []status.Field{status.FieldComponents})
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, participantStatus)
+	}
+	return statuses, nil
+}
+
+var badgeColors = map[status.ProvisioningStatus]string{
+	status.StatusReady:        "#4c1",
+	status.StatusProvisioning: "#007ec6",
+	status.StatusSeeding:      "#007ec6",
+	status.StatusSeedFailed:   "#e05d44",
+	status.StatusDegraded:     "#fe7d37",
+	status.StatusFailed:       "#e05d44",
+	status.StatusTerminating:  "#9f9f9f",
+	status.StatusOrphaned:     "#dfb317",
+	status.StatusDeleting:     "#9f9f9f",
+	status.StatusDeleted:      "#9f9f9f",
+}
+
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+<title>%[3]s: %[4]s</title>
+<rect width="%[2]d" height="20" fill="#555"/>
+<rect x="%[2]d" width="%[5]d" height="20" fill="%[6]s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="14">%[3]s</text>
+<text x="%[8]d" y="14">%[4]s</text>
+</g>
+</svg>`
+
+// statusBadge renders a shields-style SVG badge with the participant's current status.
+func statusBadge(ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("participantName")
+		participantStatus, err := statusChecker.GetStatus(ctx, name, nil)
+		if err != nil {
+			return err
+		}
+		color, ok := badgeColors[participantStatus.Status]
+		if !ok {
+			color = "#9f9f9f"
+		}
+		label := escapeXml(name)
+		value := string(participantStatus.Status)
+		labelWidth := 7*len(name) + 10
+		valueWidth := 7*len(value) + 10
+
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		return c.SendString(fmt.Sprintf(badgeTemplate, labelWidth+valueWidth, labelWidth, label, value, valueWidth, color,
+			labelWidth/2, labelWidth+valueWidth/2))
+	}
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func escapeXml(s string) string {
+	return xmlEscaper.Replace(s)
+}
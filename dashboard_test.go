@@ -0,0 +1,253 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dashboardApp serves the dashboard of the participants acme-connector of tenant acme and globex-connector of tenant
+// globex. Its context is cancelled, so streams end after their first event.
+func dashboardApp(t *testing.T, objects ...client.Object) *fiber.App {
+	t.Helper()
+	for _, participant := range []string{"acme-connector", "globex-connector"} {
+		tenant, _, _ := strings.Cut(participant, "-")
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: participant,
+			Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue, tenantLabel: tenant}}})
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	auth := newApiAuth("static-key,acme:acme-key", "admin-key", "", "", "", "")
+	registerDashboard(app, auth.middleware(), kube, ctx, status.NewStatusChecker(context.Background(), kube, 0))
+	return app
+}
+
+// readyComponents returns the ready deployments of the critical components of the participant.
+func readyComponents(namespace string) []client.Object {
+	var objects []client.Object
+	for _, name := range status.CriticalDeployments {
+		objects = append(objects, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1, AvailableReplicas: 1}})
+	}
+	return objects
+}
+
+// streamed returns the status code and the payload of the first event of the stream.
+func streamed(t *testing.T, app *fiber.App, target string, key string) (int, string) {
+	t.Helper()
+	request := httptest.NewRequest("GET", target, nil)
+	if key != "" {
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+	}
+	resp, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	payload, _ := strings.CutPrefix(strings.SplitN(string(body), "\n", 2)[0], "data: ")
+	return resp.StatusCode, payload
+}
+
+func TestDashboardRequiresCredentials(t *testing.T) {
+	app := dashboardApp(t)
+	for _, target := range []string{"/ui", "/ui/participants/acme-connector", "/ui/stream", "/ui/stream?participant=globex-connector"} {
+		if code, _ := streamed(t, app, target, ""); code != fiber.StatusUnauthorized {
+			t.Errorf("%s: expected 401 without credentials, got %d", target, code)
+		}
+		if code, _ := streamed(t, app, target, "wrong-key"); code != fiber.StatusUnauthorized {
+			t.Errorf("%s: expected 401 with an unknown key, got %d", target, code)
+		}
+	}
+	if code, _ := streamed(t, app, "/ui", "static-key"); code != fiber.StatusOK {
+		t.Errorf("expected the dashboard to be served to authenticated callers, got %d", code)
+	}
+}
+
+func TestDashboardSession(t *testing.T) {
+	app := dashboardApp(t)
+	// browserRequest sends the request the way a browser loads a page or opens an EventSource, without an
+	// Authorization header and with the session cookie if any
+	browserRequest := func(target string, session string) (int, string) {
+		t.Helper()
+		request := httptest.NewRequest("GET", target, nil)
+		if session != "" {
+			request.AddCookie(&http.Cookie{Name: sessionCookie, Value: session})
+		}
+		resp, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	signIn := func(key string) *http.Response {
+		t.Helper()
+		request := httptest.NewRequest("POST", "/ui/session", nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+		resp, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, target := range []string{"/ui", "/ui/participants/acme-connector"} {
+		if code, page := browserRequest(target, ""); code != fiber.StatusUnauthorized || page != signInHtml {
+			t.Errorf("%s: expected the sign-in form without a session, got %d", target, code)
+		}
+	}
+	if resp := signIn("wrong-key"); resp.StatusCode != fiber.StatusUnauthorized || len(resp.Cookies()) != 0 {
+		t.Errorf("expected unknown keys not to start a session, got %d", resp.StatusCode)
+	}
+	resp := signIn("acme-key")
+	cookies := resp.Cookies()
+	if resp.StatusCode != fiber.StatusNoContent || len(cookies) != 1 || cookies[0].Name != sessionCookie {
+		t.Fatalf("expected the session cookie, got %d %v", resp.StatusCode, cookies)
+	}
+	if !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteStrictMode || cookies[0].MaxAge <= 0 {
+		t.Errorf("expected an HttpOnly, same-site session cookie, got %+v", cookies[0])
+	}
+	session := cookies[0].Value
+
+	if code, page := browserRequest("/ui", session); code != fiber.StatusOK || page != dashboardHtml {
+		t.Errorf("expected the dashboard to be served with the session, got %d", code)
+	}
+	if code, page := browserRequest("/ui/participants/acme-connector", session); code != fiber.StatusOK || page != participantDetailHtml {
+		t.Errorf("expected the participant page to be served with the session, got %d", code)
+	}
+	code, payload := browserRequest("/ui/stream", session)
+	if code != fiber.StatusOK || !strings.Contains(payload, "acme-connector") || strings.Contains(payload, "globex-connector") {
+		t.Errorf("expected the stream of the tenant's participants with the session, got %d %s", code, payload)
+	}
+	if code, _ := browserRequest("/ui/participants/acme-connector/badge.svg", session); code != fiber.StatusOK {
+		t.Errorf("expected the badge to be served with the session, got %d", code)
+	}
+	for _, target := range []string{"/ui/participants/globex-connector", "/ui/stream?participant=globex-connector", "/ui/participants/globex-connector/badge.svg"} {
+		if code, _ := browserRequest(target, session); code != fiber.StatusNotFound {
+			t.Errorf("%s: expected the session to be scoped to the tenant, got %d", target, code)
+		}
+	}
+	if code, _ := browserRequest("/ui/stream", "wrong-key"); code != fiber.StatusUnauthorized {
+		t.Errorf("expected sessions with unknown keys to be rejected, got %d", code)
+	}
+}
+
+func TestDashboardStreamIsScoped(t *testing.T) {
+	app := dashboardApp(t)
+
+	code, payload := streamed(t, app, "/ui/stream", "acme-key")
+	var overview []status.ParticipantStatus
+	if err := json.Unmarshal([]byte(payload), &overview); code != fiber.StatusOK || err != nil {
+		t.Fatalf("expected the overview, got %d %q: %v", code, payload, err)
+	}
+	if len(overview) != 1 || overview[0].Name != "acme-connector" {
+		t.Errorf("expected only the participants of the tenant, got %+v", overview)
+	}
+	if _, payload := streamed(t, app, "/ui/stream", "static-key"); !strings.Contains(payload, "globex-connector") {
+		t.Errorf("expected unscoped callers to see all participants, got %s", payload)
+	}
+
+	for _, target := range []string{"/ui/stream?participant=globex-connector", "/ui/participants/globex-connector"} {
+		if code, _ := streamed(t, app, target, "acme-key"); code != fiber.StatusNotFound {
+			t.Errorf("%s: expected the participant of another tenant to be hidden, got %d", target, code)
+		}
+	}
+	code, payload = streamed(t, app, "/ui/stream?participant=acme-connector", "acme-key")
+	var participant status.ParticipantStatus
+	if err := json.Unmarshal([]byte(payload), &participant); code != fiber.StatusOK || err != nil || participant.Name != "acme-connector" {
+		t.Errorf("expected the status of the participant, got %d %q: %v", code, payload, err)
+	}
+}
+
+func TestDashboardStreamPayload(t *testing.T) {
+	app := dashboardApp(t, readyComponents("acme-connector")...)
+
+	_, payload := streamed(t, app, "/ui/stream", "static-key")
+	var overview []status.ParticipantStatus
+	if err := json.Unmarshal([]byte(payload), &overview); err != nil {
+		t.Fatalf("expected the overview, got %q: %v", payload, err)
+	}
+	states := map[string]status.ProvisioningStatus{}
+	for _, participant := range overview {
+		states[participant.Name] = participant.Status
+	}
+	if states["acme-connector"] != status.StatusReady || states["globex-connector"] != status.StatusOrphaned {
+		t.Errorf("unexpected states %v", states)
+	}
+
+	_, payload = streamed(t, app, "/ui/stream?participant=acme-connector", "acme-key")
+	var participant status.ParticipantStatus
+	if err := json.Unmarshal([]byte(payload), &participant); err != nil {
+		t.Fatalf("expected the status of the participant, got %q: %v", payload, err)
+	}
+	if participant.Status != status.StatusReady || len(participant.Components) != len(status.CriticalDeployments) {
+		t.Errorf("expected the ready participant with its components, got %+v", participant)
+	}
+}
+
+func TestStatusBadge(t *testing.T) {
+	managed := map[string]string{status.ManagedByLabel: status.ManagedByValue}
+	now := metav1.Now()
+	objects := []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "orphaned", Labels: managed}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "terminating", Labels: managed, DeletionTimestamp: &now, Finalizers: []string{"kubernetes"}}}}
+	for _, name := range []string{"ready", "seeding", "seed-failed", "terminating"} {
+		if name != "terminating" {
+			objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: managed}})
+		}
+		objects = append(objects, readyComponents(name)...)
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	statusChecker := status.NewStatusChecker(context.Background(), kube, 0)
+	statusChecker.SetSeeding("seeding", status.SeedingRunning, "")
+	statusChecker.SetSeeding("seed-failed", status.SeedingFailed, "seed assets: 503")
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/resources/:participantName/badge.svg", statusBadge(context.Background(), statusChecker))
+
+	for name, expected := range map[string]struct {
+		state status.ProvisioningStatus
+		color string
+	}{
+		"ready":       {status.StatusReady, "#4c1"},
+		"seeding":     {status.StatusSeeding, "#007ec6"},
+		"seed-failed": {status.StatusSeedFailed, "#e05d44"},
+		"orphaned":    {status.StatusOrphaned, "#dfb317"},
+		"terminating": {status.StatusTerminating, "#9f9f9f"},
+		"missing":     {status.StatusNotFound, "#9f9f9f"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/resources/"+name+"/badge.svg", nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "image/svg+xml" {
+			t.Errorf("%s: expected an SVG badge, got %d %s", name, resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+			continue
+		}
+		badge := string(body)
+		if !strings.Contains(badge, fmt.Sprintf(`aria-label="%s: %s"`, name, expected.state)) || !strings.Contains(badge, fmt.Sprintf(`fill="%s"`, expected.color)) {
+			t.Errorf("%s: expected a %s badge in %s, got %s", name, expected.state, expected.color, badge)
+		}
+	}
+}
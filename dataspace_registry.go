@@ -0,0 +1,300 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Dataspaces created through the API are kept in ConfigMaps in the provisioner's namespace, labelled with their name,
+// so they apply to all replicas and survive restarts
+const (
+	dataspaceConfigLabel = "aruba-provisioner/dataspace-config"
+	dataspaceConfigKey   = "dataspace.json"
+)
+
+// Sources of the settings of a dataspace
+const (
+	dataspaceFromConfig = "config"
+	dataspaceFromApi    = "api"
+)
+
+// dataspaceRegistry holds the dataspaces of the dataspace config file and those created through the API. Dataspaces
+// of the file can't be replaced through the API.
+type dataspaceRegistry struct {
+	client    client.Client
+	namespace string
+	// configured are the dataspaces of the dataspace config file
+	configured map[string]DataspaceConfig
+}
+
+// dataspaceDefinition creates a dataspace through the API with the settings its participants share.
+type dataspaceDefinition struct {
+	Name string `json:"name" validate:"required"`
+	// IngressHost is the host the participants' APIs are reached at, unless their definitions set one
+	IngressHost string `json:"ingressHost,omitempty"`
+	// Scheme is http or https, used for the ingress host. Defaults to http.
+	Scheme string `json:"scheme,omitempty"`
+	// Issuer overrides the issuer settings given by flags for the participants of the dataspace
+	Issuer *IssuerConfig `json:"issuer,omitempty"`
+	// TrustAnchors are the DIDs of the issuers whose credentials the participants accept
+	TrustAnchors []string `json:"trustAnchors,omitempty"`
+	// Registration registers the participants with the other members of the dataspace once they are ready
+	Registration *RegistrationConfig `json:"registration,omitempty"`
+}
+
+func (d dataspaceDefinition) validate() error {
+	var fields []fieldError
+	if errs := validation.IsDNS1123Label(d.Name); len(errs) > 0 {
+		fields = append(fields, fieldError{"name", strings.Join(errs, ", ")})
+	}
+	if d.Scheme != "" && d.Scheme != "http" && d.Scheme != "https" {
+		fields = append(fields, fieldError{"scheme", "must be http or https"})
+	}
+	if d.IngressHost != "" && strings.ContainsAny(d.IngressHost, "/ ") {
+		fields = append(fields, fieldError{"ingressHost", "must be a host without scheme or path"})
+	}
+	if d.Issuer != nil && d.Issuer.Did != "" {
+		if err := validateDid(d.Issuer.Did); err != nil {
+			fields = append(fields, fieldError{"issuer.did", err.Error()})
+		}
+	}
+	if err := d.Registration.validate(); err != nil {
+		fields = append(fields, fieldError{"registration.url", err.Error()})
+	}
+	for i, anchor := range d.TrustAnchors {
+		if err := validateDid(anchor); err != nil {
+			fields = append(fields, fieldError{"trustAnchors[" + strconv.Itoa(i) + "]", err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		return &validationError{Fields: fields}
+	}
+	return nil
+}
+
+func (d dataspaceDefinition) config() DataspaceConfig {
+	return DataspaceConfig{IngressHost: d.IngressHost, Scheme: d.Scheme, Issuer: d.Issuer, TrustAnchors: d.TrustAnchors, Registration: d.Registration}
+}
+
+// created lists the dataspaces created through the API.
+func (r *dataspaceRegistry) created(ctx context.Context) (map[string]DataspaceConfig, error) {
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, configMaps, client.InNamespace(r.namespace), client.HasLabels{dataspaceConfigLabel}); err != nil {
+		return nil, err
+	}
+	dataspaces := make(map[string]DataspaceConfig, len(configMaps.Items))
+	for _, configMap := range configMaps.Items {
+		var dataspace DataspaceConfig
+		if err := json.Unmarshal([]byte(configMap.Data[dataspaceConfigKey]), &dataspace); err != nil {
+			fmt.Printf("skipping dataspace %s: %v\n", configMap.Name, err)
+			continue
+		}
+		dataspaces[configMap.Labels[dataspaceConfigLabel]] = dataspace
+	}
+	return dataspaces, nil
+}
+
+// all returns the dataspaces of the config file and those created through the API by name.
+func (r *dataspaceRegistry) all(ctx context.Context) (map[string]DataspaceConfig, error) {
+	if r == nil {
+		return map[string]DataspaceConfig{}, nil
+	}
+	dataspaces, err := r.created(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, dataspace := range r.configured {
+		dataspaces[name] = dataspace
+	}
+	return dataspaces, nil
+}
+
+// lookup returns the settings of the dataspace, none if it isn't known or can't be read.
+func (r *dataspaceRegistry) lookup(ctx context.Context, name string) DataspaceConfig {
+	if r == nil {
+		return DataspaceConfig{}
+	}
+	if dataspace, ok := r.configured[name]; ok {
+		return dataspace
+	}
+	dataspaces, err := r.created(ctx)
+	if err != nil {
+		fmt.Printf("Reading the settings of dataspace %s failed: %v\n", name, err)
+	}
+	return dataspaces[name]
+}
+
+// create stores a dataspace created through the API, it is rejected if a dataspace of the name exists.
+func (r *dataspaceRegistry) create(ctx context.Context, definition dataspaceDefinition) error {
+	dataspaces, err := r.all(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := dataspaces[definition.Name]; ok {
+		return &conflictError{code: codeNameConflict, resource: conflictingResource{Kind: "Dataspace", Name: definition.Name},
+			message: fmt.Sprintf("dataspace %s exists", definition.Name)}
+	}
+	data, err := json.Marshal(definition.config())
+	if err != nil {
+		return err
+	}
+	return r.client.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dataspace-" + definition.Name,
+			Namespace: r.namespace,
+			Labels:    map[string]string{dataspaceConfigLabel: definition.Name},
+		},
+		Data: map[string]string{dataspaceConfigKey: string(data)},
+	})
+}
+
+// dataspaceReport describes a dataspace with the aggregate status of its participants. The API key of its issuer is
+// left out, Registration tells whether participants are registered with the other members once they are ready.
+type dataspaceReport struct {
+	Name         string                     `json:"name"`
+	Source       string                     `json:"source"`
+	IngressHost  string                     `json:"ingressHost,omitempty"`
+	Scheme       string                     `json:"scheme,omitempty"`
+	IssuerUrl    string                     `json:"issuerUrl,omitempty"`
+	IssuerDid    string                     `json:"issuerDid,omitempty"`
+	TrustAnchors []string                   `json:"trustAnchors,omitempty"`
+	Registration bool                       `json:"registration"`
+	Summary      *status.StatusSummary      `json:"summary,omitempty"`
+	Participants []status.ParticipantStatus `json:"participants,omitempty"`
+}
+
+func (r *dataspaceRegistry) report(name string, dataspace DataspaceConfig) dataspaceReport {
+	report := dataspaceReport{Name: name, Source: dataspaceFromApi, IngressHost: dataspace.IngressHost, Scheme: dataspace.Scheme, TrustAnchors: dataspace.TrustAnchors,
+		Registration: dataspace.Registration != nil}
+	if _, ok := r.configured[name]; ok {
+		report.Source = dataspaceFromConfig
+	}
+	if dataspace.Issuer != nil {
+		report.IssuerUrl, report.IssuerDid = dataspace.Issuer.Url, dataspace.Issuer.Did
+	}
+	return report
+}
+
+// trustAnchorMutator adds the trust anchors of the dataspace to the trusted issuers of the participant's control
+// plane.
+func trustAnchorMutator(anchors []string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "ConfigMap" || obj.GetName() != "controlplane-config" {
+			return nil
+		}
+		for i, anchor := range anchors {
+			key := fmt.Sprintf("EDC_IAM_TRUSTED-ISSUER_ANCHOR%d_ID", i+1)
+			if err := unstructured.SetNestedField(obj.Object, anchor, "data", key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func createDataspace(registry *dataspaceRegistry, ctx context.Context, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var definition dataspaceDefinition
+		if err := parser.parse(c, &definition); err != nil {
+			return err
+		}
+		if err := definition.validate(); err != nil {
+			return err
+		}
+		if err := registry.create(ctx, definition); err != nil {
+			return err
+		}
+		c.Location("/api/v1/dataspaces/" + definition.Name)
+		return c.Status(fiber.StatusCreated).JSON(registry.report(definition.Name, definition.config()))
+	}
+}
+
+// listDataspaces lists the dataspaces with their shared settings.
+func listDataspaces(registry *dataspaceRegistry, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dataspaces, err := registry.all(ctx)
+		if err != nil {
+			return err
+		}
+		names := slices.Sorted(func(yield func(string) bool) {
+			for name := range dataspaces {
+				if !yield(name) {
+					return
+				}
+			}
+		})
+		reports := make([]dataspaceReport, 0, len(names))
+		for _, name := range names {
+			reports = append(reports, registry.report(name, dataspaces[name]))
+		}
+		return c.JSON(offsetPage(c, reports, 0, 0))
+	}
+}
+
+// getDataspace reports the settings of the dataspace and the status of its participants in the caller's scope.
+// Participants without a dataspace belong to the default one, which exists without being configured.
+func getDataspace(registry *dataspaceRegistry, kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("dataspaceName")
+		dataspaces, err := registry.all(ctx)
+		if err != nil {
+			return err
+		}
+		dataspace, ok := dataspaces[name]
+		if !ok && name != defaultDataspace {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("dataspace %s not found", name))
+		}
+		members, err := dataspaceMembers(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		if members, err = filterScope(kubeClient, ctx, tenantOf(c), members); err != nil {
+			return err
+		}
+		report := registry.report(name, dataspace)
+		report.Participants = make([]status.ParticipantStatus, 0, len(members))
+		for _, member := range members {
+			participantStatus, err := statusChecker.GetStatus(ctx, member, nil)
+			if err != nil {
+				return err
+			}
+			report.Participants = append(report.Participants, participantStatus)
+		}
+		summary := status.Summarize(report.Participants)
+		report.Summary = &summary
+		return c.JSON(report)
+	}
+}
+
+// dataspaceMembers returns the names of the participants of the dataspace, sorted. Participants provisioned before
+// their dataspace was recorded belong to the default dataspace.
+func dataspaceMembers(c client.Client, ctx context.Context, dataspace string) ([]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		return nil, err
+	}
+	var members []string
+	for _, namespace := range namespaces.Items {
+		of := namespace.Annotations[dataspaceAnnotation]
+		if of == "" {
+			of = defaultDataspace
+		}
+		if of == dataspace && namespace.Labels[federatedCatalogLabel] == "" {
+			members = append(members, namespace.Name)
+		}
+	}
+	slices.Sort(members)
+	return members, nil
+}
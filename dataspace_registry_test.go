@@ -0,0 +1,108 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDataspaceRegistry(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		participantNamespace("alice", "catena"), participantNamespace("bob", ""), participantNamespace("carol", "other")).Build()
+	registry := &dataspaceRegistry{client: kube, namespace: "mvd-provisioner", configured: map[string]DataspaceConfig{"other": {IngressHost: "other.example.com"}}}
+	ctx := context.Background()
+	checker := status.NewStatusChecker(ctx, kube, 0)
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/api/v1/dataspaces", createDataspace(registry, ctx, payloadParser{}))
+	app.Get("/api/v1/dataspaces", listDataspaces(registry, ctx))
+	app.Get("/api/v1/dataspaces/:dataspaceName", getDataspace(registry, kube, ctx, checker))
+	send := func(method string, path string, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		_, _ = out.ReadFrom(resp.Body)
+		return resp.StatusCode, out.Bytes()
+	}
+
+	catena := `{"name":"catena","ingressHost":"catena.example.com","issuer":{"url":"http://issuer.catena","apiKey":"secret"},"trustAnchors":["did:web:issuer.catena.example.com"]}`
+	if code, body := send("POST", "/api/v1/dataspaces", catena); code != fiber.StatusCreated || bytes.Contains(body, []byte("secret")) {
+		t.Fatalf("expected the dataspace to be created without exposing the issuer key, got %d %s", code, body)
+	}
+	if code, _ := send("POST", "/api/v1/dataspaces", catena); code != fiber.StatusConflict {
+		t.Errorf("expected an existing dataspace to be rejected, got %d", code)
+	}
+	if code, _ := send("POST", "/api/v1/dataspaces", `{"name":"other"}`); code != fiber.StatusConflict {
+		t.Errorf("expected a dataspace of the config file to be rejected, got %d", code)
+	}
+	if code, _ := send("POST", "/api/v1/dataspaces", `{"name":"Bad_Name","trustAnchors":["issuer"]}`); code != fiber.StatusBadRequest {
+		t.Errorf("expected an invalid dataspace to be rejected, got %d", code)
+	}
+	if dataspace := registry.lookup(ctx, "catena"); dataspace.IngressHost != "catena.example.com" || dataspace.Issuer.ApiKey != "secret" {
+		t.Errorf("expected the stored settings of catena, got %+v", dataspace)
+	}
+
+	code, body := send("GET", "/api/v1/dataspaces", "")
+	var list listPage[dataspaceReport]
+	if err := json.Unmarshal(body, &list); err != nil || code != fiber.StatusOK {
+		t.Fatalf("listing dataspaces failed: %d %s", code, body)
+	}
+	if list.Total != 2 || list.Items[0].Name != "catena" || list.Items[0].Source != dataspaceFromApi || list.Items[1].Source != dataspaceFromConfig {
+		t.Errorf("unexpected dataspaces %+v", list.Items)
+	}
+
+	code, body = send("GET", "/api/v1/dataspaces/catena", "")
+	var report dataspaceReport
+	if err := json.Unmarshal(body, &report); err != nil || code != fiber.StatusOK {
+		t.Fatalf("getting catena failed: %d %s", code, body)
+	}
+	if len(report.Participants) != 1 || report.Participants[0].Name != "alice" || report.Summary == nil || report.Summary.Total != 1 {
+		t.Errorf("expected alice to be the only participant of catena, got %+v", report)
+	}
+	code, body = send("GET", "/api/v1/dataspaces/default", "")
+	if err := json.Unmarshal(body, &report); err != nil || code != fiber.StatusOK || len(report.Participants) != 1 || report.Participants[0].Name != "bob" {
+		t.Errorf("expected participants without a dataspace in the default one, got %d %s", code, body)
+	}
+	if code, _ := send("GET", "/api/v1/dataspaces/unknown", ""); code != fiber.StatusNotFound {
+		t.Errorf("expected 404 for an unknown dataspace, got %d", code)
+	}
+
+	// the registry of a provisioner without dataspaces knows none
+	var none *dataspaceRegistry
+	if dataspaces, err := none.all(ctx); err != nil || len(dataspaces) != 0 || none.lookup(ctx, "catena").IngressHost != "" {
+		t.Errorf("expected no dataspaces, got %v %v", dataspaces, err)
+	}
+}
+
+func TestTrustAnchorMutator(t *testing.T) {
+	config := &unstructured.Unstructured{Object: map[string]any{"kind": "ConfigMap", "metadata": map[string]any{"name": "controlplane-config"}}}
+	if err := trustAnchorMutator([]string{"did:web:issuer-a", "did:web:issuer-b"})(config); err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(config.Object, "data")
+	if data["EDC_IAM_TRUSTED-ISSUER_ANCHOR1_ID"] != "did:web:issuer-a" || data["EDC_IAM_TRUSTED-ISSUER_ANCHOR2_ID"] != "did:web:issuer-b" {
+		t.Errorf("expected both anchors to be trusted, got %v", data)
+	}
+	var invalid *validationError
+	if err := (dataspaceDefinition{Name: "catena", TrustAnchors: []string{"did:web:ok.example.com", "nope"}}).validate(); !errors.As(err, &invalid) || len(invalid.Fields) != 1 {
+		t.Errorf("expected the malformed anchor to be rejected, got %v", err)
+	}
+}
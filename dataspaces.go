@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"sigs.k8s.io/yaml"
+)
+
+// Authentication schemes for the seeding HTTP clients
+const (
+	authBearer = "bearer"
+	authHeader = "header"
+	authOAuth2 = "oauth2"
+)
+
+// AuthConfig authenticates the seeding requests against an ingress or auth proxy in front of a target, in addition to
+// the API key the components themselves expect. Values may reference environment variables as ${NAME}.
+type AuthConfig struct {
+	// Type is bearer (static token), header (static header value) or oauth2 (client credentials grant)
+	Type string `json:"type"`
+	// Header and Value are sent with every request for type header
+	Header string `json:"header,omitempty"`
+	Value  string `json:"value,omitempty"`
+	// Token is sent as bearer token for type bearer
+	Token string `json:"token,omitempty"`
+	// TokenUrl, ClientId, ClientSecret and Scopes configure the client credentials grant for type oauth2
+	TokenUrl     string   `json:"tokenUrl,omitempty"`
+	ClientId     string   `json:"clientId,omitempty"`
+	ClientSecret string   `json:"clientSecret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// Participants that don't name a dataspace use the settings of this one, if it is configured
+const defaultDataspace = "default"
+
+// DataspaceConfig holds the settings shared by the participants of a dataspace.
+type DataspaceConfig struct {
+	// IngressHost is the host the participants' APIs are reached at for seeding, unless the definition sets kubeHost
+	IngressHost string `json:"ingressHost,omitempty"`
+	// Scheme is http or https, used for ingress hosts given without scheme. Defaults to http.
+	Scheme string `json:"scheme,omitempty"`
+	// Hooks run as Jobs in the participant namespace after seeding, in the given order
+	Hooks []HookConfig `json:"hooks,omitempty"`
+	// Auth configures the authentication per seeding target, the "*" entry applies to all targets without their own
+	Auth map[string]AuthConfig `json:"auth,omitempty"`
+	// Issuer overrides the issuer settings given by flags for the participants of the dataspace
+	Issuer *IssuerConfig `json:"issuer,omitempty"`
+	// SecretStore overrides the store connector secrets are written to, vault or kubernetes
+	SecretStore string `json:"secretStore,omitempty"`
+	// Kustomization patches the manifests of the dataspace's participants, before the patches of the definitions
+	Kustomization *Kustomization `json:"kustomization,omitempty"`
+	// Vault overrides the external Vault settings given by flags for the participants of the dataspace
+	Vault *VaultConfig `json:"vault,omitempty"`
+	// Scheduling pins the pods of the dataspace's participants to nodes, e.g. to a dedicated node pool
+	Scheduling *SchedulingOptions `json:"scheduling,omitempty"`
+	// Registry overrides the private registry given by flags for the participants of the dataspace
+	Registry *RegistryConfig `json:"registry,omitempty"`
+	// Mesh enrols the dataspace's participants that don't set their own mesh settings into a service mesh
+	Mesh *MeshOptions `json:"mesh,omitempty"`
+	// TrustAnchors are the DIDs of the issuers whose credentials the dataspace's participants accept
+	TrustAnchors []string `json:"trustAnchors,omitempty"`
+	// Registration registers the dataspace's participants with its other members once they are ready
+	Registration *RegistrationConfig `json:"registration,omitempty"`
+}
+
+// loadDataspaces reads the dataspace configuration file, keyed by dataspace name.
+func loadDataspaces(path string) (map[string]DataspaceConfig, error) {
+	dataspaces := make(map[string]DataspaceConfig)
+	if path == "" {
+		return dataspaces, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dataspace config: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &dataspaces); err != nil {
+		return nil, fmt.Errorf("parse dataspace config: %w", err)
+	}
+	for name, dataspace := range dataspaces {
+		if dataspace.Scheme != "" && dataspace.Scheme != "http" && dataspace.Scheme != "https" {
+			return nil, fmt.Errorf("dataspace %s: unsupported scheme %q", name, dataspace.Scheme)
+		}
+		if dataspace.SecretStore != "" {
+			if err := validateSecretStore(dataspace.SecretStore); err != nil {
+				return nil, fmt.Errorf("dataspace %s: %w", name, err)
+			}
+		}
+		if vault := vaultFor(dataspace); vault.enabled() && vault.Token == "" {
+			return nil, fmt.Errorf("dataspace %s: the external vault requires a token", name)
+		}
+		if err := dataspace.Scheduling.validate(); err != nil {
+			return nil, fmt.Errorf("dataspace %s: %w", name, err)
+		}
+		if dataspace.Mesh != nil {
+			if err := dataspace.Mesh.validate(); err != nil {
+				return nil, fmt.Errorf("dataspace %s: %w", name, err)
+			}
+		}
+		if err := registryFor(dataspace).validate(); err != nil {
+			return nil, fmt.Errorf("dataspace %s: %w", name, err)
+		}
+		if err := dataspace.Registration.validate(); err != nil {
+			return nil, fmt.Errorf("dataspace %s: %w", name, err)
+		}
+		if _, err := dataspace.Kustomization.compile(); err != nil {
+			return nil, fmt.Errorf("dataspace %s: kustomization: %w", name, err)
+		}
+		for i := range dataspace.Hooks {
+			if err := dataspace.Hooks[i].load(); err != nil {
+				return nil, fmt.Errorf("dataspace %s: %w", name, err)
+			}
+		}
+		for target, auth := range dataspace.Auth {
+			if target != "*" && !isHttpTarget(target) {
+				return nil, fmt.Errorf("dataspace %s: unknown http target %q", name, target)
+			}
+			if err := auth.validate(); err != nil {
+				return nil, fmt.Errorf("dataspace %s, target %s: %w", name, target, err)
+			}
+		}
+	}
+	return dataspaces, nil
+}
+
+func (a AuthConfig) validate() error {
+	switch a.Type {
+	case authBearer:
+		if a.Token == "" {
+			return fmt.Errorf("bearer auth requires a token")
+		}
+	case authHeader:
+		if a.Header == "" || a.Value == "" {
+			return fmt.Errorf("header auth requires header and value")
+		}
+	case authOAuth2:
+		if a.TokenUrl == "" || a.ClientId == "" || a.ClientSecret == "" {
+			return fmt.Errorf("oauth2 auth requires tokenUrl, clientId and clientSecret")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q", a.Type)
+	}
+	return nil
+}
+
+// forTarget returns the auth settings of a target, if any.
+func (d DataspaceConfig) forTarget(target string) (AuthConfig, bool) {
+	if auth, ok := d.Auth[target]; ok {
+		return auth, true
+	}
+	auth, ok := d.Auth["*"]
+	return auth, ok
+}
+
+// transport wraps the base transport so that requests carry the configured credentials. OAuth2 tokens are fetched
+// through the base transport as well, so proxy and CA settings apply to the token endpoint, and are cached until
+// they expire.
+func (a AuthConfig) transport(base http.RoundTripper) http.RoundTripper {
+	switch a.Type {
+	case authBearer:
+		return &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: a.Token}), Base: base}
+	case authHeader:
+		return &headerTransport{header: a.Header, value: a.Value, next: base}
+	case authOAuth2:
+		config := clientcredentials.Config{
+			ClientID:     a.ClientId,
+			ClientSecret: a.ClientSecret,
+			TokenURL:     a.TokenUrl,
+			Scopes:       a.Scopes,
+		}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: base})
+		return &oauth2.Transport{Source: config.TokenSource(ctx), Base: base}
+	}
+	return base
+}
+
+// headerTransport sets a static header on every request.
+type headerTransport struct {
+	header string
+	value  string
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Header.Set(t.header, t.value)
+	return t.next.RoundTrip(request)
+}
+
+// validateDataspace checks that a participant references a configured dataspace, if any.
+func validateDataspace(dataspaces map[string]DataspaceConfig, name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := dataspaces[name]; !ok {
+		known := make([]string, 0, len(dataspaces))
+		for dataspace := range dataspaces {
+			known = append(known, dataspace)
+		}
+		return fmt.Errorf("unknown dataspace %q, configured: %s", name, strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// dataspaceOf returns the name of the dataspace whose settings apply to the participant.
+func dataspaceOf(definition ParticipantDefinition) string {
+	if definition.Dataspace != "" {
+		return definition.Dataspace
+	}
+	return defaultDataspace
+}
+
+// resolveIngressHost determines the URL the participant's APIs are reached at, from kubeHost, the host of its ingresses
+// or its dataspace, and stores it in the definition. Definitions without a host fail instead of silently seeding against a wrong one.
+func resolveIngressHost(definition *ParticipantDefinition, dataspaces map[string]DataspaceConfig) error {
+	dataspace := dataspaces[dataspaceOf(*definition)]
+	host := definition.KubernetesIngressHost
+	scheme := dataspace.Scheme
+	if host == "" && definition.Ingress != nil && definition.Ingress.Host != "" {
+		host = definition.Ingress.Host
+		if definition.Tls != nil {
+			scheme = "https"
+		}
+	}
+	if host == "" {
+		host = dataspace.IngressHost
+	}
+	if host == "" {
+		return fmt.Errorf("no ingress host: set kubeHost, ingress.host or configure ingressHost for dataspace %q", dataspaceOf(*definition))
+	}
+	if !strings.Contains(host, "://") {
+		if scheme == "" {
+			scheme = "http"
+		}
+		host = scheme + "://" + host
+	}
+	parsed, err := url.Parse(host)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid ingress host %q", host)
+	}
+	definition.KubernetesIngressHost = strings.TrimSuffix(host, "/")
+	return nil
+}
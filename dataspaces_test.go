@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2SeedingClient(t *testing.T) {
+	tokenRequests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"test-token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	var authorizations []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer apiServer.Close()
+
+	dataspaces := map[string]DataspaceConfig{
+		"test": {Auth: map[string]AuthConfig{
+			targetManagement: {Type: authOAuth2, TokenUrl: tokenServer.URL, ClientId: "provisioner", ClientSecret: "secret"},
+		}},
+	}
+	clients, err := newSeedingClients(HttpConfig{}, dataspaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	management := clients.forParticipant(ParticipantDefinition{Dataspace: "test"}).client(targetManagement)
+	for i := 0; i < 2; i++ {
+		response, err := management.Get(apiServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+	}
+	identity := clients.forParticipant(ParticipantDefinition{Dataspace: "test"}).client(targetIdentity)
+	response, err := identity.Get(apiServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+
+	want := []string{"Bearer test-token", "Bearer test-token", ""}
+	if fmt.Sprint(authorizations) != fmt.Sprint(want) {
+		t.Errorf("authorization headers = %q, want %q", authorizations, want)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("fetched %d tokens, want the token to be reused", tokenRequests)
+	}
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    AuthConfig
+		wantErr bool
+	}{
+		{"bearer", AuthConfig{Type: authBearer, Token: "t"}, false},
+		{"bearer without token", AuthConfig{Type: authBearer}, true},
+		{"header", AuthConfig{Type: authHeader, Header: "X-Proxy-Auth", Value: "v"}, false},
+		{"oauth2 without secret", AuthConfig{Type: authOAuth2, TokenUrl: "http://idp/token", ClientId: "c"}, true},
+		{"unknown type", AuthConfig{Type: "basic"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.auth.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveIngressHost(t *testing.T) {
+	dataspaces := map[string]DataspaceConfig{
+		defaultDataspace: {IngressHost: "dataspace.example.com", Scheme: "https"},
+		"local":          {IngressHost: "localhost:8080"},
+		"unset":          {},
+	}
+	tests := []struct {
+		name       string
+		definition ParticipantDefinition
+		want       string
+		wantErr    bool
+	}{
+		{"default dataspace", ParticipantDefinition{}, "https://dataspace.example.com", false},
+		{"dataspace without scheme", ParticipantDefinition{Dataspace: "local"}, "http://localhost:8080", false},
+		{"explicit host", ParticipantDefinition{Dataspace: "local", KubernetesIngressHost: "ingress.test"}, "http://ingress.test", false},
+		{"explicit url", ParticipantDefinition{KubernetesIngressHost: "http://ingress.test/"}, "http://ingress.test", false},
+		{"ingress host", ParticipantDefinition{Dataspace: "local", Ingress: &IngressOptions{Host: "alice.example.com"}}, "http://alice.example.com", false},
+		{"ingress host with tls", ParticipantDefinition{Dataspace: "local", Ingress: &IngressOptions{Host: "alice.example.com"}, Tls: &TlsOptions{Host: "alice.example.com"}}, "https://alice.example.com", false},
+		{"no host", ParticipantDefinition{Dataspace: "unset"}, "", true},
+		{"unsupported scheme", ParticipantDefinition{KubernetesIngressHost: "ftp://ingress.test"}, "", true},
+	}
+	for _, tt := range tests {
+		definition := tt.definition
+		err := resolveIngressHost(&definition, dataspaces)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: resolveIngressHost() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && definition.KubernetesIngressHost != tt.want {
+			t.Errorf("%s: resolved %q, want %q", tt.name, definition.KubernetesIngressHost, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Deletion jobs fail when the namespace is not gone within this period
+	deletionTimeout = 10 * time.Minute
+	// The namespace is deleted after this period even if pods of the participant are still running
+	drainTimeout = 2 * time.Minute
+)
+
+// Phases of a deletion job
+const (
+	phaseDrain  = "drain"
+	phaseDelete = "delete"
+	phaseVerify = "verify"
+)
+
+// deleteParticipant serves DELETE /api/v1/resources/:participantName. The participant is kept in the recycle bin for
+// the deletion grace period, or torn down by a deletion job right away.
+func deleteParticipant(kubeClient client.Client, ctx context.Context, participants *provisioner, audit *auditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespace := c.Params("participantName")
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid participant name: "+errs[0])
+		}
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		// The namespace carrying the owner is gone once the deletion completed
+		owner, err := ownerOf(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		return removeParticipant(c, kubeClient, ctx, participants, audit, namespace, owner)
+	}
+}
+
+// removeParticipant deletes the managed participant unless it is protected: it is kept in the recycle bin for the
+// deletion grace period, or torn down by a deletion job right away.
+func removeParticipant(c *fiber.Ctx, kubeClient client.Client, ctx context.Context, participants *provisioner, audit *auditLog, namespace string, owner string) error {
+	if err := checkDeletionProtection(kubeClient, ctx, namespace, c.QueryBool("force"), c.Query("confirm")); err != nil {
+		return err
+	}
+	// ?immediate=true tears the participant down without keeping it in the recycle bin
+	if deletionGracePeriod > 0 && !c.QueryBool("immediate") {
+		deleteAt, err := recycleParticipant(kubeClient, ctx, namespace, deletionGracePeriod)
+		if err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditDelete, Tenant: owner, Participant: namespace})
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"participant": namespace, "deleteAt": deleteAt})
+	}
+	var rec *recording
+	if c.QueryBool("record") {
+		rec = recordings.start(namespace)
+	}
+	job, err := participants.startDeletion(c.UserContext(), namespace, rec)
+	if err != nil {
+		return err
+	}
+	audit.record(c, ctx, auditEntry{Action: auditDelete, Tenant: owner, Participant: namespace, JobId: job.Id})
+	c.Location("/api/v1/jobs/" + job.Id)
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace})
+}
+
+// startDeletion tears the participant down in a background job. The deployments are deleted first so the connectors
+// stop before their database and credentials disappear, then the namespace, which removes everything left in it
+// including the postgres volume claims and the generated secrets. Adopted namespaces are left, only the participant's
+// objects are deleted from them. The job verifies that nothing was left behind.
+func (p *provisioner) startDeletion(ctx context.Context, namespace string, rec *recording) (*provisioningJob, error) {
+	adopted, err := isAdoptedNamespace(p.kubeClient, ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	job, err := jobs.create(ctx, namespace, status.StatusDeleting)
+	if err != nil {
+		return nil, err
+	}
+	p.statusChecker.Reset(namespace)
+	p.statusChecker.BeginDeletion(namespace)
+
+	remove := impersonated(withRetries(deleteResource))
+	if rec != nil {
+		remove = rec.action("delete", remove)
+	}
+	steps := []jobStep{
+		{phaseDrain, func(ctx context.Context) error {
+			drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+			defer cancel()
+			return drainWorkloads(p.kubeClient, drainCtx, namespace, remove)
+		}},
+		{phaseDelete, func(ctx context.Context) error {
+			// the credentials in the namespace tell which Vault the participant was set up in
+			dataspaces, err := p.dataspaces.all(ctx)
+			if err != nil {
+				return err
+			}
+			if err := removeParticipantVault(p.kubeClient, ctx, namespace, dataspaces, p.clients.forParticipant(ParticipantDefinition{ParticipantName: namespace})); err != nil {
+				return fmt.Errorf("remove participant from vault: %w", err)
+			}
+			if adopted {
+				fmt.Println("Deleting the objects of", namespace, "from its adopted namespace")
+				return releaseNamespace(p.kubeClient, ctx, namespace, remove)
+			}
+			fmt.Println("Deleting namespace", namespace)
+			ns := &corev1.Namespace{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			}
+			return client.IgnoreNotFound(remove(p.kubeClient, ctx, ns))
+		}},
+		{phaseVerify, func(ctx context.Context) error {
+			verifyCtx, cancel := context.WithTimeout(ctx, deletionTimeout)
+			defer cancel()
+			if adopted {
+				return verifyRelease(p.kubeClient, verifyCtx, namespace)
+			}
+			return verifyDeletion(p.kubeClient, verifyCtx, namespace)
+		}},
+	}
+	runInBackground(func() {
+		runCtx, stop := job.bind(withRequesterOf(withSpanOf(p.ctx, ctx), ctx))
+		defer stop()
+		if err := job.execute(runCtx, steps); err != nil {
+			fmt.Printf("deleting %s failed: %v\n", namespace, err)
+			p.statusChecker.Invalidate(namespace)
+			return
+		}
+		p.statusChecker.MarkDeleted(namespace)
+		job.succeed()
+		p.announce(eventParticipantDeleted, namespace, map[string]string{"jobId": job.Id})
+		if err := refreshFederatedCatalogs(p.kubeClient, runCtx); err != nil {
+			fmt.Printf("refreshing federated catalogs after deleting %s failed: %v\n", namespace, err)
+		}
+	})
+	return job, nil
+}
+
+// drainWorkloads deletes the deployments of the participant and waits until their pods are gone. Pods still running
+// when the context expires are left to the namespace deletion.
+func drainWorkloads(c client.Client, ctx context.Context, namespace string, remove action) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		deployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+		fmt.Println("Deleting deployment", namespace+"/"+deployment.Name)
+		if err := client.IgnoreNotFound(remove(c, ctx, deployment)); err != nil {
+			return err
+		}
+	}
+	for {
+		pods := &corev1.PodList{}
+		if err := c.List(ctx, pods, client.InNamespace(namespace)); err != nil && ctx.Err() == nil {
+			return err
+		}
+		if len(pods.Items) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Printf("%d pods of %s still running after %s, deleting the namespace anyway\n", len(pods.Items), namespace, drainTimeout)
+			return nil
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// verifyDeletion waits until the namespace is gone and checks that no persistent volume of its claims and no
+// cluster-scoped object labelled with the participant was retained. A namespace that doesn't disappear is reported
+// with the finalizers holding it and its remaining content.
+func verifyDeletion(c client.Client, ctx context.Context, namespace string) error {
+	for {
+		ns := &corev1.Namespace{}
+		err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns)
+		if apierrors.IsNotFound(err) {
+			break
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			problems := stuckFinalizers(ns)
+			remaining, _ := remainingStorage(c, context.Background(), namespace)
+			if len(remaining) > 0 {
+				problems = append(problems, "remaining: "+strings.Join(remaining, ", "))
+			}
+			return fmt.Errorf("namespace still terminating: %s", strings.Join(problems, "; "))
+		case <-time.After(readinessPollInterval):
+		}
+	}
+
+	volumes := &corev1.PersistentVolumeList{}
+	if err := c.List(ctx, volumes); err != nil {
+		return err
+	}
+	var retained []string
+	for _, volume := range volumes.Items {
+		if ref := volume.Spec.ClaimRef; ref != nil && ref.Namespace == namespace {
+			retained = append(retained, fmt.Sprintf("PersistentVolume/%s (%s)", volume.Name, volume.Status.Phase))
+		}
+	}
+	orphaned, err := orphanedClusterObjects(c, ctx, namespace)
+	if err != nil {
+		return err
+	}
+	retained = append(retained, orphaned...)
+	if len(retained) > 0 {
+		return fmt.Errorf("resources of the participant were retained: %s", strings.Join(retained, ", "))
+	}
+	return nil
+}
+
+// orphanedClusterObjects lists the cluster-scoped objects still labelled with the participant, which the namespace
+// deletion doesn't remove.
+func orphanedClusterObjects(c client.Client, ctx context.Context, participant string) ([]string, error) {
+	selector := client.MatchingLabels{status.ParticipantLabel: participant}
+	var orphaned []string
+	roles := &rbacv1.ClusterRoleList{}
+	if err := c.List(ctx, roles, selector); err != nil {
+		return nil, err
+	}
+	for _, role := range roles.Items {
+		orphaned = append(orphaned, "ClusterRole/"+role.Name)
+	}
+	bindings := &rbacv1.ClusterRoleBindingList{}
+	if err := c.List(ctx, bindings, selector); err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings.Items {
+		orphaned = append(orphaned, "ClusterRoleBinding/"+binding.Name)
+	}
+	return orphaned, nil
+}
+
+// stuckFinalizers describes why a terminating namespace is not removed, from the conditions the namespace controller
+// reports and the finalizers left on it.
+func stuckFinalizers(ns *corev1.Namespace) []string {
+	var problems []string
+	for _, condition := range ns.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case corev1.NamespaceFinalizersRemaining, corev1.NamespaceContentRemaining,
+			corev1.NamespaceDeletionContentFailure, corev1.NamespaceDeletionDiscoveryFailure:
+			problems = append(problems, condition.Message)
+		}
+	}
+	if len(ns.Spec.Finalizers) > 0 {
+		finalizers := make([]string, 0, len(ns.Spec.Finalizers))
+		for _, finalizer := range ns.Spec.Finalizers {
+			finalizers = append(finalizers, string(finalizer))
+		}
+		problems = append(problems, "namespace finalizers: "+strings.Join(finalizers, ", "))
+	}
+	return problems
+}
+
+// remainingStorage lists the pods, volume claims and secrets still present in a terminating namespace together with
+// the finalizers blocking their removal.
+func remainingStorage(c client.Client, ctx context.Context, namespace string) ([]string, error) {
+	var remaining []string
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		remaining = append(remaining, withFinalizers("Pod/"+pod.Name, pod.Finalizers))
+	}
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, claims, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, claim := range claims.Items {
+		remaining = append(remaining, withFinalizers("PersistentVolumeClaim/"+claim.Name, claim.Finalizers))
+	}
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets.Items {
+		remaining = append(remaining, withFinalizers("Secret/"+secret.Name, secret.Finalizers))
+	}
+	return remaining, nil
+}
+
+func withFinalizers(name string, finalizers []string) string {
+	if len(finalizers) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (finalizers: %s)", name, strings.Join(finalizers, ", "))
+}
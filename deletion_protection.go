@@ -0,0 +1,147 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// protectedAnnotation marks participants that are only deleted with ?force=true or the confirmation token, set with
+// the protected field of the definition or by annotating the namespace
+const protectedAnnotation = "aruba-provisioner/protected"
+
+// deleteAtAnnotation records when a participant in the recycle bin is torn down
+const deleteAtAnnotation = "aruba-provisioner/delete-at"
+
+// Deleted participants stay in the recycle bin for this period before they are torn down and can be restored until
+// then, set with --deletion-grace-period, 0 tears them down right away
+var deletionGracePeriod time.Duration
+
+// protectionMutator marks the participant namespace as protected.
+func protectionMutator(obj *unstructured.Unstructured) error {
+	if obj.GetKind() == "Namespace" {
+		addAnnotations(obj, map[string]string{protectedAnnotation: "true"})
+	}
+	return nil
+}
+
+// confirmationToken returns the token confirming the deletion of a protected participant. It is derived from the UID
+// of the namespace, so it doesn't confirm the deletion of a participant provisioned again under the same name.
+func confirmationToken(namespace *corev1.Namespace) string {
+	sum := sha256.Sum256([]byte(string(namespace.UID) + "/" + namespace.Name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkDeletionProtection rejects deleting a protected participant unless the deletion is forced or confirmed. The
+// rejection names the token confirming it.
+func checkDeletionProtection(c client.Client, ctx context.Context, name string, force bool, confirmation string) error {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return err
+	}
+	if namespace.Annotations[protectedAnnotation] != "true" || force {
+		return nil
+	}
+	token := confirmationToken(namespace)
+	if confirmation == token {
+		return nil
+	}
+	return withCode(codeParticipantProtected, fiber.StatusConflict,
+		fmt.Errorf("participant %s is protected, delete it with ?force=true or ?confirm=%s", name, token))
+}
+
+func isProtected(c client.Client, ctx context.Context, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return false, err
+	}
+	return namespace.Annotations[protectedAnnotation] == "true", nil
+}
+
+// recycleParticipant moves the participant into the recycle bin, it keeps running until it is torn down at the
+// returned time.
+func recycleParticipant(c client.Client, ctx context.Context, name string, grace time.Duration) (time.Time, error) {
+	deleteAt := time.Now().Add(grace).UTC().Truncate(time.Second)
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, deleteAtAnnotation, deleteAt.Format(time.RFC3339))
+	namespace := &corev1.Namespace{}
+	namespace.Name = name
+	return deleteAt, c.Patch(ctx, namespace, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
+
+// deleteAtOf returns when the participant in the recycle bin is torn down, nil if it isn't in the recycle bin.
+func deleteAtOf(namespace *corev1.Namespace) *time.Time {
+	deleteAt, err := time.Parse(time.RFC3339, namespace.Annotations[deleteAtAnnotation])
+	if err != nil {
+		return nil
+	}
+	return &deleteAt
+}
+
+// restoreParticipant takes the participant out of the recycle bin.
+func restoreParticipant(c client.Client, ctx context.Context, name string) error {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return err
+	}
+	if deleteAtOf(namespace) == nil {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("participant %s is not in the recycle bin", name))
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, deleteAtAnnotation)
+	return c.Patch(ctx, namespace, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
+
+// recycledParticipants returns the participants in the recycle bin whose grace period lapsed and that aren't being
+// changed.
+func (p *provisioner) recycledParticipants(ctx context.Context) []string {
+	namespaces := &corev1.NamespaceList{}
+	if err := p.kubeClient.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		fmt.Println("Listing managed namespaces failed:", err)
+		return nil
+	}
+	var lapsed []string
+	for _, namespace := range namespaces.Items {
+		deleteAt := deleteAtOf(&namespace)
+		if deleteAt == nil || time.Now().Before(*deleteAt) || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		if jobs.running(namespace.Name) || p.statusChecker.InOperation(ctx, namespace.Name) {
+			continue
+		}
+		lapsed = append(lapsed, namespace.Name)
+	}
+	return lapsed
+}
+
+// emptyRecycleBin tears down the participants whose grace period in the recycle bin lapsed.
+func (p *provisioner) emptyRecycleBin(ctx context.Context) {
+	for _, name := range p.recycledParticipants(ctx) {
+		fmt.Println("Grace period of", name, "in the recycle bin lapsed, deleting it")
+		if _, err := p.startDeletion(ctx, name, nil); err != nil {
+			fmt.Printf("Deleting recycled participant %s failed: %v\n", name, err)
+		}
+	}
+}
+
+func restoreHandler(kubeClient client.Client, ctx context.Context, audit *auditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("participantName")
+		if err := restoreParticipant(kubeClient, ctx, name); err != nil {
+			return err
+		}
+		owner, err := ownerOf(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditRestore, Tenant: owner, Participant: name})
+		return c.JSON(fiber.Map{"participant": name})
+	}
+}
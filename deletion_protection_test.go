@@ -0,0 +1,114 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func protectionClient(namespaces ...*corev1.Namespace) client.WithWatch {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	objects := make([]client.Object, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		objects = append(objects, namespace)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func TestCheckDeletionProtection(t *testing.T) {
+	protected := participantNamespace("demo", "")
+	protected.UID = types.UID("0b7c2c1e")
+	protected.Annotations[protectedAnnotation] = "true"
+	kube := protectionClient(protected, participantNamespace("scratch", ""))
+	ctx := context.Background()
+
+	if err := checkDeletionProtection(kube, ctx, "scratch", false, ""); err != nil {
+		t.Errorf("expected unprotected participants to be deleted, got %v", err)
+	}
+	err := checkDeletionProtection(kube, ctx, "demo", false, "")
+	if p := problemOf(err); p.Status != fiber.StatusConflict || p.Code != codeParticipantProtected {
+		t.Fatalf("expected a 409 %s, got %v", codeParticipantProtected, err)
+	}
+	token := confirmationToken(protected)
+	if !strings.Contains(err.Error(), "confirm="+token) {
+		t.Errorf("expected the refusal to name the confirmation token, got %v", err)
+	}
+	if err := checkDeletionProtection(kube, ctx, "demo", false, "wrong"); err == nil {
+		t.Error("expected a wrong token to be refused")
+	}
+	if err := checkDeletionProtection(kube, ctx, "demo", false, token); err != nil {
+		t.Errorf("expected the confirmed deletion to be allowed, got %v", err)
+	}
+	if err := checkDeletionProtection(kube, ctx, "demo", true, ""); err != nil {
+		t.Errorf("expected the forced deletion to be allowed, got %v", err)
+	}
+
+	// a participant provisioned again under the same name needs a new confirmation
+	recreated := protected.DeepCopy()
+	recreated.UID = types.UID("5d1f9a04")
+	if confirmationToken(recreated) == token {
+		t.Error("expected the token to change with the namespace")
+	}
+}
+
+func TestRecycleBin(t *testing.T) {
+	lapsed := participantNamespace("lapsed", "")
+	lapsed.Annotations[deleteAtAnnotation] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	kube := protectionClient(participantNamespace("demo", ""), lapsed)
+	ctx := context.Background()
+	p := &provisioner{kubeClient: kube, statusChecker: status.NewStatusChecker(ctx, kube, 0)}
+
+	deleteAt, err := recycleParticipant(kube, ctx, "demo", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespace := &corev1.Namespace{}
+	if err := kube.Get(ctx, client.ObjectKey{Name: "demo"}, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if recorded := deleteAtOf(namespace); recorded == nil || !recorded.Equal(deleteAt) {
+		t.Fatalf("expected the deletion to be scheduled at %s, got %v", deleteAt, recorded)
+	}
+	if recycled := p.recycledParticipants(ctx); !slices.Equal(recycled, []string{"lapsed"}) {
+		t.Errorf("expected only the lapsed participant to be torn down, got %v", recycled)
+	}
+
+	if err := restoreParticipant(kube, ctx, "demo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kube.Get(ctx, client.ObjectKey{Name: "demo"}, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if deleteAtOf(namespace) != nil {
+		t.Errorf("expected the participant to be restored, got %v", namespace.Annotations)
+	}
+	if err := restoreParticipant(kube, ctx, "demo"); problemOf(err).Status != fiber.StatusConflict {
+		t.Errorf("expected restoring a participant outside the recycle bin to be refused, got %v", err)
+	}
+}
+
+func TestProtectionMutator(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "demo", Did: "did:web:demo", Protected: true}
+	rendered := &unstructured.Unstructured{}
+	rendered.SetKind("Namespace")
+	for _, mutator := range definition.mutators() {
+		if err := mutator(rendered); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rendered.GetAnnotations()[protectedAnnotation] != "true" {
+		t.Errorf("expected the namespace to be protected, got %v", rendered.GetAnnotations())
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStuckFinalizers(t *testing.T) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice"},
+		Spec:       corev1.NamespaceSpec{Finalizers: []corev1.FinalizerName{corev1.FinalizerKubernetes}},
+		Status: corev1.NamespaceStatus{
+			Phase: corev1.NamespaceTerminating,
+			Conditions: []corev1.NamespaceCondition{
+				{Type: corev1.NamespaceDeletionDiscoveryFailure, Status: corev1.ConditionFalse, Message: "all resources discovered"},
+				{Type: corev1.NamespaceFinalizersRemaining, Status: corev1.ConditionTrue, Message: "Some content in the namespace has finalizers remaining: example.com/backup in 1 resource instances"},
+			},
+		},
+	}
+	problems := stuckFinalizers(ns)
+	if len(problems) != 2 {
+		t.Fatalf("expected the remaining finalizers and the namespace finalizer, got %v", problems)
+	}
+	if !strings.Contains(problems[0], "example.com/backup") || problems[1] != "namespace finalizers: kubernetes" {
+		t.Errorf("unexpected problems %v", problems)
+	}
+	if problems := stuckFinalizers(&corev1.Namespace{}); len(problems) != 0 {
+		t.Errorf("expected no problems for a namespace without finalizers, got %v", problems)
+	}
+}
+
+func TestWithFinalizers(t *testing.T) {
+	if name := withFinalizers("PersistentVolumeClaim/postgres", []string{"kubernetes.io/pvc-protection"}); name != "PersistentVolumeClaim/postgres (finalizers: kubernetes.io/pvc-protection)" {
+		t.Errorf("unexpected description %s", name)
+	}
+	if name := withFinalizers("Secret/connector-secrets", nil); name != "Secret/connector-secrets" {
+		t.Errorf("unexpected description %s", name)
+	}
+}
+
+func TestDeleteParticipantByPath(t *testing.T) {
+	previous := deletionGracePeriod
+	deletionGracePeriod = time.Hour
+	t.Cleanup(func() { deletionGracePeriod = previous })
+
+	objects := []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}}}
+	for _, participant := range []string{"acme-connector", "globex-connector"} {
+		tenant, _, _ := strings.Cut(participant, "-")
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: participant,
+			Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue, tenantLabel: tenant}}})
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	ctx := context.Background()
+	auth := newApiAuth("static-key,acme:acme-key", "admin-key", "", "", "", "")
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Delete("/api/v1/resources/:participantName", auth.middleware(), requireScope(kube, ctx),
+		deleteParticipant(kube, ctx, nil, &auditLog{client: kube, namespace: "provisioner"}))
+
+	remove := func(name string, key string) int {
+		t.Helper()
+		request := httptest.NewRequest("DELETE", "/api/v1/resources/"+name, nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+		resp, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	recycled := func(name string) bool {
+		t.Helper()
+		namespace := &corev1.Namespace{}
+		if err := kube.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+			t.Fatal(err)
+		}
+		return namespace.Annotations[deleteAtAnnotation] != ""
+	}
+
+	for name, expected := range map[string]int{
+		"Acme_Corp":               fiber.StatusBadRequest,
+		"%2e%2e":                  fiber.StatusBadRequest,
+		"acme-connector%2Fsecret": fiber.StatusBadRequest,
+		"initech":                 fiber.StatusNotFound,
+		"kube-system":             fiber.StatusNotFound,
+	} {
+		if code := remove(name, "static-key"); code != expected {
+			t.Errorf("%s: expected %d, got %d", name, expected, code)
+		}
+	}
+	if code := remove("globex-connector", "acme-key"); code != fiber.StatusNotFound || recycled("globex-connector") {
+		t.Errorf("expected the participant of another tenant to be hidden, got %d", code)
+	}
+	if recycled("kube-system") {
+		t.Error("expected namespaces not managed by the provisioner to be left alone")
+	}
+
+	if code := remove("acme-connector", "acme-key"); code != fiber.StatusAccepted || !recycled("acme-connector") {
+		t.Errorf("expected the participant to be moved to the recycle bin, got %d", code)
+	}
+	entries := &corev1.ConfigMapList{}
+	if err := kube.List(ctx, entries, client.InNamespace("provisioner"), client.MatchingLabels{auditLabel: "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries.Items) != 1 || entries.Items[0].Labels[auditParticipantLabel] != "acme-connector" {
+		t.Errorf("expected the deletion to be audited, got %+v", entries.Items)
+	}
+}
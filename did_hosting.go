@@ -0,0 +1,118 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMap in the participant namespace holding the DID document served by the provisioner
+const didDocumentConfigMapName = "did-document"
+
+// Keys of the DID document ConfigMap
+const (
+	didKey         = "did"
+	didDocumentKey = "did.json"
+)
+
+// Label selecting the DID document ConfigMap of a DID, its value is derived from the DID with didLabelValue
+const didLabel = "aruba-provisioner/did"
+
+// didWebHost is the host, with an optional port, under which the provisioner serves did:web documents, set with
+// --did-web-host. Documents aren't served when empty.
+var didWebHost string
+
+// hostedDidPrefix returns the prefix of the DIDs the provisioner hosts the documents of.
+func hostedDidPrefix() string {
+	return "did:web:" + strings.ReplaceAll(didWebHost, ":", "%3A")
+}
+
+// hostedDid returns the DID the provisioner hosts the document of for participants that don't bring their own.
+func hostedDid(participant string) string {
+	return hostedDidPrefix() + ":" + participant
+}
+
+// hostsDid reports whether the provisioner serves the document of the DID.
+func hostsDid(did string) bool {
+	return didWebHost != "" && (did == hostedDidPrefix() || strings.HasPrefix(did, hostedDidPrefix()+":"))
+}
+
+// didLabelValue shortens the DID to a valid label value.
+func didLabelValue(did string) string {
+	sum := sha256.Sum256([]byte(did))
+	return hex.EncodeToString(sum[:20])
+}
+
+// publishDidDocument copies the DID document of the participant from its identity hub, which generated the keys and
+// knows the service endpoints, into the participant namespace for the provisioner to serve it.
+func publishDidDocument(ctx context.Context, c client.Client, definition ParticipantDefinition, identityHub api.IdentityApi) error {
+	documents, err := identityHub.QueryDidDocuments(ctx, base64.StdEncoding.EncodeToString([]byte(definition.Did)))
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		var id struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(document, &id); err != nil || id.Id != definition.Did {
+			continue
+		}
+		return applyResource(c, ctx, &corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      didDocumentConfigMapName,
+				Namespace: definition.ParticipantName,
+				Labels:    map[string]string{didLabel: didLabelValue(definition.Did)},
+			},
+			Data: map[string]string{didKey: definition.Did, didDocumentKey: string(document)},
+		})
+	}
+	return fmt.Errorf("identity hub has no DID document for %s", definition.Did)
+}
+
+// didFromPath returns the did:web whose document is resolved at the path of the provisioner's host, see
+// https://w3c-ccg.github.io/did-method-web/#read-resolve.
+func didFromPath(path string) (string, bool) {
+	if path == "/.well-known/did.json" {
+		return hostedDidPrefix(), true
+	}
+	segments, ok := strings.CutSuffix(strings.Trim(path, "/"), "/did.json")
+	if !ok || segments == "" {
+		return "", false
+	}
+	return hostedDidPrefix() + ":" + strings.ReplaceAll(segments, "/", ":"), true
+}
+
+// registerDidDocuments serves the published DID documents without authentication, as resolvers of did:web expect.
+func registerDidDocuments(app *fiber.App, kubeClient client.Client, ctx context.Context) {
+	if didWebHost == "" {
+		return
+	}
+	app.Use(func(c *fiber.Ctx) error {
+		did, ok := didFromPath(c.Path())
+		if c.Method() != fiber.MethodGet || !ok {
+			return c.Next()
+		}
+		configMaps := &corev1.ConfigMapList{}
+		if err := kubeClient.List(ctx, configMaps, client.MatchingLabels{didLabel: didLabelValue(did)}); err != nil {
+			return err
+		}
+		for _, configMap := range configMaps.Items {
+			if configMap.Name == didDocumentConfigMapName && configMap.Data[didKey] == did {
+				c.Set(fiber.HeaderContentType, "application/did+json")
+				return c.SendString(configMap.Data[didDocumentKey])
+			}
+		}
+		return fiber.NewError(fiber.StatusNotFound, "no DID document for "+did)
+	})
+}
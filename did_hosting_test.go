@@ -0,0 +1,94 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listingConfigMapStore lists the kept ConfigMaps by label.
+type listingConfigMapStore struct {
+	*configMapStore
+}
+
+func (c listingConfigMapStore) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	configMaps := list.(*corev1.ConfigMapList)
+	for _, configMap := range c.configMaps {
+		if options.LabelSelector == nil || options.LabelSelector.Matches(labels.Set(configMap.Labels)) {
+			configMaps.Items = append(configMaps.Items, *configMap.DeepCopy())
+		}
+	}
+	return nil
+}
+
+func TestHostedDidDocuments(t *testing.T) {
+	previous := didWebHost
+	t.Cleanup(func() { didWebHost = previous })
+	didWebHost = "dids.example.com:8443"
+
+	did := hostedDid("alice")
+	if did != "did:web:dids.example.com%3A8443:alice" || !hostsDid(did) || hostsDid("did:web:alice.example.com") {
+		t.Fatalf("unexpected hosted DID %s", did)
+	}
+	identityHub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/participants/ZGlkOndlYjpkaWRzLmV4YW1wbGUuY29tJTNBODQ0MzphbGljZQ==/dids/query" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`[{"id":"did:web:other"},{"id":"` + did + `","verificationMethod":[{"id":"key-1"}]}]`))
+	}))
+	defer identityHub.Close()
+	kube := listingConfigMapStore{&configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}}
+
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: did}
+	if err := publishDidDocument(context.Background(), kube, definition, &api.ApiClient{BaseUrl: identityHub.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	registerDidDocuments(app, kube, context.Background())
+	for path, expected := range map[string]int{
+		"/alice/did.json":       http.StatusOK,
+		"/bob/did.json":         http.StatusNotFound,
+		"/.well-known/did.json": http.StatusNotFound,
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d %s", path, expected, resp.StatusCode, body)
+		}
+		if expected == http.StatusOK && string(body) != `{"id":"`+did+`","verificationMethod":[{"id":"key-1"}]}` {
+			t.Errorf("%s: unexpected document %s", path, body)
+		}
+	}
+}
+
+func TestDidFromPath(t *testing.T) {
+	previous := didWebHost
+	t.Cleanup(func() { didWebHost = previous })
+	didWebHost = "dids.example.com"
+
+	for path, expected := range map[string]string{
+		"/.well-known/did.json":     "did:web:dids.example.com",
+		"/alice/did.json":           "did:web:dids.example.com:alice",
+		"/dataspace/alice/did.json": "did:web:dids.example.com:dataspace:alice",
+		"/did.json":                 "",
+		"/api/v1/resources/":        "",
+	} {
+		did, ok := didFromPath(path)
+		if did != expected || ok != (expected != "") {
+			t.Errorf("%s: expected %q, got %q", path, expected, did)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Largest DID document the pre-flight check reads
+const maxDidDocumentSize = 1 << 20
+
+// didService is an entry of the services of a DID document. Endpoints may also be maps or lists, which never match the
+// participant's URLs.
+type didService struct {
+	Id              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint any    `json:"serviceEndpoint"`
+}
+
+// credentialServiceUrl returns the URL of the participant's CredentialService, registered with its identity hub.
+func credentialServiceUrl(definition ParticipantDefinition) string {
+	return fmt.Sprintf("http://%s:7082/api/credentials/v1/participants/%s", status.ServiceHost("identityhub", definition.ParticipantName), base64.StdEncoding.EncodeToString([]byte(definition.Did)))
+}
+
+// protocolEndpointUrl returns the URL of the participant's DSP endpoint, registered with its identity hub.
+func protocolEndpointUrl(definition ParticipantDefinition) string {
+	return fmt.Sprintf("http://%s:8082/api/dsp", status.ServiceHost("controlplane", definition.ParticipantName))
+}
+
+// verifyDid resolves the DID of the participant and checks that its document lists the CredentialService and DSP
+// endpoint of the participant, which counterparties look up during DSP handshakes. Documents the provisioning
+// publishes itself, served by the provisioner or the participant's identity hub, don't exist yet and aren't checked.
+// A failed check is reported as 422.
+func verifyDid(ctx context.Context, httpClient http.Client, definition ParticipantDefinition) error {
+	fail := func(format string, args ...any) error {
+		return withCode(codeDidPreflightFailed, fiber.StatusUnprocessableEntity, fmt.Errorf("DID %s: "+format, append([]any{definition.Did}, args...)...))
+	}
+	if strings.HasPrefix(definition.Did, "did:key:") {
+		return fail("did:key documents have no services, counterparties can't find the participant's CredentialService")
+	}
+	documentUrl, err := didWebUrl(definition.Did)
+	if err != nil {
+		return fail("%v", err)
+	}
+	identityHub := status.ServiceHost("identityhub", definition.ParticipantName)
+	if hostsDid(definition.Did) || identityHub == documentUrl.Hostname() || strings.HasPrefix(identityHub, documentUrl.Hostname()+".") {
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, documentUrl.String(), nil)
+	if err != nil {
+		return err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fail("resolving %s failed: %v", documentUrl, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fail("resolving %s failed with %s", documentUrl, response.Status)
+	}
+	var document struct {
+		Id      string       `json:"id"`
+		Service []didService `json:"service"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxDidDocumentSize)).Decode(&document); err != nil {
+		return fail("invalid document at %s: %v", documentUrl, err)
+	}
+	if document.Id != definition.Did {
+		return fail("the document at %s is the one of %s", documentUrl, document.Id)
+	}
+	for serviceType, expected := range map[string]string{"CredentialService": credentialServiceUrl(definition), "ProtocolEndpoint": protocolEndpointUrl(definition)} {
+		if !hasService(document.Service, serviceType, expected) {
+			return fail("the document has no %s with the endpoint %s", serviceType, expected)
+		}
+	}
+	return nil
+}
+
+// hasService reports whether the services contain one of the type with the endpoint.
+func hasService(services []didService, serviceType string, endpoint string) bool {
+	for _, service := range services {
+		if service.Type == serviceType && service.ServiceEndpoint == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// didWebUrl returns the URL of the document of a did:web: the domain, with its percent encoded port, followed by the
+// path segments or /.well-known if there are none.
+func didWebUrl(did string) (*url.URL, error) {
+	id, ok := strings.CutPrefix(did, "did:web:")
+	if !ok {
+		return nil, fmt.Errorf("%s is not a did:web", did)
+	}
+	segments := strings.Split(id, ":")
+	host, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain %q", segments[0])
+	}
+	path := "/.well-known"
+	if len(segments) > 1 {
+		path = "/" + strings.Join(segments[1:], "/")
+	}
+	return url.Parse("https://" + host + path + "/did.json")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDidWebUrl(t *testing.T) {
+	for did, expected := range map[string]string{
+		"did:web:example.com":                  "https://example.com/.well-known/did.json",
+		"did:web:example.com%3A8443:users:bob": "https://example.com:8443/users/bob/did.json",
+	} {
+		documentUrl, err := didWebUrl(did)
+		if err != nil || documentUrl.String() != expected {
+			t.Errorf("%s: expected %s, got %v %v", did, expected, documentUrl, err)
+		}
+	}
+}
+
+func TestVerifyDid(t *testing.T) {
+	var services []didService
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alice/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "did:web:" + strings.ReplaceAll(r.Host, ":", "%3A") + ":alice", "service": services})
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:" + strings.ReplaceAll(host, ":", "%3A") + ":alice"}
+	verify := func(definition ParticipantDefinition) error {
+		return verifyDid(context.Background(), *server.Client(), definition)
+	}
+	expectFailure := func(name string, err error) {
+		t.Helper()
+		var coded *codedError
+		if !errors.As(err, &coded) || coded.status != fiber.StatusUnprocessableEntity || coded.code != codeDidPreflightFailed {
+			t.Errorf("%s: expected a 422 %s, got %v", name, codeDidPreflightFailed, err)
+		}
+	}
+
+	services = []didService{{Id: "cs", Type: "CredentialService", ServiceEndpoint: "https://elsewhere.example.com/credentials"}}
+	expectFailure("wrong endpoints", verify(definition))
+
+	services = []didService{
+		{Id: "cs", Type: "CredentialService", ServiceEndpoint: credentialServiceUrl(definition)},
+		{Id: "dsp", Type: "ProtocolEndpoint", ServiceEndpoint: protocolEndpointUrl(definition)},
+	}
+	if err := verify(definition); err != nil {
+		t.Errorf("matching document: %v", err)
+	}
+
+	unknown := definition
+	unknown.Did = "did:web:" + strings.ReplaceAll(host, ":", "%3A") + ":bob"
+	expectFailure("unresolvable DID", verify(unknown))
+	expectFailure("did:key", verify(ParticipantDefinition{ParticipantName: "alice", Did: "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}))
+
+	// the document of a DID served by the participant's own identity hub doesn't exist before provisioning
+	own := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:identityhub.alice.svc.cluster.local%3A7083:alice"}
+	if err := verify(own); err != nil {
+		t.Errorf("DID of the participant's identity hub: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Seed steps bound to the DID of the participant, run again when it is rotated
+var didSeedSteps = []string{seedStepParticipant, seedStepDidDocument, seedStepIssuer, seedStepCredentials}
+
+// didRotationRequest is the body of POST /api/v1/resources/:participantName/rotate-did.
+type didRotationRequest struct {
+	Did string `json:"did"`
+}
+
+// didRotation changes the DID of a participant from the one of the definition it was seeded from to a new one.
+type didRotation struct {
+	previous ParticipantDefinition
+	rotated  ParticipantDefinition
+}
+
+// planDidRotation checks that the DID of the participant can be rotated to did. The definition of the participant is
+// only known once it was seeded, and credentials signed outside the issuer can't be reissued for the new DID.
+func planDidRotation(c client.Client, ctx context.Context, namespace string, did string) (didRotation, error) {
+	if did == "" {
+		return didRotation{}, &validationError{Fields: []fieldError{{"did", "required"}}}
+	}
+	if err := validateDid(did); err != nil {
+		return didRotation{}, &validationError{Fields: []fieldError{{"did", err.Error()}}}
+	}
+	state, err := loadSeedingState(c, ctx, namespace)
+	if err != nil {
+		return didRotation{}, err
+	}
+	if state.definition.ParticipantName == "" {
+		return didRotation{}, fiber.NewError(fiber.StatusConflict, "the definition of the participant is unknown as seeding never started, provision it again with the new DID")
+	}
+	previous := state.definition.unredacted()
+	if previous.Did == did {
+		return didRotation{}, fiber.NewError(fiber.StatusConflict, "the participant already has the DID "+did)
+	}
+	presigned, err := presignedCredentials(previous)
+	if err != nil {
+		return didRotation{}, err
+	}
+	if len(presigned) > 0 {
+		return didRotation{}, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("the presigned credentials of the participant were signed for %s, provision it again with credentials signed for the new DID", previous.Did))
+	}
+	rotated := previous
+	rotated.Did = did
+	return didRotation{previous: previous, rotated: rotated}, nil
+}
+
+// resetIdentity removes what the participant was seeded with for its previous DID: the participant context in the
+// identity hub, with its STS client and credentials, and the DID document the provisioner served. The seed steps bound
+// to the DID are marked as pending and the seeding state gets the rotated definition, so an interrupted rotation is
+// completed by resuming seeding.
+func (r didRotation) resetIdentity(ctx context.Context, c client.Client, identityHub api.IdentityApi) error {
+	if r.previous.seeds(seedStepParticipant) {
+		if err := identityHub.DeleteParticipant(ctx, base64.StdEncoding.EncodeToString([]byte(r.previous.Did))); err != nil {
+			return fmt.Errorf("delete participant context of %s: %w", r.previous.Did, err)
+		}
+	}
+	// documents of hosted DIDs are replaced when the new one is published
+	if hostsDid(r.previous.Did) && !hostsDid(r.rotated.Did) {
+		document := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: didDocumentConfigMapName, Namespace: r.previous.ParticipantName}}
+		if err := client.IgnoreNotFound(c.Delete(ctx, document)); err != nil {
+			return err
+		}
+	}
+	state, err := loadSeedingState(c, ctx, r.rotated.ParticipantName)
+	if err != nil {
+		return err
+	}
+	for _, step := range didSeedSteps {
+		delete(state.completed, step)
+	}
+	state.definition = r.rotated
+	state.resources.ParticipantContextId, state.resources.StsClientId = "", ""
+	return storeSeedingState(c, ctx, state)
+}
+
+// verifyCredentials checks that the participant context of the rotated DID holds the credentials of the issuer.
+func (r didRotation) verifyCredentials(ctx context.Context, identityHub api.IdentityApi, issuer IssuerConfig) error {
+	if issuer.Disabled || !r.rotated.seeds(seedStepCredentials) {
+		return nil
+	}
+	participantContextId := base64.StdEncoding.EncodeToString([]byte(r.rotated.Did))
+	for _, credentialType := range credentialTypes(r.rotated, issuer) {
+		held, err := identityHub.HasCredential(ctx, participantContextId, credentialType)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return fmt.Errorf("no %s was issued to %s", credentialType, r.rotated.Did)
+		}
+	}
+	return nil
+}
+
+// rotateDid rotates the DID of the participant in a background job. The manifests are rendered again with the new DID
+// and rolled out, then the participant context is recreated in the identity hub, which issues a new STS client secret
+// stored for the connector, the DID document is published and the credentials are requested for the new DID. The
+// phases are reported in the status of the participant.
+func (p *provisioner) rotateDid(ctx context.Context, rotation didRotation, plan provisioningPlan) (*provisioningJob, error) {
+	namespace := rotation.rotated.ParticipantName
+	creds, err := loadCredentials(p.kubeClient, ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	definition := rotation.rotated
+	participantClients := p.clients.forParticipant(definition)
+	// the phase is only written by the job and read once it finished
+	phase := status.DidRotationManifests
+	identity := jobStep{phaseSeeding, func(ctx context.Context) error {
+		phase = status.DidRotationParticipantContext
+		p.statusChecker.SetDidRotation(namespace, phase, status.DidRotationRunning, "")
+		clients := participantClients.withTrace(ctx)
+		if err := waitForApis(ctx, definition, clients, creds, p.statusChecker); err != nil {
+			return err
+		}
+		identityHub := identityApi(definition, clients, creds)
+		if err := rotation.resetIdentity(ctx, p.kubeClient, identityHub); err != nil {
+			return err
+		}
+		dataspace := p.dataspaces.lookup(ctx, dataspaceOf(definition))
+		if err := onDeploymentReady(ctx, p.kubeClient, definition, p.statusChecker, clients, creds, dataspace); err != nil {
+			return err
+		}
+		phase = status.DidRotationCredentials
+		p.statusChecker.SetDidRotation(namespace, phase, status.DidRotationRunning, "")
+		return rotation.verifyCredentials(ctx, identityHub, issuerFor(dataspace))
+	}}
+
+	job, err := startRollout(p.kubeClient, ctx, p.statusChecker, namespace, "DID rotation", func(ctx context.Context, kubernetesAction action) ([]appliedObject, error) {
+		return plan.apply(p.kubeClient, ctx, kubernetesAction, p.clients)
+	}, identity)
+	if err != nil {
+		return nil, err
+	}
+	// the identity phases start once the manifests were applied and the deployments are ready
+	p.statusChecker.BeginDidRotation(namespace, rotation.previous.Did, definition.Did)
+	runInBackground(func() {
+		<-job.done
+		job.mu.Lock()
+		succeeded, message := job.Status == jobSucceeded, job.Error
+		job.mu.Unlock()
+		if succeeded {
+			p.statusChecker.SetDidRotation(namespace, status.DidRotationCredentials, status.DidRotationCompleted, "")
+			return
+		}
+		p.statusChecker.SetDidRotation(namespace, phase, status.DidRotationFailed, message)
+	})
+	return job, nil
+}
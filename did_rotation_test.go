@@ -0,0 +1,105 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPlanDidRotation(t *testing.T) {
+	definition, _ := json.Marshal(ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme.example",
+		ApiKeys: &ApiKeyOverrides{ManagementApiKey: redacted}})
+	state := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: seedingStateConfigMapName, Namespace: "acme"},
+		Data:       map[string]string{seedingDefinitionKey: string(definition)},
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(state).Build()
+	ctx := context.Background()
+
+	rotation, err := planDidRotation(kube, ctx, "acme", "did:web:acme.example:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation.previous.Did != "did:web:acme.example" || rotation.rotated.Did != "did:web:acme.example:v2" || rotation.rotated.ApiKeys != nil {
+		t.Errorf("unexpected rotation %+v", rotation)
+	}
+	for _, tc := range []struct {
+		name      string
+		namespace string
+		did       string
+		status    int
+	}{
+		{"missing DID", "acme", "", fiber.StatusBadRequest},
+		{"invalid DID", "acme", "did:example:acme", fiber.StatusBadRequest},
+		{"unchanged DID", "acme", "did:web:acme.example", fiber.StatusConflict},
+		{"never seeded", "bolt", "did:web:bolt.example", fiber.StatusConflict},
+	} {
+		if _, err := planDidRotation(kube, ctx, tc.namespace, tc.did); problemOf(err).Status != tc.status {
+			t.Errorf("%s: expected %d, got %v", tc.name, tc.status, err)
+		}
+	}
+}
+
+func TestResetIdentity(t *testing.T) {
+	var deleted string
+	identityHub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = r.URL.Path
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer identityHub.Close()
+
+	previous := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme.example"}
+	definition, _ := json.Marshal(previous)
+	state := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: seedingStateConfigMapName, Namespace: "acme"},
+		Data: map[string]string{
+			seedingDefinitionKey:                    string(definition),
+			seedingResourcesKey:                     `{"participantContextId":"did:web:acme.example","stsClientId":"did:web:acme.example"}`,
+			seedingStepPrefix + seedStepAssets:      "2026-01-01T00:00:00Z",
+			seedingStepPrefix + seedStepParticipant: "2026-01-01T00:00:00Z",
+			seedingStepPrefix + seedStepCredentials: "2026-01-01T00:00:00Z",
+		},
+	}
+	kube := &configMapStore{configMaps: map[client.ObjectKey]*corev1.ConfigMap{client.ObjectKeyFromObject(state): state}}
+	ctx := context.Background()
+
+	rotated := previous
+	rotated.Did = "did:web:acme.example:v2"
+	rotation := didRotation{previous: previous, rotated: rotated}
+	if err := rotation.resetIdentity(ctx, kube, &api.ApiClient{BaseUrl: identityHub.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if deleted != "/participants/"+base64.StdEncoding.EncodeToString([]byte(previous.Did)) {
+		t.Errorf("expected the participant context of the previous DID to be deleted, got %q", deleted)
+	}
+	reset, err := loadSeedingState(kube, ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.definition.Did != rotated.Did || reset.resources.ParticipantContextId != "" {
+		t.Errorf("expected the seeding state of the rotated DID, got %+v", reset)
+	}
+	if _, ok := reset.completed[seedStepAssets]; !ok {
+		t.Error("expected the catalog to stay seeded")
+	}
+	for _, step := range []string{seedStepParticipant, seedStepCredentials} {
+		if _, ok := reset.completed[step]; ok {
+			t.Errorf("expected the %s step to run again", step)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How the live state of an object departs from the desired state
+const (
+	driftMissing = "missing"
+	driftChanged = "changed"
+)
+
+// driftReport lists the objects of a participant that were deleted or changed out-of-band since the provisioner last
+// applied them.
+type driftReport struct {
+	Participant string `json:"participant"`
+	// Revision holds the desired state, the manifests as rendered when the participant was last applied
+	Revision  int            `json:"revision"`
+	CheckedAt time.Time      `json:"checkedAt"`
+	Drifted   bool           `json:"drifted"`
+	Objects   []objectChange `json:"objects,omitempty"`
+}
+
+// dryRunApply is the server-side dry run of applyResource, the object is filled with the state it would have.
+func dryRunApply(c client.Client, ctx context.Context, object client.Object) error {
+	return c.Patch(ctx, object, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership, client.DryRunAll)
+}
+
+// detectDrift applies the manifests of the participant's latest revision in a server-side dry run and compares the
+// outcome with the live objects. Fields other managers own but the provisioner doesn't apply are not drift.
+func detectDrift(c client.Client, ctx context.Context, namespace string) (driftReport, error) {
+	report := driftReport{Participant: namespace, CheckedAt: time.Now().UTC()}
+	rev, err := latestRevision(c, ctx, namespace)
+	if err != nil {
+		return report, err
+	}
+	report.Revision = rev.Number
+	// the revision may predate a key rotation
+	creds, err := loadCredentials(c, ctx, namespace)
+	if err != nil {
+		return report, err
+	}
+	changes := &changeLog{}
+	if _, err := applyYaml(&namespace, new(string), c, ctx, rev.manifests, changes.action(dryRunApply), credentialsMutator(creds, "")); err != nil {
+		return report, err
+	}
+	for _, change := range changes.list() {
+		switch change.Action {
+		case changeUnchanged:
+			continue
+		case changeCreated:
+			change.Action = driftMissing
+		case changeUpdated:
+			change.Action = driftChanged
+		}
+		report.Objects = append(report.Objects, change)
+	}
+	report.Drifted = len(report.Objects) > 0
+	return report, nil
+}
+
+func getDrift(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespace := c.Params("participantName")
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		report, err := detectDrift(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		return c.JSON(report)
+	}
+}
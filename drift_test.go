@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// driftClient keeps revisions as secrets and serves live objects, dry-run applies leave the desired object as it is.
+type driftClient struct {
+	*secretClient
+	live map[string]*unstructured.Unstructured
+}
+
+func (c *driftClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return c.secretClient.Get(ctx, key, obj, opts...)
+	}
+	live, ok := c.live[u.GetKind()+"/"+key.Name]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: u.GetKind()}, key.Name)
+	}
+	live.DeepCopyInto(u)
+	return nil
+}
+
+func (c *driftClient) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return nil
+}
+
+func TestDetectDrift(t *testing.T) {
+	c := &driftClient{secretClient: newSecretClient(), live: make(map[string]*unstructured.Unstructured)}
+	ctx := context.Background()
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+  namespace: alice
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: controlplane
+  namespace: alice
+spec:
+  type: ClusterIP
+`
+	if _, err := saveRevision(c, ctx, "alice", "create", manifests, nil); err != nil {
+		t.Fatal(err)
+	}
+	deployment := parseObject(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: controlplane\n  namespace: alice\nspec:\n  replicas: 1\n")
+	// applied manifests hold integers, decoded test objects floats
+	deployment.Object["spec"] = map[string]any{"replicas": int64(1)}
+	c.live["Deployment/controlplane"] = deployment
+
+	report, err := detectDrift(c, ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []objectChange{{Kind: "Service", Name: "controlplane", Action: driftMissing}}
+	if !report.Drifted || report.Revision != 1 || !reflect.DeepEqual(report.Objects, want) {
+		t.Errorf("expected the deleted service to be reported, got %+v", report)
+	}
+
+	// scaled with kubectl
+	deployment.Object["spec"] = map[string]any{"replicas": int64(3)}
+	report, err = detectDrift(c, ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Objects) != 2 || report.Objects[0].Action != driftChanged || !reflect.DeepEqual(report.Objects[0].Fields, []string{"spec.replicas"}) {
+		t.Errorf("expected the scaled deployment to be reported, got %+v", report.Objects)
+	}
+}
+
+func TestDetectDriftWithoutRevision(t *testing.T) {
+	c := &driftClient{secretClient: newSecretClient()}
+	if _, err := detectDrift(c, context.Background(), "alice"); err == nil {
+		t.Error("expected an error without a revision to compare with")
+	}
+}
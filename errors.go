@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Stable codes of error responses and failed jobs. Errors raised with fiber.NewError are coded after their status,
+// e.g. NOT_FOUND or TOO_MANY_REQUESTS.
+const (
+	codeValidationFailed     = "VALIDATION_FAILED"
+	codeMalformedBody        = "MALFORMED_BODY"
+	codeManifestRenderFailed = "MANIFEST_RENDER_FAILED"
+	codeApplyFailed          = "APPLY_FAILED"
+	codeReadinessFailed      = "READINESS_FAILED"
+	codeSeedFailed           = "SEED_FAILED"
+	codeHookFailed           = "HOOK_FAILED"
+	codeDeleteFailed         = "DELETE_FAILED"
+	codeDeleteIncomplete     = "DELETE_INCOMPLETE"
+	codeKubeUnavailable      = "KUBE_UNAVAILABLE"
+	codeKubeRequestFailed    = "KUBE_REQUEST_FAILED"
+	codeParticipantBusy      = "PARTICIPANT_BUSY"
+	codeParticipantProtected = "PARTICIPANT_PROTECTED"
+	codeJobCancelled         = "JOB_CANCELLED"
+	codeDidPreflightFailed   = "DID_PREFLIGHT_FAILED"
+	codeNameReserved         = "NAME_RESERVED"
+	codeNameConflict         = "NAME_CONFLICT"
+	codeQuotaExceeded        = "QUOTA_EXCEEDED"
+	codeMaintenance          = "MAINTENANCE"
+	codePolicyRejected       = "POLICY_REJECTED"
+	codePolicyUnavailable    = "POLICY_UNAVAILABLE"
+	codeIdempotencyKeyInUse  = "IDEMPOTENCY_KEY_IN_USE"
+	codeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	codeInternal             = "INTERNAL_ERROR"
+)
+
+// Media type of RFC 7807 error responses
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 7807 error response.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the kind of error for clients, it doesn't change between releases
+	Code string `json:"code"`
+	// Errors lists the rejected fields of invalid requests
+	Errors []fieldError `json:"errors,omitempty"`
+	// Conflict is the existing resource a request collides with
+	Conflict *conflictingResource `json:"conflict,omitempty"`
+}
+
+// conflictingResource identifies an existing resource of the cluster.
+type conflictingResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// conflictError rejects a request with 409 because it collides with an existing resource.
+type conflictError struct {
+	code     string
+	resource conflictingResource
+	message  string
+}
+
+func (e *conflictError) Error() string {
+	return e.message
+}
+
+// codedError attaches a stable error code and response status to an error.
+type codedError struct {
+	code   string
+	status int
+	err    error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+func withCode(code string, status int, err error) error {
+	return &codedError{code: code, status: status, err: err}
+}
+
+// errorHandler answers failed requests with an RFC 7807 problem. Errors of the Kubernetes client and unexpected
+// errors are logged, but only described in general terms to the client.
+func errorHandler(c *fiber.Ctx, err error) error {
+	p := problemOf(err)
+	p.Instance = c.Path()
+	if p.Code == codeKubeUnavailable {
+		setKubeRetryAfter(c)
+	}
+	if p.Status >= fiber.StatusInternalServerError {
+		fmt.Printf("%s %s failed with %s: %v\n", c.Method(), c.Path(), p.Code, err)
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, problemContentType)
+	return c.Status(p.Status).Send(body)
+}
+
+func problemOf(err error) problem {
+	var invalid *validationError
+	var rejection *policyRejection
+	var coded *codedError
+	var conflict *conflictError
+	var fiberErr *fiber.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &invalid):
+		p := newProblem(fiber.StatusBadRequest, codeValidationFailed, invalid.Error())
+		p.Errors = invalid.Fields
+		return p
+	case errors.As(err, &rejection):
+		p := newProblem(fiber.StatusForbidden, codePolicyRejected, rejection.Error())
+		p.Errors = rejection.Reasons
+		return p
+	case errors.As(err, &coded):
+		return newProblem(coded.status, coded.code, coded.Error())
+	case errors.As(err, &conflict):
+		p := newProblem(fiber.StatusConflict, conflict.code, conflict.message)
+		p.Conflict = &conflict.resource
+		return p
+	case errors.As(err, &fiberErr):
+		return newProblem(fiberErr.Code, statusCode(fiberErr.Code), fiberErr.Message)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return newProblem(fiber.StatusBadRequest, codeMalformedBody, err.Error())
+	case kubeUnavailable(err):
+		return newProblem(fiber.StatusServiceUnavailable, codeKubeUnavailable, "the Kubernetes API is not reachable")
+	case apierrors.IsNotFound(err):
+		return newProblem(fiber.StatusNotFound, statusCode(fiber.StatusNotFound), "resource not found")
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return newProblem(fiber.StatusConflict, statusCode(fiber.StatusConflict), "resource was modified concurrently, retry the request")
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return newProblem(fiber.StatusBadGateway, codeKubeRequestFailed, "the Kubernetes API rejected the request")
+	}
+	return newProblem(fiber.StatusInternalServerError, codeInternal, "internal error")
+}
+
+func newProblem(status int, code string, detail string) problem {
+	return problem{
+		Type:   "urn:aruba-provisioner:problem:" + strings.ToLower(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// statusCode derives the code of errors without a more specific one from their status, e.g. NOT_FOUND.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return codeInternal
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// kubeUnavailable reports whether the error means the Kubernetes API could not be reached or is overloaded.
+func kubeUnavailable(err error) bool {
+	if errors.Is(err, errKubeUnavailable) || apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) {
+		return true
+	}
+	// connection failures, but not timeouts of the caller's context
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// jobErrorCode returns the code of the error failing a job in the phase.
+func jobErrorCode(phase string, err error) string {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if errors.Is(err, errJobCancelled) {
+		return codeJobCancelled
+	}
+	// the other phases talk to the participant's components as well
+	if (phase == phaseApply || phase == phaseDrain || phase == phaseDelete || phase == phaseVerify) && kubeUnavailable(err) {
+		return codeKubeUnavailable
+	}
+	switch phase {
+	case phaseApply:
+		return codeApplyFailed
+	case phaseReadiness:
+		return codeReadinessFailed
+	case phaseSeeding:
+		return codeSeedFailed
+	case phaseHooks:
+		return codeHookFailed
+	case phaseDrain, phaseDelete:
+		return codeDeleteFailed
+	case phaseVerify:
+		return codeDeleteIncomplete
+	default:
+		return codeInternal
+	}
+}
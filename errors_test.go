@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		leaks      string
+	}{
+		{"fiber error", fiber.NewError(fiber.StatusConflict, "participant acme already exists"), fiber.StatusConflict, "CONFLICT", ""},
+		{"validation", &validationError{Fields: []fieldError{{"did", "must be a did:web or did:key DID"}}}, fiber.StatusBadRequest, codeValidationFailed, ""},
+		{"render", withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, errors.New("bad template")), fiber.StatusInternalServerError, codeManifestRenderFailed, ""},
+		{"kube unreachable", fmt.Errorf("list namespaces: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), fiber.StatusServiceUnavailable, codeKubeUnavailable, "connection refused"},
+		{"kube forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "acme", errors.New("system:serviceaccount:provisioner cannot get")), fiber.StatusBadGateway, codeKubeRequestFailed, "serviceaccount"},
+		{"kube not found", apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "acme"), fiber.StatusNotFound, "NOT_FOUND", "secrets"},
+		{"unexpected", errors.New("nil pointer in seeding client"), fiber.StatusInternalServerError, codeInternal, "nil pointer"},
+	}
+	for _, tt := range tests {
+		app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+		app.Get("/", func(c *fiber.Ctx) error {
+			return tt.err
+		})
+		response, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, response.StatusCode, tt.wantStatus)
+		}
+		if contentType := response.Header.Get(fiber.HeaderContentType); contentType != problemContentType {
+			t.Errorf("%s: content type = %q", tt.name, contentType)
+		}
+		body, _ := io.ReadAll(response.Body)
+		var p problem
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if p.Code != tt.wantCode || p.Status != tt.wantStatus {
+			t.Errorf("%s: code = %s, status = %d, want %s, %d", tt.name, p.Code, p.Status, tt.wantCode, tt.wantStatus)
+		}
+		if tt.leaks != "" && strings.Contains(string(body), tt.leaks) {
+			t.Errorf("%s: response leaks the internal error: %s", tt.name, body)
+		}
+	}
+}
+
+func TestJobErrorCode(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		phase string
+		err   error
+		want  string
+	}{
+		{phaseApply, errors.New("invalid object"), codeApplyFailed},
+		{phaseApply, unreachable, codeKubeUnavailable},
+		{phaseSeeding, unreachable, codeSeedFailed},
+		{phaseReadiness, errors.New("timed out"), codeReadinessFailed},
+		{phaseDrain, errors.New("forbidden"), codeDeleteFailed},
+		{phaseVerify, errors.New("volumes retained"), codeDeleteIncomplete},
+	}
+	for _, tt := range tests {
+		if got := jobErrorCode(tt.phase, tt.err); got != tt.want {
+			t.Errorf("jobErrorCode(%s, %v) = %s, want %s", tt.phase, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// participantExport is everything needed to restore or clone a participant: the definition it was provisioned from,
+// the objects applied for it and the IDs of the entities seeded into its connector. Credentials are redacted, restored
+// participants get new keys.
+type participantExport struct {
+	ParticipantName string    `json:"participantName"`
+	ExportedAt      time.Time `json:"exportedAt"`
+	TemplateVersion string    `json:"templateVersion,omitempty"`
+	Did             string    `json:"did,omitempty"`
+	// Definition is empty for participants this provisioner didn't seed
+	Definition *ParticipantDefinition `json:"definition,omitempty"`
+	// Manifests are the live objects applied for the participant, as YAML
+	Manifests string    `json:"manifests"`
+	Seeded    seededIds `json:"seeded"`
+	Steps     []string  `json:"completedSeedSteps,omitempty"`
+}
+
+// seededIds lists the entities of completed seed steps.
+type seededIds struct {
+	Assets              []string `json:"assets,omitempty"`
+	Policies            []string `json:"policies,omitempty"`
+	ContractDefinitions []string `json:"contractDefinitions,omitempty"`
+}
+
+// exportParticipant collects the export of the participant, a not found error if it doesn't exist.
+func exportParticipant(c client.Client, ctx context.Context, name string) (participantExport, error) {
+	manifests, err := liveManifests(c, ctx, name)
+	if err != nil {
+		return participantExport{}, err
+	}
+	export := participantExport{ParticipantName: name, ExportedAt: time.Now().UTC(), Manifests: manifests}
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return participantExport{}, err
+	}
+	export.TemplateVersion = namespace.Annotations[templateVersionAnnotation]
+
+	state, err := loadSeedingState(c, ctx, name)
+	if err != nil {
+		return participantExport{}, err
+	}
+	if state.definition.ParticipantName == "" {
+		return export, nil
+	}
+	definition := state.definition.redacted()
+	export.Definition = &definition
+	export.Did = definition.Did
+	for _, step := range seedStepNames {
+		if _, ok := state.completed[step]; ok {
+			export.Steps = append(export.Steps, step)
+		}
+	}
+
+	if export.Seeded, err = state.seeded(); err != nil {
+		return participantExport{}, err
+	}
+	return export, nil
+}
+
+// seeded returns the IDs of the entities the completed seed steps created.
+func (s seedingState) seeded() (seededIds, error) {
+	var ids seededIds
+	catalog, err := connectorCatalog(s.definition)
+	if err != nil {
+		return ids, err
+	}
+	for _, entities := range []struct {
+		step   string
+		bodies []string
+		ids    *[]string
+	}{
+		{seedStepAssets, catalog.assets, &ids.Assets},
+		{seedStepPolicies, catalog.policies, &ids.Policies},
+		{seedStepContractDefinitions, catalog.contractDefinitions, &ids.ContractDefinitions},
+	} {
+		if _, ok := s.completed[entities.step]; !ok {
+			continue
+		}
+		if *entities.ids, err = orderedCatalogIds(entities.bodies); err != nil {
+			return ids, err
+		}
+	}
+	return ids, nil
+}
+
+// restoredDefinition returns the definition the participant of the export is provisioned from again. The redacted
+// API keys are dropped, restored participants get the keys of their credentials secret or the default ones.
+func (e participantExport) restoredDefinition() (ParticipantDefinition, error) {
+	if e.Definition == nil {
+		return ParticipantDefinition{}, fiber.NewError(fiber.StatusBadRequest, "the export has no definition, only participants seeded by the provisioner can be imported")
+	}
+	if e.Definition.ParticipantName != e.ParticipantName {
+		return ParticipantDefinition{}, fiber.NewError(fiber.StatusBadRequest, "participantName of the definition does not match the export")
+	}
+	return e.Definition.unredacted(), nil
+}
+
+// orderedCatalogIds returns the IDs of the catalog entries in the order they are seeded.
+func orderedCatalogIds(bodies []string) ([]string, error) {
+	ids := make([]string, 0, len(bodies))
+	for _, body := range bodies {
+		var entity struct {
+			Id string `json:"@id"`
+		}
+		if err := json.Unmarshal([]byte(body), &entity); err != nil {
+			return nil, fmt.Errorf("parse catalog entry: %w", err)
+		}
+		ids = append(ids, entity.Id)
+	}
+	return ids, nil
+}
+
+func getParticipantExport(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("participantName")
+		export, err := exportParticipant(kubeClient, ctx, name)
+		if apierrors.IsNotFound(err) {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-export.json"`, name))
+		return c.JSON(export)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeededIds(t *testing.T) {
+	state := seedingState{
+		definition: ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice"},
+		completed:  map[string]string{seedStepAssets: "2024-01-01T00:00:00Z", seedStepPolicies: "2024-01-01T00:00:00Z"},
+	}
+	ids, err := state.seeded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, _ := connectorCatalog(state.definition)
+	if len(ids.Assets) != len(catalog.assets) || slices.Contains(ids.Assets, "") {
+		t.Errorf("expected the IDs of all %d default assets, got %v", len(catalog.assets), ids.Assets)
+	}
+	if len(ids.Policies) != len(catalog.policies) {
+		t.Errorf("expected the IDs of all %d default policies, got %v", len(catalog.policies), ids.Policies)
+	}
+	if ids.ContractDefinitions != nil {
+		t.Errorf("expected no contract definitions before their step completed, got %v", ids.ContractDefinitions)
+	}
+}
+
+func TestRestoredDefinition(t *testing.T) {
+	if _, err := (participantExport{ParticipantName: "alice"}).restoredDefinition(); err == nil {
+		t.Error("expected an export without definition to be rejected")
+	}
+	export := participantExport{ParticipantName: "alice", Definition: &ParticipantDefinition{ParticipantName: "bob"}}
+	if _, err := export.restoredDefinition(); err == nil {
+		t.Error("expected a definition of another participant to be rejected")
+	}
+
+	export.Definition = &ParticipantDefinition{ParticipantName: "alice", ApiKeys: &ApiKeyOverrides{ManagementApiKey: redacted, IdentityApiKey: redacted}}
+	definition, err := export.restoredDefinition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if definition.ApiKeys != nil {
+		t.Errorf("expected the redacted keys to be dropped, got %+v", definition.ApiKeys)
+	}
+	if export.Definition.ApiKeys.ManagementApiKey != redacted {
+		t.Error("restoring modified the export")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secretClient is an in-memory client supporting just enough Secret operations for unit tests. Calls to other
+// methods panic through the nil embedded client.
+type secretClient struct {
+	client.Client
+	secrets map[client.ObjectKey]*corev1.Secret
+}
+
+func newSecretClient() *secretClient {
+	return &secretClient{secrets: make(map[client.ObjectKey]*corev1.Secret)}
+}
+
+func (c *secretClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	secret, ok := c.secrets[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	secret.DeepCopyInto(obj.(*corev1.Secret))
+	return nil
+}
+
+func (c *secretClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	secrets := list.(*corev1.SecretList)
+	for key, secret := range c.secrets {
+		if options.Namespace != "" && key.Namespace != options.Namespace {
+			continue
+		}
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labelSet(secret.Labels)) {
+			continue
+		}
+		secrets.Items = append(secrets.Items, *secret.DeepCopy())
+	}
+	return nil
+}
+
+func (c *secretClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.secrets[key]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	c.secrets[key] = obj.(*corev1.Secret).DeepCopy()
+	return nil
+}
+
+func (c *secretClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.secrets[key]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	c.secrets[key] = obj.(*corev1.Secret).DeepCopy()
+	return nil
+}
+
+func (c *secretClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.secrets[key]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	delete(c.secrets, key)
+	return nil
+}
+
+type labelSet map[string]string
+
+func (l labelSet) Has(key string) bool {
+	_, ok := l[key]
+	return ok
+}
+
+func (l labelSet) Get(key string) string {
+	return l[key]
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Optional component running a federated catalog node next to the participant's connector
+const federatedCatalogComponent = "federatedcatalog"
+
+// Annotation on participant namespaces and catalog participant lists naming their dataspace. Dataspace names aren't
+// restricted to label values.
+const dataspaceAnnotation = "aruba-provisioner/dataspace"
+
+// Label marking the namespaces of standalone federated catalog nodes, its value is the node's name
+const federatedCatalogLabel = "aruba-provisioner/federated-catalog"
+
+// Label marking the participant lists of federated catalog crawlers, which are kept up to date with their dataspace
+const catalogParticipantsLabel = "aruba-provisioner/catalog-participants"
+
+// ConfigMap the crawler of a federated catalog reads the participants to crawl from
+const catalogParticipantsConfigMapName = "federatedcatalog-participants"
+
+const catalogParticipantsKey = "participants.json"
+
+// Annotation on the participant list of a crawler naming the participant it crawls as, which isn't crawled itself
+const catalogIdentityAnnotation = "aruba-provisioner/catalog-identity"
+
+// Settings of the federated catalog pointing at the identity hub and Vault the crawler authenticates with
+var catalogIdentitySettings = map[string]string{
+	"EDC_IAM_STS_OAUTH_TOKEN_URL": "http://identityhub.%s.svc.cluster.local:7084/api/sts/token",
+	"EDC_VAULT_HASHICORP_URL":     "http://vault.%s.svc.cluster.local:8200",
+}
+
+// FederatedCatalogDefinition describes a federated catalog node of a dataspace that doesn't belong to a participant's
+// stack. It crawls with the identity of one of the dataspace's participants.
+type FederatedCatalogDefinition struct {
+	// Name is the namespace the node is deployed to, and the prefix of its ingress path
+	Name string `json:"name"`
+	// Dataspace whose participants are crawled, the default dataspace if empty
+	Dataspace string `json:"dataspace,omitempty"`
+	// Identity is the participant whose DID and identity hub the crawler requests catalogs with
+	Identity string `json:"identity"`
+}
+
+// federatedCatalogStatus reports a standalone federated catalog node.
+type federatedCatalogStatus struct {
+	Name      string `json:"name"`
+	Dataspace string `json:"dataspace"`
+	Did       string `json:"did"`
+	// Url is the catalog query API of the node
+	Url   string `json:"url"`
+	Ready bool   `json:"ready"`
+	// Components reports the readiness of the node's deployment
+	Components []status.ComponentStatus `json:"components"`
+	// Participants are crawled by the node, by name
+	Participants []string `json:"participants"`
+}
+
+func (d *FederatedCatalogDefinition) validate(dataspaces map[string]DataspaceConfig) error {
+	if d.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required")
+	}
+	if errs := validation.IsDNS1123Label(d.Name); len(errs) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid name: "+errs[0])
+	}
+	if d.Identity == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "identity is required")
+	}
+	if err := validateDataspace(dataspaces, d.Dataspace); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if d.Dataspace == "" {
+		d.Dataspace = defaultDataspace
+	}
+	return nil
+}
+
+// federatedCatalogUrl returns the base URL of the catalog query API of the node in the namespace.
+func federatedCatalogUrl(namespace string) string {
+	return fmt.Sprintf("http://%s:8084/api/catalog", status.ServiceHost(federatedCatalogComponent, namespace))
+}
+
+// dataspaceMutator records the participant's dataspace on its namespace, which federated catalogs find the
+// participants to crawl by.
+func dataspaceMutator(dataspace string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, map[string]string{dataspaceAnnotation: dataspace})
+		}
+		return nil
+	}
+}
+
+// dataspaceParticipants returns the DIDs of the dataspace's participants by name, leaving out the excluded one.
+// Participants provisioned before their dataspace was recorded belong to the default dataspace, those without DID
+// can't be crawled and those waiting to be registered with their dataspace aren't crawled yet.
+func dataspaceParticipants(c client.Client, ctx context.Context, dataspace string, exclude string) (map[string]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		return nil, err
+	}
+	participants := map[string]string{}
+	for _, namespace := range namespaces.Items {
+		if namespace.Name == exclude || namespace.DeletionTimestamp != nil || namespace.Annotations[registrationAnnotation] == registrationPending {
+			continue
+		}
+		of := namespace.Annotations[dataspaceAnnotation]
+		if of == "" {
+			of = defaultDataspace
+		}
+		if did := namespace.Annotations[didAnnotation]; of == dataspace && did != "" {
+			participants[namespace.Name] = did
+		}
+	}
+	return participants, nil
+}
+
+func participantsList(participants map[string]string) (string, error) {
+	list, err := json.MarshalIndent(participants, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(list) + "\n", nil
+}
+
+// federatedCatalogMutator fills the participant list of the crawler and marks it for the refreshes when participants
+// join or leave the dataspace.
+func federatedCatalogMutator(dataspace string, identity string, participants map[string]string) (objectMutator, error) {
+	list, err := participantsList(participants)
+	if err != nil {
+		return nil, err
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "ConfigMap" || obj.GetName() != catalogParticipantsConfigMapName {
+			return nil
+		}
+		addLabels(obj, map[string]string{catalogParticipantsLabel: "true"})
+		addAnnotations(obj, map[string]string{dataspaceAnnotation: dataspace, catalogIdentityAnnotation: identity})
+		return unstructured.SetNestedField(obj.Object, list, "data", catalogParticipantsKey)
+	}, nil
+}
+
+// standaloneCatalogMutator labels the namespace of a standalone node and points its crawler at the identity hub and
+// Vault of the participant it crawls as.
+func standaloneCatalogMutator(definition FederatedCatalogDefinition, did string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		switch {
+		case obj.GetKind() == "Namespace":
+			addLabels(obj, map[string]string{federatedCatalogLabel: definition.Name})
+			addAnnotations(obj, map[string]string{dataspaceAnnotation: definition.Dataspace, didAnnotation: did})
+		case obj.GetKind() == "ConfigMap" && obj.GetName() == "federatedcatalog-config":
+			for key, url := range catalogIdentitySettings {
+				if err := unstructured.SetNestedField(obj.Object, fmt.Sprintf(url, definition.Identity), "data", key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// refreshFederatedCatalogs rewrites the participant lists of all federated catalogs whose dataspace changed, a
+// crawler picks the new list up with its next crawl.
+func refreshFederatedCatalogs(c client.Client, ctx context.Context) error {
+	lists := &corev1.ConfigMapList{}
+	if err := c.List(ctx, lists, client.MatchingLabels{catalogParticipantsLabel: "true"}); err != nil {
+		return err
+	}
+	for i := range lists.Items {
+		configMap := &lists.Items[i]
+		participants, err := dataspaceParticipants(c, ctx, configMap.Annotations[dataspaceAnnotation], configMap.Annotations[catalogIdentityAnnotation])
+		if err != nil {
+			return err
+		}
+		list, err := participantsList(participants)
+		if err != nil {
+			return err
+		}
+		if configMap.Data[catalogParticipantsKey] == list {
+			continue
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[catalogParticipantsKey] = list
+		if err := c.Update(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("refresh participants of the federated catalog in %s: %w", configMap.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// claimFederatedCatalog rejects names of system namespaces and of namespaces that aren't federated catalog nodes.
+func claimFederatedCatalog(c client.Client, ctx context.Context, name string) error {
+	namespace := conflictingResource{Kind: "Namespace", Name: name}
+	if status.IsSystemNamespace(name) {
+		return &conflictError{code: codeNameReserved, resource: namespace, message: fmt.Sprintf("%s is a system namespace and can't be a federated catalog", name)}
+	}
+	existing := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, existing); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && existing.Labels[federatedCatalogLabel] != name {
+		return &conflictError{code: codeNameConflict, resource: namespace, message: fmt.Sprintf("namespace %s already exists and isn't a federated catalog", name)}
+	}
+	return nil
+}
+
+// applyFederatedCatalog deploys a standalone node crawling the participants of its dataspace, other than its identity.
+func applyFederatedCatalog(c client.Client, ctx context.Context, definition FederatedCatalogDefinition, kubernetesAction action) error {
+	participants, err := dataspaceParticipants(c, ctx, definition.Dataspace, "")
+	if err != nil {
+		return err
+	}
+	did, ok := participants[definition.Identity]
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("identity: %s is no participant of dataspace %s", definition.Identity, definition.Dataspace))
+	}
+	delete(participants, definition.Identity)
+	crawler, err := federatedCatalogMutator(definition.Dataspace, definition.Identity, participants)
+	if err != nil {
+		return err
+	}
+	namespaceYaml := fmt.Sprintf("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n---\n", definition.Name)
+	_, err = applyYaml(&definition.Name, &did, c, ctx, namespaceYaml+federatedCatalogYaml, kubernetesAction, standaloneCatalogMutator(definition, did), crawler)
+	return err
+}
+
+// getFederatedCatalogStatus reports a standalone node. Only namespaces created as federated catalogs are found.
+func getFederatedCatalogStatus(kubeClient client.Client, ctx context.Context, name string) (federatedCatalogStatus, error) {
+	namespace := &corev1.Namespace{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, namespace); apierrors.IsNotFound(err) || (err == nil && namespace.Labels[federatedCatalogLabel] != name) {
+		return federatedCatalogStatus{}, fiber.NewError(fiber.StatusNotFound, "federated catalog not found")
+	} else if err != nil {
+		return federatedCatalogStatus{}, err
+	}
+	components, ready, err := deploymentComponents(kubeClient, ctx, name, []string{federatedCatalogComponent})
+	if err != nil {
+		return federatedCatalogStatus{}, err
+	}
+	result := federatedCatalogStatus{
+		Name:         name,
+		Dataspace:    namespace.Annotations[dataspaceAnnotation],
+		Did:          namespace.Annotations[didAnnotation],
+		Url:          federatedCatalogUrl(name),
+		Ready:        ready,
+		Components:   components,
+		Participants: []string{},
+	}
+	list := &corev1.ConfigMap{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: name, Name: catalogParticipantsConfigMapName}, list); client.IgnoreNotFound(err) != nil {
+		return federatedCatalogStatus{}, err
+	}
+	var participants map[string]string
+	if err := json.Unmarshal([]byte(list.Data[catalogParticipantsKey]), &participants); err == nil {
+		for participant := range participants {
+			result.Participants = append(result.Participants, participant)
+		}
+		slices.Sort(result.Participants)
+	}
+	return result, nil
+}
+
+func provisionFederatedCatalog(kubeClient client.Client, ctx context.Context, participants *provisioner, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var definition FederatedCatalogDefinition
+		if err := parser.parse(c, &definition); err != nil {
+			return err
+		}
+		dataspaces, err := participants.dataspaces.all(ctx)
+		if err != nil {
+			return err
+		}
+		if err := definition.validate(dataspaces); err != nil {
+			return err
+		}
+		if err := claimFederatedCatalog(kubeClient, ctx, definition.Name); err != nil {
+			return err
+		}
+		if err := applyFederatedCatalog(kubeClient, c.UserContext(), definition, impersonated(withRetries(applyResource))); err != nil {
+			return err
+		}
+		result, err := getFederatedCatalogStatus(kubeClient, ctx, definition.Name)
+		if err != nil {
+			return err
+		}
+		c.Location("/api/v1/catalogs/" + definition.Name)
+		return c.Status(fiber.StatusCreated).JSON(result)
+	}
+}
+
+func getFederatedCatalog(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := getFederatedCatalogStatus(kubeClient, ctx, c.Params("catalogName"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(result)
+	}
+}
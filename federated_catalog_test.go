@@ -0,0 +1,160 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func participantNamespace(name string, dataspace string) *corev1.Namespace {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Labels:      map[string]string{status.ManagedByLabel: status.ManagedByValue},
+		Annotations: map[string]string{didAnnotation: "did:web:" + name},
+	}}
+	if dataspace != "" {
+		namespace.Annotations[dataspaceAnnotation] = dataspace
+	}
+	return namespace
+}
+
+func catalogParticipants(t *testing.T, data string) map[string]string {
+	t.Helper()
+	var participants map[string]string
+	if err := json.Unmarshal([]byte(data), &participants); err != nil {
+		t.Fatal(err)
+	}
+	return participants
+}
+
+func TestFederatedCatalogComponent(t *testing.T) {
+	if err := validateManifest(federatedCatalogYaml); err != nil {
+		t.Fatal(err)
+	}
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", Components: []string{federatedCatalogComponent}}
+	crawler, err := federatedCatalogMutator(defaultDataspace, "acme", map[string]string{"beta": "did:web:beta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := provisioningPlan{
+		definition: definition,
+		templates:  manifestSet{Connector: participantYaml, IdentityHub: identityhubYaml},
+		mutators:   append(definition.mutators(), crawler),
+	}
+	objects := map[string]*unstructured.Unstructured{}
+	deployments := &deploymentCollector{}
+	if _, err := plan.applyManifests(nil, context.Background(), deployments.action(func(_ client.Client, _ context.Context, object client.Object) error {
+		objects[object.GetObjectKind().GroupVersionKind().Kind+"/"+object.GetName()] = object.(*unstructured.Unstructured)
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if got := deployments.awaited(); !slices.Contains(got, "controlplane") || !slices.Contains(got, federatedCatalogComponent) {
+		t.Errorf("expected the full stack and the federated catalog, got %v", got)
+	}
+	namespace := objects["Namespace/acme"]
+	if got := namespace.GetAnnotations()[status.ComponentsAnnotation]; got != "controlplane,dataplane,identityhub,federatedcatalog" {
+		t.Errorf("expected the full stack and the federated catalog on the namespace, got %q", got)
+	}
+	if got := namespace.GetAnnotations()[dataspaceAnnotation]; got != defaultDataspace {
+		t.Errorf("expected the dataspace on the namespace, got %q", got)
+	}
+	list := objects["ConfigMap/"+catalogParticipantsConfigMapName]
+	if list == nil || list.GetLabels()[status.ParticipantLabel] != "acme" || list.GetLabels()[catalogParticipantsLabel] != "true" {
+		t.Fatalf("expected the participant's crawler list, got %v", list)
+	}
+	data, _, _ := unstructured.NestedString(list.Object, "data", catalogParticipantsKey)
+	if participants := catalogParticipants(t, data); participants["beta"] != "did:web:beta" || len(participants) != 1 {
+		t.Errorf("expected the other participants of the dataspace, got %v", participants)
+	}
+
+	without := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme"}
+	if without.hasComponent(federatedCatalogComponent) || !without.hasComponent("dataplane") {
+		t.Error("expected the federated catalog only to be provisioned when selected")
+	}
+}
+
+func TestRefreshFederatedCatalogs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	list := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        catalogParticipantsConfigMapName,
+			Namespace:   "acme",
+			Labels:      map[string]string{catalogParticipantsLabel: "true"},
+			Annotations: map[string]string{dataspaceAnnotation: defaultDataspace, catalogIdentityAnnotation: "acme"},
+		},
+		Data: map[string]string{catalogParticipantsKey: "{}\n"},
+	}
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		participantNamespace("acme", defaultDataspace),
+		participantNamespace("beta", ""),
+		participantNamespace("gamma", "other"),
+		list,
+	).Build()
+	ctx := context.Background()
+
+	if err := refreshFederatedCatalogs(kube, ctx); err != nil {
+		t.Fatal(err)
+	}
+	refreshed := &corev1.ConfigMap{}
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(list), refreshed); err != nil {
+		t.Fatal(err)
+	}
+	participants := catalogParticipants(t, refreshed.Data[catalogParticipantsKey])
+	if len(participants) != 1 || participants["beta"] != "did:web:beta" {
+		t.Errorf("expected only the other participant of the default dataspace, got %v", participants)
+	}
+}
+
+func TestStandaloneFederatedCatalog(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(participantNamespace("acme", "")).Build()
+	ctx := context.Background()
+
+	definition := FederatedCatalogDefinition{Name: "catalog", Identity: "acme"}
+	if err := definition.validate(map[string]DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	objects := map[string]*unstructured.Unstructured{}
+	capture := func(c client.Client, ctx context.Context, object client.Object) error {
+		objects[object.GetObjectKind().GroupVersionKind().Kind+"/"+object.GetName()] = object.(*unstructured.Unstructured)
+		return c.Create(ctx, object)
+	}
+	if err := applyFederatedCatalog(kube, ctx, definition, capture); err != nil {
+		t.Fatal(err)
+	}
+	config := objects["ConfigMap/federatedcatalog-config"]
+	if url, _, _ := unstructured.NestedString(config.Object, "data", "EDC_IAM_STS_OAUTH_TOKEN_URL"); url != "http://identityhub.acme.svc.cluster.local:7084/api/sts/token" {
+		t.Errorf("expected the crawler to authenticate with the identity's identity hub, got %q", url)
+	}
+	if id, _, _ := unstructured.NestedString(config.Object, "data", "EDC_PARTICIPANT_ID"); id != "did:web:acme" {
+		t.Errorf("expected the identity's DID, got %q", id)
+	}
+
+	catalog, err := getFederatedCatalogStatus(kube, ctx, "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalog.Ready || catalog.Dataspace != defaultDataspace || catalog.Did != "did:web:acme" || len(catalog.Participants) != 0 {
+		t.Errorf("expected a node that isn't ready yet and crawls nobody but its identity, got %+v", catalog)
+	}
+	if err := claimFederatedCatalog(kube, ctx, "acme"); err == nil {
+		t.Error("expected a participant namespace to be rejected")
+	}
+	if err := applyFederatedCatalog(kube, ctx, FederatedCatalogDefinition{Name: "catalog", Dataspace: defaultDataspace, Identity: "nobody"}, capture); err == nil {
+		t.Error("expected an identity outside the dataspace to be rejected")
+	}
+}
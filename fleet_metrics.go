@@ -0,0 +1,134 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Status fields the fleet gauges are derived from, served from the status cache
+var fleetMetricFields = []status.Field{status.FieldComponents, status.FieldCertificates, status.FieldCredentials}
+
+// fleetMetrics exports the state of every participant as gauges in the style of kube-state-metrics, so that alerts
+// like "a participant is DEGRADED for more than 5m" can be written as Prometheus rules:
+//
+//	provisioner_participant_status{status="DEGRADED"} == 1
+//
+// with for: 5m. Each participant has a series per status, 1 for its current status and 0 for the others, so that
+// series don't vanish when the status changes.
+type fleetMetrics struct {
+	kubeClient    client.Client
+	statusChecker *status.StatusChecker
+}
+
+// gauge collects the samples of one metric, rendered in the Prometheus text exposition format.
+type gauge struct {
+	name    string
+	help    string
+	samples []string
+}
+
+func (g *gauge) set(value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	g.samples = append(g.samples, fmt.Sprintf("%s{%s} %s", g.name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'g', -1, 64)))
+}
+
+func (g *gauge) write(out *strings.Builder) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, sample := range g.samples {
+		out.WriteString(sample)
+		out.WriteByte('\n')
+	}
+}
+
+// write renders the gauges of all managed participants. Participants whose status can't be evaluated are left out.
+func (f *fleetMetrics) write(ctx context.Context, out *strings.Builder) error {
+	names, err := discoverParticipants(f.kubeClient, ctx)
+	if err != nil {
+		return err
+	}
+	slices.Sort(names)
+	statuses := make([]status.ParticipantStatus, 0, len(names))
+	for _, name := range names {
+		participantStatus, err := f.statusChecker.GetStatus(ctx, name, fleetMetricFields)
+		if err != nil {
+			fmt.Printf("Exporting metrics of %s failed: %v\n", name, err)
+			continue
+		}
+		statuses = append(statuses, participantStatus)
+	}
+	for _, g := range fleetGauges(statuses, time.Now()) {
+		g.write(out)
+	}
+	return nil
+}
+
+// fleetGauges derives the gauges from the statuses of the participants.
+func fleetGauges(statuses []status.ParticipantStatus, now time.Time) []*gauge {
+	participants := &gauge{name: "provisioner_participant_status", help: "Status of the participant, 1 for the current status and 0 for the others"}
+	componentReady := &gauge{name: "provisioner_participant_component_ready", help: "Whether the component of the participant is ready"}
+	readyReplicas := &gauge{name: "provisioner_participant_component_ready_replicas", help: "Ready replicas of the component of the participant"}
+	desiredReplicas := &gauge{name: "provisioner_participant_component_desired_replicas", help: "Desired replicas of the component of the participant"}
+	certificateReady := &gauge{name: "provisioner_participant_certificate_ready", help: "Whether the TLS certificate of the participant is issued and ready"}
+	certificateExpiry := &gauge{name: "provisioner_participant_certificate_expiry_timestamp_seconds", help: "Expiry of the TLS certificate of the participant as Unix time"}
+	credentialValid := &gauge{name: "provisioner_participant_credential_valid", help: "Whether the verifiable credential held by the participant is issued and not expired"}
+	credentialExpiry := &gauge{name: "provisioner_participant_credential_expiry_timestamp_seconds", help: "Expiry of the verifiable credential held by the participant as Unix time"}
+	participantExpiry := &gauge{name: "provisioner_participant_expiry_timestamp_seconds", help: "When the ephemeral participant is deleted as Unix time"}
+
+	for _, participantStatus := range statuses {
+		name := participantStatus.Name
+		for _, candidate := range status.ProvisioningStatuses {
+			participants.set(boolValue(participantStatus.Status == candidate), "participant", name, "status", string(candidate))
+		}
+		for _, component := range participantStatus.Components {
+			componentReady.set(boolValue(component.Ready), "participant", name, "component", component.Name)
+			readyReplicas.set(float64(component.ReadyReplicas), "participant", name, "component", component.Name)
+			desiredReplicas.set(float64(component.DesiredReplicas), "participant", name, "component", component.Name)
+		}
+		for _, certificate := range participantStatus.Certificates {
+			certificateReady.set(boolValue(certificate.Ready), "participant", name, "certificate", certificate.Name)
+			if certificate.NotAfter != nil {
+				certificateExpiry.set(float64(certificate.NotAfter.Unix()), "participant", name, "certificate", certificate.Name)
+			}
+		}
+		for _, credential := range participantStatus.Credentials {
+			credentialType := credentialTypeOf(credential)
+			valid := credential.State == status.CredentialIssued && (credential.ExpiresAt == nil || credential.ExpiresAt.After(now))
+			credentialValid.set(boolValue(valid), "participant", name, "credential", credential.Id, "type", credentialType)
+			if credential.ExpiresAt != nil {
+				credentialExpiry.set(float64(credential.ExpiresAt.Unix()), "participant", name, "credential", credential.Id, "type", credentialType)
+			}
+		}
+		if participantStatus.ExpiresAt != nil {
+			participantExpiry.set(float64(participantStatus.ExpiresAt.Unix()), "participant", name)
+		}
+	}
+	return []*gauge{participants, componentReady, readyReplicas, desiredReplicas, certificateReady, certificateExpiry, credentialValid, credentialExpiry, participantExpiry}
+}
+
+// credentialTypeOf returns the most specific type of the credential, e.g. MembershipCredential rather than
+// VerifiableCredential.
+func credentialTypeOf(credential status.HeldCredential) string {
+	for _, credentialType := range slices.Backward(credential.Types) {
+		if credentialType != "VerifiableCredential" {
+			return credentialType
+		}
+	}
+	return "VerifiableCredential"
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
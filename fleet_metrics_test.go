@@ -0,0 +1,96 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFleetGauges(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+	notAfter := time.Unix(1900000000, 0)
+	statuses := []status.ParticipantStatus{{
+		Name:   "acme",
+		Status: status.StatusDegraded,
+		Components: []status.ComponentStatus{
+			{Name: "controlplane", Ready: true, ReadyReplicas: 1, DesiredReplicas: 1},
+			{Name: "dataplane", Ready: false, ReadyReplicas: 0, DesiredReplicas: 2},
+		},
+		Certificates: []status.CertificateStatus{{Name: "acme-tls", Ready: true, NotAfter: &notAfter}},
+		Credentials: []status.HeldCredential{
+			{Id: "c1", Types: []string{"VerifiableCredential", "MembershipCredential"}, State: status.CredentialIssued},
+			{Id: "c2", Types: []string{"VerifiableCredential", "DataProcessorCredential"}, State: status.CredentialIssued, ExpiresAt: &expired},
+		},
+	}}
+
+	var out strings.Builder
+	for _, g := range fleetGauges(statuses, now) {
+		g.write(&out)
+	}
+	for _, line := range []string{
+		"# TYPE provisioner_participant_status gauge",
+		`provisioner_participant_status{participant="acme",status="DEGRADED"} 1`,
+		`provisioner_participant_status{participant="acme",status="READY"} 0`,
+		`provisioner_participant_component_ready{participant="acme",component="controlplane"} 1`,
+		`provisioner_participant_component_ready{participant="acme",component="dataplane"} 0`,
+		`provisioner_participant_component_desired_replicas{participant="acme",component="dataplane"} 2`,
+		`provisioner_participant_certificate_ready{participant="acme",certificate="acme-tls"} 1`,
+		`provisioner_participant_certificate_expiry_timestamp_seconds{participant="acme",certificate="acme-tls"} 1.9e+09`,
+		`provisioner_participant_credential_valid{participant="acme",credential="c1",type="MembershipCredential"} 1`,
+		`provisioner_participant_credential_valid{participant="acme",credential="c2",type="DataProcessorCredential"} 0`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("metrics lack %q:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "provisioner_participant_expiry_timestamp_seconds{") {
+		t.Errorf("expected no expiry of a participant without lifetime:\n%s", out.String())
+	}
+}
+
+func TestServeFleetMetrics(t *testing.T) {
+	var objects []client.Object
+	objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme",
+		Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}})
+	for _, deployment := range participantDeploymentNames {
+		objects = append(objects, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deployment, Namespace: "acme"},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+		})
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	checker := status.NewStatusChecker(context.Background(), kube, 0)
+
+	app := fiber.New()
+	app.Get("/metrics", serveMetrics(&fleetMetrics{kubeClient: kube, statusChecker: checker}, context.Background()))
+	response, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	for _, line := range []string{
+		"# TYPE provisioner_provisioning_duration_seconds histogram",
+		`provisioner_participant_status{participant="acme",status="READY"} `,
+		`provisioner_participant_component_ready{participant="acme",component="` + participantDeploymentNames[0] + `"} 1`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics lack %q:\n%s", line, body)
+		}
+	}
+}
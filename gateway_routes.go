@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Ways the participant's APIs are routed to its components
+const (
+	routingIngress = "ingress"
+	routingGateway = "gateway"
+)
+
+// Gateway created in the participant namespace when its routes aren't attached to a shared Gateway
+const participantGatewayName = "gateway"
+
+// Prefix of the ingress-nginx annotations, HTTPRoutes express what they configure with filters
+const nginxAnnotationPrefix = "nginx.ingress.kubernetes.io/"
+
+// GatewayOptions routes the participant's APIs with Gateway API HTTPRoutes converted from the template ingresses.
+// The routes are attached to a shared Gateway, whose listeners have to allow routes from the participant
+// namespaces, or to a Gateway of the participant.
+type GatewayOptions struct {
+	// Name and Namespace of the shared Gateway, SectionName selects one of its listeners
+	Name        string `json:"name,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	SectionName string `json:"sectionName,omitempty"`
+	// ClassName is the GatewayClass of the Gateway created in the participant namespace if no shared one is named
+	ClassName string `json:"className,omitempty"`
+}
+
+// defaultRouting and defaultGateway are set with --routing, --gateway-name, --gateway-namespace,
+// --gateway-section-name and --gateway-class.
+var (
+	defaultRouting = routingIngress
+	defaultGateway GatewayOptions
+)
+
+// routingOf returns how the participant's APIs are routed: as requested, with Gateway API if the definition configures
+// a gateway, or as configured for the provisioner.
+func routingOf(definition ParticipantDefinition) string {
+	switch {
+	case definition.Routing != "":
+		return definition.Routing
+	case definition.Gateway != nil:
+		return routingGateway
+	}
+	return defaultRouting
+}
+
+// gatewayFor returns the gateway the participant's routes are attached to, the definition replaces the provisioner's
+// gateway settings as a whole.
+func gatewayFor(definition ParticipantDefinition) GatewayOptions {
+	if definition.Gateway != nil {
+		return *definition.Gateway
+	}
+	return defaultGateway
+}
+
+func validateRouting(routing string, gateway GatewayOptions) error {
+	switch routing {
+	case routingIngress:
+		return nil
+	case routingGateway:
+	default:
+		return fmt.Errorf("routing: unsupported routing %q, expected %s or %s", routing, routingIngress, routingGateway)
+	}
+	if gateway.Name == "" && gateway.ClassName == "" {
+		return errors.New("gateway: the name of a shared gateway or the className of the participant's gateway is required")
+	}
+	for field, value := range map[string]string{"name": gateway.Name, "namespace": gateway.Namespace, "className": gateway.ClassName} {
+		if value == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(value); len(errs) > 0 {
+			return fmt.Errorf("gateway: invalid %s %q: %s", field, value, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// parentRef references the Gateway the routes are attached to.
+func (g GatewayOptions) parentRef() map[string]any {
+	if g.Name == "" {
+		return map[string]any{"name": participantGatewayName}
+	}
+	ref := map[string]any{"name": g.Name}
+	if g.Namespace != "" {
+		ref["namespace"] = g.Namespace
+	}
+	if g.SectionName != "" {
+		ref["sectionName"] = g.SectionName
+	}
+	return ref
+}
+
+// mutator replaces the participant's ingresses by HTTPRoutes of the same name, see httpRoute.
+func (g GatewayOptions) mutator() objectMutator {
+	parentRef := g.parentRef()
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Ingress" {
+			return nil
+		}
+		route, err := httpRoute(obj, parentRef)
+		if err != nil {
+			return fmt.Errorf("gateway: ingress %s: %w", obj.GetName(), err)
+		}
+		obj.Object = route
+		return nil
+	}
+}
+
+// httpRoute converts the ingress into an HTTPRoute. The regular expressions of the ingress paths become prefix
+// matches of their literal start, e.g. /alice/cp(/|$)(.*) matches /alice/cp, and the nginx rewrite target replaces
+// the matched prefix. Annotations of ingress-nginx are dropped, TLS is terminated by the Gateway.
+func httpRoute(ingress *unstructured.Unstructured, parentRef map[string]any) (map[string]any, error) {
+	annotations := make(map[string]any)
+	for key, value := range ingress.GetAnnotations() {
+		if !strings.HasPrefix(key, nginxAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+	rewrite := ingress.GetAnnotations()[nginxAnnotationPrefix+"rewrite-target"]
+
+	ingressRules, _, err := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if err != nil {
+		return nil, err
+	}
+	var hostnames, rules []any
+	for _, ingressRule := range ingressRules {
+		ingressRule, ok := ingressRule.(map[string]any)
+		if !ok {
+			continue
+		}
+		if host, _ := ingressRule["host"].(string); host != "" && !slices.Contains(hostnames, any(host)) {
+			hostnames = append(hostnames, host)
+		}
+		paths, _, _ := unstructured.NestedSlice(ingressRule, "http", "paths")
+		for _, p := range paths {
+			p, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			rule, err := httpRouteRule(p, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	metadata := map[string]any{"name": ingress.GetName(), "namespace": ingress.GetNamespace()}
+	if labels := ingress.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = toAnyMap(labels)
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	spec := map[string]any{"parentRefs": []any{parentRef}, "rules": rules}
+	if len(hostnames) > 0 {
+		spec["hostnames"] = hostnames
+	}
+	return map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   metadata,
+		"spec":       spec,
+	}, nil
+}
+
+// httpRouteRule converts a path of an ingress into a rule of an HTTPRoute.
+func httpRouteRule(path map[string]any, rewrite string) (map[string]any, error) {
+	value, _ := path["path"].(string)
+	matchType := "PathPrefix"
+	if pathType, _ := path["pathType"].(string); pathType == "Exact" {
+		matchType = "Exact"
+	}
+	if i := strings.IndexAny(value, "(["); i >= 0 {
+		value, matchType = value[:i], "PathPrefix"
+	}
+	if value != "/" {
+		value = strings.TrimSuffix(value, "/")
+	}
+	if value == "" {
+		value = "/"
+	}
+
+	service, _, _ := unstructured.NestedString(path, "backend", "service", "name")
+	// manifests decoded into plain maps hold numbers as float64
+	var port int64
+	switch number, _, _ := unstructured.NestedFieldNoCopy(path, "backend", "service", "port", "number"); number := number.(type) {
+	case int64:
+		port = number
+	case float64:
+		port = int64(number)
+	}
+	if service == "" || port == 0 {
+		return nil, fmt.Errorf("path %s needs a backend service with a port number", value)
+	}
+	rule := map[string]any{
+		"matches":     []any{map[string]any{"path": map[string]any{"type": matchType, "value": value}}},
+		"backendRefs": []any{map[string]any{"name": service, "port": port}},
+	}
+	if filter := rewriteFilter(value, rewrite); filter != nil {
+		rule["filters"] = []any{filter}
+	}
+	return rule, nil
+}
+
+// rewriteFilter translates the nginx rewrite target of a prefix, nil if it keeps the path as it is. Targets ending in
+// a capture group, like /$2, replace the prefix, fixed targets the full path.
+func rewriteFilter(prefix string, rewrite string) map[string]any {
+	if rewrite == "" {
+		return nil
+	}
+	replacement := map[string]any{"type": "ReplaceFullPath", "replaceFullPath": rewrite}
+	if i := strings.Index(rewrite, "$"); i >= 0 {
+		replaced := strings.TrimSuffix(rewrite[:i], "/")
+		if replaced == strings.TrimSuffix(prefix, "/") {
+			return nil
+		}
+		if replaced == "" {
+			replaced = "/"
+		}
+		replacement = map[string]any{"type": "ReplacePrefixMatch", "replacePrefixMatch": replaced}
+	}
+	return map[string]any{"type": "URLRewrite", "urlRewrite": map[string]any{"path": replacement}}
+}
+
+func toAnyMap(m map[string]string) map[string]any {
+	converted := make(map[string]any, len(m))
+	for key, value := range m {
+		converted[key] = value
+	}
+	return converted
+}
+
+// manifests renders the Gateway of the participant, which serves the host of its ingress options or certificate, over
+// HTTPS with the certificate if the participant has one. Routes attached to a shared Gateway need none.
+func (g GatewayOptions) manifests(namespace string, host string, tls *TlsOptions) (string, error) {
+	if g.Name != "" {
+		return "", nil
+	}
+	http := map[string]any{"name": "http", "port": 80, "protocol": "HTTP"}
+	if host != "" {
+		http["hostname"] = host
+	}
+	listeners := []any{http}
+	if tls != nil {
+		listeners = append(listeners, map[string]any{
+			"name":     "https",
+			"port":     443,
+			"protocol": "HTTPS",
+			"hostname": tls.Host,
+			"tls": map[string]any{
+				"mode":            "Terminate",
+				"certificateRefs": []any{map[string]any{"name": ingressTlsName}},
+			},
+		})
+	}
+	doc, err := yaml.Marshal(map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]any{"name": participantGatewayName, "namespace": namespace},
+		"spec":       map[string]any{"gatewayClassName": g.ClassName, "listeners": listeners},
+	})
+	return string(doc), err
+}
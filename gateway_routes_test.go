@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGatewayMutator(t *testing.T) {
+	mutator := GatewayOptions{Name: "public", Namespace: "gateways"}.mutator()
+	ingress := parseObject(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress-controlplane
+  namespace: alice
+  annotations:
+    nginx.ingress.kubernetes.io/rewrite-target: "/$2"
+    aruba-provisioner/path-prefix: /alice
+spec:
+  ingressClassName: nginx
+  rules:
+    - host: alice.example.com
+      http:
+        paths:
+          - path: /alice/cp(/|$)(.*)
+            pathType: ImplementationSpecific
+            backend:
+              service:
+                name: controlplane
+                port:
+                  number: 8081
+`)
+	if err := mutator(ingress); err != nil {
+		t.Fatal(err)
+	}
+	if ingress.GetKind() != "HTTPRoute" || ingress.GetName() != "ingress-controlplane" || ingress.GetNamespace() != "alice" {
+		t.Fatalf("expected an HTTPRoute replacing the ingress, got %v", ingress.Object)
+	}
+	if annotations := ingress.GetAnnotations(); len(annotations) != 1 || annotations["aruba-provisioner/path-prefix"] != "/alice" {
+		t.Errorf("expected only the nginx annotations to be dropped, got %v", annotations)
+	}
+	parents, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "parentRefs")
+	hostnames, _, _ := unstructured.NestedStringSlice(ingress.Object, "spec", "hostnames")
+	if len(parents) != 1 || parents[0].(map[string]any)["namespace"] != "gateways" || len(hostnames) != 1 || hostnames[0] != "alice.example.com" {
+		t.Errorf("unexpected parents %v and hostnames %v", parents, hostnames)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	rule := rules[0].(map[string]any)
+	match, _, _ := unstructured.NestedString(rule["matches"].([]any)[0].(map[string]any), "path", "value")
+	replaced, _, _ := unstructured.NestedString(rule["filters"].([]any)[0].(map[string]any), "urlRewrite", "path", "replacePrefixMatch")
+	backend := rule["backendRefs"].([]any)[0].(map[string]any)
+	if match != "/alice/cp" || replaced != "/" || backend["name"] != "controlplane" || backend["port"] != int64(8081) {
+		t.Errorf("unexpected rule %v", rule)
+	}
+
+	did := parseObject(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: did
+  annotations:
+    nginx.ingress.kubernetes.io/rewrite-target: "/alice/$2"
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /alice(/|&)(.*)
+            backend: {service: {name: identityhub, port: {number: 7083}}}
+`)
+	if err := mutator(did); err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ = unstructured.NestedSlice(did.Object, "spec", "rules")
+	if _, ok := rules[0].(map[string]any)["filters"]; ok {
+		t.Errorf("expected no rewrite of a target keeping the prefix, got %v", rules[0])
+	}
+
+	named := parseObject(t, "apiVersion: networking.k8s.io/v1\nkind: Ingress\nmetadata:\n  name: named\nspec:\n  rules:\n    - http:\n        paths:\n          - path: /api\n            backend: {service: {name: api, port: {name: http}}}\n")
+	if err := mutator(named); err == nil {
+		t.Error("expected backends without port number to be rejected")
+	}
+}
+
+func TestRouting(t *testing.T) {
+	previous := defaultGateway
+	t.Cleanup(func() { defaultGateway = previous })
+	defaultGateway = GatewayOptions{Name: "public"}
+
+	if routing := routingOf(ParticipantDefinition{}); routing != routingIngress {
+		t.Errorf("expected ingresses by default, got %s", routing)
+	}
+	definition := ParticipantDefinition{ParticipantName: "alice", Gateway: &GatewayOptions{ClassName: "eg"}, Tls: &TlsOptions{Host: "alice.example.com"}}
+	if routing := routingOf(definition); routing != routingGateway || gatewayFor(definition).Name != "" {
+		t.Errorf("expected the definition's gateway to be used, got %s %v", routing, gatewayFor(definition))
+	}
+	manifests, err := definition.extraManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"kind: Gateway", "gatewayClassName: eg", "protocol: HTTPS", "hostname: alice.example.com", "name: " + ingressTlsName} {
+		if !strings.Contains(manifests, expected) {
+			t.Errorf("expected %q in %s", expected, manifests)
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		routing string
+		gateway GatewayOptions
+	}{
+		"unknown routing":      {"istio", GatewayOptions{}},
+		"no gateway":           {routingGateway, GatewayOptions{}},
+		"invalid gateway name": {routingGateway, GatewayOptions{Name: "Public_Gateway"}},
+	} {
+		if err := validateRouting(tt.routing, tt.gateway); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Leases are renewed at a third of their duration, a replica that stops renewing them loses them after the duration
+const defaultLeaseDuration = 15 * time.Second
+
+// Longest time a Lease or job request of the coordination takes before it is abandoned
+const coordinationRequestTimeout = 5 * time.Second
+
+// Lease held by the replica running the loops that must run once, like the status transition notifications
+const leaderLeaseName = "aruba-provisioner-leader"
+
+// Prefix of the Leases serialising the jobs of a participant across replicas
+const participantLeasePrefix = "participant-"
+
+// Annotation of a participant's Lease with the operation of the job holding it, PROVISIONING or DELETING
+const leaseOperationAnnotation = "aruba-provisioner/operation"
+
+// Interval the leader deletes the shared jobs that finished before the retention period at
+const jobCleanupInterval = time.Hour
+
+// Label marking the ConfigMaps sharing jobs between replicas
+const (
+	jobLabel = "aruba-provisioner/job"
+	jobKey   = "job.json"
+)
+
+// errLeaseLost is returned when renewing a Lease another replica took over.
+var errLeaseLost = errors.New("lease is held by another replica")
+
+// coordinator lets replicas of the provisioner share the work behind a load balancer. A job holds the Lease of its
+// participant, so no two replicas provision, seed or delete the same participant at the same time, and the Lease tells
+// the other replicas which operation runs for their status reports. Jobs are shared through ConfigMaps, so any
+// replica answers for them. A leader elected through a Lease runs the loops that must run once. All objects are kept
+// in the provisioner's namespace.
+type coordinator struct {
+	client    client.Client
+	ctx       context.Context
+	namespace string
+	identity  string
+	duration  time.Duration
+	leader    atomic.Bool
+
+	mu   sync.Mutex
+	held map[string]*heldLease
+}
+
+// heldLease counts the jobs of the replica holding a participant's Lease.
+type heldLease struct {
+	jobs  int
+	renew context.CancelFunc
+}
+
+// newCoordinator coordinates the replica through objects in the namespace. Leases are renewed until the context ends.
+func newCoordinator(ctx context.Context, c client.Client, namespace string, duration time.Duration) *coordinator {
+	return &coordinator{client: c, ctx: ctx, namespace: namespace, identity: replicaIdentity(), duration: duration, held: make(map[string]*heldLease)}
+}
+
+// replicaIdentity names the replica after its pod, or its host outside of Kubernetes.
+func replicaIdentity() string {
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	return identity
+}
+
+func holderOf(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func expired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return time.Since(lease.Spec.RenewTime.Time) > time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second
+}
+
+// acquire takes the Lease for the replica unless another replica holds it and renewed it within its duration, in which
+// case that replica is returned.
+func (c *coordinator) acquire(ctx context.Context, name string, annotations map[string]string) (string, error) {
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(c.duration / time.Second)
+	lease := &coordinationv1.Lease{}
+	err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.namespace, Annotations: annotations},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		err = c.client.Create(ctx, lease)
+		if apierrors.IsAlreadyExists(err) {
+			return c.holder(ctx, name), nil
+		}
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+	if holder := holderOf(lease); holder != "" && holder != c.identity && !expired(lease) {
+		return holder, nil
+	}
+	if holderOf(lease) != c.identity {
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &c.identity
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Annotations = annotations
+	// the resource version of the Lease read makes the update fail if another replica took it in the meantime
+	err = c.client.Update(ctx, lease)
+	if apierrors.IsConflict(err) {
+		return c.holder(ctx, name), nil
+	}
+	return "", err
+}
+
+// holder returns the replica holding the Lease, or "another replica" if it can't be read.
+func (c *coordinator) holder(ctx context.Context, name string) string {
+	lease := &coordinationv1.Lease{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, lease); err != nil || holderOf(lease) == "" {
+		return "another replica"
+	}
+	return holderOf(lease)
+}
+
+// renew extends the Lease held by the replica.
+func (c *coordinator) renew(ctx context.Context, name string) error {
+	lease := &coordinationv1.Lease{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, lease); err != nil {
+		return err
+	}
+	if holderOf(lease) != c.identity {
+		return errLeaseLost
+	}
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.RenewTime = &now
+	return c.client.Update(ctx, lease)
+}
+
+// release deletes the Lease if the replica still holds it, so other replicas don't wait for it to expire.
+func (c *coordinator) release(ctx context.Context, name string) error {
+	lease := &coordinationv1.Lease{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, lease); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if holderOf(lease) != c.identity {
+		return nil
+	}
+	return client.IgnoreNotFound(c.client.Delete(ctx, lease, client.Preconditions{ResourceVersion: &lease.ResourceVersion}))
+}
+
+// keep renews the Lease until the context ends.
+func (c *coordinator) keep(ctx context.Context, name string) {
+	ticker := time.NewTicker(c.duration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		renewCtx, cancel := context.WithTimeout(ctx, coordinationRequestTimeout)
+		if err := c.renew(renewCtx, name); err != nil && ctx.Err() == nil {
+			fmt.Printf("Renewing lease %s failed: %v\n", name, err)
+		}
+		cancel()
+	}
+}
+
+// lock takes the Lease of the participant for a job of the replica, recording the operation the job runs if not
+// empty. Jobs of the same replica share the Lease, jobs of other replicas are rejected with 409. The returned
+// function releases the job's hold of the Lease.
+func (c *coordinator) lock(ctx context.Context, participant string, operation status.ProvisioningStatus) (func(), error) {
+	name := participantLeasePrefix + participant
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if held, ok := c.held[name]; ok {
+		held.jobs++
+		return c.unlocker(name), nil
+	}
+	var annotations map[string]string
+	if operation != "" {
+		annotations = map[string]string{leaseOperationAnnotation: string(operation)}
+	}
+	ctx, cancel := context.WithTimeout(ctx, coordinationRequestTimeout)
+	defer cancel()
+	holder, err := c.acquire(ctx, name, annotations)
+	if err != nil {
+		return nil, fmt.Errorf("acquire lease of %s: %w", participant, err)
+	}
+	if holder != "" {
+		return nil, withCode(codeParticipantBusy, fiber.StatusConflict, fmt.Errorf("participant %s is being changed by replica %s, retry later", participant, holder))
+	}
+	renewCtx, renew := context.WithCancel(c.ctx)
+	c.held[name] = &heldLease{jobs: 1, renew: renew}
+	go c.keep(renewCtx, name)
+	return c.unlocker(name), nil
+}
+
+// unlocker returns the function releasing a job's hold of the Lease, the last job of the replica releases the Lease.
+func (c *coordinator) unlocker(name string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			held := c.held[name]
+			if held.jobs--; held.jobs > 0 {
+				return
+			}
+			delete(c.held, name)
+			held.renew()
+			ctx, cancel := context.WithTimeout(c.ctx, coordinationRequestTimeout)
+			defer cancel()
+			if err := c.release(ctx, name); err != nil {
+				fmt.Printf("Releasing lease %s failed: %v\n", name, err)
+			}
+		})
+	}
+}
+
+// operation returns the operation a job of another replica runs on the participant, see status.SharedOperations.
+func (c *coordinator) operation(ctx context.Context, participant string) (status.ProvisioningStatus, time.Time, bool) {
+	lease := &coordinationv1.Lease{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: participantLeasePrefix + participant}, lease); err != nil {
+		return "", time.Time{}, false
+	}
+	operation := lease.Annotations[leaseOperationAnnotation]
+	if operation == "" || holderOf(lease) == c.identity || expired(lease) || lease.Spec.AcquireTime == nil {
+		return "", time.Time{}, false
+	}
+	return status.ProvisioningStatus(operation), lease.Spec.AcquireTime.Time, true
+}
+
+// lead runs the function while the replica holds the leader Lease: from when it acquires the Lease until it fails to
+// renew it, when the function's context is cancelled. It blocks until the context ends and then hands the Lease over.
+func (c *coordinator) lead(ctx context.Context, run func(ctx context.Context)) {
+	ticker := time.NewTicker(c.duration / 3)
+	defer ticker.Stop()
+	var stop context.CancelFunc
+	var done chan struct{}
+	resign := func() {
+		stop()
+		<-done
+		stop = nil
+		c.leader.Store(false)
+	}
+	for {
+		requestCtx, cancel := context.WithTimeout(ctx, coordinationRequestTimeout)
+		if stop == nil {
+			holder, err := c.acquire(requestCtx, leaderLeaseName, nil)
+			if err != nil && ctx.Err() == nil {
+				fmt.Println("Acquiring the leader lease failed:", err)
+			}
+			if err == nil && holder == "" {
+				fmt.Println("Replica", c.identity, "became the leader")
+				c.leader.Store(true)
+				var leadCtx context.Context
+				leadCtx, stop = context.WithCancel(ctx)
+				done = make(chan struct{})
+				go func() {
+					defer close(done)
+					run(leadCtx)
+				}()
+			}
+		} else if err := c.renew(requestCtx, leaderLeaseName); err != nil && ctx.Err() == nil {
+			fmt.Println("Replica", c.identity, "lost the leadership:", err)
+			resign()
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			if stop != nil {
+				resign()
+				releaseCtx, cancel := context.WithTimeout(context.Background(), coordinationRequestTimeout)
+				_ = c.release(releaseCtx, leaderLeaseName)
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leading reports whether the replica is the leader.
+func (c *coordinator) leading() bool {
+	return c.leader.Load()
+}
+
+// saveJob shares the state of a job with the other replicas.
+func (c *coordinator) saveJob(id string, participant string, state []byte) {
+	ctx, cancel := context.WithTimeout(c.ctx, coordinationRequestTimeout)
+	defer cancel()
+	err := applyResource(c.client, ctx, &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "job-" + id,
+			Namespace: c.namespace,
+			Labels:    map[string]string{jobLabel: "true", status.ParticipantLabel: participant},
+		},
+		Data: map[string]string{jobKey: string(state)},
+	})
+	if err != nil {
+		fmt.Printf("Sharing job %s failed: %v\n", id, err)
+	}
+}
+
+// loadJob returns the state of a job shared by a replica, nil if there is none.
+func (c *coordinator) loadJob(ctx context.Context, id string) (*provisioningJob, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: "job-" + id}, configMap); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	job := &provisioningJob{}
+	if err := json.Unmarshal([]byte(configMap.Data[jobKey]), job); err != nil {
+		return nil, fmt.Errorf("job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// cleanupJobs deletes the shared jobs that finished before the retention period at the interval until the context
+// ends. The leader runs it.
+func (c *coordinator) cleanupJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		configMaps := &corev1.ConfigMapList{}
+		if err := c.client.List(ctx, configMaps, client.InNamespace(c.namespace), client.MatchingLabels{jobLabel: "true"}); err != nil && ctx.Err() == nil {
+			fmt.Println("Listing shared jobs failed:", err)
+		}
+		for i := range configMaps.Items {
+			job := &provisioningJob{}
+			if err := json.Unmarshal([]byte(configMaps.Items[i].Data[jobKey]), job); err != nil || job.FinishedAt == nil || time.Since(*job.FinishedAt) < jobRetention {
+				continue
+			}
+			if err := c.client.Delete(ctx, &configMaps.Items[i]); client.IgnoreNotFound(err) != nil {
+				fmt.Printf("Deleting shared job %s failed: %v\n", job.Id, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
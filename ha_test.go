@@ -0,0 +1,137 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// leaseClient is an in-memory client keeping Leases, shared by the coordinators of several replicas.
+type leaseClient struct {
+	client.Client
+	mu     sync.Mutex
+	leases map[client.ObjectKey]*coordinationv1.Lease
+}
+
+func (c *leaseClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lease, ok := c.leases[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, key.Name)
+	}
+	lease.DeepCopyInto(obj.(*coordinationv1.Lease))
+	return nil
+}
+
+func (c *leaseClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.leases[key]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "leases"}, key.Name)
+	}
+	c.leases[key] = obj.(*coordinationv1.Lease).DeepCopy()
+	return nil
+}
+
+func (c *leaseClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.leases[key]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, key.Name)
+	}
+	c.leases[key] = obj.(*coordinationv1.Lease).DeepCopy()
+	return nil
+}
+
+func (c *leaseClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.leases, client.ObjectKeyFromObject(obj))
+	return nil
+}
+
+func newReplica(t *testing.T, c client.Client, name string) *coordinator {
+	t.Setenv("POD_NAME", name)
+	return newCoordinator(t.Context(), c, "mvd-provisioner", defaultLeaseDuration)
+}
+
+func TestCoordinatorLock(t *testing.T) {
+	c := &leaseClient{leases: make(map[client.ObjectKey]*coordinationv1.Lease)}
+	first := newReplica(t, c, "provisioner-0")
+	second := newReplica(t, c, "provisioner-1")
+	ctx := context.Background()
+
+	unlock, err := first.lock(ctx, "alice", status.StatusProvisioning)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// jobs of the same replica share the Lease
+	unlockAgain, err := first.lock(ctx, "alice", "")
+	if err != nil {
+		t.Fatalf("re-entrant lock: %v", err)
+	}
+
+	_, err = second.lock(ctx, "alice", status.StatusDeleting)
+	var coded *codedError
+	if !errors.As(err, &coded) || coded.status != fiber.StatusConflict || coded.code != codeParticipantBusy {
+		t.Fatalf("expected a 409 %s, got %v", codeParticipantBusy, err)
+	}
+	if _, err := second.lock(ctx, "bob", ""); err != nil {
+		t.Fatalf("lock of another participant: %v", err)
+	}
+
+	operation, started, ok := second.operation(ctx, "alice")
+	if !ok || operation != status.StatusProvisioning || started.IsZero() {
+		t.Errorf("expected the other replica's PROVISIONING, got %q %v %v", operation, started, ok)
+	}
+	if _, _, ok := first.operation(ctx, "alice"); ok {
+		t.Error("expected the replica's own operation to be left to its status checker")
+	}
+
+	unlock()
+	if _, err := second.lock(ctx, "alice", ""); err == nil {
+		t.Fatal("expected the Lease to be held until the last job of the replica released it")
+	}
+	unlockAgain()
+	if _, _, ok := second.operation(ctx, "alice"); ok {
+		t.Error("expected no operation once the Lease was released")
+	}
+	if _, err := second.lock(ctx, "alice", ""); err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+}
+
+func TestCoordinatorTakesOverExpiredLease(t *testing.T) {
+	c := &leaseClient{leases: make(map[client.ObjectKey]*coordinationv1.Lease)}
+	first := newReplica(t, c, "provisioner-0")
+	second := newReplica(t, c, "provisioner-1")
+	ctx := context.Background()
+
+	if _, err := first.acquire(ctx, leaderLeaseName, nil); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if holder, _ := second.acquire(ctx, leaderLeaseName, nil); holder != "provisioner-0" {
+		t.Fatalf("expected the Lease to be held by provisioner-0, got %q", holder)
+	}
+
+	lease := c.leases[client.ObjectKey{Namespace: "mvd-provisioner", Name: leaderLeaseName}]
+	lease.Spec.RenewTime.Time = time.Now().Add(-time.Minute)
+	if holder, err := second.acquire(ctx, leaderLeaseName, nil); err != nil || holder != "" {
+		t.Fatalf("expected the expired Lease to be taken over, got %q %v", holder, err)
+	}
+	if err := first.renew(ctx, leaderLeaseName); !errors.Is(err, errLeaseLost) {
+		t.Errorf("expected the previous holder to lose the Lease, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest sources starting with this prefix name a Helm chart participants are rendered from
+const helmSourcePrefix = "helm:"
+
+const helmRenderTimeout = time.Minute
+
+// Kinds the rendered objects are left without namespace for
+var clusterScopedKinds = map[string]bool{
+	"Namespace": true, "ClusterRole": true, "ClusterRoleBinding": true, "PriorityClass": true, "StorageClass": true,
+	"CustomResourceDefinition": true, "IngressClass": true, "PersistentVolume": true,
+	"MutatingWebhookConfiguration": true, "ValidatingWebhookConfiguration": true,
+}
+
+// helmChart renders the participant stack from a Helm chart, e.g. the upstream MVD or EDC charts, with the helm binary.
+// The release is named after the participant and installed into its namespace; the chart must name the deployments
+// controlplane, dataplane and identityhub, e.g. through fullnameOverride in the values, for the readiness checks.
+type helmChart struct {
+	// binary is the helm executable, looked up in the PATH unless it is a path
+	binary string
+	// chart is a chart directory, archive, OCI reference or repo/chart of a repository added to helm
+	chart   string
+	version string
+	// valuesFile holds the values shared by all participants
+	valuesFile string
+}
+
+// render renders the chart for the participant. The values of the values file are overridden by the participant's
+// name and DID, passed as participant.name and participant.did, and those by the definition's helmValues.
+func (h helmChart) render(ctx context.Context, definition ParticipantDefinition) (string, error) {
+	dir, err := os.MkdirTemp("", "helm-values-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	name := definition.ParticipantName
+	args := []string{"template", name, h.chart, "--namespace", name}
+	if h.version != "" {
+		args = append(args, "--version", h.version)
+	}
+	if h.valuesFile != "" {
+		args = append(args, "--values", h.valuesFile)
+	}
+	layers := []map[string]any{
+		{"participant": map[string]any{"name": name, "did": definition.Did}},
+		definition.HelmValues,
+	}
+	for i, values := range layers {
+		if len(values) == 0 {
+			continue
+		}
+		data, err := yaml.Marshal(values)
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("values-%d.yaml", i))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return "", err
+		}
+		args = append(args, "--values", path)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, helmRenderTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.binary, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("helm template %s: %w: %s", h.chart, err, strings.TrimSpace(stderr.String()))
+	}
+	return inNamespace(stdout.String(), name)
+}
+
+// inNamespace moves the rendered objects without namespace into the participant's namespace and prepends the
+// namespace itself, which Helm charts don't create.
+func inNamespace(manifest string, namespace string) (string, error) {
+	docs := []string{fmt.Sprintf("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", namespace)}
+	for _, doc := range strings.Split(manifest, "\n---") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			return "", err
+		}
+		if len(obj.Object) == 0 {
+			// only comments, e.g. of templates rendering nothing
+			continue
+		}
+		if obj.GetKind() == "Namespace" && obj.GetName() == namespace {
+			continue
+		}
+		if obj.GetNamespace() == "" && !clusterScopedKinds[obj.GetKind()] {
+			obj.SetNamespace(namespace)
+		}
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(data))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHelm writes a helm executable rendering a ConfigMap with its arguments and a ClusterRole, failing if any values
+// file contains "fail".
+func fakeHelm(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "helm")
+	script := `#!/bin/sh
+previous=""
+for arg in "$@"; do
+  if [ "$previous" = "--values" ] && grep -q fail "$arg"; then
+    echo "Error: values rejected" >&2
+    exit 1
+  fi
+  previous="$arg"
+done
+cat <<EOF
+---
+# Source: connector/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: rendered
+data:
+  args: "$*"
+---
+# Source: connector/templates/role.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: connector-reader
+EOF
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHelmChartRender(t *testing.T) {
+	chart := helmChart{binary: fakeHelm(t), chart: "oci://registry.example.com/charts/connector", version: "1.2.0"}
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", HelmValues: map[string]any{"replicas": 2}}
+
+	manifest, err := chart.render(context.Background(), definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(manifest, "---\n")
+	if len(docs) != 3 || !strings.Contains(docs[0], "kind: Namespace") || !strings.Contains(docs[0], "name: alice") {
+		t.Fatalf("expected the namespace to be prepended, got\n%s", manifest)
+	}
+	if !strings.Contains(docs[1], "namespace: alice") {
+		t.Errorf("expected the ConfigMap to be moved into the participant namespace:\n%s", docs[1])
+	}
+	if strings.Contains(docs[2], "namespace:") {
+		t.Errorf("expected the ClusterRole to stay cluster scoped:\n%s", docs[2])
+	}
+	for _, expected := range []string{"template alice oci://registry.example.com/charts/connector", "--namespace alice", "--version 1.2.0"} {
+		if !strings.Contains(docs[1], expected) {
+			t.Errorf("expected %q in the helm arguments:\n%s", expected, docs[1])
+		}
+	}
+	if strings.Count(docs[1], "--values") != 2 {
+		t.Errorf("expected the participant and the definition values to be passed:\n%s", docs[1])
+	}
+
+	definition.HelmValues = map[string]any{"mode": "fail"}
+	if _, err := chart.render(context.Background(), definition); err == nil || !strings.Contains(err.Error(), "values rejected") {
+		t.Errorf("expected the helm error output, got %v", err)
+	}
+}
+
+func TestReloadFromHelmChart(t *testing.T) {
+	store := newManifestStore("helm:./charts/connector", nil)
+	store.helm = helmChart{binary: fakeHelm(t)}
+	set, err := store.reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Chart == nil || set.Chart.chart != "./charts/connector" || store.get().Chart == nil {
+		t.Errorf("expected participants to be rendered from the chart, got %+v", set)
+	}
+
+	store.helm.binary = filepath.Join(t.TempDir(), "missing")
+	if _, err := store.reload(context.Background()); err == nil {
+		t.Error("expected a chart that cannot be rendered to be rejected")
+	}
+	if store.get().Chart == nil {
+		t.Error("failed reload replaced the current chart")
+	}
+}
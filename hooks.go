@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultHookTimeout  = 10 * time.Minute
+	hookPollInterval    = 2 * time.Second
+	hookLogTailLines    = 200
+	hookJobTtlAfterDone = int64(24 * 60 * 60)
+	hookStatusRunning   = "Running"
+	hookStatusSucceeded = "Succeeded"
+	hookStatusFailed    = "Failed"
+)
+
+// HookConfig declares a Job that runs in the participant namespace after seeding, e.g. for schema migrations or
+// custom data loads. The template supports the same placeholders as the participant manifests.
+type HookConfig struct {
+	Name string `json:"name"`
+	// Template is the Job manifest, TemplateFile a path it is read from
+	Template     string `json:"template,omitempty"`
+	TemplateFile string `json:"templateFile,omitempty"`
+	// Timeout is a duration like "5m", defaults to 10 minutes
+	Timeout string `json:"timeout,omitempty"`
+}
+
+type hookResult struct {
+	Name       string     `json:"name"`
+	Job        string     `json:"job"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Logs holds the tail of the logs of the hook's pods
+	Logs map[string]string `json:"logs,omitempty"`
+}
+
+// Results of the latest hook runs per participant
+var hookResults = struct {
+	sync.RWMutex
+	results map[string][]hookResult
+}{results: make(map[string][]hookResult)}
+
+func (h *HookConfig) load() error {
+	if h.Name == "" {
+		return fmt.Errorf("hook without name")
+	}
+	if h.TemplateFile != "" {
+		content, err := os.ReadFile(h.TemplateFile)
+		if err != nil {
+			return fmt.Errorf("hook %s: %w", h.Name, err)
+		}
+		h.Template = string(content)
+	}
+	if h.Template == "" {
+		return fmt.Errorf("hook %s: template or templateFile required", h.Name)
+	}
+	if h.Timeout != "" {
+		if _, err := time.ParseDuration(h.Timeout); err != nil {
+			return fmt.Errorf("hook %s: invalid timeout: %w", h.Name, err)
+		}
+	}
+	return nil
+}
+
+func (h HookConfig) timeout() time.Duration {
+	if timeout, err := time.ParseDuration(h.Timeout); err == nil && h.Timeout != "" {
+		return timeout
+	}
+	return defaultHookTimeout
+}
+
+// runHooks runs the hooks one after the other and stops at the first failure.
+func runHooks(ctx context.Context, c client.Client, logs *podLogReader, definition ParticipantDefinition, hooks []HookConfig) error {
+	namespace := definition.ParticipantName
+	results := make([]hookResult, len(hooks))
+	for i, hook := range hooks {
+		results[i] = hookResult{Name: hook.Name, Status: hookStatusRunning, StartedAt: time.Now()}
+	}
+	setHookResults(namespace, results)
+
+	for i, hook := range hooks {
+		result := &results[i]
+		err := runHook(ctx, c, definition, hook, result)
+		finished := time.Now()
+		result.FinishedAt = &finished
+		result.Logs = hookLogs(ctx, c, logs, namespace, result.Job)
+		if err != nil {
+			result.Status = hookStatusFailed
+			result.Message = err.Error()
+			setHookResults(namespace, results)
+			return fmt.Errorf("post-provision hook %s: %w", hook.Name, err)
+		}
+		result.Status = hookStatusSucceeded
+		setHookResults(namespace, results)
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, c client.Client, definition ParticipantDefinition, hook HookConfig, result *hookResult) error {
+	jobName := fmt.Sprintf("hook-%s-%d", hook.Name, time.Now().Unix())
+	result.Job = jobName
+	nameJob := func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Job" {
+			return fmt.Errorf("hook template must be a Job, got %s", obj.GetKind())
+		}
+		obj.SetName(jobName)
+		obj.SetNamespace(definition.ParticipantName)
+		return unstructured.SetNestedField(obj.Object, hookJobTtlAfterDone, "spec", "ttlSecondsAfterFinished")
+	}
+	if _, err := applyYaml(&definition.ParticipantName, &definition.Did, c, ctx, hook.Template, applyResource, nameJob); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hook.timeout())
+	defer cancel()
+	job := &batchv1.Job{}
+	for {
+		if err := c.Get(ctx, client.ObjectKey{Namespace: definition.ParticipantName, Name: jobName}, job); err != nil {
+			return err
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return fmt.Errorf("job %s failed: %s", jobName, condition.Message)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s did not finish: %w", jobName, ctx.Err())
+		case <-time.After(hookPollInterval):
+		}
+	}
+}
+
+// hookLogs returns the log tails of the job's pods, keyed by pod name.
+func hookLogs(ctx context.Context, c client.Client, logs *podLogReader, namespace string, jobName string) map[string]string {
+	if logs == nil || jobName == "" {
+		return nil
+	}
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{"job-name": jobName}); err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	result := make(map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		tail, err := logs.tail(ctx, namespace, pod.Name, "", hookLogTailLines)
+		if err != nil {
+			result[pod.Name] = "logs unavailable: " + err.Error()
+			continue
+		}
+		result[pod.Name] = strings.TrimSpace(tail)
+	}
+	return result
+}
+
+func setHookResults(namespace string, results []hookResult) {
+	snapshot := make([]hookResult, len(results))
+	copy(snapshot, results)
+	hookResults.Lock()
+	hookResults.results[namespace] = snapshot
+	hookResults.Unlock()
+}
+
+// getHookResults serves the results and logs of a participant's latest post-provision hooks.
+func getHookResults(c *fiber.Ctx) error {
+	hookResults.RLock()
+	results, ok := hookResults.results[c.Params("participantName")]
+	hookResults.RUnlock()
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "no hooks ran for participant "+c.Params("participantName"))
+	}
+	return c.JSON(results)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const hookTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: placeholder
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: migrate
+          image: migrations:latest
+          args: ["--participant", "${PARTICIPANT_NAME}"]
+`
+
+// hookCluster reports the Jobs of hooks as finished with the condition of their hook, those without one keep
+// running.
+type hookCluster struct {
+	applyingClient
+	outcomes map[string]batchv1.JobCondition
+}
+
+func (c hookCluster) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.applyingClient.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	if job, ok := obj.(*batchv1.Job); ok {
+		for hook, condition := range c.outcomes {
+			if strings.HasPrefix(job.Name, "hook-"+hook+"-") {
+				job.Status.Conditions = []batchv1.JobCondition{condition}
+			}
+		}
+	}
+	return nil
+}
+
+func newHookCluster() hookCluster {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	return hookCluster{
+		applyingClient: applyingClient{fake.NewClientBuilder().WithScheme(scheme).Build()},
+		outcomes: map[string]batchv1.JobCondition{
+			"migrate": {Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			"load":    {Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit"},
+		},
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	c := newHookCluster()
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme"}
+
+	err := runHooks(context.Background(), c, nil, definition, []HookConfig{{Name: "migrate", Template: hookTemplate}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(context.Background(), jobs, client.InNamespace("acme")); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 1 || !strings.HasPrefix(jobs.Items[0].Name, "hook-migrate-") {
+		t.Fatalf("expected the Job of the hook to be created, got %+v", jobs.Items)
+	}
+	job := jobs.Items[0]
+	if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != int32(hookJobTtlAfterDone) {
+		t.Errorf("expected finished Jobs to be cleaned up, got %v", job.Spec.TTLSecondsAfterFinished)
+	}
+	if args := job.Spec.Template.Spec.Containers[0].Args; args[1] != "acme" {
+		t.Errorf("expected the placeholders to be replaced, got %v", args)
+	}
+
+	results := hookResults.results["acme"]
+	if len(results) != 1 || results[0].Status != hookStatusSucceeded || results[0].Job != job.Name || results[0].FinishedAt == nil {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestRunHooksReportsFailures(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "globex", Did: "did:web:globex"}
+	hooks := []HookConfig{{Name: "migrate", Template: hookTemplate}, {Name: "load", Template: hookTemplate}, {Name: "notify", Template: hookTemplate}}
+
+	job, err := jobs.create(context.Background(), "globex", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = job.execute(context.Background(), []jobStep{{phaseHooks, func(ctx context.Context) error {
+		return runHooks(ctx, newHookCluster(), nil, definition, hooks)
+	}}})
+	if err == nil || job.Status != jobFailed || job.Phase != phaseHooks {
+		t.Fatalf("expected the job to fail in the hooks phase, got %s/%s: %v", job.Status, job.Phase, err)
+	}
+	if !strings.Contains(job.Error, "post-provision hook load") || !strings.Contains(job.Error, "backoff limit") {
+		t.Errorf("expected the failed hook and its cause to be reported, got %q", job.Error)
+	}
+
+	results := hookResults.results["globex"]
+	if len(results) != 3 || results[0].Status != hookStatusSucceeded || results[1].Status != hookStatusFailed ||
+		!strings.Contains(results[1].Message, "backoff limit") {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[2].Job != "" {
+		t.Errorf("expected the hooks after the failed one not to run, got %+v", results[2])
+	}
+}
+
+func TestRunHooksTimesOut(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "initech", Did: "did:web:initech"}
+
+	err := runHooks(context.Background(), newHookCluster(), nil, definition, []HookConfig{{Name: "hang", Template: hookTemplate, Timeout: "50ms"}})
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Fatalf("expected the hook to time out, got %v", err)
+	}
+	results := hookResults.results["initech"]
+	if len(results) != 1 || results[0].Status != hookStatusFailed || results[0].FinishedAt == nil {
+		t.Errorf("expected the timed out hook to be reported as failed, got %+v", results)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"sigs.k8s.io/yaml"
+)
+
+// Targets the provisioner talks to over HTTP while provisioning participants. The did target are the hosts of the
+// DID documents resolved before provisioning, the registration target the registration services of dataspaces, the
+// bundle target the hosts the seed bundles of catalogs are fetched from.
+const (
+	targetManagement   = "management"
+	targetIdentity     = "identity"
+	targetIssuer       = "issuer"
+	targetVault        = "vault"
+	targetDid          = "did"
+	targetRegistration = "registration"
+	targetBundle       = "bundle"
+)
+
+var httpTargets = []string{targetManagement, targetIdentity, targetIssuer, targetVault, targetDid, targetRegistration, targetBundle}
+
+// Requests to the seeding targets are abandoned after this period unless a timeout is configured
+const defaultHttpTimeout = 30 * time.Second
+
+// HttpTargetConfig configures the egress of the HTTP clients for one target, or of all targets.
+type HttpTargetConfig struct {
+	// Proxy is the URL of the HTTP(S) proxy requests are sent through
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy lists hosts, domains and CIDRs reached without the proxy, comma separated
+	NoProxy string `json:"noProxy,omitempty"`
+	// CaFile is a PEM bundle trusted in addition to the system roots, e.g. of a TLS-intercepting proxy
+	CaFile string `json:"caFile,omitempty"`
+	// Timeout limits each request including reading the response, e.g. 30s
+	Timeout string `json:"timeout,omitempty"`
+	// InsecureSkipVerify accepts any server certificate, only meant for development clusters with self-signed ones
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// HttpConfig holds the global egress settings and per-target overrides.
+type HttpConfig struct {
+	HttpTargetConfig
+	Targets map[string]HttpTargetConfig `json:"targets,omitempty"`
+	// Participants overrides the settings for the APIs of individual participants, by participant name. They take
+	// precedence over the global and per-target settings.
+	Participants map[string]HttpConfig `json:"participants,omitempty"`
+}
+
+// loadHttpConfig reads the config file, if any, and applies the environment on top of it. Global settings come from
+// PROVISIONER_HTTP_PROXY, PROVISIONER_HTTP_NO_PROXY, PROVISIONER_HTTP_CA_FILE, PROVISIONER_HTTP_TIMEOUT and
+// PROVISIONER_HTTP_INSECURE_SKIP_VERIFY, per-target ones from the same variables with the upper-cased target inserted,
+// e.g. PROVISIONER_ISSUER_HTTP_PROXY. Participant overrides can only be set in the file.
+func loadHttpConfig(path string) (HttpConfig, error) {
+	config := HttpConfig{}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("read http config: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return config, fmt.Errorf("parse http config: %w", err)
+		}
+	}
+	if err := config.validateTargets(); err != nil {
+		return config, err
+	}
+	for name, participant := range config.Participants {
+		if len(participant.Participants) > 0 {
+			return config, fmt.Errorf("http settings of participant %s can't contain participants", name)
+		}
+		if err := participant.validateTargets(); err != nil {
+			return config, fmt.Errorf("http settings of participant %s: %w", name, err)
+		}
+	}
+
+	config.HttpTargetConfig = config.HttpTargetConfig.withEnv("PROVISIONER_HTTP_")
+	for _, target := range httpTargets {
+		override := config.Targets[target].withEnv("PROVISIONER_" + strings.ToUpper(target) + "_HTTP_")
+		if override != (HttpTargetConfig{}) {
+			if config.Targets == nil {
+				config.Targets = make(map[string]HttpTargetConfig)
+			}
+			config.Targets[target] = override
+		}
+	}
+	return config, nil
+}
+
+func (c HttpConfig) validateTargets() error {
+	for target := range c.Targets {
+		if !isHttpTarget(target) {
+			return fmt.Errorf("unknown http target %q, expected one of %s", target, strings.Join(httpTargets, ", "))
+		}
+	}
+	return nil
+}
+
+func isHttpTarget(target string) bool {
+	for _, known := range httpTargets {
+		if target == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (t HttpTargetConfig) withEnv(prefix string) HttpTargetConfig {
+	if value := os.Getenv(prefix + "PROXY"); value != "" {
+		t.Proxy = value
+	}
+	if value := os.Getenv(prefix + "NO_PROXY"); value != "" {
+		t.NoProxy = value
+	}
+	if value := os.Getenv(prefix + "CA_FILE"); value != "" {
+		t.CaFile = value
+	}
+	if value := os.Getenv(prefix + "TIMEOUT"); value != "" {
+		t.Timeout = value
+	}
+	if os.Getenv(prefix+"INSECURE_SKIP_VERIFY") == "true" {
+		t.InsecureSkipVerify = true
+	}
+	return t
+}
+
+// merge returns the settings with the ones set in the override replacing them.
+func (t HttpTargetConfig) merge(override HttpTargetConfig) HttpTargetConfig {
+	if override.Proxy != "" {
+		t.Proxy = override.Proxy
+	}
+	if override.NoProxy != "" {
+		t.NoProxy = override.NoProxy
+	}
+	if override.CaFile != "" {
+		t.CaFile = override.CaFile
+	}
+	if override.Timeout != "" {
+		t.Timeout = override.Timeout
+	}
+	if override.InsecureSkipVerify {
+		t.InsecureSkipVerify = true
+	}
+	return t
+}
+
+// forTarget merges the target's overrides into the global settings.
+func (c HttpConfig) forTarget(target string) HttpTargetConfig {
+	return c.HttpTargetConfig.merge(c.Targets[target])
+}
+
+// forParticipant returns the settings for the APIs of the participant: the global and per-target settings with the
+// participant's global and per-target overrides applied in turn.
+func (c HttpConfig) forParticipant(name string) HttpConfig {
+	override := c.Participants[name]
+	merged := HttpConfig{HttpTargetConfig: c.HttpTargetConfig, Targets: make(map[string]HttpTargetConfig, len(httpTargets))}
+	for _, target := range httpTargets {
+		merged.Targets[target] = c.Targets[target].merge(override.HttpTargetConfig).merge(override.Targets[target])
+	}
+	return merged
+}
+
+// timeout parses the request timeout, defaultHttpTimeout when none is set.
+func (t HttpTargetConfig) timeout() (time.Duration, error) {
+	if t.Timeout == "" {
+		return defaultHttpTimeout, nil
+	}
+	timeout, err := time.ParseDuration(t.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", t.Timeout)
+	}
+	return timeout, nil
+}
+
+// transport builds the transport for the settings. Without a proxy configured, the standard proxy environment
+// variables apply.
+func (t HttpTargetConfig) transport() (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var proxyAddress string
+	if t.Proxy != "" {
+		proxyConfig := &httpproxy.Config{HTTPProxy: t.Proxy, HTTPSProxy: t.Proxy, NoProxy: t.NoProxy}
+		proxyFunc := proxyConfig.ProxyFunc()
+		transport.Proxy = func(request *http.Request) (*url.URL, error) {
+			return proxyFunc(request.URL)
+		}
+		proxyAddress = hostPort(t.Proxy)
+	}
+	// requests for seed bundles connect to public addresses only
+	transport.DialContext = dialPublicOnly(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}, proxyAddress)
+	if t.CaFile != "" || t.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	}
+	if t.CaFile != "" {
+		pem, err := os.ReadFile(t.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", t.CaFile)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return transport, nil
+}
+
+// hostPort returns the address the proxy is connected to, the port defaulting to that of its scheme.
+func hostPort(proxy string) string {
+	parsed, err := url.Parse(proxy)
+	if err != nil || parsed.Host == "" {
+		if parsed, err = url.Parse("http://" + proxy); err != nil {
+			return proxy
+		}
+	}
+	if port := parsed.Port(); port != "" {
+		return parsed.Host
+	}
+	port := "80"
+	if parsed.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(parsed.Hostname(), port)
+}
+
+// egress holds the transports and timeouts of the seeding targets for one set of settings.
+type egress struct {
+	transports map[string]http.RoundTripper
+	timeouts   map[string]time.Duration
+	// authenticated transports per dataspace and target
+	dataspaces map[string]map[string]http.RoundTripper
+}
+
+// seedingClients hands out the HTTP clients for the seeding targets.
+type seedingClients struct {
+	egress
+	// participants holds the egress of the participants with settings of their own
+	participants map[string]egress
+	participant  string
+	dataspace    string
+	recording    *recording
+	// trace continues the trace of the job the requests are made for
+	trace context.Context
+}
+
+func newSeedingClients(config HttpConfig, dataspaces map[string]DataspaceConfig) (seedingClients, error) {
+	defaults, err := newEgress(config, dataspaces)
+	if err != nil {
+		return seedingClients{}, err
+	}
+	clients := seedingClients{egress: defaults, participants: make(map[string]egress, len(config.Participants))}
+	for name := range config.Participants {
+		if clients.participants[name], err = newEgress(config.forParticipant(name), dataspaces); err != nil {
+			return seedingClients{}, fmt.Errorf("participant %s: %w", name, err)
+		}
+	}
+	return clients, nil
+}
+
+func newEgress(config HttpConfig, dataspaces map[string]DataspaceConfig) (egress, error) {
+	transports := make(map[string]http.RoundTripper, len(httpTargets))
+	timeouts := make(map[string]time.Duration, len(httpTargets))
+	for _, target := range httpTargets {
+		settings := config.forTarget(target)
+		if settings.InsecureSkipVerify {
+			fmt.Printf("WARNING: TLS certificates of the %s API aren't verified\n", target)
+		}
+		transport, err := settings.transport()
+		if err != nil {
+			return egress{}, fmt.Errorf("%s http client: %w", target, err)
+		}
+		transports[target] = transport
+		if timeouts[target], err = settings.timeout(); err != nil {
+			return egress{}, fmt.Errorf("%s http client: %w", target, err)
+		}
+	}
+
+	authenticated := make(map[string]map[string]http.RoundTripper, len(dataspaces))
+	for name, dataspace := range dataspaces {
+		authenticated[name] = make(map[string]http.RoundTripper)
+		for _, target := range httpTargets {
+			if auth, ok := dataspace.forTarget(target); ok {
+				authenticated[name][target] = auth.transport(transports[target])
+			}
+		}
+	}
+	return egress{transports: transports, timeouts: timeouts, dataspaces: authenticated}, nil
+}
+
+// forParticipant returns clients for the APIs of the participant, authenticating as configured for its dataspace and
+// using the participant's egress settings, if it has any.
+func (s seedingClients) forParticipant(definition ParticipantDefinition) seedingClients {
+	s.participant = definition.ParticipantName
+	s.dataspace = definition.Dataspace
+	return s
+}
+
+// withRecording returns clients whose exchanges are added to the recording, if one is given.
+func (s seedingClients) withRecording(rec *recording) seedingClients {
+	s.recording = rec
+	return s
+}
+
+// withTrace returns clients whose requests are traced as part of the trace in ctx.
+func (s seedingClients) withTrace(ctx context.Context) seedingClients {
+	s.trace = ctx
+	return s
+}
+
+func (s seedingClients) client(target string) http.Client {
+	settings := s.egress
+	if participant, ok := s.participants[s.participant]; ok {
+		settings = participant
+	}
+	transport := settings.transports[target]
+	dataspace := s.dataspace
+	if dataspace == "" {
+		dataspace = defaultDataspace
+	}
+	if authenticated, ok := settings.dataspaces[dataspace][target]; ok {
+		transport = authenticated
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if s.recording != nil {
+		transport = &recordingTransport{recording: s.recording, next: transport}
+	}
+	if traces != nil {
+		transport = &tracingTransport{parent: s.trace, next: transport}
+	}
+	timeout, ok := settings.timeouts[target]
+	if !ok {
+		timeout = defaultHttpTimeout
+	}
+	return http.Client{Transport: transport, Timeout: timeout}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParticipantHttpSettings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := HttpConfig{Participants: map[string]HttpConfig{
+		"alice": {HttpTargetConfig: HttpTargetConfig{CaFile: caFile}},
+		"bob":   {Targets: map[string]HttpTargetConfig{targetIdentity: {InsecureSkipVerify: true}}},
+	}}
+	clients, err := newSeedingClients(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		participant string
+		target      string
+		trusted     bool
+	}{
+		{"carol", targetManagement, false},
+		{"alice", targetManagement, true},
+		{"alice", targetIdentity, true},
+		{"bob", targetIdentity, true},
+		{"bob", targetManagement, false},
+	}
+	for _, tt := range tests {
+		httpClient := clients.forParticipant(ParticipantDefinition{ParticipantName: tt.participant}).client(tt.target)
+		response, err := httpClient.Get(server.URL)
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		if (err == nil) != tt.trusted {
+			t.Errorf("%s %s: trusted = %v, want %v (%v)", tt.participant, tt.target, err == nil, tt.trusted, err)
+		}
+	}
+}
+
+func TestHttpConfigForParticipant(t *testing.T) {
+	config := HttpConfig{
+		HttpTargetConfig: HttpTargetConfig{Proxy: "http://proxy:3128", Timeout: "10s"},
+		Targets:          map[string]HttpTargetConfig{targetIssuer: {Timeout: "1m"}},
+		Participants: map[string]HttpConfig{"alice": {
+			HttpTargetConfig: HttpTargetConfig{CaFile: "/etc/alice/ca.pem"},
+			Targets:          map[string]HttpTargetConfig{targetIdentity: {Proxy: "http://alice-proxy:3128"}},
+		}},
+	}
+	alice := config.forParticipant("alice")
+	want := map[string]HttpTargetConfig{
+		targetManagement: {Proxy: "http://proxy:3128", Timeout: "10s", CaFile: "/etc/alice/ca.pem"},
+		targetIdentity:   {Proxy: "http://alice-proxy:3128", Timeout: "10s", CaFile: "/etc/alice/ca.pem"},
+		targetIssuer:     {Proxy: "http://proxy:3128", Timeout: "1m", CaFile: "/etc/alice/ca.pem"},
+	}
+	for target, expected := range want {
+		if got := alice.forTarget(target); got != expected {
+			t.Errorf("%s: got %+v, want %+v", target, got, expected)
+		}
+	}
+}
+
+func TestLoadHttpConfigRejectsUnknownParticipantTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http.yaml")
+	if err := os.WriteFile(path, []byte("participants:\n  alice:\n    targets:\n      ledger:\n        insecureSkipVerify: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHttpConfig(path); err == nil {
+		t.Error("expected an unknown target of a participant to be rejected")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Header of POST and DELETE requests identifying them across retries
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Header marking responses replayed for a retried request
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// Default period the responses of requests with an Idempotency-Key are replayed for, set with --idempotency-window
+const defaultIdempotencyWindow = 24 * time.Hour
+
+// A request whose response isn't stored after this period is considered abandoned, e.g. by a replica that was
+// stopped, and its key can be used again
+const idempotencyPendingTimeout = 5 * time.Minute
+
+// Responses larger than this aren't stored, requests returning them are run again when retried
+const maxIdempotentResponseSize = 512 << 10
+
+// Interval expired idempotency keys are removed at
+const idempotencySweepInterval = 10 * time.Minute
+
+// Label marking the ConfigMaps storing the responses of requests with an Idempotency-Key
+const idempotencyLabel = "aruba-provisioner/idempotency-key"
+
+// States of a stored idempotency key
+const (
+	idempotencyPending   = "pending"
+	idempotencyCompleted = "completed"
+)
+
+// idempotencyStore replays the response of a POST or DELETE request sent again with the same Idempotency-Key by the
+// same caller within the window, so retries don't provision, seed or delete twice. Keys and responses are kept in
+// ConfigMaps in the provisioner's namespace, so a retry reaching another replica is replayed as well. Failed requests
+// aren't stored and can be retried with the same key.
+type idempotencyStore struct {
+	client    client.Client
+	namespace string
+	window    time.Duration
+}
+
+// newIdempotencyStore returns the store, nil if the window is 0, which disables idempotency keys.
+func newIdempotencyStore(c client.Client, namespace string, window time.Duration) *idempotencyStore {
+	if window <= 0 {
+		return nil
+	}
+	return &idempotencyStore{client: c, namespace: namespace, window: window}
+}
+
+// idempotencyEntryName derives the name of the ConfigMap of the key, scoped to the caller so callers can't replay
+// each other's responses.
+func idempotencyEntryName(caller string, key string) string {
+	sum := sha256.Sum256([]byte(caller + "\n" + key))
+	return "idempotency-" + hex.EncodeToString(sum[:16])
+}
+
+// requestFingerprint identifies the request a key was first sent with.
+func requestFingerprint(c *fiber.Ctx) string {
+	sum := sha256.Sum256(c.Body())
+	return c.Method() + " " + c.OriginalURL() + " " + hex.EncodeToString(sum[:])
+}
+
+// expired tells whether the key of the entry can be used again.
+func (s *idempotencyStore) expired(entry *corev1.ConfigMap, now time.Time) bool {
+	storedAt, err := time.Parse(time.RFC3339, entry.Data["storedAt"])
+	if err != nil {
+		return true
+	}
+	if entry.Data["state"] == idempotencyPending {
+		return now.Sub(storedAt) > idempotencyPendingTimeout
+	}
+	return now.Sub(storedAt) > s.window
+}
+
+// claim records that the request is being processed under the key, or returns the stored entry if the key was used
+// by the same request before. Keys used for a different request, or by a request still in progress, are rejected.
+func (s *idempotencyStore) claim(ctx context.Context, name string, fingerprint string, now time.Time) (*corev1.ConfigMap, error) {
+	entry := &corev1.ConfigMap{}
+	err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: name}, entry)
+	switch {
+	case client.IgnoreNotFound(err) != nil:
+		return nil, err
+	case err == nil && s.expired(entry, now):
+		if err := s.client.Delete(ctx, entry); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	case err == nil && entry.Data["request"] != fingerprint:
+		return nil, withCode(codeIdempotencyKeyReused, fiber.StatusUnprocessableEntity, errors.New("the Idempotency-Key was sent with a different request"))
+	case err == nil && entry.Data["state"] == idempotencyPending:
+		return nil, withCode(codeIdempotencyKeyInUse, fiber.StatusConflict, errors.New("a request with the Idempotency-Key is in progress"))
+	case err == nil:
+		return entry, nil
+	}
+	err = s.client.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace, Labels: map[string]string{idempotencyLabel: "true"}},
+		Data:       map[string]string{"request": fingerprint, "state": idempotencyPending, "storedAt": now.UTC().Format(time.RFC3339)},
+	})
+	if apierrors.IsAlreadyExists(err) {
+		return nil, withCode(codeIdempotencyKeyInUse, fiber.StatusConflict, errors.New("a request with the Idempotency-Key is in progress"))
+	}
+	return nil, err
+}
+
+// complete stores the response of the request for its retries.
+func (s *idempotencyStore) complete(ctx context.Context, name string, c *fiber.Ctx) error {
+	entry := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: name}, entry); err != nil {
+		return err
+	}
+	entry.Data["state"] = idempotencyCompleted
+	entry.Data["storedAt"] = time.Now().UTC().Format(time.RFC3339)
+	entry.Data["status"] = strconv.Itoa(c.Response().StatusCode())
+	entry.Data["contentType"] = string(c.Response().Header.ContentType())
+	entry.Data["location"] = string(c.Response().Header.Peek(fiber.HeaderLocation))
+	entry.Data["body"] = string(c.Response().Body())
+	return s.client.Update(ctx, entry)
+}
+
+// release frees the key of a failed request.
+func (s *idempotencyStore) release(ctx context.Context, name string) {
+	entry := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace}}
+	if err := s.client.Delete(ctx, entry); client.IgnoreNotFound(err) != nil {
+		fmt.Printf("Releasing idempotency key %s failed: %v\n", name, err)
+	}
+}
+
+// replay answers a retried request with the stored response.
+func replay(c *fiber.Ctx, entry *corev1.ConfigMap) error {
+	code, err := strconv.Atoi(entry.Data["status"])
+	if err != nil {
+		return fmt.Errorf("stored response of %s: %w", entry.Name, err)
+	}
+	if location := entry.Data["location"]; location != "" {
+		c.Set(fiber.HeaderLocation, location)
+	}
+	if contentType := entry.Data["contentType"]; contentType != "" {
+		c.Set(fiber.HeaderContentType, contentType)
+	}
+	c.Set(idempotentReplayedHeader, "true")
+	return c.Status(code).SendString(entry.Data["body"])
+}
+
+func (s *idempotencyStore) middleware(ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotencyKeyHeader)
+		if s == nil || key == "" || (c.Method() != fiber.MethodPost && c.Method() != fiber.MethodDelete) {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("the Idempotency-Key is longer than %d characters", maxIdempotencyKeyLength))
+		}
+		name := idempotencyEntryName(clientKey(c), key)
+		entry, err := s.claim(ctx, name, requestFingerprint(c), time.Now())
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			return replay(c, entry)
+		}
+		if err := c.Next(); err != nil {
+			s.release(ctx, name)
+			return err
+		}
+		if c.Response().StatusCode() >= fiber.StatusInternalServerError || len(c.Response().Body()) > maxIdempotentResponseSize {
+			s.release(ctx, name)
+			return nil
+		}
+		if err := s.complete(ctx, name, c); err != nil {
+			fmt.Printf("Storing the response of idempotency key %s failed: %v\n", name, err)
+			s.release(ctx, name)
+		}
+		return nil
+	}
+}
+
+// sweep removes the expired keys, checking at the interval until the context is cancelled. With several replicas
+// only the leader removes them.
+func (s *idempotencyStore) sweep(ctx context.Context, interval time.Duration, leading func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !leading() {
+			continue
+		}
+		s.removeExpired(ctx, time.Now())
+	}
+}
+
+func (s *idempotencyStore) removeExpired(ctx context.Context, now time.Time) {
+	entries := &corev1.ConfigMapList{}
+	if err := s.client.List(ctx, entries, client.InNamespace(s.namespace), client.HasLabels{idempotencyLabel}); err != nil {
+		fmt.Println("Listing idempotency keys failed:", err)
+		return
+	}
+	for i := range entries.Items {
+		if entry := &entries.Items[i]; s.expired(entry, now) {
+			if err := s.client.Delete(ctx, entry); client.IgnoreNotFound(err) != nil {
+				fmt.Printf("Removing idempotency key %s failed: %v\n", entry.Name, err)
+			}
+		}
+	}
+}
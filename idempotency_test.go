@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	kube := protectionClient()
+	store := newIdempotencyStore(kube, "mvd-provisioner", time.Hour)
+	calls := 0
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(store.middleware(context.Background()))
+	app.Post("/resources", func(c *fiber.Ctx) error {
+		calls++
+		if string(c.Body()) == "invalid" {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid definition")
+		}
+		c.Location("/jobs/" + string(c.Body()))
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": calls})
+	})
+	send := func(key string, body string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/resources", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(out), resp.Header.Get(idempotentReplayedHeader)
+	}
+
+	code, first, _ := send("k1", "alice")
+	if code != fiber.StatusAccepted || calls != 1 {
+		t.Fatalf("expected the request to run, got %d after %d calls", code, calls)
+	}
+	code, retried, replayed := send("k1", "alice")
+	if code != fiber.StatusAccepted || retried != first || replayed != "true" || calls != 1 {
+		t.Errorf("expected the retry to be answered with the first response, got %d %s after %d calls", code, retried, calls)
+	}
+	if code, _, _ := send("k1", "bob"); code != fiber.StatusUnprocessableEntity || calls != 1 {
+		t.Errorf("expected the key to be rejected for another request, got %d", code)
+	}
+	if send("", "alice"); calls != 2 {
+		t.Error("expected requests without key to run")
+	}
+
+	// failed requests are run again
+	send("k2", "invalid")
+	if code, _, _ := send("k2", "invalid"); code != fiber.StatusBadRequest || calls != 4 {
+		t.Errorf("expected the failed request to run again, got %d after %d calls", code, calls)
+	}
+
+	// a key in progress is rejected, an abandoned one taken over
+	name := idempotencyEntryName("ip:0.0.0.0", "k3")
+	now := time.Now()
+	if _, err := store.claim(context.Background(), name, "POST /resources x", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.claim(context.Background(), name, "POST /resources x", now); problemOf(err).Code != codeIdempotencyKeyInUse {
+		t.Errorf("expected the key to be in use, got %v", err)
+	}
+	if _, err := store.claim(context.Background(), name, "POST /resources x", now.Add(idempotencyPendingTimeout+time.Second)); err != nil {
+		t.Errorf("expected the abandoned key to be taken over, got %v", err)
+	}
+
+	store.removeExpired(context.Background(), now.Add(2*time.Hour))
+	entries := &corev1.ConfigMapList{}
+	if err := kube.List(context.Background(), entries, client.HasLabels{idempotencyLabel}); err != nil || len(entries.Items) != 0 {
+		t.Errorf("expected expired keys to be removed, got %d", len(entries.Items))
+	}
+	if newIdempotencyStore(kube, "mvd-provisioner", 0) != nil {
+		t.Error("expected a window of 0 to disable idempotency keys")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Subjects of Kubernetes ServiceAccount tokens, which are impersonated without the prefix when the cluster issued them
+const serviceAccountPrefix = "system:serviceaccount:"
+
+// Users and groups reserved for Kubernetes, e.g. system:masters, which callers are never impersonated as
+const reservedPrefix = "system:"
+
+// impersonation is the Kubernetes user, and its groups, the resources of participants are created and deleted as.
+type impersonation struct {
+	user   string
+	groups []string
+}
+
+type requesterKey struct{}
+type impersonatingKey struct{}
+
+// impersonator attributes the creation and deletion of participant resources to the authenticated callers in the
+// cluster's audit log, instead of to the provisioner's service account. The provisioner needs the impersonate verb
+// on users, groups and service accounts, and the callers the permissions to manage the participants' resources.
+type impersonator struct {
+	// prefix is prepended to user and group names, e.g. "oidc:" matching the API server's --oidc-username-prefix. It
+	// is required, so callers can't name users or groups of the cluster.
+	prefix string
+	// serviceAccountIssuer is the issuer of the cluster's ServiceAccount tokens. Tokens it issued are impersonated as
+	// their ServiceAccount, empty impersonates no ServiceAccounts.
+	serviceAccountIssuer string
+}
+
+// newImpersonator returns the impersonator of callers, which requires a prefix.
+func newImpersonator(prefix string, serviceAccountIssuer string) (*impersonator, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("impersonation requires a prefix, e.g. oidc:")
+	}
+	return &impersonator{prefix: prefix, serviceAccountIssuer: serviceAccountIssuer}, nil
+}
+
+// middleware records the user the request's caller is impersonated as in the request context. Callers with the admin
+// key or a static API key have no Kubernetes identity and keep the provisioner's. Callers whose names are reserved
+// for Kubernetes are rejected rather than served with the provisioner's identity.
+func (i *impersonator) middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		caller, ok := c.Locals(principalKey).(*principal)
+		if !ok {
+			return c.Next()
+		}
+		requester, ok, err := i.impersonationOf(caller)
+		if err != nil {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		if ok {
+			c.SetUserContext(context.WithValue(c.UserContext(), requesterKey{}, requester))
+		}
+		return c.Next()
+	}
+}
+
+func (i *impersonator) impersonationOf(caller *principal) (impersonation, bool, error) {
+	subject, certificate := strings.CutPrefix(caller.Subject, "cert:")
+	if subject == "" || (!certificate && caller.Claims == nil) {
+		return impersonation{}, false, nil
+	}
+	if issuer, _ := caller.Claims["iss"].(string); !certificate && i.serviceAccountIssuer != "" && issuer == i.serviceAccountIssuer &&
+		strings.HasPrefix(subject, serviceAccountPrefix) {
+		return impersonation{user: subject}, true, nil
+	}
+	if strings.HasPrefix(subject, reservedPrefix) {
+		return impersonation{}, false, fmt.Errorf("user %s can't be impersonated", subject)
+	}
+	requester := impersonation{user: i.prefix + subject}
+	groups, _ := caller.Claims["groups"].([]any)
+	for _, group := range groups {
+		name, ok := group.(string)
+		if !ok || name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, reservedPrefix) {
+			return impersonation{}, false, fmt.Errorf("group %s can't be impersonated", name)
+		}
+		requester.groups = append(requester.groups, i.prefix+name)
+	}
+	return requester, true, nil
+}
+
+// withRequesterOf returns ctx with the requester recorded in from, so that jobs started by a request impersonate its
+// caller.
+func withRequesterOf(ctx context.Context, from context.Context) context.Context {
+	if requester, ok := from.Value(requesterKey{}).(impersonation); ok {
+		return context.WithValue(ctx, requesterKey{}, requester)
+	}
+	return ctx
+}
+
+// impersonated runs the action as the requester recorded in the context, if any. Other calls of the job, e.g. saving
+// revisions or waiting for deployments, keep the provisioner's identity.
+func impersonated(kubernetesAction action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		if requester, ok := ctx.Value(requesterKey{}).(impersonation); ok {
+			ctx = context.WithValue(ctx, impersonatingKey{}, requester)
+		}
+		return kubernetesAction(c, ctx, object)
+	}
+}
+
+// impersonate lets the clients of the config send the impersonation headers of the requests' contexts.
+func impersonate(config *rest.Config) {
+	config.Wrap(func(next http.RoundTripper) http.RoundTripper {
+		return impersonatingTransport{next: next}
+	})
+}
+
+type impersonatingTransport struct {
+	next http.RoundTripper
+}
+
+func (t impersonatingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requester, ok := request.Context().Value(impersonatingKey{}).(impersonation)
+	if !ok {
+		return t.next.RoundTrip(request)
+	}
+	request = request.Clone(request.Context())
+	request.Header.Set(authenticationv1.ImpersonateUserHeader, requester.user)
+	for _, group := range requester.groups {
+		request.Header.Add(authenticationv1.ImpersonateGroupHeader, group)
+	}
+	return t.next.RoundTrip(request)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestImpersonationOf(t *testing.T) {
+	if _, err := newImpersonator("", ""); err == nil {
+		t.Error("expected impersonation without a prefix to be refused")
+	}
+	impersonator, err := newImpersonator("oidc:", "https://kubernetes.default.svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		caller  principal
+		want    *impersonation
+		refused bool
+	}{
+		{principal{Subject: "alice", Claims: map[string]any{"sub": "alice", "groups": []any{"operators", 42}}}, &impersonation{user: "oidc:alice", groups: []string{"oidc:operators"}}, false},
+		{principal{Subject: "system:serviceaccount:ci:deployer", Claims: map[string]any{"iss": "https://kubernetes.default.svc"}}, &impersonation{user: "system:serviceaccount:ci:deployer"}, false},
+		{principal{Subject: "system:serviceaccount:ci:deployer", Claims: map[string]any{"iss": "https://idp.example.com"}}, nil, true},
+		{principal{Subject: "cert:system:serviceaccount:ci:deployer"}, nil, true},
+		{principal{Subject: "system:admin", Claims: map[string]any{}}, nil, true},
+		{principal{Subject: "mallory", Claims: map[string]any{"groups": []any{"operators", "system:masters"}}}, nil, true},
+		{principal{Subject: "cert:pipeline"}, &impersonation{user: "oidc:pipeline"}, false},
+		{principal{Subject: "api-key", Tenant: "acme"}, nil, false},
+		{principal{Subject: "admin"}, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok, err := impersonator.impersonationOf(&tt.caller)
+		if (err != nil) != tt.refused {
+			t.Errorf("%s: expected refused %v, got %v", tt.caller.Subject, tt.refused, err)
+		}
+		if ok != (tt.want != nil) || (ok && !reflect.DeepEqual(got, *tt.want)) {
+			t.Errorf("%s: impersonated as %+v, %v", tt.caller.Subject, got, ok)
+		}
+	}
+}
+
+func TestImpersonatedActions(t *testing.T) {
+	var users, groups [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users = append(users, r.Header.Values("Impersonate-User"))
+		groups = append(groups, r.Header.Values("Impersonate-Group"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "alice"}}`))
+	}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+	impersonate(config)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	c, err := client.New(config, client.Options{Mapper: mapper})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := context.WithValue(context.Background(), requesterKey{}, impersonation{user: "alice", groups: []string{"operators"}})
+	job := withRequesterOf(context.Background(), request)
+	get := func(c client.Client, ctx context.Context, object client.Object) error {
+		return c.Get(ctx, client.ObjectKeyFromObject(object), object)
+	}
+	namespace := &corev1.Namespace{}
+	namespace.Name = "alice"
+	if err := impersonated(get)(c, job, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(c, job, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, [][]string{{"alice"}, nil}) || !reflect.DeepEqual(groups, [][]string{{"operators"}, nil}) {
+		t.Errorf("expected only the action to impersonate the requester, got users %v and groups %v", users, groups)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Literal path segments a path prefix may consist of, the ingress paths are regular expressions
+var pathPrefixPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)*/?$`)
+
+// IngressOptions changes how the participant's ingresses are exposed, by default with the nginx class, for any host,
+// below /<participant>. The template ingresses configure the rewrites with nginx annotations, other classes need a
+// kustomization translating them.
+type IngressOptions struct {
+	// ClassName selects the ingress controller serving the participant
+	ClassName string `json:"className,omitempty"`
+	// Host restricts the ingress rules to a hostname, e.g. participant1.dataspace.example.com. The participant's APIs
+	// are seeded at the host unless kubeHost is set.
+	Host string `json:"host,omitempty"`
+	// PathPrefix replaces /<participant> in front of the component paths /cp, /cs, /public..., e.g. "/" for a host
+	// of its own. The did:web documents stay below /<participant>, where their DIDs resolve.
+	PathPrefix *string `json:"pathPrefix,omitempty"`
+}
+
+func (o *IngressOptions) validate(tls *TlsOptions) error {
+	if o.ClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(o.ClassName); len(errs) > 0 {
+			return fmt.Errorf("ingress: invalid className %q: %s", o.ClassName, strings.Join(errs, ", "))
+		}
+	}
+	if o.Host != "" {
+		if errs := validation.IsDNS1123Subdomain(o.Host); len(errs) > 0 {
+			return fmt.Errorf("ingress: invalid host %q: %s", o.Host, strings.Join(errs, ", "))
+		}
+		if tls != nil && tls.Host != o.Host {
+			return fmt.Errorf("ingress: host %q differs from the tls host %q", o.Host, tls.Host)
+		}
+	}
+	if o.PathPrefix != nil && !pathPrefixPattern.MatchString(*o.PathPrefix) {
+		return fmt.Errorf("ingress: invalid pathPrefix %q, expected literal path segments like /connectors/alice", *o.PathPrefix)
+	}
+	return nil
+}
+
+// pathPrefix returns the path the participant's component paths start with, without a trailing slash.
+func (p *ParticipantDefinition) pathPrefix() string {
+	if p.Ingress == nil || p.Ingress.PathPrefix == nil {
+		return "/" + p.ParticipantName
+	}
+	return strings.TrimSuffix(*p.Ingress.PathPrefix, "/")
+}
+
+// routeUrl returns the URL the component path, e.g. /cp, of the participant is reached at through the ingress.
+func (p *ParticipantDefinition) routeUrl(path string) string {
+	return p.getHost() + p.pathPrefix() + path
+}
+
+// mutator sets the class and host of the participant's ingresses and moves their paths below the path prefix. The
+// prefix is recorded on the ingresses for the status probes.
+func (o *IngressOptions) mutator(participant string, prefix string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Ingress" {
+			return nil
+		}
+		if o.ClassName != "" {
+			if err := unstructured.SetNestedField(obj.Object, o.ClassName, "spec", "ingressClassName"); err != nil {
+				return err
+			}
+		}
+		rules, _, err := unstructured.NestedSlice(obj.Object, "spec", "rules")
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			rule, ok := rule.(map[string]any)
+			if !ok {
+				continue
+			}
+			if o.Host != "" && rule["host"] == nil {
+				rule["host"] = o.Host
+			}
+			paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+			for _, p := range paths {
+				p, ok := p.(map[string]any)
+				if !ok {
+					continue
+				}
+				// only component paths /<participant>/<component>... move, the DID path /<participant>(...) stays
+				if path, ok := p["path"].(string); ok && strings.HasPrefix(path, "/"+participant+"/") {
+					p["path"] = prefix + strings.TrimPrefix(path, "/"+participant)
+				}
+			}
+			if len(paths) > 0 {
+				if err := unstructured.SetNestedSlice(rule, paths, "http", "paths"); err != nil {
+					return err
+				}
+			}
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules"); err != nil {
+			return err
+		}
+		addAnnotations(obj, map[string]string{status.PathPrefixAnnotation: prefix})
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIngressOptionsValidate(t *testing.T) {
+	prefix := func(p string) *string { return &p }
+	cases := map[string]IngressOptions{
+		"invalid class":     {ClassName: "Traefik_v2"},
+		"invalid host":      {Host: "alice example.com"},
+		"regex prefix":      {PathPrefix: prefix("/alice(/|$)")},
+		"relative prefix":   {PathPrefix: prefix("alice")},
+		"host of other tls": {Host: "alice.example.com"},
+	}
+	for name, options := range cases {
+		if err := options.validate(&TlsOptions{Host: "bob.example.com"}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	for _, valid := range []string{"/", "", "/connectors/alice", "/connectors/alice/"} {
+		if err := (&IngressOptions{ClassName: "traefik", Host: "alice.example.com", PathPrefix: prefix(valid)}).validate(nil); err != nil {
+			t.Errorf("%q: %v", valid, err)
+		}
+	}
+}
+
+func TestIngressMutator(t *testing.T) {
+	root := "/"
+	definition := ParticipantDefinition{
+		ParticipantName:       "alice",
+		KubernetesIngressHost: "https://alice.example.com",
+		Ingress:               &IngressOptions{ClassName: "internal", Host: "alice.example.com", PathPrefix: &root},
+	}
+	if url := definition.routeUrl("/cp/api/management/v3"); url != "https://alice.example.com/cp/api/management/v3" {
+		t.Errorf("unexpected management URL %s", url)
+	}
+
+	ingress := parseObject(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress-controlplane
+spec:
+  ingressClassName: nginx
+  rules:
+    - http:
+        paths:
+          - path: /alice/cp(/|$)(.*)
+          - path: /alice(/|&)(.*)
+`)
+	if err := definition.Ingress.mutator("alice", definition.pathPrefix())(ingress); err != nil {
+		t.Fatal(err)
+	}
+	if class, _, _ := unstructured.NestedString(ingress.Object, "spec", "ingressClassName"); class != "internal" {
+		t.Errorf("expected the ingress class to be replaced, got %s", class)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	rule := rules[0].(map[string]any)
+	paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+	if rule["host"] != "alice.example.com" || paths[0].(map[string]any)["path"] != "/cp(/|$)(.*)" || paths[1].(map[string]any)["path"] != "/alice(/|&)(.*)" {
+		t.Errorf("expected the component path below the prefix at the host, got %v", rule)
+	}
+	if prefix, ok := ingress.GetAnnotations()[status.PathPrefixAnnotation]; !ok || prefix != "" {
+		t.Errorf("expected the prefix to be recorded, got %v", ingress.GetAnnotations())
+	}
+
+	if url := (&ParticipantDefinition{ParticipantName: "bob", KubernetesIngressHost: "http://localhost"}).routeUrl("/cs"); url != "http://localhost/bob/cs" {
+		t.Errorf("expected the default layout, got %s", url)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ingressProbe sends a request to the ingress host before a participant is declared ready, so hosts that resolve but
+// don't reach an ingress controller are reported. Set with --ingress-probe.
+var ingressProbe bool
+
+// The ingress host of a participant gets this period to route to its ingresses once its deployments are ready
+const ingressRouteTimeout = 3 * time.Minute
+
+// ingressRoute checks whether the ingress host of a participant routes to the ingress controller serving its
+// ingresses.
+type ingressRoute struct {
+	client     client.Client
+	httpClient http.Client
+	definition ParticipantDefinition
+}
+
+// check returns why the ingress host doesn't route to the participant's ingresses, nil if it does or the participant
+// has no ingresses, e.g. because it is routed through a Gateway.
+func (r ingressRoute) check(ctx context.Context) error {
+	host := hostnameOf(r.definition.getHost())
+	ingresses := &networkingv1.IngressList{}
+	if err := r.client.List(ctx, ingresses, client.InNamespace(r.definition.ParticipantName)); err != nil {
+		return err
+	}
+	if len(ingresses.Items) == 0 {
+		return nil
+	}
+	served := ingressAddresses(ingresses.Items)
+	resolved, err := resolveHost(ctx, host)
+	if err != nil {
+		if len(served) > 0 {
+			return fmt.Errorf("ingress host %s does not resolve: create a DNS record pointing it at %s", host, strings.Join(served, ", "))
+		}
+		return fmt.Errorf("ingress host %s does not resolve: create a DNS record pointing it at the ingress controller", host)
+	}
+	if ingressProbe {
+		// a proxy in front of the ingress controller may serve the host at other addresses, the probe tells
+		return r.probe(ctx)
+	}
+	if len(served) > 0 {
+		controller, err := resolveAddresses(ctx, served)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(resolved, func(address string) bool { return slices.Contains(controller, address) }) {
+			return fmt.Errorf("ingress host %s resolves to %s, but the ingress controller serves the ingresses of %s at %s: point the DNS record of %s at the ingress controller, or enable --ingress-probe if a proxy in front of it serves the host",
+				host, strings.Join(resolved, ", "), r.definition.ParticipantName, strings.Join(served, ", "), host)
+		}
+	}
+	return nil
+}
+
+// probe sends a request to the ingress host. Any response means an ingress controller answers, the APIs behind it are
+// awaited before seeding.
+func (r ingressRoute) probe(ctx context.Context) error {
+	target := r.definition.routeUrl("/")
+	ctx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("no ingress controller answers at %s: check that the ingress controller is exposed at the address %s resolves to and accepts %s traffic: %w",
+			target, hostnameOf(target), request.URL.Scheme, err)
+	}
+	_ = response.Body.Close()
+	return nil
+}
+
+// waitForIngressRoute waits until the ingress host routes to the participant's ingresses. Ingress controllers take a
+// while to assign an address to new ingresses, and DNS records to propagate.
+func waitForIngressRoute(ctx context.Context, route ingressRoute) error {
+	if route.definition.KubernetesIngressHost == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, ingressRouteTimeout)
+	defer cancel()
+	var reported string
+	for {
+		err := route.check(ctx)
+		if err == nil {
+			return nil
+		}
+		if err.Error() != reported {
+			reported = err.Error()
+			fmt.Println("Waiting for the ingress host of", route.definition.ParticipantName+":", reported)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ingress host not routed within %s: %w", ingressRouteTimeout, err)
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// ingressAddresses returns the IPs and host names the ingress controller assigned to the ingresses.
+func ingressAddresses(ingresses []networkingv1.Ingress) []string {
+	var addresses []string
+	for _, ingress := range ingresses {
+		for _, lb := range ingress.Status.LoadBalancer.Ingress {
+			for _, address := range []string{lb.IP, lb.Hostname} {
+				if address != "" && !slices.Contains(addresses, address) {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+func hostnameOf(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return parsed.Hostname()
+}
+
+// resolveHost returns the IPs of the host, the host itself if it is an IP.
+func resolveHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+	defer cancel()
+	return lookupHost(ctx, host)
+}
+
+// resolveAddresses resolves the host names among the addresses, e.g. those of cloud load balancers.
+func resolveAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	var resolved []string
+	for _, address := range addresses {
+		ips, err := resolveHost(ctx, address)
+		if err != nil {
+			return nil, fmt.Errorf("address %s of the ingress controller does not resolve: %w", address, err)
+		}
+		resolved = append(resolved, ips...)
+	}
+	return resolved, nil
+}
+
+// unreachableRemediation explains how to make the seeding base URL reachable when the provisioner couldn't connect to
+// it, rather than leaving the connection error to speak for itself.
+func unreachableRemediation(definition ParticipantDefinition, err error) error {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil || errors.Is(err, context.Canceled):
+		return err
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("%w (the provisioner can't resolve %s: check the DNS record of the ingress host, or the DNS servers the provisioner uses)",
+			err, dnsErr.Name)
+	case errors.As(err, &netErr):
+		return fmt.Errorf("%w (the provisioner can't reach %s: check that the ingress controller is exposed at the address %s resolves to, and that no firewall or proxy blocks the provisioner)",
+			err, definition.routeUrl(""), hostnameOf(definition.getHost()))
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIngressRoute(t *testing.T) {
+	records := map[string][]string{"alice.dataspace.example": {"10.0.0.1"}, "lb.cloud.example": {"10.0.0.2"}}
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if addresses, ok := records[host]; ok {
+			return addresses, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	t.Cleanup(func() { lookupHost = net.DefaultResolver.LookupHost })
+
+	scheme := runtime.NewScheme()
+	_ = networkingv1.AddToScheme(scheme)
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "alice"}}
+	ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{Hostname: "lb.cloud.example"}}
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ingress).Build()
+	route := ingressRoute{client: kube, definition: ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: "http://alice.dataspace.example"}}
+	ctx := context.Background()
+
+	err := route.check(ctx)
+	if err == nil || !strings.Contains(err.Error(), "resolves to 10.0.0.1, but the ingress controller serves the ingresses of alice at lb.cloud.example") {
+		t.Errorf("expected the host pointing elsewhere to be reported, got %v", err)
+	}
+	records["alice.dataspace.example"] = []string{"10.0.0.2"}
+	if err := route.check(ctx); err != nil {
+		t.Errorf("expected the host to route to the ingress controller, got %v", err)
+	}
+	route.definition.KubernetesIngressHost = "http://bob.dataspace.example"
+	if err := route.check(ctx); err == nil || !strings.Contains(err.Error(), "create a DNS record pointing it at lb.cloud.example") {
+		t.Errorf("expected the unresolvable host to be reported, got %v", err)
+	}
+	// participants without ingresses are routed otherwise
+	route.definition.ParticipantName = "carol"
+	if err := route.check(ctx); err != nil {
+		t.Errorf("expected participants without ingresses to pass, got %v", err)
+	}
+
+	// with the probe, an answering ingress controller is enough
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	ingressProbe = true
+	t.Cleanup(func() { ingressProbe = false })
+	route.definition = ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: controller.URL}
+	if err := route.check(ctx); err != nil {
+		t.Errorf("expected the answering ingress controller to pass, got %v", err)
+	}
+	controller.Close()
+	if err := route.check(ctx); err == nil || !strings.Contains(err.Error(), "no ingress controller answers at "+controller.URL) {
+		t.Errorf("expected the closed ingress controller to be reported, got %v", err)
+	}
+}
+
+func TestUnreachableRemediation(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: "http://alice.dataspace.example"}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	err := unreachableRemediation(definition, refused)
+	if !errors.Is(err, refused) || !strings.Contains(err.Error(), "check that the ingress controller is exposed at the address alice.dataspace.example resolves to") {
+		t.Errorf("expected a remediation for the refused connection, got %v", err)
+	}
+	unresolved := &net.DNSError{Err: "no such host", Name: "alice.dataspace.example"}
+	if err := unreachableRemediation(definition, unresolved); !strings.Contains(err.Error(), "can't resolve alice.dataspace.example") {
+		t.Errorf("expected a remediation for the unresolved host, got %v", err)
+	}
+	rejected := errors.New("409 Conflict")
+	if err := unreachableRemediation(definition, rejected); err != rejected {
+		t.Errorf("expected other errors to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Certificate and Secret the participant's ingresses are served with
+const ingressTlsName = "ingress-tls"
+
+// TlsOptions serves the participant's ingresses over HTTPS with a certificate issued by cert-manager.
+type TlsOptions struct {
+	// Host is the DNS name the ingresses are served at and the certificate is issued for
+	Host string `json:"host"`
+	// ClusterIssuer names the cert-manager ClusterIssuer, Issuer an Issuer in the participant namespace
+	ClusterIssuer string `json:"clusterIssuer,omitempty"`
+	Issuer        string `json:"issuer,omitempty"`
+}
+
+func (t *TlsOptions) validate() error {
+	if t.Host == "" {
+		return errors.New("tls: host is required")
+	}
+	if errs := validation.IsDNS1123Subdomain(t.Host); len(errs) > 0 {
+		return fmt.Errorf("tls: invalid host %q: %s", t.Host, strings.Join(errs, ", "))
+	}
+	if (t.ClusterIssuer == "") == (t.Issuer == "") {
+		return errors.New("tls: either clusterIssuer or issuer is required")
+	}
+	return nil
+}
+
+// mutator serves the ingresses at the host with the certificate, redirecting plain HTTP requests.
+func (t *TlsOptions) mutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Ingress" {
+			return nil
+		}
+		rules, _, err := unstructured.NestedSlice(obj.Object, "spec", "rules")
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if rule, ok := rule.(map[string]any); ok && rule["host"] == nil {
+				rule["host"] = t.Host
+			}
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules"); err != nil {
+			return err
+		}
+		addAnnotations(obj, map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"})
+		return unstructured.SetNestedSlice(obj.Object, []any{
+			map[string]any{"hosts": []any{t.Host}, "secretName": ingressTlsName},
+		}, "spec", "tls")
+	}
+}
+
+// manifests renders the Certificate all ingresses share, so a single certificate is requested for the host.
+func (t *TlsOptions) manifests(namespace string) (string, error) {
+	issuerRef := map[string]any{"kind": "ClusterIssuer", "name": t.ClusterIssuer}
+	if t.Issuer != "" {
+		issuerRef = map[string]any{"kind": "Issuer", "name": t.Issuer}
+	}
+	doc, err := yaml.Marshal(map[string]any{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]any{"name": ingressTlsName, "namespace": namespace},
+		"spec": map[string]any{
+			"secretName": ingressTlsName,
+			"dnsNames":   []string{t.Host},
+			"issuerRef":  issuerRef,
+		},
+	})
+	return string(doc), err
+}
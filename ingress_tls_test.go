@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestTlsOptionsValidate(t *testing.T) {
+	cases := map[string]TlsOptions{
+		"missing host":   {ClusterIssuer: "letsencrypt"},
+		"invalid host":   {Host: "Alice_Example", ClusterIssuer: "letsencrypt"},
+		"missing issuer": {Host: "alice.example.com"},
+		"two issuers":    {Host: "alice.example.com", ClusterIssuer: "letsencrypt", Issuer: "internal"},
+	}
+	for name, options := range cases {
+		if err := options.validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := (&TlsOptions{Host: "alice.example.com", Issuer: "internal"}).validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTlsMutator(t *testing.T) {
+	options := &TlsOptions{Host: "alice.example.com", ClusterIssuer: "letsencrypt"}
+	ingress := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress-controlplane
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /alice/cp(/|$)(.*)
+`), &ingress.Object); err != nil {
+		t.Fatal(err)
+	}
+	if err := options.mutator()(ingress); err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if host := rules[0].(map[string]any)["host"]; host != "alice.example.com" {
+		t.Errorf("expected the rule to be served at the host, got %v", host)
+	}
+	tls, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "tls")
+	if len(tls) != 1 || tls[0].(map[string]any)["secretName"] != ingressTlsName {
+		t.Errorf("expected a TLS block with the certificate secret, got %v", tls)
+	}
+
+	certificate, err := options.manifests("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"kind: Certificate", "namespace: alice", "- alice.example.com", "kind: ClusterIssuer", "name: letsencrypt"} {
+		if !strings.Contains(certificate, expected) {
+			t.Errorf("certificate lacks %q:\n%s", expected, certificate)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Credentials are issued asynchronously, their arrival in the identity hub is polled for at most this long
+var (
+	credentialIssuanceTimeout = 2 * time.Minute
+	credentialPollInterval    = 5 * time.Second
+)
+
+// Format of the requested credentials
+const credentialFormat = "VC1_0_JWT"
+
+// IssuerConfig configures the dataspace issuer service participants are registered with as credential holders.
+type IssuerConfig struct {
+	// Url is the base URL of the issuer's admin API, by default the issuer behind the participant's ingress host
+	Url string `json:"url,omitempty"`
+	// Did identifies the issuer the holders are registered with
+	Did    string `json:"did,omitempty"`
+	ApiKey string `json:"apiKey,omitempty"`
+	// Credentials lists the verifiable credential types requested for participants once they are registered
+	Credentials []string `json:"credentials,omitempty"`
+	// Disabled skips the registration, e.g. for dataspaces without an issuer service
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// defaultIssuer is the issuer of the MVD, set with --issuer-url, --issuer-did and --issuer-api-key.
+var defaultIssuer = IssuerConfig{
+	Did:         "did:web:dataspace-issuer-service.poc-issuer.svc.cluster.local%3A10016:issuer",
+	ApiKey:      "c3VwZXItdXNlcg==.c3VwZXItc2VjcmV0LWtleQo=",
+	Credentials: []string{"MembershipCredential"},
+}
+
+// issuerFor returns the issuer settings of the dataspace, falling back to the defaults for settings it doesn't set.
+func issuerFor(dataspace DataspaceConfig) IssuerConfig {
+	issuer := defaultIssuer
+	if dataspace.Issuer == nil {
+		return issuer
+	}
+	if dataspace.Issuer.Url != "" {
+		issuer.Url = dataspace.Issuer.Url
+	}
+	if dataspace.Issuer.Did != "" {
+		issuer.Did = dataspace.Issuer.Did
+	}
+	if dataspace.Issuer.ApiKey != "" {
+		issuer.ApiKey = dataspace.Issuer.ApiKey
+	}
+	if len(dataspace.Issuer.Credentials) > 0 {
+		issuer.Credentials = dataspace.Issuer.Credentials
+	}
+	issuer.Disabled = issuer.Disabled || dataspace.Issuer.Disabled
+	return issuer
+}
+
+// seedIssuerData registers the participant as holder with the issuer, so it can request credentials.
+func seedIssuerData(ctx context.Context, definition ParticipantDefinition, clients seedingClients, issuer IssuerConfig) error {
+	baseUrl := strings.TrimSuffix(issuer.Url, "/")
+	if baseUrl == "" {
+		baseUrl = definition.getHost() + "/issuer/ad/api/admin/v1alpha"
+	}
+	issuerApi := api.ApiClient{
+		BaseUrl:    baseUrl + "/participants/" + base64.StdEncoding.EncodeToString([]byte(issuer.Did)),
+		ApiKey:     issuer.ApiKey,
+		HttpClient: clients.client(targetIssuer),
+	}
+
+	err := issuerApi.CreateHolder(ctx, definition.Did, definition.Did, definition.ParticipantName)
+	if err != nil {
+		return err
+	}
+	fmt.Println("issuer account created for participant ", definition.ParticipantName)
+	return nil
+}
+
+// credentialTypes returns the credential types requested for the participant, those of its definition or else the
+// issuer's defaults.
+func credentialTypes(definition ParticipantDefinition, issuer IssuerConfig) []string {
+	if len(definition.Credentials) > 0 {
+		return definition.Credentials
+	}
+	return issuer.Credentials
+}
+
+// requestCredentials has the participant's identity hub request the credentials from the issuer and waits until they
+// were stored in the identity hub. The state of every credential is reported in the participant's seeding status.
+func requestCredentials(ctx context.Context, definition ParticipantDefinition, clients seedingClients, creds participantCredentials, issuer IssuerConfig, types []string, statusChecker *status.StatusChecker) error {
+	identityHub := identityApi(definition, clients, creds)
+	participantContextId := base64.StdEncoding.EncodeToString([]byte(definition.Did))
+	report := func(credentialType string, state string, message string) {
+		statusChecker.SetCredential(definition.ParticipantName, status.CredentialStatus{Type: credentialType, State: state, Message: message})
+	}
+
+	// requests are identified by participant and type, so a retried step doesn't request credentials twice
+	pending := make(map[string]string)
+	for _, credentialType := range types {
+		issued, err := identityHub.HasCredential(ctx, participantContextId, credentialType)
+		if err != nil {
+			return err
+		}
+		if issued {
+			report(credentialType, status.CredentialIssued, "")
+			continue
+		}
+		holderPid := definition.ParticipantName + "-" + strings.ToLower(credentialType)
+		err = identityHub.RequestCredentials(ctx, participantContextId, api.CredentialRequest{
+			IssuerDid:   issuer.Did,
+			HolderPid:   holderPid,
+			Credentials: []api.CredentialDescriptor{{Format: credentialFormat, CredentialType: credentialType}},
+		})
+		if err != nil {
+			report(credentialType, status.CredentialFailed, err.Error())
+			return err
+		}
+		report(credentialType, status.CredentialRequested, "")
+		pending[credentialType] = holderPid
+	}
+
+	deadline := time.After(credentialIssuanceTimeout)
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			var missing []string
+			for credentialType := range pending {
+				report(credentialType, status.CredentialFailed, "not issued within "+credentialIssuanceTimeout.String())
+				missing = append(missing, credentialType)
+			}
+			return fmt.Errorf("credentials %s not issued within %s", strings.Join(missing, ", "), credentialIssuanceTimeout)
+		case <-time.After(credentialPollInterval):
+		}
+		var failures []error
+		for credentialType, holderPid := range pending {
+			issued, err := identityHub.HasCredential(ctx, participantContextId, credentialType)
+			if err != nil {
+				fmt.Printf("checking the %s of %s failed: %v\n", credentialType, definition.ParticipantName, err)
+				continue
+			}
+			if issued {
+				report(credentialType, status.CredentialIssued, "")
+				delete(pending, credentialType)
+				continue
+			}
+			request, err := identityHub.GetCredentialRequest(ctx, participantContextId, holderPid)
+			if err == nil && request != nil && request.Status == "ERROR" {
+				report(credentialType, status.CredentialFailed, request.ErrorMessage)
+				failures = append(failures, fmt.Errorf("issuer rejected the %s request: %s", credentialType, request.ErrorMessage))
+				delete(pending, credentialType)
+			}
+		}
+		if err := errors.Join(failures...); err != nil {
+			return err
+		}
+	}
+	fmt.Println("credentials issued to participant", definition.ParticipantName)
+	return nil
+}
+
+// heldCredentialLookup returns the lookup of the credentials in a participant's identity hub reported in its status.
+// Participants that weren't seeded by this provisioner have no known DID and report none.
+func heldCredentialLookup(kubeClient client.Client, clients seedingClients) status.CredentialLookup {
+	return func(ctx context.Context, name string) ([]status.HeldCredential, error) {
+		state, err := loadSeedingState(kubeClient, ctx, name)
+		if err != nil || state.definition.Did == "" {
+			return nil, err
+		}
+		creds, err := loadCredentials(kubeClient, ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		identityHub := identityApi(state.definition, clients.forParticipant(state.definition), creds)
+		resources, err := identityHub.QueryCredentials(ctx, base64.StdEncoding.EncodeToString([]byte(state.definition.Did)), "")
+		if err != nil {
+			return nil, err
+		}
+		held := make([]status.HeldCredential, 0, len(resources))
+		for _, resource := range resources {
+			credential := resource.Parsed()
+			held = append(held, status.HeldCredential{
+				Id:        resource.Id,
+				Types:     credential.Types,
+				Issuer:    resource.IssuerId,
+				State:     api.CredentialStateName(resource.State),
+				IssuedAt:  credential.IssuanceDate,
+				ExpiresAt: credential.ExpirationDate,
+			})
+		}
+		return held, nil
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Label marking the objects of an issuer service, its value is the issuer's name
+const issuerLabel = "aruba-provisioner/issuer"
+
+// Deployment of the issuer runtime, which its default DID is served by
+const issuerServiceName = "dataspace-issuer-service"
+
+// Secret in the issuer namespace holding the super-user key of the issuer's admin and identity APIs
+const issuerCredentialsSecretName = "issuer-credentials"
+
+const issuerApiKeyField = "apiKey"
+
+// Phase of issuer jobs creating the issuer's participant context
+const phaseBootstrap = "bootstrap"
+
+// IssuerDefinition describes the issuer service of a dataspace, which participants are registered with as
+// credential holders.
+type IssuerDefinition struct {
+	// Name is the namespace the issuer is deployed to, and the prefix of its ingress paths
+	Name string `json:"name"`
+	// Did identifies the issuer, by default the did:web document its runtime serves in the cluster
+	Did string `json:"did,omitempty"`
+}
+
+// issuerStatus reports an issuer service and the settings dataspaces use it with.
+type issuerStatus struct {
+	Name  string `json:"name"`
+	Did   string `json:"did"`
+	Ready bool   `json:"ready"`
+	// Components reports the readiness of the issuer's deployments
+	Components []status.ComponentStatus `json:"components"`
+	// Issuer is the issuer entry of the dataspaces config, its API key is in ApiKeySecret
+	Issuer       IssuerConfig `json:"issuer"`
+	ApiKeySecret string       `json:"apiKeySecret"`
+}
+
+// issuerDeployments are awaited before the issuer is bootstrapped
+var issuerDeployments = []string{"postgres", "vault", issuerServiceName}
+
+// defaultIssuerDid returns the did:web of the issuer, served by its runtime in the cluster.
+func defaultIssuerDid(name string) string {
+	return fmt.Sprintf("did:web:%s.%s.svc.cluster.local%%3A10016:issuer", issuerServiceName, name)
+}
+
+// validate checks the definition and sets the default DID.
+func (d *IssuerDefinition) validate() error {
+	if d.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required")
+	}
+	if errs := validation.IsDNS1123Label(d.Name); len(errs) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid name: "+errs[0])
+	}
+	if d.Did == "" {
+		d.Did = defaultIssuerDid(d.Name)
+	} else if !strings.HasPrefix(d.Did, "did:") {
+		return fiber.NewError(fiber.StatusBadRequest, "did must be a DID, e.g. did:web:issuer.example.com")
+	}
+	return nil
+}
+
+// issuerAdminUrl returns the base URL of the issuer's admin API, which holders are registered through.
+func issuerAdminUrl(name string) string {
+	return fmt.Sprintf("http://%s:10013/api/admin/v1alpha", status.ServiceHost(issuerServiceName, name))
+}
+
+// issuanceUrl returns the URL of the issuer's DCP issuance API, which holders request their credentials from.
+func issuanceUrl(definition IssuerDefinition) string {
+	return fmt.Sprintf("http://%s:10012/api/issuance/v1alpha/participants/%s", status.ServiceHost(issuerServiceName, definition.Name), base64.StdEncoding.EncodeToString([]byte(definition.Did)))
+}
+
+// claimIssuer rejects names of system namespaces and of namespaces that aren't issuers, e.g. participants.
+func claimIssuer(c client.Client, ctx context.Context, name string) error {
+	namespace := conflictingResource{Kind: "Namespace", Name: name}
+	if status.IsSystemNamespace(name) {
+		return &conflictError{code: codeNameReserved, resource: namespace, message: fmt.Sprintf("%s is a system namespace and can't be an issuer", name)}
+	}
+	existing := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, existing); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && existing.Labels[issuerLabel] != name {
+		return &conflictError{code: codeNameConflict, resource: namespace, message: fmt.Sprintf("namespace %s already exists and isn't an issuer", name)}
+	}
+	return nil
+}
+
+// issuerApiKey returns the super-user key of the issuer, generated when the issuer is provisioned the first time.
+func issuerApiKey(c client.Client, ctx context.Context, name string) (string, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: name, Name: issuerCredentialsSecretName}, secret)
+	if err == nil && len(secret.Data[issuerApiKeyField]) > 0 {
+		return string(secret.Data[issuerApiKeyField]), nil
+	}
+	if client.IgnoreNotFound(err) != nil {
+		return "", err
+	}
+	key, err := generateApiKey()
+	if err != nil {
+		return "", err
+	}
+	// the identity API resolves super-user keys by the base64 encoded ID before the dot
+	return base64.StdEncoding.EncodeToString([]byte("super-user")) + "." + key, nil
+}
+
+// issuerMutator labels the objects of the issuer, and configures the runtime with its DID and super-user key.
+func issuerMutator(definition IssuerDefinition, apiKey string) objectMutator {
+	labels := map[string]string{issuerLabel: definition.Name}
+	return func(obj *unstructured.Unstructured) error {
+		addLabels(obj, labels)
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, map[string]string{didAnnotation: definition.Did})
+		}
+		if obj.GetKind() == "ConfigMap" && obj.GetName() == "issuer-config" {
+			return unstructured.SetNestedField(obj.Object, apiKey, "data", superUserKeySetting)
+		}
+		return nil
+	}
+}
+
+// startIssuer deploys the issuer service stack in a background job, waits for it and creates the issuer's
+// participant context, which holds the keys its credentials are signed with.
+func (p *provisioner) startIssuer(ctx context.Context, definition IssuerDefinition) (*provisioningJob, error) {
+	job, err := jobs.create(ctx, definition.Name, "")
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := p.queue.join(job)
+	if err != nil {
+		jobs.discard(job.Id)
+		return nil, err
+	}
+	job.queue()
+
+	apply := impersonated(withRetries(applyResource))
+	var apiKey string
+	steps := []jobStep{
+		{phaseApply, func(ctx context.Context) error {
+			fmt.Println("Creating issuer", definition.Name)
+			var err error
+			if apiKey, err = issuerApiKey(p.kubeClient, ctx, definition.Name); err != nil {
+				return err
+			}
+			resources, err := applyYaml(&definition.Name, &definition.Did, p.kubeClient, ctx, issuerServiceYaml, apply, issuerMutator(definition, apiKey))
+			if err != nil {
+				return err
+			}
+			job.setResources(resources)
+			secret := &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Name: issuerCredentialsSecretName, Namespace: definition.Name, Labels: map[string]string{issuerLabel: definition.Name}},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       map[string][]byte{issuerApiKeyField: []byte(apiKey)},
+			}
+			return applyResource(p.kubeClient, ctx, secret)
+		}},
+		{phaseReadiness, func(ctx context.Context) error {
+			readinessCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			return waitForDeployments(p.kubeClient, readinessCtx, definition.Name, issuerDeployments)
+		}},
+		{phaseBootstrap, func(ctx context.Context) error {
+			identityApi := api.ApiClient{
+				BaseUrl:    fmt.Sprintf("http://%s:10015/api/identity/v1alpha", status.ServiceHost(issuerServiceName, definition.Name)),
+				ApiKey:     apiKey,
+				HttpClient: http.Client{Timeout: defaultHttpTimeout},
+				Retry:      api.DefaultRetry,
+			}
+			return bootstrapIssuer(ctx, &identityApi, definition)
+		}},
+	}
+	runInBackground(func() {
+		runCtx, stop := job.bind(withRequesterOf(withSpanOf(p.ctx, ctx), ctx))
+		defer stop()
+		if err := ticket.wait(runCtx); err != nil {
+			job.abandon(err)
+			return
+		}
+		defer ticket.done()
+		if err := job.execute(runCtx, steps); err != nil {
+			fmt.Printf("provisioning issuer %s failed: %v\n", definition.Name, err)
+			return
+		}
+		job.succeed()
+		fmt.Println("Issuer", definition.Name, "ready")
+	})
+	return job, nil
+}
+
+// bootstrapIssuer creates the participant context of the issuer, unless it exists already.
+func bootstrapIssuer(ctx context.Context, identityApi api.IdentityApi, definition IssuerDefinition) error {
+	existing, err := identityApi.GetParticipant(ctx, base64.StdEncoding.EncodeToString([]byte(definition.Did)))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		fmt.Printf("issuer %s already exists\n", definition.Did)
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{
+		"roles":         []string{"admin"},
+		"active":        true,
+		"participantId": definition.Did,
+		"did":           definition.Did,
+		"serviceEndpoints": []map[string]string{
+			{"type": "IssuerService", "serviceEndpoint": issuanceUrl(definition), "id": definition.Name + "-issuance"},
+		},
+		"key": map[string]any{
+			"keyId":              definition.Did + "#key-1",
+			"privateKeyAlias":    definition.Did + "#key-1",
+			"keyGeneratorParams": map[string]string{"algorithm": "EC"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = identityApi.CreateParticipant(ctx, string(body))
+	return err
+}
+
+// getIssuerStatus reports the readiness of an issuer's deployments. Only namespaces created as issuers are found.
+func getIssuerStatus(kubeClient client.Client, ctx context.Context, name string) (issuerStatus, error) {
+	namespace := &corev1.Namespace{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, namespace); apierrors.IsNotFound(err) || (err == nil && namespace.Labels[issuerLabel] != name) {
+		return issuerStatus{}, fiber.NewError(fiber.StatusNotFound, "issuer not found")
+	} else if err != nil {
+		return issuerStatus{}, err
+	}
+	components, ready, err := deploymentComponents(kubeClient, ctx, name, issuerDeployments)
+	if err != nil {
+		return issuerStatus{}, err
+	}
+	did := namespace.Annotations[didAnnotation]
+	return issuerStatus{
+		Name:         name,
+		Did:          did,
+		Ready:        ready,
+		Components:   components,
+		Issuer:       IssuerConfig{Url: issuerAdminUrl(name), Did: did},
+		ApiKeySecret: name + "/" + issuerCredentialsSecretName,
+	}, nil
+}
+
+// deploymentComponents reports the readiness of the named deployments of a namespace, and whether all of them are
+// ready. Missing deployments aren't.
+func deploymentComponents(kubeClient client.Client, ctx context.Context, namespace string, names []string) ([]status.ComponentStatus, bool, error) {
+	components := make([]status.ComponentStatus, 0, len(names))
+	ready := true
+	for _, name := range names {
+		component := status.ComponentStatus{Name: name, Status: status.ComponentMissing}
+		deployment := &appsv1.Deployment{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment); client.IgnoreNotFound(err) != nil {
+			return nil, false, err
+		} else if err == nil {
+			component.ReadyReplicas = deployment.Status.ReadyReplicas
+			component.DesiredReplicas = 1
+			if deployment.Spec.Replicas != nil {
+				component.DesiredReplicas = *deployment.Spec.Replicas
+			}
+			component.Ready = component.ReadyReplicas >= component.DesiredReplicas
+			component.Status = status.ComponentStarting
+			if component.Ready {
+				component.Status = status.ComponentRunning
+			}
+		}
+		ready = ready && component.Ready
+		components = append(components, component)
+	}
+	return components, ready, nil
+}
+
+func provisionIssuer(kubeClient client.Client, ctx context.Context, participants *provisioner, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var definition IssuerDefinition
+		if err := parser.parse(c, &definition); err != nil {
+			return err
+		}
+		if err := definition.validate(); err != nil {
+			return err
+		}
+		if err := claimIssuer(kubeClient, ctx, definition.Name); err != nil {
+			return err
+		}
+		job, err := participants.startIssuer(c.UserContext(), definition)
+		if err != nil {
+			return err
+		}
+		c.Location("/api/v1/jobs/" + job.Id)
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "issuer": definition.Name, "did": definition.Did})
+	}
+}
+
+func getIssuer(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := getIssuerStatus(kubeClient, ctx, c.Params("issuerName"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(result)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIssuerDefinitionDefaults(t *testing.T) {
+	definition := IssuerDefinition{Name: "poc-issuer"}
+	if err := definition.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if definition.Did != defaultIssuer.Did {
+		t.Errorf("expected the default issuer DID %s, got %s", defaultIssuer.Did, definition.Did)
+	}
+	for _, invalid := range []IssuerDefinition{{}, {Name: "Issuer_1"}, {Name: "issuer", Did: "issuer"}} {
+		if err := invalid.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestIssuerManifests(t *testing.T) {
+	if err := validateManifest(issuerServiceYaml); err != nil {
+		t.Fatal(err)
+	}
+	definition := IssuerDefinition{Name: "issuer", Did: "did:web:issuer"}
+	objects := map[string]*unstructured.Unstructured{}
+	capture := func(c client.Client, ctx context.Context, object client.Object) error {
+		objects[object.GetObjectKind().GroupVersionKind().Kind+"/"+object.GetName()] = object.(*unstructured.Unstructured)
+		return nil
+	}
+	if _, err := applyYaml(&definition.Name, &definition.Did, nil, context.Background(), issuerServiceYaml, capture, issuerMutator(definition, "key")); err != nil {
+		t.Fatal(err)
+	}
+	namespace := objects["Namespace/issuer"]
+	if namespace == nil || namespace.GetLabels()[issuerLabel] != "issuer" || namespace.GetAnnotations()[didAnnotation] != "did:web:issuer" {
+		t.Fatalf("expected the namespace to be labelled as issuer, got %v", namespace)
+	}
+	if _, managed := namespace.GetLabels()[status.ManagedByLabel]; managed {
+		t.Error("expected the issuer namespace not to be discovered as participant")
+	}
+	config := objects["ConfigMap/issuer-config"]
+	if key, _, _ := unstructured.NestedString(config.Object, "data", superUserKeySetting); key != "key" {
+		t.Errorf("expected the generated super-user key, got %q", key)
+	}
+	if id, _, _ := unstructured.NestedString(config.Object, "data", "EDC_ISSUER_ID"); id != "did:web:issuer" {
+		t.Errorf("expected the issuer DID, got %q", id)
+	}
+	for _, deployment := range issuerDeployments {
+		if objects["Deployment/"+deployment] == nil {
+			t.Errorf("expected deployment %s", deployment)
+		}
+	}
+}
+
+func TestClaimIssuerRejectsParticipants(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	replicas := int32(1)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "issuer", Labels: map[string]string{issuerLabel: "issuer"}, Annotations: map[string]string{didAnnotation: "did:web:issuer"}}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: issuerServiceName, Namespace: "issuer"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}, Status: appsv1.DeploymentStatus{ReadyReplicas: 1}},
+	).Build()
+	ctx := context.Background()
+
+	var conflict *conflictError
+	if err := claimIssuer(kube, ctx, "acme"); !errors.As(err, &conflict) || conflict.code != codeNameConflict {
+		t.Errorf("expected a participant namespace to be rejected, got %v", err)
+	}
+	if err := claimIssuer(kube, ctx, "kube-system"); !errors.As(err, &conflict) || conflict.code != codeNameReserved {
+		t.Errorf("expected a system namespace to be rejected, got %v", err)
+	}
+	if err := claimIssuer(kube, ctx, "issuer"); err != nil {
+		t.Errorf("expected an existing issuer to be provisioned again, got %v", err)
+	}
+
+	issuer, err := getIssuerStatus(kube, ctx, "issuer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issuer.Ready || issuer.Issuer.Did != "did:web:issuer" || issuer.Issuer.Url != issuerAdminUrl("issuer") {
+		t.Errorf("expected an issuer that isn't ready yet, got %+v", issuer)
+	}
+	if _, err := getIssuerStatus(kube, ctx, "acme"); err == nil {
+		t.Error("expected participants not to be reported as issuers")
+	}
+}
+
+func TestBootstrapIssuer(t *testing.T) {
+	var created map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &created)
+		_, _ = w.Write([]byte(`{"clientId":"issuer"}`))
+	}))
+	defer server.Close()
+	definition := IssuerDefinition{Name: "issuer", Did: "did:web:issuer"}
+
+	if err := bootstrapIssuer(context.Background(), &api.ApiClient{BaseUrl: server.URL}, definition); err != nil {
+		t.Fatal(err)
+	}
+	if created["did"] != "did:web:issuer" {
+		t.Errorf("expected the issuer's participant context to be created, got %v", created)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIssuerFor(t *testing.T) {
+	issuer := issuerFor(DataspaceConfig{})
+	if !reflect.DeepEqual(issuer, defaultIssuer) {
+		t.Errorf("expected the default issuer, got %+v", issuer)
+	}
+
+	issuer = issuerFor(DataspaceConfig{Issuer: &IssuerConfig{Url: "https://issuer.example.com/admin", Did: "did:web:issuer.example.com"}})
+	if issuer.Url != "https://issuer.example.com/admin" || issuer.Did != "did:web:issuer.example.com" {
+		t.Errorf("dataspace settings not applied: %+v", issuer)
+	}
+	if issuer.ApiKey != defaultIssuer.ApiKey {
+		t.Errorf("expected the default API key, got %q", issuer.ApiKey)
+	}
+
+	if !issuerFor(DataspaceConfig{Issuer: &IssuerConfig{Disabled: true}}).Disabled {
+		t.Error("expected the issuer to be disabled")
+	}
+}
+
+func TestSeedIssuerData(t *testing.T) {
+	var path, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiKey = r.URL.Path, r.Header.Get("x-api-key")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	issuer := IssuerConfig{Url: server.URL + "/admin/", Did: "did:web:issuer", ApiKey: "issuer-key"}
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: "http://unused.invalid"}
+	if err := seedIssuerData(context.Background(), definition, seedingClients{}, issuer); err != nil {
+		t.Fatal(err)
+	}
+	expected := "/admin/participants/" + base64.StdEncoding.EncodeToString([]byte("did:web:issuer")) + "/holders"
+	if path != expected {
+		t.Errorf("expected a request to %s, got %s", expected, path)
+	}
+	if apiKey != "issuer-key" {
+		t.Errorf("expected the issuer's API key, got %q", apiKey)
+	}
+}
+
+func TestSeedStepsWithoutIssuer(t *testing.T) {
+	steps, err := seedSteps(nil, ParticipantDefinition{ParticipantName: "alice"}, seedingClients{}, participantCredentials{}, IssuerConfig{Disabled: true}, nil, nil, &status.SeededResources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if step.name == seedStepIssuer {
+			t.Error("issuer step planned although the issuer is disabled")
+		}
+	}
+}
+
+// identityHubServer issues the credentials requested from it once they were polled for, or fails the requests with
+// errorMessage.
+type identityHubServer struct {
+	mu           sync.Mutex
+	requested    map[string]bool
+	polls        int
+	errorMessage string
+}
+
+func (s *identityHubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/credentials/request"):
+		s.requested[r.URL.Path] = true
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(r.URL.Path, "/credentials/request/"):
+		state := "REQUESTED"
+		if s.errorMessage != "" {
+			state = "ERROR"
+		}
+		_, _ = w.Write([]byte(`{"status":"` + state + `","errorMessage":"` + s.errorMessage + `"}`))
+	case strings.HasSuffix(r.URL.Path, "/credentials"):
+		if len(s.requested) > 0 && s.errorMessage == "" && s.polls > 0 {
+			_, _ = w.Write([]byte(`[{"id":"membership"}]`))
+			return
+		}
+		if len(s.requested) > 0 {
+			s.polls++
+		}
+		_, _ = w.Write([]byte(`[]`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRequestCredentials(t *testing.T) {
+	previous := credentialPollInterval
+	credentialPollInterval = time.Millisecond
+	t.Cleanup(func() { credentialPollInterval = previous })
+
+	for name, errorMessage := range map[string]string{"issued": "", "rejected": "not a member"} {
+		t.Run(name, func(t *testing.T) {
+			server := &identityHubServer{requested: make(map[string]bool), errorMessage: errorMessage}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+			definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL}
+			err := requestCredentials(context.Background(), definition, seedingClients{}, participantCredentials{}, defaultIssuer,
+				[]string{"MembershipCredential"}, checker)
+
+			if len(server.requested) != 1 {
+				t.Errorf("expected one credential request, got %v", server.requested)
+			}
+			expected := status.CredentialIssued
+			if errorMessage != "" {
+				expected = status.CredentialFailed
+				if err == nil || !strings.Contains(err.Error(), errorMessage) {
+					t.Errorf("expected the rejection to be reported, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			credentials := checker.GetSeeding("alice").Credentials
+			if len(credentials) != 1 || credentials[0].Type != "MembershipCredential" || credentials[0].State != expected {
+				t.Errorf("expected the credential to be %s, got %+v", expected, credentials)
+			}
+		})
+	}
+}
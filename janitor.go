@@ -0,0 +1,257 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultJanitorGrace = 30 * time.Minute
+
+// Policies of the janitor
+const (
+	// janitorReport only lists the participants that are stuck
+	janitorReport = "report"
+	// janitorRepair resumes the seeding of stuck participants whose components are ready
+	janitorRepair = "repair"
+	// janitorDelete repairs what it can and deletes the other stuck participants
+	janitorDelete = "delete"
+)
+
+var janitorPolicies = []string{janitorReport, janitorRepair, janitorDelete}
+
+// Problems of stuck participants
+const (
+	// problemFailed is a participant whose provisioning or seeding failed
+	problemFailed = "failed"
+	// problemInterrupted is a participant whose provisioning stopped without an outcome, e.g. with a restart of the
+	// provisioner while it waited for the deployments
+	problemInterrupted = "interrupted"
+	// problemOrphaned is a managed namespace whose participant components are gone
+	problemOrphaned = "orphaned"
+)
+
+// What the janitor did about a stuck participant
+const (
+	janitorReported       = "reported"
+	janitorSeedingResumed = "seedingResumed"
+	janitorDeleted        = "deleted"
+)
+
+// janitorFinding is a participant the janitor found stuck.
+type janitorFinding struct {
+	Participant string `json:"participant"`
+	Problem     string `json:"problem"`
+	// Status and Message are the participant's status when it was found
+	Status  status.ProvisioningStatus `json:"status"`
+	Message string                    `json:"message,omitempty"`
+	// Since is when the participant's state last changed
+	Since  time.Time `json:"since"`
+	Action string    `json:"action"`
+	JobId  string    `json:"jobId,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// janitorSweep is the outcome of one pass of the janitor over all managed namespaces.
+type janitorSweep struct {
+	Policy     string           `json:"policy"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Findings   []janitorFinding `json:"findings"`
+}
+
+// janitor periodically looks for participants that are stuck in FAILED or half provisioned, e.g. because the
+// provisioner restarted while it waited for their deployments, and repairs or deletes them according to its policy.
+// Participants are only touched once their state didn't change for the grace period. Repairs that fail again are
+// retried after another grace period.
+type janitor struct {
+	participants *provisioner
+	policy       string
+	grace        time.Duration
+	// leading reports whether this replica sweeps, with several replicas only the leader does
+	leading func() bool
+
+	mu   sync.Mutex
+	last *janitorSweep
+}
+
+func validateJanitorPolicy(policy string) error {
+	if !slices.Contains(janitorPolicies, policy) {
+		return fmt.Errorf("unknown janitor policy %q, must be one of %v", policy, janitorPolicies)
+	}
+	return nil
+}
+
+// run sweeps at the interval until the context is cancelled.
+func (j *janitor) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if j.leading != nil && !j.leading() {
+			continue
+		}
+		sweep, err := j.sweep(ctx, j.policy)
+		if err != nil {
+			fmt.Println("Janitor sweep failed:", err)
+			continue
+		}
+		for _, finding := range sweep.Findings {
+			fmt.Printf("Janitor: %s is %s since %s, %s %s\n", finding.Participant, finding.Problem, finding.Since.Format(time.RFC3339), finding.Action, finding.Error)
+		}
+	}
+}
+
+// sweep inspects all managed namespaces and deals with the stuck participants according to the policy.
+func (j *janitor) sweep(ctx context.Context, policy string) (janitorSweep, error) {
+	sweep := janitorSweep{Policy: policy, StartedAt: time.Now().UTC(), Findings: []janitorFinding{}}
+	c := j.participants.kubeClient
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		return sweep, err
+	}
+	for _, namespace := range namespaces.Items {
+		finding, err := j.inspect(ctx, namespace)
+		if err != nil {
+			fmt.Printf("Janitor: inspecting %s failed: %v\n", namespace.Name, err)
+			continue
+		}
+		if finding == nil {
+			continue
+		}
+		j.resolve(ctx, finding, policy)
+		sweep.Findings = append(sweep.Findings, *finding)
+	}
+	sweep.FinishedAt = time.Now().UTC()
+	j.mu.Lock()
+	j.last = &sweep
+	j.mu.Unlock()
+	return sweep, nil
+}
+
+// inspect returns what is wrong with the participant of the namespace, nil if it is fine, busy or within the grace
+// period.
+func (j *janitor) inspect(ctx context.Context, namespace corev1.Namespace) (*janitorFinding, error) {
+	name := namespace.Name
+	if namespace.DeletionTimestamp != nil || jobs.running(name) || j.participants.statusChecker.InOperation(ctx, name) {
+		return nil, nil
+	}
+	marker, since, err := readReadinessMarker(j.participants.kubeClient, ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if since.IsZero() {
+		since = namespace.CreationTimestamp.Time
+	}
+	if time.Since(since) < j.grace {
+		return nil, nil
+	}
+	participantStatus, err := j.participants.statusChecker.GetStatus(ctx, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	finding := &janitorFinding{Participant: name, Status: participantStatus.Status, Message: participantStatus.Message, Since: since.UTC(), Action: janitorReported}
+	switch {
+	case participantStatus.Status == status.StatusOrphaned:
+		finding.Problem = problemOrphaned
+	case marker == markerProvisioning:
+		finding.Problem = problemInterrupted
+	case marker == markerFailed || participantStatus.Status == status.StatusFailed:
+		finding.Problem = problemFailed
+	default:
+		return nil, nil
+	}
+	return finding, nil
+}
+
+// resolve repairs or deletes the stuck participant as far as the policy allows and records what it did. Participants
+// whose components are ready are repaired by resuming their seeding, which requires that it started before.
+func (j *janitor) resolve(ctx context.Context, finding *janitorFinding, policy string) {
+	if policy == janitorReport {
+		return
+	}
+	var job *provisioningJob
+	var err error
+	if finding.Problem != problemOrphaned {
+		job, err = j.repair(ctx, finding)
+	}
+	if job == nil && policy == janitorDelete {
+		// protected participants are only deleted by request
+		protected, protectedErr := isProtected(j.participants.kubeClient, ctx, finding.Participant)
+		if protectedErr != nil {
+			err = protectedErr
+		} else if !protected {
+			if job, err = j.participants.startDeletion(ctx, finding.Participant, nil); err == nil {
+				finding.Action = janitorDeleted
+			}
+		}
+	}
+	if job != nil {
+		finding.JobId = job.Id
+	}
+	if err != nil {
+		finding.Error = err.Error()
+	}
+}
+
+// repair resumes the seeding of the participant, it returns no job if the participant can't be repaired.
+func (j *janitor) repair(ctx context.Context, finding *janitorFinding) (*provisioningJob, error) {
+	// the seeding status is lost with a restart, so components that are ready report READY
+	if finding.Status != status.StatusReady && finding.Status != status.StatusSeedFailed {
+		return nil, nil
+	}
+	state, err := loadSeedingState(j.participants.kubeClient, ctx, finding.Participant)
+	if err != nil || state.definition.ParticipantName == "" || !state.definition.Seed.enabled() {
+		return nil, err
+	}
+	job, err := j.participants.resumeSeeding(ctx, finding.Participant)
+	if err != nil {
+		return nil, err
+	}
+	finding.Action = janitorSeedingResumed
+	return job, nil
+}
+
+// lastSweep returns the outcome of the latest sweep, nil if there was none.
+func (j *janitor) lastSweep() *janitorSweep {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+func getJanitorSweep(j *janitor) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sweep := j.lastSweep()
+		if sweep == nil {
+			return fiber.NewError(fiber.StatusNotFound, "the janitor didn't sweep yet")
+		}
+		return c.JSON(sweep)
+	}
+}
+
+// runJanitorSweep sweeps right away, with the configured policy unless ?policy= selects another one, e.g. report for
+// a dry run.
+func runJanitorSweep(j *janitor, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy := c.Query("policy", j.policy)
+		if err := validateJanitorPolicy(policy); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		sweep, err := j.sweep(ctx, policy)
+		if err != nil {
+			return err
+		}
+		return c.JSON(sweep)
+	}
+}
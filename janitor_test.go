@@ -0,0 +1,83 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJanitorFindsStuckParticipants(t *testing.T) {
+	longAgo := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	var objects []client.Object
+	participant := func(name string, marker string, updatedAt time.Time, deployments bool) {
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: longAgo,
+			Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}})
+		if marker != "" {
+			objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: readinessConfigMapName, Namespace: name},
+				Data: map[string]string{"status": marker, "updatedAt": updatedAt.UTC().Format(time.RFC3339)}})
+		}
+		if !deployments {
+			return
+		}
+		for _, deployment := range participantDeploymentNames {
+			objects = append(objects, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: deployment, Namespace: name, CreationTimestamp: longAgo},
+				Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+			})
+		}
+	}
+	participant("ready", markerReady, longAgo.Time, true)
+	participant("interrupted", markerProvisioning, longAgo.Time, true)
+	participant("failed", markerFailed, longAgo.Time, true)
+	participant("recent", markerFailed, time.Now(), true)
+	participant("orphaned", "", time.Time{}, false)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	checker := status.NewStatusChecker(context.Background(), kube, 0)
+	cleanup := &janitor{participants: &provisioner{kubeClient: kube, statusChecker: checker}, policy: janitorReport, grace: time.Hour}
+
+	sweep, err := cleanup.sweep(context.Background(), janitorReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := map[string]string{}
+	for _, finding := range sweep.Findings {
+		problems[finding.Participant] = finding.Problem
+		if finding.Action != janitorReported {
+			t.Errorf("expected %s to be reported only, got %s", finding.Participant, finding.Action)
+		}
+	}
+	expected := map[string]string{"interrupted": problemInterrupted, "failed": problemFailed, "orphaned": problemOrphaned}
+	if len(problems) != len(expected) {
+		t.Errorf("expected findings %v, got %v", expected, problems)
+	}
+	for name, problem := range expected {
+		if problems[name] != problem {
+			t.Errorf("expected %s to be %s, got %q", name, problem, problems[name])
+		}
+	}
+	if last := cleanup.lastSweep(); last == nil || len(last.Findings) != len(expected) {
+		t.Errorf("sweep not recorded: %+v", last)
+	}
+
+	// participants being provisioned are left alone
+	checker.BeginProvisioning("interrupted")
+	defer checker.EndOperation("interrupted")
+	if finding, err := cleanup.inspect(context.Background(), corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "interrupted", CreationTimestamp: longAgo}}); err != nil || finding != nil {
+		t.Errorf("expected no finding during an operation, got %+v, %v", finding, err)
+	}
+	if err := validateJanitorPolicy("purge"); err == nil {
+		t.Error("expected unknown policies to be rejected")
+	}
+}
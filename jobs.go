@@ -0,0 +1,438 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Finished jobs are kept this long for clients polling their outcome
+const jobRetention = 24 * time.Hour
+
+const (
+	jobQueued    = "QUEUED"
+	jobRunning   = "RUNNING"
+	jobSucceeded = "SUCCEEDED"
+	jobFailed    = "FAILED"
+	jobCancelled = "CANCELLED"
+)
+
+// errJobCancelled ends the context of a job cancelled by a client
+var errJobCancelled = errors.New("job cancelled")
+
+// A cancelled job gets this long to stop its phase before the participant is torn down
+const jobStopTimeout = 30 * time.Second
+
+// Phases of a provisioning job, in order
+const (
+	phaseApply     = "apply"
+	phaseReadiness = "readiness"
+	phaseSeeding   = "seeding"
+	phaseHooks     = "hooks"
+)
+
+type jobPhase struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// provisioningJob tracks a provisioning request running in the background.
+type provisioningJob struct {
+	mu          sync.Mutex
+	Id          string `json:"id"`
+	Participant string `json:"participant"`
+	Status      string `json:"status"`
+	Phase       string `json:"phase,omitempty"`
+	// QueuePosition is the position of a QUEUED job among the jobs waiting for a free slot, starting at one
+	QueuePosition int    `json:"queuePosition,omitempty"`
+	Error         string `json:"error,omitempty"`
+	// Code is the stable code of the error failing the job
+	Code   string     `json:"code,omitempty"`
+	Phases []jobPhase `json:"phases"`
+	// Resources lists the applied objects once the apply phase completed
+	Resources appliedObjects `json:"resources,omitempty"`
+	// Changes compares the applied objects with their previous state, for upgrades of existing participants
+	Changes    []objectChange `json:"changes,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+
+	// creates is set for jobs provisioning a participant that didn't exist yet, which may tear it down when cancelled
+	creates bool
+	// store shares the job with other replicas, unlock releases its hold of the participant's Lease
+	store  *jobStore
+	unlock func()
+	// cancelled ends when the job is cancelled, done is closed once it finished. Both are nil for jobs of other
+	// replicas.
+	cancelled context.Context
+	stop      context.CancelCauseFunc
+	done      chan struct{}
+	finished  sync.Once
+}
+
+// jobStep is a phase of a job and the function performing it. The context carries the trace of the phase.
+type jobStep struct {
+	phase string
+	run   func(ctx context.Context) error
+}
+
+type jobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*provisioningJob
+	// shared coordinates the jobs with other replicas, nil for a single replica
+	shared *coordinator
+}
+
+var jobs = &jobStore{jobs: make(map[string]*provisioningJob)}
+
+// create registers a new running job for the participant and drops jobs that finished before the retention period.
+// With other replicas, the job holds the participant's Lease, recording the operation it runs, until it finishes.
+func (s *jobStore) create(ctx context.Context, participant string, operation status.ProvisioningStatus) (*provisioningJob, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	job := &provisioningJob{
+		Id:          hex.EncodeToString(id),
+		Participant: participant,
+		Status:      jobRunning,
+		Phases:      []jobPhase{},
+		CreatedAt:   time.Now(),
+		store:       s,
+		unlock:      func() {},
+		done:        make(chan struct{}),
+	}
+	job.cancelled, job.stop = context.WithCancelCause(context.Background())
+	if s.shared != nil {
+		unlock, err := s.shared.lock(ctx, participant, operation)
+		if err != nil {
+			return nil, err
+		}
+		job.unlock = unlock
+	}
+	s.mu.Lock()
+	for id, existing := range s.jobs {
+		if finished := existing.finishedAt(); finished != nil && time.Since(*finished) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.Id] = job
+	s.mu.Unlock()
+	job.share()
+	return job, nil
+}
+
+// discard drops a job that was rejected before it started.
+func (s *jobStore) discard(id string) {
+	s.mu.Lock()
+	job := s.jobs[id]
+	delete(s.jobs, id)
+	s.mu.Unlock()
+	if job != nil {
+		job.unlock()
+	}
+}
+
+// get returns the job, from the replica running it if it runs on another one.
+func (s *jobStore) get(id string) *provisioningJob {
+	s.mu.RLock()
+	job := s.jobs[id]
+	s.mu.RUnlock()
+	if job != nil || s.shared == nil {
+		return job
+	}
+	ctx, cancel := context.WithTimeout(s.shared.ctx, coordinationRequestTimeout)
+	defer cancel()
+	job, err := s.shared.loadJob(ctx, id)
+	if err != nil {
+		fmt.Printf("Loading shared job %s failed: %v\n", id, err)
+	}
+	return job
+}
+
+// unfinished returns the IDs of the jobs of this replica that didn't finish yet, sorted.
+func (s *jobStore) unfinished() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := []string{}
+	for id, job := range s.jobs {
+		if job.finishedAt() == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// running reports whether a job of this replica works on the participant.
+func (s *jobStore) running(participant string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, job := range s.jobs {
+		if job.Participant == participant && job.finishedAt() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// share publishes the state of the job to the other replicas. Callers don't hold the job's lock.
+func (j *provisioningJob) share() {
+	if j.store == nil || j.store.shared == nil {
+		return
+	}
+	j.mu.Lock()
+	state, err := json.Marshal(j)
+	j.mu.Unlock()
+	if err != nil {
+		fmt.Printf("Sharing job %s failed: %v\n", j.Id, err)
+		return
+	}
+	j.store.shared.saveJob(j.Id, j.Participant, state)
+}
+
+// finish shares the final state of the job and releases its hold of the participant's Lease.
+func (j *provisioningJob) finish() {
+	j.share()
+	j.unlock()
+	j.finished.Do(func() {
+		j.stop(context.Canceled)
+		close(j.done)
+	})
+}
+
+// bind returns the context the job runs in, which ends with ctx or when the job is cancelled.
+func (j *provisioningJob) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(j.cancelled, func() { cancel(context.Cause(j.cancelled)) })
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// cancel stops a queued or running job, its current phase fails with errJobCancelled.
+func (j *provisioningJob) cancel() error {
+	if j.stop == nil {
+		return fiber.NewError(fiber.StatusConflict, "the job runs on another replica")
+	}
+	if j.finishedAt() != nil {
+		return fiber.NewError(fiber.StatusConflict, "the job already finished")
+	}
+	j.stop(errJobCancelled)
+	return nil
+}
+
+// wait blocks until the job finished or the timeout passed and reports whether it finished.
+func (j *provisioningJob) wait(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-j.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// execute runs the steps in order, stopping at the first failing one, and returns its error. The job and each of its
+// phases are traced as spans.
+func (j *provisioningJob) execute(ctx context.Context, steps []jobStep) error {
+	ctx, jobSpan := startSpan(ctx, "job", spanKindInternal)
+	jobSpan.set("job.id", j.Id)
+	jobSpan.set("participant", j.Participant)
+	for _, step := range steps {
+		j.begin(step.phase)
+		phaseCtx, phaseSpan := startSpan(ctx, step.phase, spanKindInternal)
+		err := context.Cause(ctx)
+		if err == nil {
+			err = step.run(phaseCtx)
+		}
+		// the phase reports how the cancellation reached it, e.g. as a failed request
+		if errors.Is(context.Cause(ctx), errJobCancelled) {
+			err = errJobCancelled
+		}
+		phaseSpan.finish(err)
+		j.end(err)
+		if err != nil {
+			jobSpan.finish(err)
+			return err
+		}
+	}
+	jobSpan.finish(nil)
+	return nil
+}
+
+// queue marks the job as waiting for other jobs to finish before it starts.
+func (j *provisioningJob) queue() {
+	defer j.share()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = jobQueued
+}
+
+func (j *provisioningJob) setQueuePosition(position int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.QueuePosition = position
+}
+
+func (j *provisioningJob) queuePosition() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.QueuePosition
+}
+
+// abandon fails a job that never started, e.g. because it was cancelled while queued.
+func (j *provisioningJob) abandon(err error) {
+	defer j.finish()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.Status = jobFailed
+	if errors.Is(err, errJobCancelled) {
+		j.Status = jobCancelled
+	}
+	j.QueuePosition = 0
+	j.Error = "queued: " + err.Error()
+	j.Code = jobErrorCode("", err)
+	j.FinishedAt = &now
+}
+
+func (j *provisioningJob) begin(phase string) {
+	defer j.share()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = jobRunning
+	j.Phase = phase
+	j.Phases = append(j.Phases, jobPhase{Name: phase, Status: jobRunning, StartedAt: time.Now()})
+}
+
+// end completes the current phase, a failed phase fails the whole job.
+func (j *provisioningJob) end(err error) {
+	defer func() {
+		if err != nil {
+			j.finish()
+		} else {
+			j.share()
+		}
+	}()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	current := &j.Phases[len(j.Phases)-1]
+	current.FinishedAt = &now
+	current.Status = jobSucceeded
+	if err != nil {
+		current.Status = jobFailed
+		current.Error = err.Error()
+		j.Status = jobFailed
+		if errors.Is(err, errJobCancelled) {
+			current.Status = jobCancelled
+			j.Status = jobCancelled
+		}
+		j.Error = current.Name + ": " + err.Error()
+		j.Code = jobErrorCode(current.Name, err)
+		j.FinishedAt = &now
+	}
+}
+
+// succeed marks the job as completed after all phases passed.
+func (j *provisioningJob) succeed() {
+	defer j.finish()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.Status = jobSucceeded
+	j.Phase = ""
+	j.FinishedAt = &now
+}
+
+func (j *provisioningJob) setResources(resources []appliedObject) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Resources = resources
+}
+
+func (j *provisioningJob) setChanges(changes []objectChange) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Changes = changes
+}
+
+func (j *provisioningJob) finishedAt() *time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.FinishedAt
+}
+
+func getJob(c *fiber.Ctx) error {
+	job := jobs.get(c.Params("id"))
+	if job == nil {
+		return fiber.NewError(fiber.StatusNotFound, "no such job")
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return c.JSON(job)
+}
+
+// cancelJob stops a queued or running job, e.g. one waiting for a participant provisioned with a wrong ingress host.
+// With teardown=true the participant the job was creating is deleted once the job stopped, like by DELETE
+// /api/v1/resources/:participantName, otherwise the cancelled job is returned. Jobs changing an existing participant
+// can't tear it down.
+func cancelJob(p *provisioner, audit *auditLog, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job := jobs.get(c.Params("id"))
+		if job == nil {
+			return fiber.NewError(fiber.StatusNotFound, "no such job")
+		}
+		teardown := c.QueryBool("teardown")
+		if teardown && !job.creates {
+			return fiber.NewError(fiber.StatusConflict, "only jobs creating a participant can tear it down, delete the participant instead")
+		}
+		if err := job.cancel(); err != nil {
+			return err
+		}
+		namespace := job.Participant
+		owner, err := ownerOf(p.kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditCancel, Tenant: owner, Participant: namespace, JobId: job.Id})
+
+		stopped := job.wait(jobStopTimeout)
+		if !teardown {
+			job.mu.Lock()
+			defer job.mu.Unlock()
+			if !stopped {
+				return c.Status(fiber.StatusAccepted).JSON(job)
+			}
+			return c.JSON(job)
+		}
+		if !stopped {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("the job did not stop within %s, the participant was not torn down", jobStopTimeout))
+		}
+		managed, err := status.IsManagedNamespace(ctx, p.kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			// cancelled before anything was created
+			job.mu.Lock()
+			defer job.mu.Unlock()
+			return c.JSON(job)
+		}
+		return removeParticipant(c, p.kubeClient, ctx, p, audit, namespace, owner)
+	}
+}
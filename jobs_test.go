@@ -0,0 +1,188 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJobExecuteStopsAtFailingPhase(t *testing.T) {
+	job, err := jobs.create(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded := false
+	err = job.execute(context.Background(), []jobStep{
+		{phaseApply, func(context.Context) error { return nil }},
+		{phaseReadiness, func(context.Context) error { return errors.New("timed out") }},
+		{phaseSeeding, func(context.Context) error { seeded = true; return nil }},
+	})
+	if err == nil || seeded {
+		t.Fatalf("expected the job to stop at the readiness phase, err = %v, seeded = %v", err, seeded)
+	}
+	if job.Status != jobFailed || job.Phase != phaseReadiness || job.Error != "readiness: timed out" {
+		t.Errorf("unexpected job state %s/%s/%q", job.Status, job.Phase, job.Error)
+	}
+	if len(job.Phases) != 2 || job.Phases[0].Status != jobSucceeded || job.Phases[1].Status != jobFailed {
+		t.Errorf("unexpected phases %+v", job.Phases)
+	}
+	if jobs.get(job.Id) != job {
+		t.Error("job not retrievable by id")
+	}
+}
+
+func TestJobSucceeds(t *testing.T) {
+	job, err := jobs.create(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.execute(context.Background(), []jobStep{{phaseApply, func(context.Context) error { return nil }}}); err != nil {
+		t.Fatal(err)
+	}
+	job.succeed()
+	if job.Status != jobSucceeded || job.FinishedAt == nil {
+		t.Errorf("unexpected job state %s, finished %v", job.Status, job.FinishedAt)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	job, err := jobs.create(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := job.bind(context.Background())
+	defer stop()
+	seeded := false
+	errs := make(chan error)
+	go func() {
+		errs <- job.execute(ctx, []jobStep{
+			{phaseReadiness, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+			{phaseSeeding, func(context.Context) error { seeded = true; return nil }},
+		})
+	}()
+	if err := job.cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; !errors.Is(err, errJobCancelled) || seeded {
+		t.Fatalf("expected the job to stop at the readiness phase, err = %v, seeded = %v", err, seeded)
+	}
+	if !job.wait(time.Second) {
+		t.Fatal("expected the cancelled job to be finished")
+	}
+	if job.Status != jobCancelled || job.Code != codeJobCancelled || job.Error != "readiness: job cancelled" {
+		t.Errorf("unexpected job state %s/%s/%q", job.Status, job.Code, job.Error)
+	}
+	if err := job.cancel(); err == nil {
+		t.Error("expected a finished job not to be cancellable")
+	}
+}
+
+func TestJobCancelWhileQueued(t *testing.T) {
+	queue := newWorkQueue(1, 0, nil)
+	running, err := jobs.create(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queue.join(running); err != nil {
+		t.Fatal(err)
+	}
+	job, err := jobs.create(context.Background(), "globex", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := queue.join(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := job.bind(context.Background())
+	defer stop()
+	if err := job.cancel(); err != nil {
+		t.Fatal(err)
+	}
+	err = ticket.wait(ctx)
+	if !errors.Is(err, errJobCancelled) {
+		t.Fatalf("expected the cancellation, got %v", err)
+	}
+	job.abandon(err)
+	if job.Status != jobCancelled || job.Code != codeJobCancelled || job.queuePosition() != 0 {
+		t.Errorf("unexpected job state %s/%s at %d", job.Status, job.Code, job.queuePosition())
+	}
+}
+
+func TestCancelJobTeardown(t *testing.T) {
+	previous := deletionGracePeriod
+	deletionGracePeriod = time.Hour
+	t.Cleanup(func() { deletionGracePeriod = previous })
+
+	managed := map[string]string{status.ManagedByLabel: status.ManagedByValue}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: managed, Annotations: map[string]string{protectedAnnotation: "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "globex", Labels: managed}},
+	).Build()
+	ctx := context.Background()
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Delete("/api/v1/jobs/:id", cancelJob(&provisioner{kubeClient: kube, ctx: ctx}, &auditLog{client: kube, namespace: "provisioner"}, ctx))
+
+	running := func(participant string, creates bool) *provisioningJob {
+		t.Helper()
+		job, err := jobs.create(ctx, participant, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		job.creates = creates
+		jobCtx, stop := job.bind(ctx)
+		t.Cleanup(stop)
+		go func() {
+			_ = job.execute(jobCtx, []jobStep{{phaseReadiness, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}})
+		}()
+		return job
+	}
+	cancel := func(job *provisioningJob, query string) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("DELETE", "/api/v1/jobs/"+job.Id+"?"+query, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	recycled := func(name string) bool {
+		t.Helper()
+		namespace := &corev1.Namespace{}
+		if err := kube.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+			t.Fatal(err)
+		}
+		return namespace.Annotations[deleteAtAnnotation] != ""
+	}
+
+	upgrade := running("globex", false)
+	if code := cancel(upgrade, "teardown=true"); code != fiber.StatusConflict || recycled("globex") {
+		t.Errorf("expected jobs changing an existing participant not to tear it down, got %d", code)
+	}
+	if upgrade.wait(0) {
+		t.Error("expected the refused job to keep running")
+	}
+	if code := cancel(running("acme", true), "teardown=true"); code != fiber.StatusConflict || recycled("acme") {
+		t.Errorf("expected the protected participant to be kept, got %d", code)
+	}
+	if code := cancel(running("acme", true), "teardown=true&force=true"); code != fiber.StatusAccepted || !recycled("acme") {
+		t.Errorf("expected the participant to be moved to the recycle bin, got %d", code)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Requests in a row that find the Kubernetes API unreachable before the circuit opens
+const kubeCircuitThreshold = 5
+
+// While the circuit is open, Kubernetes requests fail right away for this period, set with --kube-circuit-cooldown,
+// 0 disables the circuit
+var kubeCircuitCooldown = 10 * time.Second
+
+// Retry-After of 503 responses when the Kubernetes API is unreachable but the circuit isn't open
+const kubeRetryAfter = 5 * time.Second
+
+// errKubeUnavailable fails the Kubernetes requests sent while the circuit is open.
+var errKubeUnavailable = errors.New("the Kubernetes API is not reachable")
+
+// kubeCircuit is the circuit of the provisioner's Kubernetes client, nil when it is disabled
+var kubeCircuit *circuitBreaker
+
+// circuitBreaker fails requests right away once several requests in a row found the API server unreachable, rather
+// than letting every request wait for it to time out. Once the cooldown passed, requests are sent again: the first
+// that is answered closes the circuit, the first that fails opens it again.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns an error while the circuit is open.
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.openUntil) {
+		return fmt.Errorf("%w, retrying in %s", errKubeUnavailable, b.openUntil.Sub(now).Round(time.Second))
+	}
+	return nil
+}
+
+// record counts the request as failure if the API server couldn't be reached, opening the circuit at the threshold,
+// and closes it when the request was answered. Requests that were cancelled by their caller count as neither.
+func (b *circuitBreaker) record(err error, now time.Time) {
+	if err != nil && !kubeUnavailable(err) && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil || !kubeUnavailable(err) {
+		if b.failures >= b.threshold {
+			fmt.Println("Kubernetes API reachable again, closing the circuit")
+		}
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			fmt.Printf("Kubernetes API unreachable for %d requests in a row, failing requests for %s: %v\n", b.failures, b.cooldown, err)
+		}
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// retryAfter returns when the circuit lets requests through again, zero if it is closed.
+func (b *circuitBreaker) retryAfter(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(b.openUntil.Sub(now), 0)
+}
+
+// circuitClient sends the requests of the client through the circuit.
+type circuitClient struct {
+	client.WithWatch
+	circuit *circuitBreaker
+}
+
+func (c circuitClient) call(request func() error) error {
+	if err := c.circuit.allow(time.Now()); err != nil {
+		return err
+	}
+	err := request()
+	c.circuit.record(err, time.Now())
+	return err
+}
+
+func (c circuitClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.call(func() error { return c.WithWatch.Get(ctx, key, obj, opts...) })
+}
+
+func (c circuitClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.call(func() error { return c.WithWatch.List(ctx, list, opts...) })
+}
+
+func (c circuitClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.call(func() error { return c.WithWatch.Create(ctx, obj, opts...) })
+}
+
+func (c circuitClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.call(func() error { return c.WithWatch.Update(ctx, obj, opts...) })
+}
+
+func (c circuitClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.call(func() error { return c.WithWatch.Patch(ctx, obj, patch, opts...) })
+}
+
+func (c circuitClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.call(func() error { return c.WithWatch.Delete(ctx, obj, opts...) })
+}
+
+// setKubeRetryAfter tells clients of a request failing because the Kubernetes API is unreachable when to retry: once
+// the circuit closes, or after a few seconds.
+func setKubeRetryAfter(c *fiber.Ctx) {
+	retryAfter := kubeRetryAfter
+	if kubeCircuit != nil {
+		if open := kubeCircuit.retryAfter(time.Now()); open > 0 {
+			retryAfter = open
+		}
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// statusOrLastKnown returns the status of the participant, or its last known status while the Kubernetes API is
+// unreachable.
+func statusOrLastKnown(c *fiber.Ctx, ctx context.Context, statusChecker *status.StatusChecker, name string, fields []status.Field) (status.ParticipantStatus, error) {
+	participantStatus, err := statusChecker.GetStatus(ctx, name, fields)
+	if err == nil || !kubeUnavailable(err) {
+		return participantStatus, err
+	}
+	last, ok := statusChecker.LastKnown(name, fields)
+	if !ok {
+		return participantStatus, err
+	}
+	setKubeRetryAfter(c)
+	return last, nil
+}
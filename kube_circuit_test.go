@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// flakyClient fails every request with a connection error while down.
+type flakyClient struct {
+	client.WithWatch
+	down  *bool
+	calls *int
+}
+
+func (c flakyClient) failure() error {
+	*c.calls++
+	if *c.down {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return nil
+}
+
+func (c flakyClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.failure(); err != nil {
+		return err
+	}
+	return c.WithWatch.Get(ctx, key, obj, opts...)
+}
+
+func (c flakyClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.failure(); err != nil {
+		return err
+	}
+	return c.WithWatch.List(ctx, list, opts...)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := newCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	breaker.record(unreachable, now)
+	breaker.record(context.Canceled, now)
+	breaker.record(apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "alice"), now)
+	if err := breaker.allow(now); err != nil {
+		t.Fatalf("expected answered requests to reset the failures, got %v", err)
+	}
+	breaker.record(unreachable, now)
+	breaker.record(unreachable, now)
+	err := breaker.allow(now.Add(time.Second))
+	if !kubeUnavailable(err) || problemOf(err).Code != codeKubeUnavailable {
+		t.Fatalf("expected the open circuit to fail requests as unavailable, got %v", err)
+	}
+	if retryAfter := breaker.retryAfter(now.Add(time.Second)); retryAfter != 59*time.Second {
+		t.Errorf("expected requests to be let through after the cooldown, got %s", retryAfter)
+	}
+
+	// after the cooldown a failing request opens the circuit again, an answered one closes it
+	later := now.Add(2 * time.Minute)
+	if err := breaker.allow(later); err != nil {
+		t.Fatalf("expected a request after the cooldown, got %v", err)
+	}
+	breaker.record(unreachable, later)
+	if breaker.allow(later) == nil {
+		t.Fatal("expected the failed request to open the circuit again")
+	}
+	breaker.record(nil, later)
+	if err := breaker.allow(later); err != nil || breaker.retryAfter(later) != 0 {
+		t.Errorf("expected the answered request to close the circuit, got %v", err)
+	}
+}
+
+func TestStatusServedFromLastKnownWhileUnreachable(t *testing.T) {
+	down, calls := false, 0
+	breaker := newCircuitBreaker(kubeCircuitThreshold, time.Minute)
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	cluster := fake.NewClientBuilder().WithScheme(scheme).WithObjects(participantNamespace("alice", "")).Build()
+	kube := circuitClient{WithWatch: flakyClient{WithWatch: cluster, down: &down, calls: &calls}, circuit: breaker}
+	kubeCircuit = breaker
+	defer func() { kubeCircuit = nil }()
+	checker := status.NewStatusChecker(context.Background(), kube, 0)
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/:participantName/status", func(c *fiber.Ctx) error {
+		participantStatus, err := statusOrLastKnown(c, context.Background(), checker, c.Params("participantName"), nil)
+		if err != nil {
+			return err
+		}
+		return c.JSON(participantStatus)
+	})
+	get := func(path string) (int, string, status.ParticipantStatus) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var participantStatus status.ParticipantStatus
+		_ = json.NewDecoder(resp.Body).Decode(&participantStatus)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter), participantStatus
+	}
+
+	if code, _, fresh := get("/alice/status"); code != fiber.StatusOK || fresh.Stale {
+		t.Fatalf("expected the evaluated status, got %d %+v", code, fresh)
+	}
+	down = true
+	code, retryAfter, last := get("/alice/status")
+	if code != fiber.StatusOK || !last.Stale || last.Name != "alice" || retryAfter == "" {
+		t.Errorf("expected the last known status, got %d %+v, Retry-After %q", code, last, retryAfter)
+	}
+	if code, retryAfter, _ := get("/bob/status"); code != fiber.StatusServiceUnavailable || retryAfter == "" {
+		t.Errorf("expected 503 with Retry-After without a known status, got %d %q", code, retryAfter)
+	}
+
+	for i := 0; i < kubeCircuitThreshold; i++ {
+		_ = kube.List(context.Background(), &corev1.NamespaceList{})
+	}
+	sent := calls
+	if err := kube.Get(context.Background(), client.ObjectKey{Name: "alice"}, &corev1.Namespace{}); !kubeUnavailable(err) || calls != sent {
+		t.Errorf("expected the open circuit to fail requests without sending them, got %v after %d calls", err, calls-sent)
+	}
+	if _, retryAfter, _ := get("/bob/status"); retryAfter != "60" {
+		t.Errorf("expected Retry-After to point at the end of the cooldown, got %q", retryAfter)
+	}
+}
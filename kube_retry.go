@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kubeBackoff bounds the retries of Kubernetes requests failing with a transient error, so a hiccup of the API server
+// doesn't leave a half-created participant behind.
+var kubeBackoff = backoff{attempts: 5, initial: time.Second, max: 15 * time.Second}
+
+// withRetries retries the action with backoff while it fails with a transient error. The last error is returned once
+// the attempts are exhausted.
+func withRetries(kubernetesAction action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		for attempt := 1; ; attempt++ {
+			err := kubernetesAction(c, ctx, object)
+			if err == nil || !transientKubeError(err) {
+				return err
+			}
+			if attempt >= kubeBackoff.attempts {
+				return fmt.Errorf("%s/%s failed after %d attempts: %w", object.GetObjectKind().GroupVersionKind().Kind, object.GetName(), attempt, err)
+			}
+			delay := kubeBackoff.delay(attempt)
+			fmt.Printf("%s/%s failed, retrying in %s: %v\n", object.GetObjectKind().GroupVersionKind().Kind, object.GetName(), delay, err)
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+	}
+}
+
+// transientKubeError reports whether a failed Kubernetes request may succeed when sent again.
+func transientKubeError(err error) bool {
+	return kubeUnavailable(err) || apierrors.IsConflict(err) || apierrors.IsInternalError(err)
+}
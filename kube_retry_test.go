@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestWithRetries(t *testing.T) {
+	previous := kubeBackoff
+	kubeBackoff = backoff{attempts: 3, initial: time.Millisecond, max: time.Millisecond}
+	t.Cleanup(func() { kubeBackoff = previous })
+
+	configMap := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "ih-config"}}
+	unavailable := apierrors.NewServiceUnavailable("etcd leader changed")
+	failing := func(failures int, err error) (action, *int) {
+		calls := 0
+		return func(client.Client, context.Context, client.Object) error {
+			calls++
+			if calls <= failures {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	recovering, calls := failing(2, unavailable)
+	if err := withRetries(recovering)(nil, context.Background(), configMap); err != nil || *calls != 3 {
+		t.Errorf("expected the transient failure to be retried, got %v after %d calls", err, *calls)
+	}
+
+	exhausted, calls := failing(5, unavailable)
+	err := withRetries(exhausted)(nil, context.Background(), configMap)
+	if !apierrors.IsServiceUnavailable(err) || !strings.Contains(err.Error(), "after 3 attempts") || *calls != 3 {
+		t.Errorf("expected the last error after 3 attempts, got %v after %d calls", err, *calls)
+	}
+
+	invalid, calls := failing(1, apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "ih-config", nil))
+	if err := withRetries(invalid)(nil, context.Background(), configMap); err == nil || *calls != 1 {
+		t.Errorf("expected an invalid object not to be retried, got %v after %d calls", err, *calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	kubeBackoff.initial, kubeBackoff.max = time.Hour, time.Hour
+	cancelled, _ := failing(5, unavailable)
+	if err := withRetries(cancelled)(nil, ctx, configMap); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the retries to stop with the context, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Requests per second and burst the Kubernetes client sends before client-side throttling delays them, set with
+// --kube-qps and --kube-burst. The client-go defaults of 5 and 10 throttle listing a few hundred participants.
+var (
+	kubeQps   = 50.0
+	kubeBurst = 100
+)
+
+// kubeCache serves the Deployments and Pods read by status checks from informers instead of listing them per
+// participant namespace, set with --kube-cache. kubeCacheSyncPeriod is how often the informers resync.
+var (
+	kubeCache           bool
+	kubeCacheSyncPeriod = 10 * time.Hour
+)
+
+// The informers get this long to list the Deployments and Pods of the cluster at startup
+const kubeCacheSyncTimeout = 2 * time.Minute
+
+// statusConcurrency bounds the participant statuses loaded at the same time by the list and batch status endpoints,
+// set with --status-concurrency.
+var statusConcurrency = 16
+
+// tuneKubeConfig applies the client-side rate limits to the REST config.
+func tuneKubeConfig(config *rest.Config) {
+	config.QPS = float32(kubeQps)
+	config.Burst = kubeBurst
+}
+
+// cachedKinds are the objects read from the informer cache, those status checks list in every participant namespace
+var cachedKinds = []client.Object{&appsv1.Deployment{}, &corev1.Pod{}}
+
+// newInformerCache starts the informers of the cached kinds and waits until they listed the cluster. Managed fields
+// are dropped from the cached objects, which nothing reading them needs.
+func newInformerCache(ctx context.Context, config *rest.Config, scheme *runtime.Scheme) (cache.Cache, error) {
+	informers, err := cache.New(config, cache.Options{
+		Scheme:                      scheme,
+		SyncPeriod:                  &kubeCacheSyncPeriod,
+		ReaderFailOnMissingInformer: true,
+		DefaultTransform:            cache.TransformStripManagedFields(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range cachedKinds {
+		if _, err := informers.GetInformer(ctx, kind); err != nil {
+			return nil, err
+		}
+	}
+	go func() {
+		if err := informers.Start(ctx); err != nil {
+			fmt.Println("Kubernetes informer cache stopped:", err)
+		}
+	}()
+	syncCtx, cancel := context.WithTimeout(ctx, kubeCacheSyncTimeout)
+	defer cancel()
+	if !informers.WaitForCacheSync(syncCtx) {
+		return nil, fmt.Errorf("informer cache not synced within %s", kubeCacheSyncTimeout)
+	}
+	return informers, nil
+}
+
+// cachedClient reads Deployments and Pods from the informer cache, everything else from the API server. Writes and
+// watches always go to the API server.
+type cachedClient struct {
+	client.WithWatch
+	cache cache.Cache
+}
+
+func cached(obj runtime.Object) bool {
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.DeploymentList, *corev1.Pod, *corev1.PodList:
+		return true
+	}
+	return false
+}
+
+func (c cachedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if cached(obj) {
+		return c.cache.Get(ctx, key, obj, opts...)
+	}
+	return c.WithWatch.Get(ctx, key, obj, opts...)
+}
+
+func (c cachedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if cached(list) {
+		return c.cache.List(ctx, list, opts...)
+	}
+	return c.WithWatch.List(ctx, list, opts...)
+}
+
+// loadConcurrently calls load for every name, at most statusConcurrency at a time, and returns the results in the
+// order of the names. The first error is returned once all calls returned.
+func loadConcurrently[T any](names []string, load func(name string) (T, error)) ([]T, error) {
+	results := make([]T, len(names))
+	errs := make([]error, len(names))
+	slots := make(chan struct{}, max(statusConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i], errs[i] = load(name)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTuneKubeConfig(t *testing.T) {
+	config := &rest.Config{}
+	tuneKubeConfig(config)
+	if config.QPS != 50 || config.Burst != 100 {
+		t.Errorf("expected the default rate limits, got %v and %d", config.QPS, config.Burst)
+	}
+}
+
+func TestLoadConcurrently(t *testing.T) {
+	defer func(concurrency int) { statusConcurrency = concurrency }(statusConcurrency)
+	statusConcurrency = 3
+	var running, peak atomic.Int32
+	names := []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace"}
+	loaded, err := loadConcurrently(names, func(name string) (string, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			highest := peak.Load()
+			if current <= highest || peak.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return name + "-status", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded[0] != "alice-status" || loaded[6] != "grace-status" {
+		t.Errorf("expected the results in the order of the names, got %v", loaded)
+	}
+	if peak.Load() > 3 || peak.Load() < 2 {
+		t.Errorf("expected up to 3 concurrent loads, got %d", peak.Load())
+	}
+
+	failed := errors.New("kube unavailable")
+	if _, err := loadConcurrently(names, func(name string) (string, error) {
+		if name == "dave" {
+			return "", failed
+		}
+		return name, nil
+	}); !errors.Is(err, failed) {
+		t.Errorf("expected the failed load to be reported, got %v", err)
+	}
+}
+
+// listCache is an informer cache serving the deployments it was given.
+type listCache struct {
+	cache.Cache
+	deployments []appsv1.Deployment
+}
+
+func (c listCache) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*appsv1.DeploymentList).Items = c.deployments
+	return nil
+}
+
+func TestCachedClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	live := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "alice"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "provisioner-seeding", Namespace: "alice"}},
+	).Build()
+	c := cachedClient{WithWatch: live, cache: listCache{deployments: []appsv1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "cached"}}}}}
+	ctx := context.Background()
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace("alice")); err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments.Items) != 1 || deployments.Items[0].Name != "cached" {
+		t.Errorf("expected the deployments to be listed from the cache, got %v", deployments.Items)
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, client.InNamespace("alice")); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(configMaps.Items))
+	for _, configMap := range configMaps.Items {
+		names = append(names, configMap.Name)
+	}
+	if !slices.Equal(names, []string{"provisioner-seeding"}) {
+		t.Errorf("expected the config maps to be listed from the API server, got %v", names)
+	}
+}
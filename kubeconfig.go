@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// expandHome replaces a leading ~ of the path with the home directory of the user, which shells only do for
+// arguments, not for defaults and environment variables.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// kubeconfigFiles returns the existing files of a kubeconfig path list, separated like KUBECONFIG by the OS's path
+// list separator.
+func kubeconfigFiles(paths string) []string {
+	var files []string
+	for _, path := range filepath.SplitList(paths) {
+		if path == "" {
+			continue
+		}
+		path = expandHome(path)
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("kubeconfig file %s does not exist, skipping it\n", path)
+			continue
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+// loadKubeConfig builds the REST config of the kubeconfig context, the current one of the files when empty. Several
+// files are merged like kubectl does. Without any kubeconfig file the in-cluster config is used, which has no contexts.
+// The context is chosen once at startup: the status cache, job leases and participant records all live in that
+// cluster, so requests can't select another context.
+func loadKubeConfig(paths string, kubeContext string) (*rest.Config, error) {
+	files := kubeconfigFiles(paths)
+	if len(files) == 0 {
+		if kubeContext != "" {
+			return nil, fmt.Errorf("context %s requires a kubeconfig file, none of %q exists", kubeContext, paths)
+		}
+		fmt.Println("No kubeconfig provided, using in-cluster config")
+		return rest.InClusterConfig()
+	}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{Precedence: files},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	)
+	raw, err := loader.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	if kubeContext == "" {
+		kubeContext = raw.CurrentContext
+	}
+	if _, ok := raw.Contexts[kubeContext]; !ok {
+		contexts := make([]string, 0, len(raw.Contexts))
+		for name := range raw.Contexts {
+			contexts = append(contexts, name)
+		}
+		slices.Sort(contexts)
+		return nil, fmt.Errorf("context %q not found in %s, available contexts: %s", kubeContext, strings.Join(files, ", "), strings.Join(contexts, ", "))
+	}
+	fmt.Printf("Load kubeconfig context %s from %s\n", kubeContext, strings.Join(files, ", "))
+	return loader.ClientConfig()
+}
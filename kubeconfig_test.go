@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+`
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("HOME", "/home/operator")
+	tests := map[string]string{
+		"~/.kube/config": "/home/operator/.kube/config",
+		"~":              "/home/operator",
+		"/etc/kube":      "/etc/kube",
+		"~other/config":  "~other/config",
+	}
+	for path, want := range tests {
+		if got := expandHome(path); got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoadKubeConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".kube"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".kube", "config"), []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadKubeConfig("~/.kube/config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://dev.example.com" {
+		t.Errorf("expected the current context, got host %s", config.Host)
+	}
+
+	// Missing files of a list are skipped
+	paths := filepath.Join(home, "missing") + string(os.PathListSeparator) + "~/.kube/config"
+	config, err = loadKubeConfig(paths, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://prod.example.com" {
+		t.Errorf("expected the selected context, got host %s", config.Host)
+	}
+
+	if _, err := loadKubeConfig("~/.kube/config", "staging"); err == nil || !strings.Contains(err.Error(), "dev, prod") {
+		t.Errorf("expected an unknown context to be rejected listing the contexts, got %v", err)
+	}
+	if _, err := loadKubeConfig(filepath.Join(home, "missing"), "prod"); err == nil {
+		t.Error("expected a context without kubeconfig to be rejected")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// Kustomization patches the rendered manifests, e.g. to add node selectors or change service types, with the patch
+// formats and field names of kustomize. Patches matching no object are ignored.
+type Kustomization struct {
+	// PatchesStrategicMerge are partial objects merged into the object of the same kind and name
+	PatchesStrategicMerge []string `json:"patchesStrategicMerge,omitempty"`
+	// PatchesJson6902 are JSON patches applied to the objects matching their target
+	PatchesJson6902 []Json6902Patch `json:"patchesJson6902,omitempty"`
+}
+
+// Json6902Patch applies RFC 6902 operations, given as YAML or JSON, to the objects matching the target.
+type Json6902Patch struct {
+	Target PatchTarget `json:"target"`
+	Patch  string      `json:"patch"`
+}
+
+// PatchTarget selects objects by kind and optionally group, version, name and namespace.
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (t PatchTarget) matches(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return t.Kind == gvk.Kind &&
+		(t.Group == "" || t.Group == gvk.Group) &&
+		(t.Version == "" || t.Version == gvk.Version) &&
+		(t.Name == "" || t.Name == obj.GetName()) &&
+		(t.Namespace == "" || t.Namespace == obj.GetNamespace())
+}
+
+// compiledPatch is a parsed patch of a kustomization.
+type compiledPatch struct {
+	target PatchTarget
+	// merge is set for strategic merge patches, operations for JSON patches
+	merge      []byte
+	operations jsonpatch.Patch
+}
+
+// compile parses the patches, so that invalid ones are rejected before anything is applied.
+func (k *Kustomization) compile() ([]compiledPatch, error) {
+	if k == nil {
+		return nil, nil
+	}
+	var patches []compiledPatch
+	for i, patch := range k.PatchesStrategicMerge {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(patch), &obj.Object); err != nil {
+			return nil, fmt.Errorf("patchesStrategicMerge[%d]: %w", i, err)
+		}
+		if obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("patchesStrategicMerge[%d]: kind and metadata.name select the patched object", i)
+		}
+		merge, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("patchesStrategicMerge[%d]: %w", i, err)
+		}
+		gvk := obj.GroupVersionKind()
+		target := PatchTarget{Group: gvk.Group, Kind: gvk.Kind, Name: obj.GetName(), Namespace: obj.GetNamespace()}
+		patches = append(patches, compiledPatch{target: target, merge: merge})
+	}
+	for i, patch := range k.PatchesJson6902 {
+		if patch.Target.Kind == "" {
+			return nil, fmt.Errorf("patchesJson6902[%d]: target.kind is required", i)
+		}
+		data, err := yaml.YAMLToJSON([]byte(patch.Patch))
+		if err != nil {
+			return nil, fmt.Errorf("patchesJson6902[%d]: %w", i, err)
+		}
+		operations, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, fmt.Errorf("patchesJson6902[%d]: %w", i, err)
+		}
+		patches = append(patches, compiledPatch{target: patch.Target, operations: operations})
+	}
+	return patches, nil
+}
+
+// kustomizationMutator applies the patches of the kustomizations, in order, to the objects they target.
+func kustomizationMutator(kustomizations ...*Kustomization) (objectMutator, error) {
+	var patches []compiledPatch
+	for _, kustomization := range kustomizations {
+		compiled, err := kustomization.compile()
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, compiled...)
+	}
+	return func(obj *unstructured.Unstructured) error {
+		for _, patch := range patches {
+			if !patch.target.matches(obj) {
+				continue
+			}
+			if err := patch.apply(obj); err != nil {
+				return fmt.Errorf("patch %s %s: %w", obj.GetKind(), obj.GetName(), err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+func (p compiledPatch) apply(obj *unstructured.Unstructured) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	switch {
+	case p.operations != nil:
+		patched, err = p.operations.Apply(original)
+	default:
+		// lists of built-in types are merged by their patch strategy, e.g. containers by name, objects of other types
+		// are merged like JSON merge patches, replacing lists
+		typed, schemeErr := scheme.Scheme.New(obj.GroupVersionKind())
+		switch {
+		case schemeErr == nil:
+			patched, err = strategicpatch.StrategicMergePatch(original, p.merge, typed)
+		case runtime.IsNotRegisteredError(schemeErr):
+			patched, err = jsonpatch.MergePatch(original, p.merge)
+		default:
+			err = schemeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return obj.UnmarshalJSON(patched)
+}
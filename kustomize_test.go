@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func parseObject(t *testing.T, manifest string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestKustomizationMutator(t *testing.T) {
+	dataspace := &Kustomization{
+		PatchesStrategicMerge: []string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+spec:
+  template:
+    spec:
+      nodeSelector:
+        pool: connectors
+      containers:
+        - name: controlplane
+          env:
+            - name: EDC_LOG_LEVEL
+              value: debug
+`},
+	}
+	participant := &Kustomization{
+		PatchesJson6902: []Json6902Patch{{
+			Target: PatchTarget{Kind: "Service"},
+			Patch:  "- op: replace\n  path: /spec/type\n  value: NodePort\n",
+		}},
+	}
+	mutator, err := kustomizationMutator(dataspace, participant)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployment := parseObject(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+  namespace: alice
+spec:
+  template:
+    spec:
+      containers:
+        - name: controlplane
+          image: controlplane:0.7.0
+          env:
+            - name: EDC_HOSTNAME
+              value: alice
+`)
+	if err := mutator(deployment); err != nil {
+		t.Fatal(err)
+	}
+	selector, _, _ := unstructured.NestedString(deployment.Object, "spec", "template", "spec", "nodeSelector", "pool")
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if selector != "connectors" || len(containers) != 1 {
+		t.Fatalf("expected the patch to be merged into the deployment, got %v", deployment.Object)
+	}
+	container := containers[0].(map[string]any)
+	if container["image"] != "controlplane:0.7.0" || len(container["env"].([]any)) != 2 {
+		t.Errorf("expected the containers to be merged by name, got %v", container)
+	}
+
+	service := parseObject(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: controlplane\nspec:\n  type: ClusterIP\n")
+	if err := mutator(service); err != nil {
+		t.Fatal(err)
+	}
+	if serviceType, _, _ := unstructured.NestedString(service.Object, "spec", "type"); serviceType != "NodePort" {
+		t.Errorf("expected the JSON patch to change the service type, got %s", serviceType)
+	}
+
+	configMap := parseObject(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: controlplane\ndata:\n  key: value\n")
+	if err := mutator(configMap); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, _ := unstructured.NestedStringMap(configMap.Object, "data"); len(data) != 1 || data["key"] != "value" {
+		t.Errorf("expected objects not targeted to stay unchanged, got %v", data)
+	}
+}
+
+func TestKustomizationMutatorCustomResources(t *testing.T) {
+	mutator, err := kustomizationMutator(&Kustomization{PatchesStrategicMerge: []string{`
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: alice-tls
+spec:
+  dnsNames: [alice.example.com]
+`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := parseObject(t, "apiVersion: cert-manager.io/v1\nkind: Certificate\nmetadata:\n  name: alice-tls\nspec:\n  secretName: alice-tls\n  dnsNames: [alice.local]\n")
+	if err := mutator(certificate); err != nil {
+		t.Fatal(err)
+	}
+	names, _, _ := unstructured.NestedStringSlice(certificate.Object, "spec", "dnsNames")
+	secret, _, _ := unstructured.NestedString(certificate.Object, "spec", "secretName")
+	if len(names) != 1 || names[0] != "alice.example.com" || secret != "alice-tls" {
+		t.Errorf("expected types unknown to the scheme to be merged like JSON merge patches, got %v", certificate.Object)
+	}
+}
+
+func TestInvalidKustomization(t *testing.T) {
+	for name, kustomization := range map[string]*Kustomization{
+		"untargeted merge patch": {PatchesStrategicMerge: []string{"spec:\n  replicas: 2\n"}},
+		"missing target kind":    {PatchesJson6902: []Json6902Patch{{Patch: "[]"}}},
+		"invalid operations":     {PatchesJson6902: []Json6902Patch{{Target: PatchTarget{Kind: "Service"}, Patch: "op: replace"}}},
+	} {
+		if _, err := kustomizationMutator(kustomization); err == nil {
+			t.Errorf("%s: expected the kustomization to be rejected", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "dataspaces.yaml")
+	config := "default:\n  ingressHost: example.com\n  kustomization:\n    patchesJson6902:\n      - patch: '[]'\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadDataspaces(path); err == nil {
+		t.Error("expected an invalid dataspace kustomization to be rejected")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Running jobs get this long to finish after a shutdown signal before they are cancelled
+	defaultShutdownTimeout = 2 * time.Minute
+	// Cancelled jobs and background loops get this long to return before the process exits anyway
+	cancelGracePeriod = 10 * time.Second
+	// Readiness checks fail when the Kubernetes API doesn't answer within this period
+	readinessCheckTimeout = 3 * time.Second
+)
+
+// inFlight tracks the jobs running in the background, shutdown waits for them.
+var inFlight sync.WaitGroup
+
+// runInBackground runs a job in a goroutine tracked by inFlight.
+func runInBackground(work func()) {
+	inFlight.Add(1)
+	go func() {
+		defer inFlight.Done()
+		work()
+	}()
+}
+
+// waitFor waits for the wait group until the timeout and reports whether it completed.
+func waitFor(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// lifecycle tracks whether the provisioner accepts requests and serves its health endpoints.
+type lifecycle struct {
+	kubeClient   client.Client
+	shuttingDown atomic.Bool
+}
+
+func (l *lifecycle) register(app *fiber.App) {
+	// the process is alive as long as it answers
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		if l.shuttingDown.Load() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "shutting down"})
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), readinessCheckTimeout)
+		defer cancel()
+		if err := l.kubeClient.List(ctx, &corev1.NamespaceList{}, client.Limit(1)); err != nil {
+			fmt.Println("Readiness check failed:", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "kubernetes API unavailable"})
+		}
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+}
+
+// shutdown stops accepting requests, waits up to the timeout for running jobs and then cancels the remaining work
+// through cancel. It returns once the jobs returned and drained, which waits for the background loops, completed.
+func (l *lifecycle) shutdown(app *fiber.App, timeout time.Duration, cancel context.CancelFunc, drained func()) {
+	l.shuttingDown.Store(true)
+	fmt.Println("Shutting down, waiting up to", timeout, "for running jobs")
+	deadline := time.Now().Add(timeout)
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		fmt.Println("Closing connections failed:", err)
+	}
+	if !waitFor(&inFlight, time.Until(deadline)) {
+		fmt.Println("Jobs still running after", timeout, "- cancelling them")
+	}
+	cancel()
+	var loops sync.WaitGroup
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		inFlight.Wait()
+		drained()
+	}()
+	if !waitFor(&loops, cancelGracePeriod) {
+		fmt.Println("Background work did not stop within", cancelGracePeriod)
+	}
+}
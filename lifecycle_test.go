@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// unreachableClient fails every list as if the API server was down.
+type unreachableClient struct {
+	client.Client
+}
+
+func (unreachableClient) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return errors.New("connection refused")
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		client       client.Client
+		shuttingDown bool
+		want         int
+	}{
+		{"kubernetes reachable", newNamespaceClient(tenantNamespace("acme", "")), false, fiber.StatusOK},
+		{"kubernetes unreachable", unreachableClient{}, false, fiber.StatusServiceUnavailable},
+		{"shutting down", newNamespaceClient(), true, fiber.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		life := &lifecycle{kubeClient: tt.client}
+		life.shuttingDown.Store(tt.shuttingDown)
+		app := fiber.New()
+		life.register(app)
+		response, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, response.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestShutdownDrainsJobs(t *testing.T) {
+	var finished atomic.Bool
+	runInBackground(func() {
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	})
+	cancelled := false
+	life := &lifecycle{kubeClient: newNamespaceClient()}
+	life.shutdown(fiber.New(), time.Second, func() { cancelled = true }, func() {})
+	if !finished.Load() {
+		t.Error("shutdown returned before the running job finished")
+	}
+	if !cancelled {
+		t.Error("background work was not cancelled")
+	}
+}
+
+func TestShutdownCancelsJobsAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runInBackground(func() {
+		<-ctx.Done()
+	})
+	start := time.Now()
+	life := &lifecycle{kubeClient: newNamespaceClient()}
+	life.shutdown(fiber.New(), 50*time.Millisecond, cancel, func() {})
+	if elapsed := time.Since(start); elapsed > cancelGracePeriod {
+		t.Errorf("shutdown took %s", elapsed)
+	}
+	if ctx.Err() == nil {
+		t.Error("jobs were not cancelled after the timeout")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listPage is the envelope of the responses of list endpoints: the items of the page, the number of items across all
+// pages, the number of the page, counted from 1, and the page size, 0 when all items are returned. Continue is passed
+// as the continue parameter to get the next page and empty on the last one.
+type listPage[T any] struct {
+	Items    []T    `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	Limit    int    `json:"limit"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// pageLink is a page linked in the Link header of a list response, an empty token links the first page.
+type pageLink struct {
+	rel   string
+	token string
+}
+
+// setPageHeaders reports the number of items across all pages in X-Total-Count and links the given pages in a Link
+// header. The links keep the query of the request apart from the continue token.
+func setPageHeaders(c *fiber.Ctx, total int, links ...pageLink) {
+	c.Set("X-Total-Count", strconv.Itoa(total))
+	if len(links) == 0 {
+		return
+	}
+	values := make([]string, 0, len(links))
+	for _, link := range links {
+		params := url.Values{}
+		for key, value := range c.Queries() {
+			params.Set(key, value)
+		}
+		params.Del("continue")
+		if link.token != "" {
+			params.Set("continue", link.token)
+		}
+		target := c.Path()
+		if len(params) > 0 {
+			target += "?" + params.Encode()
+		}
+		values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, target, link.rel))
+	}
+	c.Set(fiber.HeaderLink, strings.Join(values, ", "))
+}
+
+// pageOffset returns the position of the first item of the page of an offset paged list, its continue token.
+func pageOffset(c *fiber.Ctx) (int, error) {
+	token := c.Query("continue")
+	if token == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(token)
+	if err != nil || offset < 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid continue token")
+	}
+	return offset, nil
+}
+
+// offsetPage returns the page of limit items starting at offset, all remaining items when limit is 0, and sets the
+// headers of the response to it.
+func offsetPage[T any](c *fiber.Ctx, items []T, offset int, limit int) listPage[T] {
+	page := listPage[T]{Items: []T{}, Total: len(items), Page: 1, Limit: limit}
+	if limit > 0 {
+		page.Page = offset/limit + 1
+	}
+	if offset < len(items) {
+		end := len(items)
+		if limit > 0 {
+			end = min(offset+limit, len(items))
+		}
+		page.Items = items[offset:end]
+		if end < len(items) {
+			page.Continue = strconv.Itoa(end)
+		}
+	}
+	var links []pageLink
+	if page.Continue != "" {
+		links = append(links, pageLink{rel: "next", token: page.Continue})
+	}
+	if offset > 0 {
+		previous := ""
+		if limit > 0 && offset > limit {
+			previous = strconv.Itoa(offset - limit)
+		}
+		links = append(links, pageLink{rel: "prev", token: previous}, pageLink{rel: "first"})
+	}
+	setPageHeaders(c, page.Total, links...)
+	return page
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOffsetPage(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	app := fiber.New()
+	app.Get("/items", func(c *fiber.Ctx) error {
+		offset, err := pageOffset(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(offsetPage(c, items, offset, c.QueryInt("limit", 0)))
+	})
+	get := func(target string) (listPage[string], string, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page listPage[string]
+		_ = json.NewDecoder(resp.Body).Decode(&page)
+		return page, resp.Header.Get("X-Total-Count"), resp.Header.Get(fiber.HeaderLink)
+	}
+
+	page, total, link := get("/items?limit=2&continue=2")
+	if strings.Join(page.Items, "") != "cd" || page.Total != 5 || page.Page != 2 || page.Limit != 2 || page.Continue != "4" || total != "5" {
+		t.Fatalf("unexpected second page %+v, X-Total-Count %s", page, total)
+	}
+	for _, want := range []string{`</items?continue=4&limit=2>; rel="next"`, `</items?limit=2>; rel="prev"`, `</items?limit=2>; rel="first"`} {
+		if !strings.Contains(link, want) {
+			t.Errorf("expected the Link header to contain %s, got %s", want, link)
+		}
+	}
+
+	page, _, link = get("/items")
+	if len(page.Items) != 5 || page.Page != 1 || page.Continue != "" || link != "" {
+		t.Errorf("expected a single page without links, got %+v %s", page, link)
+	}
+	if page, _, _ = get("/items?limit=2&continue=9"); page.Items == nil || len(page.Items) != 0 {
+		t.Errorf("expected an empty page past the end, got %+v", page)
+	}
+}
+
+func TestListPageSchema(t *testing.T) {
+	schemas := fiber.Map{}
+	schemaOf(reflect.TypeOf(participantPage{}), schemas)
+	schema, ok := schemas["ParticipantPage"].(fiber.Map)
+	if !ok {
+		t.Fatalf("expected the participant page schema, got %v", schemas)
+	}
+	properties := schema["properties"].(fiber.Map)
+	for _, name := range []string{"items", "total", "page", "limit", "continue", "summary"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("expected the schema to have %s, got %v", name, properties)
+		}
+	}
+	if name := schemaName(reflect.TypeOf(listPage[status.Event]{}).Name()); name != "ListPageEvent" {
+		t.Errorf("expected the page of events to be named ListPageEvent, got %s", name)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Namespaced kinds the provisioner applies to participant namespaces
+var provisionedKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "ConfigMap"},
+	{Version: "v1", Kind: "Secret"},
+	{Version: "v1", Kind: "Service"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+	{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"},
+	{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"},
+	{Group: "security.istio.io", Version: "v1beta1", Kind: "PeerAuthentication"},
+	{Group: "networking.istio.io", Version: "v1beta1", Kind: "DestinationRule"},
+}
+
+// liveManifests returns the live objects of a participant the provisioner applied, as YAML with credentials redacted.
+func liveManifests(c client.Client, ctx context.Context, namespace string) (string, error) {
+	var objects []*unstructured.Unstructured
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return "", err
+	}
+	objects = append(objects, ns)
+
+	for _, gvk := range provisionedKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			// mesh and Gateway API resources only exist in clusters running them
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return "", err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+
+	var docs []string
+	for _, obj := range objects {
+		if !appliedBy(obj, fieldOwner) {
+			continue
+		}
+		obj.SetManagedFields(nil)
+		doc, err := yaml.Marshal(redactObject(obj))
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// appliedBy reports whether the manager owns fields of the object through a server-side apply.
+func appliedBy(obj *unstructured.Unstructured, manager string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == manager && entry.Operation == "Apply" {
+			return true
+		}
+	}
+	return false
+}
+
+func getLiveManifests(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		manifests, err := liveManifests(kubeClient, ctx, c.Params("participantName"))
+		if apierrors.IsNotFound(err) {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, "application/yaml")
+		return c.SendString(manifests)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAppliedBy(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]any{}}
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate},
+		{Manager: fieldOwner, Operation: metav1.ManagedFieldsOperationUpdate},
+	})
+	if appliedBy(obj, fieldOwner) {
+		t.Error("object updated, but not applied, by the provisioner reported as applied")
+	}
+	obj.SetManagedFields(append(obj.GetManagedFields(), metav1.ManagedFieldsEntry{Manager: fieldOwner, Operation: metav1.ManagedFieldsOperationApply}))
+	if !appliedBy(obj, fieldOwner) {
+		t.Error("object applied by the provisioner not reported")
+	}
+}
@@ -3,25 +3,34 @@ package main
 
 import (
 	"aruba-provisioner/api"
+	"aruba-provisioner/api/gateway"
+	"aruba-provisioner/api/status"
 	"context"
 	"encoding/base64"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -34,133 +43,875 @@ var participantYaml string
 //go:embed templates/identityhub.yaml
 var identityhubYaml string
 
-// Centralize deployment names used for readiness checks
+//go:embed templates/issuerservice.yaml
+var issuerServiceYaml string
+
+//go:embed templates/federatedcatalog.yaml
+var federatedCatalogYaml string
+
+// Deployments every participant has, they identify participants provisioned before namespaces were labelled and are
+// restarted when their configuration changed
 var participantDeploymentNames = []string{"controlplane", "identityhub", "dataplane"}
 
-const apiKey = "c3VwZXItdXNlcg==.c3VwZXItc2VjcmV0LWtleQo="
+// Interval deployments and terminating namespaces are polled at, set with --readiness-poll-interval
+var readinessPollInterval = 2 * time.Second
+
+// Field manager of the provisioner's server-side applies
+const fieldOwner = "go-provisioner"
+
+// Provisioning jobs fail when the deployments are not ready within this period, set with --readiness-timeout
+var readinessTimeout = 15 * time.Minute
+
+// Provisioning jobs that didn't complete within this period once they started are given up and the participant is
+// reported as FAILED, set with --provisioning-timeout
+var provisioningTimeout = 30 * time.Minute
+
+// Probed ingress routes that don't answer within this period are reported as unreachable
+const defaultStatusProbeTimeout = 3 * time.Second
+
+// The statuses of all participants are checked this often to notice degradations nobody asked about
+const defaultStatusWatchInterval = time.Minute
+
+// deploymentChanges subscribes to the deployment changes of a namespace, nil when deployments aren't watched
+var deploymentChanges func(namespace string) (<-chan struct{}, func())
 
-const readinessPollInterval = 2 * time.Second
+// Watched deployments are still checked at this interval in case a change was missed while the watch reconnected
+const deploymentResyncInterval = 30 * time.Second
 
 func main() {
-	kubeconfig := flag.String("kubeconfig", "~/.kube/config", "Path to kubeconfig file")
+	configFile := flag.String("config", os.Getenv("PROVISIONER_CONFIG"), "Path to a YAML file with settings, overridden by environment variables and flags")
+	listenAddress := flag.String("listen", envOrDefault("PROVISIONER_LISTEN", ":9999"), "Address the HTTP server listens on")
+	kubeconfig := flag.String("kubeconfig", envOrDefault("KUBECONFIG", "~/.kube/config"), "Path to kubeconfig file, or a list of files separated like KUBECONFIG; the in-cluster config is used when none exists")
+	sharedNamespace := flag.String("shared-namespace", os.Getenv("PROVISIONER_SHARED_NAMESPACE"), "Existing namespace all participants are provisioned into, their objects named after them, for clusters where namespaces can't be created")
+	kubeContext := flag.String("context", os.Getenv("PROVISIONER_KUBE_CONTEXT"), "Kubeconfig context all requests are served with, the current context of the kubeconfig when empty. Contexts can't be switched per request, run a provisioner per cluster instead")
+	statusCacheTtl := flag.Duration("status-cache-ttl", envDuration("PROVISIONER_STATUS_CACHE_TTL", status.DefaultCacheTTL), "How long evaluated participant statuses are cached, 0 disables caching")
+	statusProbeUrl := flag.String("status-probe-url", os.Getenv("PROVISIONER_STATUS_PROBE_URL"), "Address of the ingress controller, e.g. http://ingress-nginx-controller.ingress-nginx, the participants' ingress routes are probed through when reporting their status")
+	statusHealthChecks := flag.Bool("status-health-checks", os.Getenv("PROVISIONER_STATUS_HEALTH_CHECKS") == "true", "Report running components whose /api/check/health endpoint reports a failure as degraded")
+	statusProbeTimeout := flag.Duration("status-probe-timeout", envDuration("PROVISIONER_STATUS_PROBE_TIMEOUT", defaultStatusProbeTimeout), "Time a probed ingress route or health endpoint gets to answer")
+	notificationWebhook := flag.String("notification-webhook", os.Getenv("PROVISIONER_NOTIFICATION_WEBHOOK"), "URL lifecycle events are POSTed to")
+	notificationSlackWebhook := flag.String("notification-slack-webhook", os.Getenv("PROVISIONER_NOTIFICATION_SLACK_WEBHOOK"), "Slack incoming webhook URL lifecycle events are posted to as messages")
+	notificationSmtpServer := flag.String("notification-smtp-server", os.Getenv("PROVISIONER_NOTIFICATION_SMTP_SERVER"), "SMTP server, as host:port, lifecycle events are mailed through")
+	notificationSmtpUsername := flag.String("notification-smtp-username", os.Getenv("PROVISIONER_NOTIFICATION_SMTP_USERNAME"), "Username the SMTP server is authenticated with")
+	notificationSmtpPassword := flag.String("notification-smtp-password", os.Getenv("PROVISIONER_NOTIFICATION_SMTP_PASSWORD"), "Password of --notification-smtp-username")
+	notificationEmailFrom := flag.String("notification-email-from", envOrDefault("PROVISIONER_NOTIFICATION_EMAIL_FROM", "aruba-provisioner@localhost"), "Sender of notification mails")
+	notificationEmailTo := flag.String("notification-email-to", os.Getenv("PROVISIONER_NOTIFICATION_EMAIL_TO"), "Comma separated recipients of notification mails")
+	eventsNatsUrl := flag.String("events-nats-url", os.Getenv("PROVISIONER_EVENTS_NATS_URL"), "NATS server lifecycle events are published to, e.g. nats://token@nats.nats:4222")
+	eventsNatsSubject := flag.String("events-nats-subject", envOrDefault("PROVISIONER_EVENTS_NATS_SUBJECT", "aruba.provisioner"), "Subject prefix of the published lifecycle events, followed by the event type")
+	eventsKafkaRestUrl := flag.String("events-kafka-rest-url", os.Getenv("PROVISIONER_EVENTS_KAFKA_REST_URL"), "Kafka REST proxy lifecycle events are published through, e.g. http://kafka-rest.kafka:8082")
+	eventsKafkaTopic := flag.String("events-kafka-topic", envOrDefault("PROVISIONER_EVENTS_KAFKA_TOPIC", "aruba-provisioner-events"), "Kafka topic the lifecycle events are published to")
+	notificationEvents := flag.String("notification-events", os.Getenv("PROVISIONER_NOTIFICATION_EVENTS"), "Comma separated lifecycle event types delivered to the webhook, Slack and mail, e.g. participant.degraded,participant.failed, all when empty")
+	ha := flag.Bool("ha", os.Getenv("PROVISIONER_HA") == "true", "Coordinate with other replicas of the provisioner through Leases, so they can run behind a load balancer")
+	haLeaseDuration := flag.Duration("ha-lease-duration", envDuration("PROVISIONER_HA_LEASE_DURATION", defaultLeaseDuration), "Time after which the Leases of a replica that stopped renewing them are taken over")
+	haNamespace := flag.String("ha-namespace", envOrDefault("PROVISIONER_HA_NAMESPACE", envOrDefault("POD_NAMESPACE", "mvd-provisioner")), "Namespace the Leases and shared jobs of the replicas are kept in")
+	statusWatchInterval := flag.Duration("status-watch-interval", envDuration("PROVISIONER_STATUS_WATCH_INTERVAL", defaultStatusWatchInterval), "Interval the statuses of all participants are checked at to notify about degradations and failures, 0 disables it")
+	callbackBaseUrl := flag.String("callback-base-url", os.Getenv("PROVISIONER_CALLBACK_BASE_URL"), "URL under which connectors reach the provisioner to report negotiation and transfer events")
+	httpConfigFile := flag.String("http-config", os.Getenv("PROVISIONER_HTTP_CONFIG"), "Path to a YAML file with proxy, CA and timeout settings for the seeding HTTP clients")
+	dataspaceConfigFile := flag.String("dataspace-config", os.Getenv("PROVISIONER_DATASPACE_CONFIG"), "Path to a YAML file with per-dataspace settings, e.g. authentication of the seeding HTTP clients")
+	strictPayloads := flag.Bool("strict-payloads", os.Getenv("PROVISIONER_STRICT_PAYLOADS") == "true", "Reject request bodies with unknown fields")
+	manifestSource := flag.String("manifests", os.Getenv("PROVISIONER_MANIFESTS"), "Directory, URL or configmap:<namespace>/<name> the participant manifests are loaded from instead of the embedded ones, or helm:<chart> to render participants from a Helm chart")
+	flavorsFile := flag.String("flavors", os.Getenv("PROVISIONER_FLAVORS"), "Path to a YAML file with named participant stacks, each with its own manifests and seed directory, selected by the flavor of participants")
+	helmBinary := flag.String("helm-binary", envOrDefault("PROVISIONER_HELM_BINARY", "helm"), "Helm executable charts given with --manifests helm:<chart> are rendered with")
+	helmChartVersion := flag.String("helm-chart-version", os.Getenv("PROVISIONER_HELM_CHART_VERSION"), "Version of the Helm chart, the latest when empty")
+	helmValues := flag.String("helm-values", os.Getenv("PROVISIONER_HELM_VALUES"), "Values file shared by all participants rendered from the Helm chart, e.g. naming the deployments controlplane, dataplane and identityhub")
+	adminApiKey := flag.String("admin-api-key", os.Getenv("PROVISIONER_ADMIN_API_KEY"), "API key required for admin endpoints such as the component proxy")
+	apiKeys := flag.String("api-keys", os.Getenv("PROVISIONER_API_KEYS"), "Comma separated API keys accepted as bearer tokens on /api/v1, keys given as tenant:key are scoped to the participants of the tenant")
+	oidcIssuer := flag.String("oidc-issuer", os.Getenv("PROVISIONER_OIDC_ISSUER"), "Issuer URL of the OpenID Connect provider whose bearer tokens are accepted on /api/v1")
+	oidcAudience := flag.String("oidc-audience", os.Getenv("PROVISIONER_OIDC_AUDIENCE"), "Audience OIDC bearer tokens must be issued for")
+	oidcTenantClaim := flag.String("oidc-tenant-claim", os.Getenv("PROVISIONER_OIDC_TENANT_CLAIM"), "OIDC claim scoping callers to the participants of their tenant")
+	authExempt := flag.String("auth-exempt", envOrDefault("PROVISIONER_AUTH_EXEMPT", "/api/v1/callbacks/,/api/v1/openapi.json"), "Comma separated path prefixes under /api/v1 served without authentication, e.g. health or badge endpoints")
+	idempotencyWindow := flag.Duration("idempotency-window", envDuration("PROVISIONER_IDEMPOTENCY_WINDOW", defaultIdempotencyWindow), "Time the responses of POST and DELETE requests with an Idempotency-Key are replayed to retries for, 0 disables idempotency keys")
+	rateLimitPerMinute := flag.Int("rate-limit", envInt("PROVISIONER_RATE_LIMIT", defaultRateLimit), "Requests per minute each client may send to /api/v1, 0 disables rate limiting")
+	auditNamespace := flag.String("audit-namespace", envOrDefault("PROVISIONER_AUDIT_NAMESPACE", envOrDefault("POD_NAMESPACE", "mvd-provisioner")), "Namespace the audit log of provisioning actions is stored in")
+	otlpEndpoint := flag.String("otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "Base URL of an OTLP/HTTP collector traces are exported to, e.g. http://tempo:4318")
+	shutdownTimeout := flag.Duration("shutdown-timeout", envDuration("PROVISIONER_SHUTDOWN_TIMEOUT", defaultShutdownTimeout), "Time running jobs get to finish on SIGTERM before they are cancelled")
+	maxConcurrentProvisionings := flag.Int("max-concurrent-provisionings", envInt("PROVISIONER_MAX_CONCURRENT_PROVISIONINGS", defaultMaxConcurrentProvisionings), "Provisioning and seeding jobs running at a time, further jobs are queued, 0 disables the limit")
+	maxQueuedProvisionings := flag.Int("max-queued-provisionings", envInt("PROVISIONER_MAX_QUEUED_PROVISIONINGS", defaultMaxQueuedProvisionings), "Jobs waiting for a free slot before further requests are rejected with 503, 0 disables the limit")
+	maxParticipants := flag.Int("max-participants", envInt("PROVISIONER_MAX_PARTICIPANTS", 0), "Participants the provisioner manages at most, 0 disables the limit, admins can change it at /api/v1/quotas")
+	maxParticipantsPerTenant := flag.Int("max-participants-per-tenant", envInt("PROVISIONER_MAX_PARTICIPANTS_PER_TENANT", 0), "Participants each tenant may have at most, 0 disables the limit, admins can change it at /api/v1/quotas")
+	bodyLimit := flag.Int("body-limit", envInt("PROVISIONER_BODY_LIMIT", defaultBodyLimit), "Maximum size of request bodies in bytes")
+	tlsCertFile := flag.String("tls-cert-file", os.Getenv("PROVISIONER_TLS_CERT_FILE"), "PEM certificate chain the API is served with over TLS, reloaded when it changes")
+	tlsKeyFile := flag.String("tls-key-file", os.Getenv("PROVISIONER_TLS_KEY_FILE"), "PEM private key of --tls-cert-file")
+	tlsSecret := flag.String("tls-secret", os.Getenv("PROVISIONER_TLS_SECRET"), "kubernetes.io/tls Secret, as namespace/name or name in the provisioner's namespace, the API is served with instead of certificate files")
+	tlsClientCaFile := flag.String("tls-client-ca-file", os.Getenv("PROVISIONER_TLS_CLIENT_CA_FILE"), "PEM bundle of the CAs whose client certificates authenticate callers")
+	tlsRequireClientCert := flag.Bool("tls-require-client-cert", os.Getenv("PROVISIONER_TLS_REQUIRE_CLIENT_CERT") == "true", "Reject TLS connections without a client certificate issued by --tls-client-ca-file")
+	flag.DurationVar(&readinessTimeout, "readiness-timeout", envDuration("PROVISIONER_READINESS_TIMEOUT", readinessTimeout), "Time the deployments of a participant get to become ready")
+	flag.BoolVar(&ingressProbe, "ingress-probe", os.Getenv("PROVISIONER_INGRESS_PROBE") == "true", "Send a request to the ingress host of participants before they are declared ready, to report hosts no ingress controller answers at")
+	flag.BoolVar(&participantRbac, "participant-rbac", os.Getenv("PROVISIONER_PARTICIPANT_RBAC") == "true", "Run the connector components of participants with a ServiceAccount of their own, only allowed to read the connector secrets")
+	flag.DurationVar(&deletionGracePeriod, "deletion-grace-period", envDuration("PROVISIONER_DELETION_GRACE_PERIOD", deletionGracePeriod), "Time deleted participants stay in the recycle bin, restorable, before they are torn down, 0 tears them down right away")
+	flag.DurationVar(&provisioningTimeout, "provisioning-timeout", envDuration("PROVISIONER_PROVISIONING_TIMEOUT", provisioningTimeout), "Time a provisioning job gets to apply, wait for and seed the participant before it is marked FAILED, 0 disables it")
+	flag.StringVar(&postgresStorageClass, "postgres-storage-class", os.Getenv("PROVISIONER_POSTGRES_STORAGE_CLASS"), "StorageClass of the participants' postgres volume claims unless their definition selects one, by default the cluster's default StorageClass")
+	criticalDeploymentList := flag.String("critical-deployments", envOrDefault("PROVISIONER_CRITICAL_DEPLOYMENTS", strings.Join(status.CriticalDeployments, ",")), "Comma separated deployments a participant is degraded without, those a participant wasn't provisioned with aren't required of it")
+	seedBundleHosts := flag.String("seed-bundle-hosts", os.Getenv("PROVISIONER_SEED_BUNDLE_HOSTS"), "Comma separated hosts the seed bundles of catalogs may be fetched from, .example.com allowing a domain; by default any host resolving to public addresses")
+	readinessDeploymentList := flag.String("readiness-deployments", os.Getenv("PROVISIONER_READINESS_DEPLOYMENTS"), "Comma separated deployments jobs wait for to become ready, by default all deployments of the rendered manifests")
+	flag.DurationVar(&apiReadinessTimeout, "api-readiness-timeout", envDuration("PROVISIONER_API_READINESS_TIMEOUT", apiReadinessTimeout), "Time the management and identity APIs of a participant get to answer once its deployments are ready before seeding fails, 0 seeds right away")
+	flag.DurationVar(&kubeCircuitCooldown, "kube-circuit-cooldown", envDuration("PROVISIONER_KUBE_CIRCUIT_COOLDOWN", kubeCircuitCooldown), "Time Kubernetes requests fail right away once the API server was unreachable for several requests in a row, 0 disables the circuit breaker")
+	flag.Float64Var(&kubeQps, "kube-qps", envFloat("PROVISIONER_KUBE_QPS", kubeQps), "Requests per second sent to the Kubernetes API server before client-side throttling delays them")
+	flag.IntVar(&kubeBurst, "kube-burst", envInt("PROVISIONER_KUBE_BURST", kubeBurst), "Requests sent to the Kubernetes API server in a burst above --kube-qps")
+	flag.BoolVar(&kubeCache, "kube-cache", os.Getenv("PROVISIONER_KUBE_CACHE") == "true", "Serve the Deployments and Pods read by status checks from informers watching the cluster, instead of listing them per participant, requires list and watch of both cluster-wide")
+	flag.DurationVar(&kubeCacheSyncPeriod, "kube-cache-sync-period", envDuration("PROVISIONER_KUBE_CACHE_SYNC_PERIOD", kubeCacheSyncPeriod), "Interval the informers of --kube-cache resync their objects at")
+	flag.IntVar(&statusConcurrency, "status-concurrency", envInt("PROVISIONER_STATUS_CONCURRENCY", statusConcurrency), "Participant statuses loaded at the same time when listing participants or fetching a batch of statuses")
+	flag.DurationVar(&readinessPollInterval, "readiness-poll-interval", envDuration("PROVISIONER_READINESS_POLL_INTERVAL", readinessPollInterval), "Interval deployments are polled at while waiting for them to become ready")
+	flag.StringVar(&defaultCredentials.ManagementApiKey, "management-api-key", envOrDefault("PROVISIONER_MANAGEMENT_API_KEY", defaultCredentials.ManagementApiKey), "Management API key of participants whose keys were never rotated or generated")
+	flag.StringVar(&credentialGeneration, "credential-generator", envOrDefault("PROVISIONER_CREDENTIAL_GENERATOR", credentialGeneration), "How new participants get their management API key: random gives each a key of its own, static the key of --management-api-key")
+	flag.StringVar(&defaultIssuer.Url, "issuer-url", os.Getenv("PROVISIONER_ISSUER_URL"), "Base URL of the issuer admin API participants are registered with, by default the issuer behind the participant's ingress host")
+	flag.StringVar(&defaultIssuer.Did, "issuer-did", envOrDefault("PROVISIONER_ISSUER_DID", defaultIssuer.Did), "DID of the issuer participants are registered with")
+	flag.StringVar(&defaultIssuer.ApiKey, "issuer-api-key", envOrDefault("PROVISIONER_ISSUER_API_KEY", defaultIssuer.ApiKey), "API key of the issuer admin API")
+	flag.BoolVar(&defaultIssuer.Disabled, "disable-issuer", os.Getenv("PROVISIONER_DISABLE_ISSUER") == "true", "Don't register participants with an issuer")
+	flag.StringVar(&didWebHost, "did-web-host", os.Getenv("PROVISIONER_DID_WEB_HOST"), "Host, with optional port, the provisioner serves did:web documents at, e.g. dids.example.com; participants without a DID get did:web:<host>:<participant>")
+	flag.StringVar(&defaultVault.Url, "vault-url", os.Getenv("PROVISIONER_VAULT_URL"), "Address of a HashiCorp Vault keeping the connector secrets instead of a development Vault per participant")
+	flag.StringVar(&defaultVault.Token, "vault-token", os.Getenv("PROVISIONER_VAULT_TOKEN"), "Token the provisioner manages the participants' policies, AppRoles and secrets in the Vault with")
+	flag.StringVar(&defaultVault.Namespace, "vault-namespace", os.Getenv("PROVISIONER_VAULT_NAMESPACE"), "Vault Enterprise namespace participants are set up in")
+	flag.BoolVar(&defaultVault.ParticipantNamespaces, "vault-participant-namespaces", os.Getenv("PROVISIONER_VAULT_PARTICIPANT_NAMESPACES") == "true", "Set every participant up in a Vault Enterprise namespace of its own")
+	flag.StringVar(&defaultVault.Mount, "vault-mount", envOrDefault("PROVISIONER_VAULT_MOUNT", defaultVault.Mount), "Path of the KV v2 engine keeping the connector secrets")
+	flag.StringVar(&defaultRouting, "routing", envOrDefault("PROVISIONER_ROUTING", defaultRouting), "Route the participants' APIs with Ingresses (ingress) or Gateway API HTTPRoutes (gateway)")
+	flag.StringVar(&defaultGateway.Name, "gateway-name", os.Getenv("PROVISIONER_GATEWAY_NAME"), "Shared Gateway the participants' HTTPRoutes are attached to")
+	flag.StringVar(&defaultGateway.Namespace, "gateway-namespace", os.Getenv("PROVISIONER_GATEWAY_NAMESPACE"), "Namespace of --gateway-name")
+	flag.StringVar(&defaultGateway.SectionName, "gateway-section-name", os.Getenv("PROVISIONER_GATEWAY_SECTION_NAME"), "Listener of --gateway-name the HTTPRoutes are attached to, all listeners if empty")
+	flag.StringVar(&defaultGateway.ClassName, "gateway-class", os.Getenv("PROVISIONER_GATEWAY_CLASS"), "GatewayClass of the Gateway created per participant when no shared Gateway is named")
+	flag.StringVar(&defaultRegistry.Mirror, "image-registry", os.Getenv("PROVISIONER_IMAGE_REGISTRY"), "Registry, with an optional path prefix, e.g. registry.local:5000/mirror, the images of the participants are pulled from instead of their public registries")
+	flag.StringVar(&defaultRegistry.PullSecret, "image-pull-secret", os.Getenv("PROVISIONER_IMAGE_PULL_SECRET"), "Image pull secret added to the participants' pods, it has to exist in their namespaces unless --image-registry-username is given")
+	flag.StringVar(&defaultRegistry.Username, "image-registry-username", os.Getenv("PROVISIONER_IMAGE_REGISTRY_USERNAME"), "Username of --image-registry the --image-pull-secret is created with in every participant namespace")
+	flag.StringVar(&defaultRegistry.Password, "image-registry-password", os.Getenv("PROVISIONER_IMAGE_REGISTRY_PASSWORD"), "Password of --image-registry-username")
+	impersonateCallers := flag.Bool("impersonate", os.Getenv("PROVISIONER_IMPERSONATE") == "true", "Create and delete the resources of participants as the authenticated OIDC or client certificate user, so the cluster's audit log attributes them to the requester, requires the impersonate verb")
+	impersonationPrefix := flag.String("impersonation-prefix", os.Getenv("PROVISIONER_IMPERSONATION_PREFIX"), "Prefix of the impersonated user and group names, e.g. oidc: matching the API server's OIDC username prefix, required by --impersonate")
+	impersonationServiceAccountIssuer := flag.String("impersonation-service-account-issuer", os.Getenv("PROVISIONER_IMPERSONATION_SERVICE_ACCOUNT_ISSUER"), "Issuer of the cluster's ServiceAccount tokens, e.g. https://kubernetes.default.svc; callers with its tokens are impersonated as their ServiceAccount instead of a prefixed user")
+	janitorInterval := flag.Duration("janitor-interval", envDuration("PROVISIONER_JANITOR_INTERVAL", 0), "Interval the janitor looks for failed, interrupted and orphaned participants at, 0 disables it")
+	janitorPolicy := flag.String("janitor-policy", envOrDefault("PROVISIONER_JANITOR_POLICY", janitorReport), "What the janitor does about stuck participants: report them, repair those whose components are ready, or delete the ones it can't repair")
+	janitorGrace := flag.Duration("janitor-grace", envDuration("PROVISIONER_JANITOR_GRACE", defaultJanitorGrace), "Time the state of a participant has to be unchanged before the janitor touches it")
+	admissionWebhook := flag.String("admission-webhook", os.Getenv("PROVISIONER_ADMISSION_WEBHOOK"), "URL of a webhook or Open Policy Agent decision, e.g. http://opa:8181/v1/data/provisioner/admission, every participant definition is reviewed by before it is provisioned")
+	admissionTimeout := flag.Duration("admission-timeout", envDuration("PROVISIONER_ADMISSION_TIMEOUT", defaultAdmissionTimeout), "Time the admission webhook gets to decide, definitions are rejected when it doesn't")
+	meshProvider := flag.String("mesh", os.Getenv("PROVISIONER_MESH"), "Service mesh, istio or linkerd, participants are enrolled into unless their definition or dataspace sets mesh options")
+	meshMtlsMode := flag.String("mesh-mtls-mode", os.Getenv("PROVISIONER_MESH_MTLS_MODE"), "mTLS mode, STRICT or PERMISSIVE, of participants enrolled with --mesh")
+	meshExcludeInboundPorts := flag.String("mesh-exclude-inbound-ports", os.Getenv("PROVISIONER_MESH_EXCLUDE_INBOUND_PORTS"), "Comma separated ports whose inbound traffic bypasses the sidecars of participants enrolled with --mesh, e.g. the DSP port for counterparts outside the mesh")
+	meshExcludeOutboundPorts := flag.String("mesh-exclude-outbound-ports", os.Getenv("PROVISIONER_MESH_EXCLUDE_OUTBOUND_PORTS"), "Comma separated ports whose outbound traffic bypasses the sidecars of participants enrolled with --mesh")
+	managementApiKeyFile := flag.String("management-api-key-file", os.Getenv("PROVISIONER_MANAGEMENT_API_KEY_FILE"), "File the management API key is read from instead of --management-api-key, e.g. a mounted Secret")
+	identityApiKeyFile := flag.String("identity-api-key-file", os.Getenv("PROVISIONER_IDENTITY_API_KEY_FILE"), "File the identity hub super-user key is read from instead of --identity-api-key")
+	issuerCredentials := flag.String("issuer-credentials", envOrDefault("PROVISIONER_ISSUER_CREDENTIALS", strings.Join(defaultIssuer.Credentials, ",")), "Comma separated verifiable credential types requested for participants once they are registered with the issuer, empty to skip")
+	flag.StringVar(&seedDir, "seed-dir", os.Getenv("PROVISIONER_SEED_DIR"), "Directory, e.g. a mounted ConfigMap, whose *.json assets, policy definitions and contract definitions participants are seeded with instead of the embedded ones, and whose <participant>.<name>.jwt presigned credentials are stored in the participant's identity hub, read on every seeding")
+	flag.StringVar(&defaultSecretStore, "secret-store", envOrDefault("PROVISIONER_SECRET_STORE", defaultSecretStore), "Store connector secrets created while seeding are written to: vault or kubernetes")
+	flag.StringVar(&defaultCredentials.IdentityApiKey, "identity-api-key", envOrDefault("PROVISIONER_IDENTITY_API_KEY", defaultCredentials.IdentityApiKey), "Identity hub super-user key participants are bootstrapped with")
 	flag.Parse()
-
-	konfig := &rest.Config{}
-	exists := true
-	if kubeconfig == nil {
-		exists = false
-	} else if _, err := os.Stat(*kubeconfig); errors.Is(err, os.ErrNotExist) {
-		fmt.Printf("kubeconfig file %s does not exist, falling back to in-cluster config\n", *kubeconfig)
-		exists = false
+	if *configFile != "" {
+		if err := applyConfigFile(flag.CommandLine, *configFile); err != nil {
+			log.Fatalf("load config: %v", err)
+		}
 	}
-	if exists {
-
-		// Load kubeconfig (or use in-cluster if applicable)
-		fmt.Println("Load kubeconfig from ", *kubeconfig, "")
-		cfg, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	defaultIssuer.Credentials = splitList(*issuerCredentials)
+	readinessDeployments = splitList(*readinessDeploymentList)
+	status.CriticalDeployments = splitList(*criticalDeploymentList)
+	status.SharedNamespace = *sharedNamespace
+	if err := validateSecretStore(defaultSecretStore); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateCredentialGenerator(credentialGeneration); err != nil {
+		log.Fatal(err)
+	}
+	if err := defaultRegistry.validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateJanitorPolicy(*janitorPolicy); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := defaultCatalog(seedDir); err != nil {
+		log.Fatal(err)
+	}
+	if *meshProvider != "" {
+		if status.SharedNamespace != "" {
+			log.Fatal("--mesh is not supported in the shared namespace")
+		}
+		inbound, err := parsePorts(*meshExcludeInboundPorts)
 		if err != nil {
-			log.Fatalf("load kubeconfig: %v", err)
+			log.Fatalf("--mesh-exclude-inbound-ports: %v", err)
 		}
-		konfig = cfg
-	} else {
-		fmt.Println("No kubeconfig provided, using in-cluster config", "")
-		cfg, err := rest.InClusterConfig()
+		outbound, err := parsePorts(*meshExcludeOutboundPorts)
 		if err != nil {
-			log.Fatalf("load in-cluster config: %v", err)
+			log.Fatalf("--mesh-exclude-outbound-ports: %v", err)
+		}
+		defaultMesh = &MeshOptions{Provider: *meshProvider, MTLSMode: *meshMtlsMode, ExcludeInboundPorts: inbound, ExcludeOutboundPorts: outbound}
+		if err := defaultMesh.validate(); err != nil {
+			log.Fatal(err)
 		}
-		konfig = cfg
+	}
+	if err := loadKeyFiles(*managementApiKeyFile, *identityApiKeyFile); err != nil {
+		log.Fatalf("load API keys: %v", err)
 	}
 
-	ctx := context.Background()
+	konfig, err := loadKubeConfig(*kubeconfig, *kubeContext)
+	if err != nil {
+		log.Fatalf("load kubeconfig: %v", err)
+	}
+	tuneKubeConfig(konfig)
+	if *impersonateCallers {
+		impersonate(konfig)
+	}
+
+	// ctx is cancelled on shutdown once running jobs finished or the shutdown timeout passed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Scheme with core types
 	// --- Prepare scheme ---
 	scheme := runtime.NewScheme()
 	_ = appsv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
 	_ = networkingv1.AddToScheme(scheme)
-
-	kubeClient, err := client.New(konfig, client.Options{Scheme: scheme})
+	_ = rbacv1.AddToScheme(scheme)
+	_ = schedulingv1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = gateway.AddToScheme(scheme)
+	_ = coordinationv1.AddToScheme(scheme)
+
+	traces = newTracer(*otlpEndpoint, envOrDefault("OTEL_SERVICE_NAME", "aruba-provisioner"))
+	kubeClient, err := client.NewWithWatch(konfig, client.Options{Scheme: scheme})
 	if err != nil {
 		log.Fatalf("create client: %v", err)
 	}
+	if kubeCircuitCooldown > 0 {
+		kubeCircuit = newCircuitBreaker(kubeCircuitThreshold, kubeCircuitCooldown)
+		kubeClient = circuitClient{WithWatch: kubeClient, circuit: kubeCircuit}
+	}
+	if traces != nil {
+		kubeClient = tracedClient{kubeClient}
+	}
+	// status checks read through the informer cache, everything else sees the writes of the provisioner right away
+	statusClient := kubeClient
+	if kubeCache {
+		informers, err := newInformerCache(ctx, konfig, scheme)
+		if err != nil {
+			log.Fatalf("start informer cache: %v", err)
+		}
+		statusClient = cachedClient{WithWatch: kubeClient, cache: informers}
+	}
+	if status.SharedNamespace != "" {
+		if err := kubeClient.Get(ctx, client.ObjectKey{Name: status.SharedNamespace}, &corev1.Namespace{}); err != nil {
+			log.Fatalf("shared namespace %s: %v", status.SharedNamespace, err)
+		}
+		fmt.Println("Provisioning all participants into the shared namespace", status.SharedNamespace)
+		kubeClient = newSharedNamespaceClient(kubeClient, status.SharedNamespace, *auditNamespace)
+		statusClient = newSharedNamespaceClient(statusClient, status.SharedNamespace, *auditNamespace)
+	}
+	podLogs, err := newPodLogReader(konfig)
+	if err != nil {
+		log.Fatalf("create pod log reader: %v", err)
+	}
 
-	app := fiber.New()
+	httpConfig, err := loadHttpConfig(*httpConfigFile)
+	if err != nil {
+		log.Fatalf("load http config: %v", err)
+	}
+	dataspaces, err := loadDataspaces(*dataspaceConfigFile)
+	if err != nil {
+		log.Fatalf("load dataspace config: %v", err)
+	}
+	clients, err := newSeedingClients(httpConfig, dataspaces)
+	if err != nil {
+		log.Fatalf("create http clients: %v", err)
+	}
+	seedBundles = seedBundleFetcher{client: clients.client(targetBundle), hosts: splitList(*seedBundleHosts)}
+
+	if flavorConfigs, err = loadFlavors(*flavorsFile); err != nil {
+		log.Fatalf("load flavors: %v", err)
+	}
+	manifests := newManifestStore(*manifestSource, kubeClient)
+	manifests.helm = helmChart{binary: *helmBinary, version: *helmChartVersion, valuesFile: *helmValues}
+	flavors := newStackFlavors(manifests, flavorConfigs, kubeClient)
+	if err := warmUpTemplates(ctx, flavors); err != nil {
+		log.Fatalf("load templates: %v", err)
+	}
+
+	statusChecker := status.NewStatusChecker(ctx, statusClient, *statusCacheTtl)
+	statusChecker.EnableHistory()
+	if *statusProbeUrl != "" {
+		statusChecker.EnableProbes(*statusProbeUrl, *statusProbeTimeout)
+	}
+	if *statusHealthChecks {
+		statusChecker.EnableHealthChecks(*statusProbeTimeout)
+	}
+	statusChecker.EnableCredentials(heldCredentialLookup(kubeClient, clients))
+	notifier := newNotifier(notificationSettings{
+		webhookUrl:      *notificationWebhook,
+		slackWebhookUrl: *notificationSlackWebhook,
+		smtpServer:      *notificationSmtpServer,
+		smtpUsername:    *notificationSmtpUsername,
+		smtpPassword:    *notificationSmtpPassword,
+		emailFrom:       *notificationEmailFrom,
+		emailTo:         splitList(*notificationEmailTo),
+		events:          splitList(*notificationEvents),
+		natsUrl:         *eventsNatsUrl,
+		natsSubject:     *eventsNatsSubject,
+		kafkaRestUrl:    *eventsKafkaRestUrl,
+		kafkaTopic:      *eventsKafkaTopic,
+	})
+	// leading reports whether this replica runs the background routines that must run once
+	leading := func() bool { return true }
+	if *ha {
+		// only the leader watches the participants, so transitions are notified once
+		replicas := newCoordinator(ctx, kubeClient, *haNamespace, *haLeaseDuration)
+		jobs.shared = replicas
+		leading = replicas.leading
+		statusChecker.EnableSharedOperations(replicas.operation)
+		notify := notifyTransitions(ctx, notifier)
+		statusChecker.OnTransition(func(transition status.Transition) {
+			if replicas.leading() {
+				notify(transition)
+			}
+		})
+		go replicas.lead(ctx, func(ctx context.Context) {
+			if *statusWatchInterval > 0 {
+				go statusChecker.WatchTransitions(ctx, *statusWatchInterval)
+			}
+			replicas.cleanupJobs(ctx, jobCleanupInterval)
+		})
+	} else {
+		statusChecker.OnTransition(notifyTransitions(ctx, notifier))
+		if *statusWatchInterval > 0 {
+			go statusChecker.WatchTransitions(ctx, *statusWatchInterval)
+		}
+	}
+	go statusChecker.WatchEvents(ctx, kubeClient)
+	go statusChecker.WatchDeployments(ctx, kubeClient)
+	deploymentChanges = statusChecker.DeploymentChanges
+
+	dataspaceGroups := &dataspaceRegistry{client: kubeClient, namespace: *auditNamespace, configured: dataspaces}
+	participants := &provisioner{
+		kubeClient:    kubeClient,
+		ctx:           ctx,
+		statusChecker: statusChecker,
+		clients:       clients,
+		dataspaces:    dataspaceGroups,
+		podLogs:       podLogs,
+		notifier:      notifier,
+		queue:         newWorkQueue(*maxConcurrentProvisionings, *maxQueuedProvisionings, statusChecker.SetQueuePosition),
+	}
+	cleanup := &janitor{participants: participants, policy: *janitorPolicy, grace: *janitorGrace, leading: leading}
+	if *janitorInterval > 0 {
+		go cleanup.run(ctx, *janitorInterval)
+	}
+	go participants.expireParticipants(ctx, expiryCheckInterval, leading)
+
+	audit := &auditLog{client: kubeClient, namespace: *auditNamespace}
+	maintenance := &maintenanceMode{client: kubeClient, namespace: *auditNamespace}
+	participants.work = newWorkTracker(ctx, kubeClient, *auditNamespace, *ha, participants, maintenance, leading)
+	go participants.work.run(ctx, workHeartbeatInterval)
+	quotas := &participantQuotas{client: kubeClient, namespace: *auditNamespace, defaults: quotaLimits{Global: *maxParticipants, PerTenant: *maxParticipantsPerTenant}}
+	parser := payloadParser{strict: *strictPayloads}
+	admission := newAdmissionPolicy(*admissionWebhook, *admissionTimeout)
+	schedules := &scheduler{client: kubeClient, namespace: *auditNamespace, participants: participants, maintenance: maintenance, leading: leading,
+		plan: func(ctx context.Context, definition ParticipantDefinition, tenant string) (provisioningPlan, error) {
+			plan, err := planProvisioning(kubeClient, ctx, definition, dataspaceGroups, *callbackBaseUrl, flavors)
+			if err != nil {
+				return provisioningPlan{}, err
+			}
+			if err := claimParticipant(kubeClient, ctx, tenant, plan.definition.ParticipantName, plan.definition.AdoptNamespace); err != nil {
+				return provisioningPlan{}, err
+			}
+			if err := quotas.check(ctx, tenant, plan.definition.ParticipantName); err != nil {
+				return provisioningPlan{}, err
+			}
+			plan.mutators = append(plan.mutators, tenantMutator(tenant))
+			return plan, nil
+		}}
+	go schedules.run(ctx, scheduleCheckInterval)
+	app := fiber.New(fiber.Config{BodyLimit: *bodyLimit, ErrorHandler: errorHandler})
+	life := &lifecycle{kubeClient: kubeClient}
+	life.register(app)
+	app.Get("/metrics", serveMetrics(&fleetMetrics{kubeClient: kubeClient, statusChecker: statusChecker}, ctx))
+	app.Use(traceRequests())
+	auth := newApiAuth(*apiKeys, *adminApiKey, *oidcIssuer, *oidcAudience, *oidcTenantClaim, *authExempt)
+	auth.clientCertificates = *tlsClientCaFile != ""
+	authenticate := auth.middleware()
+	app.Use("/api/v1", authenticate)
+	if *impersonateCallers {
+		if !auth.enabled() {
+			log.Fatal("--impersonate requires OIDC or client certificate authentication")
+		}
+		impersonation, err := newImpersonator(*impersonationPrefix, *impersonationServiceAccountIssuer)
+		if err != nil {
+			log.Fatalf("--impersonate: %v", err)
+		}
+		app.Use("/api/v1", impersonation.middleware())
+	}
+	app.Use("/api/v1", rateLimit(*rateLimitPerMinute))
+	app.Use("/api/v1", negotiateContent())
+	idempotency := newIdempotencyStore(kubeClient, *auditNamespace, *idempotencyWindow)
+	app.Use("/api/v1", idempotency.middleware(ctx))
+	if idempotency != nil {
+		go idempotency.sweep(ctx, idempotencySweepInterval, leading)
+	}
+	app.Use("/api/v1/resources", maintenance.middleware(ctx))
+	app.Use("/api/v1/jobs", maintenance.middleware(ctx))
 	{
 		group := app.Group("/api/v1/resources")
+		scoped := requireScope(kubeClient, ctx)
 		group.Post("/", func(c *fiber.Ctx) error {
-			definition := ParticipantDefinition{
-				KubernetesIngressHost: "localhost",
+			var definition ParticipantDefinition
+			if err := parser.parse(c, &definition); err != nil {
+				return err
 			}
-			if err := c.BodyParser(&definition); err != nil {
+			tenant := tenantOf(c)
+			plan, err := planProvisioning(kubeClient, ctx, definition, dataspaceGroups, *callbackBaseUrl, flavors)
+			if err != nil {
 				return err
 			}
-
-			fmt.Println("Creating resources")
-			resources1, e1 := applyYaml(&definition.ParticipantName, &definition.Did, kubeClient, ctx, participantYaml, applyResource)
-			if e1 != nil {
-				return e1
+			if err := admission.review(c.UserContext(), admissionCreate, tenant, plan.definition); err != nil {
+				return err
 			}
-			resources2, e2 := applyYaml(&definition.ParticipantName, &definition.Did, kubeClient, ctx, identityhubYaml, applyResource)
-			if e2 != nil {
-				return e2
+			// ?verifyDid=true checks that the DID document points at the participant before anything is applied
+			if c.QueryBool("verifyDid") {
+				if err := verifyDid(c.UserContext(), participants.clients.forParticipant(plan.definition).client(targetDid), plan.definition); err != nil {
+					return err
+				}
 			}
-			// Merge maps
-			mergedResources := make(map[string]string)
-			for k, v := range resources1 {
-				mergedResources[k] = v
+			if err := claimParticipant(kubeClient, ctx, tenant, plan.definition.ParticipantName, plan.definition.AdoptNamespace); err != nil {
+				return err
 			}
-			for k, v := range resources2 {
-				mergedResources[k] = v
+			if err := quotas.check(ctx, tenant, plan.definition.ParticipantName); err != nil {
+				return err
+			}
+			plan.mutators = append(plan.mutators, tenantMutator(tenant))
+			// the scheduler plans the definition as requested once it is due
+			requested := definition
+			definition = plan.definition
+
+			// ?dryRun=true returns the rendered manifests, ?dryRun=server additionally validates them with the API server
+			switch c.Query("dryRun") {
+			case "true":
+				rendered, err := plan.render()
+				if err != nil {
+					return err
+				}
+				c.Set(fiber.HeaderContentType, "application/yaml")
+				return c.SendString(rendered)
+			case "server":
+				rendered, err := plan.render()
+				if err != nil {
+					return err
+				}
+				validation, err := plan.validate(kubeClient, ctx)
+				if err != nil {
+					return err
+				}
+				return c.JSON(fiber.Map{"manifests": rendered, "validation": validation})
+			case "", "false":
+			default:
+				return fiber.NewError(fiber.StatusBadRequest, "dryRun must be true or server")
 			}
 
-			// Introduce a clear variable for namespace usage
-			namespace := definition.ParticipantName
-
-			// Start readiness wait in a separate goroutine (non-blocking definition)
-			waitForDeploymentsAsync(
-				kubeClient,
-				ctx,
-				namespace,
-				participantDeploymentNames,
-				func() {
-					onDeploymentReady(definition)
-				},
-			)
-
-			return c.JSON(mergedResources)
+			// a notBefore in the future leaves the provisioning to the scheduler
+			if definition.NotBefore != nil && time.Now().Before(*definition.NotBefore) {
+				scheduled, err := schedules.schedule(ctx, tenant, requested)
+				if err != nil {
+					return err
+				}
+				audit.record(c, ctx, auditEntry{Action: auditSchedule, Participant: definition.ParticipantName, Definition: &definition})
+				c.Location("/api/v1/schedules")
+				return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"participant": definition.ParticipantName, "notBefore": scheduled.At})
+			}
 
+			// Record the run for bug reports when asked to
+			var rec *recording
+			if c.QueryBool("record") {
+				rec = recordings.start(definition.ParticipantName)
+			}
+			job, err := participants.start(c.UserContext(), plan, rec, nil)
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditCreate, Participant: definition.ParticipantName, JobId: job.Id, Definition: &definition})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": definition.ParticipantName, "queuePosition": job.queuePosition()})
+		})
+		group.Post("/batch", func(c *fiber.Ctx) error {
+			var definitions []ParticipantDefinition
+			if err := parser.parse(c, &definitions); err != nil {
+				return err
+			}
+			concurrency := c.QueryInt("concurrency", defaultBatchConcurrency)
+			if concurrency < 1 || concurrency > maxBatchConcurrency {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("concurrency must be between 1 and %d", maxBatchConcurrency))
+			}
+			tenant := tenantOf(c)
+			var planned sync.Map
+			results, err := participants.startBatch(c.UserContext(), definitions, concurrency, func(definition ParticipantDefinition) (provisioningPlan, error) {
+				plan, err := planProvisioning(kubeClient, ctx, definition, dataspaceGroups, *callbackBaseUrl, flavors)
+				if err != nil {
+					return plan, err
+				}
+				if err := admission.review(c.UserContext(), admissionCreate, tenant, plan.definition); err != nil {
+					return plan, err
+				}
+				if err := claimParticipant(kubeClient, ctx, tenant, plan.definition.ParticipantName, plan.definition.AdoptNamespace); err != nil {
+					return plan, err
+				}
+				if err := quotas.check(ctx, tenant, plan.definition.ParticipantName); err != nil {
+					return plan, err
+				}
+				plan.mutators = append(plan.mutators, tenantMutator(tenant))
+				planned.Store(plan.definition.ParticipantName, plan.definition)
+				return plan, nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if result.JobId == "" {
+					continue
+				}
+				definition, _ := planned.Load(result.Participant)
+				audited := definition.(ParticipantDefinition)
+				audit.record(c, ctx, auditEntry{Action: auditCreate, Participant: result.Participant, JobId: result.JobId, Definition: &audited})
+			}
+			return c.Status(fiber.StatusAccepted).JSON(results)
+		})
+		// the participant is provisioned again from the exported definition, applying its manifests and seeding its
+		// catalog where they are missing
+		group.Post("/import", func(c *fiber.Ctx) error {
+			var export participantExport
+			if err := parser.parse(c, &export); err != nil {
+				return err
+			}
+			definition, err := export.restoredDefinition()
+			if err != nil {
+				return err
+			}
+			tenant := tenantOf(c)
+			plan, err := planProvisioning(kubeClient, ctx, definition, dataspaceGroups, *callbackBaseUrl, flavors)
+			if err != nil {
+				return err
+			}
+			if err := admission.review(c.UserContext(), admissionImport, tenant, plan.definition); err != nil {
+				return err
+			}
+			if err := claimParticipant(kubeClient, ctx, tenant, plan.definition.ParticipantName, plan.definition.AdoptNamespace); err != nil {
+				return err
+			}
+			if err := quotas.check(ctx, tenant, plan.definition.ParticipantName); err != nil {
+				return err
+			}
+			plan.mutators = append(plan.mutators, tenantMutator(tenant))
+			definition = plan.definition
+			job, err := participants.start(c.UserContext(), plan, nil, nil)
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditImport, Participant: definition.ParticipantName, JobId: job.Id, Definition: &definition})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": definition.ParticipantName, "queuePosition": job.queuePosition()})
+		})
+		group.Put("/:participantName", scoped, func(c *fiber.Ctx) error {
+			var definition ParticipantDefinition
+			if err := parser.parse(c, &definition); err != nil {
+				return err
+			}
+			namespace := c.Params("participantName")
+			if definition.ParticipantName == "" {
+				definition.ParticipantName = namespace
+			} else if definition.ParticipantName != namespace {
+				return fiber.NewError(fiber.StatusBadRequest, "participantName does not match the URL")
+			}
+			managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+			if err != nil {
+				return err
+			}
+			if !managed {
+				return fiber.NewError(fiber.StatusNotFound, "participant not found")
+			}
+			plan, err := planProvisioning(kubeClient, ctx, definition, dataspaceGroups, *callbackBaseUrl, flavors)
+			if err != nil {
+				return err
+			}
+			if err := admission.review(c.UserContext(), admissionUpdate, tenantOf(c), plan.definition); err != nil {
+				return err
+			}
+			// Keep the participant with the tenant that provisioned it, also when upgraded by an unscoped caller
+			owner, err := ownerOf(kubeClient, ctx, namespace)
+			if err != nil {
+				return err
+			}
+			plan.mutators = append(plan.mutators, tenantMutator(owner))
+			job, err := startRollout(kubeClient, withRequesterOf(withSpanOf(ctx, c.UserContext()), c.UserContext()), statusChecker, namespace, "upgrade", func(ctx context.Context, kubernetesAction action) ([]appliedObject, error) {
+				return plan.apply(kubeClient, ctx, kubernetesAction, clients)
+			})
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditUpdate, Tenant: owner, Participant: namespace, JobId: job.Id, Definition: &plan.definition})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace})
 		})
-		group.Delete("/", func(c *fiber.Ctx) error {
-			var request ParticipantDefinition
-			if err := c.BodyParser(&request); err != nil {
+		group.Get("/:participantName/manifests", scoped, getLiveManifests(kubeClient, ctx))
+		group.Get("/:participantName/export", scoped, getParticipantExport(kubeClient, ctx))
+		group.Get("/:participantName/credentials", scoped, getParticipantApiKeys(kubeClient, ctx, audit))
+		group.Get("/:participantName/drift", scoped, getDrift(kubeClient, ctx))
+		group.Get("/:participantName/revisions", scoped, func(c *fiber.Ctx) error {
+			revisions, err := listRevisions(kubeClient, ctx, c.Params("participantName"))
+			if err != nil {
 				return err
 			}
-			fmt.Println("Deleting resources")
-			resources1, e1 := applyYaml(&request.ParticipantName, &request.Did, kubeClient, ctx, participantYaml, deleteResource)
-			if e1 != nil {
-				return e1
+			return c.JSON(revisions)
+		})
+		group.Post("/:participantName/rollback", scoped, func(c *fiber.Ctx) error {
+			namespace := c.Params("participantName")
+			number := c.QueryInt("revision")
+			if number <= 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "revision must be a positive number")
 			}
-			resources2, e2 := applyYaml(&request.ParticipantName, &request.Did, kubeClient, ctx, identityhubYaml, deleteResource)
-			if e2 != nil {
-				return e2
+			managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+			if err != nil {
+				return err
+			}
+			if !managed {
+				return fiber.NewError(fiber.StatusNotFound, "participant not found")
 			}
-			// Merge maps
-			mergedResources := make(map[string]string)
-			for k, v := range resources1 {
-				mergedResources[k] = v
+			rev, err := loadRevision(kubeClient, ctx, namespace, number)
+			if apierrors.IsNotFound(err) {
+				return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("revision %d not found", number))
+			}
+			if err != nil {
+				return err
 			}
-			for k, v := range resources2 {
-				mergedResources[k] = v
+			// The revision may predate a key rotation
+			creds, err := loadCredentials(kubeClient, ctx, namespace)
+			if err != nil {
+				return err
+			}
+			owner, err := ownerOf(kubeClient, ctx, namespace)
+			if err != nil {
+				return err
+			}
+			job, err := startRollout(kubeClient, withRequesterOf(withSpanOf(ctx, c.UserContext()), c.UserContext()), statusChecker, namespace, fmt.Sprintf("rollback to revision %d", number), func(ctx context.Context, kubernetesAction action) ([]appliedObject, error) {
+				return applyYaml(&namespace, new(string), kubeClient, ctx, rev.manifests, kubernetesAction, credentialsMutator(creds, ""))
+			})
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditRollback, Tenant: owner, Participant: namespace, JobId: job.Id, Revision: number})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace})
+		})
+		group.Post("/:participantName/smoketest", scoped, runSmokeTest(kubeClient, ctx, participants.clients, parser))
+		// ?seed=true additionally runs the seed steps that failed or never ran once the deployments are ready
+		group.Post("/:participantName/reconcile", scoped, reconcileParticipant(kubeClient, ctx, participants, statusChecker, audit))
+		// the DID of the participant is changed where it is bound: its manifests, the participant context in the
+		// identity hub with its STS client, its DID document and its credentials
+		group.Post("/:participantName/rotate-did", scoped, func(c *fiber.Ctx) error {
+			namespace := c.Params("participantName")
+			var request didRotationRequest
+			if err := parser.parse(c, &request); err != nil {
+				return err
+			}
+			managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+			if err != nil {
+				return err
+			}
+			if !managed {
+				return fiber.NewError(fiber.StatusNotFound, "participant not found")
+			}
+			rotation, err := planDidRotation(kubeClient, ctx, namespace, request.Did)
+			if err != nil {
+				return err
+			}
+			plan, err := planProvisioning(kubeClient, ctx, rotation.rotated, dataspaceGroups, *callbackBaseUrl, flavors)
+			if err != nil {
+				return err
+			}
+			if err := admission.review(c.UserContext(), admissionUpdate, tenantOf(c), plan.definition); err != nil {
+				return err
+			}
+			// ?verifyDid=true checks that the document of the new DID points at the participant before anything is applied
+			if c.QueryBool("verifyDid") {
+				if err := verifyDid(c.UserContext(), participants.clients.forParticipant(plan.definition).client(targetDid), plan.definition); err != nil {
+					return err
+				}
+			}
+			owner, err := ownerOf(kubeClient, ctx, namespace)
+			if err != nil {
+				return err
+			}
+			plan.mutators = append(plan.mutators, tenantMutator(owner))
+			rotation.rotated = plan.definition
+			job, err := participants.rotateDid(withRequesterOf(withSpanOf(ctx, c.UserContext()), c.UserContext()), rotation, plan)
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditRotateDid, Tenant: owner, Participant: namespace, JobId: job.Id, Definition: &plan.definition})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace, "did": plan.definition.Did, "previousDid": rotation.previous.Did})
+		})
+		group.Post("/:participantName/seed", scoped, func(c *fiber.Ctx) error {
+			namespace := c.Params("participantName")
+			managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+			if err != nil {
+				return err
+			}
+			if !managed {
+				return fiber.NewError(fiber.StatusNotFound, "participant not found")
+			}
+			owner, err := ownerOf(kubeClient, ctx, namespace)
+			if err != nil {
+				return err
+			}
+			job, err := participants.resumeSeeding(c.UserContext(), namespace)
+			if err != nil {
+				return err
+			}
+			audit.record(c, ctx, auditEntry{Action: auditSeed, Tenant: owner, Participant: namespace, JobId: job.Id})
+			c.Location("/api/v1/jobs/" + job.Id)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace})
+		})
+		group.Delete("/:participantName", scoped, deleteParticipant(kubeClient, ctx, participants, audit))
+		group.Post("/:participantName/restore", scoped, restoreHandler(kubeClient, ctx, audit))
+		group.Get("/", listParticipants(kubeClient, ctx, statusChecker))
+		group.Post("/status", getStatuses(kubeClient, ctx, statusChecker, parser))
+		group.Get("/:participantName", scoped, getParticipantDetails(kubeClient, ctx, statusChecker))
+		group.Get("/:participantName/status", scoped, func(c *fiber.Ctx) error {
+			fields, err := status.ParseFields(c.Query("fields"))
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+			if c.QueryBool("refresh") {
+				statusChecker.Invalidate(c.Params("participantName"))
+			}
+			participantStatus, err := statusOrLastKnown(c, ctx, statusChecker, c.Params("participantName"), fields)
+			if err != nil {
+				return err
+			}
+			if participantStatus.Status == status.StatusNotFound {
+				c.Status(fiber.StatusNotFound)
+			}
+			return c.JSON(participantStatus)
+		})
+		group.Get("/:participantName/status/stream", scoped, func(c *fiber.Ctx) error {
+			fields, err := status.ParseFields(c.Query("fields"))
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+			name := strings.Clone(c.Params("participantName"))
+			subscribe := func() (<-chan struct{}, func()) {
+				return statusChecker.DeploymentChanges(name)
+			}
+			return streamJson(c, ctx, statusStreamInterval, subscribe, func() (any, error) {
+				return statusChecker.GetStatus(ctx, name, fields)
+			})
+		})
+		group.Get("/:participantName/status/history", scoped, func(c *fiber.Ctx) error {
+			history, err := statusChecker.GetHistory(ctx, c.Params("participantName"))
+			if err != nil {
+				return err
+			}
+			if history == nil {
+				history = []status.HistoryEntry{}
+			}
+			return c.JSON(history)
+		})
+		group.Get("/:participantName/events", scoped, getParticipantEvents(ctx, statusChecker))
+		group.Get("/:participantName/logs", requireAdminKey(*adminApiKey), scoped, getComponentLogs(kubeClient, ctx, podLogs))
+		group.Get("/:participantName/badge.svg", scoped, statusBadge(ctx, statusChecker))
+		group.Get("/:participantName/recording", requireAdminKey(*adminApiKey), scoped, downloadRecording)
+		group.Get("/:participantName/hooks", requireAdminKey(*adminApiKey), scoped, getHookResults)
+		group.All("/:participantName/proxy/:component/*", requireAdminKey(*adminApiKey), scoped, proxyToComponent(kubeClient, ctx))
+	}
+	{
+		group := app.Group("/api/v1/maintenance", requireAdminKey(*adminApiKey))
+		group.Post("/rotate-api-keys", func(c *fiber.Ctx) error {
+			var request keyRotationRequest
+			if len(c.Body()) > 0 {
+				if err := parser.parse(c, &request); err != nil {
+					return err
+				}
+			}
+			namespaces := request.Participants
+			if len(namespaces) == 0 {
+				discovered, err := discoverParticipants(kubeClient, ctx)
+				if err != nil {
+					return err
+				}
+				namespaces = discovered
+			} else if err := checkRotatedParticipants(kubeClient, ctx, namespaces); err != nil {
+				return err
 			}
 
-			return c.JSON(mergedResources)
+			log.Println("Rotating API keys for", namespaces)
+			return c.Status(fiber.StatusAccepted).JSON(participants.startKeyRotations(c.UserContext(), namespaces))
+		})
+		group.Get("/janitor", getJanitorSweep(cleanup))
+		group.Post("/janitor", runJanitorSweep(cleanup, ctx))
+		group.Get("/mode", getMaintenanceMode(maintenance, ctx))
+		group.Put("/mode", setMaintenanceMode(maintenance, ctx, parser))
+		group.Post("/reload-manifests", func(c *fiber.Ctx) error {
+			set, flavorSets, err := flavors.reload(ctx)
+			if err != nil {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+			}
+			loaded := make(map[string]fiber.Map, len(flavorSets))
+			for name, flavorSet := range flavorSets {
+				loaded[name] = fiber.Map{"source": flavorSet.Source, "loadedAt": flavorSet.LoadedAt}
+			}
+			return c.JSON(fiber.Map{"source": set.Source, "loadedAt": set.LoadedAt, "flavors": loaded})
 		})
 	}
-	err = app.Listen(":9999")
+	registerDashboard(app, authenticate, kubeClient, ctx, statusChecker)
+	registerDidDocuments(app, kubeClient, ctx)
+	registerApiDocs(app)
+	app.Get("/api/v1/jobs/:id", requireJobScope(kubeClient, ctx), getJob)
+	app.Delete("/api/v1/jobs/:id", requireJobScope(kubeClient, ctx), cancelJob(participants, audit, ctx))
+	app.Get("/api/v1/audit", audit.handler(ctx))
+	app.Get("/api/v1/schedules", listSchedules(schedules, ctx))
+	app.Delete("/api/v1/schedules/:participantName", cancelSchedule(schedules, audit, ctx))
+	app.Get("/api/v1/reports/health", requireAdminKey(*adminApiKey), getHealthReport(kubeClient, ctx, statusChecker))
+	app.Get("/api/v1/quotas", requireAdminKey(*adminApiKey), getQuotas(quotas, ctx))
+	app.Get("/api/v1/admin/templates", requireAdminKey(*adminApiKey), getTemplates(flavors))
+	app.Get("/api/v1/admin/background-tasks", requireAdminKey(*adminApiKey), listBackgroundTasks(participants.work, ctx))
+	app.Post("/api/v1/issuers", requireAdminKey(*adminApiKey), provisionIssuer(kubeClient, ctx, participants, parser))
+	app.Get("/api/v1/issuers/:issuerName", requireAdminKey(*adminApiKey), getIssuer(kubeClient, ctx))
+	app.Post("/api/v1/catalogs", requireAdminKey(*adminApiKey), provisionFederatedCatalog(kubeClient, ctx, participants, parser))
+	app.Get("/api/v1/catalogs/:catalogName", requireAdminKey(*adminApiKey), getFederatedCatalog(kubeClient, ctx))
+	app.Post("/api/v1/dataspaces", requireAdminKey(*adminApiKey), createDataspace(dataspaceGroups, ctx, parser))
+	app.Get("/api/v1/dataspaces", listDataspaces(dataspaceGroups, ctx))
+	app.Get("/api/v1/dataspaces/:dataspaceName", getDataspace(dataspaceGroups, kubeClient, ctx, statusChecker))
+	app.Put("/api/v1/quotas", requireAdminKey(*adminApiKey), setQuotas(quotas, ctx, parser))
+	app.Post("/api/v1/callbacks/:participantName/:token", receiveCallback(kubeClient, ctx, statusChecker))
+	app.Get("/api/v1/compatibility", func(c *fiber.Ctx) error {
+		outdated, err := outdatedParticipants(kubeClient, ctx, tenantOf(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{
+			"currentTemplateVersion": templateVersion,
+			"matrix":                 compatibilityMatrix,
+			"outdatedParticipants":   outdated,
+		})
+	})
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		<-signals
+		life.shutdown(app, *shutdownTimeout, cancel, func() {
+			statusChecker.Wait()
+			traces.shutdown()
+		})
+		close(stopped)
+	}()
+	var certificates certificateSource
+	switch {
+	case *tlsSecret != "":
+		certificates = secretCertificates(kubeClient, secretKey(*tlsSecret, envOrDefault("POD_NAMESPACE", "mvd-provisioner")))
+	case *tlsCertFile != "" || *tlsKeyFile != "":
+		certificates = fileCertificates(*tlsCertFile, *tlsKeyFile)
+	case *tlsClientCaFile != "":
+		log.Fatal("client certificates need TLS, set --tls-cert-file and --tls-key-file or --tls-secret")
+	}
+	if certificates == nil {
+		err = app.Listen(*listenAddress)
+	} else {
+		err = listenTls(ctx, app, *listenAddress, certificates, *tlsClientCaFile, *tlsRequireClientCert)
+	}
 	if err != nil {
 		panic(err)
 	}
+	<-stopped
 }
 
 //go:embed resources/asset1.json
@@ -184,139 +935,239 @@ var defRequireMembership string
 //go:embed resources/contractdef_require_sensitive.json
 var defSensitive string
 
-func onDeploymentReady(definition ParticipantDefinition) {
-	fmt.Println("Deployments ready in namespace", definition.ParticipantName, "-> seeding data")
+// envOrDefault returns the value of the environment variable, or the fallback when it is not set.
+func envOrDefault(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
 
-	seedConnectorData(definition)
-	seedIdentityHubData(definition)
-	seedIssuerData(definition)
+// envInt returns the integer value of the environment variable, or fallback when it is unset or not a number.
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
 
-	fmt.Println("Data seeding complete in namespace", definition.ParticipantName)
+// envFloat returns the number in the environment variable, or fallback when it is unset or not a number.
+func envFloat(name string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
 
+// envDuration returns the duration in the environment variable, or fallback when it is unset or invalid.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
 }
 
 //go:embed templates/participant.json
 var participantJson string
 
-func seedIdentityHubData(definition ParticipantDefinition) {
+// seedIdentityHubData creates the participant context in the identity hub and stores the secret of its STS client.
+// The participant's private key is stored before, the identity hub publishes its public key; participants without key
+// pair get one generated by the identity hub. It returns the ID of the participant context and of the STS client,
+// which is empty if the context existed already.
+func seedIdentityHubData(ctx context.Context, definition ParticipantDefinition, clients seedingClients, creds participantCredentials, keys *participantKeyPair, secrets secretStore) (string, string, error) {
 	json := participantJson
-	kubernetesHost := definition.getHost()
-	namespace := definition.ParticipantName
-
-	identityApi := api.ApiClient{
-		BaseUrl:    kubernetesHost + "/" + namespace + "/cs/api/identity/v1alpha",
-		ApiKey:     apiKey,
-		HttpClient: http.Client{},
-	}
-	ihBaseUrl := fmt.Sprintf("http://identityhub.%s.svc.cluster.local:7082", namespace)
-	edcUrl := fmt.Sprintf("http://controlplane.%s.svc.cluster.local:8082", namespace)
-
+	identityHub := identityApi(definition, clients, creds)
 	json = strings.Replace(json, "${PARTICIPANT_NAME}", definition.ParticipantName, -1)
 	json = strings.Replace(json, "${PARTICIPANT_DID}", definition.Did, -1)
-	json = strings.Replace(json, "${PARTICIPANT_DID_BASE64}", base64.StdEncoding.EncodeToString([]byte(definition.Did)), -1)
-	json = strings.Replace(json, "${IH_BASE_URL}", ihBaseUrl, -1)
-	json = strings.Replace(json, "${EDC_BASE_URL}", edcUrl, -1)
+	json = strings.Replace(json, "${CREDENTIAL_SERVICE_URL}", credentialServiceUrl(definition), -1)
+	json = strings.Replace(json, "${PROTOCOL_ENDPOINT_URL}", protocolEndpointUrl(definition), -1)
 
-	participant, err := identityApi.CreateParticipant(json)
+	existing, err := identityHub.GetParticipant(ctx, base64.StdEncoding.EncodeToString([]byte(definition.Did)))
 	if err != nil {
-		fmt.Println(err)
-		return
+		return "", "", err
 	}
-	if participant == nil {
-		fmt.Println("participant already exists")
-		return
+	if existing != nil {
+		fmt.Printf("participant %s already exists in the identity hub\n", definition.Did)
+		return existing.ParticipantContextId, "", nil
 	}
-
-	var mgmtApi = api.ApiClient{
-		HttpClient: http.Client{},
-		BaseUrl:    kubernetesHost + "/" + namespace + "/cp/api/management/v3",
-		ApiKey:     "password",
+	if keys != nil {
+		if err := secrets.put(ctx, keys.KeyId, keys.PrivateKey); err != nil {
+			return "", "", err
+		}
+		if json, err = withKeyPair(json, *keys); err != nil {
+			return "", "", err
+		}
 	}
-	secretBody := `
-	{
-		"@context": [
-			"https://w3id.org/edc/connector/management/v0.0.1"
-		],
-		"@id": "${ID}",
-		"value": "${SECRET}"
-    }`
-	secretBody = strings.Replace(secretBody, "${ID}", participant.ClientId+"-sts-client-secret", -1)
-	secretBody = strings.Replace(secretBody, "${SECRET}", participant.ClientSecret, -1)
-
-	_, err = mgmtApi.CreateSecret(secretBody)
+	participant, err := identityHub.CreateParticipant(ctx, json)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return "", "", err
+	}
+	if participant == nil {
+		// created concurrently, e.g. by a seeding run that timed out waiting for the response
+		fmt.Printf("participant %s already exists in the identity hub\n", definition.Did)
+		return definition.Did, "", nil
+	}
+
+	// the connector reads the secret from the store instead of getting it through its management API
+	if err := secrets.put(ctx, participant.ClientId+"-sts-client-secret", participant.ClientSecret); err != nil {
+		return "", "", err
 	}
 	fmt.Println("participant created")
+	return definition.Did, participant.ClientId, nil
 }
 
-func seedConnectorData(definition ParticipantDefinition) {
-
-	kubernetesHost := definition.getHost()
-	namespace := definition.ParticipantName
+// identityApi returns a client of the participant's identity hub API, reached through the ingress.
+func identityApi(definition ParticipantDefinition, clients seedingClients, creds participantCredentials) *api.ApiClient {
+	return &api.ApiClient{
+		BaseUrl:    definition.routeUrl("/cs/api/identity/v1alpha"),
+		ApiKey:     creds.IdentityApiKey,
+		HttpClient: clients.client(targetIdentity),
+	}
+}
 
-	mgmtApi := api.ApiClient{
-		BaseUrl:    kubernetesHost + "/" + namespace + "/cp/api/management/v3",
-		ApiKey:     "password",
-		HttpClient: http.Client{},
+// managementApi returns a client of the participant's management API, reached through the ingress.
+func managementApi(definition ParticipantDefinition, clients seedingClients, creds participantCredentials) *api.ApiClient {
+	return &api.ApiClient{
+		BaseUrl:    definition.routeUrl("/cp/api/management/v3"),
+		ApiKey:     creds.ManagementApiKey,
+		HttpClient: clients.client(targetManagement),
 	}
+}
 
-	// create assets
-	for _, asset := range []string{asset1Json, asset2json} {
-		_, err := mgmtApi.CreateAsset(asset)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+// seedCatalog is what the connector of a participant is seeded with.
+type seedCatalog struct {
+	assets              []string
+	policies            []string
+	contractDefinitions []string
+}
 
+// connectorCatalog returns the assets, policies and contract definitions seeded into the participant's connector.
+func connectorCatalog(definition ParticipantDefinition) (seedCatalog, error) {
+	defaults, err := defaultCatalog(seedDirOf(definition))
+	if err != nil {
+		return seedCatalog{}, err
 	}
-	fmt.Println("assets created")
-
-	// create policies
-	for _, policy := range []string{policyDataProcessorJson, policyMembershipJson, policySensitiveDataJson} {
-		_, err := mgmtApi.CreatePolicy(policy)
-		if err != nil {
-			fmt.Println(err)
-			return
+	assets, policies, contractDefinitions := defaults.assets, defaults.policies, defaults.contractDefinitions
+	if catalog := definition.Catalog; catalog != nil {
+		if assets, err = catalogBodies(catalog.Assets, assets); err != nil {
+			return seedCatalog{}, err
 		}
-	}
-	fmt.Println("policies created")
-
-	// create contract defs
-	for _, cd := range []string{defRequireMembership, defSensitive} {
-		_, err := mgmtApi.CreateContractDefinition(cd)
-		if err != nil {
-			fmt.Println(err)
-			return
+		if policies, err = catalogBodies(catalog.PolicyDefinitions, policies); err != nil {
+			return seedCatalog{}, err
+		}
+		if contractDefinitions, err = catalogBodies(catalog.ContractDefinitions, contractDefinitions); err != nil {
+			return seedCatalog{}, err
 		}
 	}
-	fmt.Println("contract definitions created")
-
-}
-
-func seedIssuerData(definition ParticipantDefinition) {
-	kubernetesHost := definition.getHost()
-	issuerId := "did:web:dataspace-issuer-service.poc-issuer.svc.cluster.local%3A10016:issuer"
-	issuerB64 := base64.StdEncoding.EncodeToString([]byte(issuerId))
-	issuerApi := api.ApiClient{
-		BaseUrl:    kubernetesHost + "/issuer/ad/api/admin/v1alpha/participants/" + issuerB64,
-		ApiKey:     "c3VwZXItdXNlcg==.c3VwZXItc2VjcmV0LWtleQo=",
-		HttpClient: http.Client{},
+	if len(definition.ContractDefinitions) > 0 {
+		contractDefinitions = nil
+		for _, spec := range definition.ContractDefinitions {
+			body, err := spec.toJson()
+			if err != nil {
+				return seedCatalog{}, err
+			}
+			contractDefinitions = append(contractDefinitions, body)
+		}
 	}
-
-	err := issuerApi.CreateHolder(definition.Did, definition.Did, definition.ParticipantName)
-	if err != nil {
-		fmt.Println(err)
-		return
+	if definition.SeedGenerator != nil {
+		assets, contractDefinitions, err = generateCatalog(definition.ParticipantName, *definition.SeedGenerator, assets, contractDefinitions)
+		if err != nil {
+			return seedCatalog{}, err
+		}
 	}
-	fmt.Println("issuer account created for participant ", definition.ParticipantName)
+	return seedCatalog{assets: assets, policies: policies, contractDefinitions: contractDefinitions}, nil
 }
 
 type ParticipantDefinition struct {
 	ParticipantName       string `json:"participantName,omitempty" validate:"required"`
 	Did                   string `json:"did,omitempty" validate:"required"`
 	KubernetesIngressHost string `json:"kubeHost,omitempty"`
+	// Dataspace selects the dataspace settings from the dataspace config
+	Dataspace string `json:"dataspace,omitempty"`
+	// Services overrides the exposure of individual Services, keyed by Service name
+	Services map[string]ServiceOptions `json:"services,omitempty"`
+	Mesh     *MeshOptions              `json:"mesh,omitempty"`
+	// Ingress sets the class, host and path prefix of the participant's ingresses
+	Ingress *IngressOptions `json:"ingress,omitempty"`
+	// Routing selects Ingresses (ingress) or Gateway API HTTPRoutes (gateway) for the participant's APIs, by default
+	// gateway if Gateway is set and the provisioner's --routing otherwise
+	Routing string `json:"routing,omitempty"`
+	// Gateway replaces the provisioner's settings of the Gateway the HTTPRoutes are attached to
+	Gateway *GatewayOptions `json:"gateway,omitempty"`
+	// Tls serves the participant's ingresses over HTTPS with a certificate from cert-manager
+	Tls *TlsOptions `json:"tls,omitempty"`
+	// NetworkPolicies isolates the participant namespace from other workloads of the cluster
+	NetworkPolicies *NetworkPolicyOptions `json:"networkPolicies,omitempty"`
+	// Labels and Annotations are added to every object created for the participant
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Metadata records the owner, team, environment and description of the participant on its namespace
+	Metadata *MetadataOptions `json:"metadata,omitempty"`
+	// Protected participants are only deleted with ?force=true or the confirmation token the refused deletion names
+	Protected bool `json:"protected,omitempty"`
+	// AdoptNamespace deploys the participant into its existing namespace, e.g. created with quotas and RBAC by
+	// platform tooling, instead of creating it. Deleting the participant leaves the namespace and deletes its objects.
+	AdoptNamespace bool `json:"adoptNamespace,omitempty"`
+	// ExpiresAfter deletes the participant once the lifetime, e.g. 8h or 2d, lapsed after it was provisioned
+	ExpiresAfter string `json:"expiresAfter,omitempty"`
+	// NotBefore defers the provisioning of a new participant to this time, the request only schedules it
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	// Teardown deletes the participant at an RFC 3339 time or at the first time a cron expression like "0 18 * * 5"
+	// matches after it was provisioned, in UTC
+	Teardown string `json:"teardown,omitempty"`
+	// KeyAlgorithm selects the key pair generated for the participant when it is provisioned the first time: EC, the
+	// default, or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// Tier selects the PriorityClass of the participant's pods: production, standard or demo
+	Tier string `json:"tier,omitempty"`
+	// Flavor selects the named participant stack, with its own manifests and seed resources, the participant is
+	// provisioned with. Participants without flavor get the default stack.
+	Flavor string `json:"flavor,omitempty"`
+	// Size selects the sizing profile of the participant's components: small, medium or large. Without one the
+	// components run without resource limits and the postgres data isn't persisted.
+	Size string `json:"size,omitempty"`
+	// Postgres keeps the postgres data on a volume claim of the given size and StorageClass
+	Postgres *PostgresOptions `json:"postgres,omitempty"`
+	// Scaling sets the replicas of the controlplane and dataplane or lets autoscalers scale them, keyed by deployment
+	// name. The components run a single replica by default.
+	Scaling map[string]ScalingOptions `json:"scaling,omitempty"`
+	// Scheduling pins the participant's pods to nodes, on top of the scheduling of the dataspace
+	Scheduling *SchedulingOptions `json:"scheduling,omitempty"`
+	// Seed skips seeding when false or selects the seed steps to run
+	Seed *SeedOptions `json:"seed,omitempty"`
+	// SeedGenerator generates participant specific asset IDs, titles and descriptions for the seeded catalog
+	SeedGenerator *SeedGeneratorOptions `json:"seedGenerator,omitempty"`
+	// Catalog replaces the embedded demo assets, policy definitions and contract definitions
+	Catalog *SeedCatalog `json:"catalog,omitempty"`
+	// ApiKeys replaces the default API keys the participant's components are provisioned with
+	ApiKeys *ApiKeyOverrides `json:"apiKeys,omitempty"`
+	// Credentials lists the verifiable credential types requested from the issuer, e.g. MembershipCredential and
+	// DataProcessorCredential. The issuer's default credentials are requested if empty.
+	Credentials []string `json:"credentials,omitempty"`
+	// PresignedCredentials are stored in the participant's identity hub as they are, e.g. for demos without a live
+	// issuer
+	PresignedCredentials []PresignedCredential `json:"presignedCredentials,omitempty"`
+	// ContractDefinitions replace the embedded demo contract definitions
+	ContractDefinitions []ContractDefinitionSpec `json:"contractDefinitions,omitempty"`
+	// ComponentVersions pins the image tags of individual components, keyed by deployment name
+	ComponentVersions map[string]string `json:"componentVersions,omitempty"`
+	// ComponentImages replaces the images of individual components, e.g. with builds from a private registry, keyed
+	// by deployment name. A tag in ComponentVersions takes precedence over the tag of the image.
+	ComponentImages map[string]string `json:"componentImages,omitempty"`
+	// Env sets environment variables of individual components, keyed by deployment name, e.g. EDC_* settings or log
+	// levels. Config properties like edc.log.level are set as their variable EDC_LOG_LEVEL.
+	Env map[string]map[string]string `json:"env,omitempty"`
+	// HelmValues override the values of the Helm chart the participant is rendered from, if one is configured
+	HelmValues map[string]any `json:"helmValues,omitempty"`
+	// Components selects the components of a partial stack, e.g. controlplane and identityhub without the dataplane.
+	// All of them are provisioned if none is selected. Optional components, like the federatedcatalog, are added to
+	// the stack by selecting them.
+	Components []string `json:"components,omitempty"`
+	// Kustomization patches the rendered manifests after the kustomization of the dataspace
+	Kustomization *Kustomization `json:"kustomization,omitempty"`
 }
 
 func (p *ParticipantDefinition) getHost() string {
@@ -333,7 +1184,7 @@ func (p *ParticipantDefinition) getHost() string {
 
 type action func(client.Client, context.Context, client.Object) error
 
-func applyYaml(participantName *string, did *string, c client.Client, ctx context.Context, yamlString string, kubernetesAction action) (map[string]string, error) {
+func applyYaml(participantName *string, did *string, c client.Client, ctx context.Context, yamlString string, kubernetesAction action, mutators ...objectMutator) ([]appliedObject, error) {
 	yamlString = strings.Replace(yamlString, "${PARTICIPANT_NAME}", *participantName, -1)
 	yamlString = strings.Replace(yamlString, "$PARTICIPANT_NAME", *participantName, -1)
 	yamlString = strings.Replace(yamlString, "${PARTICIPANT_ID}", *did, -1)
@@ -341,7 +1192,7 @@ func applyYaml(participantName *string, did *string, c client.Client, ctx contex
 
 	docs := strings.Split(yamlString, "---")
 
-	resourceMap := make(map[string]string)
+	var applied []appliedObject
 	for _, doc := range docs {
 		doc = strings.TrimSpace(doc)
 		if doc == "" {
@@ -353,13 +1204,26 @@ func applyYaml(participantName *string, did *string, c client.Client, ctx contex
 			return nil, err
 		}
 
-		resourceMap[obj.GetName()] = obj.GetKind()
+		skipped := false
+		for _, mutate := range mutators {
+			if err := mutate(obj); errors.Is(err, errSkipObject) {
+				skipped = true
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if skipped {
+			continue
+		}
+
 		err := kubernetesAction(c, ctx, obj)
 		if err != nil {
 			return nil, err
 		}
+		applied = append(applied, appliedObjectOf(obj))
 	}
-	return resourceMap, nil
+	return applied, nil
 }
 
 func applyResource(c client.Client, ctx context.Context, object client.Object) error {
@@ -368,7 +1232,7 @@ func applyResource(c client.Client, ctx context.Context, object client.Object) e
 		ctx,
 		object,
 		client.Apply,
-		client.FieldOwner("go-provisioner"),
+		client.FieldOwner(fieldOwner),
 		// Optional: take ownership of fields (overwrites conflicts)
 		client.ForceOwnership,
 	)
@@ -379,24 +1243,6 @@ func deleteResource(c client.Client, ctx context.Context, object client.Object)
 	return c.Delete(ctx, object)
 }
 
-// waitForDeploymentsAsync runs the readiness check in the background and invokes the callback on success.
-func waitForDeploymentsAsync(
-	c client.Client,
-	ctx context.Context,
-	namespace string,
-	deployments []string,
-	callback func(),
-) {
-	fmt.Println("Waiting for deployments", deployments, "")
-	go func() {
-		if err := waitForDeployments(c, ctx, namespace, deployments); err != nil {
-			fmt.Printf("deployment readiness check failed for namespace %s: %v\n", namespace, err)
-			return
-		}
-		callback()
-	}()
-}
-
 // waitForDeployments waits for all given deployments concurrently and returns an error if any fail.
 func waitForDeployments(c client.Client, ctx context.Context, namespace string, deployments []string) error {
 	errCh := make(chan error, len(deployments))
@@ -417,27 +1263,41 @@ func waitForDeployments(c client.Client, ctx context.Context, namespace string,
 	return firstErr
 }
 
-// waitForDeployment polls until the deployment reaches the desired ready replicas.
+// deploymentReady reports whether the deployment reached the ready replicas it requires and any rollout has completed.
+func deploymentReady(deployment *appsv1.Deployment) bool {
+	desired := status.DesiredReplicas(deployment)
+	rolledOut := deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == desired &&
+		deployment.Status.Replicas == desired
+	return deployment.Status.ReadyReplicas >= status.RequiredReplicas(deployment) && rolledOut
+}
+
+// waitForDeployment waits until the deployment reaches the desired ready replicas and any rollout has completed. It
+// checks the deployment whenever the watch reports a change of the namespace, or polls when deployments aren't watched.
 func waitForDeployment(c client.Client, ctx context.Context, namespace string, name string) error {
+	interval := readinessPollInterval
+	var changes <-chan struct{}
+	if deploymentChanges != nil {
+		var unsubscribe func()
+		changes, unsubscribe = deploymentChanges(namespace)
+		defer unsubscribe()
+		interval = deploymentResyncInterval
+	}
 	deployment := &appsv1.Deployment{}
 	for {
 		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment); err != nil {
 			return err
 		}
 
-		desired := int32(1)
-		if deployment.Spec.Replicas != nil {
-			desired = *deployment.Spec.Replicas
-		}
-		if deployment.Status.ReadyReplicas == desired {
+		if deploymentReady(deployment) {
 			return nil
 		}
 
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(readinessPollInterval):
-			continue
+		case <-changes:
+		case <-time.After(interval):
 		}
 	}
 }
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const rolloutTimeout = 5 * time.Minute
+
+// Phase of a key rotation job
+const phaseRotate = "rotate"
+
+const storeCredentialsAttempts = 3
+
+// Base64 encoded ID of IdentityHub's super-user participant context
+const superUserContextId = "c3VwZXItdXNlcg=="
+
+const assetQuerySpec = `{
+	"@context": {
+		"@vocab": "https://w3id.org/edc/v0.0.1/ns/"
+	},
+	"@type": "QuerySpec",
+	"limit": 1
+}`
+
+type keyRotationRequest struct {
+	Participants []string `json:"participants,omitempty"`
+}
+
+// checkRotatedParticipants rejects rotating the keys of participants that don't exist or that the provisioner
+// doesn't manage, and rotating the keys of a participant twice at once.
+func checkRotatedParticipants(c client.Client, ctx context.Context, namespaces []string) error {
+	seen := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		if seen[namespace] {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("participant %s is listed more than once", namespace))
+		}
+		seen[namespace] = true
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid participant name %q: %s", namespace, errs[0]))
+		}
+		managed, err := status.IsManagedNamespace(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("participant %s not found", namespace))
+		}
+	}
+	return nil
+}
+
+// startKeyRotations starts a job rotating the keys of each participant. The jobs wait in the provisioner's queue, so
+// only as many rollouts run at a time as provisionings do.
+func (p *provisioner) startKeyRotations(ctx context.Context, namespaces []string) []batchResult {
+	results := make([]batchResult, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		result := batchResult{Participant: namespace}
+		if job, err := p.startKeyRotation(ctx, namespace); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.JobId = job.Id
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// startKeyRotation rotates the keys of the participant in a background job.
+func (p *provisioner) startKeyRotation(ctx context.Context, namespace string) (*provisioningJob, error) {
+	job, err := jobs.create(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := p.queue.join(job)
+	if err != nil {
+		jobs.discard(job.Id)
+		return nil, err
+	}
+	job.queue()
+	runInBackground(func() {
+		runCtx, stop := job.bind(withSpanOf(p.ctx, ctx))
+		defer stop()
+		if err := ticket.wait(runCtx); err != nil {
+			job.abandon(err)
+			return
+		}
+		defer ticket.done()
+		err := job.execute(runCtx, []jobStep{{phaseRotate, func(ctx context.Context) error {
+			return rotateApiKeys(p.kubeClient, ctx, namespace)
+		}}})
+		if err != nil {
+			log.Printf("API key rotation failed for namespace %s: %v", namespace, err)
+			return
+		}
+		job.succeed()
+	})
+	return job, nil
+}
+
+// rotateApiKeys generates a new management API key and has IdentityHub regenerate its super-user key. A management
+// key the restarted controlplane does not accept is rolled back, and so is the management key when IdentityHub fails
+// to regenerate its key, so the stored credentials always match what the components accept.
+func rotateApiKeys(c client.Client, ctx context.Context, namespace string) error {
+	current, err := loadCredentials(c, ctx, namespace)
+	if err != nil {
+		return fmt.Errorf("load credentials: %w", err)
+	}
+	managementKey, err := generateApiKey()
+	if err != nil {
+		return err
+	}
+
+	if err := rolloutManagementKey(c, ctx, namespace, managementKey); err != nil {
+		return rollbackManagementKey(c, ctx, namespace, current.ManagementApiKey, err)
+	}
+
+	identityApi := inClusterIdentityApi(namespace, current.IdentityApiKey)
+	identityKey, err := identityApi.RegenerateToken(ctx, superUserContextId)
+	if err != nil {
+		return rollbackManagementKey(c, ctx, namespace, current.ManagementApiKey, fmt.Errorf("regenerate identity API key: %w", err))
+	}
+
+	// IdentityHub already switched to the new key, losing it would lock the provisioner out
+	rotated := current
+	rotated.ManagementApiKey = managementKey
+	rotated.IdentityApiKey = identityKey
+	rotated.RotatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := storeCredentialsWithRetry(c, ctx, namespace, rotated); err != nil {
+		return fmt.Errorf("store credentials: %w", err)
+	}
+	identityApi = inClusterIdentityApi(namespace, identityKey)
+	if _, err := identityApi.ListParticipants(ctx); err != nil {
+		return fmt.Errorf("verify identity API key: %w", err)
+	}
+	return nil
+}
+
+// rolloutManagementKey configures the controlplane with the key, restarts it and verifies the key is accepted.
+func rolloutManagementKey(c client.Client, ctx context.Context, namespace string, key string) error {
+	if err := patchConfigMap(c, ctx, namespace, "controlplane-config", map[string]string{managementApiKeySetting: key}); err != nil {
+		return fmt.Errorf("update controlplane config: %w", err)
+	}
+	if err := restartDeployment(c, ctx, namespace, "controlplane"); err != nil {
+		return fmt.Errorf("restart controlplane: %w", err)
+	}
+	rolloutCtx, cancel := context.WithTimeout(ctx, rolloutTimeout)
+	defer cancel()
+	if err := waitForDeployment(c, rolloutCtx, namespace, "controlplane"); err != nil {
+		return fmt.Errorf("wait for rollout: %w", err)
+	}
+
+	mgmtApi := api.ApiClient{
+		BaseUrl:    fmt.Sprintf("http://%s/api/management/v3", componentAddress("controlplane", namespace, 8081)),
+		ApiKey:     key,
+		HttpClient: http.Client{Timeout: defaultHttpTimeout},
+		Retry:      api.DefaultRetry,
+	}
+	if _, err := mgmtApi.QueryAssets(ctx, assetQuerySpec); err != nil {
+		return fmt.Errorf("verify management API key: %w", err)
+	}
+	return nil
+}
+
+// rollbackManagementKey restores the previous management key after a failed rotation and returns the cause.
+func rollbackManagementKey(c client.Client, ctx context.Context, namespace string, previousKey string, cause error) error {
+	log.Printf("API key rotation failed for namespace %s, rolling back: %v", namespace, cause)
+	if err := rolloutManagementKey(c, ctx, namespace, previousKey); err != nil {
+		return errors.Join(cause, fmt.Errorf("rollback failed: %w", err))
+	}
+	return cause
+}
+
+func storeCredentialsWithRetry(c client.Client, ctx context.Context, namespace string, creds participantCredentials) error {
+	var err error
+	for attempt := 0; attempt < storeCredentialsAttempts; attempt++ {
+		if err = storeCredentials(c, ctx, namespace, creds); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return err
+}
+
+func inClusterIdentityApi(namespace string, key string) api.ApiClient {
+	return api.ApiClient{
+		BaseUrl:    fmt.Sprintf("http://%s/api/identity/v1alpha", componentAddress("identityhub", namespace, 7081)),
+		ApiKey:     key,
+		HttpClient: http.Client{Timeout: defaultHttpTimeout},
+		Retry:      api.DefaultRetry,
+	}
+}
+
+func patchConfigMap(c client.Client, ctx context.Context, namespace string, name string, data map[string]string) error {
+	patch, err := json.Marshal(map[string]any{"data": data})
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	return c.Patch(ctx, configMap, client.RawPatch(types.MergePatchType, patch))
+}
+
+// restartDeployment triggers a rolling restart the same way "kubectl rollout restart" does.
+func restartDeployment(c client.Client, ctx context.Context, namespace string, name string) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`, time.Now().Format(time.RFC3339))
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	return c.Patch(ctx, deployment, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
+
+// discoverParticipants returns the namespaces labelled as managed by the provisioner. Participants provisioned before
+// namespaces were labelled are only found by their deployments, which takes listing the deployments of the cluster, so
+// that is only done when no namespace carries the label.
+func discoverParticipants(c client.Client, ctx context.Context) ([]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		names = append(names, namespace.Name)
+	}
+	if len(names) > 0 {
+		return names, nil
+	}
+	return discoverUnlabelledParticipants(c, ctx)
+}
+
+// discoverUnlabelledParticipants returns the namespaces containing all participant deployments.
+func discoverUnlabelledParticipants(c client.Client, ctx context.Context) ([]string, error) {
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments); err != nil {
+		return nil, err
+	}
+	found := make(map[string]int)
+	for _, deployment := range deployments.Items {
+		if slices.Contains(participantDeploymentNames, deployment.Name) {
+			found[deployment.Namespace]++
+		}
+	}
+	var names []string
+	for namespace, count := range found {
+		if count == len(participantDeploymentNames) {
+			names = append(names, namespace)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
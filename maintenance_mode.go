@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The maintenance mode is kept in a ConfigMap in the provisioner's namespace, so it applies to all replicas. Replicas
+// read it again after maintenanceRefreshInterval.
+const (
+	maintenanceConfigMap       = "provisioner-maintenance"
+	maintenanceKey             = "maintenance.json"
+	maintenanceRefreshInterval = 5 * time.Second
+)
+
+// Clients rejected during maintenance are asked to retry after this period
+const maintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceState reports whether the provisioner is in maintenance mode, since when and why.
+type maintenanceState struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// maintenanceMode rejects requests changing participants while the cluster is maintained. Status reads keep working
+// and running jobs finish.
+type maintenanceMode struct {
+	client    client.Client
+	namespace string
+
+	mu        sync.Mutex
+	state     maintenanceState
+	refreshed time.Time
+}
+
+// current returns the maintenance state, read again from the cluster once it is older than the refresh interval. The
+// last known state is kept when it can't be read.
+func (m *maintenanceMode) current(ctx context.Context) maintenanceState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.refreshed) < maintenanceRefreshInterval {
+		return m.state
+	}
+	configMap := &corev1.ConfigMap{}
+	err := m.client.Get(ctx, client.ObjectKey{Namespace: m.namespace, Name: maintenanceConfigMap}, configMap)
+	switch {
+	case client.IgnoreNotFound(err) != nil:
+		fmt.Println("Reading the maintenance mode failed:", err)
+		return m.state
+	case err != nil:
+		m.state = maintenanceState{}
+	default:
+		var state maintenanceState
+		if err := json.Unmarshal([]byte(configMap.Data[maintenanceKey]), &state); err != nil {
+			fmt.Println("Ignoring unreadable maintenance mode:", err)
+			return m.state
+		}
+		m.state = state
+	}
+	m.refreshed = time.Now()
+	return m.state
+}
+
+// set enables or disables the maintenance mode for all replicas.
+func (m *maintenanceMode) set(ctx context.Context, enabled bool, reason string) (maintenanceState, error) {
+	state := maintenanceState{Enabled: enabled}
+	if enabled {
+		now := time.Now().UTC()
+		state.Reason, state.Since = reason, &now
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return state, err
+	}
+	err = applyResource(m.client, ctx, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: maintenanceConfigMap, Namespace: m.namespace},
+		Data:       map[string]string{maintenanceKey: string(data)},
+	})
+	if err != nil {
+		return state, err
+	}
+	m.mu.Lock()
+	m.state, m.refreshed = state, time.Now()
+	m.mu.Unlock()
+	return state, nil
+}
+
+// middleware rejects provisioning, upgrades, deletions and the other changes of participants with 503 during
+// maintenance. Reads, including batch status requests, pass, and so do cancellations of jobs that don't tear the
+// participant down.
+func (m *maintenanceMode) middleware(ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch {
+		case c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead:
+			return c.Next()
+		case c.Path() == "/api/v1/resources/status":
+			return c.Next()
+		case strings.HasPrefix(c.Path(), "/api/v1/jobs/") && !c.QueryBool("teardown"):
+			return c.Next()
+		}
+		state := m.current(ctx)
+		if !state.Enabled {
+			return c.Next()
+		}
+		message := "the provisioner is in maintenance mode"
+		if state.Reason != "" {
+			message += ": " + state.Reason
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(maintenanceRetryAfter.Seconds())))
+		return withCode(codeMaintenance, fiber.StatusServiceUnavailable, errors.New(message))
+	}
+}
+
+// maintenanceRequest enables or disables the maintenance mode.
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// maintenanceReport is the maintenance state and the jobs of the replica still running, the replica is drained once
+// there are none.
+type maintenanceReport struct {
+	maintenanceState
+	UnfinishedJobs []string `json:"unfinishedJobs"`
+	Drained        bool     `json:"drained"`
+}
+
+func reportMaintenance(state maintenanceState) maintenanceReport {
+	unfinished := jobs.unfinished()
+	return maintenanceReport{maintenanceState: state, UnfinishedJobs: unfinished, Drained: len(unfinished) == 0}
+}
+
+// getMaintenanceMode reports the maintenance state and whether the replica finished its jobs.
+func getMaintenanceMode(mode *maintenanceMode, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(reportMaintenance(mode.current(ctx)))
+	}
+}
+
+// setMaintenanceMode enables or disables the maintenance mode. Jobs started before keep running, the report tells
+// when they finished.
+func setMaintenanceMode(mode *maintenanceMode, ctx context.Context, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request maintenanceRequest
+		if err := parser.parse(c, &request); err != nil {
+			return err
+		}
+		state, err := mode.set(ctx, request.Enabled, request.Reason)
+		if err != nil {
+			return err
+		}
+		if state.Enabled {
+			fmt.Println("Maintenance mode enabled:", state.Reason)
+		} else {
+			fmt.Println("Maintenance mode disabled")
+		}
+		return c.JSON(reportMaintenance(state))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	ctx := context.Background()
+	store := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	mode := &maintenanceMode{client: store, namespace: "provisioner"}
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use("/api/v1/resources", mode.middleware(ctx))
+	app.Use("/api/v1/jobs", mode.middleware(ctx))
+	app.All("/*", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+	request := func(method string, target string) *http.Response {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := request(http.MethodPost, "/api/v1/resources"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected provisioning to pass without maintenance, got %d", resp.StatusCode)
+	}
+	if _, err := mode.set(ctx, true, "cluster upgrade"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.configMaps[client.ObjectKey{Namespace: "provisioner", Name: maintenanceConfigMap}]; !ok {
+		t.Fatal("maintenance mode not kept in a ConfigMap")
+	}
+
+	// Another replica reads the mode from the ConfigMap
+	replica := &maintenanceMode{client: store, namespace: "provisioner"}
+	if state := replica.current(ctx); !state.Enabled || state.Reason != "cluster upgrade" || state.Since == nil {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodPost, "/api/v1/resources", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/resources/acme", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/resources/acme", http.StatusOK},
+		{http.MethodPost, "/api/v1/resources/status", http.StatusOK},
+		{http.MethodDelete, "/api/v1/jobs/1234", http.StatusOK},
+		{http.MethodDelete, "/api/v1/jobs/1234?teardown=true", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		resp := request(tt.method, tt.target)
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, resp.StatusCode)
+		}
+		if tt.want == http.StatusServiceUnavailable && resp.Header.Get(fiber.HeaderRetryAfter) != "300" {
+			t.Errorf("%s %s: unexpected Retry-After %q", tt.method, tt.target, resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+	}
+
+	if _, err := mode.set(ctx, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if resp := request(http.MethodPost, "/api/v1/resources"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected provisioning to pass after maintenance, got %d", resp.StatusCode)
+	}
+}
+
+func TestMaintenanceReportDrained(t *testing.T) {
+	job, err := jobs.create(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := reportMaintenance(maintenanceState{Enabled: true}); report.Drained || !slices.Contains(report.UnfinishedJobs, job.Id) {
+		t.Fatalf("expected job %s to be unfinished, got %+v", job.Id, report)
+	}
+	job.succeed()
+	if report := reportMaintenance(maintenanceState{Enabled: true}); slices.Contains(report.UnfinishedJobs, job.Id) {
+		t.Errorf("expected job %s to be finished, got %+v", job.Id, report)
+	}
+}
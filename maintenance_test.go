@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// discoveryClient lists namespaces by label and the deployments of the cluster.
+type discoveryClient struct {
+	client.Client
+	namespaces  []corev1.Namespace
+	deployments []appsv1.Deployment
+	lists       int
+}
+
+func (c *discoveryClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.lists++
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	switch result := list.(type) {
+	case *corev1.NamespaceList:
+		for _, namespace := range c.namespaces {
+			if options.LabelSelector == nil || options.LabelSelector.Matches(labels.Set(namespace.Labels)) {
+				result.Items = append(result.Items, namespace)
+			}
+		}
+	case *appsv1.DeploymentList:
+		result.Items = c.deployments
+	}
+	return nil
+}
+
+func participantDeployments(namespace string, names ...string) []appsv1.Deployment {
+	var deployments []appsv1.Deployment
+	for _, name := range names {
+		deployments = append(deployments, appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}})
+	}
+	return deployments
+}
+
+func TestDiscoverParticipants(t *testing.T) {
+	c := &discoveryClient{
+		namespaces: []corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "bob"}},
+		},
+		deployments: participantDeployments("bob", participantDeploymentNames...),
+	}
+	names, err := discoverParticipants(c, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"alice"}) || c.lists != 1 {
+		t.Errorf("expected only the labelled namespace from a single list, got %v after %d lists", names, c.lists)
+	}
+}
+
+func TestDiscoverUnlabelledParticipants(t *testing.T) {
+	c := &discoveryClient{
+		namespaces:  []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "bob"}}, {ObjectMeta: metav1.ObjectMeta{Name: "web"}}},
+		deployments: append(participantDeployments("bob", participantDeploymentNames...), participantDeployments("web", "controlplane")...),
+	}
+	names, err := discoverParticipants(c, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"bob"}) {
+		t.Errorf("expected the namespace with all participant deployments, got %v", names)
+	}
+}
+
+// rotationComponents serves the management API of the controlplane, accepting the keys accepted returns true for,
+// and the identity API of IdentityHub, regenerating the super-user key with the given status.
+func rotationComponents(t *testing.T, accepted func(key string) bool, regenerateStatus int) *int {
+	t.Helper()
+	regenerations := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-api-key")
+		switch {
+		case r.URL.Path == "/api/management/v3/assets/request" && accepted(key):
+			_, _ = w.Write([]byte("[]"))
+		case r.URL.Path == "/api/identity/v1alpha/participants/"+superUserContextId+"/token" && key == "identity-key":
+			regenerations++
+			w.WriteHeader(regenerateStatus)
+			_, _ = w.Write([]byte(`"regenerated-identity-key"`))
+		case r.URL.Path == "/api/identity/v1alpha/participants" && key == "regenerated-identity-key":
+			_, _ = w.Write([]byte("[]"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	previous := componentAddress
+	componentAddress = func(string, string, int) string { return strings.TrimPrefix(server.URL, "http://") }
+	t.Cleanup(func() { componentAddress = previous })
+	return &regenerations
+}
+
+// applyingClient writes objects applied with server-side apply, which the fake client doesn't support, as creates or
+// updates.
+type applyingClient struct {
+	client.Client
+}
+
+func (c applyingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch != client.Apply {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	if err := c.Update(ctx, obj); !apierrors.IsNotFound(err) {
+		return err
+	}
+	return c.Create(ctx, obj)
+}
+
+// rotationCluster holds the credentials, controlplane config and ready controlplane of the participant acme.
+func rotationCluster() client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	return applyingClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "acme"},
+			Data: map[string][]byte{managementApiKeyField: []byte("management-key"), identityApiKeyField: []byte("identity-key")}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-config", Namespace: "acme"},
+			Data: map[string]string{managementApiKeySetting: "management-key"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "acme"},
+			Status: appsv1.DeploymentStatus{ObservedGeneration: 10, Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1}},
+	).Build()}
+}
+
+// configuredManagementKey returns the management key the controlplane of acme is configured with.
+func configuredManagementKey(t *testing.T, c client.Client) string {
+	t.Helper()
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "acme", Name: "controlplane-config"}, configMap); err != nil {
+		t.Fatal(err)
+	}
+	return configMap.Data[managementApiKeySetting]
+}
+
+func TestRotateApiKeys(t *testing.T) {
+	rotationComponents(t, func(string) bool { return true }, http.StatusOK)
+	c := rotationCluster()
+
+	if err := rotateApiKeys(c, context.Background(), "acme"); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := loadCredentials(c, context.Background(), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.ManagementApiKey == "management-key" || creds.ManagementApiKey != configuredManagementKey(t, c) {
+		t.Errorf("expected a new management key to be stored and configured, got %q", creds.ManagementApiKey)
+	}
+	if creds.IdentityApiKey != "regenerated-identity-key" || creds.RotatedAt == "" {
+		t.Errorf("expected the regenerated identity key to be stored, got %+v", creds)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "acme", Name: "controlplane"}, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] == "" {
+		t.Error("expected the controlplane to be restarted")
+	}
+}
+
+func TestRotateApiKeysRollsBack(t *testing.T) {
+	for name, test := range map[string]struct {
+		accepted         func(key string) bool
+		regenerateStatus int
+		cause            string
+		regenerations    int
+	}{
+		"rejected management key": {func(key string) bool { return key == "management-key" }, http.StatusOK, "verify management API key", 0},
+		"failed regeneration":     {func(string) bool { return true }, http.StatusForbidden, "regenerate identity API key", 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			regenerations := rotationComponents(t, test.accepted, test.regenerateStatus)
+			c := rotationCluster()
+
+			err := rotateApiKeys(c, context.Background(), "acme")
+			if err == nil || !strings.Contains(err.Error(), test.cause) || strings.Contains(err.Error(), "rollback failed") {
+				t.Fatalf("expected the rotation to fail with %q and be rolled back, got %v", test.cause, err)
+			}
+			if *regenerations != test.regenerations {
+				t.Errorf("expected %d regenerations of the identity key, got %d", test.regenerations, *regenerations)
+			}
+			if key := configuredManagementKey(t, c); key != "management-key" {
+				t.Errorf("expected the previous management key to be configured again, got %q", key)
+			}
+			creds, err := loadCredentials(c, context.Background(), "acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.ManagementApiKey != "management-key" || creds.IdentityApiKey != "identity-key" || creds.RotatedAt != "" {
+				t.Errorf("expected the stored credentials to be unchanged, got %+v", creds)
+			}
+		})
+	}
+}
+
+func TestRollbackManagementKeyReportsFailure(t *testing.T) {
+	rotationComponents(t, func(string) bool { return false }, http.StatusOK)
+	cause := errors.New("verify management API key: rejected")
+
+	err := rollbackManagementKey(rotationCluster(), context.Background(), "acme", "management-key", cause)
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "rollback failed") {
+		t.Errorf("expected the cause and the failed rollback to be reported, got %v", err)
+	}
+}
+
+func TestCheckRotatedParticipants(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	).Build()
+
+	if err := checkRotatedParticipants(c, context.Background(), []string{"acme"}); err != nil {
+		t.Errorf("expected managed participants to be rotated, got %v", err)
+	}
+	for _, test := range []struct {
+		namespaces []string
+		code       int
+	}{
+		{[]string{"acme", "Acme_Corp"}, fiber.StatusBadRequest},
+		{[]string{"acme", "acme"}, fiber.StatusBadRequest},
+		{[]string{"initech"}, fiber.StatusNotFound},
+		{[]string{"kube-system"}, fiber.StatusNotFound},
+	} {
+		if problem := problemOf(checkRotatedParticipants(c, context.Background(), test.namespaces)); problem.Status != test.code {
+			t.Errorf("%v: expected %d, got %d", test.namespaces, test.code, problem.Status)
+		}
+	}
+}
+
+func TestStartKeyRotations(t *testing.T) {
+	rotationComponents(t, func(string) bool { return true }, http.StatusOK)
+	c := rotationCluster()
+	p := &provisioner{kubeClient: c, ctx: context.Background(), queue: newWorkQueue(1, 0, nil)}
+
+	results := p.startKeyRotations(context.Background(), []string{"acme"})
+	if len(results) != 1 || results[0].JobId == "" {
+		t.Fatalf("expected a rotation job, got %+v", results)
+	}
+	job := jobs.get(results[0].JobId)
+	if !job.wait(10 * time.Second) {
+		t.Fatal("expected the rotation job to finish")
+	}
+	if job.Status != jobSucceeded || len(job.Phases) != 1 || job.Phases[0].Name != phaseRotate {
+		t.Errorf("unexpected job state %s with phases %+v: %s", job.Status, job.Phases, job.Error)
+	}
+	if key := configuredManagementKey(t, c); key == "management-key" {
+		t.Error("expected the management key to be rotated")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// File names, or ConfigMap keys, of the participant manifests in an external manifest source
+const (
+	connectorManifest   = "connector.yaml"
+	identityhubManifest = "identityhub.yaml"
+)
+
+const manifestFetchTimeout = 30 * time.Second
+
+// manifestSet holds the manifest templates participants are rendered from.
+type manifestSet struct {
+	Connector   string
+	IdentityHub string
+	// Chart, if set, renders the participant stack instead of the Connector and IdentityHub templates
+	Chart *helmChart
+	// Source describes where the manifests were loaded from
+	Source   string
+	LoadedAt time.Time
+}
+
+// manifestStore serves the current manifest set and reloads it from the configured source. Without a source it
+// serves the manifests embedded in the binary.
+type manifestStore struct {
+	mu      sync.RWMutex
+	current manifestSet
+	// source is a directory, a URL the manifest files are fetched from, configmap:<namespace>/<name> or helm:<chart>
+	source string
+	client client.Client
+	// helm holds the settings charts are rendered with
+	helm helmChart
+}
+
+func newManifestStore(source string, c client.Client) *manifestStore {
+	return &manifestStore{
+		source: source,
+		client: c,
+		current: manifestSet{
+			Connector:   participantYaml,
+			IdentityHub: identityhubYaml,
+			Source:      "embedded",
+			LoadedAt:    time.Now(),
+		},
+	}
+}
+
+func (s *manifestStore) get() manifestSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// reload fetches the manifests from the source and replaces the current set if they are valid. Manifests missing
+// from the source fall back to the embedded ones.
+func (s *manifestStore) reload(ctx context.Context) (manifestSet, error) {
+	if s.source == "" {
+		return s.get(), nil
+	}
+	if strings.HasPrefix(s.source, helmSourcePrefix) {
+		return s.reloadChart(ctx)
+	}
+	files, err := s.fetch(ctx)
+	if err != nil {
+		return manifestSet{}, fmt.Errorf("load manifests from %s: %w", s.source, err)
+	}
+	set := manifestSet{
+		Connector:   participantYaml,
+		IdentityHub: identityhubYaml,
+		Source:      s.source,
+		LoadedAt:    time.Now(),
+	}
+	if manifest, ok := files[connectorManifest]; ok {
+		set.Connector = manifest
+	}
+	if manifest, ok := files[identityhubManifest]; ok {
+		set.IdentityHub = manifest
+	}
+	for name, manifest := range map[string]string{connectorManifest: set.Connector, identityhubManifest: set.IdentityHub} {
+		if err := validateManifestTemplate(manifest); err != nil {
+			return manifestSet{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.current = set
+	s.mu.Unlock()
+	fmt.Println("Loaded manifests from", s.source)
+	return set, nil
+}
+
+// reloadChart checks that the chart renders for a sample participant before participants are rendered from it.
+func (s *manifestStore) reloadChart(ctx context.Context) (manifestSet, error) {
+	chart := s.helm
+	chart.chart = strings.TrimPrefix(s.source, helmSourcePrefix)
+	manifest, err := chart.render(ctx, ParticipantDefinition{ParticipantName: "sample", Did: "did:web:sample"})
+	if err != nil {
+		return manifestSet{}, fmt.Errorf("load manifests from %s: %w", s.source, err)
+	}
+	if err := validateManifest(manifest); err != nil {
+		return manifestSet{}, fmt.Errorf("%s: %w", chart.chart, err)
+	}
+	set := manifestSet{Chart: &chart, Source: s.source, LoadedAt: time.Now()}
+	s.mu.Lock()
+	s.current = set
+	s.mu.Unlock()
+	fmt.Println("Loaded manifests from", s.source)
+	return set, nil
+}
+
+// fetch returns the manifest files present in the source, keyed by file name.
+func (s *manifestStore) fetch(ctx context.Context) (map[string]string, error) {
+	files := make(map[string]string)
+	switch {
+	case strings.HasPrefix(s.source, "configmap:"):
+		namespace, name, ok := strings.Cut(strings.TrimPrefix(s.source, "configmap:"), "/")
+		if !ok {
+			return nil, fmt.Errorf("expected configmap:<namespace>/<name>")
+		}
+		configMap := &corev1.ConfigMap{}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, configMap); err != nil {
+			return nil, err
+		}
+		for _, file := range []string{connectorManifest, identityhubManifest} {
+			if manifest, ok := configMap.Data[file]; ok {
+				files[file] = manifest
+			}
+		}
+	case strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://"):
+		httpClient := http.Client{Timeout: manifestFetchTimeout}
+		for _, file := range []string{connectorManifest, identityhubManifest} {
+			manifest, found, err := fetchManifest(ctx, httpClient, strings.TrimSuffix(s.source, "/")+"/"+file)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				files[file] = manifest
+			}
+		}
+	default:
+		for _, file := range []string{connectorManifest, identityhubManifest} {
+			content, err := os.ReadFile(filepath.Join(s.source, file))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			files[file] = string(content)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("neither %s nor %s found", connectorManifest, identityhubManifest)
+	}
+	return files, nil
+}
+
+func fetchManifest(ctx context.Context, httpClient http.Client, url string) (string, bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", false, err
+	}
+	return string(body), true, nil
+}
+
+// validateManifest checks that every document of a manifest template is a Kubernetes object.
+func validateManifest(manifest string) error {
+	for i, doc := range strings.Split(manifest, "---") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			return fmt.Errorf("document %d: %w", i+1, err)
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return fmt.Errorf("document %d: missing apiVersion or kind", i+1)
+		}
+	}
+	return nil
+}
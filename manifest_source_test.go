@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbeddedManifestsAreValid(t *testing.T) {
+	for name, manifest := range map[string]string{connectorManifest: participantYaml, identityhubManifest: identityhubYaml} {
+		if err := validateManifest(manifest); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestReloadFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	connector := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: custom\n"
+	if err := os.WriteFile(filepath.Join(dir, connectorManifest), []byte(connector), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := newManifestStore(dir, nil)
+	set, err := store.reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Connector != connector || store.get().Connector != connector {
+		t.Error("connector manifest not loaded from directory")
+	}
+	if set.IdentityHub != identityhubYaml {
+		t.Error("missing identityhub manifest did not fall back to the embedded one")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, connectorManifest), []byte("metadata:\n  name: broken\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.reload(context.Background()); err == nil {
+		t.Error("expected manifest without kind to be rejected")
+	}
+	if store.get().Connector != connector {
+		t.Error("invalid manifest replaced the current one")
+	}
+}
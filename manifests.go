@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// objectMutator customizes a rendered manifest object before it is sent to the cluster.
+type objectMutator func(obj *unstructured.Unstructured) error
+
+// errSkipObject is returned by mutators to leave the object out, e.g. components replaced by external services.
+var errSkipObject = errors.New("object skipped")
+
+// ServiceOptions overrides how a participant Service is exposed.
+type ServiceOptions struct {
+	Type           string            `json:"type,omitempty"`
+	IPFamilyPolicy string            `json:"ipFamilyPolicy,omitempty"`
+	IPFamilies     []string          `json:"ipFamilies,omitempty"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+}
+
+var validServiceTypes = map[string]bool{"ClusterIP": true, "NodePort": true, "LoadBalancer": true}
+
+var validIPFamilyPolicies = map[string]bool{"SingleStack": true, "PreferDualStack": true, "RequireDualStack": true}
+
+var validIPFamilies = map[string]bool{"IPv4": true, "IPv6": true}
+
+func (o ServiceOptions) validate(name string) error {
+	if o.Type != "" && !validServiceTypes[o.Type] {
+		return fmt.Errorf("service %s: unsupported type %q", name, o.Type)
+	}
+	if o.IPFamilyPolicy != "" && !validIPFamilyPolicies[o.IPFamilyPolicy] {
+		return fmt.Errorf("service %s: unsupported ipFamilyPolicy %q", name, o.IPFamilyPolicy)
+	}
+	for _, family := range o.IPFamilies {
+		if !validIPFamilies[family] {
+			return fmt.Errorf("service %s: unsupported ipFamily %q", name, family)
+		}
+	}
+	return nil
+}
+
+// mutators returns the manifest customizations requested by the definition.
+func (p *ParticipantDefinition) mutators() []objectMutator {
+	mutators := []objectMutator{ownershipMutator(p.ParticipantName, p.Did, p.Labels, p.Annotations), componentVersionMutator(p.ComponentVersions, p.ComponentImages), dataspaceMutator(dataspaceOf(*p))}
+	if p.Metadata != nil {
+		mutators = append(mutators, p.Metadata.mutator())
+	}
+	if len(p.Components) > 0 {
+		mutators = append(mutators, componentMutator(p.Components))
+	}
+	if len(p.Services) > 0 {
+		mutators = append(mutators, serviceMutator(p.Services))
+	}
+	if p.Mesh != nil {
+		mutators = append(mutators, p.Mesh.mutator())
+	}
+	if p.Tier != "" {
+		mutators = append(mutators, priorityClassMutator(p.Tier))
+	}
+	if p.Flavor != "" {
+		mutators = append(mutators, flavorMutator(p.Flavor))
+	}
+	if p.Size != "" {
+		mutators = append(mutators, sizingProfiles[p.Size].mutator())
+	}
+	if p.persistsPostgres() {
+		mutators = append(mutators, postgresDataMutator)
+	}
+	if len(p.Scaling) > 0 {
+		mutators = append(mutators, scalingMutator(p.Scaling))
+	}
+	if p.Ingress != nil {
+		mutators = append(mutators, p.Ingress.mutator(p.ParticipantName, p.pathPrefix()))
+	}
+	if p.Tls != nil {
+		mutators = append(mutators, p.Tls.mutator())
+	}
+	if p.Protected {
+		mutators = append(mutators, protectionMutator)
+	}
+	if p.AdoptNamespace {
+		mutators = append(mutators, adoptedNamespaceMutator)
+	}
+	if len(p.Env) > 0 {
+		mutators = append(mutators, componentEnvMutator(p.Env))
+	}
+	return mutators
+}
+
+// extraManifests renders objects that are not part of the templates but requested by the definition.
+func (p *ParticipantDefinition) extraManifests() (string, error) {
+	var docs []string
+	if p.Mesh != nil {
+		doc, err := p.Mesh.manifests(p.ParticipantName)
+		if err != nil {
+			return "", err
+		}
+		if doc != "" {
+			docs = append(docs, doc)
+		}
+	}
+	if p.Tls != nil {
+		doc, err := p.Tls.manifests(p.ParticipantName)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, doc)
+	}
+	if routingOf(*p) == routingGateway {
+		host := ""
+		if p.Ingress != nil && p.Ingress.Host != "" {
+			host = p.Ingress.Host
+		} else if p.Tls != nil {
+			host = p.Tls.Host
+		}
+		doc, err := gatewayFor(*p).manifests(p.ParticipantName, host, p.Tls)
+		if err != nil {
+			return "", err
+		}
+		if doc != "" {
+			docs = append(docs, doc)
+		}
+	}
+	if p.persistsPostgres() {
+		size, storageClass := p.postgresClaim()
+		doc, err := postgresClaimManifest(p.ParticipantName, size, storageClass)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, doc)
+	}
+	if len(p.Scaling) > 0 {
+		doc, err := scalingManifests(p.ParticipantName, p.Scaling)
+		if err != nil {
+			return "", err
+		}
+		if doc != "" {
+			docs = append(docs, doc)
+		}
+	}
+	if p.NetworkPolicies != nil {
+		doc, err := p.NetworkPolicies.manifests(p.ParticipantName)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, doc)
+	}
+	return strings.Join(docs, "\n---\n"), nil
+}
+
+// serviceMutator applies ServiceOptions to the Services they are keyed by.
+func serviceMutator(options map[string]ServiceOptions) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Service" {
+			return nil
+		}
+		opts, ok := options[obj.GetName()]
+		if !ok {
+			return nil
+		}
+		if opts.Type != "" {
+			if err := unstructured.SetNestedField(obj.Object, opts.Type, "spec", "type"); err != nil {
+				return err
+			}
+		}
+		if opts.IPFamilyPolicy != "" {
+			if err := unstructured.SetNestedField(obj.Object, opts.IPFamilyPolicy, "spec", "ipFamilyPolicy"); err != nil {
+				return err
+			}
+		}
+		if len(opts.IPFamilies) > 0 {
+			if err := unstructured.SetNestedStringSlice(obj.Object, opts.IPFamilies, "spec", "ipFamilies"); err != nil {
+				return err
+			}
+		}
+		if len(opts.Annotations) > 0 {
+			addAnnotations(obj, opts.Annotations)
+		}
+		return nil
+	}
+}
+
+func addLabels(obj *unstructured.Unstructured, labels map[string]string) {
+	merged := obj.GetLabels()
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	obj.SetLabels(merged)
+}
+
+func addAnnotations(obj *unstructured.Unstructured, annotations map[string]string) {
+	merged := obj.GetAnnotations()
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	obj.SetAnnotations(merged)
+}
+
+func addPodTemplateLabels(obj *unstructured.Unstructured, labels map[string]string) error {
+	merged, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	if err != nil {
+		return err
+	}
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return unstructured.SetNestedStringMap(obj.Object, merged, "spec", "template", "metadata", "labels")
+}
+
+func addPodTemplateAnnotations(obj *unstructured.Unstructured, annotations map[string]string) error {
+	merged, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+	if err != nil {
+		return err
+	}
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	return unstructured.SetNestedStringMap(obj.Object, merged, "spec", "template", "metadata", "annotations")
+}
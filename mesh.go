@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	meshIstio   = "istio"
+	meshLinkerd = "linkerd"
+)
+
+// MeshOptions enrols the participant into a service mesh.
+type MeshOptions struct {
+	// Provider is either "istio" or "linkerd"
+	Provider string `json:"provider"`
+	// MTLSMode is STRICT or PERMISSIVE, rendered as Istio PeerAuthentication or Linkerd default inbound policy
+	MTLSMode string `json:"mtlsMode,omitempty"`
+	// TrafficPolicy is rendered into an Istio DestinationRule covering all participant services
+	TrafficPolicy map[string]any `json:"trafficPolicy,omitempty"`
+	// Annotations are added to all participant pods, e.g. proxy resource settings
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// ExcludeInboundPorts and ExcludeOutboundPorts bypass the sidecar, e.g. for the DSP port of connectors whose
+	// counterparts are outside the mesh and can't speak mTLS
+	ExcludeInboundPorts  []int `json:"excludeInboundPorts,omitempty"`
+	ExcludeOutboundPorts []int `json:"excludeOutboundPorts,omitempty"`
+}
+
+// defaultMesh enrols the participants of dataspaces without mesh settings that don't set their own, set with --mesh,
+// --mesh-mtls-mode, --mesh-exclude-inbound-ports and --mesh-exclude-outbound-ports. Nil leaves them out of the mesh.
+var defaultMesh *MeshOptions
+
+// meshFor returns the mesh settings of the definition, falling back to those of the dataspace and the defaults.
+func meshFor(dataspace DataspaceConfig, definition ParticipantDefinition) *MeshOptions {
+	switch {
+	case definition.Mesh != nil:
+		return definition.Mesh
+	case dataspace.Mesh != nil:
+		return dataspace.Mesh
+	}
+	return defaultMesh
+}
+
+// parsePorts parses a comma separated list of ports, e.g. of a flag.
+func parsePorts(list string) ([]int, error) {
+	var ports []int
+	for _, entry := range splitList(list) {
+		port, err := strconv.Atoi(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", entry)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// joinPorts renders ports as the comma separated list the injection annotations take.
+func joinPorts(ports []int) string {
+	entries := make([]string, len(ports))
+	for i, port := range ports {
+		entries[i] = strconv.Itoa(port)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (m *MeshOptions) validate() error {
+	if m.Provider != meshIstio && m.Provider != meshLinkerd {
+		return fmt.Errorf("mesh: unsupported provider %q", m.Provider)
+	}
+	if m.MTLSMode != "" && m.MTLSMode != "STRICT" && m.MTLSMode != "PERMISSIVE" {
+		return fmt.Errorf("mesh: unsupported mtlsMode %q", m.MTLSMode)
+	}
+	if m.TrafficPolicy != nil && m.Provider != meshIstio {
+		return fmt.Errorf("mesh: trafficPolicy is only supported with %s", meshIstio)
+	}
+	for _, port := range append(append([]int{}, m.ExcludeInboundPorts...), m.ExcludeOutboundPorts...) {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("mesh: invalid excluded port %d", port)
+		}
+	}
+	return nil
+}
+
+// mutator labels the namespace and annotates pod templates for sidecar injection.
+func (m *MeshOptions) mutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		switch obj.GetKind() {
+		case "Namespace":
+			if m.Provider == meshIstio {
+				addLabels(obj, map[string]string{"istio-injection": "enabled"})
+			} else {
+				annotations := map[string]string{"linkerd.io/inject": "enabled"}
+				if m.MTLSMode == "STRICT" {
+					annotations["config.linkerd.io/default-inbound-policy"] = "all-authenticated"
+				}
+				addAnnotations(obj, annotations)
+			}
+		case "Deployment", "StatefulSet":
+			annotations := map[string]string{}
+			if m.Provider == meshIstio {
+				annotations["sidecar.istio.io/inject"] = "true"
+				// EDC needs the database on startup, so don't start it before the proxy can route traffic
+				annotations["proxy.istio.io/config"] = "holdApplicationUntilProxyStarts: true"
+				if len(m.ExcludeInboundPorts) > 0 {
+					annotations["traffic.sidecar.istio.io/excludeInboundPorts"] = joinPorts(m.ExcludeInboundPorts)
+				}
+				if len(m.ExcludeOutboundPorts) > 0 {
+					annotations["traffic.sidecar.istio.io/excludeOutboundPorts"] = joinPorts(m.ExcludeOutboundPorts)
+				}
+			} else {
+				annotations["linkerd.io/inject"] = "enabled"
+				annotations["config.alpha.linkerd.io/proxy-wait-before-exit-seconds"] = "5"
+				if len(m.ExcludeInboundPorts) > 0 {
+					annotations["config.linkerd.io/skip-inbound-ports"] = joinPorts(m.ExcludeInboundPorts)
+				}
+				if len(m.ExcludeOutboundPorts) > 0 {
+					annotations["config.linkerd.io/skip-outbound-ports"] = joinPorts(m.ExcludeOutboundPorts)
+				}
+			}
+			for k, v := range m.Annotations {
+				annotations[k] = v
+			}
+			return addPodTemplateAnnotations(obj, annotations)
+		}
+		return nil
+	}
+}
+
+// manifests renders the Istio policy objects requested by the options.
+func (m *MeshOptions) manifests(namespace string) (string, error) {
+	if m.Provider != meshIstio {
+		return "", nil
+	}
+	var docs []string
+	if m.MTLSMode != "" {
+		doc, err := yaml.Marshal(map[string]any{
+			"apiVersion": "security.istio.io/v1beta1",
+			"kind":       "PeerAuthentication",
+			"metadata":   map[string]any{"name": "default", "namespace": namespace},
+			"spec":       map[string]any{"mtls": map[string]any{"mode": m.MTLSMode}},
+		})
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	if m.TrafficPolicy != nil {
+		doc, err := yaml.Marshal(map[string]any{
+			"apiVersion": "networking.istio.io/v1beta1",
+			"kind":       "DestinationRule",
+			"metadata":   map[string]any{"name": "default", "namespace": namespace},
+			"spec": map[string]any{
+				"host":          "*." + namespace + ".svc.cluster.local",
+				"trafficPolicy": m.TrafficPolicy,
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "\n---\n"), nil
+}
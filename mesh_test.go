@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMeshExcludedPorts(t *testing.T) {
+	for provider, expected := range map[string][2]string{
+		meshIstio:   {"traffic.sidecar.istio.io/excludeInboundPorts", "traffic.sidecar.istio.io/excludeOutboundPorts"},
+		meshLinkerd: {"config.linkerd.io/skip-inbound-ports", "config.linkerd.io/skip-outbound-ports"},
+	} {
+		mesh := &MeshOptions{Provider: provider, ExcludeInboundPorts: []int{8282, 8283}, ExcludeOutboundPorts: []int{5432}}
+		if err := mesh.validate(); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &unstructured.Unstructured{Object: map[string]any{"kind": "Deployment"}}
+		if err := mesh.mutator()(deployment); err != nil {
+			t.Fatal(err)
+		}
+		annotations, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "annotations")
+		if annotations[expected[0]] != "8282,8283" || annotations[expected[1]] != "5432" {
+			t.Errorf("%s: unexpected annotations %v", provider, annotations)
+		}
+	}
+
+	if err := (&MeshOptions{Provider: meshIstio, ExcludeInboundPorts: []int{70000}}).validate(); err == nil {
+		t.Error("expected invalid ports to be rejected")
+	}
+}
+
+func TestMeshDefaults(t *testing.T) {
+	defer func(mesh *MeshOptions) { defaultMesh = mesh }(defaultMesh)
+	defaultMesh = &MeshOptions{Provider: meshLinkerd}
+	dataspace := DataspaceConfig{Mesh: &MeshOptions{Provider: meshIstio}}
+	own := &MeshOptions{Provider: meshIstio, MTLSMode: "STRICT"}
+
+	if mesh := meshFor(DataspaceConfig{}, ParticipantDefinition{}); mesh != defaultMesh {
+		t.Errorf("expected the default mesh, got %v", mesh)
+	}
+	if mesh := meshFor(dataspace, ParticipantDefinition{}); mesh != dataspace.Mesh {
+		t.Errorf("expected the mesh of the dataspace, got %v", mesh)
+	}
+	if mesh := meshFor(dataspace, ParticipantDefinition{Mesh: own}); mesh != own {
+		t.Errorf("expected the mesh of the definition, got %v", mesh)
+	}
+	if ports, err := parsePorts("8282, 8283"); err != nil || joinPorts(ports) != "8282,8283" {
+		t.Errorf("unexpected ports %v, %v", ports, err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Upper bounds in seconds of the buckets provisioning durations are counted in
+var provisioningDurationBuckets = []float64{5, 10, 30, 60, 120, 300, 600, 900, 1800, 3600}
+
+// Durations of the completed provisionings per phase, total being the time from the request until READY
+var provisioningDurations = newHistogram("provisioner_provisioning_duration_seconds", "Duration of successful participant provisionings from the request until READY, by phase", "phase", provisioningDurationBuckets)
+
+// histogram counts observations in cumulative buckets per value of its label, served in the Prometheus text format.
+type histogram struct {
+	name    string
+	help    string
+	label   string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name string, help string, label string, buckets []float64) *histogram {
+	return &histogram{name: name, help: help, label: label, buckets: buckets, series: make(map[string]*histogramSeries)}
+}
+
+func (h *histogram) observe(labelValue string, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.series[labelValue]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[labelValue] = series
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.count++
+	series.sum += value
+}
+
+// write renders the histogram in the Prometheus text exposition format.
+func (h *histogram) write(out *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	values := make([]string, 0, len(h.series))
+	for value := range h.series {
+		values = append(values, value)
+	}
+	slices.Sort(values)
+	for _, value := range values {
+		series := h.series[value]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(out, "%s_bucket{%s=%q,le=%q} %d\n", h.name, h.label, value, strconv.FormatFloat(bound, 'g', -1, 64), series.counts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket{%s=%q,le=\"+Inf\"} %d\n", h.name, h.label, value, series.count)
+		fmt.Fprintf(out, "%s_sum{%s=%q} %s\n", h.name, h.label, value, strconv.FormatFloat(series.sum, 'g', -1, 64))
+		fmt.Fprintf(out, "%s_count{%s=%q} %d\n", h.name, h.label, value, series.count)
+	}
+}
+
+// serveMetrics serves the provisioner's metrics for Prometheus to scrape, and the state of the participants unless
+// fleet is nil. Failing to list the participants leaves out their gauges rather than failing the scrape.
+func serveMetrics(fleet *fleetMetrics, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var out strings.Builder
+		provisioningDurations.write(&out)
+		if fleet != nil {
+			if err := fleet.write(ctx, &out); err != nil {
+				fmt.Println("Exporting participant metrics failed:", err)
+			}
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4")
+		return c.SendString(out.String())
+	}
+}
+
+// durations breaks down how long the succeeded job took, from its creation until it finished, and counts the
+// durations in the provisioning histogram.
+func (j *provisioningJob) durations() status.ProvisioningDurations {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	format := func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	}
+	finished := time.Now()
+	if j.FinishedAt != nil {
+		finished = *j.FinishedAt
+	}
+	total := finished.Sub(j.CreatedAt)
+	durations := status.ProvisioningDurations{Total: format(total), CompletedAt: finished}
+	provisioningDurations.observe("total", total.Seconds())
+	if len(j.Phases) > 0 {
+		queued := j.Phases[0].StartedAt.Sub(j.CreatedAt)
+		durations.Queued = format(queued)
+		provisioningDurations.observe("queued", queued.Seconds())
+	}
+	for _, phase := range j.Phases {
+		if phase.FinishedAt == nil {
+			continue
+		}
+		took := phase.FinishedAt.Sub(phase.StartedAt)
+		provisioningDurations.observe(phase.Name, took.Seconds())
+		switch phase.Name {
+		case phaseApply:
+			durations.Apply = format(took)
+		case phaseReadiness:
+			durations.Readiness = format(took)
+		case phaseSeeding:
+			durations.Seeding = format(took)
+		case phaseHooks:
+			durations.Hooks = format(took)
+		}
+	}
+	return durations
+}
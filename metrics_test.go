@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProvisioningDurations(t *testing.T) {
+	created := time.Now().Add(-10 * time.Minute)
+	at := func(offset time.Duration) *time.Time {
+		t := created.Add(offset)
+		return &t
+	}
+	job := &provisioningJob{
+		CreatedAt: created,
+		Phases: []jobPhase{
+			{Name: phaseApply, StartedAt: *at(30 * time.Second), FinishedAt: at(40 * time.Second)},
+			{Name: phaseReadiness, StartedAt: *at(40 * time.Second), FinishedAt: at(4 * time.Minute)},
+			{Name: phaseSeeding, StartedAt: *at(4 * time.Minute), FinishedAt: at(5 * time.Minute)},
+		},
+		FinishedAt: at(5 * time.Minute),
+	}
+
+	durations := job.durations()
+	if durations.Total != "5m0s" || durations.Queued != "30s" || durations.Apply != "10s" || durations.Readiness != "3m20s" || durations.Seeding != "1m0s" || durations.Hooks != "" {
+		t.Errorf("unexpected durations %+v", durations)
+	}
+
+	app := fiber.New()
+	app.Get("/metrics", serveMetrics(nil, context.Background()))
+	response, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	for _, line := range []string{
+		"# TYPE provisioner_provisioning_duration_seconds histogram",
+		`provisioner_provisioning_duration_seconds_bucket{phase="total",le="300"} `,
+		`provisioner_provisioning_duration_seconds_bucket{phase="readiness",le="120"} `,
+		`provisioner_provisioning_duration_seconds_sum{phase="apply"} `,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics lack %q:\n%s", line, body)
+		}
+	}
+}
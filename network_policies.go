@@ -0,0 +1,117 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Namespace of the ingress controller, unless the options name another one
+const defaultIngressNamespace = "ingress-nginx"
+
+// Label every namespace carries with its name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// Ports of the participant's endpoints other participants and the issuer talk to
+var participantPorts = map[string][]int{
+	"controlplane": {8082},       // dataspace protocol
+	"dataplane":    {11002},      // public API
+	"identityhub":  {7082, 7083}, // credentials and DID documents
+}
+
+// NetworkPolicyOptions isolates the participant namespace: inbound traffic is denied except from the ingress
+// controller, between the participant's own components and to the endpoints other participants use. Outbound traffic
+// is left open, as connectors reach other participants, the issuer and DID hosts.
+type NetworkPolicyOptions struct {
+	// IngressNamespace is the namespace of the ingress controller, ingress-nginx by default
+	IngressNamespace string `json:"ingressNamespace,omitempty"`
+	// AllowNamespaces may reach the endpoints other participants use as well, e.g. the issuer's namespace
+	AllowNamespaces []string `json:"allowNamespaces,omitempty"`
+}
+
+func (n *NetworkPolicyOptions) validate() error {
+	for _, namespace := range append([]string{n.IngressNamespace}, n.AllowNamespaces...) {
+		if namespace == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("networkPolicies: invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// manifests renders the NetworkPolicies of the namespace.
+func (n *NetworkPolicyOptions) manifests(namespace string) (string, error) {
+	ingressNamespace := n.IngressNamespace
+	if ingressNamespace == "" {
+		ingressNamespace = defaultIngressNamespace
+	}
+	connector := map[string]any{"matchExpressions": []any{
+		map[string]any{"key": "App", "operator": "In", "values": []string{"controlplane", "dataplane", "identityhub"}},
+	}}
+	fromIngress := []any{map[string]any{"namespaceSelector": map[string]any{"matchLabels": map[string]string{namespaceNameLabel: ingressNamespace}}}}
+
+	peers := []any{map[string]any{"namespaceSelector": map[string]any{"matchLabels": map[string]string{status.ManagedByLabel: status.ManagedByValue}}}}
+	for _, allowed := range n.AllowNamespaces {
+		peers = append(peers, map[string]any{"namespaceSelector": map[string]any{"matchLabels": map[string]string{namespaceNameLabel: allowed}}})
+	}
+	policies := []map[string]any{
+		networkPolicy(namespace, "default-deny-ingress", map[string]any{}, nil),
+		networkPolicy(namespace, "allow-ingress-controller", connector, []any{map[string]any{"from": fromIngress}}),
+		// seeding writes secrets to the vault through the ingress
+		networkPolicy(namespace, "allow-ingress-controller-vault", appLabel("app", "vault"), []any{
+			map[string]any{"from": fromIngress, "ports": tcpPorts(8200)},
+		}),
+		networkPolicy(namespace, "allow-connector", connector, []any{map[string]any{"from": []any{map[string]any{"podSelector": connector}}}}),
+		networkPolicy(namespace, "allow-postgres", appLabel("App", "postgres"), []any{
+			map[string]any{"from": []any{map[string]any{"podSelector": connector}}, "ports": tcpPorts(5432)},
+		}),
+		networkPolicy(namespace, "allow-vault", appLabel("app", "vault"), []any{
+			map[string]any{"from": []any{map[string]any{"podSelector": connector}}, "ports": tcpPorts(8200)},
+		}),
+	}
+	for _, app := range []string{"controlplane", "dataplane", "identityhub"} {
+		policies = append(policies, networkPolicy(namespace, "allow-participants-"+app, appLabel("App", app), []any{
+			map[string]any{"from": peers, "ports": tcpPorts(participantPorts[app]...)},
+		}))
+	}
+
+	docs := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		doc, err := yaml.Marshal(policy)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "\n---\n"), nil
+}
+
+func networkPolicy(namespace string, name string, podSelector map[string]any, ingress []any) map[string]any {
+	spec := map[string]any{"podSelector": podSelector, "policyTypes": []string{"Ingress"}}
+	if ingress != nil {
+		spec["ingress"] = ingress
+	}
+	return map[string]any{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       spec,
+	}
+}
+
+func appLabel(label string, app string) map[string]any {
+	return map[string]any{"matchLabels": map[string]string{label: app}}
+}
+
+func tcpPorts(ports ...int) []any {
+	result := make([]any, len(ports))
+	for i, port := range ports {
+		result[i] = map[string]any{"protocol": "TCP", "port": port}
+	}
+	return result
+}
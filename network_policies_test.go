@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestNetworkPolicyManifests(t *testing.T) {
+	options := &NetworkPolicyOptions{AllowNamespaces: []string{"poc-issuer"}}
+	rendered, err := options.manifests("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := make(map[string]map[string]any)
+	for _, doc := range strings.Split(rendered, "---") {
+		var policy map[string]any
+		if err := yaml.Unmarshal([]byte(doc), &policy); err != nil {
+			t.Fatal(err)
+		}
+		metadata := policy["metadata"].(map[string]any)
+		if metadata["namespace"] != "alice" {
+			t.Errorf("policy %s rendered into namespace %v", metadata["name"], metadata["namespace"])
+		}
+		policies[metadata["name"].(string)] = policy
+	}
+
+	deny, ok := policies["default-deny-ingress"]
+	if !ok {
+		t.Fatal("default deny policy missing")
+	}
+	if spec := deny["spec"].(map[string]any); len(spec["podSelector"].(map[string]any)) != 0 || spec["ingress"] != nil {
+		t.Errorf("expected the default deny policy to select all pods without allowing anything, got %v", spec)
+	}
+	for _, name := range []string{"allow-ingress-controller", "allow-connector", "allow-postgres", "allow-vault", "allow-participants-controlplane"} {
+		if _, ok := policies[name]; !ok {
+			t.Errorf("policy %s missing", name)
+		}
+	}
+	for _, expected := range []string{"kubernetes.io/metadata.name: ingress-nginx", "kubernetes.io/metadata.name: poc-issuer", "app.kubernetes.io/managed-by: aruba-provisioner"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected the policies to select %q", expected)
+		}
+	}
+}
+
+func TestNetworkPolicyOptionsValidate(t *testing.T) {
+	if err := (&NetworkPolicyOptions{AllowNamespaces: []string{"Issuer_NS"}}).validate(); err == nil {
+		t.Error("expected invalid namespaces to be rejected")
+	}
+	if err := (&NetworkPolicyOptions{IngressNamespace: "traefik"}).validate(); err != nil {
+		t.Error(err)
+	}
+}
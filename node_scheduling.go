@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var validTolerationEffects = map[corev1.TaintEffect]bool{"": true, corev1.TaintEffectNoSchedule: true, corev1.TaintEffectPreferNoSchedule: true, corev1.TaintEffectNoExecute: true}
+
+// SchedulingOptions pins the pods of a participant's components to nodes, e.g. to a dedicated node pool.
+type SchedulingOptions struct {
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity replaces the affinity of the pods
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+func (s *SchedulingOptions) validate() error {
+	if s == nil {
+		return nil
+	}
+	for key, value := range s.NodeSelector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("scheduling: nodeSelector %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("scheduling: nodeSelector %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, toleration := range s.Tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+			if toleration.Key == "" {
+				return fmt.Errorf("scheduling: tolerations with operator Equal need a key")
+			}
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("scheduling: toleration %s: operator Exists takes no value", toleration.Key)
+			}
+		default:
+			return fmt.Errorf("scheduling: toleration %s: unsupported operator %q", toleration.Key, toleration.Operator)
+		}
+		if !validTolerationEffects[toleration.Effect] {
+			return fmt.Errorf("scheduling: toleration %s: unsupported effect %q", toleration.Key, toleration.Effect)
+		}
+	}
+	return nil
+}
+
+// mergeScheduling returns the scheduling of the dataspace overridden by that of the definition, nil if neither sets
+// one. Node selectors of the definition win over those of the dataspace, tolerations of both apply.
+func mergeScheduling(dataspace *SchedulingOptions, definition *SchedulingOptions) *SchedulingOptions {
+	if dataspace == nil || definition == nil {
+		if dataspace != nil {
+			return dataspace
+		}
+		return definition
+	}
+	merged := &SchedulingOptions{NodeSelector: make(map[string]string), Affinity: dataspace.Affinity}
+	for _, options := range []*SchedulingOptions{dataspace, definition} {
+		for key, value := range options.NodeSelector {
+			merged.NodeSelector[key] = value
+		}
+		merged.Tolerations = append(merged.Tolerations, options.Tolerations...)
+	}
+	if definition.Affinity != nil {
+		merged.Affinity = definition.Affinity
+	}
+	return merged
+}
+
+// mutator adds the node selector and tolerations to the pod templates of all deployments and stateful sets and sets
+// their affinity.
+func (s *SchedulingOptions) mutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Deployment" && obj.GetKind() != "StatefulSet" {
+			return nil
+		}
+		if len(s.NodeSelector) > 0 {
+			if err := addNestedLabels(obj.Object, s.NodeSelector, "spec", "template", "spec", "nodeSelector"); err != nil {
+				return err
+			}
+		}
+		if len(s.Tolerations) > 0 {
+			tolerations, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "tolerations")
+			if err != nil {
+				return err
+			}
+			for i := range s.Tolerations {
+				toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&s.Tolerations[i])
+				if err != nil {
+					return err
+				}
+				tolerations = append(tolerations, toleration)
+			}
+			if err := unstructured.SetNestedSlice(obj.Object, tolerations, "spec", "template", "spec", "tolerations"); err != nil {
+				return err
+			}
+		}
+		if s.Affinity != nil {
+			affinity, err := runtime.DefaultUnstructuredConverter.ToUnstructured(s.Affinity)
+			if err != nil {
+				return err
+			}
+			return unstructured.SetNestedMap(obj.Object, affinity, "spec", "template", "spec", "affinity")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSchedulingMutator(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]any{
+		"kind": "Deployment",
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"nodeSelector": map[string]any{"kubernetes.io/os": "linux"},
+			"tolerations":  []any{map[string]any{"key": "existing", "operator": "Exists"}},
+		}}},
+	}}
+	scheduling := mergeScheduling(
+		&SchedulingOptions{NodeSelector: map[string]string{"pool": "shared"}, Tolerations: []corev1.Toleration{{Key: "dataspace", Operator: corev1.TolerationOpExists}}},
+		&SchedulingOptions{
+			NodeSelector: map[string]string{"pool": "participants"},
+			Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "participants", Effect: corev1.TaintEffectNoSchedule}},
+			Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}}},
+			}}},
+		},
+	)
+	if err := scheduling.mutator()(deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeSelector, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "spec", "nodeSelector")
+	if nodeSelector["pool"] != "participants" || nodeSelector["kubernetes.io/os"] != "linux" {
+		t.Errorf("unexpected node selector %v", nodeSelector)
+	}
+	tolerations, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "tolerations")
+	if len(tolerations) != 3 || tolerations[2].(map[string]any)["effect"] != "NoSchedule" {
+		t.Errorf("unexpected tolerations %v", tolerations)
+	}
+	terms, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+	if len(terms) != 1 {
+		t.Errorf("unexpected affinity %v", deployment.Object["spec"])
+	}
+
+	service := &unstructured.Unstructured{Object: map[string]any{"kind": "Service", "spec": map[string]any{}}}
+	if err := scheduling.mutator()(service); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := unstructured.NestedFieldNoCopy(service.Object, "spec", "template"); ok {
+		t.Error("expected services to be left alone")
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *SchedulingOptions
+		valid   bool
+	}{
+		{"none", nil, true},
+		{"node selector", &SchedulingOptions{NodeSelector: map[string]string{"node.kubernetes.io/pool": "participants"}}, true},
+		{"invalid node selector value", &SchedulingOptions{NodeSelector: map[string]string{"pool": "not a label"}}, false},
+		{"toleration of all taints of a key", &SchedulingOptions{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}}, true},
+		{"exists with value", &SchedulingOptions{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "x"}}}, false},
+		{"equal without key", &SchedulingOptions{Tolerations: []corev1.Toleration{{Value: "x"}}}, false},
+		{"unknown effect", &SchedulingOptions{Tolerations: []corev1.Toleration{{Key: "dedicated", Effect: "Evict"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.options.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid = %v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"aruba-provisioner/api/status"
+)
+
+// Events sent when provisioning, seeding or deleting a participant completes
+const (
+	eventParticipantCreated    = "participant.created"
+	eventParticipantReady      = "participant.ready"
+	eventParticipantSeedFailed = "participant.seed_failed"
+	eventParticipantDeleted    = "participant.deleted"
+	eventParticipantExpired    = "participant.expired"
+)
+
+// Events sent when the reported status of a participant changes
+const (
+	eventParticipantDegraded  = "participant.degraded"
+	eventParticipantFailed    = "participant.failed"
+	eventParticipantRecovered = "participant.recovered"
+)
+
+// LifecycleEvent describes a noteworthy change in a participant's life, e.g. its activation.
+type LifecycleEvent struct {
+	Type        string            `json:"type"`
+	Participant string            `json:"participant"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+func newLifecycleEvent(eventType string, participant string, details map[string]string) LifecycleEvent {
+	return LifecycleEvent{
+		Type:        eventType,
+		Participant: participant,
+		Timestamp:   time.Now(),
+		Details:     details,
+	}
+}
+
+// Notifier delivers lifecycle events to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, event LifecycleEvent) error
+}
+
+type logNotifier struct{}
+
+func (logNotifier) Notify(_ context.Context, event LifecycleEvent) error {
+	fmt.Println("Lifecycle event", event.Type, "for participant", event.Participant, event.Details)
+	return nil
+}
+
+// webhookNotifier POSTs events as JSON to a configured URL.
+type webhookNotifier struct {
+	url        string
+	httpClient http.Client
+}
+
+func (w webhookNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	rq, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	resp, err := w.httpClient.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+// multiNotifier fans out events to all notifiers and reports every failure.
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// summary describes an event in one line, for notifiers delivering text instead of JSON.
+func (e LifecycleEvent) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: participant %s", e.Type, e.Participant)
+	keys := make([]string, 0, len(e.Details))
+	for key := range e.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, ", %s: %s", key, e.Details[key])
+	}
+	return b.String()
+}
+
+// slackNotifier posts events as messages to a Slack incoming webhook.
+type slackNotifier struct {
+	webhookNotifier
+}
+
+func (s slackNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	body, err := json.Marshal(map[string]string{"text": event.summary()})
+	if err != nil {
+		return err
+	}
+	rq, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// emailNotifier mails events through an SMTP server, authenticating when a username is set.
+type emailNotifier struct {
+	server   string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (e emailNotifier) Notify(_ context.Context, event LifecycleEvent) error {
+	var auth smtp.Auth
+	if e.username != "" {
+		host, _, _ := strings.Cut(e.server, ":")
+		auth = smtp.PlainAuth("", e.username, e.password, host)
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [aruba-provisioner] %s %s\r\n\r\n%s\r\n",
+		e.from, strings.Join(e.to, ", "), event.Type, event.Participant, event.summary())
+	if err := smtp.SendMail(e.server, auth, e.from, e.to, []byte(message)); err != nil {
+		return fmt.Errorf("mail to %s failed: %w", strings.Join(e.to, ", "), err)
+	}
+	return nil
+}
+
+// filteredNotifier only delivers events of the given types.
+type filteredNotifier struct {
+	Notifier
+	types map[string]bool
+}
+
+func (f filteredNotifier) Notify(ctx context.Context, event LifecycleEvent) error {
+	if !f.types[event.Type] {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, event)
+}
+
+// notificationSettings configures where lifecycle events are delivered to, besides the log.
+type notificationSettings struct {
+	webhookUrl      string
+	slackWebhookUrl string
+	smtpServer      string
+	smtpUsername    string
+	smtpPassword    string
+	emailFrom       string
+	emailTo         []string
+	// events restricts the event types delivered to the webhook, Slack and mail, all are delivered when empty
+	events []string
+	// Message brokers receive all events, for downstream systems to react to
+	natsUrl      string
+	natsSubject  string
+	kafkaRestUrl string
+	kafkaTopic   string
+}
+
+func newNotifier(settings notificationSettings) Notifier {
+	var notifiers multiNotifier
+	if settings.webhookUrl != "" {
+		notifiers = append(notifiers, webhookNotifier{url: settings.webhookUrl, httpClient: http.Client{Timeout: 10 * time.Second}})
+	}
+	if settings.slackWebhookUrl != "" {
+		notifiers = append(notifiers, slackNotifier{webhookNotifier{url: settings.slackWebhookUrl, httpClient: http.Client{Timeout: 10 * time.Second}}})
+	}
+	if settings.smtpServer != "" && len(settings.emailTo) > 0 {
+		notifiers = append(notifiers, emailNotifier{
+			server:   settings.smtpServer,
+			username: settings.smtpUsername,
+			password: settings.smtpPassword,
+			from:     settings.emailFrom,
+			to:       settings.emailTo,
+		})
+	}
+	var delivered Notifier = notifiers
+	if len(settings.events) > 0 {
+		types := make(map[string]bool, len(settings.events))
+		for _, eventType := range settings.events {
+			types[eventType] = true
+		}
+		delivered = filteredNotifier{Notifier: notifiers, types: types}
+	}
+	all := multiNotifier{logNotifier{}, delivered}
+	if settings.natsUrl != "" {
+		all = append(all, &natsNotifier{url: settings.natsUrl, subject: settings.natsSubject})
+	}
+	if settings.kafkaRestUrl != "" {
+		all = append(all, kafkaNotifier{url: settings.kafkaRestUrl, topic: settings.kafkaTopic, httpClient: http.Client{Timeout: brokerTimeout}})
+	}
+	return all
+}
+
+// announce delivers a lifecycle event of a participant in the background, so slow receivers don't hold up jobs.
+func (p *provisioner) announce(eventType string, participant string, details map[string]string) {
+	if p.notifier == nil {
+		return
+	}
+	event := newLifecycleEvent(eventType, participant, details)
+	go func() {
+		ctx, cancel := context.WithTimeout(p.ctx, brokerTimeout)
+		defer cancel()
+		if err := p.notifier.Notify(ctx, event); err != nil {
+			fmt.Printf("notification of %s for %s failed: %v\n", eventType, participant, err)
+		}
+	}()
+}
+
+// transitionEvent maps a status transition of a participant to the lifecycle event announcing it.
+func transitionEvent(transition status.Transition) LifecycleEvent {
+	eventType := eventParticipantDegraded
+	switch transition.To {
+	case status.StatusFailed:
+		eventType = eventParticipantFailed
+	case status.StatusReady:
+		eventType = eventParticipantRecovered
+	}
+	details := map[string]string{"from": string(transition.From), "to": string(transition.To)}
+	if transition.Component != "" {
+		details["component"] = transition.Component
+	}
+	if transition.Message != "" {
+		details["message"] = transition.Message
+	}
+	event := newLifecycleEvent(eventType, transition.Participant, details)
+	event.Timestamp = transition.Timestamp
+	return event
+}
+
+// notifyTransitions delivers the notable status transitions of participants as lifecycle events.
+func notifyTransitions(ctx context.Context, notifier Notifier) func(status.Transition) {
+	return func(transition status.Transition) {
+		go func() {
+			if err := notifier.Notify(ctx, transitionEvent(transition)); err != nil {
+				fmt.Printf("notification of %s becoming %s failed: %v\n", transition.Participant, transition.To, err)
+			}
+		}()
+	}
+}
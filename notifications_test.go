@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aruba-provisioner/api/status"
+)
+
+func TestTransitionEvent(t *testing.T) {
+	event := transitionEvent(status.Transition{
+		Participant: "alice",
+		From:        status.StatusReady,
+		To:          status.StatusDegraded,
+		Component:   "dataplane",
+		Message:     "degraded components: dataplane",
+		Timestamp:   time.Now(),
+	})
+	if event.Type != eventParticipantDegraded || event.Participant != "alice" || event.Details["component"] != "dataplane" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if summary := event.summary(); summary != "participant.degraded: participant alice, component: dataplane, from: READY, message: degraded components: dataplane, to: DEGRADED" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	if recovered := transitionEvent(status.Transition{Participant: "alice", From: status.StatusDegraded, To: status.StatusReady}); recovered.Type != eventParticipantRecovered {
+		t.Errorf("expected a recovery event, got %+v", recovered)
+	}
+}
+
+func TestSlackNotifierFiltersEvents(t *testing.T) {
+	var messages []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		messages = append(messages, body["text"])
+	}))
+	defer slack.Close()
+
+	notifier := newNotifier(notificationSettings{slackWebhookUrl: slack.URL, events: []string{eventParticipantFailed}})
+	ctx := context.Background()
+	if err := notifier.Notify(ctx, newLifecycleEvent(eventParticipantActivated, "alice", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(ctx, newLifecycleEvent(eventParticipantFailed, "alice", map[string]string{"component": "postgres"})); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "participant alice, component: postgres") {
+		t.Errorf("expected only the failure to be posted, got %v", messages)
+	}
+}
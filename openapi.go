@@ -0,0 +1,371 @@
+package main
+
+import (
+	_ "embed"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed ui/swagger.html
+var swaggerHtml string
+
+// apiOperation describes an endpoint of the API for the OpenAPI document.
+type apiOperation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	// params lists the query parameters as name: description
+	params  map[string]string
+	request any
+	// responses maps status codes to a Go value of the response body type, nil for empty bodies. Strings stand for
+	// bodies of the given media type.
+	responses map[int]any
+	admin     bool
+	// public endpoints are served without authentication
+	public bool
+}
+
+// Body of 202 responses to requests handled by a background job
+var acceptedJob = struct {
+	JobId       string `json:"jobId"`
+	Participant string `json:"participant"`
+	// QueuePosition is set when the job waits for a free slot
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// NotBefore is set instead of the job when the provisioning was scheduled
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+}{}
+
+var quotaReport = struct {
+	Limits   quotaLimits `json:"limits"`
+	Defaults quotaLimits `json:"defaults"`
+	Usage    quotaUsage  `json:"usage"`
+}{}
+
+var jobResponse = map[int]any{http.StatusAccepted: acceptedJob, http.StatusNotFound: nil}
+
+// apiOperations lists the /api/v1 endpoints, the OpenAPI document is generated from it and the request and response
+// types of the handlers.
+var apiOperations = []apiOperation{
+	{method: "post", path: "/api/v1/resources", tag: "participants", summary: "Provision a participant",
+		params:    map[string]string{"dryRun": "true returns the rendered manifests, server additionally validates them with the API server", "record": "true records the run for bug reports", "verifyDid": "true checks that the DID document lists the participant's CredentialService and DSP endpoint before provisioning"},
+		request:   ParticipantDefinition{},
+		responses: map[int]any{http.StatusAccepted: acceptedJob, http.StatusOK: "application/yaml", http.StatusBadRequest: nil, http.StatusForbidden: nil, http.StatusConflict: nil, http.StatusUnprocessableEntity: nil, http.StatusServiceUnavailable: nil}},
+	{method: "post", path: "/api/v1/resources/batch", tag: "participants", summary: "Provision several participants",
+		params:    map[string]string{"concurrency": "number of participants provisioned at a time"},
+		request:   []ParticipantDefinition{},
+		responses: map[int]any{http.StatusAccepted: []batchResult{}}},
+	{method: "post", path: "/api/v1/resources/import", tag: "participants", summary: "Restore a participant from an export",
+		request:   participantExport{},
+		responses: map[int]any{http.StatusAccepted: acceptedJob, http.StatusBadRequest: nil, http.StatusConflict: nil, http.StatusServiceUnavailable: nil}},
+	{method: "get", path: "/api/v1/resources", tag: "participants", summary: "List participants",
+		params:    map[string]string{"sort": "name, status or lastUpdated, name by default", "order": "asc or desc", "limit": "participants per page, all by default", "continue": "token of the next page", "label": "label selector the participants must match, e.g. team=alpha", "team": "team of the participants' metadata", "environment": "environment of the participants' metadata"},
+		responses: map[int]any{http.StatusOK: participantPage{}, http.StatusBadRequest: nil, http.StatusServiceUnavailable: nil}},
+	{method: "put", path: "/api/v1/resources/{participantName}", tag: "participants", summary: "Upgrade a participant",
+		request: ParticipantDefinition{}, responses: jobResponse},
+	{method: "delete", path: "/api/v1/resources/{participantName}", tag: "participants", summary: "Delete a participant, or move it to the recycle bin if a grace period is configured",
+		params: map[string]string{"record": "true records the run for bug reports", "force": "true deletes a protected participant", "confirm": "token confirming the deletion of a protected participant",
+			"immediate": "true tears the participant down without keeping it in the recycle bin"}, responses: jobResponse},
+	{method: "post", path: "/api/v1/resources/{participantName}/restore", tag: "participants", summary: "Take a participant out of the recycle bin",
+		responses: map[int]any{http.StatusOK: nil, http.StatusConflict: nil, http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}", tag: "participants", summary: "Get the DID, URLs, component versions and seeding summary of a participant",
+		responses: map[int]any{http.StatusOK: participantDetails{}, http.StatusNotFound: nil}},
+	{method: "post", path: "/api/v1/resources/status", tag: "participants", summary: "Get the statuses of several participants",
+		params:  map[string]string{"fields": "comma separated sections to include", "refresh": "true evaluates the statuses from the cluster instead of returning cached ones"},
+		request: statusBatchRequest{},
+		responses: map[int]any{http.StatusOK: struct {
+			Participants []status.ParticipantStatus `json:"participants"`
+			Summary      status.StatusSummary       `json:"summary"`
+		}{}, http.StatusBadRequest: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/status", tag: "participants", summary: "Get the status of a participant",
+		params:    map[string]string{"fields": "comma separated sections to include", "refresh": "true evaluates the status from the cluster instead of returning a cached one"},
+		responses: map[int]any{http.StatusOK: status.ParticipantStatus{}, http.StatusNotFound: status.ParticipantStatus{}, http.StatusServiceUnavailable: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/status/stream", tag: "participants", summary: "Stream the status of a participant as server-sent events",
+		params:    map[string]string{"fields": "comma separated sections to include"},
+		responses: map[int]any{http.StatusOK: "text/event-stream"}},
+	{method: "get", path: "/api/v1/resources/{participantName}/status/history", tag: "participants", summary: "List the status changes of a participant, oldest first",
+		responses: map[int]any{http.StatusOK: []status.HistoryEntry{}}},
+	{method: "get", path: "/api/v1/resources/{participantName}/events", tag: "participants", summary: "List the events of a participant",
+		params:    map[string]string{"type": "Normal or Warning", "reason": "only events with this reason", "object": "involved object as Kind/Name or name", "since": "RFC 3339 time of the oldest event", "until": "RFC 3339 time of the newest event", "limit": "events per page, 50 by default", "continue": "token of the next page"},
+		responses: map[int]any{http.StatusOK: listPage[status.Event]{}, http.StatusBadRequest: nil}},
+	{method: "post", path: "/api/v1/resources/{participantName}/smoketest", tag: "participants", summary: "Request a catalog, negotiate a contract and dry-run a transfer with a counterparty",
+		params:    map[string]string{"counterparty": "participant whose catalog is requested, the participant itself by default"},
+		request:   smokeTestRequest{},
+		responses: map[int]any{http.StatusOK: smokeTestResult{}, http.StatusBadRequest: nil, http.StatusNotFound: nil, http.StatusConflict: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/logs", tag: "admin", summary: "Get the recent logs of a component's pods",
+		params:    map[string]string{"component": "controlplane, dataplane, identityhub or postgres", "tail": "lines per pod, 200 by default", "container": "container of the pods, by default the one named like the component", "previous": "true returns the logs of the previous container instance, e.g. after a crash"},
+		responses: map[int]any{http.StatusOK: "text/plain", http.StatusBadRequest: nil, http.StatusNotFound: nil}, admin: true},
+	{method: "get", path: "/api/v1/resources/{participantName}/badge.svg", tag: "participants", summary: "Get a status badge",
+		responses: map[int]any{http.StatusOK: "image/svg+xml"}},
+	{method: "get", path: "/api/v1/resources/{participantName}/manifests", tag: "participants", summary: "Get the live objects applied for a participant",
+		responses: map[int]any{http.StatusOK: "application/yaml", http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/credentials", tag: "participants", summary: "Get the API keys of the management and identity APIs of a participant, the read is audited",
+		responses: map[int]any{http.StatusOK: participantApiKeys{}, http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/export", tag: "participants", summary: "Export the definition, live objects and seeded entities of a participant for backup",
+		responses: map[int]any{http.StatusOK: participantExport{}, http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/drift", tag: "participants", summary: "List the objects changed or deleted out-of-band since the participant was last applied",
+		responses: map[int]any{http.StatusOK: driftReport{}, http.StatusNotFound: nil, http.StatusConflict: nil}},
+	{method: "get", path: "/api/v1/resources/{participantName}/revisions", tag: "participants", summary: "List the applied revisions",
+		responses: map[int]any{http.StatusOK: []revision{}}},
+	{method: "post", path: "/api/v1/resources/{participantName}/rollback", tag: "participants", summary: "Roll back to a revision",
+		params: map[string]string{"revision": "number of the revision to roll back to"}, responses: jobResponse},
+	{method: "post", path: "/api/v1/resources/{participantName}/reconcile", tag: "participants", summary: "Apply the latest revision again, undoing drift",
+		params: map[string]string{"seed": "true also runs the seed steps that failed or never ran"}, responses: jobResponse},
+	{method: "post", path: "/api/v1/resources/{participantName}/rotate-did", tag: "participants", summary: "Change the DID of a participant, recreating its participant context and credentials",
+		params:  map[string]string{"verifyDid": "true checks the document of the new DID before anything is applied"},
+		request: didRotationRequest{}, responses: map[int]any{http.StatusAccepted: acceptedJob, http.StatusBadRequest: nil, http.StatusNotFound: nil, http.StatusConflict: nil}},
+	{method: "post", path: "/api/v1/resources/{participantName}/seed", tag: "participants", summary: "Resume the seeding of a partially seeded participant",
+		responses: jobResponse},
+	{method: "get", path: "/api/v1/resources/{participantName}/hooks", tag: "admin", summary: "Get the results of post-provisioning hooks",
+		responses: map[int]any{http.StatusOK: []hookResult{}, http.StatusNotFound: nil}, admin: true},
+	{method: "get", path: "/api/v1/resources/{participantName}/recording", tag: "admin", summary: "Download the recording of a run",
+		responses: map[int]any{http.StatusOK: "application/json", http.StatusNotFound: nil}, admin: true},
+	{method: "get", path: "/api/v1/jobs/{id}", tag: "jobs", summary: "Get a job",
+		responses: map[int]any{http.StatusOK: provisioningJob{}, http.StatusNotFound: nil}},
+	{method: "delete", path: "/api/v1/jobs/{id}", tag: "jobs", summary: "Cancel a queued or running job",
+		params:    map[string]string{"teardown": "delete the participant once the job stopped, the deletion job is returned"},
+		responses: map[int]any{http.StatusOK: provisioningJob{}, http.StatusAccepted: acceptedJob, http.StatusNotFound: nil, http.StatusConflict: nil}},
+	{method: "get", path: "/api/v1/schedules", tag: "jobs", summary: "List the scheduled provisionings and teardowns, the next one first",
+		responses: map[int]any{http.StatusOK: []scheduledOperation{}}},
+	{method: "delete", path: "/api/v1/schedules/{participantName}", tag: "jobs", summary: "Cancel the scheduled provisioning of a participant",
+		responses: map[int]any{http.StatusNoContent: nil, http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/audit", tag: "audit", summary: "List who created, updated, rolled back, seeded or deleted participants or cancelled their jobs",
+		params:    map[string]string{"participant": "only entries of this participant"},
+		responses: map[int]any{http.StatusOK: listPage[auditEntry]{}, http.StatusBadRequest: nil}},
+	{method: "get", path: "/api/v1/compatibility", tag: "participants", summary: "Get the component compatibility matrix",
+		responses: map[int]any{http.StatusOK: struct {
+			CurrentTemplateVersion string                  `json:"currentTemplateVersion"`
+			Matrix                 []templateCompatibility `json:"matrix"`
+			OutdatedParticipants   map[string]string       `json:"outdatedParticipants"`
+		}{}}},
+	{method: "get", path: "/api/v1/reports/health", tag: "admin", summary: "Get the fleet health report",
+		params:    map[string]string{"format": "json or csv"},
+		responses: map[int]any{http.StatusOK: []participantHealth{}}, admin: true},
+	{method: "post", path: "/api/v1/maintenance/rotate-api-keys", tag: "admin", summary: "Rotate the API keys of participants",
+		request: keyRotationRequest{}, responses: map[int]any{http.StatusAccepted: []batchResult{}, http.StatusBadRequest: nil, http.StatusNotFound: nil}, admin: true},
+	{method: "get", path: "/api/v1/maintenance/mode", tag: "admin", summary: "Get the maintenance mode and the unfinished jobs",
+		responses: map[int]any{http.StatusOK: maintenanceReport{}}, admin: true},
+	{method: "put", path: "/api/v1/maintenance/mode", tag: "admin", summary: "Enable or disable the maintenance mode, rejecting changes of participants with 503",
+		request: maintenanceRequest{}, responses: map[int]any{http.StatusOK: maintenanceReport{}}, admin: true},
+	{method: "post", path: "/api/v1/maintenance/reload-manifests", tag: "admin", summary: "Reload the participant manifests",
+		responses: map[int]any{http.StatusOK: struct {
+			Source   string    `json:"source"`
+			LoadedAt time.Time `json:"loadedAt"`
+			Flavors  map[string]struct {
+				Source   string    `json:"source"`
+				LoadedAt time.Time `json:"loadedAt"`
+			} `json:"flavors"`
+		}{}}, admin: true},
+	{method: "get", path: "/api/v1/admin/templates", tag: "admin", summary: "Get the loaded manifest templates and seed resources with their source, checksum and problems",
+		params:    map[string]string{"flavor": "the templates of this flavor instead of the default stack"},
+		responses: map[int]any{http.StatusOK: templateReport{}, http.StatusBadRequest: nil}, admin: true},
+	{method: "get", path: "/api/v1/admin/background-tasks", tag: "admin", summary: "List the readiness waits and seedings running in the background, and those interrupted by a restart that are resumed",
+		responses: map[int]any{http.StatusOK: []backgroundTask{}}, admin: true},
+	{method: "get", path: "/api/v1/maintenance/janitor", tag: "admin", summary: "Get the latest sweep of the janitor over stuck participants",
+		responses: map[int]any{http.StatusOK: janitorSweep{}, http.StatusNotFound: nil}, admin: true},
+	{method: "post", path: "/api/v1/maintenance/janitor", tag: "admin", summary: "Sweep stuck participants now",
+		params: map[string]string{"policy": "report, repair or delete, the configured policy by default"}, responses: map[int]any{http.StatusOK: janitorSweep{}}, admin: true},
+	{method: "post", path: "/api/v1/issuers", tag: "admin", summary: "Provision the issuer service of a dataspace",
+		request: IssuerDefinition{}, responses: map[int]any{http.StatusAccepted: struct {
+			JobId  string `json:"jobId"`
+			Issuer string `json:"issuer"`
+			Did    string `json:"did"`
+		}{}, http.StatusBadRequest: nil, http.StatusConflict: nil}, admin: true},
+	{method: "get", path: "/api/v1/issuers/{issuerName}", tag: "admin", summary: "Get the status of an issuer service and its dataspace settings",
+		responses: map[int]any{http.StatusOK: issuerStatus{}, http.StatusNotFound: nil}, admin: true},
+	{method: "post", path: "/api/v1/catalogs", tag: "admin", summary: "Provision a standalone federated catalog node of a dataspace",
+		request: FederatedCatalogDefinition{}, responses: map[int]any{http.StatusCreated: federatedCatalogStatus{}, http.StatusBadRequest: nil, http.StatusConflict: nil}, admin: true},
+	{method: "get", path: "/api/v1/catalogs/{catalogName}", tag: "admin", summary: "Get the status of a standalone federated catalog node",
+		responses: map[int]any{http.StatusOK: federatedCatalogStatus{}, http.StatusNotFound: nil}, admin: true},
+	{method: "post", path: "/api/v1/dataspaces", tag: "dataspaces", summary: "Create a dataspace with the settings its participants share",
+		request: dataspaceDefinition{}, responses: map[int]any{http.StatusCreated: dataspaceReport{}, http.StatusBadRequest: nil, http.StatusConflict: nil}, admin: true},
+	{method: "get", path: "/api/v1/dataspaces", tag: "dataspaces", summary: "List the dataspaces",
+		responses: map[int]any{http.StatusOK: listPage[dataspaceReport]{}}},
+	{method: "get", path: "/api/v1/dataspaces/{dataspaceName}", tag: "dataspaces", summary: "Get a dataspace with the aggregate status of its participants",
+		responses: map[int]any{http.StatusOK: dataspaceReport{}, http.StatusNotFound: nil}},
+	{method: "get", path: "/api/v1/quotas", tag: "admin", summary: "Get the participant limits and their usage",
+		responses: map[int]any{http.StatusOK: quotaReport}, admin: true},
+	{method: "put", path: "/api/v1/quotas", tag: "admin", summary: "Replace the participant limits",
+		request: quotaLimits{}, responses: map[int]any{http.StatusOK: quotaReport, http.StatusBadRequest: nil}, admin: true},
+	{method: "post", path: "/api/v1/callbacks/{participantName}/{token}", tag: "callbacks", summary: "Report a connector event",
+		request: edcEventEnvelope{}, responses: map[int]any{http.StatusNoContent: nil, http.StatusUnauthorized: nil}, public: true},
+}
+
+// openApiDocument generates the OpenAPI 3 document of the API.
+func openApiDocument() fiber.Map {
+	schemas := fiber.Map{}
+	paths := fiber.Map{}
+	for _, op := range apiOperations {
+		var parameters []fiber.Map
+		for _, segment := range strings.Split(op.path, "/") {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				parameters = append(parameters, fiber.Map{"name": strings.TrimSuffix(name, "}"), "in": "path", "required": true, "schema": fiber.Map{"type": "string"}})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(op.params)) {
+			parameters = append(parameters, fiber.Map{"name": name, "in": "query", "description": op.params[name], "schema": fiber.Map{"type": "string"}})
+		}
+		if op.method == "post" || op.method == "delete" {
+			parameters = append(parameters, fiber.Map{"name": idempotencyKeyHeader, "in": "header", "description": "identifies the request across retries, which are answered with the response of the first request",
+				"schema": fiber.Map{"type": "string", "maxLength": maxIdempotencyKeyLength}})
+		}
+		responses := fiber.Map{}
+		for code, body := range op.responses {
+			response := fiber.Map{"description": http.StatusText(code)}
+			switch body := body.(type) {
+			case nil:
+				if code >= http.StatusBadRequest {
+					response["content"] = fiber.Map{problemContentType: fiber.Map{"schema": schemaOf(reflect.TypeOf(problem{}), schemas)}}
+				}
+			case string:
+				response["content"] = fiber.Map{body: fiber.Map{"schema": fiber.Map{"type": "string"}}}
+			default:
+				schema := fiber.Map{"schema": schemaOf(reflect.TypeOf(body), schemas)}
+				response["content"] = fiber.Map{"application/json": schema, yamlContentType: schema}
+			}
+			responses[strconv.Itoa(code)] = response
+		}
+		responses["default"] = fiber.Map{"description": "Error", "content": fiber.Map{problemContentType: fiber.Map{"schema": schemaOf(reflect.TypeOf(problem{}), schemas)}}}
+		operation := fiber.Map{"tags": []string{op.tag}, "summary": op.summary, "responses": responses}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.request != nil {
+			schema := fiber.Map{"schema": schemaOf(reflect.TypeOf(op.request), schemas)}
+			operation["requestBody"] = fiber.Map{"required": true, "content": fiber.Map{"application/json": schema, yamlContentType: schema}}
+		}
+		if op.admin {
+			operation["security"] = []fiber.Map{{"adminKey": []string{}}}
+		} else if op.public {
+			operation["security"] = []fiber.Map{}
+		}
+		item, _ := paths[op.path].(fiber.Map)
+		if item == nil {
+			item = fiber.Map{}
+			paths[op.path] = item
+		}
+		item[op.method] = operation
+	}
+	return fiber.Map{
+		"openapi": "3.0.3",
+		"info":    fiber.Map{"title": "aruba-provisioner", "version": templateVersion},
+		"paths":   paths,
+		"components": fiber.Map{
+			"schemas": schemas,
+			"securitySchemes": fiber.Map{
+				"bearer":   fiber.Map{"type": "http", "scheme": "bearer"},
+				"adminKey": fiber.Map{"type": "apiKey", "in": "header", "name": "x-api-key"},
+			},
+		},
+		"security": []fiber.Map{{"bearer": []string{}}, {"adminKey": []string{}}},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the JSON schema of a Go type as encoded by encoding/json. Named structs are added to schemas and
+// referenced.
+func schemaOf(t reflect.Type, schemas fiber.Map) fiber.Map {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.Bool:
+		return fiber.Map{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fiber.Map{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return fiber.Map{"type": "number"}
+	case reflect.String:
+		return fiber.Map{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return fiber.Map{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return fiber.Map{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t == timeType {
+			return fiber.Map{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name := schemaName(t.Name())
+		ref := fiber.Map{"$ref": "#/components/schemas/" + name}
+		if _, ok := schemas[name]; !ok {
+			// placeholder for recursive types
+			schemas[name] = fiber.Map{}
+			schemas[name] = structSchema(t, schemas)
+		}
+		return ref
+	default:
+		return fiber.Map{}
+	}
+}
+
+// schemaName returns the name of the schema of a named struct. Instances of generic types are named after the type and
+// its type arguments, e.g. ListPageEvent for listPage[status.Event].
+func schemaName(typeName string) string {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(typeName, func(r rune) bool { return r == '[' || r == ']' || r == ',' }) {
+		part = part[strings.LastIndex(part, ".")+1:]
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+	return name.String()
+}
+
+func structSchema(t reflect.Type, schemas fiber.Map) fiber.Map {
+	properties := fiber.Map{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			// encoding/json promotes the fields of embedded structs
+			embedded := structSchema(field.Type, schemas)
+			maps.Copy(properties, embedded["properties"].(fiber.Map))
+			if names, ok := embedded["required"].([]string); ok {
+				required = append(required, names...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+		if strings.Contains(field.Tag.Get("validate"), "required") || (!strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer) {
+			required = append(required, name)
+		}
+	}
+	schema := fiber.Map{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func registerApiDocs(app *fiber.App) {
+	app.Get("/api/v1/openapi.json", func(c *fiber.Ctx) error {
+		return c.JSON(openApiDocument())
+	})
+	app.Get("/docs", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(swaggerHtml)
+	})
+}
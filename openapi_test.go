@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestOpenApiDocument(t *testing.T) {
+	raw, err := json.Marshal(openApiDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/api/v1/resources", "/api/v1/resources/{participantName}", "/api/v1/resources/{participantName}/status"} {
+		if _, ok := document.Paths[path]; !ok {
+			t.Errorf("path %s missing", path)
+		}
+	}
+	if _, ok := document.Paths["/api/v1/resources/{participantName}"]["delete"]; !ok {
+		t.Error("delete operation missing")
+	}
+
+	definition, ok := document.Components.Schemas["ParticipantDefinition"]
+	if !ok {
+		t.Fatal("ParticipantDefinition schema missing")
+	}
+	if !slices.Contains(definition.Required, "participantName") || !slices.Contains(definition.Required, "did") {
+		t.Errorf("required = %v, want participantName and did", definition.Required)
+	}
+	if slices.Contains(definition.Required, "kubeHost") {
+		t.Error("optional kubeHost marked as required")
+	}
+	if _, ok := document.Components.Schemas["ProvisioningJob"].Properties["mu"]; ok {
+		t.Error("unexported fields are part of the schema")
+	}
+
+	for _, ref := range regexp.MustCompile(`#/components/schemas/(\w+)`).FindAllStringSubmatch(string(raw), -1) {
+		if _, ok := document.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("unresolved reference to %s", ref[1])
+		}
+	}
+}
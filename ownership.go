@@ -0,0 +1,76 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Annotation with the DID of the participant an object belongs to. DIDs contain ':' and therefore cannot be
+// label values.
+const didAnnotation = "aruba-provisioner/did"
+
+// Kinds whose pod template receives the ownership labels as well. Job templates are immutable and left alone.
+var podTemplateKinds = map[string]bool{"Deployment": true, "StatefulSet": true, "DaemonSet": true}
+
+// validateMetadata checks the extra labels and annotations of a definition. Keys owned by the provisioner cannot
+// be overridden.
+func validateMetadata(labels map[string]string, annotations map[string]string) error {
+	for key, value := range labels {
+		if reservedMetadataKey(key) {
+			return fmt.Errorf("label %s is reserved for the provisioner", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("label %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("label %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for key := range annotations {
+		if reservedMetadataKey(key) {
+			return fmt.Errorf("annotation %s is reserved for the provisioner", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("annotation %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+func reservedMetadataKey(key string) bool {
+	return key == status.ManagedByLabel || strings.HasPrefix(key, "aruba-provisioner/")
+}
+
+// ownershipLabels returns the labels every object of the participant carries.
+func ownershipLabels(participant string, extra map[string]string) map[string]string {
+	labels := make(map[string]string, len(extra)+2)
+	for k, v := range extra {
+		labels[k] = v
+	}
+	labels[status.ManagedByLabel] = status.ManagedByValue
+	labels[status.ParticipantLabel] = participant
+	return labels
+}
+
+// ownershipMutator stamps every object with the provisioner's ownership labels, the participant DID and the
+// extra labels and annotations of the definition.
+func ownershipMutator(participant string, did string, extraLabels map[string]string, extraAnnotations map[string]string) objectMutator {
+	labels := ownershipLabels(participant, extraLabels)
+	annotations := make(map[string]string, len(extraAnnotations)+1)
+	for k, v := range extraAnnotations {
+		annotations[k] = v
+	}
+	annotations[didAnnotation] = did
+	return func(obj *unstructured.Unstructured) error {
+		addLabels(obj, labels)
+		addAnnotations(obj, annotations)
+		if podTemplateKinds[obj.GetKind()] {
+			return addPodTemplateLabels(obj, labels)
+		}
+		return nil
+	}
+}
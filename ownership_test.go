@@ -0,0 +1,64 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	cases := map[string][2]map[string]string{
+		"reserved label":      {{status.ManagedByLabel: "someone-else"}, nil},
+		"provisioner prefix":  {{status.ParticipantLabel: "bob"}, nil},
+		"invalid label value": {{"team": "data space"}, nil},
+		"invalid label key":   {{"team/": "a"}, nil},
+		"reserved annotation": {nil, {didAnnotation: "did:web:bob"}},
+	}
+	for name, metadata := range cases {
+		if err := validateMetadata(metadata[0], metadata[1]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := validateMetadata(map[string]string{"example.com/team": "data-space"}, map[string]string{"example.com/owner": "Alice Smith"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOwnershipMutator(t *testing.T) {
+	deployment := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+  labels:
+    app: controlplane
+spec:
+  template:
+    metadata:
+      labels:
+        app: controlplane
+`), &deployment.Object); err != nil {
+		t.Fatal(err)
+	}
+	mutator := ownershipMutator("alice", "did:web:alice", map[string]string{"team": "blue"}, map[string]string{"example.com/owner": "ops"})
+	if err := mutator(deployment); err != nil {
+		t.Fatal(err)
+	}
+	labels := deployment.GetLabels()
+	for key, expected := range map[string]string{"app": "controlplane", status.ManagedByLabel: status.ManagedByValue, status.ParticipantLabel: "alice", "team": "blue"} {
+		if labels[key] != expected {
+			t.Errorf("expected label %s=%s, got %q", key, expected, labels[key])
+		}
+	}
+	annotations := deployment.GetAnnotations()
+	if annotations[didAnnotation] != "did:web:alice" || annotations["example.com/owner"] != "ops" {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+	podLabels, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "labels")
+	if podLabels["app"] != "controlplane" || podLabels[status.ParticipantLabel] != "alice" {
+		t.Errorf("expected the pod template to carry the ownership labels, got %v", podLabels)
+	}
+}
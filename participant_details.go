@@ -0,0 +1,147 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// participantDetails is the record of a participant: who it is, where its APIs are reached and what it runs. Its
+// health is reported by the status endpoint.
+type participantDetails struct {
+	Name      string                      `json:"name"`
+	Did       string                      `json:"did,omitempty"`
+	Dataspace string                      `json:"dataspace"`
+	Status    status.ProvisioningStatus   `json:"status"`
+	CreatedAt time.Time                   `json:"createdAt"`
+	Metadata  *status.ParticipantMetadata `json:"metadata,omitempty"`
+	// Urls are the participant's APIs reached through its ingress, by API, known once the participant was seeded
+	Urls map[string]string `json:"urls,omitempty"`
+	// Endpoints are the participant's APIs within the cluster, by API
+	Endpoints  map[string]string  `json:"endpoints,omitempty"`
+	Components []componentDetails `json:"components"`
+	Seeding    *seedingSummary    `json:"seeding,omitempty"`
+	// Objects lists the objects the latest revision applied, empty for participants last applied by earlier versions
+	Objects []appliedObject `json:"objects,omitempty"`
+}
+
+// componentDetails is the deployed version of a component.
+type componentDetails struct {
+	Name    string `json:"name"`
+	Image   string `json:"image,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// seedingSummary reports which seed steps completed and what they created, as recorded in the participant's
+// namespace.
+type seedingSummary struct {
+	State     string                 `json:"state,omitempty"`
+	Completed []string               `json:"completed"`
+	Resources status.SeededResources `json:"resources"`
+}
+
+var participantDetailFields = []status.Field{status.FieldComponents, status.FieldEndpoints, status.FieldSeeding}
+
+// participantUrls returns the URLs of the APIs the participant's ingresses route to, those of the components its
+// stack includes.
+func participantUrls(definition ParticipantDefinition) map[string]string {
+	if definition.getHost() == "" {
+		return nil
+	}
+	urls := map[string]string{}
+	if definition.hasComponent("controlplane") {
+		urls["management"] = definition.routeUrl("/cp/api/management")
+	}
+	if definition.hasComponent("dataplane") {
+		urls["public"] = definition.routeUrl("/public")
+	}
+	if definition.hasComponent("identityhub") {
+		urls["identity"] = definition.routeUrl("/cs/api/identity")
+		urls["did"] = definition.routeUrl("")
+	}
+	if definition.hasComponent(federatedCatalogComponent) {
+		urls["federatedCatalog"] = definition.routeUrl("/catalog/api/catalog")
+	}
+	return urls
+}
+
+// getParticipantDetailsOf assembles the record of the participant from its namespace, its status and its seeding
+// state.
+func getParticipantDetailsOf(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker, name string) (participantDetails, error) {
+	participantStatus, err := statusChecker.GetStatus(ctx, name, participantDetailFields)
+	if err != nil {
+		return participantDetails{}, err
+	}
+	if participantStatus.Status == status.StatusNotFound || participantStatus.Status == status.StatusDeleted {
+		return participantDetails{}, fiber.NewError(fiber.StatusNotFound, "participant not found")
+	}
+	namespace := &corev1.Namespace{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return participantDetails{}, err
+	}
+	state, err := loadSeedingState(kubeClient, ctx, name)
+	if err != nil {
+		return participantDetails{}, err
+	}
+
+	details := participantDetails{
+		Name:       name,
+		Did:        namespace.Annotations[didAnnotation],
+		Dataspace:  namespace.Annotations[dataspaceAnnotation],
+		Status:     participantStatus.Status,
+		CreatedAt:  namespace.CreationTimestamp.UTC(),
+		Metadata:   participantStatus.Metadata,
+		Endpoints:  participantStatus.Endpoints,
+		Components: make([]componentDetails, 0, len(participantStatus.Components)),
+	}
+	if details.Did == "" {
+		details.Did = state.definition.Did
+	}
+	if details.Dataspace == "" {
+		details.Dataspace = dataspaceOf(state.definition)
+	}
+	for _, component := range participantStatus.Components {
+		details.Components = append(details.Components, componentDetails{Name: component.Name, Image: component.Image, Version: component.Version})
+	}
+	if state.definition.ParticipantName != "" {
+		details.Urls = participantUrls(state.definition)
+	}
+	revisions, err := listRevisions(kubeClient, ctx, name)
+	if err != nil {
+		return participantDetails{}, err
+	}
+	if len(revisions) > 0 {
+		latest, err := loadRevision(kubeClient, ctx, name, revisions[len(revisions)-1].Number)
+		if err != nil {
+			return participantDetails{}, err
+		}
+		details.Objects = latest.Objects
+	}
+	if len(state.completed) > 0 || participantStatus.Seeding != nil {
+		summary := &seedingSummary{Completed: []string{}, Resources: state.resources}
+		for _, step := range seedStepNames {
+			if _, ok := state.completed[step]; ok {
+				summary.Completed = append(summary.Completed, step)
+			}
+		}
+		if participantStatus.Seeding != nil {
+			summary.State = participantStatus.Seeding.State
+		}
+		details.Seeding = summary
+	}
+	return details, nil
+}
+
+func getParticipantDetails(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		details, err := getParticipantDetailsOf(kubeClient, ctx, statusChecker, c.Params("participantName"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(details)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetParticipantDetails(t *testing.T) {
+	definition, _ := json.Marshal(ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", KubernetesIngressHost: "dataspace.example.com"})
+	objects := []client.Object{
+		participantNamespace("acme", ""),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: seedingStateConfigMapName, Namespace: "acme"},
+			Data: map[string]string{
+				seedingDefinitionKey:                    string(definition),
+				seedingResourcesKey:                     `{"assets":["asset-1"]}`,
+				seedingStepPrefix + seedStepParticipant: "2026-01-01T00:00:00Z",
+				seedingStepPrefix + seedStepAssets:      "2026-01-01T00:00:00Z",
+			},
+		},
+	}
+	for _, deployment := range participantDeploymentNames {
+		objects = append(objects, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deployment, Namespace: "acme"},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+		})
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	checker := status.NewStatusChecker(context.Background(), kube, 0)
+
+	app := fiber.New()
+	app.Get("/:participantName", getParticipantDetails(kube, context.Background(), checker))
+	response, err := app.Test(httptest.NewRequest("GET", "/acme", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", response.StatusCode)
+	}
+	var details participantDetails
+	if err := json.NewDecoder(response.Body).Decode(&details); err != nil {
+		t.Fatal(err)
+	}
+	if details.Did != "did:web:acme" || details.Dataspace != defaultDataspace {
+		t.Errorf("unexpected identity %q in %q", details.Did, details.Dataspace)
+	}
+	if got := details.Urls["management"]; got != "http://dataspace.example.com/acme/cp/api/management" {
+		t.Errorf("unexpected management URL %q", got)
+	}
+	if got := details.Urls["identity"]; got != "http://dataspace.example.com/acme/cs/api/identity" {
+		t.Errorf("unexpected identity URL %q", got)
+	}
+	if !slices.ContainsFunc(details.Components, func(c componentDetails) bool { return c.Name == "controlplane" }) {
+		t.Errorf("expected the controlplane among the components, got %v", details.Components)
+	}
+	if details.Seeding == nil || !slices.Equal(details.Seeding.Completed, []string{seedStepAssets, seedStepParticipant}) {
+		t.Fatalf("unexpected seeding summary %+v", details.Seeding)
+	}
+	if !slices.Equal(details.Seeding.Resources.Assets, []string{"asset-1"}) {
+		t.Errorf("unexpected seeded assets %v", details.Seeding.Resources.Assets)
+	}
+
+	response, err = app.Test(httptest.NewRequest("GET", "/unknown", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != fiber.StatusNotFound {
+		t.Errorf("expected 404 for an unknown participant, got %d", response.StatusCode)
+	}
+}
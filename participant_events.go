@@ -0,0 +1,58 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Bounds of the page size of the events endpoint
+const (
+	defaultEventPageSize = 50
+	maxEventPageSize     = 500
+)
+
+// getParticipantEvents lists the events of a participant filtered by type, reason, involved object and time range,
+// newest first and in pages.
+func getParticipantEvents(ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		filter := status.EventFilter{Type: c.Query("type"), Reason: c.Query("reason"), Object: c.Query("object")}
+		var err error
+		if filter.Since, err = queryTime(c, "since"); err != nil {
+			return err
+		}
+		if filter.Until, err = queryTime(c, "until"); err != nil {
+			return err
+		}
+		limit := c.QueryInt("limit", defaultEventPageSize)
+		if limit < 1 || limit > maxEventPageSize {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxEventPageSize))
+		}
+		offset, err := pageOffset(c)
+		if err != nil {
+			return err
+		}
+
+		events, err := statusChecker.GetEvents(ctx, c.Params("participantName"), filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(offsetPage(c, events, offset, limit))
+	}
+}
+
+// queryTime parses the RFC 3339 time of the query parameter, zero if it is missing.
+func queryTime(c *fiber.Ctx, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s must be an RFC 3339 time", name))
+	}
+	return parsed, nil
+}
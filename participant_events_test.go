@@ -0,0 +1,73 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// eventClient lists the given events.
+type eventClient struct {
+	client.Client
+	events []corev1.Event
+}
+
+func (c eventClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1.EventList).Items = c.events
+	return nil
+}
+
+func TestGetParticipantEvents(t *testing.T) {
+	now := time.Now()
+	var events []corev1.Event
+	for i := 0; i < 5; i++ {
+		events = append(events, corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: fmt.Sprintf("event-%d", i)},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "controlplane-7d9f"},
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Duration(i) * time.Hour)),
+		})
+	}
+	events = append(events, corev1.Event{Type: corev1.EventTypeNormal, Reason: "Pulled", LastTimestamp: metav1.NewTime(now)})
+	checker := status.NewStatusChecker(context.Background(), eventClient{events: events}, status.DefaultCacheTTL)
+	app := fiber.New()
+	app.Get("/:participantName/events", getParticipantEvents(context.Background(), checker))
+
+	get := func(path string) (int, listPage[status.Event]) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page listPage[status.Event]
+		_ = json.NewDecoder(resp.Body).Decode(&page)
+		return resp.StatusCode, page
+	}
+
+	code, first := get("/alice/events?type=Warning&limit=2")
+	if code != fiber.StatusOK || len(first.Items) != 2 || first.Continue == "" || first.Total != 5 || first.Page != 1 {
+		t.Fatalf("expected a first page of 2 warnings, got %d %+v", code, first)
+	}
+	_, last := get("/alice/events?type=Warning&limit=2&continue=" + first.Continue + "&continue=" + first.Continue)
+	if len(last.Items) != 2 || last.Page != 2 || !last.Items[0].Timestamp.Before(first.Items[1].Timestamp) {
+		t.Errorf("expected the next, older page, got %+v", last)
+	}
+	_, recent := get("/alice/events?since=" + now.Add(-90*time.Minute).Format(time.RFC3339) + "&reason=BackOff")
+	if len(recent.Items) != 2 || recent.Continue != "" {
+		t.Errorf("expected the 2 warnings of the last 90 minutes, got %+v", recent)
+	}
+	for _, path := range []string{"/alice/events?since=yesterday", "/alice/events?limit=0", "/alice/events?continue=x"} {
+		if code, _ := get(path); code != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, code)
+		}
+	}
+}
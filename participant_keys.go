@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Secret in the participant namespace holding the key pair the participant signs its tokens with
+const participantKeysSecretName = "participant-keys"
+
+const (
+	keyAlgorithmField = "algorithm"
+	keyIdField        = "key-id"
+	privateKeyField   = "private-key"
+	publicKeyField    = "public-key"
+)
+
+const (
+	keyAlgorithmEC      = "EC"
+	keyAlgorithmEd25519 = "Ed25519"
+)
+
+var keyAlgorithms = []string{keyAlgorithmEC, keyAlgorithmEd25519}
+
+// Settings of the components referencing the participant's key pair by its alias in the secret store
+var keyAliasSettings = map[string][]string{
+	"controlplane-config":     {"EDC_IAM_STS_PRIVATEKEY_ALIAS", "EDC_IAM_STS_PUBLICKEY_ID"},
+	"dataplane-config":        {"EDC_TRANSFER_PROXY_TOKEN_VERIFIER_PUBLICKEY_ALIAS", "EDC_TRANSFER_PROXY_TOKEN_SIGNER_PRIVATEKEY_ALIAS"},
+	"ih-config":               {"EDC_IAM_STS_PRIVATEKEY_ALIAS", "EDC_IAM_STS_PUBLICKEY_ID"},
+	"federatedcatalog-config": {"EDC_IAM_STS_PRIVATEKEY_ALIAS", "EDC_IAM_STS_PUBLICKEY_ID"},
+}
+
+// participantKeyPair is generated when a participant is provisioned the first time. The private key is written to
+// the participant's secret store under the key ID when its participant context is created, which publishes the public
+// key in its DID document.
+type participantKeyPair struct {
+	Algorithm string
+	KeyId     string
+	// PrivateKey is PEM encoded PKCS #8
+	PrivateKey string
+	// PublicKey is a JSON Web Key
+	PublicKey map[string]string
+}
+
+func validateKeyAlgorithm(algorithm string) error {
+	if !slices.Contains(keyAlgorithms, algorithm) {
+		return fmt.Errorf("keyAlgorithm must be one of %s", strings.Join(keyAlgorithms, ", "))
+	}
+	return nil
+}
+
+// participantKeyId returns the ID of the participant's verification method, which is its alias in the secret store as
+// well.
+func participantKeyId(did string) string {
+	return did + "#key-1"
+}
+
+// generateKeyPair creates a P-256 or Ed25519 key pair for the participant.
+func generateKeyPair(algorithm string, did string) (participantKeyPair, error) {
+	var private any
+	var public map[string]string
+	switch algorithm {
+	case keyAlgorithmEC:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return participantKeyPair{}, err
+		}
+		point, err := key.PublicKey.ECDH()
+		if err != nil {
+			return participantKeyPair{}, err
+		}
+		// the uncompressed point is 0x04 followed by the 32 byte coordinates
+		encoded := point.Bytes()
+		private, public = key, map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(encoded[1:33]),
+			"y":   base64.RawURLEncoding.EncodeToString(encoded[33:]),
+		}
+	case keyAlgorithmEd25519:
+		publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return participantKeyPair{}, err
+		}
+		private, public = key, map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(publicKey),
+		}
+	default:
+		return participantKeyPair{}, validateKeyAlgorithm(algorithm)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return participantKeyPair{}, err
+	}
+	keyId := participantKeyId(did)
+	public["kid"] = keyId
+	return participantKeyPair{
+		Algorithm:  algorithm,
+		KeyId:      keyId,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		PublicKey:  public,
+	}, nil
+}
+
+// loadKeyPair returns the participant's key pair, nil for participants provisioned before key pairs were generated,
+// whose identity hub generated their keys.
+func loadKeyPair(c client.Client, ctx context.Context, namespace string) (*participantKeyPair, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: participantKeysSecretName}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := &participantKeyPair{
+		Algorithm:  string(secret.Data[keyAlgorithmField]),
+		KeyId:      string(secret.Data[keyIdField]),
+		PrivateKey: string(secret.Data[privateKeyField]),
+	}
+	if err := json.Unmarshal(secret.Data[publicKeyField], &keys.PublicKey); err != nil {
+		return nil, fmt.Errorf("parse public key of %s: %w", namespace, err)
+	}
+	return keys, nil
+}
+
+func storeKeyPair(c client.Client, ctx context.Context, namespace string, keys participantKeyPair) error {
+	public, err := json.Marshal(keys.PublicKey)
+	if err != nil {
+		return err
+	}
+	return applyResource(c, ctx, &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: participantKeysSecretName, Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			keyAlgorithmField: []byte(keys.Algorithm),
+			keyIdField:        []byte(keys.KeyId),
+			privateKeyField:   []byte(keys.PrivateKey),
+			publicKeyField:    public,
+		},
+	})
+}
+
+// planKeyPair returns the stored key pair of the participant, or a new one of the definition's algorithm, EC by
+// default. Re-provisioning keeps the stored key pair, whatever the algorithm, as its public key is already published.
+func planKeyPair(c client.Client, ctx context.Context, definition ParticipantDefinition) (participantKeyPair, bool, error) {
+	stored, err := loadKeyPair(c, ctx, definition.ParticipantName)
+	if err != nil || stored != nil {
+		if stored == nil {
+			return participantKeyPair{}, false, err
+		}
+		return *stored, false, nil
+	}
+	algorithm := definition.KeyAlgorithm
+	if algorithm == "" {
+		algorithm = keyAlgorithmEC
+	}
+	keys, err := generateKeyPair(algorithm, definition.Did)
+	return keys, err == nil, err
+}
+
+// keyPairMutator references the participant's key pair in the settings of its components.
+func keyPairMutator(keys participantKeyPair) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "ConfigMap" {
+			return nil
+		}
+		for _, setting := range keyAliasSettings[obj.GetName()] {
+			if err := unstructured.SetNestedField(obj.Object, keys.KeyId, "data", setting); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// withKeyPair replaces the key the identity hub would generate for the participant context by the participant's key
+// pair, whose private key it reads from the secret store.
+func withKeyPair(participant string, keys participantKeyPair) (string, error) {
+	var body map[string]any
+	if err := json.Unmarshal([]byte(participant), &body); err != nil {
+		return "", err
+	}
+	body["key"] = map[string]any{
+		"keyId":           keys.KeyId,
+		"privateKeyAlias": keys.KeyId,
+		"publicKeyJwk":    keys.PublicKey,
+	}
+	updated, err := json.Marshal(body)
+	return string(updated), err
+}
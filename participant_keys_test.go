@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	for algorithm, check := range map[string]func(any) bool{
+		keyAlgorithmEC:      func(key any) bool { _, ok := key.(*ecdsa.PrivateKey); return ok },
+		keyAlgorithmEd25519: func(key any) bool { _, ok := key.(ed25519.PrivateKey); return ok },
+	} {
+		keys, err := generateKeyPair(algorithm, "did:web:acme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode([]byte(keys.PrivateKey))
+		if block == nil {
+			t.Fatalf("%s: expected a PEM encoded private key", algorithm)
+		}
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil || !check(private) {
+			t.Errorf("%s: expected a matching private key, got %T %v", algorithm, private, err)
+		}
+		if keys.KeyId != "did:web:acme#key-1" || keys.PublicKey["kid"] != keys.KeyId || keys.PublicKey["x"] == "" {
+			t.Errorf("%s: expected the public key of the verification method, got %+v", algorithm, keys)
+		}
+	}
+	if _, err := generateKeyPair("RSA", "did:web:acme"); err == nil {
+		t.Error("expected unsupported algorithms to be rejected")
+	}
+}
+
+func TestPlanKeyPairKeepsStoredKeys(t *testing.T) {
+	kube := applyingSecretClient{newSecretClient()}
+	ctx := context.Background()
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", KeyAlgorithm: keyAlgorithmEd25519}
+
+	keys, generated, err := planKeyPair(kube, ctx, definition)
+	if err != nil || !generated || keys.Algorithm != keyAlgorithmEd25519 {
+		t.Fatalf("expected a new Ed25519 key pair, got %+v %v", keys, err)
+	}
+	if err := storeKeyPair(kube, ctx, "acme", keys); err != nil {
+		t.Fatal(err)
+	}
+	definition.KeyAlgorithm = keyAlgorithmEC
+	stored, generated, err := planKeyPair(kube, ctx, definition)
+	if err != nil || generated || stored.PrivateKey != keys.PrivateKey || stored.PublicKey["x"] != keys.PublicKey["x"] {
+		t.Errorf("expected the stored key pair to be kept, got %+v %v", stored, err)
+	}
+}
+
+func TestKeyPairInManifestsAndParticipantContext(t *testing.T) {
+	keys, err := generateKeyPair(keyAlgorithmEC, "did:web:acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &unstructured.Unstructured{Object: map[string]any{"kind": "ConfigMap", "metadata": map[string]any{"name": "ih-config"}, "data": map[string]any{"EDC_IAM_STS_PRIVATEKEY_ALIAS": "key-1"}}}
+	if err := keyPairMutator(keys)(config); err != nil {
+		t.Fatal(err)
+	}
+	if alias, _, _ := unstructured.NestedString(config.Object, "data", "EDC_IAM_STS_PRIVATEKEY_ALIAS"); alias != keys.KeyId {
+		t.Errorf("expected the identity hub to reference the participant's key, got %q", alias)
+	}
+
+	body, err := withKeyPair(`{"did":"did:web:acme","key":{"keyGeneratorParams":{"algorithm":"EC"}}}`, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var participant struct {
+		Key map[string]any `json:"key"`
+	}
+	if err := json.Unmarshal([]byte(body), &participant); err != nil {
+		t.Fatal(err)
+	}
+	if _, generates := participant.Key["keyGeneratorParams"]; generates || participant.Key["privateKeyAlias"] != keys.KeyId || participant.Key["publicKeyJwk"] == nil {
+		t.Errorf("expected the participant context to publish the generated key, got %v", participant.Key)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const maxParticipantPageSize = 500
+
+// Orders the participant list can be sorted in, ties are broken by name
+const (
+	sortByName        = "name"
+	sortByStatus      = "status"
+	sortByLastUpdated = "lastUpdated"
+)
+
+// participantCursor is the position after which the next page of participants starts: the sort key and name of the
+// last participant of the previous page. Unlike offsets, it stays valid when participants are added or removed.
+type participantCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Key        string `json:"k,omitempty"`
+	Name       string `json:"n"`
+	// Page is the number of the page the cursor starts
+	Page int `json:"p,omitempty"`
+}
+
+func (c participantCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// participantQuery is the sort order and page of a participant listing.
+type participantQuery struct {
+	sort       string
+	descending bool
+	// limit is the page size, zero returns all participants
+	limit int
+	after *participantCursor
+	// selector selects participants by the labels of their namespace, nil selects all
+	selector labels.Selector
+}
+
+func parseParticipantQuery(c *fiber.Ctx) (participantQuery, error) {
+	query := participantQuery{sort: c.Query("sort", sortByName), limit: c.QueryInt("limit", 0)}
+	if !slices.Contains([]string{sortByName, sortByStatus, sortByLastUpdated}, query.sort) {
+		return query, fiber.NewError(fiber.StatusBadRequest, "sort must be name, status or lastUpdated")
+	}
+	switch c.Query("order", "asc") {
+	case "asc":
+	case "desc":
+		query.descending = true
+	default:
+		return query, fiber.NewError(fiber.StatusBadRequest, "order must be asc or desc")
+	}
+	if query.limit < 0 || query.limit > maxParticipantPageSize {
+		return query, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxParticipantPageSize))
+	}
+	if token := c.Query("continue"); token != "" {
+		cursor := &participantCursor{}
+		data, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || json.Unmarshal(data, cursor) != nil {
+			return query, fiber.NewError(fiber.StatusBadRequest, "invalid continue token")
+		}
+		if cursor.Sort != query.sort || cursor.Descending != query.descending {
+			return query, fiber.NewError(fiber.StatusBadRequest, "continue token was issued for a different sort order")
+		}
+		query.after = cursor
+	}
+	selector, err := participantSelector(c.Query("label"), c.Query("team"), c.Query("environment"))
+	if err != nil {
+		return query, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	query.selector = selector
+	return query, nil
+}
+
+// participantSelector combines the label selector of a listing with its team and environment, nil if none is given.
+func participantSelector(label string, team string, environment string) (labels.Selector, error) {
+	if label == "" && team == "" && environment == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(label)
+	if err != nil {
+		return nil, fmt.Errorf("label: %v", err)
+	}
+	for key, value := range map[string]string{status.TeamLabel: team, status.EnvironmentLabel: environment} {
+		if value == "" {
+			continue
+		}
+		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// filterLabels drops the participants whose namespace doesn't match the selector.
+func filterLabels(c client.Client, ctx context.Context, selector labels.Selector, names []string) ([]string, error) {
+	if selector == nil {
+		return names, nil
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	matching := make(map[string]bool, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		matching[namespace.Name] = true
+	}
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		if matching[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// sortKey returns the value the participant is ordered by, compared as strings.
+func (q participantQuery) sortKey(participantStatus status.ParticipantStatus) string {
+	switch q.sort {
+	case sortByStatus:
+		return string(participantStatus.Status)
+	case sortByLastUpdated:
+		// fixed width, so the keys order like the times
+		return fmt.Sprintf("%020d", participantStatus.LastUpdated.UnixNano())
+	default:
+		return ""
+	}
+}
+
+func (q participantQuery) compare(aKey string, aName string, bKey string, bName string) int {
+	result := cmp.Or(cmp.Compare(aKey, bKey), cmp.Compare(aName, bName))
+	if q.descending {
+		return -result
+	}
+	return result
+}
+
+// pageNumber returns the number of the requested page, counted from 1.
+func (q participantQuery) pageNumber() int {
+	if q.after == nil || q.after.Page < 1 {
+		return 1
+	}
+	return q.after.Page
+}
+
+// participantPage is a page of the participant list with the summary of its statuses.
+type participantPage struct {
+	listPage[status.ParticipantStatus]
+	Summary status.StatusSummary `json:"summary"`
+}
+
+// page returns the participants of the requested page and the cursor of the next one, nil on the last page. Sorted by
+// name, only the statuses of the page are loaded; the other orders need the statuses of all participants.
+func (q participantQuery) page(names []string, load func(name string) (status.ParticipantStatus, error)) ([]status.ParticipantStatus, *participantCursor, error) {
+	if q.sort == sortByName {
+		names = slices.Clone(names)
+		slices.SortFunc(names, func(a, b string) int { return q.compare("", a, "", b) })
+		if q.after != nil {
+			names = slices.DeleteFunc(names, func(name string) bool { return q.compare("", name, "", q.after.Name) <= 0 })
+		}
+		var next *participantCursor
+		if q.limit > 0 && len(names) > q.limit {
+			names = names[:q.limit]
+			next = &participantCursor{Sort: q.sort, Descending: q.descending, Name: names[len(names)-1], Page: q.pageNumber() + 1}
+		}
+		statuses, err := loadConcurrently(names, load)
+		if err != nil {
+			return nil, nil, err
+		}
+		return statuses, next, nil
+	}
+
+	loaded, err := loadConcurrently(names, load)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses := slices.DeleteFunc(loaded, func(participantStatus status.ParticipantStatus) bool {
+		return q.after != nil && q.compare(q.sortKey(participantStatus), participantStatus.Name, q.after.Key, q.after.Name) <= 0
+	})
+	slices.SortFunc(statuses, func(a, b status.ParticipantStatus) int {
+		return q.compare(q.sortKey(a), a.Name, q.sortKey(b), b.Name)
+	})
+	var next *participantCursor
+	if q.limit > 0 && len(statuses) > q.limit {
+		statuses = statuses[:q.limit]
+		last := statuses[len(statuses)-1]
+		next = &participantCursor{Sort: q.sort, Descending: q.descending, Key: q.sortKey(last), Name: last.Name, Page: q.pageNumber() + 1}
+	}
+	return statuses, next, nil
+}
+
+// listParticipants lists the participants in the caller's scope with their component status, a page at a time when a
+// limit is given. ?label selects participants by their labels, ?team and ?environment by their metadata. X-Total-Count
+// reports the number of participants, a Link header the next and first page.
+func listParticipants(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query, err := parseParticipantQuery(c)
+		if err != nil {
+			return err
+		}
+		names, err := discoverParticipants(kubeClient, ctx)
+		if err != nil {
+			return err
+		}
+		if names, err = filterScope(kubeClient, ctx, tenantOf(c), names); err != nil {
+			return err
+		}
+		if names, err = filterLabels(kubeClient, ctx, query.selector, names); err != nil {
+			return err
+		}
+		statuses, next, err := query.page(names, func(name string) (status.ParticipantStatus, error) {
+			return statusChecker.GetStatus(ctx, name, []status.Field{status.FieldComponents})
+		})
+		if err != nil {
+			return err
+		}
+
+		response := participantPage{
+			listPage: listPage[status.ParticipantStatus]{Items: statuses, Total: len(names), Page: query.pageNumber(), Limit: query.limit},
+			Summary:  status.Summarize(statuses),
+		}
+		var links []pageLink
+		if next != nil {
+			response.Continue = next.encode()
+			links = append(links, pageLink{rel: "next", token: response.Continue})
+		}
+		if query.after != nil {
+			links = append(links, pageLink{rel: "first"})
+		}
+		setPageHeaders(c, len(names), links...)
+		return c.JSON(response)
+	}
+}
+
+// Most participants a batch status request can ask for, the list endpoint pages through more
+const maxStatusBatchSize = maxParticipantPageSize
+
+// statusBatchRequest lists the participants whose statuses are returned in one call.
+type statusBatchRequest struct {
+	Participants []string `json:"participants"`
+}
+
+// batchStatuses returns the statuses of the participants in the order they were asked for, each once. Participants
+// that aren't visible to the caller are reported as NOT_FOUND without being loaded.
+func batchStatuses(names []string, visible map[string]bool, load func(name string) (status.ParticipantStatus, error)) ([]status.ParticipantStatus, error) {
+	unique := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return loadConcurrently(unique, func(name string) (status.ParticipantStatus, error) {
+		if !visible[name] {
+			return status.ParticipantStatus{Name: name, Status: status.StatusNotFound}, nil
+		}
+		return load(name)
+	})
+}
+
+// getStatuses returns the statuses of several participants in one call, served from the status cache like single
+// status requests. ?fields and ?refresh apply to all of them.
+func getStatuses(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request statusBatchRequest
+		if err := parser.parse(c, &request); err != nil {
+			return err
+		}
+		if len(request.Participants) == 0 || len(request.Participants) > maxStatusBatchSize {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("participants must list between 1 and %d names", maxStatusBatchSize))
+		}
+		fields, err := status.ParseFields(c.Query("fields"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		names, err := filterScope(kubeClient, ctx, tenantOf(c), request.Participants)
+		if err != nil {
+			return err
+		}
+		visible := make(map[string]bool, len(names))
+		for _, name := range names {
+			visible[name] = true
+		}
+		refresh := c.QueryBool("refresh")
+		statuses, err := batchStatuses(request.Participants, visible, func(name string) (status.ParticipantStatus, error) {
+			if refresh {
+				statusChecker.Invalidate(name)
+			}
+			return statusChecker.GetStatus(ctx, name, fields)
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"participants": statuses, "summary": status.Summarize(statuses)})
+	}
+}
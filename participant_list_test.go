@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"aruba-provisioner/api/status"
+)
+
+func participantNames(statuses []status.ParticipantStatus) []string {
+	names := make([]string, 0, len(statuses))
+	for _, participantStatus := range statuses {
+		names = append(names, participantStatus.Name)
+	}
+	return names
+}
+
+func TestParticipantPagesByName(t *testing.T) {
+	var mu sync.Mutex
+	var loaded []string
+	load := func(name string) (status.ParticipantStatus, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loaded = append(loaded, name)
+		return status.ParticipantStatus{Name: name}, nil
+	}
+	names := []string{"dave", "alice", "carol", "bob", "erin"}
+
+	query := participantQuery{sort: sortByName, limit: 2}
+	first, next, err := query.page(names, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(first), []string{"alice", "bob"}) || next == nil {
+		t.Fatalf("unexpected first page %v, next %v", participantNames(first), next)
+	}
+	// statuses are loaded concurrently
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, []string{"alice", "bob"}) {
+		t.Errorf("expected only the statuses of the page to be loaded, got %v", loaded)
+	}
+
+	// participants removed before the cursor don't shift the next page
+	query.after = next
+	second, next, err := query.page([]string{"carol", "dave", "erin"}, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(second), []string{"carol", "dave"}) || next == nil {
+		t.Fatalf("unexpected second page %v", participantNames(second))
+	}
+	query.after = next
+	last, next, err := query.page(names, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(last), []string{"erin"}) || next != nil {
+		t.Errorf("unexpected last page %v, next %v", participantNames(last), next)
+	}
+}
+
+func TestParticipantPagesByStatus(t *testing.T) {
+	now := time.Now()
+	statuses := map[string]status.ParticipantStatus{
+		"alice": {Name: "alice", Status: status.StatusReady, LastUpdated: now},
+		"bob":   {Name: "bob", Status: status.StatusDegraded, LastUpdated: now.Add(-time.Minute)},
+		"carol": {Name: "carol", Status: status.StatusReady, LastUpdated: now.Add(-2 * time.Minute)},
+	}
+	load := func(name string) (status.ParticipantStatus, error) {
+		return statuses[name], nil
+	}
+	names := []string{"alice", "bob", "carol"}
+
+	query := participantQuery{sort: sortByStatus, descending: true, limit: 2}
+	first, next, err := query.page(names, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(first), []string{"carol", "alice"}) || next == nil {
+		t.Fatalf("unexpected first page %v", participantNames(first))
+	}
+	query.after = next
+	second, next, err := query.page(names, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(second), []string{"bob"}) || next != nil {
+		t.Errorf("unexpected second page %v, next %v", participantNames(second), next)
+	}
+
+	byTime, _, err := participantQuery{sort: sortByLastUpdated}.page(names, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(byTime), []string{"carol", "bob", "alice"}) {
+		t.Errorf("unexpected order by last update %v", participantNames(byTime))
+	}
+}
+
+func TestBatchStatuses(t *testing.T) {
+	var mu sync.Mutex
+	var loaded []string
+	load := func(name string) (status.ParticipantStatus, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loaded = append(loaded, name)
+		return status.ParticipantStatus{Name: name, Status: status.StatusReady}, nil
+	}
+	visible := map[string]bool{"alice": true, "bob": true}
+
+	statuses, err := batchStatuses([]string{"bob", "carol", "alice", "bob"}, visible, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participantNames(statuses), []string{"bob", "carol", "alice"}) {
+		t.Fatalf("expected the statuses in request order without duplicates, got %v", participantNames(statuses))
+	}
+	if statuses[1].Status != status.StatusNotFound || statuses[0].Status != status.StatusReady {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, []string{"alice", "bob"}) {
+		t.Errorf("expected only visible participants to be loaded, got %v", loaded)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Longest description of a participant
+const maxDescriptionLength = 1024
+
+// MetadataOptions describes who a participant belongs to and what it is for. It is kept on the participant's
+// namespace and returned with its status, team and environment can be filtered by when listing participants.
+type MetadataOptions struct {
+	// Owner is the email address of the participant's owner
+	Owner       string `json:"owner,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (m *MetadataOptions) validate() error {
+	if m.Owner != "" {
+		if address, err := mail.ParseAddress(m.Owner); err != nil || address.Address != m.Owner {
+			return fmt.Errorf("metadata: owner must be an email address")
+		}
+	}
+	for name, value := range map[string]string{"team": m.Team, "environment": m.Environment} {
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("metadata: %s: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	if len(m.Description) > maxDescriptionLength {
+		return fmt.Errorf("metadata: description must not be longer than %d characters", maxDescriptionLength)
+	}
+	return nil
+}
+
+// mutator records the metadata on the participant's namespace.
+func (m *MetadataOptions) mutator() objectMutator {
+	labels := make(map[string]string)
+	annotations := make(map[string]string)
+	for key, value := range map[string]string{status.TeamLabel: m.Team, status.EnvironmentLabel: m.Environment} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	for key, value := range map[string]string{status.OwnerAnnotation: m.Owner, status.DescriptionAnnotation: m.Description} {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addLabels(obj, labels)
+			addAnnotations(obj, annotations)
+		}
+		return nil
+	}
+}
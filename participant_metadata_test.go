@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"aruba-provisioner/api/status"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateMetadataOptions(t *testing.T) {
+	tests := []struct {
+		metadata MetadataOptions
+		valid    bool
+	}{
+		{MetadataOptions{Owner: "alice@example.com", Team: "alpha", Environment: "prod", Description: "Alice's connector"}, true},
+		{MetadataOptions{Owner: "Alice <alice@example.com>"}, false},
+		{MetadataOptions{Owner: "alice"}, false},
+		{MetadataOptions{Team: "alpha team"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.metadata.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: valid = %v, got %v", tt.metadata, tt.valid, err)
+		}
+	}
+}
+
+func TestMetadataMutator(t *testing.T) {
+	metadata := &MetadataOptions{Owner: "alice@example.com", Team: "alpha"}
+	namespace := &unstructured.Unstructured{}
+	namespace.SetKind("Namespace")
+	deployment := &unstructured.Unstructured{}
+	deployment.SetKind("Deployment")
+	for _, obj := range []*unstructured.Unstructured{namespace, deployment} {
+		if err := metadata.mutator()(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if namespace.GetLabels()[status.TeamLabel] != "alpha" || namespace.GetAnnotations()[status.OwnerAnnotation] != "alice@example.com" {
+		t.Errorf("metadata not recorded on the namespace: %v %v", namespace.GetLabels(), namespace.GetAnnotations())
+	}
+	if _, ok := namespace.GetLabels()[status.EnvironmentLabel]; ok {
+		t.Error("empty environment was recorded")
+	}
+	if len(deployment.GetLabels()) > 0 || len(deployment.GetAnnotations()) > 0 {
+		t.Error("only the namespace carries the metadata")
+	}
+}
+
+func TestFilterLabels(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		labels[status.ManagedByLabel] = status.ManagedByValue
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	c := newNamespaceClient(
+		namespace("alice", map[string]string{"team": "alpha", status.EnvironmentLabel: "prod"}),
+		namespace("bob", map[string]string{"team": "beta", status.TeamLabel: "alpha", status.EnvironmentLabel: "dev"}),
+		namespace("carol", map[string]string{}),
+	)
+	names := []string{"alice", "bob", "carol"}
+	tests := []struct {
+		label, team, environment string
+		want                     []string
+	}{
+		{"", "", "", names},
+		{"team=alpha", "", "", []string{"alice"}},
+		{"", "alpha", "", []string{"bob"}},
+		{"team", "", "prod", []string{"alice"}},
+		{"team!=alpha", "", "", []string{"bob", "carol"}},
+	}
+	for _, tt := range tests {
+		selector, err := participantSelector(tt.label, tt.team, tt.environment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filtered, err := filterLabels(c, context.Background(), selector, names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(filtered)
+		if !slices.Equal(filtered, tt.want) {
+			t.Errorf("label %q, team %q, environment %q: got %v, want %v", tt.label, tt.team, tt.environment, filtered, tt.want)
+		}
+	}
+	if _, err := participantSelector("team in (alpha", "", ""); err == nil {
+		t.Error("invalid selector was accepted")
+	}
+}
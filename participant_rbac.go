@@ -0,0 +1,101 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// participantRbac gives the connector components of every participant a ServiceAccount of their own, bound to a Role
+// that only allows reading the connector secrets, rather than running them with the namespace's default
+// ServiceAccount. Set with --participant-rbac.
+var participantRbac bool
+
+// Name of the ServiceAccount, Role and RoleBinding of a participant's connector components
+const connectorServiceAccount = "connector"
+
+// connectorDeployments run with the connector ServiceAccount; databases and Vault don't talk to the Kubernetes API.
+var connectorDeployments = append(slices.Clone(participantDeploymentNames), federatedCatalogComponent)
+
+// participantRbacManifests renders the ServiceAccount of the participant's connector components, its Role and the
+// RoleBinding between them.
+func participantRbacManifests(participant string) (string, error) {
+	objects := []map[string]any{
+		{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   map[string]any{"name": connectorServiceAccount, "namespace": participant},
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "Role",
+			"metadata":   map[string]any{"name": connectorServiceAccount, "namespace": participant},
+			"rules": []any{map[string]any{
+				"apiGroups":     []any{""},
+				"resources":     []any{"secrets"},
+				"resourceNames": []any{connectorSecretsName},
+				"verbs":         []any{"get", "watch"},
+			}},
+		},
+		{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   map[string]any{"name": connectorServiceAccount, "namespace": participant},
+			"roleRef":    map[string]any{"apiGroup": "rbac.authorization.k8s.io", "kind": "Role", "name": connectorServiceAccount},
+			"subjects":   []any{map[string]any{"kind": "ServiceAccount", "name": connectorServiceAccount, "namespace": participant}},
+		},
+	}
+	docs := make([]string, 0, len(objects))
+	for _, object := range objects {
+		doc, err := yaml.Marshal(object)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// serviceAccountMutator runs the connector components with the connector ServiceAccount.
+func serviceAccountMutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Deployment" || !slices.Contains(connectorDeployments, obj.GetName()) {
+			return nil
+		}
+		return unstructured.SetNestedField(obj.Object, connectorServiceAccount, "spec", "template", "spec", "serviceAccountName")
+	}
+}
+
+// renameRbacReferences points the RoleBinding and Role of a participant in the shared namespace at the participant's
+// renamed objects.
+func renameRbacReferences(obj *unstructured.Unstructured, participant string) error {
+	switch obj.GetKind() {
+	case "RoleBinding":
+		if name, _, _ := unstructured.NestedString(obj.Object, "roleRef", "name"); name != "" {
+			if err := unstructured.SetNestedField(obj.Object, status.ObjectName(name, participant), "roleRef", "name"); err != nil {
+				return err
+			}
+		}
+		for _, subject := range nestedItems(obj.Object, "subjects") {
+			if name, ok := subject["name"].(string); ok && subject["kind"] == "ServiceAccount" {
+				subject["name"] = status.ObjectName(name, participant)
+				subject["namespace"] = status.SharedNamespace
+			}
+		}
+	case "Role":
+		for _, rule := range nestedItems(obj.Object, "rules") {
+			names, _, _ := unstructured.NestedStringSlice(rule, "resourceNames")
+			renamed := make([]any, len(names))
+			for i, name := range names {
+				renamed[i] = status.ObjectName(name, participant)
+			}
+			if len(renamed) > 0 {
+				rule["resourceNames"] = renamed
+			}
+		}
+	}
+	return nil
+}
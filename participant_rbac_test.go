@@ -0,0 +1,77 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParticipantRbac(t *testing.T) {
+	rbac, err := participantRbacManifests("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+spec:
+  template:
+    spec:
+      containers: []
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: postgres
+spec:
+  template:
+    spec:
+      containers: []
+`
+	render := func(mutators ...objectMutator) map[string]*unstructured.Unstructured {
+		objects := map[string]*unstructured.Unstructured{}
+		collect := func(_ client.Client, _ context.Context, object client.Object) error {
+			objects[object.GetObjectKind().GroupVersionKind().Kind+"/"+object.GetName()] = object.(*unstructured.Unstructured)
+			return nil
+		}
+		name := "alice"
+		if _, err := applyYaml(&name, new(string), nil, context.Background(), rbac+"---\n"+deployments, collect, mutators...); err != nil {
+			t.Fatal(err)
+		}
+		return objects
+	}
+
+	objects := render(serviceAccountMutator())
+	for _, name := range []string{"ServiceAccount/connector", "Role/connector", "RoleBinding/connector"} {
+		if objects[name] == nil || objects[name].GetNamespace() != "alice" {
+			t.Errorf("expected %s in the participant namespace, got %v", name, objects[name])
+		}
+	}
+	if account, _, _ := unstructured.NestedString(objects["Deployment/controlplane"].Object, "spec", "template", "spec", "serviceAccountName"); account != connectorServiceAccount {
+		t.Errorf("expected the controlplane to run with the connector ServiceAccount, got %q", account)
+	}
+	if account, _, _ := unstructured.NestedString(objects["Deployment/postgres"].Object, "spec", "template", "spec", "serviceAccountName"); account != "" {
+		t.Errorf("expected postgres to keep the default ServiceAccount, got %q", account)
+	}
+
+	// in the shared namespace, the references point at the participant's renamed objects
+	status.SharedNamespace = "participants"
+	defer func() { status.SharedNamespace = "" }()
+	objects = render(serviceAccountMutator(), sharedNamespaceMutator("alice"))
+	if account, _, _ := unstructured.NestedString(objects["Deployment/controlplane"].Object, "spec", "template", "spec", "serviceAccountName"); account != "connector-alice" {
+		t.Errorf("expected the renamed ServiceAccount, got %q", account)
+	}
+	binding := objects["RoleBinding/connector"].Object
+	subject := nestedItems(binding, "subjects")[0]
+	if role, _, _ := unstructured.NestedString(binding, "roleRef", "name"); role != "connector-alice" || subject["name"] != "connector-alice" || subject["namespace"] != "participants" {
+		t.Errorf("expected the binding of the renamed Role and ServiceAccount, got %v", binding)
+	}
+	rule := nestedItems(objects["Role/connector"].Object, "rules")[0]
+	if names, _, _ := unstructured.NestedStringSlice(rule, "resourceNames"); len(names) != 1 || names[0] != status.ObjectName(connectorSecretsName, "alice") {
+		t.Errorf("expected the Role to allow reading the renamed connector secrets, got %v", names)
+	}
+}
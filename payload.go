@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// payloadParser parses request bodies, optionally rejecting fields the target type doesn't know.
+type payloadParser struct {
+	// strict rejects unknown fields unless a request opts out with ?strict=false
+	strict bool
+}
+
+// parse decodes the body into out. In strict mode, which requests can also enable with ?strict=true, unknown or
+// misspelled fields are rejected with a 400 listing all of them.
+// Bodies sent as YAML are converted to JSON first.
+func (p payloadParser) parse(c *fiber.Ctx, out any) error {
+	body := c.Body()
+	yamlBody := isYaml(c.Get(fiber.HeaderContentType))
+	if yamlBody {
+		converted, err := yaml.YAMLToJSON(body)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid YAML: "+err.Error())
+		}
+		body = converted
+	}
+	if c.QueryBool("strict", p.strict) {
+		if unknown := unknownFields(body, reflect.TypeOf(out)); len(unknown) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "unknown fields: "+strings.Join(unknown, ", "))
+		}
+	}
+	if yamlBody {
+		return json.Unmarshal(body, out)
+	}
+	return c.BodyParser(out)
+}
+
+// unknownFields returns the paths of all JSON object keys that have no matching field in the type. Bodies that are
+// not valid JSON are left to the regular parser to report.
+func unknownFields(body []byte, t reflect.Type) []string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	var unknown []string
+	collectUnknownFields(value, t, "", &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func collectUnknownFields(value any, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, nested := range object {
+			field, ok := fields[key]
+			if !ok {
+				// encoding/json matches keys case-insensitively
+				field, ok = fields[strings.ToLower(key)]
+			}
+			if !ok {
+				*unknown = append(*unknown, joinPath(path, key))
+				continue
+			}
+			collectUnknownFields(nested, field, joinPath(path, key), unknown)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for key, nested := range object {
+			collectUnknownFields(nested, t.Elem(), joinPath(path, key), unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			collectUnknownFields(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", unknown)
+		}
+	}
+}
+
+// jsonFields maps the JSON names of a struct's fields, including those of embedded structs, to their types. Names are
+// also registered in lower case for case-insensitive matching.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, nested := range jsonFields(embedded) {
+					fields[key] = nested
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
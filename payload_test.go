@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"participantName":"a","did":"did:web:a","services":{"controlplane":{"type":"NodePort"}}}`, nil},
+		{"case insensitive", `{"ParticipantName":"a"}`, nil},
+		{"misspelled", `{"particpantName":"a","did":"did:web:a"}`, []string{"particpantName"}},
+		{"nested", `{"services":{"controlplane":{"typ":"NodePort"}},"mesh":{"provider":"istio","mtls":"STRICT"}}`, []string{"mesh.mtls", "services.controlplane.typ"}},
+		{"in lists", `{"contractDefinitions":[{"id":"a"},{"id":"b","assetId":["x"]}]}`, []string{"contractDefinitions[1].assetId"}},
+		{"free-form maps", `{"mesh":{"trafficPolicy":{"anything":{"goes":true}}}}`, nil},
+		{"invalid json", `{"participantName":`, nil},
+	}
+	for _, tt := range tests {
+		got := unknownFields([]byte(tt.body), reflect.TypeOf(&ParticipantDefinition{}))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unknownFields() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
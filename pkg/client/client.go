@@ -0,0 +1,146 @@
+// Package client is a Go client of the provisioner REST API, for services provisioning and monitoring participants
+// without going through the command line client.
+//
+//	c := &client.Client{BaseUrl: "https://provisioner.example.com", Token: apiKey}
+//	accepted, err := c.Provision(ctx, client.ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice"})
+//	...
+//	status, err := c.WaitForStatus(ctx, accepted.Participant, client.StatusReady)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Requests are abandoned after this period unless the HttpClient has a timeout
+const defaultTimeout = 30 * time.Second
+
+// Interval at which the Wait methods poll unless PollInterval is set
+const defaultPollInterval = 5 * time.Second
+
+// Client calls the provisioner API. The zero value of the optional fields is usable.
+type Client struct {
+	// BaseUrl is the address of the provisioner, e.g. http://provisioner.provisioner:9999
+	BaseUrl string
+	// Token is sent as bearer token: a static API key or an OIDC access token
+	Token string
+	// AdminKey is sent as x-api-key header, required by the maintenance endpoints
+	AdminKey   string
+	HttpClient *http.Client
+	// PollInterval is the interval at which WaitForJob and WaitForStatus poll
+	PollInterval time.Duration
+}
+
+// Error is an error response of the provisioner.
+type Error struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Code identifies the kind of error, e.g. NOT_FOUND or VALIDATION_FAILED, it doesn't change between releases
+	Code string `json:"code"`
+	// Errors lists the rejected fields of invalid requests
+	Errors []FieldError `json:"errors,omitempty"`
+	// Conflict is the existing resource a request collides with, e.g. the namespace of a participant name
+	Conflict *ConflictingResource `json:"conflict,omitempty"`
+}
+
+// ConflictingResource identifies an existing resource of the cluster.
+type ConflictingResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// FieldError is a rejected field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = e.Title
+	}
+	for _, field := range e.Errors {
+		message += fmt.Sprintf("\n  %s: %s", field.Field, field.Message)
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("%s (%d)", message, e.Status)
+	}
+	return fmt.Sprintf("%s (%d %s)", message, e.Status, e.Code)
+}
+
+// IsNotFound reports whether the error is a 404 response, e.g. for an unknown participant.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// do sends the request with the body encoded as JSON, unless it is raw bytes, and returns the response body.
+// Responses with an error status are returned as *Error, with the body as detail if it isn't a problem document.
+func (c *Client) do(ctx context.Context, method string, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseUrl, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if reader != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.AdminKey != "" {
+		request.Header.Set("x-api-key", c.AdminKey)
+	}
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Status == 0 {
+			apiErr = &Error{Title: response.Status, Status: response.StatusCode, Detail: strings.TrimSpace(string(data))}
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
+
+// doJson sends the request and decodes the JSON response.
+func doJson[T any](ctx context.Context, c *Client, method string, path string, body any) (T, error) {
+	var result T
+	data, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
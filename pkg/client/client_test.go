@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/resources/alice/status":
+			if r.URL.RawQuery != "fields=components%2Cseeding&refresh=true" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"name":"alice","status":"SEEDING","components":[{"name":"controlplane","ready":true}],"seeding":{"state":"RUNNING"}}`))
+		case "/api/v1/resources/bob/status":
+			// the provisioner answers unknown participants with their status
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"name":"bob","status":"NOT_FOUND"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"title":"Forbidden","status":403,"detail":"participant belongs to another tenant","code":"FORBIDDEN"}`))
+		}
+	}))
+	defer server.Close()
+	c := &Client{BaseUrl: server.URL}
+	ctx := context.Background()
+
+	alice, err := c.Status(ctx, "alice", StatusOptions{Fields: []string{"components", "seeding"}, Refresh: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Status != StatusSeeding || len(alice.Components) != 1 || alice.Seeding == nil || alice.Seeding.State != "RUNNING" {
+		t.Errorf("unexpected status %+v", alice)
+	}
+	bob, err := c.Status(ctx, "bob", StatusOptions{})
+	if err != nil || bob.Status != StatusNotFound {
+		t.Errorf("expected bob to be NOT_FOUND, got %+v, %v", bob, err)
+	}
+	_, err = c.Status(ctx, "carol", StatusOptions{})
+	if err == nil || err.Error() != "participant belongs to another tenant (403 FORBIDDEN)" || IsNotFound(err) {
+		t.Errorf("expected the problem of the response, got %v", err)
+	}
+}
+
+func TestProvisionAndWait(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/resources/":
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"jobId":"42","participant":"alice","queuePosition":2}`))
+		case "GET /api/v1/jobs/42":
+			_, _ = w.Write([]byte(`{"id":"42","participant":"alice","status":"SUCCEEDED"}`))
+		case "GET /api/v1/resources/alice/status":
+			polls++
+			if polls < 2 {
+				_, _ = w.Write([]byte(`{"name":"alice","status":"PROVISIONING"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"alice","status":"SEED_FAILED","message":"seeding failed: identity hub unavailable"}`))
+		}
+	}))
+	defer server.Close()
+	c := &Client{BaseUrl: server.URL, Token: "secret", PollInterval: time.Millisecond}
+	ctx := context.Background()
+
+	accepted, err := c.Provision(ctx, ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted.JobId != "42" || accepted.QueuePosition != 2 {
+		t.Errorf("unexpected job %+v", accepted)
+	}
+	if job, err := c.WaitForJob(ctx, accepted.JobId); err != nil || job.Status != JobSucceeded {
+		t.Fatalf("expected the job to succeed, got %+v, %v", job, err)
+	}
+	_, err = c.WaitForStatus(ctx, "alice", StatusReady)
+	if err == nil || !strings.Contains(err.Error(), "identity hub unavailable") {
+		t.Errorf("expected waiting to fail with the seeding failure, got %v", err)
+	}
+	if polls != 2 {
+		t.Errorf("expected the status to be polled until it failed, got %d polls", polls)
+	}
+}
+
+func TestList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "continue=abc&limit=10&order=desc&sort=lastUpdated" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"items":[{"name":"alice","status":"READY"}],"total":11,"page":2,"limit":10,"summary":{"total":1,"ready":1,"byStatus":{"READY":1}},"continue":"def"}`))
+	}))
+	defer server.Close()
+	c := &Client{BaseUrl: server.URL + "/"}
+
+	list, err := c.List(context.Background(), ListOptions{Sort: "lastUpdated", Descending: true, Limit: 10, Continue: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Total != 11 || list.Page != 2 || list.Continue != "def" {
+		t.Errorf("unexpected list %+v", list)
+	}
+}
@@ -0,0 +1,474 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ParticipantDefinition describes a participant to provision. The sections configuring the exposure, seeding and API
+// keys of the participant are passed through as JSON, see the provisioner's OpenAPI document for their schema.
+type ParticipantDefinition struct {
+	ParticipantName       string `json:"participantName,omitempty"`
+	Did                   string `json:"did,omitempty"`
+	KubernetesIngressHost string `json:"kubeHost,omitempty"`
+	// Dataspace selects the dataspace settings of the provisioner
+	Dataspace string `json:"dataspace,omitempty"`
+	// Labels and Annotations are added to every object created for the participant
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Metadata records the owner, team, environment and description of the participant
+	Metadata *MetadataOptions `json:"metadata,omitempty"`
+	// Protected participants are only deleted when the deletion is forced or confirmed
+	Protected bool `json:"protected,omitempty"`
+	// AdoptNamespace deploys the participant into its existing namespace instead of creating it
+	AdoptNamespace bool `json:"adoptNamespace,omitempty"`
+	// ExpiresAfter deletes the participant once the lifetime, e.g. 8h or 2d, lapsed
+	ExpiresAfter string `json:"expiresAfter,omitempty"`
+	// NotBefore schedules the provisioning of a new participant for this time
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	// Teardown deletes the participant at an RFC 3339 time or when a cron expression first matches, in UTC
+	Teardown string `json:"teardown,omitempty"`
+	// KeyAlgorithm selects the key pair generated for the participant: EC, the default, or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// Tier selects the PriorityClass of the participant's pods: production, standard or demo
+	Tier string `json:"tier,omitempty"`
+	// Flavor selects the named participant stack configured in the provisioner, the default stack if empty
+	Flavor string `json:"flavor,omitempty"`
+	// Size selects the sizing profile of the participant's components: small, medium or large
+	Size string `json:"size,omitempty"`
+	// Postgres sets the size and StorageClass of the volume claim keeping the postgres data
+	Postgres json.RawMessage `json:"postgres,omitempty"`
+	// Scaling sets the replicas of the controlplane and dataplane or enables their autoscalers, see the OpenAPI
+	// document for its schema
+	Scaling json.RawMessage `json:"scaling,omitempty"`
+	// Scheduling sets the nodeSelector, tolerations and affinity of the participant's pods, see the OpenAPI document
+	// for its schema
+	Scheduling json.RawMessage `json:"scheduling,omitempty"`
+	// Credentials lists the verifiable credential types requested from the issuer
+	Credentials []string `json:"credentials,omitempty"`
+	// ComponentVersions pins the image tags and ComponentImages replaces the images of components, by deployment name
+	ComponentVersions map[string]string `json:"componentVersions,omitempty"`
+	ComponentImages   map[string]string `json:"componentImages,omitempty"`
+	// Env sets environment variables or config properties of components, by deployment name
+	Env map[string]map[string]string `json:"env,omitempty"`
+	// Routing selects ingress or gateway routing of the participant's APIs
+	Routing string `json:"routing,omitempty"`
+	// HelmValues override the values of the Helm chart participants are rendered from
+	HelmValues map[string]any `json:"helmValues,omitempty"`
+	// Components selects the components of a partial stack, e.g. controlplane and identityhub, all if empty
+	Components []string `json:"components,omitempty"`
+
+	Services            json.RawMessage `json:"services,omitempty"`
+	Mesh                json.RawMessage `json:"mesh,omitempty"`
+	Ingress             json.RawMessage `json:"ingress,omitempty"`
+	Tls                 json.RawMessage `json:"tls,omitempty"`
+	Gateway             json.RawMessage `json:"gateway,omitempty"`
+	NetworkPolicies     json.RawMessage `json:"networkPolicies,omitempty"`
+	Kustomization       json.RawMessage `json:"kustomization,omitempty"`
+	Seed                json.RawMessage `json:"seed,omitempty"`
+	SeedGenerator       json.RawMessage `json:"seedGenerator,omitempty"`
+	Catalog             json.RawMessage `json:"catalog,omitempty"`
+	ApiKeys             json.RawMessage `json:"apiKeys,omitempty"`
+	ContractDefinitions json.RawMessage `json:"contractDefinitions,omitempty"`
+	// PresignedCredentials are stored in the participant's identity hub as they are, see the OpenAPI document
+	PresignedCredentials json.RawMessage `json:"presignedCredentials,omitempty"`
+}
+
+// MetadataOptions describes who a participant belongs to and what it is for.
+type MetadataOptions struct {
+	// Owner is the email address of the participant's owner
+	Owner       string `json:"owner,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// AcceptedJob is the response of the requests starting a job, whose progress is reported by Job.
+type AcceptedJob struct {
+	JobId       string `json:"jobId"`
+	Participant string `json:"participant"`
+	// QueuePosition is the position of the job among the jobs waiting for a free slot
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// NotBefore is set instead of JobId when the provisioning was scheduled
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+}
+
+// ScheduledOperation is a provisioning waiting for its notBefore time or the teardown of a participant.
+type ScheduledOperation struct {
+	Participant string `json:"participant"`
+	// Operation is provision or teardown
+	Operation string    `json:"operation"`
+	At        time.Time `json:"at"`
+	// State is PENDING, or FAILED for provisionings that could not be started when they were due
+	State    string `json:"state"`
+	Teardown string `json:"teardown,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of starting the provisioning of one participant of a batch.
+type BatchResult struct {
+	Participant string `json:"participant"`
+	JobId       string `json:"jobId,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Job states reported in Job.Status
+const (
+	JobQueued    = "QUEUED"
+	JobRunning   = "RUNNING"
+	JobSucceeded = "SUCCEEDED"
+	JobFailed    = "FAILED"
+	JobCancelled = "CANCELLED"
+)
+
+// Job is a provisioning, upgrade, seeding or deletion running in the background.
+type Job struct {
+	Id            string     `json:"id"`
+	Participant   string     `json:"participant"`
+	Status        string     `json:"status"`
+	Phase         string     `json:"phase,omitempty"`
+	QueuePosition int        `json:"queuePosition,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	Code          string     `json:"code,omitempty"`
+	Phases        []JobPhase `json:"phases"`
+	// Resources lists the applied objects once the apply phase completed
+	Resources  []AppliedObject `json:"resources,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// AppliedObject identifies an object a job applied, with the resource version the API server returned for it.
+type AppliedObject struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Operation is created, updated or unchanged, or applied when the object wasn't compared with its previous state
+	Operation       string `json:"operation"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type JobPhase struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Participant states reported in ParticipantStatus.Status
+const (
+	StatusProvisioning = "PROVISIONING"
+	StatusSeeding      = "SEEDING"
+	StatusSeedFailed   = "SEED_FAILED"
+	StatusReady        = "READY"
+	StatusDegraded     = "DEGRADED"
+	StatusFailed       = "FAILED"
+	StatusNotFound     = "NOT_FOUND"
+	StatusDeleting     = "DELETING"
+	StatusDeleted      = "DELETED"
+	StatusTerminating  = "TERMINATING"
+	StatusOrphaned     = "ORPHANED"
+)
+
+type ParticipantStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Metadata describes who the participant belongs to and what it is for
+	Metadata *ParticipantMetadata `json:"metadata,omitempty"`
+	// ExpiresAt is when an ephemeral participant is deleted, ExpiresIn the lifetime it has left
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	ExpiresIn string     `json:"expiresIn,omitempty"`
+	// QueuePosition is the position of a pending provisioning among the jobs waiting for a free slot
+	QueuePosition int               `json:"queuePosition,omitempty"`
+	Components    []ComponentStatus `json:"components,omitempty"`
+	Events        []Event           `json:"events,omitempty"`
+	Seeding       *SeedingStatus    `json:"seeding,omitempty"`
+	// Steps reports the progress of the latest provisioning step by step
+	Steps        []ProgressStep      `json:"steps,omitempty"`
+	Endpoints    map[string]string   `json:"endpoints,omitempty"`
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+	Probes       []RouteProbe        `json:"probes,omitempty"`
+	Credentials  []HeldCredential    `json:"credentials,omitempty"`
+	// Remaining lists the resources left in the namespace of a TERMINATING or ORPHANED participant
+	Remaining []string `json:"remaining,omitempty"`
+	// Failure is why the provisioning of a FAILED participant was given up
+	Failure   *Failure   `json:"failure,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Provisioning breaks down how long the latest completed provisioning took
+	Provisioning *ProvisioningDurations `json:"provisioning,omitempty"`
+	// DidRotation reports the latest rotation of the participant's DID
+	DidRotation *DidRotationStatus `json:"didRotation,omitempty"`
+	LastUpdated time.Time          `json:"lastUpdated"`
+	// Stale marks the last known status reported while the provisioner can't reach Kubernetes
+	Stale bool `json:"stale,omitempty"`
+}
+
+// ProvisioningDurations breaks down how long a provisioning took from the request until the participant was READY.
+type ProvisioningDurations struct {
+	Total       string    `json:"total"`
+	Queued      string    `json:"queued,omitempty"`
+	Apply       string    `json:"apply,omitempty"`
+	Readiness   string    `json:"readiness,omitempty"`
+	Seeding     string    `json:"seeding,omitempty"`
+	Hooks       string    `json:"hooks,omitempty"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// DidRotationStatus reports the phase of a DID rotation: manifests, participantContext or credentials.
+type DidRotationStatus struct {
+	PreviousDid string    `json:"previousDid"`
+	Did         string    `json:"did"`
+	State       string    `json:"state"`
+	Phase       string    `json:"phase"`
+	Message     string    `json:"message,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ParticipantMetadata is the metadata of a participant and the labels given in its definition.
+type ParticipantMetadata struct {
+	Owner       string            `json:"owner,omitempty"`
+	Team        string            `json:"team,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Failure names the component blocking a provisioning that timed out and the events about its pods.
+type Failure struct {
+	Component string    `json:"component,omitempty"`
+	Reason    string    `json:"reason"`
+	Events    []Event   `json:"events,omitempty"`
+	FailedAt  time.Time `json:"failedAt"`
+}
+
+type ComponentStatus struct {
+	Name            string      `json:"name"`
+	Ready           bool        `json:"ready"`
+	ReadyReplicas   int32       `json:"readyReplicas"`
+	DesiredReplicas int32       `json:"desiredReplicas"`
+	Status          string      `json:"status"`
+	Message         string      `json:"message,omitempty"`
+	Reason          string      `json:"reason,omitempty"`
+	Image           string      `json:"image,omitempty"`
+	Version         string      `json:"version,omitempty"`
+	Pods            []PodStatus `json:"pods,omitempty"`
+}
+
+type PodStatus struct {
+	Name                  string `json:"name"`
+	Phase                 string `json:"phase"`
+	Ready                 bool   `json:"ready"`
+	Restarts              int32  `json:"restarts"`
+	Reason                string `json:"reason,omitempty"`
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	Image                 string `json:"image,omitempty"`
+}
+
+type Event struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Object    string    `json:"object"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type SeedingStatus struct {
+	State       string             `json:"state"`
+	Message     string             `json:"message,omitempty"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+	Steps       []SeedingStep      `json:"steps,omitempty"`
+	Credentials []CredentialStatus `json:"credentials,omitempty"`
+	Resources   *SeededResources   `json:"resources,omitempty"`
+	// Verification reports whether the seeded entities were found after seeding
+	Verification *CatalogVerification `json:"verification,omitempty"`
+}
+
+// CatalogVerification reports whether the seeded entities exist and the participant's catalog offers the seeded
+// assets, participants failing it are DEGRADED.
+type CatalogVerification struct {
+	Verified  bool      `json:"verified"`
+	Missing   []string  `json:"missing,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// SeededResources identifies the entities seeding created, e.g. the assets to negotiate for.
+type SeededResources struct {
+	Assets               []string `json:"assets,omitempty"`
+	Policies             []string `json:"policies,omitempty"`
+	ContractDefinitions  []string `json:"contractDefinitions,omitempty"`
+	ParticipantContextId string   `json:"participantContextId,omitempty"`
+	StsClientId          string   `json:"stsClientId,omitempty"`
+}
+
+// ProgressStep is the state of a step of a provisioning, e.g. manifestsApplied or assetsSeeded.
+type ProgressStep struct {
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+type SeedingStep struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Attempts  int       `json:"attempts,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CredentialStatus struct {
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CertificateStatus struct {
+	Name     string     `json:"name"`
+	Ready    bool       `json:"ready"`
+	Message  string     `json:"message,omitempty"`
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+type RouteProbe struct {
+	Name       string `json:"name"`
+	Url        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+type HeldCredential struct {
+	Id        string     `json:"id"`
+	Types     []string   `json:"types,omitempty"`
+	Issuer    string     `json:"issuer,omitempty"`
+	State     string     `json:"state"`
+	IssuedAt  *time.Time `json:"issuedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// HistoryEntry is a change of the status of a participant.
+type HistoryEntry struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	Component string    `json:"component,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Duration is how long the participant stayed in the status, empty for its current status
+	Duration string `json:"duration,omitempty"`
+	// Provisioning breaks down how long the provisioning took, on the READY entry ending it
+	Provisioning *ProvisioningDurations `json:"provisioning,omitempty"`
+}
+
+// ListPage is the envelope of the responses of list endpoints.
+type ListPage[T any] struct {
+	Items []T `json:"items"`
+	// Total is the number of items across all pages
+	Total int `json:"total"`
+	// Page is the number of the page, counted from 1
+	Page int `json:"page"`
+	// Limit is the page size, 0 when all items are returned
+	Limit int `json:"limit"`
+	// Continue is passed as the continue option to list the next page, empty on the last page
+	Continue string `json:"continue,omitempty"`
+}
+
+// ParticipantList is a page of participants.
+type ParticipantList struct {
+	ListPage[ParticipantStatus]
+	Summary StatusSummary `json:"summary"`
+}
+
+type StatusSummary struct {
+	Total    int            `json:"total"`
+	Ready    int            `json:"ready"`
+	ByStatus map[string]int `json:"byStatus"`
+}
+
+// ApiKeys are the keys the management and identity APIs of a participant are called with.
+type ApiKeys struct {
+	ManagementApiKey string `json:"managementApiKey"`
+	IdentityApiKey   string `json:"identityApiKey"`
+	// RotatedAt is the RFC 3339 time the keys were last rotated, empty for keys never rotated
+	RotatedAt string `json:"rotatedAt,omitempty"`
+}
+
+// ParticipantExport is the backup of a participant, with its credentials redacted.
+type ParticipantExport struct {
+	ParticipantName string    `json:"participantName"`
+	ExportedAt      time.Time `json:"exportedAt"`
+	TemplateVersion string    `json:"templateVersion,omitempty"`
+	Did             string    `json:"did,omitempty"`
+	// Definition is nil for participants the provisioner didn't seed
+	Definition *ParticipantDefinition `json:"definition,omitempty"`
+	// Manifests are the live objects of the participant as YAML
+	Manifests          string    `json:"manifests"`
+	Seeded             SeededIds `json:"seeded"`
+	CompletedSeedSteps []string  `json:"completedSeedSteps,omitempty"`
+}
+
+// SeededIds lists the entities seeded into the participant's connector.
+type SeededIds struct {
+	Assets              []string `json:"assets,omitempty"`
+	Policies            []string `json:"policies,omitempty"`
+	ContractDefinitions []string `json:"contractDefinitions,omitempty"`
+}
+
+// DriftReport lists the objects of a participant whose live state departs from the latest applied revision.
+type DriftReport struct {
+	Participant string        `json:"participant"`
+	Revision    int           `json:"revision"`
+	CheckedAt   time.Time     `json:"checkedAt"`
+	Drifted     bool          `json:"drifted"`
+	Objects     []DriftObject `json:"objects,omitempty"`
+}
+
+// DriftObject is an object that is missing or whose fields were changed.
+type DriftObject struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Action is missing or changed
+	Action string   `json:"action"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// SmokeTestRequest selects the counterparty of a smoke test and optionally the asset that is negotiated.
+type SmokeTestRequest struct {
+	Counterparty string `json:"counterparty,omitempty"`
+	AssetId      string `json:"assetId,omitempty"`
+}
+
+// SmokeTestResult reports whether a participant can find, negotiate and transfer the data of a counterparty.
+type SmokeTestResult struct {
+	Participant  string          `json:"participant"`
+	Counterparty string          `json:"counterparty"`
+	Passed       bool            `json:"passed"`
+	AssetId      string          `json:"assetId,omitempty"`
+	AgreementId  string          `json:"agreementId,omitempty"`
+	Steps        []SmokeTestStep `json:"steps"`
+	StartedAt    time.Time       `json:"startedAt"`
+	FinishedAt   time.Time       `json:"finishedAt"`
+}
+
+// SmokeTestStep is the outcome of the catalog, negotiation or transfer step of a smoke test.
+type SmokeTestStep struct {
+	Name string `json:"name"`
+	// State is COMPLETED, FAILED or SKIPPED after an earlier step failed
+	State    string `json:"state"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Revision is a set of manifests applied to a participant, which it can be rolled back to.
+type Revision struct {
+	Number          int       `json:"revision"`
+	Cause           string    `json:"cause"`
+	TemplateVersion string    `json:"templateVersion,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const resourcesPath = "/api/v1/resources/"
+
+func participantPath(name string) string {
+	return resourcesPath + url.PathEscape(name)
+}
+
+// Provision starts the provisioning of a participant.
+func (c *Client) Provision(ctx context.Context, definition ParticipantDefinition) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, resourcesPath, definition)
+}
+
+// ProvisionVerified starts the provisioning of a participant after checking that its DID document lists the
+// participant's CredentialService and DSP endpoint. A mismatch fails with DID_PREFLIGHT_FAILED.
+func (c *Client) ProvisionVerified(ctx context.Context, definition ParticipantDefinition) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, resourcesPath+"?verifyDid=true", definition)
+}
+
+// ProvisionBatch starts the provisioning of several participants, at most concurrency at a time, zero for the
+// provisioner's default. Invalid definitions are reported in their result without failing the others.
+func (c *Client) ProvisionBatch(ctx context.Context, definitions []ParticipantDefinition, concurrency int) ([]BatchResult, error) {
+	path := resourcesPath + "batch"
+	if concurrency > 0 {
+		path += "?concurrency=" + strconv.Itoa(concurrency)
+	}
+	return doJson[[]BatchResult](ctx, c, http.MethodPost, path, definitions)
+}
+
+// Update upgrades an existing participant to the definition.
+func (c *Client) Update(ctx context.Context, definition ParticipantDefinition) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPut, participantPath(definition.ParticipantName), definition)
+}
+
+// Delete starts the deletion of a participant.
+func (c *Client) Delete(ctx context.Context, name string) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodDelete, participantPath(name), nil)
+}
+
+// Seed seeds the data of a participant again, resuming after the last completed step.
+func (c *Client) Seed(ctx context.Context, name string) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, participantPath(name)+"/seed", nil)
+}
+
+// Rollback applies the manifests of an earlier revision of a participant again.
+func (c *Client) Rollback(ctx context.Context, name string, revision int) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, participantPath(name)+"/rollback?revision="+strconv.Itoa(revision), nil)
+}
+
+// Reconcile applies the latest revision of a participant again, also running its missing seed steps if seed is set.
+func (c *Client) Reconcile(ctx context.Context, name string, seed bool) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, participantPath(name)+"/reconcile?seed="+strconv.FormatBool(seed), nil)
+}
+
+// RotateDid changes the DID of a participant, recreating its participant context and requesting its credentials for
+// the new DID.
+func (c *Client) RotateDid(ctx context.Context, name string, did string) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, participantPath(name)+"/rotate-did", map[string]string{"did": did})
+}
+
+// Revisions lists the revisions of a participant.
+func (c *Client) Revisions(ctx context.Context, name string) ([]Revision, error) {
+	return doJson[[]Revision](ctx, c, http.MethodGet, participantPath(name)+"/revisions", nil)
+}
+
+// ApiKeys returns the keys of the management and identity APIs of a participant. The provisioner audits every read.
+func (c *Client) ApiKeys(ctx context.Context, name string) (ApiKeys, error) {
+	return doJson[ApiKeys](ctx, c, http.MethodGet, participantPath(name)+"/credentials", nil)
+}
+
+// Export returns the definition, live objects and seeded entity IDs of a participant, for backups or cloning it.
+func (c *Client) Export(ctx context.Context, name string) (ParticipantExport, error) {
+	return doJson[ParticipantExport](ctx, c, http.MethodGet, participantPath(name)+"/export", nil)
+}
+
+// Import provisions the participant of an export again, e.g. to restore it after a disaster.
+func (c *Client) Import(ctx context.Context, export ParticipantExport) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodPost, resourcesPath+"import", export)
+}
+
+// Drift lists the objects of a participant that were changed or deleted out-of-band since it was last applied.
+func (c *Client) Drift(ctx context.Context, name string) (DriftReport, error) {
+	return doJson[DriftReport](ctx, c, http.MethodGet, participantPath(name)+"/drift", nil)
+}
+
+// SmokeTest requests a catalog, negotiates a contract and dry-runs a transfer between a participant and the
+// counterparty, the participant itself if it is empty, and reports the outcome of each step.
+func (c *Client) SmokeTest(ctx context.Context, name string, counterparty string) (SmokeTestResult, error) {
+	return doJson[SmokeTestResult](ctx, c, http.MethodPost, participantPath(name)+"/smoketest", SmokeTestRequest{Counterparty: counterparty})
+}
+
+// Job returns the progress of a job.
+func (c *Client) Job(ctx context.Context, id string) (Job, error) {
+	return doJson[Job](ctx, c, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id), nil)
+}
+
+// CancelJob stops a queued or running job and returns it, the job may still be stopping when it's RUNNING.
+func (c *Client) CancelJob(ctx context.Context, id string) (Job, error) {
+	return doJson[Job](ctx, c, http.MethodDelete, "/api/v1/jobs/"+url.PathEscape(id), nil)
+}
+
+// CancelJobAndTearDown stops a queued or running job and deletes the participant once it stopped.
+func (c *Client) CancelJobAndTearDown(ctx context.Context, id string) (AcceptedJob, error) {
+	return doJson[AcceptedJob](ctx, c, http.MethodDelete, "/api/v1/jobs/"+url.PathEscape(id)+"?teardown=true", nil)
+}
+
+// Schedules lists the scheduled provisionings and teardowns, the next one first.
+func (c *Client) Schedules(ctx context.Context) ([]ScheduledOperation, error) {
+	return doJson[[]ScheduledOperation](ctx, c, http.MethodGet, "/api/v1/schedules", nil)
+}
+
+// CancelSchedule cancels the scheduled provisioning of a participant.
+func (c *Client) CancelSchedule(ctx context.Context, name string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/schedules/"+url.PathEscape(name), nil)
+	return err
+}
+
+// StatusOptions selects the sections of a participant status.
+type StatusOptions struct {
+	// Fields lists the optional sections to include, e.g. components and seeding, all when empty
+	Fields []string
+	// Refresh evaluates the status from the cluster instead of returning a cached one
+	Refresh bool
+}
+
+// Status returns the status of a participant. Unknown participants are reported as NOT_FOUND.
+func (c *Client) Status(ctx context.Context, name string, options StatusOptions) (ParticipantStatus, error) {
+	participantStatus, err := doJson[ParticipantStatus](ctx, c, http.MethodGet, participantPath(name)+"/status"+options.query(), nil)
+	if IsNotFound(err) {
+		return ParticipantStatus{Name: name, Status: StatusNotFound}, nil
+	}
+	return participantStatus, err
+}
+
+// Statuses returns the statuses of several participants in one call, in the order of the names. Unknown participants
+// are reported as NOT_FOUND. The list has no further pages.
+func (c *Client) Statuses(ctx context.Context, names []string, options StatusOptions) (ParticipantList, error) {
+	body := map[string][]string{"participants": names}
+	return doJson[ParticipantList](ctx, c, http.MethodPost, resourcesPath+"status"+options.query(), body)
+}
+
+func (o StatusOptions) query() string {
+	query := url.Values{}
+	if len(o.Fields) > 0 {
+		query.Set("fields", strings.Join(o.Fields, ","))
+	}
+	if o.Refresh {
+		query.Set("refresh", "true")
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// StatusHistory returns the status changes of a participant, oldest first.
+func (c *Client) StatusHistory(ctx context.Context, name string) ([]HistoryEntry, error) {
+	return doJson[[]HistoryEntry](ctx, c, http.MethodGet, participantPath(name)+"/status/history", nil)
+}
+
+// ListOptions selects the order and page of a participant listing.
+type ListOptions struct {
+	// Sort is name, status or lastUpdated, name when empty
+	Sort       string
+	Descending bool
+	// Limit is the number of participants per page, zero lists all
+	Limit int
+	// Continue is the token of the page to list, from ParticipantList.Continue
+	Continue string
+	// Label is a label selector the participants must match, e.g. team=alpha
+	Label string
+	// Team and Environment select participants by their metadata
+	Team        string
+	Environment string
+}
+
+// List lists the participants with the status of their components.
+func (c *Client) List(ctx context.Context, options ListOptions) (ParticipantList, error) {
+	query := url.Values{}
+	if options.Sort != "" {
+		query.Set("sort", options.Sort)
+	}
+	if options.Descending {
+		query.Set("order", "desc")
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Continue != "" {
+		query.Set("continue", options.Continue)
+	}
+	for key, value := range map[string]string{"label": options.Label, "team": options.Team, "environment": options.Environment} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	path := resourcesPath
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return doJson[ParticipantList](ctx, c, http.MethodGet, path, nil)
+}
+
+// LogOptions selects the logs of a component.
+type LogOptions struct {
+	// Component is controlplane, dataplane or identityhub
+	Component string
+	// Container defaults to the component's main container
+	Container string
+	// Tail is the number of lines per pod, the provisioner's default when zero
+	Tail int
+	// Previous returns the logs of the previous, e.g. crashed, containers
+	Previous bool
+}
+
+// Logs returns the latest log lines of the pods of a participant's component.
+func (c *Client) Logs(ctx context.Context, name string, options LogOptions) (string, error) {
+	query := url.Values{"component": {options.Component}}
+	if options.Container != "" {
+		query.Set("container", options.Container)
+	}
+	if options.Tail > 0 {
+		query.Set("tail", strconv.Itoa(options.Tail))
+	}
+	if options.Previous {
+		query.Set("previous", "true")
+	}
+	data, err := c.do(ctx, http.MethodGet, participantPath(name)+"/logs?"+query.Encode(), nil)
+	return string(data), err
+}
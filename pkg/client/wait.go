@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Statuses a participant doesn't leave without intervention, waiting for another one fails
+var failedStatuses = []string{StatusFailed, StatusSeedFailed}
+
+// WaitForJob polls the job until it succeeded, and fails when it failed or the context ends.
+func (c *Client) WaitForJob(ctx context.Context, id string) (Job, error) {
+	var job Job
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		if job, err = c.Job(ctx, id); err != nil {
+			return false, err
+		}
+		switch job.Status {
+		case JobSucceeded:
+			return true, nil
+		case JobFailed:
+			return false, fmt.Errorf("job %s failed in phase %s: %s (%s)", id, job.Phase, job.Error, job.Code)
+		}
+		return false, nil
+	})
+	return job, err
+}
+
+// WaitForStatus polls the status of the participant until it is one of the targets, and fails when the participant
+// FAILED or the context ends.
+func (c *Client) WaitForStatus(ctx context.Context, name string, targets ...string) (ParticipantStatus, error) {
+	var participantStatus ParticipantStatus
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		participantStatus, err = c.Status(ctx, name, StatusOptions{Fields: []string{"components"}, Refresh: true})
+		if err != nil {
+			return false, err
+		}
+		if slices.Contains(targets, participantStatus.Status) {
+			return true, nil
+		}
+		if slices.Contains(failedStatuses, participantStatus.Status) {
+			return false, fmt.Errorf("%s is %s: %s", name, participantStatus.Status, participantStatus.Message)
+		}
+		return false, nil
+	})
+	return participantStatus, err
+}
+
+// poll calls check at the poll interval until it reports completion or fails, or the context ends.
+func (c *Client) poll(ctx context.Context, check func() (bool, error)) error {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Bounds of the lines returned per pod by the logs endpoint
+const (
+	defaultLogTailLines = 200
+	maxLogTailLines     = 5000
+)
+
+// Components whose logs can be read through the logs endpoint
+var logComponents = []string{"controlplane", "dataplane", "identityhub", "postgres", "federatedcatalog"}
+
+// podLogReader reads pod logs through the API server, which the controller-runtime client doesn't support.
+type podLogReader struct {
+	httpClient *http.Client
+	host       string
+}
+
+func newPodLogReader(config *rest.Config) (*podLogReader, error) {
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &podLogReader{httpClient: httpClient, host: strings.TrimSuffix(config.Host, "/")}, nil
+}
+
+// tail returns the last lines of the pod's logs, of the given container or the only one if empty.
+func (r *podLogReader) tail(ctx context.Context, namespace string, pod string, container string, lines int) (string, error) {
+	return r.read(ctx, namespace, pod, container, lines, false)
+}
+
+// read returns the last lines of the pod's logs, of the previous instance of the container when previous is set,
+// e.g. to see why it crashed.
+func (r *podLogReader) read(ctx context.Context, namespace string, pod string, container string, lines int, previous bool) (string, error) {
+	// pods of participants in the shared namespace keep the names generated for their deployments
+	if status.SharedNamespace != "" {
+		namespace = status.SharedNamespace
+	}
+	query := url.Values{"tailLines": {strconv.Itoa(lines)}}
+	if container != "" {
+		query.Set("container", container)
+	}
+	if previous {
+		query.Set("previous", "true")
+	}
+	logUrl := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s/log?%s", r.host, url.PathEscape(namespace), url.PathEscape(pod), query.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, logUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reading logs of %s/%s: %s: %s", namespace, pod, response.Status, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// getComponentLogs returns the recent logs of the pods of a participant's component as plain text, one section per
+// pod, so admins can debug a participant without access to the cluster. Only namespaces of participants are read.
+func getComponentLogs(kubeClient client.Client, ctx context.Context, logs *podLogReader) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespace := c.Params("participantName")
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid participant name: "+errs[0])
+		}
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		component := c.Query("component")
+		if !slices.Contains(logComponents, component) {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("component must be one of %s", strings.Join(logComponents, ", ")))
+		}
+		lines := c.QueryInt("tail", defaultLogTailLines)
+		if lines < 1 || lines > maxLogTailLines {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("tail must be between 1 and %d", maxLogTailLines))
+		}
+		deployment := &appsv1.Deployment{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: component}, deployment); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fiber.NewError(fiber.StatusNotFound, "component not found")
+			}
+			return err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return err
+		}
+		pods := &corev1.PodList{}
+		if err := kubeClient.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return err
+		}
+		var out strings.Builder
+		for _, pod := range pods.Items {
+			text, err := logs.read(c.UserContext(), namespace, pod.Name, c.Query("container", component), lines, c.QueryBool("previous"))
+			if err != nil {
+				text = err.Error() + "\n"
+			}
+			fmt.Fprintf(&out, "==> %s <==\n%s", pod.Name, text)
+			if !strings.HasSuffix(text, "\n") {
+				out.WriteString("\n")
+			}
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(out.String())
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// componentClient serves the namespace of the participant alice, its controlplane deployment and its pods. The
+// namespace bob isn't managed by the provisioner.
+type componentClient struct {
+	client.Client
+}
+
+func (componentClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	if namespace, ok := obj.(*corev1.Namespace); ok {
+		*namespace = corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: key.Name}}
+		if key.Name == "alice" {
+			namespace.Labels = map[string]string{status.ManagedByLabel: status.ManagedByValue}
+		}
+		return nil
+	}
+	if key != (client.ObjectKey{Namespace: "alice", Name: "controlplane"}) {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, key.Name)
+	}
+	*obj.(*appsv1.Deployment) = appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "controlplane"}}}}
+	return nil
+}
+
+func (componentClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1.PodList).Items = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-7d9f", Labels: map[string]string{"app": "controlplane"}}}}
+	return nil
+}
+
+func TestGetComponentLogs(t *testing.T) {
+	var query string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/alice/pods/controlplane-7d9f/log" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		_, _ = io.WriteString(w, "started\nready")
+	}))
+	defer apiServer.Close()
+	logs := &podLogReader{httpClient: apiServer.Client(), host: apiServer.URL}
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/:participantName/logs", requireAdminKey("admin-key"), getComponentLogs(componentClient{}, context.Background(), logs))
+	get := func(target string, key string) *http.Response {
+		t.Helper()
+		request := httptest.NewRequest("GET", target, nil)
+		request.Header.Set("x-api-key", key)
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := get("/alice/logs?component=controlplane", "tenant-key"); resp.StatusCode != fiber.StatusUnauthorized || query != "" {
+		t.Errorf("expected requests without the admin key to be rejected, got %d", resp.StatusCode)
+	}
+	resp := get("/alice/logs?component=controlplane&tail=50&previous=true", "admin-key")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || string(body) != "==> controlplane-7d9f <==\nstarted\nready\n" {
+		t.Errorf("unexpected response %d %q", resp.StatusCode, body)
+	}
+	for _, expected := range []string{"tailLines=50", "container=controlplane", "previous=true"} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected %s in the log query %s", expected, query)
+		}
+	}
+
+	for path, expected := range map[string]int{
+		"/alice/logs?component=vault":               fiber.StatusBadRequest,
+		"/alice/logs?component=controlplane&tail=0": fiber.StatusBadRequest,
+		"/bob/logs?component=controlplane":          fiber.StatusNotFound,
+		"/kube-system/logs?component=controlplane":  fiber.StatusNotFound,
+		"/Alice_Corp/logs?component=controlplane":   fiber.StatusBadRequest,
+	} {
+		if resp := get(path, "admin-key"); resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d", path, expected, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Name of the volume claim keeping the postgres data of participants with persistent storage
+const postgresDataClaim = "postgres-data"
+
+// Size of the postgres volume claim of participants without a sizing profile or another size
+const defaultPostgresStorage = "1Gi"
+
+// StorageClass of the postgres volume claims unless the definition selects another one, set with
+// --postgres-storage-class. The cluster's default StorageClass is used if empty.
+var postgresStorageClass string
+
+// PostgresOptions keeps the postgres data of the participant on a volume claim.
+type PostgresOptions struct {
+	// StorageSize is the requested size of the claim, e.g. 20Gi, by default that of the sizing profile or 1Gi
+	StorageSize string `json:"storageSize,omitempty"`
+	// StorageClassName selects the StorageClass of the claim, by default the provisioner's --postgres-storage-class
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+func (o *PostgresOptions) validate() error {
+	if o.StorageSize != "" {
+		quantity, err := resource.ParseQuantity(o.StorageSize)
+		if err != nil || quantity.Sign() <= 0 {
+			return fmt.Errorf("postgres: invalid storageSize %q", o.StorageSize)
+		}
+	}
+	if o.StorageClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(o.StorageClassName); len(errs) > 0 {
+			return fmt.Errorf("postgres: storageClassName: %s", strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// persistsPostgres reports whether the postgres data of the participant is kept on a volume claim, which sizing
+// profiles imply. The data of other participants doesn't survive a restart of postgres.
+func (p *ParticipantDefinition) persistsPostgres() bool {
+	return p.Size != "" || p.Postgres != nil
+}
+
+// postgresClaim returns the size and StorageClass of the participant's postgres volume claim.
+func (p *ParticipantDefinition) postgresClaim() (size string, storageClass string) {
+	size, storageClass = defaultPostgresStorage, postgresStorageClass
+	if p.Size != "" {
+		size = sizingProfiles[p.Size].postgresStorage
+	}
+	if p.Postgres != nil && p.Postgres.StorageSize != "" {
+		size = p.Postgres.StorageSize
+	}
+	if p.Postgres != nil && p.Postgres.StorageClassName != "" {
+		storageClass = p.Postgres.StorageClassName
+	}
+	return size, storageClass
+}
+
+// postgresClaimManifest renders the volume claim of the postgres data.
+func postgresClaimManifest(namespace string, size string, storageClass string) (string, error) {
+	spec := map[string]any{
+		"accessModes": []string{"ReadWriteOnce"},
+		"resources":   map[string]any{"requests": map[string]any{"storage": size}},
+	}
+	if storageClass != "" {
+		spec["storageClassName"] = storageClass
+	}
+	doc, err := yaml.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"metadata":   map[string]any{"name": postgresDataClaim, "namespace": namespace},
+		"spec":       spec,
+	})
+	return string(doc), err
+}
+
+// postgresDataMutator keeps the data of the postgres deployment on the volume claim.
+func postgresDataMutator(obj *unstructured.Unstructured) error {
+	if obj.GetKind() != "Deployment" || obj.GetName() != "postgres" {
+		return nil
+	}
+	return withPostgresData(obj)
+}
+
+// withPostgresData mounts the volume claim at the data directory of postgres. The data lives in a subdirectory, as
+// postgres refuses to initialize a directory with lost+found in it, and the pod is replaced rather than rolled, as the
+// claim can only be attached to one node.
+func withPostgresData(obj *unstructured.Unstructured) error {
+	err := updateContainer(obj, "postgres", func(container map[string]any) {
+		mounts, _ := container["volumeMounts"].([]any)
+		container["volumeMounts"] = append(mounts, map[string]any{"name": postgresDataClaim, "mountPath": "/var/lib/postgresql/data"})
+		env, _ := container["env"].([]any)
+		container["env"] = append(env, map[string]any{"name": "PGDATA", "value": "/var/lib/postgresql/data/pgdata"})
+	})
+	if err != nil {
+		return err
+	}
+	volumes, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "volumes")
+	if err != nil {
+		return err
+	}
+	volumes = append(volumes, map[string]any{"name": postgresDataClaim, "persistentVolumeClaim": map[string]any{"claimName": postgresDataClaim}})
+	if err := unstructured.SetNestedSlice(obj.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(obj.Object, map[string]any{"type": "Recreate"}, "spec", "strategy")
+}
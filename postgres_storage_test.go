@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPostgresClaim(t *testing.T) {
+	postgresStorageClass = "standard-rwo"
+	defer func() { postgresStorageClass = "" }()
+
+	cases := []struct {
+		name         string
+		definition   ParticipantDefinition
+		size         string
+		storageClass string
+	}{
+		{"provisioner default", ParticipantDefinition{Postgres: &PostgresOptions{}}, defaultPostgresStorage, "standard-rwo"},
+		{"sizing profile", ParticipantDefinition{Size: "medium"}, "10Gi", "standard-rwo"},
+		{"definition", ParticipantDefinition{Size: "medium", Postgres: &PostgresOptions{StorageSize: "20Gi", StorageClassName: "fast"}}, "20Gi", "fast"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			size, storageClass := c.definition.postgresClaim()
+			if size != c.size || storageClass != c.storageClass {
+				t.Errorf("expected %s of %s, got %s of %s", c.size, c.storageClass, size, storageClass)
+			}
+		})
+	}
+
+	doc, err := postgresClaimManifest("acme", "5Gi", "fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := parseObject(t, doc)
+	if class, _, _ := unstructured.NestedString(claim.Object, "spec", "storageClassName"); class != "fast" {
+		t.Errorf("expected the StorageClass on the claim, got %q", class)
+	}
+	if doc, _ := postgresClaimManifest("acme", "5Gi", ""); parseObject(t, doc).Object["spec"].(map[string]any)["storageClassName"] != nil {
+		t.Error("expected the cluster's default StorageClass without one")
+	}
+}
+
+func TestPostgresOptionsValidate(t *testing.T) {
+	if err := (&PostgresOptions{StorageSize: "20Gi", StorageClassName: "ssd.example.com"}).validate(); err != nil {
+		t.Error(err)
+	}
+	for _, options := range []PostgresOptions{{StorageSize: "lots"}, {StorageSize: "0"}, {StorageClassName: "Fast_SSD"}} {
+		if err := options.validate(); err == nil {
+			t.Errorf("%+v: expected an error", options)
+		}
+	}
+}
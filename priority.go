@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type priorityTier struct {
+	value            int32
+	preemptionPolicy corev1.PreemptionPolicy
+	description      string
+}
+
+// Participant tiers and the PriorityClass each of them is scheduled with. Demo participants never preempt other pods,
+// so under pressure they are the first to give way.
+var priorityTiers = map[string]priorityTier{
+	"production": {value: 100000, preemptionPolicy: corev1.PreemptLowerPriority, description: "Production dataspace participants"},
+	"standard":   {value: 10000, preemptionPolicy: corev1.PreemptLowerPriority, description: "Standard dataspace participants"},
+	"demo":       {value: 1000, preemptionPolicy: corev1.PreemptNever, description: "Demo dataspace participants"},
+}
+
+func priorityClassName(tier string) string {
+	return "aruba-participant-" + tier
+}
+
+func validateTier(tier string) error {
+	if _, ok := priorityTiers[tier]; !ok {
+		return fmt.Errorf("unknown tier %q", tier)
+	}
+	return nil
+}
+
+// ensurePriorityClass creates or updates the PriorityClass of the given tier.
+func ensurePriorityClass(c client.Client, ctx context.Context, tier string) error {
+	settings := priorityTiers[tier]
+	priorityClass := &schedulingv1.PriorityClass{
+		TypeMeta:         metav1.TypeMeta{APIVersion: "scheduling.k8s.io/v1", Kind: "PriorityClass"},
+		ObjectMeta:       metav1.ObjectMeta{Name: priorityClassName(tier)},
+		Value:            settings.value,
+		PreemptionPolicy: &settings.preemptionPolicy,
+		Description:      settings.description,
+	}
+	return applyResource(c, ctx, priorityClass)
+}
+
+// priorityClassMutator references the tier's PriorityClass from all pod templates.
+func priorityClassMutator(tier string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		switch obj.GetKind() {
+		case "Deployment", "StatefulSet":
+			return unstructured.SetNestedField(obj.Object, priorityClassName(tier), "spec", "template", "spec", "priorityClassName")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,714 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// provisioningPlan is a validated participant definition together with everything needed to render and apply its
+// manifests.
+type provisioningPlan struct {
+	definition ParticipantDefinition
+	templates  manifestSet
+	extraYaml  string
+	creds      participantCredentials
+	// credentialsChanged is set when a callback token or management API key was generated or keys were overridden,
+	// which still have to be stored
+	credentialsChanged bool
+	keys               participantKeyPair
+	// keysGenerated is set when the participant's key pair was generated, which still has to be stored
+	keysGenerated bool
+	mutators      []objectMutator
+	vault         VaultConfig
+}
+
+// planProvisioning validates the definition and prepares the rendering of its manifests without changing the
+// cluster. Invalid definitions are reported as 400 errors.
+func planProvisioning(c client.Client, ctx context.Context, definition ParticipantDefinition, registry *dataspaceRegistry, callbackBaseUrl string, flavors *stackFlavors) (provisioningPlan, error) {
+	dataspaces, err := registry.all(ctx)
+	if err != nil {
+		return provisioningPlan{}, err
+	}
+	if definition.Did == "" && didWebHost != "" && definition.ParticipantName != "" {
+		definition.Did = hostedDid(definition.ParticipantName)
+	}
+	definition.Mesh = meshFor(dataspaces[dataspaceOf(definition)], definition)
+	if err := definition.validate(ctx, dataspaces); err != nil {
+		return provisioningPlan{}, err
+	}
+	for name, options := range definition.Services {
+		if err := options.validate(name); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if err := validateMetadata(definition.Labels, definition.Annotations); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if definition.Metadata != nil {
+		if err := definition.Metadata.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Mesh != nil {
+		if err := definition.Mesh.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Tls != nil {
+		if err := definition.Tls.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Ingress != nil {
+		if err := definition.Ingress.validate(definition.Tls); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if err := validateRouting(routingOf(definition), gatewayFor(definition)); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validateSharedNamespace(definition); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	var expiry objectMutator
+	if definition.Teardown != "" {
+		if definition.ExpiresAfter != "" {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, "teardown and expiresAfter are exclusive")
+		}
+		teardownAt, err := parseTeardown(definition.Teardown, definition.provisionedFrom(time.Now()))
+		if err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, "teardown: "+err.Error())
+		}
+		if expiry, err = teardownMutator(c, ctx, definition.ParticipantName, definition.Teardown, teardownAt); err != nil {
+			return provisioningPlan{}, err
+		}
+	}
+	if definition.ExpiresAfter != "" {
+		lifetime, err := parseLifetime(definition.ExpiresAfter)
+		if err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, "expiresAfter: "+err.Error())
+		}
+		if expiry, err = expiryMutator(c, ctx, definition.ParticipantName, definition.ExpiresAfter, lifetime); err != nil {
+			return provisioningPlan{}, err
+		}
+	}
+	if definition.NetworkPolicies != nil {
+		if err := definition.NetworkPolicies.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if err := validateDataspace(dataspaces, definition.Dataspace); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validateComponentVersions(definition.ComponentVersions, definition.ComponentImages); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validateComponents(definition.Components); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validateComponentEnv(definition.Env); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	for _, credential := range definition.PresignedCredentials {
+		if err := credential.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	for _, spec := range definition.ContractDefinitions {
+		if err := spec.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.KeyAlgorithm != "" {
+		if err := validateKeyAlgorithm(definition.KeyAlgorithm); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Tier != "" {
+		if err := validateTier(definition.Tier); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Size != "" {
+		if err := validateSize(definition.Size); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if definition.Postgres != nil {
+		if err := definition.Postgres.validate(); err != nil {
+			return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if err := validateScaling(definition); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := definition.Scheduling.validate(); err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	templates, err := flavors.manifests(definition.Flavor)
+	if err != nil {
+		return provisioningPlan{}, err
+	}
+	if len(definition.HelmValues) > 0 && templates.Chart == nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, "helmValues require participants to be rendered from a Helm chart")
+	}
+	if templates.Chart != nil {
+		rendered, err := templates.Chart.render(ctx, definition)
+		if err != nil {
+			return provisioningPlan{}, withCode(codeManifestRenderFailed, fiber.StatusBadRequest, err)
+		}
+		templates.Connector, templates.IdentityHub = rendered, ""
+	}
+	overlays, err := kustomizationMutator(dataspaces[dataspaceOf(definition)].Kustomization, definition.Kustomization)
+	if err != nil {
+		return provisioningPlan{}, fiber.NewError(fiber.StatusBadRequest, "kustomization: "+err.Error())
+	}
+	extraYaml, err := definition.extraManifests()
+	if err != nil {
+		return provisioningPlan{}, withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, err)
+	}
+	imageRegistry := registryFor(dataspaces[dataspaceOf(definition)])
+	pullSecret, err := imageRegistry.manifests(definition.ParticipantName)
+	if err != nil {
+		return provisioningPlan{}, withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, err)
+	}
+	if pullSecret != "" && extraYaml != "" {
+		extraYaml += "\n---\n" + pullSecret
+	} else if pullSecret != "" {
+		extraYaml = pullSecret
+	}
+	if participantRbac {
+		rbac, err := participantRbacManifests(definition.ParticipantName)
+		if err != nil {
+			return provisioningPlan{}, withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, err)
+		}
+		if extraYaml != "" {
+			extraYaml += "\n---\n"
+		}
+		extraYaml += rbac
+	}
+
+	// Re-applying the templates must not reset rotated keys, unless the definition gives new ones
+	stored, err := loadCredentials(c, ctx, definition.ParticipantName)
+	if err != nil {
+		return provisioningPlan{}, err
+	}
+	creds, overridden := stored.withOverrides(definition.ApiKeys)
+	creds, generated, err := generateCredentials(c, ctx, definition, creds)
+	if err != nil {
+		return provisioningPlan{}, err
+	}
+	newCallbackToken := callbackBaseUrl != "" && creds.CallbackToken == ""
+	if newCallbackToken {
+		if creds.CallbackToken, err = generateApiKey(); err != nil {
+			return provisioningPlan{}, err
+		}
+	}
+	if definition.AdoptNamespace, err = adoptsNamespace(c, ctx, definition); err != nil {
+		return provisioningPlan{}, err
+	}
+	keys, keysGenerated, err := planKeyPair(c, ctx, definition)
+	if err != nil {
+		return provisioningPlan{}, err
+	}
+	mutators := append(definition.mutators(), credentialsMutator(creds, superUserKey(definition.ApiKeys)), keyPairMutator(keys))
+	if callbackBaseUrl != "" {
+		mutators = append(mutators, callbackMutator(callbackBaseUrl, definition.ParticipantName, creds.CallbackToken))
+	}
+	vault := vaultFor(dataspaces[dataspaceOf(definition)])
+	if vault.enabled() {
+		mutators = append(mutators, vault.mutator(definition.ParticipantName))
+	}
+	if scheduling := mergeScheduling(dataspaces[dataspaceOf(definition)].Scheduling, definition.Scheduling); scheduling != nil {
+		mutators = append(mutators, scheduling.mutator())
+	}
+	if participantRbac {
+		mutators = append(mutators, serviceAccountMutator())
+	}
+	if imageRegistry.enabled() {
+		mutators = append(mutators, imageRegistry.mutator(definition.ParticipantName))
+	}
+	if anchors := dataspaces[dataspaceOf(definition)].TrustAnchors; len(anchors) > 0 {
+		mutators = append(mutators, trustAnchorMutator(anchors))
+	}
+	if dataspaces[dataspaceOf(definition)].Registration != nil {
+		registered, err := isRegistered(c, ctx, definition.ParticipantName)
+		if err != nil {
+			return provisioningPlan{}, err
+		}
+		if !registered {
+			mutators = append(mutators, registrationMutator())
+		}
+	}
+	if definition.hasComponent(federatedCatalogComponent) {
+		participants, err := dataspaceParticipants(c, ctx, dataspaceOf(definition), definition.ParticipantName)
+		if err != nil {
+			return provisioningPlan{}, err
+		}
+		crawler, err := federatedCatalogMutator(dataspaceOf(definition), definition.ParticipantName, participants)
+		if err != nil {
+			return provisioningPlan{}, withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, err)
+		}
+		mutators = append(mutators, crawler)
+	}
+	if expiry != nil {
+		mutators = append(mutators, expiry)
+	}
+	// the Gateway API conversion sees the ingresses as the other mutators left them
+	if routingOf(definition) == routingGateway {
+		mutators = append(mutators, gatewayFor(definition).mutator())
+	}
+	// overlays have the last word on the rendered objects, before they are fitted into the shared namespace
+	mutators = append(mutators, overlays)
+	if status.SharedNamespace != "" {
+		mutators = append(mutators, sharedNamespaceMutator(definition.ParticipantName))
+	}
+
+	return provisioningPlan{
+		definition:         definition,
+		templates:          templates,
+		extraYaml:          extraYaml,
+		creds:              creds,
+		credentialsChanged: newCallbackToken || overridden || generated,
+		keys:               keys,
+		keysGenerated:      keysGenerated,
+		mutators:           mutators,
+		vault:              vault,
+	}, nil
+}
+
+// apply renders the manifests and applies every object with the given action, returning the applied objects. A newly generated callback token and key pair are stored once all objects were applied, as are the
+// credentials of the participant's external Vault, if any; its components start once they are.
+func (p provisioningPlan) apply(c client.Client, ctx context.Context, kubernetesAction action, clients seedingClients) ([]appliedObject, error) {
+	if p.definition.Tier != "" {
+		if err := ensurePriorityClass(c, ctx, p.definition.Tier); err != nil {
+			return nil, err
+		}
+	}
+	resources, err := p.applyManifests(c, ctx, kubernetesAction)
+	if err != nil {
+		return nil, err
+	}
+	if p.credentialsChanged {
+		if err := storeCredentials(c, ctx, p.definition.ParticipantName, p.creds); err != nil {
+			return nil, err
+		}
+	}
+	if p.keysGenerated {
+		if err := storeKeyPair(c, ctx, p.definition.ParticipantName, p.keys); err != nil {
+			return nil, err
+		}
+	}
+	if p.vault.enabled() {
+		if err := setupParticipantVault(c, ctx, p.vault, p.definition, clients.forParticipant(p.definition)); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// applyManifests renders the manifests and passes every object to the action.
+func (p provisioningPlan) applyManifests(c client.Client, ctx context.Context, kubernetesAction action) ([]appliedObject, error) {
+	definition := p.definition
+	resources1, e1 := applyYaml(&definition.ParticipantName, &definition.Did, c, ctx, p.templates.Connector, kubernetesAction, p.mutators...)
+	if e1 != nil {
+		return nil, e1
+	}
+	resources2, e2 := applyYaml(&definition.ParticipantName, &definition.Did, c, ctx, p.templates.IdentityHub, kubernetesAction, p.mutators...)
+	if e2 != nil {
+		return nil, e2
+	}
+	mergedResources := append(resources1, resources2...)
+	if definition.hasComponent(federatedCatalogComponent) {
+		catalog, err := applyYaml(&definition.ParticipantName, &definition.Did, c, ctx, federatedCatalogYaml, kubernetesAction, p.mutators...)
+		if err != nil {
+			return nil, err
+		}
+		mergedResources = append(mergedResources, catalog...)
+	}
+	if p.extraYaml != "" {
+		var extraMutators []objectMutator
+		if status.SharedNamespace != "" {
+			extraMutators = append(extraMutators, sharedNamespaceMutator(definition.ParticipantName))
+		}
+		resources3, e3 := applyYaml(&definition.ParticipantName, &definition.Did, c, ctx, p.extraYaml, kubernetesAction, extraMutators...)
+		if e3 != nil {
+			return nil, e3
+		}
+		mergedResources = append(mergedResources, resources3...)
+	}
+	return mergedResources, nil
+}
+
+// dryRunValidation is the outcome of the server-side dry run of a rendered object.
+type dryRunValidation struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// render returns the rendered manifests as YAML, with credentials redacted.
+func (p provisioningPlan) render() (string, error) {
+	var docs []string
+	_, err := p.applyManifests(nil, context.Background(), func(_ client.Client, _ context.Context, object client.Object) error {
+		doc, err := yaml.Marshal(redactObject(object))
+		if err != nil {
+			return err
+		}
+		docs = append(docs, string(doc))
+		return nil
+	})
+	if err != nil {
+		return "", withCode(codeManifestRenderFailed, fiber.StatusInternalServerError, err)
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// validate runs a server-side dry run of every rendered object. Objects in a namespace that doesn't exist yet can't
+// be validated by the API server and are reported as such.
+func (p provisioningPlan) validate(c client.Client, ctx context.Context) ([]dryRunValidation, error) {
+	namespaceExists := true
+	if err := c.Get(ctx, client.ObjectKey{Name: p.definition.ParticipantName}, &corev1.Namespace{}); apierrors.IsNotFound(err) {
+		namespaceExists = false
+	} else if err != nil {
+		return nil, err
+	}
+	var results []dryRunValidation
+	_, err := p.applyManifests(c, ctx, func(c client.Client, ctx context.Context, object client.Object) error {
+		result := dryRunValidation{Kind: object.GetObjectKind().GroupVersionKind().Kind, Name: object.GetName()}
+		if object.GetNamespace() != "" && !namespaceExists {
+			result.Error = "not validated, the namespace does not exist yet"
+		} else if err := c.Patch(ctx, object, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership, client.DryRunAll); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Valid = true
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// Bounds of the number of participants of a batch that are provisioned concurrently
+const (
+	defaultBatchConcurrency = 5
+	maxBatchConcurrency     = 20
+	maxBatchSize            = 200
+)
+
+// provisioner bundles what provisioning jobs need beyond the plan of a participant.
+type provisioner struct {
+	kubeClient    client.Client
+	ctx           context.Context
+	statusChecker *status.StatusChecker
+	clients       seedingClients
+	dataspaces    *dataspaceRegistry
+	podLogs       *podLogReader
+	notifier      Notifier
+	// queue bounds the provisioning and seeding jobs running at a time
+	queue *workQueue
+	// work records the readiness waits and seedings so they are resumed after a restart, nil leaves them untracked
+	work *workTracker
+}
+
+// start provisions the planned participant in a background job: it applies the manifests, waits for the deployments,
+// seeds the data and runs the dataspace's hooks. Runs are recorded when rec is set. The job waits in the provisioner's
+// queue for a free slot and, when given a gate, for a free slot in it as well.
+func (p *provisioner) start(ctx context.Context, plan provisioningPlan, rec *recording, gate chan struct{}) (*provisioningJob, error) {
+	definition := plan.definition
+	namespace := definition.ParticipantName
+	existing, err := status.IsManagedNamespace(ctx, p.kubeClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	job, err := jobs.create(ctx, namespace, status.StatusProvisioning)
+	if err != nil {
+		return nil, err
+	}
+	job.creates = !existing
+	// status calls report PROVISIONING from here on, even before the cluster shows the new resources
+	p.statusChecker.BeginProvisioning(namespace)
+	p.statusChecker.BeginProgress(namespace, skippedProgress(definition))
+	// jobs of a batch join the queue once the batch has room for them
+	var ticket *queueTicket
+	if gate == nil {
+		if ticket, err = p.queue.join(job); err != nil {
+			jobs.discard(job.Id)
+			p.statusChecker.EndOperation(namespace)
+			return nil, err
+		}
+	}
+
+	apply := impersonated(withRetries(applyResource))
+	if rec != nil {
+		apply = rec.action("apply", apply)
+	}
+	revisions := &revisionRecorder{}
+	deployments := &deploymentCollector{}
+	apply = deployments.action(revisions.action(apply))
+
+	participantClients := p.clients.forParticipant(definition)
+	steps := []jobStep{
+		{phaseApply, p.tracked(namespace, status.StepManifestsApplied, func(ctx context.Context) error {
+			fmt.Println("Creating resources of", namespace)
+			resources, err := plan.apply(p.kubeClient, ctx, apply, p.clients)
+			if err != nil {
+				return err
+			}
+			job.setResources(resources)
+			if _, err := saveRevision(p.kubeClient, ctx, namespace, "create", revisions.manifests(), resources); err != nil {
+				fmt.Printf("saving revision of %s failed: %v\n", namespace, err)
+			}
+			if err := deployments.record(p.kubeClient, ctx, namespace); err != nil {
+				fmt.Printf("recording the deployments of %s failed: %v\n", namespace, err)
+			}
+			p.statusChecker.Reset(namespace)
+			writeReadinessMarker(p.kubeClient, ctx, definition, markerProvisioning, "")
+			p.announce(eventParticipantCreated, namespace, map[string]string{"did": definition.Did, "dataspace": dataspaceOf(definition)})
+			if err := refreshFederatedCatalogs(p.kubeClient, ctx); err != nil {
+				fmt.Printf("refreshing federated catalogs after creating %s failed: %v\n", namespace, err)
+			}
+			return nil
+		})},
+	}
+	steps = append(steps, p.readinessSteps(definition, deployments.awaited)...)
+	if definition.Seed.enabled() {
+		steps = append(steps, jobStep{phaseSeeding, func(ctx context.Context) error {
+			clients := participantClients.withRecording(rec).withTrace(ctx)
+			err := waitForApis(ctx, definition, clients, plan.creds, p.statusChecker)
+			if err == nil {
+				err = onDeploymentReady(ctx, p.kubeClient, definition, p.statusChecker, clients, plan.creds, p.dataspaces.lookup(ctx, dataspaceOf(definition)))
+			}
+			if err != nil {
+				p.announce(eventParticipantSeedFailed, namespace, map[string]string{"error": err.Error()})
+			}
+			return err
+		}})
+	}
+	if hooks := p.dataspaces.lookup(ctx, dataspaceOf(definition)).Hooks; len(hooks) > 0 {
+		steps = append(steps, jobStep{phaseHooks, func(ctx context.Context) error {
+			return runHooks(ctx, p.kubeClient, p.podLogs, definition, hooks)
+		}})
+	}
+
+	steps, endWork := p.work.track(job, definition, deployments.awaited, 0, steps)
+
+	job.queue()
+	runInBackground(func() {
+		defer p.statusChecker.EndOperation(namespace)
+		defer endWork()
+		runCtx, stop := job.bind(withRequesterOf(withSpanOf(p.ctx, ctx), ctx))
+		defer stop()
+		if gate != nil {
+			select {
+			case gate <- struct{}{}:
+			case <-runCtx.Done():
+				job.abandon(context.Cause(runCtx))
+				return
+			}
+			defer func() { <-gate }()
+			var err error
+			if ticket, err = p.queue.join(job); err != nil {
+				job.abandon(err)
+				return
+			}
+		}
+		if err := ticket.wait(runCtx); err != nil {
+			job.abandon(err)
+			return
+		}
+		defer ticket.done()
+		started := time.Now()
+		if provisioningTimeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(runCtx, provisioningTimeout)
+			defer cancel()
+		}
+		if err := p.executeOnboarding(runCtx, job, definition, steps); err != nil {
+			fmt.Printf("provisioning %s failed: %v\n", namespace, err)
+			if errors.Is(err, context.DeadlineExceeded) && p.ctx.Err() == nil {
+				p.markTimedOut(namespace, deployments.awaited(), started, err)
+			}
+			return
+		}
+		p.statusChecker.RecordProvisioning(namespace, job.durations())
+		p.announce(eventParticipantReady, namespace, map[string]string{"did": definition.Did, "jobId": job.Id})
+		if registration := p.dataspaces.lookup(p.ctx, dataspaceOf(definition)).Registration; registration != nil {
+			if err := registerWithDataspace(p.ctx, p.kubeClient, definition, *registration, participantClients.client(targetRegistration)); err != nil {
+				fmt.Printf("registering %s with dataspace %s failed: %v\n", namespace, dataspaceOf(definition), err)
+			}
+		}
+		startActivationWatch(p.ctx, p.kubeClient, definition, p.notifier, participantClients)
+	})
+	return job, nil
+}
+
+// readinessSteps wait for the deployments of the participant to become ready and for its ingress to route to it.
+func (p *provisioner) readinessSteps(definition ParticipantDefinition, awaited func() []string) []jobStep {
+	namespace := definition.ParticipantName
+	participantClients := p.clients.forParticipant(definition)
+	return []jobStep{
+		{phaseReadiness, p.tracked(namespace, status.StepDeploymentsReady, func(ctx context.Context) error {
+			fmt.Println("Waiting for deployments", awaited(), "of", namespace)
+			readinessCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			return waitForDeployments(p.kubeClient, readinessCtx, namespace, awaited())
+		})},
+		{phaseReadiness, func(ctx context.Context) error {
+			route := ingressRoute{client: p.kubeClient, httpClient: participantClients.client(targetManagement), definition: definition}
+			return waitForIngressRoute(ctx, route)
+		}},
+	}
+}
+
+// tracked reports the progress of a step of the participant's provisioning while run performs it.
+func (p *provisioner) tracked(participant string, step string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		p.statusChecker.SetProgress(participant, step, status.StepRunning, "")
+		if err := run(ctx); err != nil {
+			p.statusChecker.SetProgress(participant, step, status.StepFailed, err.Error())
+			return err
+		}
+		p.statusChecker.SetProgress(participant, step, status.StepCompleted, "")
+		return nil
+	}
+}
+
+// Time the diagnosis of a timed out provisioning gets to read the deployments and events
+const failureDiagnosisTimeout = 10 * time.Second
+
+// markTimedOut reports the participant whose provisioning ran out of time as FAILED, naming the first component
+// that isn't ready and attaching the events about its pods since the provisioning started.
+func (p *provisioner) markTimedOut(namespace string, deployments []string, started time.Time, err error) {
+	ctx, cancel := context.WithTimeout(p.ctx, failureDiagnosisTimeout)
+	defer cancel()
+	failure := status.Failure{Reason: fmt.Sprintf("provisioning timed out after %s: %v", time.Since(started).Round(time.Second), err)}
+	for _, name := range deployments {
+		deployment := &appsv1.Deployment{}
+		err := p.kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment)
+		if client.IgnoreNotFound(err) != nil || err == nil && deploymentReady(deployment) {
+			continue
+		}
+		failure.Component = name
+		failure.Reason = fmt.Sprintf("%s did not become ready within %s", name, time.Since(started).Round(time.Second))
+		break
+	}
+	if failure.Component != "" {
+		events, err := p.statusChecker.GetEvents(ctx, namespace, status.EventFilter{Since: started})
+		if err != nil {
+			fmt.Printf("reading events of %s failed: %v\n", namespace, err)
+		}
+		failure.Events = status.FailureEvents(events, failure.Component)
+	}
+	p.statusChecker.MarkFailed(namespace, failure)
+}
+
+// batchResult reports the job provisioning a participant of a batch, or why it could not be started.
+type batchResult struct {
+	Participant string `json:"participant"`
+	JobId       string `json:"jobId,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// startBatch starts a job for every valid definition, with at most concurrency jobs running at a time. Invalid
+// definitions don't prevent the others from being provisioned.
+func (p *provisioner) startBatch(ctx context.Context, definitions []ParticipantDefinition, concurrency int, plan func(ParticipantDefinition) (provisioningPlan, error)) ([]batchResult, error) {
+	if len(definitions) == 0 || len(definitions) > maxBatchSize {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("a batch must contain between 1 and %d participants", maxBatchSize))
+	}
+	gate := make(chan struct{}, concurrency)
+	seen := make(map[string]bool)
+	results := make([]batchResult, 0, len(definitions))
+	for _, definition := range definitions {
+		result := batchResult{Participant: definition.ParticipantName}
+		if seen[definition.ParticipantName] {
+			result.Error = "participant occurs more than once in the batch"
+			results = append(results, result)
+			continue
+		}
+		seen[definition.ParticipantName] = true
+
+		participantPlan, err := plan(definition)
+		if err == nil {
+			var job *provisioningJob
+			if job, err = p.start(ctx, participantPlan, nil, gate); err == nil {
+				result.JobId = job.Id
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// startRollout applies objects to an existing participant in a background job, records them as a new revision and
+// waits for the deployments to roll out, then runs the further steps. Deployments are restarted when a ConfigMap
+// changed, as pods only pick up changed configuration when restarted.
+func startRollout(c client.Client, ctx context.Context, statusChecker *status.StatusChecker, namespace string, cause string, apply func(context.Context, action) ([]appliedObject, error), then ...jobStep) (*provisioningJob, error) {
+	job, err := jobs.create(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	changes := &changeLog{}
+	revisions := &revisionRecorder{}
+	deployments := &deploymentCollector{}
+	steps := []jobStep{
+		{phaseApply, func(ctx context.Context) error {
+			fmt.Printf("Applying resources of %s (%s)\n", namespace, cause)
+			resources, err := apply(ctx, deployments.action(revisions.action(changes.action(impersonated(withRetries(applyResource))))))
+			job.setChanges(changes.list())
+			if err != nil {
+				return err
+			}
+			resources = withOperations(resources, changes.list())
+			job.setResources(resources)
+			if _, err := saveRevision(c, ctx, namespace, cause, revisions.manifests(), resources); err != nil {
+				fmt.Printf("saving revision of %s failed: %v\n", namespace, err)
+			}
+			if err := deployments.record(c, ctx, namespace); err != nil {
+				fmt.Printf("recording the deployments of %s failed: %v\n", namespace, err)
+			}
+			if changes.updated("ConfigMap") {
+				// partial stacks lack some of the deployments
+				for _, name := range participantDeploymentNames {
+					if err := client.IgnoreNotFound(restartDeployment(c, ctx, namespace, name)); err != nil {
+						return err
+					}
+				}
+			}
+			statusChecker.Invalidate(namespace)
+			return nil
+		}},
+		{phaseReadiness, func(ctx context.Context) error {
+			readinessCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			return waitForDeployments(c, readinessCtx, namespace, deployments.awaited())
+		}},
+	}
+	steps = append(steps, then...)
+	runInBackground(func() {
+		defer statusChecker.Invalidate(namespace)
+		ctx, stop := job.bind(ctx)
+		defer stop()
+		if err := job.execute(ctx, steps); err != nil {
+			fmt.Printf("%s of %s failed: %v\n", cause, namespace, err)
+			return
+		}
+		job.succeed()
+	})
+	return job, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderRedactsCredentials(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme"}
+	creds := participantCredentials{ManagementApiKey: "rotated-management-key", IdentityApiKey: defaultCredentials.IdentityApiKey}
+	plan := provisioningPlan{
+		definition: definition,
+		templates:  manifestSet{Connector: participantYaml, IdentityHub: identityhubYaml},
+		creds:      creds,
+		mutators:   append(definition.mutators(), credentialsMutator(creds, defaultCredentials.IdentityApiKey)),
+	}
+	rendered, err := plan.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered, "namespace: acme") {
+		t.Error("participant name not substituted")
+	}
+	if strings.Contains(rendered, "${PARTICIPANT_NAME}") || strings.Contains(rendered, "$PARTICIPANT_ID") {
+		t.Error("placeholders left in rendered manifests")
+	}
+	if strings.Contains(rendered, creds.ManagementApiKey) {
+		t.Error("management API key not redacted")
+	}
+}
+
+func TestStartBatchReportsInvalidParticipants(t *testing.T) {
+	p := &provisioner{}
+	invalid := func(definition ParticipantDefinition) (provisioningPlan, error) {
+		return provisioningPlan{}, errors.New("invalid tier")
+	}
+	results, err := p.startBatch(context.Background(), []ParticipantDefinition{{ParticipantName: "a"}, {ParticipantName: "a"}, {ParticipantName: "b"}}, 2, invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []batchResult{
+		{Participant: "a", Error: "invalid tier"},
+		{Participant: "a", Error: "participant occurs more than once in the batch"},
+		{Participant: "b", Error: "invalid tier"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+	if _, err := p.startBatch(context.Background(), nil, 2, invalid); err == nil {
+		t.Error("expected an empty batch to be rejected")
+	}
+}
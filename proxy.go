@@ -0,0 +1,113 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/proxy"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const proxyTimeout = 30 * time.Second
+
+// proxyTarget describes the in-cluster API of a participant component that can be reached through the proxy.
+type proxyTarget struct {
+	port   int
+	path   string
+	apiKey func(participantCredentials) string
+}
+
+// Components reachable through /api/v1/resources/:participantName/proxy/:component/*
+var proxyTargets = map[string]proxyTarget{
+	"controlplane": {port: 8081, path: "/api/management", apiKey: func(creds participantCredentials) string { return creds.ManagementApiKey }},
+	"identityhub":  {port: 7081, path: "/api/identity", apiKey: func(creds participantCredentials) string { return creds.IdentityApiKey }},
+}
+
+// Request headers forwarded to components. Others, such as the caller's credentials and cookies, are dropped.
+var proxyHeaders = []string{fiber.HeaderAccept, fiber.HeaderAcceptEncoding, fiber.HeaderAcceptLanguage, fiber.HeaderContentType,
+	fiber.HeaderContentLength, fiber.HeaderIfMatch, fiber.HeaderIfNoneMatch}
+
+// componentAddress returns the host and port the component's service is reached at.
+var componentAddress = func(component string, namespace string, port int) string {
+	return fmt.Sprintf("%s:%d", status.ServiceHost(component, namespace), port)
+}
+
+// url returns the escaped URL of the path below the target's API. Paths that are malformed or leave the API
+// through .. segments are rejected.
+func (t proxyTarget) url(component string, namespace string, rawPath string) (string, error) {
+	unescaped, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "invalid path: "+err.Error())
+	}
+	if slices.Contains(strings.Split(unescaped, "/"), "..") {
+		return "", fiber.NewError(fiber.StatusBadRequest, "invalid path: .. segments are not allowed")
+	}
+	target := url.URL{Scheme: "http", Host: componentAddress(component, namespace, t.port), Path: t.path + "/" + unescaped}
+	return target.String(), nil
+}
+
+// requireAdminKey rejects requests that don't carry the configured admin key in the x-api-key header.
+// An empty key disables the guarded routes altogether.
+func requireAdminKey(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key == "" {
+			return fiber.NewError(fiber.StatusForbidden, "admin API key not configured")
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Get("x-api-key")), []byte(key)) != 1 {
+			return fiber.ErrUnauthorized
+		}
+		return c.Next()
+	}
+}
+
+// proxyToComponent forwards the request to the in-cluster API of a participant component, replacing the
+// caller's admin key with the component's own API key. Only the headers in proxyHeaders are forwarded.
+func proxyToComponent(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespace := c.Params("participantName")
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid participant name: "+errs[0])
+		}
+		component := c.Params("component")
+		target, ok := proxyTargets[component]
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "unknown component "+component)
+		}
+		creds, err := loadCredentials(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+
+		url, err := target.url(component, namespace, c.Params("*"))
+		if err != nil {
+			return err
+		}
+		if query := c.Request().URI().QueryString(); len(query) > 0 {
+			url += "?" + string(query)
+		}
+
+		var dropped []string
+		c.Request().Header.VisitAll(func(key, _ []byte) {
+			if !slices.Contains(proxyHeaders, string(key)) {
+				dropped = append(dropped, string(key))
+			}
+		})
+		for _, key := range dropped {
+			c.Request().Header.Del(key)
+		}
+		c.Request().Header.Set("x-api-key", target.apiKey(creds))
+		fmt.Println("Proxying", c.Method(), "request to", url)
+		if err := proxy.DoTimeout(c, url, proxyTimeout); err != nil {
+			return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("%s of %s unreachable: %v", component, namespace, err))
+		}
+		return nil
+	}
+}
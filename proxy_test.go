@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProxyToComponent(t *testing.T) {
+	type forwarded struct {
+		Path    string
+		Query   string
+		Headers http.Header
+	}
+	component := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(forwarded{Path: r.URL.EscapedPath(), Query: r.URL.RawQuery, Headers: r.Header})
+	}))
+	defer component.Close()
+	previous := componentAddress
+	componentAddress = func(string, string, int) string { return strings.TrimPrefix(component.URL, "http://") }
+	t.Cleanup(func() { componentAddress = previous })
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "acme"},
+		Data:       map[string][]byte{managementApiKeyField: []byte("component-key")},
+	}).Build()
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.All("/resources/:participantName/proxy/:component/*", requireAdminKey("admin-key"), proxyToComponent(kube, context.Background()))
+
+	send := func(target string, headers map[string]string) (int, forwarded) {
+		t.Helper()
+		request := httptest.NewRequest("GET", target, nil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		resp, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var received forwarded
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&received); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, received
+	}
+	admin := map[string]string{"x-api-key": "admin-key"}
+
+	for _, key := range []string{"", "component-key"} {
+		if code, _ := send("/resources/acme/proxy/controlplane/v3/assets", map[string]string{"x-api-key": key}); code != fiber.StatusUnauthorized {
+			t.Errorf("expected requests without the admin key to be rejected, got %d", code)
+		}
+	}
+
+	code, received := send("/resources/acme/proxy/controlplane/v3/assets/request?limit=5", map[string]string{
+		"x-api-key": "admin-key", fiber.HeaderAuthorization: "Bearer caller", fiber.HeaderCookie: "session=caller",
+		fiber.HeaderAccept: "application/json",
+	})
+	if code != fiber.StatusOK {
+		t.Fatalf("expected the request to be proxied, got %d", code)
+	}
+	if received.Path != "/api/management/v3/assets/request" || received.Query != "limit=5" {
+		t.Errorf("unexpected target %s?%s", received.Path, received.Query)
+	}
+	if received.Headers.Get("x-api-key") != "component-key" {
+		t.Errorf("expected the admin key to be replaced by the component's key, got %q", received.Headers.Get("x-api-key"))
+	}
+	if received.Headers.Get(fiber.HeaderAuthorization) != "" || received.Headers.Get(fiber.HeaderCookie) != "" {
+		t.Errorf("expected the caller's credentials to be dropped, got %v", received.Headers)
+	}
+	if received.Headers.Get(fiber.HeaderAccept) != "application/json" {
+		t.Errorf("expected allowed headers to be forwarded, got %v", received.Headers)
+	}
+
+	if code, received := send("/resources/acme/proxy/controlplane/v3/assets/meter%20readings", admin); code != fiber.StatusOK ||
+		received.Path != "/api/management/v3/assets/meter%20readings" {
+		t.Errorf("expected the path to be escaped, got %d %s", code, received.Path)
+	}
+	for _, path := range []string{"/resources/acme/proxy/controlplane/v3/%2e%2e/%2e%2e/health", "/resources/acme/proxy/controlplane/v3/..%2F..%2Fhealth"} {
+		if code, _ := send(path, admin); code != fiber.StatusBadRequest {
+			t.Errorf("%s: expected the path to be rejected, got %d", path, code)
+		}
+	}
+	if code, _ := send("/resources/acme/proxy/vault/v1/secret", admin); code != fiber.StatusNotFound {
+		t.Errorf("expected unknown components to be 404, got %d", code)
+	}
+	if code, _ := send("/resources/Acme_Corp/proxy/controlplane/v3/assets", admin); code != fiber.StatusBadRequest {
+		t.Errorf("expected invalid participant names to be rejected, got %d", code)
+	}
+}
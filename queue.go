@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Provisioning jobs running at a time and waiting for a free slot by default
+const (
+	defaultMaxConcurrentProvisionings = 10
+	defaultMaxQueuedProvisionings     = 100
+)
+
+// workQueue bounds the number of provisioning jobs running at a time so bursts of requests don't overwhelm the
+// Kubernetes API and the connectors being seeded. Jobs beyond the limit wait in the order they were submitted and
+// report their position in the queue; once the queue is full further jobs are rejected.
+type workQueue struct {
+	mu        sync.Mutex
+	limit     int
+	maxQueued int
+	running   int
+	waiting   []*queueTicket
+	// onPosition is told the queue position of a participant's job, zero once it runs
+	onPosition func(participant string, position int)
+}
+
+// queueTicket is the place of a job in the queue.
+type queueTicket struct {
+	queue    *workQueue
+	job      *provisioningJob
+	admitted bool
+	admit    chan struct{}
+}
+
+// newWorkQueue returns a queue running at most limit jobs at a time and holding at most maxQueued waiting ones. A
+// limit of zero or less doesn't bound the running jobs, a maxQueued of zero or less doesn't bound the queue.
+func newWorkQueue(limit int, maxQueued int, onPosition func(string, int)) *workQueue {
+	if limit <= 0 {
+		fmt.Println("No limit of concurrent provisionings configured")
+	}
+	return &workQueue{limit: limit, maxQueued: maxQueued, onPosition: onPosition}
+}
+
+// join admits the job right away when a slot is free and otherwise puts it at the end of the queue, or fails with 503
+// when the queue is full.
+func (q *workQueue) join(job *provisioningJob) (*queueTicket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ticket := &queueTicket{queue: q, job: job, admit: make(chan struct{})}
+	if q.free() {
+		q.admit(ticket)
+		return ticket, nil
+	}
+	if q.maxQueued > 0 && len(q.waiting) >= q.maxQueued {
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, fmt.Sprintf("%d provisionings are queued already, retry later", len(q.waiting)))
+	}
+	q.waiting = append(q.waiting, ticket)
+	q.reportPositions()
+	return ticket, nil
+}
+
+// wait blocks until the job may run. A job whose context ends first leaves the queue and gets the cause of the
+// context's end.
+func (t *queueTicket) wait(ctx context.Context) error {
+	select {
+	case <-t.admit:
+		return nil
+	case <-ctx.Done():
+	}
+	q := t.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if t.admitted {
+		q.running--
+		q.dispatch()
+		return context.Cause(ctx)
+	}
+	for i, waiting := range q.waiting {
+		if waiting == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	q.reportPositions()
+	return context.Cause(ctx)
+}
+
+// done frees the slot of an admitted job and admits the next one.
+func (t *queueTicket) done() {
+	q := t.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.dispatch()
+}
+
+// free reports whether a job can be admitted. Callers hold the lock.
+func (q *workQueue) free() bool {
+	return q.limit <= 0 || q.running < q.limit
+}
+
+// admit takes a slot for the job. Callers hold the lock.
+func (q *workQueue) admit(ticket *queueTicket) {
+	q.running++
+	ticket.admitted = true
+	ticket.job.setQueuePosition(0)
+	if q.onPosition != nil {
+		q.onPosition(ticket.job.Participant, 0)
+	}
+	close(ticket.admit)
+}
+
+// dispatch admits waiting jobs in queue order while slots are free. Callers hold the lock.
+func (q *workQueue) dispatch() {
+	if len(q.waiting) == 0 || !q.free() {
+		return
+	}
+	for len(q.waiting) > 0 && q.free() {
+		q.admit(q.waiting[0])
+		q.waiting = q.waiting[1:]
+	}
+	q.reportPositions()
+}
+
+// reportPositions tells the waiting jobs their position, starting at one. Callers hold the lock.
+func (q *workQueue) reportPositions() {
+	for i, ticket := range q.waiting {
+		ticket.job.setQueuePosition(i + 1)
+		if q.onPosition != nil {
+			q.onPosition(ticket.job.Participant, i+1)
+		}
+	}
+}
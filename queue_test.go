@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkQueueAdmitsInOrder(t *testing.T) {
+	positions := map[string]int{}
+	queue := newWorkQueue(1, 2, func(participant string, position int) { positions[participant] = position })
+	first, _ := queue.join(&provisioningJob{Participant: "alice"})
+	second, _ := queue.join(&provisioningJob{Participant: "bob"})
+	third, _ := queue.join(&provisioningJob{Participant: "carol"})
+	if positions["alice"] != 0 || positions["bob"] != 1 || positions["carol"] != 2 {
+		t.Fatalf("unexpected queue positions %v", positions)
+	}
+	if second.job.queuePosition() != 1 {
+		t.Errorf("expected the job to report its queue position, got %d", second.job.queuePosition())
+	}
+	if _, err := queue.join(&provisioningJob{Participant: "dave"}); err == nil {
+		t.Error("expected a job to be rejected when the queue is full")
+	}
+
+	if err := first.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first.done()
+	if positions["bob"] != 0 || positions["carol"] != 1 {
+		t.Errorf("expected the queue to advance, got %v", positions)
+	}
+	if err := second.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := third.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the queued job to be cancelled, got %v", err)
+	}
+	if len(queue.waiting) != 0 {
+		t.Error("expected the cancelled job to leave the queue")
+	}
+}
+
+func TestWorkQueueWithoutLimit(t *testing.T) {
+	queue := newWorkQueue(0, 0, nil)
+	for i := 0; i < 50; i++ {
+		ticket, err := queue.join(&provisioningJob{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ticket.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
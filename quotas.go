@@ -0,0 +1,171 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The limits raised or lowered by admins are kept in a ConfigMap in the provisioner's namespace, so they apply to all
+// replicas and survive restarts
+const (
+	quotaConfigMap = "provisioner-quotas"
+	quotaKey       = "quotas.json"
+)
+
+// quotaLimits caps the number of participants, zero means unlimited.
+type quotaLimits struct {
+	// Global caps the participants of all tenants together
+	Global int `json:"global,omitempty"`
+	// PerTenant caps the participants of each tenant that Tenants gives no other limit
+	PerTenant int            `json:"perTenant,omitempty"`
+	Tenants   map[string]int `json:"tenants,omitempty"`
+}
+
+func (l quotaLimits) validate() error {
+	if l.Global < 0 || l.PerTenant < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	for tenant, limit := range l.Tenants {
+		if limit < 0 {
+			return fmt.Errorf("limit of tenant %s must not be negative", tenant)
+		}
+	}
+	return nil
+}
+
+// tenantLimit returns the limit of the tenant, zero for unscoped callers.
+func (l quotaLimits) tenantLimit(tenant string) int {
+	if tenant == "" {
+		return 0
+	}
+	if limit, ok := l.Tenants[tenant]; ok {
+		return limit
+	}
+	return l.PerTenant
+}
+
+// quotaUsage counts the participants, in total and per tenant.
+type quotaUsage struct {
+	Global  int            `json:"global"`
+	Tenants map[string]int `json:"tenants"`
+}
+
+// participantQuotas enforces the participant limits configured with --max-participants and
+// --max-participants-per-tenant, or set by admins.
+type participantQuotas struct {
+	client    client.Client
+	namespace string
+	defaults  quotaLimits
+}
+
+// limits returns the limits set by admins, the configured ones if they set none.
+func (q *participantQuotas) limits(ctx context.Context) (quotaLimits, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := q.client.Get(ctx, client.ObjectKey{Namespace: q.namespace, Name: quotaConfigMap}, configMap); err != nil {
+		return q.defaults, client.IgnoreNotFound(err)
+	}
+	var limits quotaLimits
+	if err := json.Unmarshal([]byte(configMap.Data[quotaKey]), &limits); err != nil {
+		return q.defaults, fmt.Errorf("quotas: %w", err)
+	}
+	return limits, nil
+}
+
+// setLimits replaces the configured limits, also after restarts, until they are set again.
+func (q *participantQuotas) setLimits(ctx context.Context, limits quotaLimits) error {
+	data, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+	return applyResource(q.client, ctx, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: quotaConfigMap, Namespace: q.namespace},
+		Data:       map[string]string{quotaKey: string(data)},
+	})
+}
+
+// usage counts the managed namespaces, per tenant by their tenant label.
+func (q *participantQuotas) usage(ctx context.Context) (quotaUsage, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := q.client.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		return quotaUsage{}, err
+	}
+	usage := quotaUsage{Global: len(namespaces.Items), Tenants: make(map[string]int)}
+	for _, namespace := range namespaces.Items {
+		if tenant := namespace.Labels[tenantLabel]; tenant != "" {
+			usage.Tenants[tenant]++
+		}
+	}
+	return usage, nil
+}
+
+// check rejects provisioning a new participant when the tenant or the cluster has used up its limit. Participants
+// that exist are provisioned again without counting. Participants whose namespace isn't created yet don't count, so
+// concurrent requests can exceed a limit by the participants they create.
+func (q *participantQuotas) check(ctx context.Context, tenant string, name string) error {
+	limits, err := q.limits(ctx)
+	if err != nil {
+		return err
+	}
+	tenantLimit := limits.tenantLimit(tenant)
+	if limits.Global == 0 && tenantLimit == 0 {
+		return nil
+	}
+	if err := q.client.Get(ctx, client.ObjectKey{Name: name}, &corev1.Namespace{}); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil {
+		return nil
+	}
+	usage, err := q.usage(ctx)
+	if err != nil {
+		return err
+	}
+	if tenantLimit > 0 && usage.Tenants[tenant] >= tenantLimit {
+		return withCode(codeQuotaExceeded, fiber.StatusForbidden, fmt.Errorf("tenant %s has reached its limit of %d participants", tenant, tenantLimit))
+	}
+	if limits.Global > 0 && usage.Global >= limits.Global {
+		return withCode(codeQuotaExceeded, fiber.StatusForbidden, fmt.Errorf("the provisioner has reached its limit of %d participants", limits.Global))
+	}
+	return nil
+}
+
+// getQuotas reports the participant limits, the configured ones they replace and how many participants count against
+// them.
+func getQuotas(quotas *participantQuotas, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limits, err := quotas.limits(ctx)
+		if err != nil {
+			return err
+		}
+		usage, err := quotas.usage(ctx)
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"limits": limits, "defaults": quotas.defaults, "usage": usage})
+	}
+}
+
+// setQuotas replaces the participant limits, e.g. to raise the limit of a tenant. Existing participants beyond a
+// lowered limit are kept.
+func setQuotas(quotas *participantQuotas, ctx context.Context, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var limits quotaLimits
+		if err := parser.parse(c, &limits); err != nil {
+			return err
+		}
+		if err := limits.validate(); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if err := quotas.setLimits(ctx, limits); err != nil {
+			return err
+		}
+		return getQuotas(quotas, ctx)(c)
+	}
+}
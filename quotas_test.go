@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// quotaClient serves the namespaces of namespaceClient and the ConfigMaps of configMapStore.
+type quotaClient struct {
+	*namespaceClient
+	configMaps *configMapStore
+}
+
+func (c quotaClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		return c.configMaps.Get(ctx, key, obj, opts...)
+	}
+	return c.namespaceClient.Get(ctx, key, obj, opts...)
+}
+
+func (c quotaClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.configMaps.Patch(ctx, obj, patch, opts...)
+}
+
+func TestParticipantQuotas(t *testing.T) {
+	kube := quotaClient{
+		namespaceClient: newNamespaceClient(tenantNamespace("acme-1", "acme"), tenantNamespace("acme-2", "acme"), tenantNamespace("globex-1", "globex")),
+		configMaps:      &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)},
+	}
+	quotas := &participantQuotas{client: kube, namespace: "mvd-provisioner", defaults: quotaLimits{Global: 4, PerTenant: 2}}
+	ctx := context.Background()
+	exceeded := func(err error) bool {
+		var coded *codedError
+		return errors.As(err, &coded) && coded.code == codeQuotaExceeded && coded.status == fiber.StatusForbidden
+	}
+
+	if err := quotas.check(ctx, "acme", "acme-3"); !exceeded(err) {
+		t.Errorf("expected acme to have reached its limit, got %v", err)
+	}
+	if err := quotas.check(ctx, "acme", "acme-1"); err != nil {
+		t.Errorf("existing participants don't count against the limit: %v", err)
+	}
+	if err := quotas.check(ctx, "globex", "globex-2"); err != nil {
+		t.Errorf("globex is below its limit: %v", err)
+	}
+
+	// admins raise the limit of acme, which fills the global limit
+	if err := quotas.setLimits(ctx, quotaLimits{Global: 4, PerTenant: 2, Tenants: map[string]int{"acme": 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := quotas.check(ctx, "acme", "acme-3"); err != nil {
+		t.Errorf("raised limit not applied: %v", err)
+	}
+	kube.namespaces["acme-3"] = tenantNamespace("acme-3", "acme")
+	if err := quotas.check(ctx, "", "other"); !exceeded(err) {
+		t.Errorf("expected the global limit to be reached, got %v", err)
+	}
+
+	usage, err := quotas.usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Global != 4 || usage.Tenants["acme"] != 3 || usage.Tenants["globex"] != 1 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if err := (quotaLimits{Tenants: map[string]int{"acme": -1}}).validate(); err == nil {
+		t.Error("negative limit was accepted")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// Requests a client may send to the API per minute by default
+	defaultRateLimit = 120
+	// Largest request body the API accepts by default, well above a full batch of participant definitions
+	defaultBodyLimit = 1024 * 1024
+	// Length of the windows requests are counted in
+	rateLimitWindow = time.Minute
+)
+
+// rateLimiter counts the requests of each client in fixed windows.
+type rateLimiter struct {
+	limit   int
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimit limits the requests each client can send to the API per minute, answering 429 with a Retry-After header
+// once the limit is reached. A limit of zero disables rate limiting.
+func rateLimit(perMinute int) fiber.Handler {
+	if perMinute <= 0 {
+		fmt.Println("No rate limit configured, the API is not rate limited")
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	limiter := &rateLimiter{limit: perMinute, windows: make(map[string]*rateWindow)}
+	return func(c *fiber.Ctx) error {
+		if retryAfter, ok := limiter.allow(clientKey(c), time.Now()); !ok {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return fiber.NewError(fiber.StatusTooManyRequests, fmt.Sprintf("rate limit of %d requests per minute exceeded", perMinute))
+		}
+		return c.Next()
+	}
+}
+
+// allow counts a request of the client and reports whether it is within the limit, or else how long until the
+// client's window resets.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	window, ok := l.windows[key]
+	if !ok || now.Sub(window.start) >= rateLimitWindow {
+		// drop expired windows of other clients while at it
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= rateLimitWindow {
+				delete(l.windows, k)
+			}
+		}
+		window = &rateWindow{start: now}
+		l.windows[key] = window
+	}
+	if window.count >= l.limit {
+		return window.start.Add(rateLimitWindow).Sub(now), false
+	}
+	window.count++
+	return 0, true
+}
+
+// clientKey identifies the client a request is counted against: the authenticated caller, or the remote address of
+// unauthenticated requests.
+func clientKey(c *fiber.Ctx) string {
+	if caller, ok := c.Locals(principalKey).(*principal); ok {
+		return "principal:" + caller.Tenant + "/" + caller.Subject
+	}
+	return "ip:" + c.IP()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimit(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if subject := c.Get("x-subject"); subject != "" {
+			c.Locals(principalKey, &principal{Subject: subject})
+		}
+		return c.Next()
+	})
+	app.Use(rateLimit(2))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	get := func(subject string) (int, string) {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("x-subject", subject)
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.StatusCode, response.Header.Get(fiber.HeaderRetryAfter)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code, _ := get("ci"); code != fiber.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, code)
+		}
+	}
+	code, retryAfter := get("ci")
+	if code != fiber.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", code)
+	}
+	if retryAfter == "" {
+		t.Error("429 without Retry-After header")
+	}
+	if code, _ := get("other"); code != fiber.StatusOK {
+		t.Errorf("other client: status = %d, want 200", code)
+	}
+}
+
+func TestRateLimitPerStaticKey(t *testing.T) {
+	app := fiber.New()
+	app.Use(newApiAuth("first-key,second-key", "", "", "", "", "").middleware())
+	app.Use(rateLimit(1))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(principalKey).(*principal).Subject)
+	})
+
+	get := func(key string) (int, string) {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	code, first := get("first-key")
+	if code != fiber.StatusOK || !strings.HasPrefix(first, "api-key:") || strings.Contains(first, "first-key") {
+		t.Fatalf("expected the key's fingerprint as subject, got %d %q", code, first)
+	}
+	code, second := get("second-key")
+	if code != fiber.StatusOK || second == first {
+		t.Errorf("expected another key to be another client, got %d %q", code, second)
+	}
+	if code, _ := get("first-key"); code != fiber.StatusTooManyRequests {
+		t.Errorf("expected the key's own requests to be limited, got %d", code)
+	}
+}
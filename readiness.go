@@ -0,0 +1,68 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Deployments jobs wait for to become ready, set with --readiness-deployments. By default the jobs wait for the
+// deployments they applied, so components added to the templates are waited for as well.
+var readinessDeployments []string
+
+// deploymentCollector collects the names of the deployments applied through its action.
+type deploymentCollector struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (d *deploymentCollector) action(next action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		if err := next(c, ctx, object); err != nil {
+			return err
+		}
+		if object.GetObjectKind().GroupVersionKind().Kind == "Deployment" {
+			d.mu.Lock()
+			if !slices.Contains(d.names, object.GetName()) {
+				d.names = append(d.names, object.GetName())
+			}
+			d.mu.Unlock()
+		}
+		return nil
+	}
+}
+
+// awaited returns the deployments to wait for, the applied ones among the configured ones or else all applied ones.
+// Partial stacks lack some of the configured deployments.
+func (d *deploymentCollector) awaited() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(readinessDeployments) > 0 {
+		return slices.DeleteFunc(slices.Clone(readinessDeployments), func(name string) bool {
+			return !slices.Contains(d.names, name)
+		})
+	}
+	return slices.Clone(d.names)
+}
+
+// record lists the applied deployments on the participant's namespace, the status only requires those of
+// the critical deployments the participant was provisioned with.
+func (d *deploymentCollector) record(c client.Client, ctx context.Context, participant string) error {
+	d.mu.Lock()
+	names := slices.Sorted(slices.Values(d.names))
+	d.mu.Unlock()
+	if len(names) == 0 {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, status.DeploymentsAnnotation, strings.Join(names, ","))
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: participant}}
+	return c.Patch(ctx, namespace, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMap in the participant namespace reporting the onboarding progress to in-cluster consumers, which can wait
+// for it with e.g. kubectl wait --for=jsonpath='{.data.status}'=READY configmap/participant-status
+const readinessConfigMapName = "participant-status"
+
+// States written to the readiness marker
+const (
+	markerProvisioning = "PROVISIONING"
+	markerReady        = "READY"
+	markerFailed       = "FAILED"
+)
+
+// readReadinessMarker returns the onboarding state recorded in the participant's namespace and when it was recorded,
+// an empty state if there is no marker.
+func readReadinessMarker(c client.Client, ctx context.Context, namespace string) (string, time.Time, error) {
+	marker := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: readinessConfigMapName}, marker); err != nil {
+		return "", time.Time{}, client.IgnoreNotFound(err)
+	}
+	updatedAt, _ := time.Parse(time.RFC3339, marker.Data["updatedAt"])
+	return marker.Data["status"], updatedAt, nil
+}
+
+// writeReadinessMarker records the onboarding state of the participant in its namespace.
+func writeReadinessMarker(c client.Client, ctx context.Context, definition ParticipantDefinition, state string, message string) {
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      readinessConfigMapName,
+			Namespace: definition.ParticipantName,
+		},
+		Data: map[string]string{
+			"status":          state,
+			"message":         message,
+			"participant":     definition.ParticipantName,
+			"did":             definition.Did,
+			"templateVersion": templateVersion,
+			"updatedAt":       time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	if err := applyResource(c, ctx, configMap); err != nil {
+		fmt.Printf("writing readiness marker for %s failed: %v\n", definition.ParticipantName, err)
+	}
+}
+
+// executeOnboarding runs the steps of the job onboarding the participant and records in its readiness marker whether
+// the participant became ready.
+func (p *provisioner) executeOnboarding(ctx context.Context, job *provisioningJob, definition ParticipantDefinition, steps []jobStep) error {
+	if err := job.execute(ctx, steps); err != nil {
+		writeReadinessMarker(p.kubeClient, p.ctx, definition, markerFailed, err.Error())
+		return err
+	}
+	job.succeed()
+	writeReadinessMarker(p.kubeClient, p.ctx, definition, markerReady, "")
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReadinessMarker(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := applyingClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "acme"},
+			Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "globex"}},
+	).Build()}
+	ctx := context.Background()
+	p := &provisioner{kubeClient: kube, ctx: ctx}
+
+	marker := func(namespace string) map[string]string {
+		t.Helper()
+		configMap := &corev1.ConfigMap{}
+		if err := kube.Get(ctx, client.ObjectKey{Namespace: namespace, Name: readinessConfigMapName}, configMap); err != nil {
+			t.Fatal(err)
+		}
+		return configMap.Data
+	}
+	onboard := func(namespace string) error {
+		t.Helper()
+		definition := ParticipantDefinition{ParticipantName: namespace, Did: "did:web:" + namespace}
+		writeReadinessMarker(kube, ctx, definition, markerProvisioning, "")
+		if state := marker(namespace)["status"]; state != markerProvisioning {
+			t.Errorf("%s: expected the marker to report the provisioning, got %s", namespace, state)
+		}
+		job, err := jobs.create(ctx, namespace, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.executeOnboarding(ctx, job, definition, []jobStep{{phaseReadiness, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			return waitForDeployments(kube, ctx, namespace, []string{"controlplane"})
+		}}})
+	}
+
+	if err := onboard("acme"); err != nil {
+		t.Fatal(err)
+	}
+	ready := marker("acme")
+	if ready["status"] != markerReady || ready["message"] != "" || ready["participant"] != "acme" || ready["did"] != "did:web:acme" ||
+		ready["templateVersion"] != templateVersion {
+		t.Errorf("unexpected marker of the ready participant %v", ready)
+	}
+	if state, updatedAt, err := readReadinessMarker(kube, ctx, "acme"); err != nil || state != markerReady || time.Since(updatedAt) > time.Minute {
+		t.Errorf("expected the state to be read back, got %s at %s: %v", state, updatedAt, err)
+	}
+
+	if err := onboard("globex"); err == nil {
+		t.Fatal("expected the readiness wait to time out")
+	}
+	failed := marker("globex")
+	if failed["status"] != markerFailed || !strings.Contains(failed["message"], "deadline exceeded") {
+		t.Errorf("expected the marker to report the failure, got %v", failed)
+	}
+
+	if state, _, err := readReadinessMarker(kube, ctx, "initech"); err != nil || state != "" {
+		t.Errorf("expected no state without a marker, got %q: %v", state, err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDeploymentCollector(t *testing.T) {
+	deployments := &deploymentCollector{}
+	apply := deployments.action(func(client.Client, context.Context, client.Object) error { return nil })
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: controlplane
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: catalog-server
+`
+	name := "alice"
+	if _, err := applyYaml(&name, new(string), nil, context.Background(), manifests, apply); err != nil {
+		t.Fatal(err)
+	}
+	if got := deployments.awaited(); !slices.Equal(got, []string{"controlplane", "catalog-server"}) {
+		t.Errorf("expected the applied deployments to be awaited, got %v", got)
+	}
+
+	readinessDeployments = []string{"controlplane", "dataplane"}
+	defer func() { readinessDeployments = nil }()
+	if got := deployments.awaited(); !slices.Equal(got, []string{"controlplane"}) {
+		t.Errorf("expected the applied ones of the configured deployments to be awaited, got %v", got)
+	}
+
+	kube := protectionClient(participantNamespace("alice", ""))
+	if err := deployments.record(kube, context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	namespace := &corev1.Namespace{}
+	if err := kube.Get(context.Background(), client.ObjectKey{Name: "alice"}, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if got := namespace.Annotations[status.DeploymentsAnnotation]; got != "catalog-server,controlplane" {
+		t.Errorf("expected the applied deployments on the namespace, got %q", got)
+	}
+}
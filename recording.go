@@ -0,0 +1,325 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Bodies larger than this are truncated in recordings
+const maxRecordedBody = 64 * 1024
+
+const redacted = "<redacted>"
+
+// Header, field and config keys containing one of these fragments are redacted
+var sensitiveKeyFragments = []string{"key", "secret", "password", "token", "authorization", "credential"}
+
+// Settings that are sensitive although their names don't say so
+var sensitiveSettings = map[string]bool{
+	// carries the callback token
+	"EDC_CALLBACK_PROVISIONER_URI": true,
+}
+
+type kubernetesOperation struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Operation string         `json:"operation"`
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	Object    map[string]any `json:"object,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type httpExchange struct {
+	Timestamp       time.Time           `json:"timestamp"`
+	Duration        string              `json:"duration"`
+	Method          string              `json:"method"`
+	Url             string              `json:"url"`
+	RequestHeaders  map[string][]string `json:"requestHeaders,omitempty"`
+	RequestBody     any                 `json:"requestBody,omitempty"`
+	StatusCode      int                 `json:"statusCode,omitempty"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	ResponseBody    any                 `json:"responseBody,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// recording collects the Kubernetes operations and seeding HTTP exchanges of one provisioning run, with credentials
+// redacted, so it can be attached to bug reports.
+type recording struct {
+	mu          sync.Mutex
+	Participant string                `json:"participant"`
+	StartedAt   time.Time             `json:"startedAt"`
+	Kubernetes  []kubernetesOperation `json:"kubernetes"`
+	Http        []httpExchange        `json:"http"`
+}
+
+type recordingStore struct {
+	mu         sync.RWMutex
+	recordings map[string]*recording
+}
+
+// recordings holds the latest recording of every participant
+var recordings = &recordingStore{recordings: make(map[string]*recording)}
+
+// start begins a new recording for the participant, replacing any previous one.
+func (s *recordingStore) start(participant string) *recording {
+	rec := &recording{Participant: participant, StartedAt: time.Now()}
+	s.mu.Lock()
+	s.recordings[participant] = rec
+	s.mu.Unlock()
+	return rec
+}
+
+func (s *recordingStore) get(participant string) *recording {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recordings[participant]
+}
+
+// action wraps a Kubernetes action so that every call is recorded.
+func (r *recording) action(name string, next action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		operation := kubernetesOperation{
+			Timestamp: time.Now(),
+			Operation: name,
+			Kind:      object.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: object.GetNamespace(),
+			Name:      object.GetName(),
+			Object:    redactObject(object),
+		}
+		err := next(c, ctx, object)
+		if err != nil {
+			operation.Error = err.Error()
+		}
+		r.mu.Lock()
+		r.Kubernetes = append(r.Kubernetes, operation)
+		r.mu.Unlock()
+		return err
+	}
+}
+
+// recordingTransport records every exchange made through it.
+type recordingTransport struct {
+	recording *recording
+	next      http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	exchange := httpExchange{
+		Timestamp:      time.Now(),
+		Method:         request.Method,
+		Url:            request.URL.String(),
+		RequestHeaders: redactHeaders(request.Header),
+	}
+	if request.Body != nil {
+		body, err := io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = redactBody(request.URL.Path, body)
+	}
+
+	response, err := t.next.RoundTrip(request)
+	exchange.Duration = time.Since(exchange.Timestamp).String()
+	if err != nil {
+		exchange.Error = err.Error()
+	} else {
+		body, readErr := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			exchange.Error = readErr.Error()
+		}
+		exchange.StatusCode = response.StatusCode
+		exchange.ResponseHeaders = redactHeaders(response.Header)
+		exchange.ResponseBody = redactBody(request.URL.Path, body)
+	}
+
+	t.recording.mu.Lock()
+	t.recording.Http = append(t.recording.Http, exchange)
+	t.recording.mu.Unlock()
+	return response, err
+}
+
+// bundle packs the recording into a zip archive.
+func (r *recording) bundle() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := map[string]any{
+		"recording.json": map[string]any{
+			"participant":     r.Participant,
+			"startedAt":       r.StartedAt,
+			"templateVersion": templateVersion,
+		},
+		"kubernetes.json": r.Kubernetes,
+		"http.json":       r.Http,
+	}
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	for name, content := range files {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downloadRecording serves the latest recording of a participant as zip bundle.
+func downloadRecording(c *fiber.Ctx) error {
+	name := c.Params("participantName")
+	rec := recordings.get(name)
+	if rec == nil {
+		return fiber.NewError(fiber.StatusNotFound, "no recording for participant "+name)
+	}
+	bundle, err := rec.bundle()
+	if err != nil {
+		return err
+	}
+	c.Attachment(name + "-recording.zip")
+	c.Set(fiber.HeaderContentType, "application/zip")
+	return c.Send(bundle)
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range sensitiveKeyFragments {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(headers http.Header) map[string][]string {
+	result := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		if isSensitiveKey(key) {
+			result[key] = []string{redacted}
+			continue
+		}
+		result[key] = values
+	}
+	return result
+}
+
+// redactBody returns JSON bodies with sensitive fields redacted and other bodies as text, both truncated to
+// maxRecordedBody. Some endpoints carry secrets in fields with innocuous names and are redacted by path: the value of
+// management API secrets and the keys returned when regenerating tokens.
+func redactBody(path string, body []byte) any {
+	if len(body) == 0 {
+		return nil
+	}
+	if strings.HasSuffix(path, "/token") {
+		return redacted
+	}
+	var parsed any
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		parsed = redactValue(parsed)
+		if secret, ok := parsed.(map[string]any); ok && strings.HasSuffix(path, "/secrets") {
+			if _, ok := secret["value"]; ok {
+				secret["value"] = redacted
+			}
+		}
+		redactedBody, err := json.Marshal(parsed)
+		if err != nil || len(redactedBody) <= maxRecordedBody {
+			return parsed
+		}
+		body = redactedBody
+	}
+	if len(body) > maxRecordedBody {
+		return string(body[:maxRecordedBody]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if _, isString := nested.(string); isString && isSensitiveKey(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(nested)
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = redactValue(nested)
+		}
+	}
+	return value
+}
+
+// redactObject converts a Kubernetes object to a map, dropping Secret contents, sensitive ConfigMap values and
+// sensitive container environment values.
+func redactObject(object client.Object) map[string]any {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return nil
+	}
+	var result map[string]any
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil
+	}
+	switch object.GetObjectKind().GroupVersionKind().Kind {
+	case "Secret":
+		for _, field := range []string{"data", "stringData"} {
+			if data, ok := result[field].(map[string]any); ok {
+				for key := range data {
+					data[key] = redacted
+				}
+			}
+		}
+	case "ConfigMap":
+		if data, ok := result["data"].(map[string]any); ok {
+			for key := range data {
+				if isSensitiveKey(key) || sensitiveSettings[key] {
+					data[key] = redacted
+				}
+			}
+		}
+	default:
+		redactEnv(result)
+	}
+	return result
+}
+
+// redactEnv redacts the values of environment variables with sensitive names anywhere in the object.
+func redactEnv(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if name, ok := v["name"].(string); ok && isSensitiveKey(name) {
+			if _, ok := v["value"].(string); ok {
+				v["value"] = redacted
+			}
+		}
+		for _, nested := range v {
+			redactEnv(nested)
+		}
+	case []any:
+		for _, nested := range v {
+			redactEnv(nested)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	testApiKey       = "test-management-key"
+	testClientSecret = "test-sts-client-secret-value"
+	testIdentityKey  = "dGVzdA==.test-identity-key"
+)
+
+func TestRecordingRedactsSeedingSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/participants"):
+			_, _ = fmt.Fprintf(w, `{"clientId":"client","clientSecret":%q,"apiKey":%q}`, testClientSecret, testIdentityKey)
+		case strings.HasSuffix(r.URL.Path, "/token"):
+			_, _ = fmt.Fprint(w, testIdentityKey)
+		default:
+			_, _ = fmt.Fprint(w, `{"@id":"created"}`)
+		}
+	}))
+	defer server.Close()
+
+	rec := recordings.start("recording-test")
+	clients, err := newSeedingClients(HttpConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients = clients.withRecording(rec)
+
+	identityApi := api.ApiClient{BaseUrl: server.URL, ApiKey: testIdentityKey, HttpClient: clients.client(targetIdentity)}
+	if _, err := identityApi.CreateParticipant(context.Background(), `{"participantId":"did:web:test"}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := identityApi.RegenerateToken(context.Background(), superUserContextId); err != nil {
+		t.Fatal(err)
+	}
+	mgmtApi := api.ApiClient{BaseUrl: server.URL, ApiKey: testApiKey, HttpClient: clients.client(targetManagement)}
+	if _, err := mgmtApi.CreateSecret(context.Background(), fmt.Sprintf(`{"@id":"client-sts-client-secret","value":%q}`, testClientSecret)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.Http) != 3 {
+		t.Fatalf("recorded %d exchanges, want 3", len(rec.Http))
+	}
+	recorded, err := json.Marshal(rec.Http)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{testApiKey, testClientSecret, testIdentityKey} {
+		if strings.Contains(string(recorded), secret) {
+			t.Errorf("recorded exchanges contain secret %q: %s", secret, recorded)
+		}
+	}
+}
+
+func TestRedactBodyTruncatesJson(t *testing.T) {
+	body, err := json.Marshal(map[string]string{"description": strings.Repeat("x", 2*maxRecordedBody)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := redactBody("/assets", body).(string)
+	if !ok {
+		t.Fatalf("large JSON body was not truncated")
+	}
+	if len(got) > maxRecordedBody+len("...(truncated)") {
+		t.Errorf("truncated body has %d bytes", len(got))
+	}
+}
+
+func TestRedactObject(t *testing.T) {
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsSecretName, Namespace: "test"},
+		Data:       map[string][]byte{managementApiKeyField: []byte(testApiKey)},
+	}
+	configMap := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "controlplane-config", "namespace": "test"},
+		"data": map[string]any{
+			managementApiKeySetting:        testApiKey,
+			"EDC_CALLBACK_PROVISIONER_URI": "http://provisioner/api/v1/callbacks/test/" + testClientSecret,
+			"EDC_HOSTNAME":                 "controlplane",
+		},
+	}}
+
+	for _, object := range []client.Object{secret, configMap} {
+		recorded, err := json.Marshal(redactObject(object))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range []string{testApiKey, testClientSecret} {
+			if strings.Contains(string(recorded), value) {
+				t.Errorf("redacted object contains %q: %s", value, recorded)
+			}
+		}
+	}
+	if data := redactObject(configMap)["data"].(map[string]any); data["EDC_HOSTNAME"] != "controlplane" {
+		t.Errorf("non-sensitive setting was redacted: %v", data["EDC_HOSTNAME"])
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// RegistryConfig points the participants' pods at a private registry, for clusters that can't pull the public images.
+type RegistryConfig struct {
+	// Mirror is the registry, with an optional path prefix, e.g. registry.local:5000/mirror, that replaces the registry
+	// of every image. Images of Docker Hub are expected under library/ for official images. Images already pulled from
+	// the mirror are left alone.
+	Mirror string `json:"mirror,omitempty"`
+	// PullSecret is the name of the image pull secret added to every pod
+	PullSecret string `json:"pullSecret,omitempty"`
+	// Username and Password create the pull secret in the participant namespace, it has to exist there otherwise
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// defaultRegistry is set with --image-registry, --image-pull-secret, --image-registry-username and
+// --image-registry-password.
+var defaultRegistry RegistryConfig
+
+// registryFor returns the registry settings of the dataspace, falling back to the defaults for settings it doesn't
+// set.
+func registryFor(dataspace DataspaceConfig) RegistryConfig {
+	registry := defaultRegistry
+	if dataspace.Registry == nil {
+		return registry
+	}
+	if dataspace.Registry.Mirror != "" {
+		registry.Mirror = dataspace.Registry.Mirror
+	}
+	if dataspace.Registry.PullSecret != "" {
+		registry.PullSecret = dataspace.Registry.PullSecret
+	}
+	if dataspace.Registry.Username != "" {
+		registry.Username, registry.Password = dataspace.Registry.Username, dataspace.Registry.Password
+	}
+	return registry
+}
+
+func (r RegistryConfig) enabled() bool {
+	return r.Mirror != "" || r.PullSecret != ""
+}
+
+// createsPullSecret tells whether the pull secret is created from the credentials rather than expected to exist.
+func (r RegistryConfig) createsPullSecret() bool {
+	return r.Username != ""
+}
+
+func (r RegistryConfig) validate() error {
+	if strings.HasPrefix(r.Mirror, "http://") || strings.HasPrefix(r.Mirror, "https://") || strings.HasSuffix(r.Mirror, "/") {
+		return fmt.Errorf("registry: mirror %q must be a registry host with an optional path, e.g. registry.local:5000/mirror", r.Mirror)
+	}
+	if r.PullSecret != "" {
+		if errs := validation.IsDNS1123Subdomain(r.PullSecret); len(errs) > 0 {
+			return fmt.Errorf("registry: pull secret %s: %s", r.PullSecret, strings.Join(errs, ", "))
+		}
+	}
+	if r.createsPullSecret() {
+		if r.PullSecret == "" {
+			return fmt.Errorf("registry: credentials require the name of the pull secret")
+		}
+		if r.Mirror == "" {
+			return fmt.Errorf("registry: credentials require a mirror they are valid for")
+		}
+	}
+	return nil
+}
+
+// server returns the host of the mirror, the credentials of the pull secret are valid for.
+func (r RegistryConfig) server() string {
+	host, _, _ := strings.Cut(r.Mirror, "/")
+	return host
+}
+
+// mirrored returns the image pulled from the mirror: the registry of the image, Docker Hub for images without one, is
+// replaced by the mirror, e.g. ghcr.io/org/app:1 becomes registry.local/mirror/org/app:1 and postgres:16
+// registry.local/mirror/library/postgres:16.
+func (r RegistryConfig) mirrored(image string) string {
+	if r.Mirror == "" || image == "" || strings.HasPrefix(image, r.Mirror+"/") {
+		return image
+	}
+	repository := image
+	first, rest, found := strings.Cut(image, "/")
+	switch {
+	case !found:
+		repository = "library/" + image
+	case strings.ContainsAny(first, ".:") || first == "localhost":
+		repository = rest
+	}
+	return r.Mirror + "/" + repository
+}
+
+// pullSecretName is the name the pods refer to the pull secret by. A pull secret created per participant is renamed
+// in the shared namespace, one that exists there is not.
+func (r RegistryConfig) pullSecretName(participant string) string {
+	if r.createsPullSecret() && status.SharedNamespace != "" {
+		return status.ObjectName(r.PullSecret, participant)
+	}
+	return r.PullSecret
+}
+
+// mutator pulls the images of all containers of the participant's workloads from the mirror and adds the pull secret
+// to their pods.
+func (r RegistryConfig) mutator(participant string) objectMutator {
+	pullSecret := r.pullSecretName(participant)
+	return func(obj *unstructured.Unstructured) error {
+		path := []string{"spec", "template", "spec"}
+		switch obj.GetKind() {
+		case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		case "CronJob":
+			path = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+		default:
+			return nil
+		}
+		podSpec, ok, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+		spec, isMap := podSpec.(map[string]any)
+		if err != nil || !ok || !isMap {
+			return err
+		}
+		for _, containers := range []string{"containers", "initContainers"} {
+			for _, container := range nestedItems(spec, containers) {
+				if image, ok := container["image"].(string); ok {
+					container["image"] = r.mirrored(image)
+				}
+			}
+		}
+		if pullSecret == "" {
+			return nil
+		}
+		secrets := nestedItems(spec, "imagePullSecrets")
+		for _, secret := range secrets {
+			if secret["name"] == pullSecret {
+				return nil
+			}
+		}
+		refs := make([]any, 0, len(secrets)+1)
+		for _, secret := range secrets {
+			refs = append(refs, secret)
+		}
+		spec["imagePullSecrets"] = append(refs, map[string]any{"name": pullSecret})
+		return nil
+	}
+}
+
+// manifests renders the pull secret of the participant from the credentials, nothing when it is expected to exist.
+func (r RegistryConfig) manifests(participant string) (string, error) {
+	if !r.createsPullSecret() {
+		return "", nil
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + r.Password))
+	config, err := json.Marshal(map[string]any{"auths": map[string]any{
+		r.server(): map[string]string{"username": r.Username, "password": r.Password, "auth": auth},
+	}})
+	if err != nil {
+		return "", err
+	}
+	doc, err := yaml.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "kubernetes.io/dockerconfigjson",
+		"metadata":   map[string]any{"name": r.PullSecret, "namespace": participant},
+		"stringData": map[string]any{".dockerconfigjson": string(config)},
+	})
+	return string(doc), err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMirroredImages(t *testing.T) {
+	registry := RegistryConfig{Mirror: "registry.local:5000/mirror"}
+	for image, expected := range map[string]string{
+		"ghcr.io/paullatzelsperger/minimumviabledataspace/controlplane:latest": "registry.local:5000/mirror/paullatzelsperger/minimumviabledataspace/controlplane:latest",
+		"hashicorp/vault:1.15.6":                         "registry.local:5000/mirror/hashicorp/vault:1.15.6",
+		"postgres:16.3-alpine3.20":                       "registry.local:5000/mirror/library/postgres:16.3-alpine3.20",
+		"localhost/app:1":                                "registry.local:5000/mirror/app:1",
+		"registry.local:5000/mirror/controlplane:custom": "registry.local:5000/mirror/controlplane:custom",
+	} {
+		if mirrored := registry.mirrored(image); mirrored != expected {
+			t.Errorf("expected %s to be pulled as %s, got %s", image, expected, mirrored)
+		}
+	}
+}
+
+func TestRegistryMutator(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]any{
+		"kind": "Deployment",
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"initContainers":   []any{map[string]any{"name": "init", "image": "busybox"}},
+			"containers":       []any{map[string]any{"name": "postgres", "image": "postgres:16"}},
+			"imagePullSecrets": []any{map[string]any{"name": "existing"}},
+		}}},
+	}}
+	registry := RegistryConfig{Mirror: "registry.local", PullSecret: "registry-credentials"}
+	mutate := registry.mutator("alice")
+	for i := 0; i < 2; i++ {
+		if err := mutate(deployment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if image := containers[0].(map[string]any)["image"]; image != "registry.local/library/postgres:16" {
+		t.Errorf("unexpected image %v", image)
+	}
+	initContainers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "initContainers")
+	if image := initContainers[0].(map[string]any)["image"]; image != "registry.local/library/busybox" {
+		t.Errorf("unexpected init image %v", image)
+	}
+	secrets, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "imagePullSecrets")
+	if len(secrets) != 2 || secrets[1].(map[string]any)["name"] != "registry-credentials" {
+		t.Errorf("unexpected image pull secrets %v", secrets)
+	}
+}
+
+func TestRegistryPullSecret(t *testing.T) {
+	existing := RegistryConfig{Mirror: "registry.local", PullSecret: "registry-credentials"}
+	if doc, err := existing.manifests("alice"); err != nil || doc != "" {
+		t.Errorf("expected no secret without credentials, got %q, %v", doc, err)
+	}
+
+	created := RegistryConfig{Mirror: "registry.local:5000/mirror", PullSecret: "registry-credentials", Username: "robot", Password: "secret"}
+	doc, err := created.manifests("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"kubernetes.io/dockerconfigjson", "namespace: alice", `registry.local:5000`, `"auth":"cm9ib3Q6c2VjcmV0"`} {
+		if !strings.Contains(doc, expected) {
+			t.Errorf("expected %s in %s", expected, doc)
+		}
+	}
+
+	if err := (RegistryConfig{Mirror: "registry.local", Username: "robot"}).validate(); err == nil {
+		t.Error("expected credentials without a pull secret to be rejected")
+	}
+	if err := (RegistryConfig{Mirror: "https://registry.local"}).validate(); err == nil {
+		t.Error("expected a mirror URL to be rejected")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// API keys are due for rotation this long after they were last rotated
+const apiKeyMaxAge = 90 * 24 * time.Hour
+
+type certificateExpiry struct {
+	Secret   string    `json:"secret"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// participantHealth is a row of the fleet-wide health report.
+type participantHealth struct {
+	Participant     string                    `json:"participant"`
+	Status          status.ProvisioningStatus `json:"status"`
+	TemplateVersion string                    `json:"templateVersion"`
+	// ComponentVersions maps deployments to the image tag of their main container
+	ComponentVersions map[string]string   `json:"componentVersions"`
+	Certificates      []certificateExpiry `json:"certificates"`
+	// Credentials that were never rotated are the template defaults and have neither timestamp
+	CredentialsRotatedAt string `json:"credentialsRotatedAt,omitempty"`
+	CredentialsExpireAt  string `json:"credentialsExpireAt,omitempty"`
+	// LastVerifiedAt is when the participant last passed seeding and hooks, according to its readiness marker
+	LastVerifiedAt string `json:"lastVerifiedAt,omitempty"`
+}
+
+type healthReport struct {
+	GeneratedAt     time.Time           `json:"generatedAt"`
+	TemplateVersion string              `json:"templateVersion"`
+	Participants    []participantHealth `json:"participants"`
+}
+
+// getHealthReport returns the health report of all managed participants, as CSV with ?format=csv and as JSON
+// otherwise.
+func getHealthReport(kubeClient client.Client, ctx context.Context, statusChecker *status.StatusChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		format := c.Query("format", "json")
+		if format != "json" && format != "csv" {
+			return fiber.NewError(fiber.StatusBadRequest, "format must be json or csv")
+		}
+		report, err := buildHealthReport(kubeClient, ctx, statusChecker)
+		if err != nil {
+			return err
+		}
+		c.Attachment(fmt.Sprintf("health-report-%s.%s", report.GeneratedAt.Format("2006-01-02"), format))
+		if format == "csv" {
+			body, err := report.csv()
+			if err != nil {
+				return err
+			}
+			c.Set(fiber.HeaderContentType, "text/csv")
+			return c.Send(body)
+		}
+		return c.JSON(report)
+	}
+}
+
+func buildHealthReport(c client.Client, ctx context.Context, statusChecker *status.StatusChecker) (healthReport, error) {
+	names, err := discoverParticipants(c, ctx)
+	if err != nil {
+		return healthReport{}, err
+	}
+	sort.Strings(names)
+	report := healthReport{
+		GeneratedAt:     time.Now().UTC(),
+		TemplateVersion: templateVersion,
+		Participants:    make([]participantHealth, 0, len(names)),
+	}
+	for _, name := range names {
+		health, err := participantHealthOf(c, ctx, statusChecker, name)
+		if err != nil {
+			return healthReport{}, fmt.Errorf("participant %s: %w", name, err)
+		}
+		report.Participants = append(report.Participants, health)
+	}
+	return report, nil
+}
+
+func participantHealthOf(c client.Client, ctx context.Context, statusChecker *status.StatusChecker, name string) (participantHealth, error) {
+	participantStatus, err := statusChecker.GetStatus(ctx, name, nil)
+	if err != nil {
+		return participantHealth{}, err
+	}
+	health := participantHealth{
+		Participant:       name,
+		Status:            participantStatus.Status,
+		ComponentVersions: make(map[string]string),
+		Certificates:      []certificateExpiry{},
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return participantHealth{}, err
+	}
+	health.TemplateVersion = namespace.Annotations[templateVersionAnnotation]
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(name)); err != nil {
+		return participantHealth{}, err
+	}
+	for _, deployment := range deployments.Items {
+		if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+			health.ComponentVersions[deployment.Name] = status.ImageTag(containers[0].Image)
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(name)); err != nil {
+		return participantHealth{}, err
+	}
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeTLS {
+			continue
+		}
+		notAfter, err := certificateNotAfter(secret.Data[corev1.TLSCertKey])
+		if err != nil {
+			fmt.Printf("reading certificate %s/%s failed: %v\n", name, secret.Name, err)
+			continue
+		}
+		health.Certificates = append(health.Certificates, certificateExpiry{Secret: secret.Name, NotAfter: notAfter})
+	}
+	sort.Slice(health.Certificates, func(i, j int) bool {
+		return health.Certificates[i].NotAfter.Before(health.Certificates[j].NotAfter)
+	})
+
+	creds, err := loadCredentials(c, ctx, name)
+	if err != nil {
+		return participantHealth{}, err
+	}
+	if rotatedAt, err := time.Parse(time.RFC3339, creds.RotatedAt); err == nil {
+		health.CredentialsRotatedAt = creds.RotatedAt
+		health.CredentialsExpireAt = rotatedAt.Add(apiKeyMaxAge).Format(time.RFC3339)
+	}
+
+	marker := &corev1.ConfigMap{}
+	err = c.Get(ctx, client.ObjectKey{Namespace: name, Name: readinessConfigMapName}, marker)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return participantHealth{}, err
+	}
+	if marker.Data["status"] == markerReady {
+		health.LastVerifiedAt = marker.Data["updatedAt"]
+	}
+	return health, nil
+}
+
+// certificateNotAfter returns the expiry of the leaf certificate of a PEM encoded chain.
+func certificateNotAfter(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no PEM encoded certificate")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return certificate.NotAfter, nil
+}
+
+// csv renders the report with one row per participant, listing only the earliest certificate expiry.
+func (r healthReport) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{
+		"participant", "status", "templateVersion", "componentVersions", "nextCertificateExpiry",
+		"credentialsRotatedAt", "credentialsExpireAt", "lastVerifiedAt",
+	}}
+	for _, p := range r.Participants {
+		components := make([]string, 0, len(p.ComponentVersions))
+		for name, version := range p.ComponentVersions {
+			components = append(components, name+"="+version)
+		}
+		sort.Strings(components)
+		nextExpiry := ""
+		if len(p.Certificates) > 0 {
+			nextExpiry = p.Certificates[0].NotAfter.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			p.Participant, string(p.Status), p.TemplateVersion, strings.Join(components, ";"), nextExpiry,
+			p.CredentialsRotatedAt, p.CredentialsExpireAt, p.LastVerifiedAt,
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"aruba-provisioner/api/status"
+)
+
+func TestCertificateNotAfter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second).UTC()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "participant"},
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := certificateNotAfter(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(notAfter) {
+		t.Errorf("notAfter = %s, want %s", got, notAfter)
+	}
+	if _, err := certificateNotAfter([]byte("not a certificate")); err == nil {
+		t.Error("expected an error for data without certificate")
+	}
+}
+
+func TestHealthReportCsv(t *testing.T) {
+	expiry := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := healthReport{Participants: []participantHealth{{
+		Participant:       "acme",
+		Status:            status.StatusReady,
+		TemplateVersion:   "1.2.0",
+		ComponentVersions: map[string]string{"dataplane": "0.13.0", "controlplane": "0.13.0"},
+		Certificates:      []certificateExpiry{{Secret: "tls", NotAfter: expiry}},
+		LastVerifiedAt:    "2026-01-01T00:00:00Z",
+	}}}
+	body, err := report.csv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and one row, got %q", body)
+	}
+	want := "acme,READY,1.2.0,controlplane=0.13.0;dataplane=0.13.0,2026-03-01T00:00:00Z,,,2026-01-01T00:00:00Z"
+	if lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Every applied manifest set is kept as a Secret in the participant namespace, since the rendered manifests contain
+// credentials. Only the latest maxRevisions are kept.
+const (
+	revisionSecretPrefix = "provisioner-revision-"
+	revisionLabel        = "aruba-provisioner/revision"
+	revisionManifestsKey = "manifests.yaml"
+	revisionCauseKey     = "cause"
+	revisionObjectsKey   = "objects.json"
+	maxRevisions         = 10
+)
+
+type revision struct {
+	Number          int       `json:"revision"`
+	Cause           string    `json:"cause"`
+	TemplateVersion string    `json:"templateVersion,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	// Objects lists the objects applied with the revision, empty for revisions saved by earlier versions
+	Objects   []appliedObject `json:"objects,omitempty"`
+	manifests string
+}
+
+// revisionRecorder collects the rendered objects applied through its action.
+type revisionRecorder struct {
+	mu   sync.Mutex
+	docs []string
+}
+
+// action wraps an action, recording every object as rendered, before the wrapped action fills in the server state.
+func (r *revisionRecorder) action(next action) action {
+	return func(c client.Client, ctx context.Context, object client.Object) error {
+		var doc []byte
+		if obj, ok := object.(*unstructured.Unstructured); ok {
+			rendered, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				return err
+			}
+			doc = rendered
+		}
+		if err := next(c, ctx, object); err != nil {
+			return err
+		}
+		if doc != nil {
+			r.mu.Lock()
+			r.docs = append(r.docs, string(doc))
+			r.mu.Unlock()
+		}
+		return nil
+	}
+}
+
+func (r *revisionRecorder) manifests() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.docs, "---\n")
+}
+
+// saveRevision stores the manifests and the objects applied from them as the next revision of the participant and
+// prunes the oldest revisions.
+func saveRevision(c client.Client, ctx context.Context, namespace string, cause string, manifests string, objects []appliedObject) (int, error) {
+	revisions, err := listRevisions(c, ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := json.Marshal(objects)
+	if err != nil {
+		return 0, err
+	}
+	number := 1
+	if len(revisions) > 0 {
+		number = revisions[len(revisions)-1].Number + 1
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        revisionSecretPrefix + strconv.Itoa(number),
+			Namespace:   namespace,
+			Labels:      map[string]string{revisionLabel: strconv.Itoa(number)},
+			Annotations: map[string]string{templateVersionAnnotation: templateVersion},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			revisionManifestsKey: []byte(manifests),
+			revisionCauseKey:     []byte(cause),
+			revisionObjectsKey:   applied,
+		},
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(revisions)+1-maxRevisions; i++ {
+		stale := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: revisionSecretPrefix + strconv.Itoa(revisions[i].Number), Namespace: namespace}}
+		if err := c.Delete(ctx, stale); client.IgnoreNotFound(err) != nil {
+			fmt.Printf("pruning revision %d of %s failed: %v\n", revisions[i].Number, namespace, err)
+		}
+	}
+	return number, nil
+}
+
+// listRevisions returns the revisions of a participant, oldest first, without their manifests and objects.
+func listRevisions(c client.Client, ctx context.Context, namespace string) ([]revision, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(namespace), client.HasLabels{revisionLabel}); err != nil {
+		return nil, err
+	}
+	revisions := make([]revision, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		number, err := strconv.Atoi(secret.Labels[revisionLabel])
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, revision{
+			Number:          number,
+			Cause:           string(secret.Data[revisionCauseKey]),
+			TemplateVersion: secret.Annotations[templateVersionAnnotation],
+			CreatedAt:       secret.CreationTimestamp.Time,
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Number < revisions[j].Number })
+	return revisions, nil
+}
+
+// loadRevision returns a revision of a participant including its manifests and objects.
+func loadRevision(c client.Client, ctx context.Context, namespace string, number int) (revision, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: revisionSecretPrefix + strconv.Itoa(number)}, secret); err != nil {
+		return revision{}, err
+	}
+	var objects []appliedObject
+	if applied, ok := secret.Data[revisionObjectsKey]; ok {
+		if err := json.Unmarshal(applied, &objects); err != nil {
+			return revision{}, fmt.Errorf("objects of revision %d: %w", number, err)
+		}
+	}
+	return revision{
+		Number:          number,
+		Cause:           string(secret.Data[revisionCauseKey]),
+		TemplateVersion: secret.Annotations[templateVersionAnnotation],
+		CreatedAt:       secret.CreationTimestamp.Time,
+		Objects:         objects,
+		manifests:       string(secret.Data[revisionManifestsKey]),
+	}, nil
+}
+
+// latestRevision returns the revision the participant was last applied with, a conflict if it has none.
+func latestRevision(c client.Client, ctx context.Context, namespace string) (revision, error) {
+	revisions, err := listRevisions(c, ctx, namespace)
+	if err != nil {
+		return revision{}, err
+	}
+	if len(revisions) == 0 {
+		return revision{}, fiber.NewError(fiber.StatusConflict, "participant has no applied revision")
+	}
+	return loadRevision(c, ctx, namespace, revisions[len(revisions)-1].Number)
+}
+
+// reconcileParticipant re-applies the latest revision of the participant, taking back fields other managers changed.
+func reconcileParticipant(kubeClient client.Client, ctx context.Context, participants *provisioner, statusChecker *status.StatusChecker, audit *auditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespace := c.Params("participantName")
+		managed, err := status.IsManagedNamespace(ctx, kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		rev, err := latestRevision(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		creds, err := loadCredentials(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		var then []jobStep
+		if c.QueryBool("seed") {
+			_, seeding, err := participants.seedingResumption(c.UserContext(), namespace)
+			if err != nil {
+				return err
+			}
+			then = append(then, seeding)
+		}
+		owner, err := ownerOf(kubeClient, ctx, namespace)
+		if err != nil {
+			return err
+		}
+		// applies are forced, fields taken over by other managers return to the provisioner
+		job, err := startRollout(kubeClient, withRequesterOf(withSpanOf(ctx, c.UserContext()), c.UserContext()), statusChecker, namespace, fmt.Sprintf("reconcile with revision %d", rev.Number), func(ctx context.Context, kubernetesAction action) ([]appliedObject, error) {
+			return applyYaml(&namespace, new(string), kubeClient, ctx, rev.manifests, kubernetesAction, credentialsMutator(creds, ""))
+		}, then...)
+		if err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditReconcile, Tenant: owner, Participant: namespace, JobId: job.Id, Revision: rev.Number})
+		c.Location("/api/v1/jobs/" + job.Id)
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"jobId": job.Id, "participant": namespace})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRevisionsArePrunedAndLoadable(t *testing.T) {
+	c := newSecretClient()
+	ctx := context.Background()
+
+	for i := 1; i <= maxRevisions+2; i++ {
+		objects := []appliedObject{{Version: "v1", Kind: "ConfigMap", Namespace: "acme", Name: "config", Operation: changeUpdated, ResourceVersion: strconv.Itoa(i)}}
+		number, err := saveRevision(c, ctx, "acme", "upgrade", "kind: ConfigMap\n", objects)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if number != i {
+			t.Fatalf("revision number = %d, want %d", number, i)
+		}
+	}
+	revisions, err := listRevisions(c, ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != maxRevisions || revisions[0].Number != 3 {
+		t.Errorf("expected revisions 3 to %d to be kept, got %d starting at %d", maxRevisions+2, len(revisions), revisions[0].Number)
+	}
+	rev, err := loadRevision(c, ctx, "acme", maxRevisions+2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.manifests != "kind: ConfigMap\n" || rev.Cause != "upgrade" || len(rev.Objects) != 1 || rev.Objects[0].ResourceVersion != strconv.Itoa(maxRevisions+2) {
+		t.Errorf("unexpected revision %+v", rev)
+	}
+}
+
+func TestRevisionRecorderKeepsRenderedObjects(t *testing.T) {
+	recorder := &revisionRecorder{}
+	apply := recorder.action(func(c client.Client, ctx context.Context, object client.Object) error {
+		// the API server fills in the status when applying
+		object.(*unstructured.Unstructured).Object["status"] = map[string]any{"phase": "Active"}
+		return nil
+	})
+	obj := &unstructured.Unstructured{Object: map[string]any{"apiVersion": "v1", "kind": "Namespace", "metadata": map[string]any{"name": "acme"}}}
+	if err := apply(nil, context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+	manifests := recorder.manifests()
+	if !strings.Contains(manifests, "name: acme") || strings.Contains(manifests, "Active") {
+		t.Errorf("unexpected recorded manifests %q", manifests)
+	}
+}
+
+func TestReconcileParticipant(t *testing.T) {
+	previous := apiReadinessTimeout
+	apiReadinessTimeout = 0
+	t.Cleanup(func() { apiReadinessTimeout = previous })
+	seed := &seedServer{requests: make(map[string]int), failing: map[string]int{}}
+	seedApis := httptest.NewServer(seed)
+	defer seedApis.Close()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	kube := applyingClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "globex", Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}},
+	).Build()}
+	ctx := context.Background()
+	manifests := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: controlplane-config\n  namespace: acme\ndata:\n  EDC_HOSTNAME: acme\n"
+	if _, err := saveRevision(kube, ctx, "acme", "provision", manifests, nil); err != nil {
+		t.Fatal(err)
+	}
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", KubernetesIngressHost: seedApis.URL}
+	if err := storeSeedingState(kube, ctx, seedingState{definition: definition, completed: map[string]string{}}); err != nil {
+		t.Fatal(err)
+	}
+	statusChecker := status.NewStatusChecker(ctx, kube, 0)
+	participants := &provisioner{kubeClient: kube, ctx: ctx, statusChecker: statusChecker}
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/resources/:participantName/reconcile", reconcileParticipant(kube, ctx, participants, statusChecker, &auditLog{client: kube, namespace: "provisioner"}))
+
+	reconcile := func(target string) (int, *provisioningJob) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("POST", target, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var accepted struct {
+			JobId string `json:"jobId"`
+		}
+		if resp.StatusCode != fiber.StatusAccepted {
+			return resp.StatusCode, nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&accepted); err != nil {
+			t.Fatal(err)
+		}
+		job := jobs.get(accepted.JobId)
+		if job == nil || !job.wait(10*time.Second) {
+			t.Fatalf("%s: expected the job %s to finish", target, accepted.JobId)
+		}
+		return resp.StatusCode, job
+	}
+	phases := func(job *provisioningJob) []string {
+		var names []string
+		for _, phase := range job.Phases {
+			names = append(names, phase.Name+":"+phase.Status)
+		}
+		return names
+	}
+
+	_, job := reconcile("/resources/acme/reconcile")
+	if job.Status != jobSucceeded || !slices.Equal(phases(job), []string{"apply:" + jobSucceeded, "readiness:" + jobSucceeded}) {
+		t.Errorf("expected the revision to be re-applied without seeding, got %s %v: %s", job.Status, phases(job), job.Error)
+	}
+	if seed.count("/assets") != 0 {
+		t.Error("expected no seeding without ?seed=true")
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := kube.Get(ctx, client.ObjectKey{Namespace: "acme", Name: "controlplane-config"}, configMap); err != nil || configMap.Data["EDC_HOSTNAME"] != "acme" {
+		t.Errorf("expected the manifests of the revision to be applied, got %v: %v", configMap.Data, err)
+	}
+
+	_, job = reconcile("/resources/acme/reconcile?seed=true")
+	if job.Status != jobSucceeded || !slices.Equal(phases(job), []string{"apply:" + jobSucceeded, "readiness:" + jobSucceeded, "seeding:" + jobSucceeded}) {
+		t.Errorf("expected the reconcile to chain into seeding, got %s %v: %s", job.Status, phases(job), job.Error)
+	}
+	if seed.count("/assets") == 0 {
+		t.Error("expected the missing seed steps to run")
+	}
+
+	for target, expected := range map[string]int{
+		"/resources/initech/reconcile":          fiber.StatusNotFound,
+		"/resources/globex/reconcile":           fiber.StatusConflict,
+		"/resources/globex/reconcile?seed=true": fiber.StatusConflict,
+	} {
+		if code, _ := reconcile(target); code != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, code)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Components whose replicas can be set, the others keep their state in memory or on a volume
+var scalableComponents = []string{"controlplane", "dataplane"}
+
+// CPU utilization autoscalers aim at unless the definition sets another one
+const defaultTargetCpuUtilization = 80
+
+// ScalingOptions sets the replicas of a component, or lets a HorizontalPodAutoscaler scale it.
+type ScalingOptions struct {
+	Replicas    int32               `json:"replicas,omitempty"`
+	Autoscaling *AutoscalingOptions `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingOptions configures the HorizontalPodAutoscaler of a component, which scales it on its CPU utilization.
+type AutoscalingOptions struct {
+	MinReplicas int32 `json:"minReplicas"`
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCpuUtilization is the average CPU utilization in percent of the requests, 80 if not set
+	TargetCpuUtilization int32 `json:"targetCpuUtilization,omitempty"`
+}
+
+// validateScaling checks the scaling of the participant's components. Autoscalers compare the CPU usage with the
+// requests, which only sizing profiles set.
+func validateScaling(definition ParticipantDefinition) error {
+	for name, options := range definition.Scaling {
+		if !slices.Contains(scalableComponents, name) {
+			return fmt.Errorf("scaling: unsupported component %q, must be one of %s", name, strings.Join(scalableComponents, ", "))
+		}
+		if !definition.hasComponent(name) {
+			return fmt.Errorf("scaling: %s is not among the participant's components", name)
+		}
+		autoscaling := options.Autoscaling
+		switch {
+		case options.Replicas < 0:
+			return fmt.Errorf("scaling %s: replicas must not be negative", name)
+		case autoscaling == nil:
+			continue
+		case options.Replicas > 0:
+			return fmt.Errorf("scaling %s: replicas and autoscaling are mutually exclusive", name)
+		case definition.Size == "":
+			return fmt.Errorf("scaling %s: autoscaling requires a size, which sets the CPU requests", name)
+		case autoscaling.MinReplicas < 1 || autoscaling.MaxReplicas < autoscaling.MinReplicas:
+			return fmt.Errorf("scaling %s: autoscaling needs 1 <= minReplicas <= maxReplicas", name)
+		case autoscaling.TargetCpuUtilization < 0 || autoscaling.TargetCpuUtilization > 100:
+			return fmt.Errorf("scaling %s: targetCpuUtilization must be a percentage", name)
+		}
+	}
+	return nil
+}
+
+// scalingMutator sets the replicas of the components. Autoscaled components are left to their autoscaler, they are
+// annotated with the replicas they need at least to be ready.
+func scalingMutator(scaling map[string]ScalingOptions) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		options, ok := scaling[obj.GetName()]
+		if !ok || obj.GetKind() != "Deployment" {
+			return nil
+		}
+		if options.Autoscaling != nil {
+			unstructured.RemoveNestedField(obj.Object, "spec", "replicas")
+			addAnnotations(obj, map[string]string{status.MinReplicasAnnotation: strconv.Itoa(int(options.Autoscaling.MinReplicas))})
+			return nil
+		}
+		if options.Replicas > 0 {
+			return unstructured.SetNestedField(obj.Object, int64(options.Replicas), "spec", "replicas")
+		}
+		return nil
+	}
+}
+
+// scalingManifests renders the HorizontalPodAutoscalers of the autoscaled components.
+func scalingManifests(namespace string, scaling map[string]ScalingOptions) (string, error) {
+	names := make([]string, 0, len(scaling))
+	for name := range scaling {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var docs []string
+	for _, name := range names {
+		autoscaling := scaling[name].Autoscaling
+		if autoscaling == nil {
+			continue
+		}
+		target := autoscaling.TargetCpuUtilization
+		if target == 0 {
+			target = defaultTargetCpuUtilization
+		}
+		doc, err := yaml.Marshal(map[string]any{
+			"apiVersion": "autoscaling/v2",
+			"kind":       "HorizontalPodAutoscaler",
+			"metadata":   map[string]any{"name": name, "namespace": namespace},
+			"spec": map[string]any{
+				"scaleTargetRef": map[string]any{"apiVersion": "apps/v1", "kind": "Deployment", "name": name},
+				"minReplicas":    autoscaling.MinReplicas,
+				"maxReplicas":    autoscaling.MaxReplicas,
+				"metrics": []any{map[string]any{
+					"type": "Resource",
+					"resource": map[string]any{
+						"name":   "cpu",
+						"target": map[string]any{"type": "Utilization", "averageUtilization": target},
+					},
+				}},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestScalingMutator(t *testing.T) {
+	mutate := scalingMutator(map[string]ScalingOptions{
+		"controlplane": {Replicas: 3},
+		"dataplane":    {Autoscaling: &AutoscalingOptions{MinReplicas: 2, MaxReplicas: 5}},
+	})
+	deployment := func(name string) *unstructured.Unstructured {
+		obj := parseObject(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: "+name+"\nspec:\n  replicas: 1\n")
+		if err := mutate(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	if replicas, _, _ := unstructured.NestedInt64(deployment("controlplane").Object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("expected 3 controlplane replicas, got %d", replicas)
+	}
+	dataplane := deployment("dataplane")
+	if _, found, _ := unstructured.NestedFieldNoCopy(dataplane.Object, "spec", "replicas"); found {
+		t.Error("expected the replicas of the autoscaled dataplane to be left to its autoscaler")
+	}
+	if dataplane.GetAnnotations()[status.MinReplicasAnnotation] != "2" {
+		t.Errorf("expected the minimum replicas to be recorded, got %v", dataplane.GetAnnotations())
+	}
+	identityhub := deployment("identityhub")
+	if replicas, _, _ := unstructured.NestedFieldNoCopy(identityhub.Object, "spec", "replicas"); replicas != float64(1) {
+		t.Errorf("expected the identityhub to be left alone, got %v", replicas)
+	}
+}
+
+func TestScalingManifests(t *testing.T) {
+	doc, err := scalingManifests("acme", map[string]ScalingOptions{
+		"controlplane": {Replicas: 2},
+		"dataplane":    {Autoscaling: &AutoscalingOptions{MinReplicas: 1, MaxReplicas: 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hpa := parseObject(t, doc)
+	if hpa.GetKind() != "HorizontalPodAutoscaler" || hpa.GetName() != "dataplane" || hpa.GetNamespace() != "acme" {
+		t.Fatalf("expected the autoscaler of the dataplane only, got %s", doc)
+	}
+	if !strings.Contains(doc, "averageUtilization: 80") {
+		t.Errorf("expected the default CPU utilization target, got %s", doc)
+	}
+}
+
+func TestValidateScaling(t *testing.T) {
+	autoscaled := map[string]ScalingOptions{"dataplane": {Autoscaling: &AutoscalingOptions{MinReplicas: 1, MaxReplicas: 3}}}
+	valid := []ParticipantDefinition{
+		{Scaling: map[string]ScalingOptions{"controlplane": {Replicas: 2}}},
+		{Size: "small", Scaling: autoscaled},
+	}
+	for _, definition := range valid {
+		if err := validateScaling(definition); err != nil {
+			t.Errorf("%+v: %v", definition.Scaling, err)
+		}
+	}
+	invalid := []ParticipantDefinition{
+		{Scaling: map[string]ScalingOptions{"postgres": {Replicas: 2}}},
+		{Scaling: autoscaled},
+		{Size: "small", Scaling: map[string]ScalingOptions{"dataplane": {Replicas: 2, Autoscaling: &AutoscalingOptions{MinReplicas: 1, MaxReplicas: 3}}}},
+		{Size: "small", Scaling: map[string]ScalingOptions{"dataplane": {Autoscaling: &AutoscalingOptions{MinReplicas: 3, MaxReplicas: 2}}}},
+		{Components: []string{"identityhub"}, Scaling: map[string]ScalingOptions{"controlplane": {Replicas: 2}}},
+	}
+	for _, definition := range invalid {
+		if err := validateScaling(definition); err == nil {
+			t.Errorf("%+v: expected an error", definition.Scaling)
+		}
+	}
+}
+
+func TestDeploymentReadyWithAutoscaler(t *testing.T) {
+	replicas := int32(4)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "dataplane", Generation: 1, Annotations: map[string]string{status.MinReplicasAnnotation: "2"}},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 4, UpdatedReplicas: 4, ReadyReplicas: 2},
+	}
+	if !deploymentReady(deployment) {
+		t.Error("expected the minimum replicas of the autoscaler to suffice")
+	}
+	deployment.Status.ReadyReplicas = 1
+	if deploymentReady(deployment) {
+		t.Error("expected fewer than the minimum replicas not to be ready")
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Interval the scheduled provisionings are checked for being due at
+const scheduleCheckInterval = time.Minute
+
+// Label marking the Secrets holding scheduled provisionings, which keep the definitions with their API keys
+const scheduleLabel = "aruba-provisioner/schedule"
+
+// Namespace annotation recording the teardown schedule the participant was provisioned with
+const teardownAnnotation = "aruba-provisioner/teardown"
+
+// Keys of the Secret of a scheduled provisioning
+const (
+	scheduleDefinitionKey = "definition.json"
+	scheduleNotBeforeKey  = "notBefore"
+	scheduleErrorKey      = "error"
+)
+
+// Operations listed by the schedule view
+const (
+	scheduledProvisioning = "provision"
+	scheduledTeardown     = "teardown"
+)
+
+// States of scheduled operations
+const (
+	schedulePending = "PENDING"
+	scheduleFailed  = "FAILED"
+)
+
+// scheduledOperation is a provisioning waiting for its notBefore time or the teardown of a provisioned participant.
+type scheduledOperation struct {
+	Participant string    `json:"participant"`
+	Operation   string    `json:"operation"`
+	At          time.Time `json:"at"`
+	State       string    `json:"state"`
+	// Teardown is the schedule the participant is deleted on, an RFC 3339 time or a cron expression, or the lifetime of
+	// an ephemeral participant
+	Teardown string `json:"teardown,omitempty"`
+	// Error is why a due provisioning could not be started
+	Error string `json:"error,omitempty"`
+}
+
+// scheduler starts the provisionings whose notBefore time came, e.g. to create the participants of a workshop the
+// night before. Scheduled provisionings are kept in Secrets in the provisioner's namespace, so they survive restarts and
+// are started once by the leader. Teardowns are recorded as the expiry of the participant namespace and carried out by
+// the expiry of ephemeral participants.
+type scheduler struct {
+	client       client.Client
+	namespace    string
+	participants *provisioner
+	maintenance  *maintenanceMode
+	// plan validates a due definition again and prepares its provisioning for the tenant that scheduled it
+	plan func(ctx context.Context, definition ParticipantDefinition, tenant string) (provisioningPlan, error)
+	// leading reports whether this replica starts the due provisionings, with several replicas only the leader does
+	leading func() bool
+}
+
+func scheduleSecretName(participant string) string {
+	return "schedule-" + participant
+}
+
+// schedule stores the provisioning of the definition until its notBefore time. Scheduling a participant again
+// replaces its pending provisioning, unless another tenant scheduled it.
+func (s *scheduler) schedule(ctx context.Context, tenant string, definition ParticipantDefinition) (scheduledOperation, error) {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return scheduledOperation{}, err
+	}
+	labels := map[string]string{scheduleLabel: "true", status.ParticipantLabel: definition.ParticipantName}
+	if tenant != "" {
+		labels[tenantLabel] = tenant
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: scheduleSecretName(definition.ParticipantName), Namespace: s.namespace, Labels: labels},
+		Data: map[string][]byte{
+			scheduleDefinitionKey: data,
+			scheduleNotBeforeKey:  []byte(definition.NotBefore.UTC().Format(time.RFC3339)),
+		},
+	}
+	existing := &corev1.Secret{}
+	err = s.client.Get(ctx, client.ObjectKeyFromObject(secret), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		err = s.client.Create(ctx, secret)
+	case err != nil:
+	case existing.Labels[tenantLabel] != tenant:
+		return scheduledOperation{}, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("participant %s is scheduled already", definition.ParticipantName))
+	default:
+		secret.ResourceVersion = existing.ResourceVersion
+		err = s.client.Update(ctx, secret)
+	}
+	if err != nil {
+		return scheduledOperation{}, err
+	}
+	return scheduledOperation{Participant: definition.ParticipantName, Operation: scheduledProvisioning, At: definition.NotBefore.UTC(),
+		State: schedulePending, Teardown: definition.Teardown}, nil
+}
+
+// pending returns the Secrets of the scheduled provisionings of the tenant, of all tenants if empty.
+func (s *scheduler) pending(ctx context.Context, tenant string) ([]corev1.Secret, error) {
+	selector := client.MatchingLabels{scheduleLabel: "true"}
+	if tenant != "" {
+		selector[tenantLabel] = tenant
+	}
+	secrets := &corev1.SecretList{}
+	if err := s.client.List(ctx, secrets, client.InNamespace(s.namespace), selector); err != nil {
+		return nil, err
+	}
+	return secrets.Items, nil
+}
+
+// scheduledDefinition reads the definition and notBefore time of a scheduled provisioning.
+func scheduledDefinition(secret corev1.Secret) (ParticipantDefinition, time.Time, error) {
+	var definition ParticipantDefinition
+	if err := json.Unmarshal(secret.Data[scheduleDefinitionKey], &definition); err != nil {
+		return definition, time.Time{}, fmt.Errorf("scheduled definition %s: %w", secret.Name, err)
+	}
+	notBefore, err := time.Parse(time.RFC3339, string(secret.Data[scheduleNotBeforeKey]))
+	if err != nil {
+		return definition, time.Time{}, fmt.Errorf("notBefore of %s: %w", secret.Name, err)
+	}
+	return definition, notBefore, nil
+}
+
+// list returns the pending provisionings and the teardowns of the provisioned participants of the tenant, of all
+// tenants if empty, the next one first.
+func (s *scheduler) list(ctx context.Context, tenant string) ([]scheduledOperation, error) {
+	secrets, err := s.pending(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	operations := make([]scheduledOperation, 0, len(secrets))
+	for _, secret := range secrets {
+		definition, notBefore, err := scheduledDefinition(secret)
+		if err != nil {
+			return nil, err
+		}
+		operation := scheduledOperation{Participant: definition.ParticipantName, Operation: scheduledProvisioning, At: notBefore,
+			State: schedulePending, Teardown: definition.Teardown}
+		if failure := string(secret.Data[scheduleErrorKey]); failure != "" {
+			operation.State, operation.Error = scheduleFailed, failure
+		}
+		operations = append(operations, operation)
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := s.client.List(ctx, namespaces, tenantSelector(tenant)); err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaces.Items {
+		expiresAt := status.ExpiryOf(&namespace)
+		if expiresAt == nil || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		teardown := namespace.Annotations[teardownAnnotation]
+		if teardown == "" {
+			teardown = namespace.Annotations[status.ExpiresAfterAnnotation]
+		}
+		operations = append(operations, scheduledOperation{Participant: namespace.Name, Operation: scheduledTeardown, At: *expiresAt,
+			State: schedulePending, Teardown: teardown})
+	}
+	slices.SortStableFunc(operations, func(a, b scheduledOperation) int { return a.At.Compare(b.At) })
+	return operations, nil
+}
+
+// cancel removes the pending provisioning of the participant, failing with 404 if the tenant didn't schedule one.
+func (s *scheduler) cancel(ctx context.Context, tenant string, participant string) error {
+	secret := &corev1.Secret{}
+	err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: scheduleSecretName(participant)}, secret)
+	if apierrors.IsNotFound(err) || (err == nil && tenant != "" && secret.Labels[tenantLabel] != tenant) {
+		return fiber.NewError(fiber.StatusNotFound, "no provisioning of "+participant+" is scheduled")
+	}
+	if err != nil {
+		return err
+	}
+	return client.IgnoreNotFound(s.client.Delete(ctx, secret))
+}
+
+// run starts the due provisionings at the interval until the context is cancelled. Nothing is started during
+// maintenance.
+func (s *scheduler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.leading() || s.maintenance.current(ctx).Enabled {
+			continue
+		}
+		s.startDue(ctx, time.Now())
+	}
+}
+
+// startDue starts the pending provisionings whose notBefore time came. Provisionings the definition or the tenant's
+// quota no longer allows are marked as failed and kept until they are cancelled, others are retried at the next check.
+func (s *scheduler) startDue(ctx context.Context, now time.Time) {
+	secrets, err := s.pending(ctx, "")
+	if err != nil {
+		fmt.Println("Listing scheduled provisionings failed:", err)
+		return
+	}
+	for _, secret := range secrets {
+		if len(secret.Data[scheduleErrorKey]) > 0 {
+			continue
+		}
+		definition, notBefore, err := scheduledDefinition(secret)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if now.Before(notBefore) || jobs.running(definition.ParticipantName) {
+			continue
+		}
+		job, err := s.start(ctx, definition, secret.Labels[tenantLabel])
+		if err != nil {
+			fmt.Printf("Starting the scheduled provisioning of %s failed: %v\n", definition.ParticipantName, err)
+			if problemOf(err).Status < fiber.StatusInternalServerError {
+				s.markFailed(ctx, secret, err)
+			}
+			continue
+		}
+		fmt.Printf("Started the scheduled provisioning of %s in job %s\n", definition.ParticipantName, job.Id)
+		if err := s.client.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+			fmt.Printf("Removing the scheduled provisioning of %s failed: %v\n", definition.ParticipantName, err)
+		}
+	}
+}
+
+func (s *scheduler) start(ctx context.Context, definition ParticipantDefinition, tenant string) (*provisioningJob, error) {
+	plan, err := s.plan(ctx, definition, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return s.participants.start(ctx, plan, nil, nil)
+}
+
+// markFailed records why the due provisioning could not be started.
+func (s *scheduler) markFailed(ctx context.Context, secret corev1.Secret, cause error) {
+	patch := client.MergeFrom(secret.DeepCopy())
+	secret.Data[scheduleErrorKey] = []byte(cause.Error())
+	if err := s.client.Patch(ctx, &secret, patch); err != nil {
+		fmt.Printf("Marking the scheduled provisioning %s as failed failed: %v\n", secret.Name, err)
+	}
+}
+
+// listSchedules serves GET /api/v1/schedules.
+func listSchedules(s *scheduler, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		operations, err := s.list(ctx, tenantOf(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(operations)
+	}
+}
+
+// cancelSchedule serves DELETE /api/v1/schedules/:participantName.
+func cancelSchedule(s *scheduler, audit *auditLog, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		participant := c.Params("participantName")
+		if err := s.cancel(ctx, tenantOf(c), participant); err != nil {
+			return err
+		}
+		audit.record(c, ctx, auditEntry{Action: auditUnschedule, Participant: participant})
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// provisionedFrom is when the participant of the definition is provisioned, now or its notBefore time.
+func (p ParticipantDefinition) provisionedFrom(now time.Time) time.Time {
+	if p.NotBefore != nil && p.NotBefore.After(now) {
+		return *p.NotBefore
+	}
+	return now
+}
+
+// parseTeardown returns when the participant provisioned at from is torn down: at the RFC 3339 time, or at the first
+// time the cron expression matches after from.
+func parseTeardown(value string, from time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		if !at.After(from) {
+			return time.Time{}, fmt.Errorf("%s is not after the provisioning at %s", value, from.UTC().Format(time.RFC3339))
+		}
+		return at, nil
+	}
+	schedule, err := parseCron(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 time or a cron expression: %w", err)
+	}
+	at, ok := schedule.next(from)
+	if !ok {
+		return time.Time{}, fmt.Errorf("the cron expression %q never matches", value)
+	}
+	return at, nil
+}
+
+// teardownMutator records the teardown of the participant as the expiry of its namespace. Re-applying a participant
+// keeps its teardown time unless the definition asks for another schedule.
+func teardownMutator(c client.Client, ctx context.Context, participant string, teardown string, at time.Time) (objectMutator, error) {
+	namespace := &corev1.Namespace{}
+	err := c.Get(ctx, client.ObjectKey{Name: participant}, namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if existing := status.ExpiryOf(namespace); existing != nil && namespace.Annotations[teardownAnnotation] == teardown {
+		at = *existing
+	}
+	annotations := map[string]string{
+		teardownAnnotation:         teardown,
+		status.ExpiresAtAnnotation: at.UTC().Format(time.RFC3339),
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, annotations)
+		}
+		return nil
+	}, nil
+}
+
+// cronSchedule is a cron expression of five fields: minute, hour, day of month, month and day of week, evaluated in
+// UTC. Each field is a set of the values it matches.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// as in cron, a time matches if either the day of month or the day of week does when both are restricted
+	anyDay bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 7}}
+
+// parseCron parses a cron expression like "0 18 * * 5". Fields are *, values, ranges like 1-5 and lists of them,
+// optionally with a step like */15. Sunday is 0 or 7.
+func parseCron(expression string) (cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("a cron expression has %d fields, got %d", len(cronFields), len(fields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("%s %q: %w", cronFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+	weekdays := sets[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays = weekdays&^(1<<7) | 1
+	}
+	return cronSchedule{minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3], weekdays: weekdays,
+		anyDay: strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")}, nil
+}
+
+func parseCronField(field string, low int, high int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		values, stepValue, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepValue); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepValue)
+			}
+		}
+		from, to := low, high
+		if values != "*" {
+			first, last, isRange := strings.Cut(values, "-")
+			var err error
+			if from, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if stepped {
+				to = high
+			}
+		}
+		if from < low || to > high || from > to {
+			return 0, fmt.Errorf("%s is outside %d-%d", values, low, high)
+		}
+		for value := from; value <= to; value += step {
+			set |= 1 << value
+		}
+	}
+	if set == 0 {
+		return 0, errors.New("matches nothing")
+	}
+	return set, nil
+}
+
+// Times are searched this far ahead for a match of a cron expression, which never matches after that, e.g. on
+// February 30
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// next returns the first time after from the schedule matches, false if it never does.
+func (s cronSchedule) next(from time.Time) (time.Time, bool) {
+	t := from.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronHorizon)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseTeardown(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2026, 10, 14, 21, 30, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Time{
+		"2026-10-15T18:00:00Z": time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC),
+		"0 18 * * *":           time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC),
+		"*/20 * * * *":         time.Date(2026, 10, 14, 21, 40, 0, 0, time.UTC),
+		"0 9 * * 1-5":          time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+		"0 0 * * 7":            time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		"0 12 1 * 5":           time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		"30 21 14 10 *":        time.Date(2027, 10, 14, 21, 30, 0, 0, time.UTC),
+		"0 6 29 2 *":           time.Date(2028, 2, 29, 6, 0, 0, 0, time.UTC),
+	} {
+		if at, err := parseTeardown(value, from); err != nil || !at.Equal(expected) {
+			t.Errorf("%s: expected %s, got %s, %v", value, expected, at, err)
+		}
+	}
+	for _, value := range []string{"", "tomorrow", "2026-10-14T20:00:00Z", "0 18 * *", "60 * * * *", "0 18 * * mon", "*/0 * * * *", "5-1 * * * *", "0 0 30 2 *"} {
+		if _, err := parseTeardown(value, from); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestTeardownMutator(t *testing.T) {
+	scheduled := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workshop",
+		Annotations: map[string]string{teardownAnnotation: "0 18 * * *", status.ExpiresAtAnnotation: scheduled.Format(time.RFC3339)}}}).Build()
+
+	teardownOf := func(teardown string) string {
+		mutate, err := teardownMutator(kube, context.Background(), "workshop", teardown, scheduled.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		namespace := &unstructured.Unstructured{Object: map[string]any{"kind": "Namespace"}}
+		if err := mutate(namespace); err != nil {
+			t.Fatal(err)
+		}
+		return namespace.GetAnnotations()[status.ExpiresAtAnnotation]
+	}
+	if at := teardownOf("0 18 * * *"); at != scheduled.Format(time.RFC3339) {
+		t.Errorf("expected re-applying to keep the teardown %s, got %s", scheduled, at)
+	}
+	if at := teardownOf("0 20 * * *"); at == scheduled.Format(time.RFC3339) {
+		t.Error("expected another schedule to replace the teardown")
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	expiresAt := time.Now().Add(3 * time.Hour).UTC().Truncate(time.Second)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo",
+		Labels:      map[string]string{status.ManagedByLabel: status.ManagedByValue, tenantLabel: "acme"},
+		Annotations: map[string]string{teardownAnnotation: "0 18 * * *", status.ExpiresAtAnnotation: expiresAt.Format(time.RFC3339)}}}).Build()
+	planned := map[string]error{}
+	s := &scheduler{client: kube, namespace: "provisioner", plan: func(_ context.Context, definition ParticipantDefinition, tenant string) (provisioningPlan, error) {
+		if tenant != "acme" {
+			t.Errorf("expected the tenant that scheduled %s, got %q", definition.ParticipantName, tenant)
+		}
+		return provisioningPlan{}, planned[definition.ParticipantName]
+	}}
+	ctx := context.Background()
+
+	now := time.Now()
+	for name, notBefore := range map[string]time.Time{"alice": now.Add(time.Hour), "bob": now.Add(2 * time.Hour), "carol": now.Add(4 * time.Hour)} {
+		if _, err := s.schedule(ctx, "acme", ParticipantDefinition{ParticipantName: name, NotBefore: &notBefore, Teardown: "0 18 * * *"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notBefore := now.Add(time.Hour)
+	if _, err := s.schedule(ctx, "globex", ParticipantDefinition{ParticipantName: "alice", NotBefore: &notBefore}); problemOf(err).Status != fiber.StatusConflict {
+		t.Errorf("expected scheduling a participant of another tenant to conflict, got %v", err)
+	}
+
+	planned["alice"] = fiber.NewError(fiber.StatusConflict, "participant alice already exists")
+	planned["bob"] = errors.New("kube unavailable")
+	s.startDue(ctx, now.Add(150*time.Minute))
+
+	operations, err := s.list(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, operation := range operations {
+		order = append(order, operation.Participant+" "+operation.Operation+" "+operation.State)
+	}
+	expected := []string{"alice provision FAILED", "bob provision PENDING", "demo teardown PENDING", "carol provision PENDING"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+	if operations[0].Error != "participant alice already exists" || operations[2].Teardown != "0 18 * * *" {
+		t.Errorf("unexpected operations %+v", operations)
+	}
+	if others, err := s.list(ctx, "globex"); err != nil || len(others) != 0 {
+		t.Errorf("expected the operations of other tenants to be hidden, got %v, %v", others, err)
+	}
+
+	if err := s.cancel(ctx, "globex", "carol"); problemOf(err).Status != fiber.StatusNotFound {
+		t.Errorf("expected cancelling the schedule of another tenant to fail with 404, got %v", err)
+	}
+	if err := s.cancel(ctx, "acme", "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.cancel(ctx, "", "carol"); problemOf(err).Status != fiber.StatusNotFound {
+		t.Errorf("expected the cancelled schedule to be gone, got %v", err)
+	}
+}
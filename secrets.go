@@ -0,0 +1,144 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Stores the connector secrets created while seeding, e.g. the STS client secret, are written to
+const (
+	// secretStoreVault writes to the participant's Vault, which its components read secrets from
+	secretStoreVault = "vault"
+	// secretStoreKubernetes writes to a Secret in the participant namespace, for connectors whose vault is backed by
+	// Kubernetes Secrets
+	secretStoreKubernetes = "kubernetes"
+)
+
+var secretStores = []string{secretStoreVault, secretStoreKubernetes}
+
+// defaultSecretStore is used for dataspaces that don't set their store, set with --secret-store
+var defaultSecretStore = secretStoreVault
+
+// Root token of the participant's development Vault, see templates/connector.yaml
+const participantVaultToken = "root"
+
+// Secret the kubernetes store keeps the connector secrets in
+const connectorSecretsName = "connector-secrets"
+
+// Characters not allowed in the keys of Secrets, replaced in secret aliases
+var invalidSecretKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// secretStore keeps secrets the participant's connector reads by alias.
+type secretStore interface {
+	put(ctx context.Context, alias string, value string) error
+}
+
+func validateSecretStore(store string) error {
+	for _, known := range secretStores {
+		if store == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown secret store %q, expected one of %s", store, strings.Join(secretStores, ", "))
+}
+
+// secretStoreFor returns the store of the dataspace the participant's secrets are written to.
+func secretStoreFor(dataspace DataspaceConfig, c client.Client, definition ParticipantDefinition, clients seedingClients) secretStore {
+	store := defaultSecretStore
+	if dataspace.SecretStore != "" {
+		store = dataspace.SecretStore
+	}
+	if store == secretStoreKubernetes {
+		return kubernetesSecretStore{client: c, namespace: definition.ParticipantName}
+	}
+	if vault := vaultFor(dataspace); vault.enabled() {
+		return externalVaultSecretStore{
+			vault:     vault.client(clients),
+			namespace: vault.namespaceOf(definition.ParticipantName),
+			mount:     vault.Mount,
+			folder:    definition.ParticipantName,
+		}
+	}
+	return vaultSecretStore{vault: &api.ApiClient{
+		BaseUrl:    definition.routeUrl("/vault"),
+		ApiKey:     participantVaultToken,
+		HttpClient: clients.client(targetVault),
+	}}
+}
+
+type vaultSecretStore struct {
+	vault *api.ApiClient
+}
+
+func (s vaultSecretStore) put(ctx context.Context, alias string, value string) error {
+	return s.vault.PutSecret(ctx, alias, value)
+}
+
+// externalVaultSecretStore writes to the participant's folder of an external Vault, see VaultConfig.
+type externalVaultSecretStore struct {
+	vault     *api.ApiClient
+	namespace string
+	mount     string
+	folder    string
+}
+
+func (s externalVaultSecretStore) put(ctx context.Context, alias string, value string) error {
+	return s.vault.PutSecretIn(ctx, s.namespace, s.mount, s.folder+"/"+alias, value)
+}
+
+type kubernetesSecretStore struct {
+	client    client.Client
+	namespace string
+}
+
+// put adds the secret to the connector secrets, keyed by its alias with characters Secrets don't allow in keys
+// replaced by underscores. Other secrets are kept.
+func (s kubernetesSecretStore) put(ctx context.Context, alias string, value string) error {
+	key := invalidSecretKeyChars.ReplaceAllString(alias, "_")
+	secret := &corev1.Secret{}
+	err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: connectorSecretsName}, secret)
+	if apierrors.IsNotFound(err) {
+		return s.client.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: connectorSecretsName, Namespace: s.namespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{key: []byte(value)},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[key] = []byte(value)
+	return s.client.Update(ctx, secret)
+}
+
+// progressSecretStore reports storing the participant's secret as a step of its provisioning.
+type progressSecretStore struct {
+	secretStore
+	statusChecker *status.StatusChecker
+	participant   string
+	// stored is set once a secret was stored
+	stored bool
+}
+
+func (s *progressSecretStore) put(ctx context.Context, alias string, value string) error {
+	s.statusChecker.SetProgress(s.participant, status.StepSecretStored, status.StepRunning, "")
+	if err := s.secretStore.put(ctx, alias, value); err != nil {
+		s.statusChecker.SetProgress(s.participant, status.StepSecretStored, status.StepFailed, err.Error())
+		return err
+	}
+	s.stored = true
+	s.statusChecker.SetProgress(s.participant, status.StepSecretStored, status.StepCompleted, "")
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestVaultSecretStore(t *testing.T) {
+	var path, token, content string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, token = r.URL.EscapedPath(), r.Header.Get("X-Vault-Token")
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		content = body.Data["content"]
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	definition := ParticipantDefinition{ParticipantName: "alice", KubernetesIngressHost: server.URL}
+	store := secretStoreFor(DataspaceConfig{}, nil, definition, seedingClients{})
+	if err := store.put(context.Background(), "did:web:alice-sts-client-secret", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/alice/vault/v1/secret/data/did:web:alice-sts-client-secret" {
+		t.Errorf("unexpected vault path %s", path)
+	}
+	if token != participantVaultToken || content != "s3cret" {
+		t.Errorf("expected the secret to be written with the vault token, got token %q and content %q", token, content)
+	}
+}
+
+func TestKubernetesSecretStore(t *testing.T) {
+	kube := newSecretClient()
+	definition := ParticipantDefinition{ParticipantName: "alice"}
+	store := secretStoreFor(DataspaceConfig{SecretStore: secretStoreKubernetes}, kube, definition, seedingClients{})
+
+	if err := store.put(context.Background(), "did:web:alice-sts-client-secret", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.put(context.Background(), "signing-key", "key"); err != nil {
+		t.Fatal(err)
+	}
+	secret := kube.secrets[client.ObjectKey{Namespace: "alice", Name: connectorSecretsName}]
+	if secret == nil {
+		t.Fatal("connector secrets not created")
+	}
+	if string(secret.Data["did_web_alice-sts-client-secret"]) != "s3cret" || string(secret.Data["signing-key"]) != "key" {
+		t.Errorf("expected both secrets, got %v", secret.Data)
+	}
+}
+
+func TestValidateSecretStore(t *testing.T) {
+	if err := validateSecretStore(secretStoreKubernetes); err != nil {
+		t.Error(err)
+	}
+	if err := validateSecretStore("etcd"); err == nil {
+		t.Error("expected unknown stores to be rejected")
+	}
+}
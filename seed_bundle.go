@@ -0,0 +1,339 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	seedBundleFetchTimeout = 30 * time.Second
+	// Bundles are read into memory, larger ones are rejected
+	maxSeedBundleSize = 10 << 20
+	// Limits of the unpacked bundle, so a small archive can't expand to fill the memory
+	maxSeedBundleExtractedSize = 50 << 20
+	maxSeedBundleFileSize      = 5 << 20
+)
+
+// seedBundleFetcher fetches seed bundles with the client of the bundle target from the hosts bundles may come from.
+type seedBundleFetcher struct {
+	client http.Client
+	// hosts lists the hosts bundles may be fetched from, .example.com allowing the domain. If empty, any host that
+	// resolves to public addresses only is allowed. Listed hosts may resolve to cluster-internal addresses.
+	hosts []string
+}
+
+// seedBundles fetches the seed bundles, its client and hosts are set from the seeding clients and --seed-bundle-hosts.
+var seedBundles = seedBundleFetcher{client: http.Client{Timeout: seedBundleFetchTimeout, Transport: &http.Transport{
+	Proxy:       http.ProxyFromEnvironment,
+	DialContext: dialPublicOnly(&net.Dialer{}, ""),
+}}}
+
+// sharedAddressSpace is the range of carrier-grade NAT (RFC 6598), which clusters use for pods and services as well
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isInternalAddress reports whether the address can't be reached from the internet, e.g. of the cluster or the cloud
+// metadata endpoint.
+func isInternalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+type publicOnlyKey struct{}
+
+// publicOnly marks the requests of ctx as connecting to public addresses only.
+func publicOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicOnlyKey{}, true)
+}
+
+// dialPublicOnly dials with the dialer, refusing connections of requests marked by publicOnly to internal addresses.
+// The address is checked once resolved, so a host can't resolve to a public address when checked and to an internal
+// one when connected to. Connections to the proxy at proxyAddress aren't checked, it resolves the hosts itself.
+func dialPublicOnly(dialer *net.Dialer, proxyAddress string) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	guarded := *dialer
+	guarded.Control = func(network string, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || isInternalAddress(ip) {
+			return fmt.Errorf("connecting to the internal address %s is not allowed", host)
+		}
+		return nil
+	}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if marked, _ := ctx.Value(publicOnlyKey{}).(bool); marked && address != proxyAddress {
+			return guarded.DialContext(ctx, network, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// Directories of a seed bundle, every JSON file in them holds an entity or an array of entities
+const (
+	bundleAssetsDir              = "assets"
+	bundlePoliciesDir            = "policies"
+	bundleContractDefinitionsDir = "contractdefinitions"
+)
+
+// fetchSource loads the seed bundle the catalog references, a gzipped tarball with assets/, policies/ and
+// contractdefinitions/ directories. Entities given in the catalog itself take precedence over those of the bundle.
+// Sources are given as
+//
+//	https://example.com/catalogs.tar.gz#energy
+//	git+https://github.com/acme/catalogs.git?ref=v1.2#energy
+//
+// where the optional fragment selects a directory of the bundle.
+func (c *SeedCatalog) fetchSource(ctx context.Context) error {
+	if c == nil || c.Source == "" {
+		return nil
+	}
+	archiveUrl, dir, err := seedBundleUrl(c.Source)
+	if err != nil {
+		return err
+	}
+	files, err := seedBundles.fetchTarball(ctx, archiveUrl)
+	if err != nil {
+		return err
+	}
+	bundle, err := parseSeedBundle(files, dir)
+	if err != nil {
+		return err
+	}
+	if len(c.Assets) == 0 {
+		c.Assets = bundle.Assets
+	}
+	if len(c.PolicyDefinitions) == 0 {
+		c.PolicyDefinitions = bundle.PolicyDefinitions
+	}
+	if len(c.ContractDefinitions) == 0 {
+		c.ContractDefinitions = bundle.ContractDefinitions
+	}
+	return nil
+}
+
+// seedBundleUrl returns the URL of the tarball a source refers to and the directory selected in it.
+func seedBundleUrl(source string) (string, string, error) {
+	git := strings.HasPrefix(source, "git+")
+	parsed, err := url.Parse(strings.TrimPrefix(source, "git+"))
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", "", fmt.Errorf("invalid source %q, expected an http(s) URL", source)
+	}
+	dir := strings.Trim(parsed.Fragment, "/")
+	parsed.Fragment = ""
+	if !git {
+		return parsed.String(), dir, nil
+	}
+	archiveUrl, err := gitArchiveUrl(parsed)
+	return archiveUrl, dir, err
+}
+
+// gitArchiveUrl returns the URL GitHub or GitLab serve the tarball of a ref of the repository at, as the provisioner
+// image has no git client.
+func gitArchiveUrl(repo *url.URL) (string, error) {
+	ref := repo.Query().Get("ref")
+	if ref == "" {
+		return "", errors.New("git sources need a ?ref= naming a branch, tag or commit")
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(repo.Path, "/"), ".git")
+	switch {
+	case repo.Host == "github.com":
+		return "https://codeload.github.com/" + repoPath + "/tar.gz/" + url.PathEscape(ref), nil
+	case strings.HasPrefix(repo.Host, "gitlab."):
+		name := path.Base(repoPath)
+		return fmt.Sprintf("%s://%s/%s/-/archive/%s/%s-%s.tar.gz", repo.Scheme, repo.Host, repoPath, url.PathEscape(ref), name,
+			strings.ReplaceAll(ref, "/", "-")), nil
+	default:
+		return "", fmt.Errorf("git sources are supported for GitHub and GitLab repositories, reference a tarball of %s instead", repo.Host)
+	}
+}
+
+// allowed rejects fetching from the host unless it is listed, or, without a list, resolves to public addresses only,
+// so sources can't reach the provisioner's cluster or cloud metadata endpoints. Without a list, fetchTarball checks
+// the addresses connected to again.
+func (f seedBundleFetcher) allowed(ctx context.Context, host string) error {
+	if len(f.hosts) > 0 {
+		for _, allowed := range f.hosts {
+			if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("seed bundles can't be fetched from %s, allowed are %s", host, strings.Join(f.hosts, ", "))
+	}
+	addresses := []string{host}
+	if net.ParseIP(host) == nil {
+		lookupCtx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+		defer cancel()
+		var err error
+		if addresses, err = lookupHost(lookupCtx, host); err != nil {
+			return fmt.Errorf("host %q does not resolve", host)
+		}
+	}
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip == nil || isInternalAddress(ip) {
+			return fmt.Errorf("seed bundles can't be fetched from %s, it resolves to the internal address %s", host, address)
+		}
+	}
+	return nil
+}
+
+// fetchTarball returns the regular files of the gzipped tarball, keyed by path. Redirects are followed to allowed
+// hosts only.
+func (f seedBundleFetcher) fetchTarball(ctx context.Context, archiveUrl string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, seedBundleFetchTimeout)
+	defer cancel()
+	if len(f.hosts) == 0 {
+		ctx = publicOnly(ctx)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.allowed(ctx, request.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	httpClient := f.client
+	httpClient.CheckRedirect = func(redirect *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return f.allowed(redirect.Context(), redirect.URL.Hostname())
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", archiveUrl, response.Status)
+	}
+	limited := &io.LimitedReader{R: response.Body, N: maxSeedBundleSize + 1}
+	archive, err := gzip.NewReader(limited)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a gzipped tarball: %w", archiveUrl, err)
+	}
+	extracted := &io.LimitedReader{R: archive, N: maxSeedBundleExtractedSize + 1}
+	files := make(map[string][]byte)
+	reader := tar.NewReader(extracted)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if extracted.N <= 0 {
+			return nil, fmt.Errorf("%s unpacks to more than %d bytes", archiveUrl, maxSeedBundleExtractedSize)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", archiveUrl, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxSeedBundleFileSize {
+			return nil, fmt.Errorf("%s of %s is larger than %d bytes", header.Name, archiveUrl, maxSeedBundleFileSize)
+		}
+		content, err := io.ReadAll(io.LimitReader(reader, maxSeedBundleFileSize+1))
+		if extracted.N <= 0 {
+			return nil, fmt.Errorf("%s unpacks to more than %d bytes", archiveUrl, maxSeedBundleExtractedSize)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", archiveUrl, err)
+		}
+		if len(content) > maxSeedBundleFileSize {
+			return nil, fmt.Errorf("%s of %s is larger than %d bytes", header.Name, archiveUrl, maxSeedBundleFileSize)
+		}
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("%s is larger than %d bytes", archiveUrl, maxSeedBundleSize)
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "./"))] = content
+	}
+	return files, nil
+}
+
+// parseSeedBundle collects the entities in the bundle directory dir. Tarballs of git forges put everything in a
+// directory named after the repository and ref, a single top-level directory is therefore skipped.
+func parseSeedBundle(files map[string][]byte, dir string) (SeedCatalog, error) {
+	root := commonRoot(files)
+	if dir != "" {
+		root = path.Join(root, dir)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	// entities are created in the order of their file names
+	sort.Strings(names)
+
+	var bundle SeedCatalog
+	found := false
+	for _, name := range names {
+		relative, ok := strings.CutPrefix(name, root+"/")
+		if root == "" {
+			relative, ok = name, true
+		}
+		if !ok || path.Ext(relative) != ".json" {
+			continue
+		}
+		var target *[]map[string]any
+		switch path.Dir(relative) {
+		case bundleAssetsDir:
+			target = &bundle.Assets
+		case bundlePoliciesDir:
+			target = &bundle.PolicyDefinitions
+		case bundleContractDefinitionsDir:
+			target = &bundle.ContractDefinitions
+		default:
+			continue
+		}
+		entities, err := parseBundleFile(files[name])
+		if err != nil {
+			return SeedCatalog{}, fmt.Errorf("%s: %w", relative, err)
+		}
+		*target = append(*target, entities...)
+		found = true
+	}
+	if !found {
+		return SeedCatalog{}, fmt.Errorf("no JSON files found in %s/, %s/ or %s/ of the bundle", bundleAssetsDir, bundlePoliciesDir, bundleContractDefinitionsDir)
+	}
+	return bundle, nil
+}
+
+// commonRoot returns the top-level directory all files are in, or "" if there is none.
+func commonRoot(files map[string][]byte) string {
+	root := ""
+	for name := range files {
+		first, _, nested := strings.Cut(name, "/")
+		if !nested || (root != "" && first != root) {
+			return ""
+		}
+		root = first
+	}
+	if root == bundleAssetsDir || root == bundlePoliciesDir || root == bundleContractDefinitionsDir {
+		return ""
+	}
+	return root
+}
+
+func parseBundleFile(content []byte) ([]map[string]any, error) {
+	var entities []map[string]any
+	if err := json.Unmarshal(content, &entities); err == nil {
+		return entities, nil
+	}
+	var entity map[string]any
+	if err := json.Unmarshal(content, &entity); err != nil {
+		return nil, errors.New("expected a JSON object or an array of objects")
+	}
+	return []map[string]any{entity}, nil
+}
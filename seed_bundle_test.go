@@ -0,0 +1,213 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// allowSeedBundleHosts lets the test fetch bundles from the hosts, e.g. its httptest servers.
+func allowSeedBundleHosts(t *testing.T, hosts ...string) {
+	t.Helper()
+	previous := seedBundles
+	seedBundles.hosts = hosts
+	t.Cleanup(func() { seedBundles = previous })
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	compressed := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(compressed)
+	for name, content := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchSeedBundle(t *testing.T) {
+	bundle := tarball(t, map[string]string{
+		"catalogs-v1.2/README.md":                           "catalogs",
+		"catalogs-v1.2/energy/assets/meters.json":           `[{"@id": "meters"}, {"@id": "tariffs"}]`,
+		"catalogs-v1.2/energy/policies/open.json":           `{"@id": "open"}`,
+		"catalogs-v1.2/energy/contractdefinitions/all.json": `{"@id": "all", "accessPolicyId": "open", "contractPolicyId": "open"}`,
+		"catalogs-v1.2/weather/assets/forecast.json":        `{"@id": "forecast"}`,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalogs.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(bundle)
+	}))
+	defer server.Close()
+	allowSeedBundleHosts(t, "127.0.0.1")
+
+	catalog := &SeedCatalog{
+		Source:            server.URL + "/catalogs.tar.gz#energy",
+		PolicyDefinitions: []map[string]any{{"@id": "own"}},
+	}
+	if err := catalog.fetchSource(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog.Assets) != 2 || catalog.Assets[0]["@id"] != "meters" {
+		t.Errorf("unexpected assets %v", catalog.Assets)
+	}
+	if len(catalog.PolicyDefinitions) != 1 || catalog.PolicyDefinitions[0]["@id"] != "own" {
+		t.Errorf("policies given in the request should take precedence, got %v", catalog.PolicyDefinitions)
+	}
+	if len(catalog.ContractDefinitions) != 1 {
+		t.Errorf("unexpected contract definitions %v", catalog.ContractDefinitions)
+	}
+
+	for _, source := range []string{server.URL + "/missing.tar.gz", server.URL + "/catalogs.tar.gz#solar"} {
+		if err := (&SeedCatalog{Source: source}).fetchSource(context.Background()); err == nil {
+			t.Errorf("%s: expected an error", source)
+		}
+	}
+}
+
+func TestFetchSeedBundleRestrictsHosts(t *testing.T) {
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host == "catalogs.example.com" {
+			return []string{"203.0.113.10"}, nil
+		}
+		return []string{"10.0.0.12"}, nil
+	}
+	t.Cleanup(func() { lookupHost = net.DefaultResolver.LookupHost })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://vault.vault.svc/v1/secret", http.StatusFound)
+	}))
+	defer server.Close()
+
+	for _, source := range []string{"http://127.0.0.1:8200/bundle.tar.gz", "http://169.254.169.254/latest/meta-data.tar.gz",
+		"http://vault.vault.svc/bundle.tar.gz"} {
+		if err := (&SeedCatalog{Source: source}).fetchSource(context.Background()); err == nil || !strings.Contains(err.Error(), "internal address") {
+			t.Errorf("%s: expected internal hosts to be rejected, got %v", source, err)
+		}
+	}
+	if err := seedBundles.allowed(context.Background(), "catalogs.example.com"); err != nil {
+		t.Errorf("expected public hosts to be allowed, got %v", err)
+	}
+
+	allowSeedBundleHosts(t, "127.0.0.1", ".example.com")
+	if err := seedBundles.allowed(context.Background(), "git.example.com"); err != nil {
+		t.Errorf("expected hosts of allowed domains to be allowed, got %v", err)
+	}
+	for _, host := range []string{"example.org", "catalogs.example.com.evil.org", "10.0.0.12"} {
+		if err := seedBundles.allowed(context.Background(), host); err == nil {
+			t.Errorf("%s: expected hosts that aren't allowed to be rejected", host)
+		}
+	}
+	if err := (&SeedCatalog{Source: server.URL + "/bundle.tar.gz"}).fetchSource(context.Background()); err == nil ||
+		!strings.Contains(err.Error(), "vault.vault.svc") {
+		t.Errorf("expected redirects to hosts that aren't allowed to be rejected, got %v", err)
+	}
+}
+
+func TestFetchSeedBundleChecksDialedAddress(t *testing.T) {
+	// the check resolves localhost to a public address, the connection to the server at 127.0.0.1
+	lookupHost = func(context.Context, string) ([]string, error) { return []string{"203.0.113.10"}, nil }
+	t.Cleanup(func() { lookupHost = net.DefaultResolver.LookupHost })
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		_, _ = w.Write(tarball(t, map[string]string{"assets/meter.json": `{"@id": "meter"}`}))
+	}))
+	defer server.Close()
+	transport, err := HttpTargetConfig{}.transport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := seedBundles
+	seedBundles = seedBundleFetcher{client: http.Client{Transport: transport}}
+	t.Cleanup(func() { seedBundles = previous })
+	source := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/bundle.tar.gz"
+
+	if err := seedBundles.allowed(context.Background(), "localhost"); err != nil {
+		t.Fatalf("expected the check to pass with the public address, got %v", err)
+	}
+	if err := (&SeedCatalog{Source: source}).fetchSource(context.Background()); err == nil || !strings.Contains(err.Error(), "internal address") || requested != 0 {
+		t.Errorf("expected the connection to the internal address to be refused, got %v after %d requests", err, requested)
+	}
+	for _, address := range []string{"100.64.0.1", "100.127.255.254"} {
+		if !isInternalAddress(net.ParseIP(address)) {
+			t.Errorf("expected the shared address %s to be internal", address)
+		}
+	}
+
+	allowSeedBundleHosts(t, "localhost")
+	catalog := &SeedCatalog{Source: source}
+	if err := catalog.fetchSource(context.Background()); err != nil || len(catalog.Assets) != 1 {
+		t.Errorf("expected listed hosts to be fetched from, got %v", err)
+	}
+}
+
+func TestFetchSeedBundleLimitsExtractedSize(t *testing.T) {
+	allowSeedBundleHosts(t, "127.0.0.1")
+	padding := strings.Repeat("0", maxSeedBundleFileSize-100)
+	bombs := map[string]map[string]string{
+		"oversized file": {"catalogs/assets/large.json": strings.Repeat("0", maxSeedBundleFileSize+1)},
+		"bomb":           {},
+	}
+	for i := 0; i*len(padding) <= maxSeedBundleExtractedSize; i++ {
+		bombs["bomb"][fmt.Sprintf("catalogs/assets/%d.json", i)] = padding
+	}
+	for name, files := range bombs {
+		bundle := tarball(t, files)
+		if len(bundle) > maxSeedBundleSize {
+			t.Fatalf("%s: the archive of %d bytes should stay below the limit of the download", name, len(bundle))
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(bundle)
+		}))
+		err := (&SeedCatalog{Source: server.URL + "/catalogs.tar.gz"}).fetchSource(context.Background())
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), "bytes") {
+			t.Errorf("%s: expected the bundle to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestGitArchiveUrl(t *testing.T) {
+	for source, expected := range map[string]string{
+		"https://github.com/acme/catalogs.git?ref=v1.2":     "https://codeload.github.com/acme/catalogs/tar.gz/v1.2",
+		"https://gitlab.example.com/data/catalogs?ref=main": "https://gitlab.example.com/data/catalogs/-/archive/main/catalogs-main.tar.gz",
+		"https://github.com/acme/catalogs":                  "",
+		"https://git.example.com/acme/catalogs.git?ref=v1":  "",
+	} {
+		repo, err := url.Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archiveUrl, err := gitArchiveUrl(repo)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", source, archiveUrl)
+			}
+			continue
+		}
+		if err != nil || archiveUrl != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", source, expected, archiveUrl, err)
+		}
+	}
+}
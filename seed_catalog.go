@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SeedCatalog replaces the embedded demo catalog with the participant's own entities, given as the JSON-LD bodies
+// the management API accepts. Lists left empty keep the embedded entities.
+type SeedCatalog struct {
+	// Source references a seed bundle the entities not given here are loaded from
+	Source              string           `json:"source,omitempty"`
+	Assets              []map[string]any `json:"assets,omitempty"`
+	PolicyDefinitions   []map[string]any `json:"policyDefinitions,omitempty"`
+	ContractDefinitions []map[string]any `json:"contractDefinitions,omitempty"`
+}
+
+// validate checks that every entity has an ID, unique within its list, so contract definitions can reference them
+// and seeding can be resumed. Contract definitions can't be given both here and as contractDefinitions specs.
+func (c *SeedCatalog) validate(specs []ContractDefinitionSpec) []fieldError {
+	if c == nil {
+		return nil
+	}
+	var fields []fieldError
+	for name, entities := range map[string][]map[string]any{
+		"catalog.assets":              c.Assets,
+		"catalog.policyDefinitions":   c.PolicyDefinitions,
+		"catalog.contractDefinitions": c.ContractDefinitions,
+	} {
+		seen := make(map[string]bool, len(entities))
+		for i, entity := range entities {
+			field := fmt.Sprintf("%s[%d]", name, i)
+			id, _ := entity["@id"].(string)
+			switch {
+			case id == "":
+				fields = append(fields, fieldError{field, "@id is required"})
+			case seen[id]:
+				fields = append(fields, fieldError{field, fmt.Sprintf("duplicate @id %q", id)})
+			}
+			seen[id] = true
+		}
+	}
+	if len(c.ContractDefinitions) > 0 && len(specs) > 0 {
+		fields = append(fields, fieldError{"catalog.contractDefinitions", "can't be combined with contractDefinitions"})
+	}
+	return fields
+}
+
+// catalogBodies encodes the entities as request bodies of the management API, or returns the embedded bodies if no
+// entities were given.
+func catalogBodies(entities []map[string]any, embedded []string) ([]string, error) {
+	if len(entities) == 0 {
+		return embedded, nil
+	}
+	bodies := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		body, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(body))
+	}
+	return bodies, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Formats of pre-signed credentials, those of JWTs are parsed for their types
+var presignedCredentialFormats = []string{credentialFormat, "VC2_0_JOSE", "VC1_0_LD"}
+
+// PresignedCredential is a verifiable credential signed outside the dataspace's issuer, e.g. for demos without a live
+// issuer, which is stored in the participant's identity hub as it is.
+type PresignedCredential struct {
+	// Id identifies the credential in the identity hub, derived from the raw credential if empty
+	Id string `json:"id,omitempty"`
+	// RawVc is the signed credential, e.g. a JWT
+	RawVc string `json:"rawVc"`
+	// Format is VC1_0_JWT, VC2_0_JOSE or VC1_0_LD, VC1_0_JWT if empty
+	Format string `json:"format,omitempty"`
+}
+
+func (c PresignedCredential) validate() error {
+	if strings.TrimSpace(c.RawVc) == "" {
+		return fmt.Errorf("presigned credential: rawVc is required")
+	}
+	if c.Format != "" && !slices.Contains(presignedCredentialFormats, c.Format) {
+		return fmt.Errorf("presigned credential: format must be one of %s", strings.Join(presignedCredentialFormats, ", "))
+	}
+	if c.format() != "VC1_0_LD" && strings.Count(strings.TrimSpace(c.RawVc), ".") != 2 {
+		return fmt.Errorf("presigned credential: %s credentials must be JWTs", c.format())
+	}
+	return nil
+}
+
+func (c PresignedCredential) format() string {
+	if c.Format == "" {
+		return credentialFormat
+	}
+	return c.Format
+}
+
+// id returns the ID of the credential, the same for every run of the seed step.
+func (c PresignedCredential) id() string {
+	if c.Id != "" {
+		return c.Id
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(c.RawVc)))
+	return "presigned-" + hex.EncodeToString(sum[:8])
+}
+
+// parsed returns the credential of a JWT, or the raw credential of other formats, nil if it can't be parsed.
+func (c PresignedCredential) parsed() json.RawMessage {
+	raw := strings.TrimSpace(c.RawVc)
+	if c.format() == "VC1_0_LD" {
+		if json.Valid([]byte(raw)) {
+			return json.RawMessage(raw)
+		}
+		return nil
+	}
+	parts := strings.Split(raw, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+	var claims struct {
+		Vc json.RawMessage `json:"vc"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	// VC 2.0 JOSE credentials are the payload themselves
+	if len(claims.Vc) > 0 {
+		return claims.Vc
+	}
+	return payload
+}
+
+// presignedCredentials returns the credentials the participant is seeded with: those of the definition and those of
+// the seed directory of its flavor named <participant>.<name>.jwt, read on every seeding.
+func presignedCredentials(definition ParticipantDefinition) ([]PresignedCredential, error) {
+	credentials := slices.Clone(definition.PresignedCredentials)
+	dir := seedDirOf(definition)
+	if dir == "" {
+		return credentials, nil
+	}
+	names, err := filepath.Glob(filepath.Join(dir, definition.ParticipantName+".*.jwt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("load presigned credential: %w", err)
+		}
+		credential := PresignedCredential{RawVc: strings.TrimSpace(string(content))}
+		if err := credential.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+// storePresignedCredentials adds the credentials the participant context doesn't hold yet to its identity hub. Every
+// credential is reported in the participant's seeding status by its most specific type.
+func storePresignedCredentials(ctx context.Context, definition ParticipantDefinition, identityHub api.IdentityApi, credentials []PresignedCredential, statusChecker *status.StatusChecker) error {
+	participantContextId := base64.StdEncoding.EncodeToString([]byte(definition.Did))
+	held, err := identityHub.QueryCredentials(ctx, participantContextId, "")
+	if err != nil {
+		return err
+	}
+	heldIds := make(map[string]bool, len(held))
+	for _, resource := range held {
+		heldIds[resource.Id] = true
+	}
+	for _, credential := range credentials {
+		parsed := credential.parsed()
+		report := func(state string, message string) {
+			statusChecker.SetCredential(definition.ParticipantName, status.CredentialStatus{Type: presignedCredentialType(parsed, credential.id()), State: state, Message: message})
+		}
+		if heldIds[credential.id()] {
+			report(status.CredentialIssued, "")
+			continue
+		}
+		err := identityHub.AddCredential(ctx, participantContextId, api.CredentialManifest{
+			Id:                   credential.id(),
+			ParticipantContextId: participantContextId,
+			VerifiableCredentialContainer: api.CredentialContainer{
+				RawVc:      strings.TrimSpace(credential.RawVc),
+				Format:     credential.format(),
+				Credential: parsed,
+			},
+		})
+		if err != nil {
+			report(status.CredentialFailed, err.Error())
+			return fmt.Errorf("store presigned credential %s: %w", credential.id(), err)
+		}
+		report(status.CredentialIssued, "")
+	}
+	fmt.Println("presigned credentials stored for participant", definition.ParticipantName)
+	return nil
+}
+
+// presignedCredentialType returns the last type of the credential, which is the most specific one, or the ID of
+// credentials whose types are unknown.
+func presignedCredentialType(parsed json.RawMessage, id string) string {
+	var credential api.Credential
+	if json.Unmarshal(parsed, &credential) != nil || len(credential.Types) == 0 {
+		return id
+	}
+	return credential.Types[len(credential.Types)-1]
+}
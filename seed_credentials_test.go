@@ -0,0 +1,88 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func presignedJwt(t *testing.T, types ...string) string {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{"iss": "did:web:issuer", "vc": map[string]any{"type": types}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJFUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestPresignedCredentials(t *testing.T) {
+	defer func(dir string) { seedDir = dir }(seedDir)
+	seedDir = t.TempDir()
+	membership := presignedJwt(t, "VerifiableCredential", "MembershipCredential")
+	for name, content := range map[string]string{"acme.membership.jwt": membership + "\n", "other.membership.jwt": membership} {
+		if err := os.WriteFile(filepath.Join(seedDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	definition := ParticipantDefinition{ParticipantName: "acme", PresignedCredentials: []PresignedCredential{{Id: "processor", RawVc: presignedJwt(t, "VerifiableCredential", "DataProcessorCredential")}}}
+
+	credentials, err := presignedCredentials(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 2 || credentials[0].id() != "processor" || credentials[1].RawVc != membership {
+		t.Fatalf("expected the credential of the definition and the participant's one of the seed directory, got %+v", credentials)
+	}
+	if got := presignedCredentialType(credentials[1].parsed(), credentials[1].id()); got != "MembershipCredential" {
+		t.Errorf("expected the most specific type, got %q", got)
+	}
+	if credentials[1].id() != (PresignedCredential{RawVc: membership}).id() {
+		t.Error("expected derived IDs to be stable")
+	}
+
+	for _, invalid := range []PresignedCredential{{}, {RawVc: "not-a-jwt"}, {RawVc: membership, Format: "PDF"}} {
+		if err := invalid.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestStorePresignedCredentials(t *testing.T) {
+	var added []api.CredentialManifest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"id":"processor","state":300}]`))
+			return
+		}
+		var manifest api.CredentialManifest
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &manifest)
+		added = append(added, manifest)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme"}
+	credentials := []PresignedCredential{
+		{Id: "processor", RawVc: presignedJwt(t, "VerifiableCredential", "DataProcessorCredential")},
+		{RawVc: presignedJwt(t, "VerifiableCredential", "MembershipCredential")},
+	}
+
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	if err := storePresignedCredentials(context.Background(), definition, &api.ApiClient{BaseUrl: server.URL}, credentials, checker); err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0].Id != credentials[1].id() || added[0].VerifiableCredentialContainer.Format != credentialFormat {
+		t.Fatalf("expected only the credential not held yet to be added, got %+v", added)
+	}
+	if len(added[0].VerifiableCredentialContainer.Credential) == 0 || added[0].ParticipantContextId != base64.StdEncoding.EncodeToString([]byte("did:web:acme")) {
+		t.Errorf("expected the parsed credential in the participant context, got %+v", added[0])
+	}
+}
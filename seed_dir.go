@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// seedDir is a directory, e.g. a mounted ConfigMap, the default seed resources are loaded from instead of the
+// embedded ones. It is read on every seeding, so that resources added to it are seeded without a restart.
+var seedDir string
+
+// JSON-LD types the resources in the seed directory are classified by
+const (
+	seedTypeAsset              = "Asset"
+	seedTypePolicyDefinition   = "PolicyDefinition"
+	seedTypeContractDefinition = "ContractDefinition"
+)
+
+// defaultCatalog returns the resources participants are seeded with unless their definition brings a catalog: those
+// of the seed directory dir, or the embedded demo resources. Types without any resource in the directory keep the
+// embedded ones.
+func defaultCatalog(dir string) (seedCatalog, error) {
+	catalog := embeddedCatalog()
+	if dir == "" {
+		return catalog, nil
+	}
+	loaded, err := loadSeedDir(dir)
+	if err != nil {
+		return seedCatalog{}, fmt.Errorf("load seed resources from %s: %w", dir, err)
+	}
+	if len(loaded.assets) > 0 {
+		catalog.assets = loaded.assets
+	}
+	if len(loaded.policies) > 0 {
+		catalog.policies = loaded.policies
+	}
+	if len(loaded.contractDefinitions) > 0 {
+		catalog.contractDefinitions = loaded.contractDefinitions
+	}
+	return catalog, nil
+}
+
+// embeddedCatalog returns the demo resources embedded in the binary.
+func embeddedCatalog() seedCatalog {
+	return seedCatalog{
+		assets:              []string{asset1Json, asset2json},
+		policies:            []string{policyDataProcessorJson, policyMembershipJson, policySensitiveDataJson},
+		contractDefinitions: []string{defRequireMembership, defSensitive},
+	}
+}
+
+// loadSeedDir reads the *.json files of the directory, each holding a resource or an array of resources, and
+// classifies them by their @type. Resources are created in the order of their file names.
+func loadSeedDir(dir string) (seedCatalog, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return seedCatalog{}, err
+	}
+	// ConfigMap mounts link the keys to files in hidden directories, which the pattern skips
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return seedCatalog{}, err
+	}
+	sort.Strings(names)
+	var catalog seedCatalog
+	for _, name := range names {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return seedCatalog{}, err
+		}
+		entities, err := parseBundleFile(content)
+		if err != nil {
+			return seedCatalog{}, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		for _, entity := range entities {
+			body, err := json.Marshal(entity)
+			if err != nil {
+				return seedCatalog{}, err
+			}
+			entityType, _ := entity["@type"].(string)
+			switch strings.TrimPrefix(entityType, "edc:") {
+			case seedTypeAsset:
+				catalog.assets = append(catalog.assets, string(body))
+			case seedTypePolicyDefinition:
+				catalog.policies = append(catalog.policies, string(body))
+			case seedTypeContractDefinition:
+				catalog.contractDefinitions = append(catalog.contractDefinitions, string(body))
+			default:
+				return seedCatalog{}, fmt.Errorf("%s: unknown @type %q, expected %s, %s or %s", filepath.Base(name), entityType,
+					seedTypeAsset, seedTypePolicyDefinition, seedTypeContractDefinition)
+			}
+		}
+	}
+	return catalog, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeedDir(t *testing.T) {
+	defer func(dir string) { seedDir = dir }(seedDir)
+	seedDir = t.TempDir()
+	files := map[string]string{
+		"a-asset.json":    `{"@id": "asset-3", "@type": "Asset"}`,
+		"b-assets.json":   `[{"@id": "asset-4", "@type": "edc:Asset"}, {"@id": "def-3", "@type": "ContractDefinition"}]`,
+		"notes.txt":       `not a resource`,
+		"..data/old.json": `{"@id": "stale", "@type": "Asset"}`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(seedDir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(seedDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	catalog, err := connectorCatalog(ParticipantDefinition{ParticipantName: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog.assets) != 2 || !strings.Contains(catalog.assets[0], "asset-3") || !strings.Contains(catalog.assets[1], "asset-4") {
+		t.Errorf("unexpected assets %v", catalog.assets)
+	}
+	if len(catalog.contractDefinitions) != 1 || !strings.Contains(catalog.contractDefinitions[0], "def-3") {
+		t.Errorf("unexpected contract definitions %v", catalog.contractDefinitions)
+	}
+	if len(catalog.policies) != 3 {
+		t.Errorf("expected the embedded policies without policies in the directory, got %d", len(catalog.policies))
+	}
+
+	if err := os.WriteFile(filepath.Join(seedDir, "c-unknown.json"), []byte(`{"@id": "x", "@type": "Dataset"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connectorCatalog(ParticipantDefinition{ParticipantName: "alice"}); err == nil || !strings.Contains(err.Error(), "c-unknown.json") {
+		t.Errorf("expected resources of unknown types to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+)
+
+// SeedGeneratorOptions replaces the fixed demo asset IDs, titles and descriptions with generated ones.
+type SeedGeneratorOptions struct {
+	// Seed makes the generated content reproducible. The participant name is mixed in, so participants sharing a
+	// seed still get distinct catalogs.
+	Seed int64 `json:"seed"`
+}
+
+var generatorAdjectives = []string{
+	"Aggregated", "Anonymized", "Curated", "Historical", "Hourly", "Raw", "Real-time", "Regional", "Validated", "Weekly",
+}
+
+var generatorNouns = []string{
+	"Energy Consumption", "Logistics Events", "Maintenance Logs", "Production Metrics", "Quality Reports",
+	"Sensor Readings", "Supply Chain Records", "Traffic Counts", "Warehouse Inventory", "Weather Observations",
+}
+
+// generateCatalog rewrites the given assets with generated IDs, titles and descriptions and updates the asset
+// selectors of the contract definitions accordingly.
+func generateCatalog(participantName string, options SeedGeneratorOptions, assets []string, contractDefinitions []string) ([]string, []string, error) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(participantName))
+	rng := rand.New(rand.NewSource(options.Seed ^ int64(hash.Sum64())))
+
+	renamed := make(map[string]string)
+	generatedAssets := make([]string, 0, len(assets))
+	for _, body := range assets {
+		var asset map[string]any
+		if err := json.Unmarshal([]byte(body), &asset); err != nil {
+			return nil, nil, fmt.Errorf("parse asset: %w", err)
+		}
+		oldId, _ := asset["@id"].(string)
+		newId := fmt.Sprintf("%s-%s-%04x", participantName, oldId, rng.Intn(0x10000))
+		title := generatorAdjectives[rng.Intn(len(generatorAdjectives))] + " " + generatorNouns[rng.Intn(len(generatorNouns))]
+
+		properties, _ := asset["properties"].(map[string]any)
+		if properties == nil {
+			properties = make(map[string]any)
+		}
+		properties["name"] = title
+		properties["description"] = fmt.Sprintf("%s provided by %s.", title, participantName)
+		asset["properties"] = properties
+		asset["@id"] = newId
+		renamed[oldId] = newId
+
+		generated, err := json.Marshal(asset)
+		if err != nil {
+			return nil, nil, err
+		}
+		generatedAssets = append(generatedAssets, string(generated))
+	}
+
+	generatedDefinitions := make([]string, 0, len(contractDefinitions))
+	for _, body := range contractDefinitions {
+		var definition map[string]any
+		if err := json.Unmarshal([]byte(body), &definition); err != nil {
+			return nil, nil, fmt.Errorf("parse contract definition: %w", err)
+		}
+		renameSelectedAssets(definition["assetsSelector"], renamed)
+		generated, err := json.Marshal(definition)
+		if err != nil {
+			return nil, nil, err
+		}
+		generatedDefinitions = append(generatedDefinitions, string(generated))
+	}
+	return generatedAssets, generatedDefinitions, nil
+}
+
+// renameSelectedAssets replaces asset IDs in a single criterion or a list of criteria, including the ID lists of
+// "in" criteria.
+func renameSelectedAssets(selector any, renamed map[string]string) {
+	switch s := selector.(type) {
+	case []any:
+		for _, criterion := range s {
+			renameSelectedAssets(criterion, renamed)
+		}
+	case map[string]any:
+		switch right := s["operandRight"].(type) {
+		case string:
+			if newId, ok := renamed[right]; ok {
+				s["operandRight"] = newId
+			}
+		case []any:
+			for i, id := range right {
+				if id, ok := id.(string); ok {
+					if newId, ok := renamed[id]; ok {
+						right[i] = newId
+					}
+				}
+			}
+		}
+	}
+}
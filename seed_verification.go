@@ -0,0 +1,63 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// verifySeeding checks through the participant's management API that the seeded assets, policies and contract
+// definitions exist, and that the participant's own catalog, requested via DSP, offers the seeded assets. Failing
+// requests fail the verification as well, so that a participant whose APIs misbehave isn't reported READY.
+func verifySeeding(ctx context.Context, mgmtApi api.ManagementApi, definition ParticipantDefinition, seeded status.SeededResources) status.CatalogVerification {
+	verification := status.CatalogVerification{CheckedAt: time.Now().UTC()}
+	var problems []string
+	check := func(kind string, ids []string, get func(context.Context, string) (string, error)) {
+		for _, id := range ids {
+			if _, err := get(ctx, id); api.IsNotFound(err) {
+				verification.Missing = append(verification.Missing, kind+" "+id)
+			} else if err != nil {
+				problems = append(problems, fmt.Sprintf("get %s %s: %v", kind, id, err))
+			}
+		}
+	}
+	check("asset", seeded.Assets, mgmtApi.GetAsset)
+	check("policy", seeded.Policies, mgmtApi.GetPolicy)
+	check("contractDefinition", seeded.ContractDefinitions, mgmtApi.GetContractDefinition)
+
+	// assets are only offered through contract definitions
+	if len(seeded.Assets) > 0 && len(seeded.ContractDefinitions) > 0 {
+		offered, err := catalogDatasets(ctx, mgmtApi, definition)
+		if err != nil {
+			problems = append(problems, "request catalog: "+err.Error())
+		}
+		for _, id := range seeded.Assets {
+			if err == nil && !offered[id] {
+				verification.Missing = append(verification.Missing, "dataset "+id)
+			}
+		}
+	}
+
+	verification.Verified = len(verification.Missing) == 0 && len(problems) == 0
+	if len(verification.Missing) > 0 {
+		problems = append([]string{"missing " + strings.Join(verification.Missing, ", ")}, problems...)
+	}
+	verification.Message = strings.Join(problems, "; ")
+	return verification
+}
+
+// catalogDatasets returns the IDs of the datasets the participant's catalog offers to the participant itself.
+func catalogDatasets(ctx context.Context, mgmtApi api.ManagementApi, definition ParticipantDefinition) (map[string]bool, error) {
+	offers, err := requestCatalog(ctx, mgmtApi, protocolEndpointUrl(definition), definition.Did)
+	if err != nil {
+		return nil, err
+	}
+	datasets := make(map[string]bool, len(offers))
+	for _, offer := range offers {
+		datasets[offer.assetId] = true
+	}
+	return datasets, nil
+}
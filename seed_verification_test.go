@@ -0,0 +1,42 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestVerifySeeding(t *testing.T) {
+	catalog := `{"dcat:dataset":[{"@id":"asset-1","odrl:hasPolicy":{"@id":"offer-1"}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalog/request":
+			_, _ = w.Write([]byte(catalog))
+		case "/assets/asset-1", "/assets/asset-2", "/policydefinitions/policy-1", "/contractdefinitions/cd-1":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	mgmtApi := &api.ApiClient{BaseUrl: server.URL}
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme"}
+
+	verification := verifySeeding(context.Background(), mgmtApi, definition, status.SeededResources{
+		Assets: []string{"asset-1"}, Policies: []string{"policy-1"}, ContractDefinitions: []string{"cd-1"},
+	})
+	if !verification.Verified || len(verification.Missing) > 0 {
+		t.Errorf("expected the seeded catalog to be verified, got %+v", verification)
+	}
+
+	verification = verifySeeding(context.Background(), mgmtApi, definition, status.SeededResources{
+		Assets: []string{"asset-1", "asset-2"}, Policies: []string{"policy-2"}, ContractDefinitions: []string{"cd-1"},
+	})
+	if verification.Verified || !slices.Equal(verification.Missing, []string{"policy policy-2", "dataset asset-2"}) {
+		t.Errorf("expected the missing policy and dataset, got %+v", verification)
+	}
+}
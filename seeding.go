@@ -0,0 +1,542 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Completed seed steps and the definition they were seeded from are kept in this ConfigMap, so seeding resumes where
+// it stopped instead of starting over.
+const seedingStateConfigMapName = "provisioner-seeding"
+
+const (
+	seedingDefinitionKey = "definition"
+	// seedingResourcesKey holds the IDs of the seeded entities as JSON
+	seedingResourcesKey = "resources"
+	// Keys of completed steps are prefixed, their value is the completion time
+	seedingStepPrefix = "step."
+)
+
+// Seed steps, in the order they are reported
+const (
+	seedStepAssets              = "assets"
+	seedStepPolicies            = "policies"
+	seedStepContractDefinitions = "contractDefinitions"
+	seedStepParticipant         = "participant"
+	seedStepDidDocument         = "didDocument"
+	seedStepIssuer              = "issuer"
+	seedStepCredentials         = "credentials"
+	// seedStepPresignedCredentials stores credentials signed outside the issuer, see PresignedCredential
+	seedStepPresignedCredentials = "presignedCredentials"
+)
+
+var seedStepNames = []string{seedStepAssets, seedStepPolicies, seedStepContractDefinitions, seedStepParticipant, seedStepDidDocument, seedStepIssuer, seedStepCredentials, seedStepPresignedCredentials}
+
+// Progress steps of the status reporting the seed steps. The secret of the participant is stored by the participant
+// step itself.
+var seedProgressSteps = map[string]string{
+	seedStepAssets:              status.StepAssetsSeeded,
+	seedStepPolicies:            status.StepPoliciesSeeded,
+	seedStepContractDefinitions: status.StepContractDefinitionsSeeded,
+	seedStepParticipant:         status.StepParticipantCreated,
+}
+
+// skippedProgress returns the progress steps the provisioning of the definition doesn't run.
+func skippedProgress(definition ParticipantDefinition) []string {
+	var skipped []string
+	for step, progress := range seedProgressSteps {
+		if !definition.Seed.enabled() || !definition.seeds(step) {
+			skipped = append(skipped, progress)
+			if step == seedStepParticipant {
+				skipped = append(skipped, status.StepSecretStored)
+			}
+		}
+	}
+	return skipped
+}
+
+// SeedOptions selects the seed steps run for a participant. It is given as false to skip seeding, e.g. for production
+// participants that must not get the demo catalog, or as an object listing the steps to run.
+type SeedOptions struct {
+	// Skip disables seeding, set by "seed": false
+	Skip bool `json:"skip,omitempty"`
+	// Steps are run in their usual order, all of them if empty
+	Steps []string `json:"steps,omitempty"`
+}
+
+func (o *SeedOptions) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*o = SeedOptions{Skip: !enabled}
+		return nil
+	}
+	type options SeedOptions
+	return json.Unmarshal(data, (*options)(o))
+}
+
+// enabled reports whether the participant is seeded at all.
+func (o *SeedOptions) enabled() bool {
+	return o == nil || !o.Skip
+}
+
+// runs reports whether the step was selected.
+func (o *SeedOptions) runs(step string) bool {
+	return o == nil || len(o.Steps) == 0 || slices.Contains(o.Steps, step)
+}
+
+func (o *SeedOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, step := range o.Steps {
+		if !slices.Contains(seedStepNames, step) {
+			return fmt.Errorf("unknown seed step %q, must be one of %s", step, strings.Join(seedStepNames, ", "))
+		}
+	}
+	return nil
+}
+
+// seedBackoff bounds the retries of a failing seed step. Only connection failures and 408, 429 and 5xx responses are
+// retried, other errors won't go away by trying again.
+var seedBackoff = backoff{attempts: 5, initial: 2 * time.Second, max: 30 * time.Second}
+
+type backoff struct {
+	attempts int
+	initial  time.Duration
+	max      time.Duration
+}
+
+// delay returns how long to wait before the given retry, starting at 1.
+func (b backoff) delay(retry int) time.Duration {
+	delay := b.initial
+	for i := 1; i < retry && delay < b.max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.max)
+}
+
+// seedingState tracks which seed steps of a participant completed and what they created.
+type seedingState struct {
+	definition ParticipantDefinition
+	completed  map[string]string
+	resources  status.SeededResources
+}
+
+// loadSeedingState returns the seeding state of the participant, empty if seeding never started.
+func loadSeedingState(c client.Client, ctx context.Context, namespace string) (seedingState, error) {
+	state := seedingState{completed: make(map[string]string)}
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: seedingStateConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if definition := configMap.Data[seedingDefinitionKey]; definition != "" {
+		if err := json.Unmarshal([]byte(definition), &state.definition); err != nil {
+			return state, fmt.Errorf("parse seeding state of %s: %w", namespace, err)
+		}
+	}
+	if resources := configMap.Data[seedingResourcesKey]; resources != "" {
+		if err := json.Unmarshal([]byte(resources), &state.resources); err != nil {
+			return state, fmt.Errorf("parse seeding state of %s: %w", namespace, err)
+		}
+	}
+	for key, completedAt := range configMap.Data {
+		if step, ok := strings.CutPrefix(key, seedingStepPrefix); ok {
+			state.completed[step] = completedAt
+		}
+	}
+	return state, nil
+}
+
+func storeSeedingState(c client.Client, ctx context.Context, state seedingState) error {
+	// the keys are in the credentials secret, seeding reads them from there
+	definition, err := json.Marshal(state.definition.redacted())
+	if err != nil {
+		return err
+	}
+	resources, err := json.Marshal(state.resources)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{seedingDefinitionKey: string(definition), seedingResourcesKey: string(resources)}
+	for step, completedAt := range state.completed {
+		data[seedingStepPrefix+step] = completedAt
+	}
+	return applyResource(c, ctx, &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      seedingStateConfigMapName,
+			Namespace: state.definition.ParticipantName,
+		},
+		Data: data,
+	})
+}
+
+// seedStep creates one kind of entity in the participant's components. Steps are idempotent, entities that already
+// exist are left alone, so a step interrupted half-way can run again.
+type seedStep struct {
+	name string
+	run  func(ctx context.Context) error
+	// requires lists the steps that must have completed before this one runs
+	requires []string
+}
+
+// seedSteps returns the seed steps of the participant, which record the IDs of the entities they create in seeded.
+func seedSteps(c client.Client, definition ParticipantDefinition, clients seedingClients, creds participantCredentials, issuer IssuerConfig, secrets secretStore, statusChecker *status.StatusChecker, seeded *status.SeededResources) ([]seedStep, error) {
+	catalog, err := connectorCatalog(definition)
+	if err != nil {
+		return nil, err
+	}
+	mgmtApi := managementApi(definition, clients, creds)
+	// the IDs are recorded as the entities are created, so a failing step leaves the ones it created in the state
+	create := func(bodies []string, send func(context.Context, string) (string, error), ids *[]string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			*ids = make([]string, 0, len(bodies))
+			for i, body := range bodies {
+				response, err := send(ctx, body)
+				if err != nil {
+					return fmt.Errorf("created %d of %d: %w", i, len(bodies), err)
+				}
+				if id := createdId(response, body); id != "" {
+					*ids = append(*ids, id)
+				}
+			}
+			return nil
+		}
+	}
+	steps := []seedStep{
+		{name: seedStepAssets, run: create(catalog.assets, mgmtApi.CreateAsset, &seeded.Assets)},
+		{name: seedStepPolicies, run: create(catalog.policies, mgmtApi.CreatePolicy, &seeded.Policies)},
+		{name: seedStepContractDefinitions, requires: []string{seedStepAssets, seedStepPolicies}, run: func(ctx context.Context) error {
+			if err := validateCatalogReferences(ctx, mgmtApi, catalog.assets, catalog.policies, catalog.contractDefinitions); err != nil {
+				return err
+			}
+			return create(catalog.contractDefinitions, mgmtApi.CreateContractDefinition, &seeded.ContractDefinitions)(ctx)
+		}},
+		{name: seedStepParticipant, run: func(ctx context.Context) error {
+			tracked := &progressSecretStore{secretStore: secrets, statusChecker: statusChecker, participant: definition.ParticipantName}
+			keys, err := loadKeyPair(c, ctx, definition.ParticipantName)
+			if err != nil {
+				return err
+			}
+			contextId, clientId, err := seedIdentityHubData(ctx, definition, clients, creds, keys, tracked)
+			if err != nil {
+				return err
+			}
+			seeded.ParticipantContextId = contextId
+			// the client of an existing participant context is only known from the run that created it
+			if clientId != "" {
+				seeded.StsClientId = clientId
+			}
+			if !tracked.stored {
+				statusChecker.SetProgress(definition.ParticipantName, status.StepSecretStored, status.StepCompleted, "stored when the participant was created")
+			}
+			return nil
+		}},
+	}
+	// issuers resolve the DID of holders, so a hosted document is published before they are registered
+	holderRequires := []string{seedStepParticipant}
+	if hostsDid(definition.Did) {
+		steps = append(steps, seedStep{name: seedStepDidDocument, requires: []string{seedStepParticipant}, run: func(ctx context.Context) error {
+			return publishDidDocument(ctx, c, definition, identityApi(definition, clients, creds))
+		}})
+		holderRequires = append(holderRequires, seedStepDidDocument)
+	}
+	if !issuer.Disabled {
+		// holders are registered once their participant context exists
+		steps = append(steps, seedStep{name: seedStepIssuer, requires: holderRequires, run: func(ctx context.Context) error {
+			return seedIssuerData(ctx, definition, clients, issuer)
+		}})
+		if types := credentialTypes(definition, issuer); len(types) > 0 {
+			steps = append(steps, seedStep{name: seedStepCredentials, requires: []string{seedStepIssuer}, run: func(ctx context.Context) error {
+				return requestCredentials(ctx, definition, clients, creds, issuer, types, statusChecker)
+			}})
+		}
+	}
+	presigned, err := presignedCredentials(definition)
+	if err != nil {
+		return nil, err
+	}
+	if len(presigned) > 0 {
+		steps = append(steps, seedStep{name: seedStepPresignedCredentials, requires: []string{seedStepParticipant}, run: func(ctx context.Context) error {
+			return storePresignedCredentials(ctx, definition, identityApi(definition, clients, creds), presigned, statusChecker)
+		}})
+	}
+	return steps, nil
+}
+
+// onDeploymentReady seeds the participant once its deployments are ready. Steps completed by an earlier run are
+// skipped, failing steps are retried with backoff. Independent steps run concurrently, those not depending on a failed
+// one still run.
+func onDeploymentReady(ctx context.Context, c client.Client, definition ParticipantDefinition, statusChecker *status.StatusChecker, clients seedingClients, creds participantCredentials, dataspace DataspaceConfig) error {
+	fmt.Println("Deployments ready in namespace", definition.ParticipantName, "-> seeding data")
+	statusChecker.SetSeeding(definition.ParticipantName, status.SeedingRunning, "")
+	fail := func(err error) error {
+		err = unreachableRemediation(definition, err)
+		fmt.Println(err)
+		statusChecker.SetSeeding(definition.ParticipantName, status.SeedingFailed, err.Error())
+		return err
+	}
+
+	state, err := loadSeedingState(c, ctx, definition.ParticipantName)
+	if err != nil {
+		return fail(err)
+	}
+	// stored before any step ran, so a participant failing its first step can be resumed as well
+	state.definition = definition
+	if err := storeSeedingState(c, ctx, state); err != nil {
+		fmt.Printf("storing seeding state of %s failed: %v\n", definition.ParticipantName, err)
+	}
+	statusChecker.SetSeededResources(definition.ParticipantName, state.resources)
+	steps, err := seedSteps(c, definition, clients, creds, issuerFor(dataspace), secretStoreFor(dataspace, c, definition, clients), statusChecker, &state.resources)
+	if err != nil {
+		return fail(err)
+	}
+	steps = slices.DeleteFunc(steps, func(step seedStep) bool {
+		return !definition.seeds(step.name)
+	})
+	for _, step := range steps {
+		progress := status.SeedingStep{Name: step.name, State: status.SeedingPending}
+		if completedAt, ok := state.completed[step.name]; ok {
+			progress = status.SeedingStep{Name: step.name, State: status.SeedingCompleted, Message: "completed at " + completedAt}
+			if progressStep, ok := seedProgressSteps[step.name]; ok {
+				statusChecker.SetProgress(definition.ParticipantName, progressStep, status.StepCompleted, progress.Message)
+			}
+		}
+		statusChecker.SetSeedingStep(definition.ParticipantName, progress)
+	}
+
+	failures := runSeedSteps(ctx, definition, steps, &state, statusChecker, func() {
+		statusChecker.SetSeededResources(definition.ParticipantName, state.resources)
+		if err := storeSeedingState(c, ctx, state); err != nil {
+			fmt.Printf("storing seeding state of %s failed: %v\n", definition.ParticipantName, err)
+		}
+	})
+	if err := errors.Join(failures...); err != nil {
+		return fail(err)
+	}
+	statusChecker.SetSeeding(definition.ParticipantName, status.SeedingCompleted, "")
+
+	verification := verifySeeding(ctx, managementApi(definition, clients, creds), definition, state.resources)
+	statusChecker.SetVerification(definition.ParticipantName, verification)
+	if !verification.Verified {
+		fmt.Printf("Verifying the seeded catalog of %s failed: %s\n", definition.ParticipantName, verification.Message)
+	}
+	fmt.Println("Data seeding complete in namespace", definition.ParticipantName)
+	return nil
+}
+
+// createdId returns the ID of an entity created through the management API: the @id of the response, or of the
+// request body when the entity existed already and the response is empty.
+func createdId(response string, body string) string {
+	for _, document := range []string{response, body} {
+		var entity struct {
+			Id string `json:"@id"`
+		}
+		if json.Unmarshal([]byte(document), &entity) == nil && entity.Id != "" {
+			return entity.Id
+		}
+	}
+	return ""
+}
+
+// missingSteps returns the steps the step requires that didn't complete. Steps the participant doesn't run don't
+// count, the entities they create may exist already.
+func missingSteps(step seedStep, state seedingState, definition ParticipantDefinition) []string {
+	var missing []string
+	for _, required := range step.requires {
+		if _, ok := state.completed[required]; !ok && definition.seeds(required) {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+// runSeedSteps runs the steps that didn't complete yet in rounds. The steps of a round run concurrently, they are
+// those whose required steps completed, e.g. the assets and the policies, then their contract definitions. The state
+// is recorded after every round, with the entities failed steps created before they failed, as resuming seeding runs
+// those steps again. Steps requiring a failed step are left pending.
+func runSeedSteps(ctx context.Context, definition ParticipantDefinition, steps []seedStep, state *seedingState, statusChecker *status.StatusChecker, record func()) []error {
+	failures := make([]error, len(steps))
+	pending := slices.DeleteFunc(slices.Clone(steps), func(step seedStep) bool {
+		_, ok := state.completed[step.name]
+		return ok
+	})
+	for {
+		var round, waiting []seedStep
+		for _, step := range pending {
+			if len(missingSteps(step, *state, definition)) == 0 {
+				round = append(round, step)
+			} else {
+				waiting = append(waiting, step)
+			}
+		}
+		if len(round) == 0 {
+			break
+		}
+		errs := make([]error, len(round))
+		var wg sync.WaitGroup
+		for i, step := range round {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = runSeedStep(ctx, definition.ParticipantName, step, statusChecker)
+			}()
+		}
+		wg.Wait()
+		for i, step := range round {
+			if errs[i] != nil {
+				failures[slices.IndexFunc(steps, func(s seedStep) bool { return s.name == step.name })] = fmt.Errorf("seed %s: %w", step.name, errs[i])
+				continue
+			}
+			state.completed[step.name] = time.Now().UTC().Format(time.RFC3339)
+		}
+		record()
+		pending = waiting
+	}
+	for _, step := range pending {
+		missing := strings.Join(missingSteps(step, *state, definition), ", ")
+		statusChecker.SetSeedingStep(definition.ParticipantName, status.SeedingStep{Name: step.name, State: status.SeedingPending,
+			Message: "waiting for " + missing})
+		if progress, ok := seedProgressSteps[step.name]; ok {
+			statusChecker.SetProgress(definition.ParticipantName, progress, status.StepPending, "waiting for "+missing)
+		}
+	}
+	return slices.DeleteFunc(failures, func(err error) bool { return err == nil })
+}
+
+// runSeedStep runs the step until it succeeds, fails with an error that isn't retryable or runs out of attempts.
+func runSeedStep(ctx context.Context, participant string, step seedStep, statusChecker *status.StatusChecker) error {
+	progress, tracked := seedProgressSteps[step.name]
+	if tracked {
+		statusChecker.SetProgress(participant, progress, status.StepRunning, "")
+	}
+	for attempt := 1; ; attempt++ {
+		statusChecker.SetSeedingStep(participant, status.SeedingStep{Name: step.name, State: status.SeedingRunning, Attempts: attempt})
+		err := step.run(ctx)
+		if err == nil {
+			statusChecker.SetSeedingStep(participant, status.SeedingStep{Name: step.name, State: status.SeedingCompleted, Attempts: attempt})
+			if tracked {
+				statusChecker.SetProgress(participant, progress, status.StepCompleted, "")
+			}
+			return nil
+		}
+		statusChecker.SetSeedingStep(participant, status.SeedingStep{Name: step.name, State: status.SeedingFailed, Attempts: attempt, Message: err.Error()})
+		if attempt >= seedBackoff.attempts || !api.IsTransient(err) {
+			if tracked {
+				statusChecker.SetProgress(participant, progress, status.StepFailed, err.Error())
+			}
+			return err
+		}
+		delay := seedBackoff.delay(attempt)
+		fmt.Printf("seeding %s of %s failed, retrying in %s: %v\n", step.name, participant, delay, err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// resumeSeeding runs the seed steps the participant is missing in a background job, e.g. after a component was
+// unavailable for longer than the retries cover. The dataspace's hooks run again once seeding completed.
+func (p *provisioner) resumeSeeding(ctx context.Context, namespace string) (*provisioningJob, error) {
+	definition, seeding, err := p.seedingResumption(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return p.runResumption(ctx, definition, []jobStep{seeding}, nil, 0)
+}
+
+// runResumption runs the steps finishing the provisioning of a participant in a background job, followed by the
+// dataspace's hooks, and marks the participant ready once they completed. Resumptions counts the times the work was
+// interrupted before.
+func (p *provisioner) runResumption(ctx context.Context, definition ParticipantDefinition, steps []jobStep, deployments []string, resumptions int) (*provisioningJob, error) {
+	namespace := definition.ParticipantName
+	job, err := jobs.create(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := p.queue.join(job)
+	if err != nil {
+		jobs.discard(job.Id)
+		return nil, err
+	}
+	job.queue()
+	resumed := steps[0].phase
+	if hooks := p.dataspaces.lookup(ctx, dataspaceOf(definition)).Hooks; len(hooks) > 0 {
+		steps = append(steps, jobStep{phaseHooks, func(ctx context.Context) error {
+			return runHooks(ctx, p.kubeClient, p.podLogs, definition, hooks)
+		}})
+	}
+	steps, endWork := p.work.track(job, definition, func() []string { return deployments }, resumptions, steps)
+	runInBackground(func() {
+		defer endWork()
+		runCtx, stop := job.bind(withSpanOf(p.ctx, ctx))
+		defer stop()
+		if err := ticket.wait(runCtx); err != nil {
+			job.abandon(err)
+			return
+		}
+		defer ticket.done()
+		if err := p.executeOnboarding(runCtx, job, definition, steps); err != nil {
+			fmt.Printf("resuming %s of %s failed: %v\n", resumed, namespace, err)
+			return
+		}
+		p.announce(eventParticipantReady, namespace, map[string]string{"did": definition.Did, "jobId": job.Id})
+	})
+	return job, nil
+}
+
+// seedingResumption returns the step running the seed steps the participant is missing and the definition it was
+// seeded from.
+func (p *provisioner) seedingResumption(ctx context.Context, namespace string) (ParticipantDefinition, jobStep, error) {
+	state, err := loadSeedingState(p.kubeClient, ctx, namespace)
+	if err != nil {
+		return ParticipantDefinition{}, jobStep{}, err
+	}
+	if state.definition.ParticipantName == "" {
+		return ParticipantDefinition{}, jobStep{}, fiber.NewError(fiber.StatusConflict, "seeding of the participant never started, provision it again")
+	}
+	if !state.definition.Seed.enabled() {
+		return ParticipantDefinition{}, jobStep{}, fiber.NewError(fiber.StatusConflict, "seeding is disabled for the participant")
+	}
+	creds, err := loadCredentials(p.kubeClient, ctx, namespace)
+	if err != nil {
+		return ParticipantDefinition{}, jobStep{}, err
+	}
+	return state.definition, p.seedingStep(state.definition, creds), nil
+}
+
+// seedingStep waits for the APIs of the participant and runs the seed steps it is missing.
+func (p *provisioner) seedingStep(definition ParticipantDefinition, creds participantCredentials) jobStep {
+	participantClients := p.clients.forParticipant(definition)
+	return jobStep{phaseSeeding, func(ctx context.Context) error {
+		clients := participantClients.withTrace(ctx)
+		err := waitForApis(ctx, definition, clients, creds, p.statusChecker)
+		if err == nil {
+			err = onDeploymentReady(ctx, p.kubeClient, definition, p.statusChecker, clients, creds, p.dataspaces.lookup(ctx, dataspaceOf(definition)))
+		}
+		if err != nil {
+			p.announce(eventParticipantSeedFailed, definition.ParticipantName, map[string]string{"error": err.Error()})
+		}
+		return err
+	}}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configMapStore is an in-memory client keeping the ConfigMaps applied with server-side apply.
+type configMapStore struct {
+	client.Client
+	configMaps map[client.ObjectKey]*corev1.ConfigMap
+}
+
+func (c *configMapStore) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	configMap, ok := c.configMaps[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	configMap.DeepCopyInto(obj.(*corev1.ConfigMap))
+	return nil
+}
+
+func (c *configMapStore) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	c.configMaps[client.ObjectKeyFromObject(obj)] = obj.(*corev1.ConfigMap).DeepCopy()
+	return nil
+}
+
+// seedServer answers the seeding requests of all components, counting them by path. Paths in failing respond with
+// the status until it is removed.
+type seedServer struct {
+	mu       sync.Mutex
+	requests map[string]int
+	failing  map[string]int
+}
+
+var participantPath = regexp.MustCompile(`/participants/[^/]+$`)
+
+func (s *seedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path, code := range s.failing {
+		if strings.HasSuffix(r.URL.Path, path) {
+			s.requests[path]++
+			w.WriteHeader(code)
+			return
+		}
+	}
+	if r.Method == http.MethodGet && participantPath.MatchString(r.URL.Path) {
+		// no participant context exists yet
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	for _, path := range []string{"/assets", "/policydefinitions", "/contractdefinitions", "/participants", "/holders"} {
+		if strings.HasSuffix(r.URL.Path, path) {
+			s.requests[path]++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, "/credentials") {
+		// credentials count as issued right away
+		_, _ = w.Write([]byte(`[{"id":"membership"}]`))
+		return
+	}
+	_, _ = w.Write([]byte(`{"clientId":"alice","clientSecret":"secret"}`))
+}
+
+func (s *seedServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func TestSeedingResumesAfterFailedStep(t *testing.T) {
+	previous := seedBackoff
+	seedBackoff = backoff{attempts: 3, initial: time.Millisecond, max: time.Millisecond}
+	t.Cleanup(func() { seedBackoff = previous })
+
+	server := &seedServer{requests: make(map[string]int), failing: map[string]int{"/holders": http.StatusServiceUnavailable}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL}
+	creds := participantCredentials{ManagementApiKey: "management", IdentityApiKey: "identity"}
+
+	err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, creds, DataspaceConfig{})
+	if err == nil || !strings.Contains(err.Error(), "seed issuer") {
+		t.Fatalf("expected the issuer step to fail, got %v", err)
+	}
+	if attempts := server.count("/holders"); attempts != 3 {
+		t.Errorf("expected 3 attempts of the issuer step, got %d", attempts)
+	}
+	state, err := loadSeedingState(kube, context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.definition.Did != definition.Did {
+		t.Errorf("definition not stored: %+v", state.definition)
+	}
+	for _, step := range []string{seedStepAssets, seedStepPolicies, seedStepContractDefinitions, seedStepParticipant} {
+		if _, ok := state.completed[step]; !ok {
+			t.Errorf("step %s not recorded as completed", step)
+		}
+	}
+	if _, ok := state.completed[seedStepIssuer]; ok {
+		t.Error("failed issuer step recorded as completed")
+	}
+	if resources := state.resources; len(resources.Assets) == 0 || len(resources.ContractDefinitions) == 0 || resources.ParticipantContextId != definition.Did || resources.StsClientId != "alice" {
+		t.Errorf("seeded resources not stored: %+v", resources)
+	}
+	if seeding := checker.GetSeeding("alice"); seeding.Resources == nil || seeding.Resources.StsClientId != "alice" {
+		t.Errorf("seeded resources not reported: %+v", seeding)
+	}
+	for _, step := range checker.GetProgress("alice") {
+		if step.State != status.StepCompleted || step.FinishedAt == nil {
+			t.Errorf("expected step %s to be completed, got %+v", step.Name, step)
+		}
+	}
+	if progress := checker.GetProgress("alice"); len(progress) != 5 || progress[0].Name != status.StepParticipantCreated || progress[1].Name != status.StepSecretStored {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+
+	// once the issuer is back, only the missing step runs
+	assets := server.count("/assets")
+	delete(server.failing, "/holders")
+	if err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, creds, DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.count("/assets") != assets {
+		t.Error("completed steps ran again")
+	}
+	if server.count("/holders") != 4 {
+		t.Errorf("expected the issuer step to run once more, got %d requests", server.count("/holders"))
+	}
+}
+
+func TestSeedStepsAreNotRetriedOnClientErrors(t *testing.T) {
+	previous := seedBackoff
+	seedBackoff = backoff{attempts: 3, initial: time.Millisecond, max: time.Millisecond}
+	t.Cleanup(func() { seedBackoff = previous })
+
+	server := &seedServer{requests: make(map[string]int), failing: map[string]int{"/assets": http.StatusBadRequest}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL}
+
+	err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{})
+	if err == nil {
+		t.Fatal("expected seeding to fail")
+	}
+	if attempts := server.count("/assets"); attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+	if server.count("/contractdefinitions") != 0 {
+		t.Error("contract definitions were seeded without their assets")
+	}
+	if server.count("/holders") != 1 {
+		t.Error("independent steps should still run")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := backoff{attempts: 5, initial: 2 * time.Second, max: 30 * time.Second}
+	for retry, expected := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 4: 16 * time.Second, 5: 30 * time.Second} {
+		if delay := b.delay(retry); delay != expected {
+			t.Errorf("retry %d: expected %s, got %s", retry, expected, delay)
+		}
+	}
+}
+
+func TestSeedOptions(t *testing.T) {
+	for body, expected := range map[string]SeedOptions{
+		`{"seed": false}`:                      {Skip: true},
+		`{"seed": true}`:                       {},
+		`{"seed": {"steps": ["participant"]}}`: {Steps: []string{seedStepParticipant}},
+	} {
+		var definition ParticipantDefinition
+		if err := json.Unmarshal([]byte(body), &definition); err != nil {
+			t.Fatalf("%s: %v", body, err)
+		}
+		if definition.Seed == nil || definition.Seed.Skip != expected.Skip || !slices.Equal(definition.Seed.Steps, expected.Steps) {
+			t.Errorf("%s: expected %+v, got %+v", body, expected, definition.Seed)
+		}
+	}
+	if err := (&SeedOptions{Steps: []string{"assets", "wallet"}}).validate(); err == nil {
+		t.Error("expected unknown steps to be rejected")
+	}
+}
+
+func TestSeedingRunsSelectedSteps(t *testing.T) {
+	server := &seedServer{requests: make(map[string]int), failing: map[string]int{}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL,
+		Seed: &SeedOptions{Steps: []string{seedStepContractDefinitions, seedStepParticipant}}}
+
+	if err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.count("/assets") != 0 || server.count("/policydefinitions") != 0 || server.count("/holders") != 0 {
+		t.Errorf("steps that weren't selected ran: %v", server.requests)
+	}
+	if server.count("/contractdefinitions") == 0 || server.count("/participants") != 1 {
+		t.Errorf("selected steps didn't run: %v", server.requests)
+	}
+}
+
+func TestSeedingCustomCatalog(t *testing.T) {
+	server := &seedServer{requests: make(map[string]int), failing: map[string]int{}}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL,
+		Seed: &SeedOptions{Steps: []string{seedStepAssets, seedStepPolicies, seedStepContractDefinitions}},
+		Catalog: &SeedCatalog{
+			Assets: []map[string]any{{"@id": "weather"}, {"@id": "traffic"}, {"@id": "energy"}},
+			ContractDefinitions: []map[string]any{{"@id": "open", "accessPolicyId": "require-membership", "contractPolicyId": "require-membership",
+				"assetsSelector": []any{}}},
+		}}
+
+	if err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.count("/assets") != 3 || server.count("/contractdefinitions") != 1 {
+		t.Errorf("custom catalog not seeded: %v", server.requests)
+	}
+	// policies weren't replaced
+	if server.count("/policydefinitions") != 3 {
+		t.Errorf("expected the embedded policies, got %v", server.requests)
+	}
+}
+
+func TestSeedCatalogValidation(t *testing.T) {
+	catalog := &SeedCatalog{
+		Assets:              []map[string]any{{"@id": "weather"}, {"@id": "weather"}, {"properties": map[string]any{}}},
+		ContractDefinitions: []map[string]any{{"@id": "open"}},
+	}
+	fields := catalog.validate([]ContractDefinitionSpec{{Id: "spec"}})
+	if len(fields) != 3 {
+		t.Errorf("expected duplicate and missing IDs and the combination with specs to be rejected, got %+v", fields)
+	}
+}
+
+func TestSeedingRecordsEntitiesOfFailedSteps(t *testing.T) {
+	var mu sync.Mutex
+	assets := 0
+	seed := &seedServer{requests: make(map[string]int), failing: map[string]int{}}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/assets") {
+			mu.Lock()
+			assets++
+			refused := assets == 2
+			mu.Unlock()
+			if refused {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		seed.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL,
+		Seed:    &SeedOptions{Steps: []string{seedStepAssets, seedStepPolicies, seedStepContractDefinitions}},
+		Catalog: &SeedCatalog{Assets: []map[string]any{{"@id": "weather"}, {"@id": "traffic"}, {"@id": "energy"}}}}
+
+	err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{})
+	if err == nil || !strings.Contains(err.Error(), "created 1 of 3") {
+		t.Fatalf("expected the assets step to fail after the first asset, got %v", err)
+	}
+	state, err := loadSeedingState(kube, context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(state.resources.Assets, []string{"weather"}) {
+		t.Errorf("expected the created asset to be recorded, got %v", state.resources.Assets)
+	}
+	if _, ok := state.completed[seedStepAssets]; ok {
+		t.Error("failed assets step recorded as completed")
+	}
+	if _, ok := state.completed[seedStepPolicies]; !ok {
+		t.Error("policies weren't seeded alongside the assets")
+	}
+	if seed.count("/contractdefinitions") != 0 {
+		t.Error("contract definitions were seeded without their assets")
+	}
+
+	// the re-seed creates the remaining assets and the contract definitions
+	if err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ = loadSeedingState(kube, context.Background(), "alice"); len(state.resources.Assets) != 3 || len(state.resources.ContractDefinitions) == 0 {
+		t.Errorf("expected the catalog to be complete, got %+v", state.resources)
+	}
+}
+
+func TestIndependentSeedStepsRunConcurrently(t *testing.T) {
+	policies := make(chan struct{})
+	var once sync.Once
+	seed := &seedServer{requests: make(map[string]int), failing: map[string]int{}}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/policydefinitions"):
+				once.Do(func() { close(policies) })
+			case strings.HasSuffix(r.URL.Path, "/assets"):
+				// the first asset is only created once the policies are being seeded
+				select {
+				case <-policies:
+				case <-time.After(5 * time.Second):
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		seed.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	kube := &configMapStore{configMaps: make(map[client.ObjectKey]*corev1.ConfigMap)}
+	checker := status.NewStatusChecker(context.Background(), nil, status.DefaultCacheTTL)
+	definition := ParticipantDefinition{ParticipantName: "alice", Did: "did:web:alice", KubernetesIngressHost: httpServer.URL,
+		Seed: &SeedOptions{Steps: []string{seedStepAssets, seedStepPolicies, seedStepContractDefinitions}}}
+
+	if err := onDeploymentReady(context.Background(), kube, definition, checker, seedingClients{}, participantCredentials{}, DataspaceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Longest participant name in the shared namespace, leaving room for the names of its services, e.g.
+// postgres-service-<participant>, which are limited to 63 characters
+const maxSharedParticipantName = 40
+
+// sharedNamespaceLabel marks the ConfigMap standing in for the namespace of a participant in the shared namespace,
+// its value is the participant
+const sharedNamespaceLabel = "aruba-provisioner/namespace"
+
+// Kinds whose objects are named by Kubernetes after their owners, e.g. the pods of the renamed deployments
+var generatedKinds = map[string]bool{"Pod": true, "ReplicaSet": true, "Event": true}
+
+// Namespaced kinds deleted with a participant in the shared namespace, where deleting its namespace doesn't remove
+// them
+var sharedNamespaceKinds = append(provisionedKinds[:len(provisionedKinds):len(provisionedKinds)],
+	schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"},
+	schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
+	schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+	schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"},
+	schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"},
+	schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+)
+
+// validateSharedNamespace rejects definitions that can't be provisioned into the shared namespace: policies, mesh
+// settings and gateways apply to whole namespaces and would affect the other participants.
+func validateSharedNamespace(definition ParticipantDefinition) error {
+	if status.SharedNamespace == "" {
+		return nil
+	}
+	if len(definition.ParticipantName) > maxSharedParticipantName {
+		return fmt.Errorf("participant names in the shared namespace must not be longer than %d characters", maxSharedParticipantName)
+	}
+	switch {
+	case definition.NetworkPolicies != nil:
+		return fmt.Errorf("networkPolicies are not supported in the shared namespace")
+	case definition.Mesh != nil:
+		return fmt.Errorf("mesh is not supported in the shared namespace")
+	case routingOf(definition) == routingGateway:
+		return fmt.Errorf("gateway routing is not supported in the shared namespace")
+	case definition.AdoptNamespace:
+		return fmt.Errorf("adoptNamespace is not supported in the shared namespace")
+	case definition.ExpiresAfter != "":
+		return fmt.Errorf("expiresAfter is not supported in the shared namespace")
+	case definition.Teardown != "":
+		return fmt.Errorf("teardown is not supported in the shared namespace")
+	}
+	return nil
+}
+
+// sharedNamespaceMutator points the references between the participant's objects to their names in the shared
+// namespace and selects its pods by the participant label, as the other participants' pods carry the same component
+// labels. Host names of its services, e.g. in connector settings, are rewritten as well. The objects themselves are
+// moved and renamed by the sharedNamespaceClient.
+func sharedNamespaceMutator(participant string) objectMutator {
+	hosts := regexp.MustCompile(`\b([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.` + regexp.QuoteMeta(participant) + `\.svc\b`)
+	sharedHost := "${1}-" + participant + "." + status.SharedNamespace + ".svc"
+	selector := map[string]string{status.ParticipantLabel: participant}
+	rename := func(object map[string]any, path ...string) error {
+		name, ok, err := unstructured.NestedString(object, path...)
+		if err != nil || !ok || name == "" {
+			return err
+		}
+		return unstructured.SetNestedField(object, status.ObjectName(name, participant), path...)
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			return nil
+		}
+		obj.Object = rewriteHosts(obj.Object, hosts, sharedHost).(map[string]any)
+		switch obj.GetKind() {
+		case "Deployment", "StatefulSet", "DaemonSet":
+			if err := addNestedLabels(obj.Object, selector, "spec", "selector", "matchLabels"); err != nil {
+				return err
+			}
+			return renamePodReferences(obj.Object, rename, "spec", "template", "spec")
+		case "Job":
+			return renamePodReferences(obj.Object, rename, "spec", "template", "spec")
+		case "Service":
+			if _, ok, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); ok {
+				return addNestedLabels(obj.Object, selector, "spec", "selector")
+			}
+		case "Ingress":
+			if err := rename(obj.Object, "spec", "defaultBackend", "service", "name"); err != nil {
+				return err
+			}
+			for _, rule := range nestedItems(obj.Object, "spec", "rules") {
+				for _, path := range nestedItems(rule, "http", "paths") {
+					if err := rename(path, "backend", "service", "name"); err != nil {
+						return err
+					}
+				}
+			}
+			for _, tls := range nestedItems(obj.Object, "spec", "tls") {
+				if err := rename(tls, "secretName"); err != nil {
+					return err
+				}
+			}
+		case "HorizontalPodAutoscaler":
+			return rename(obj.Object, "spec", "scaleTargetRef", "name")
+		case "PodDisruptionBudget":
+			return addNestedLabels(obj.Object, selector, "spec", "selector", "matchLabels")
+		case "Certificate":
+			return rename(obj.Object, "spec", "secretName")
+		case "Role", "RoleBinding":
+			return renameRbacReferences(obj, participant)
+		}
+		return nil
+	}
+}
+
+// renamePodReferences renames the ConfigMaps, Secrets, claims and ServiceAccount the pod spec at the path refers to.
+func renamePodReferences(object map[string]any, rename func(map[string]any, ...string) error, path ...string) error {
+	spec, ok, err := unstructured.NestedFieldNoCopy(object, path...)
+	podSpec, isMap := spec.(map[string]any)
+	if err != nil || !ok || !isMap {
+		return err
+	}
+	refs := [][]string{{"serviceAccountName"}}
+	owners := []map[string]any{podSpec}
+	for _, volume := range nestedItems(podSpec, "volumes") {
+		owners = append(owners, volume, volume, volume)
+		refs = append(refs, []string{"configMap", "name"}, []string{"secret", "secretName"}, []string{"persistentVolumeClaim", "claimName"})
+		for _, source := range nestedItems(volume, "projected", "sources") {
+			owners = append(owners, source, source)
+			refs = append(refs, []string{"configMap", "name"}, []string{"secret", "name"})
+		}
+	}
+	for _, containers := range []string{"containers", "initContainers"} {
+		for _, container := range nestedItems(podSpec, containers) {
+			for _, envFrom := range nestedItems(container, "envFrom") {
+				owners = append(owners, envFrom, envFrom)
+				refs = append(refs, []string{"configMapRef", "name"}, []string{"secretRef", "name"})
+			}
+			for _, env := range nestedItems(container, "env") {
+				owners = append(owners, env, env)
+				refs = append(refs, []string{"valueFrom", "configMapKeyRef", "name"}, []string{"valueFrom", "secretKeyRef", "name"})
+			}
+		}
+	}
+	for i, owner := range owners {
+		if err := rename(owner, refs[i]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nestedItems returns the objects of the list at the path, to be changed in place.
+func nestedItems(object map[string]any, path ...string) []map[string]any {
+	value, ok, err := unstructured.NestedFieldNoCopy(object, path...)
+	if err != nil || !ok {
+		return nil
+	}
+	list, _ := value.([]any)
+	items := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if itemMap, ok := item.(map[string]any); ok {
+			items = append(items, itemMap)
+		}
+	}
+	return items
+}
+
+func addNestedLabels(object map[string]any, labels map[string]string, path ...string) error {
+	merged, _, err := unstructured.NestedStringMap(object, path...)
+	if err != nil {
+		return err
+	}
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return unstructured.SetNestedStringMap(object, merged, path...)
+}
+
+// rewriteHosts replaces the matching host names in all strings of the value.
+func rewriteHosts(value any, hosts *regexp.Regexp, replacement string) any {
+	switch v := value.(type) {
+	case string:
+		return hosts.ReplaceAllString(v, replacement)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = rewriteHosts(item, hosts, replacement)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = rewriteHosts(item, hosts, replacement)
+		}
+	}
+	return value
+}
+
+// sharedNamespaceClient maps the namespace of every participant onto the shared namespace, so the rest of the
+// provisioner keeps addressing participants by their namespace. Objects in the namespace of a participant are kept in
+// the shared namespace, named after the participant and labelled with it. The namespace itself is a ConfigMap named
+// after the participant carrying the namespace's labels and annotations; deleting it deletes the participant's
+// objects.
+type sharedNamespaceClient struct {
+	client.WithWatch
+	namespace string
+	// passthrough are the namespaces that aren't participants, e.g. the provisioner's own
+	passthrough map[string]bool
+}
+
+func newSharedNamespaceClient(c client.WithWatch, namespace string, passthrough ...string) sharedNamespaceClient {
+	skipped := map[string]bool{namespace: true}
+	for _, name := range passthrough {
+		skipped[name] = true
+	}
+	return sharedNamespaceClient{WithWatch: c, namespace: namespace, passthrough: skipped}
+}
+
+// participantOf reports whether the namespace is that of a participant, which is mapped onto the shared namespace.
+func (c sharedNamespaceClient) participantOf(namespace string) (string, bool) {
+	if namespace == "" || c.passthrough[namespace] || status.IsSystemNamespace(namespace) {
+		return "", false
+	}
+	return namespace, true
+}
+
+func (c sharedNamespaceClient) kindOf(obj runtime.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return obj.GetObjectKind().GroupVersionKind().Kind
+	}
+	return strings.TrimSuffix(gvk.Kind, "List")
+}
+
+// objectName returns the name of an object of the participant in the shared namespace.
+func (c sharedNamespaceClient) objectName(obj runtime.Object, name string, participant string) string {
+	if generatedKinds[c.kindOf(obj)] {
+		return name
+	}
+	return status.ObjectName(name, participant)
+}
+
+// toShared moves an object of a participant's namespace into the shared namespace, returning whether it did.
+func (c sharedNamespaceClient) toShared(obj client.Object) bool {
+	participant, ok := c.participantOf(obj.GetNamespace())
+	if !ok {
+		return false
+	}
+	objectLabels := obj.GetLabels()
+	if objectLabels == nil {
+		objectLabels = make(map[string]string)
+	}
+	objectLabels[status.ParticipantLabel] = participant
+	obj.SetLabels(objectLabels)
+	obj.SetNamespace(c.namespace)
+	if obj.GetName() != "" {
+		obj.SetName(c.objectName(obj, obj.GetName(), participant))
+	}
+	return true
+}
+
+// fromShared moves an object of the shared namespace back into the namespace of the participant it is labelled with.
+func (c sharedNamespaceClient) fromShared(obj client.Object) {
+	participant := obj.GetLabels()[status.ParticipantLabel]
+	if obj.GetNamespace() != c.namespace || participant == "" {
+		return
+	}
+	obj.SetNamespace(participant)
+	obj.SetName(strings.TrimSuffix(obj.GetName(), "-"+participant))
+}
+
+// withParticipant narrows a list to the objects of the participant.
+func (c sharedNamespaceClient) withParticipant(options *client.ListOptions, participant string) {
+	options.Namespace = c.namespace
+	requirement, err := labels.NewRequirement(status.ParticipantLabel, selection.Equals, []string{participant})
+	if err != nil {
+		return
+	}
+	if options.LabelSelector == nil {
+		options.LabelSelector = labels.NewSelector()
+	}
+	options.LabelSelector = options.LabelSelector.Add(*requirement)
+}
+
+func (c sharedNamespaceClient) isNamespace(obj runtime.Object) bool {
+	return c.kindOf(obj) == "Namespace"
+}
+
+func (c sharedNamespaceClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if key.Namespace == "" && c.isNamespace(obj) {
+		if _, ok := c.participantOf(key.Name); ok {
+			return c.getNamespace(ctx, key.Name, obj, opts...)
+		}
+	}
+	participant, ok := c.participantOf(key.Namespace)
+	if !ok {
+		return c.WithWatch.Get(ctx, key, obj, opts...)
+	}
+	if err := c.WithWatch.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: c.objectName(obj, key.Name, participant)}, obj, opts...); err != nil {
+		return err
+	}
+	c.fromShared(obj)
+	return nil
+}
+
+func (c sharedNamespaceClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	if namespaces, ok := list.(*corev1.NamespaceList); ok {
+		return c.listNamespaces(ctx, namespaces, options)
+	}
+	participant, ok := c.participantOf(options.Namespace)
+	if ok {
+		if events, isEvents := list.(*corev1.EventList); isEvents {
+			return c.listEvents(ctx, events, options, participant)
+		}
+		c.withParticipant(options, participant)
+	}
+	if err := c.WithWatch.List(ctx, list, options); err != nil {
+		return err
+	}
+	return meta.EachListItem(list, func(item runtime.Object) error {
+		if obj, ok := item.(client.Object); ok {
+			c.fromShared(obj)
+		}
+		return nil
+	})
+}
+
+// listEvents lists the events of the participant's objects, which Kubernetes doesn't label, by the names of the
+// objects they involve.
+func (c sharedNamespaceClient) listEvents(ctx context.Context, events *corev1.EventList, options *client.ListOptions, participant string) error {
+	options.Namespace = c.namespace
+	if err := c.WithWatch.List(ctx, events, options); err != nil {
+		return err
+	}
+	owned := events.Items[:0]
+	for _, event := range events.Items {
+		name := event.InvolvedObject.Name
+		if strings.HasSuffix(name, "-"+participant) || strings.Contains(name, "-"+participant+"-") {
+			event.Namespace, event.InvolvedObject.Namespace = participant, participant
+			owned = append(owned, event)
+		}
+	}
+	events.Items = owned
+	return nil
+}
+
+func (c sharedNamespaceClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.isNamespace(obj) {
+		if _, ok := c.participantOf(obj.GetName()); ok {
+			return c.writeNamespace(obj, func(marker *corev1.ConfigMap) error {
+				return c.WithWatch.Create(ctx, marker, opts...)
+			})
+		}
+	}
+	if c.toShared(obj) {
+		defer c.fromShared(obj)
+	}
+	return c.WithWatch.Create(ctx, obj, opts...)
+}
+
+func (c sharedNamespaceClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.isNamespace(obj) {
+		if _, ok := c.participantOf(obj.GetName()); ok {
+			return c.writeNamespace(obj, func(marker *corev1.ConfigMap) error {
+				return c.WithWatch.Update(ctx, marker, opts...)
+			})
+		}
+	}
+	if c.toShared(obj) {
+		defer c.fromShared(obj)
+	}
+	return c.WithWatch.Update(ctx, obj, opts...)
+}
+
+func (c sharedNamespaceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.isNamespace(obj) {
+		if _, ok := c.participantOf(obj.GetName()); ok {
+			// applied namespaces are applied as ConfigMap, the other patches only change the metadata and apply to
+			// the ConfigMap as they are
+			data, err := patch.Data(obj)
+			if err != nil {
+				return err
+			}
+			return c.writeNamespace(obj, func(marker *corev1.ConfigMap) error {
+				if patch.Type() == types.ApplyPatchType {
+					return c.WithWatch.Patch(ctx, marker, patch, opts...)
+				}
+				return c.WithWatch.Patch(ctx, marker, client.RawPatch(patch.Type(), data), opts...)
+			})
+		}
+	}
+	if c.toShared(obj) {
+		defer c.fromShared(obj)
+	}
+	return c.WithWatch.Patch(ctx, obj, patch, opts...)
+}
+
+func (c sharedNamespaceClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if c.isNamespace(obj) {
+		if participant, ok := c.participantOf(obj.GetName()); ok {
+			return c.deleteParticipant(ctx, participant, opts...)
+		}
+	}
+	if c.toShared(obj) {
+		defer c.fromShared(obj)
+	}
+	return c.WithWatch.Delete(ctx, obj, opts...)
+}
+
+func (c sharedNamespaceClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	options := &client.DeleteAllOfOptions{}
+	options.ApplyOptions(opts)
+	if participant, ok := c.participantOf(options.Namespace); ok {
+		c.withParticipant(&options.ListOptions, participant)
+	}
+	return c.WithWatch.DeleteAllOf(ctx, obj, options)
+}
+
+func (c sharedNamespaceClient) Watch(ctx context.Context, list client.ObjectList, opts ...client.ListOption) (watch.Interface, error) {
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	if participant, ok := c.participantOf(options.Namespace); ok {
+		c.withParticipant(options, participant)
+	}
+	watcher, err := c.WithWatch.Watch(ctx, list, options)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(watcher, func(event watch.Event) (watch.Event, bool) {
+		if obj, ok := event.Object.(client.Object); ok {
+			c.fromShared(obj)
+		}
+		return event, true
+	}), nil
+}
+
+// getNamespace reads the namespace of a participant from the ConfigMap standing in for it.
+func (c sharedNamespaceClient) getNamespace(ctx context.Context, name string, obj client.Object, opts ...client.GetOption) error {
+	marker := &corev1.ConfigMap{}
+	if err := c.WithWatch.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, marker, opts...); err != nil {
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewNotFound(corev1.Resource("namespaces"), name)
+		}
+		return err
+	}
+	if marker.Labels[sharedNamespaceLabel] != name {
+		return apierrors.NewNotFound(corev1.Resource("namespaces"), name)
+	}
+	return copyNamespace(namespaceOf(marker), obj)
+}
+
+func (c sharedNamespaceClient) listNamespaces(ctx context.Context, namespaces *corev1.NamespaceList, options *client.ListOptions) error {
+	options.Namespace = c.namespace
+	requirement, err := labels.NewRequirement(sharedNamespaceLabel, selection.Exists, nil)
+	if err != nil {
+		return err
+	}
+	if options.LabelSelector == nil {
+		options.LabelSelector = labels.NewSelector()
+	}
+	options.LabelSelector = options.LabelSelector.Add(*requirement)
+	markers := &corev1.ConfigMapList{}
+	if err := c.WithWatch.List(ctx, markers, options); err != nil {
+		return err
+	}
+	namespaces.ListMeta = markers.ListMeta
+	namespaces.Items = make([]corev1.Namespace, 0, len(markers.Items))
+	for i := range markers.Items {
+		namespaces.Items = append(namespaces.Items, *namespaceOf(&markers.Items[i]))
+	}
+	return nil
+}
+
+// writeNamespace writes the namespace of a participant as the ConfigMap standing in for it and reads the result back
+// into the namespace.
+func (c sharedNamespaceClient) writeNamespace(obj client.Object, write func(marker *corev1.ConfigMap) error) error {
+	markerLabels := make(map[string]string, len(obj.GetLabels())+1)
+	for k, v := range obj.GetLabels() {
+		markerLabels[k] = v
+	}
+	markerLabels[sharedNamespaceLabel] = obj.GetName()
+	marker := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            obj.GetName(),
+			Namespace:       c.namespace,
+			Labels:          markerLabels,
+			Annotations:     obj.GetAnnotations(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+	}
+	if err := write(marker); err != nil {
+		return err
+	}
+	return copyNamespace(namespaceOf(marker), obj)
+}
+
+// deleteParticipant deletes the objects of the participant in the shared namespace and then the ConfigMap standing in
+// for its namespace.
+func (c sharedNamespaceClient) deleteParticipant(ctx context.Context, participant string, opts ...client.DeleteOption) error {
+	for _, gvk := range sharedNamespaceKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.WithWatch.List(ctx, list, client.InNamespace(c.namespace), client.MatchingLabels{status.ParticipantLabel: participant})
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			if err := c.WithWatch.Delete(ctx, &list.Items[i], opts...); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	marker := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: participant, Namespace: c.namespace}}
+	if err := c.WithWatch.Delete(ctx, marker, opts...); err != nil {
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewNotFound(corev1.Resource("namespaces"), participant)
+		}
+		return err
+	}
+	return nil
+}
+
+// namespaceOf returns the namespace a ConfigMap stands in for.
+func namespaceOf(marker *corev1.ConfigMap) *corev1.Namespace {
+	namespaceLabels := make(map[string]string, len(marker.Labels))
+	for k, v := range marker.Labels {
+		if k != sharedNamespaceLabel {
+			namespaceLabels[k] = v
+		}
+	}
+	phase := corev1.NamespaceActive
+	if marker.DeletionTimestamp != nil {
+		phase = corev1.NamespaceTerminating
+	}
+	return &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              marker.Labels[sharedNamespaceLabel],
+			Labels:            namespaceLabels,
+			Annotations:       marker.Annotations,
+			UID:               marker.UID,
+			ResourceVersion:   marker.ResourceVersion,
+			CreationTimestamp: marker.CreationTimestamp,
+			DeletionTimestamp: marker.DeletionTimestamp,
+		},
+		Status: corev1.NamespaceStatus{Phase: phase},
+	}
+}
+
+func copyNamespace(namespace *corev1.Namespace, obj client.Object) error {
+	switch target := obj.(type) {
+	case *corev1.Namespace:
+		*target = *namespace
+	case *unstructured.Unstructured:
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(namespace)
+		if err != nil {
+			return err
+		}
+		target.SetUnstructuredContent(content)
+	default:
+		return fmt.Errorf("namespaces can't be read into %T in the shared namespace", obj)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// objectStore is an in-memory client keeping typed objects by kind, namespace and name. Patches replace the stored
+// object with the patched one.
+type objectStore struct {
+	client.WithWatch
+	scheme  *runtime.Scheme
+	objects map[string]runtime.Object
+}
+
+func newObjectStore() *objectStore {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	return &objectStore{scheme: scheme, objects: make(map[string]runtime.Object)}
+}
+
+func (s *objectStore) Scheme() *runtime.Scheme {
+	return s.scheme
+}
+
+func (s *objectStore) kind(obj runtime.Object) string {
+	gvk, _ := apiutil.GVKForObject(obj, s.scheme)
+	return strings.TrimSuffix(gvk.Kind, "List")
+}
+
+func (s *objectStore) key(kind string, namespace string, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+func (s *objectStore) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	stored, ok := s.objects[s.key(s.kind(obj), key.Namespace, key.Name)]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: s.kind(obj)}, key.Name)
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(stored.DeepCopyObject()).Elem())
+	return nil
+}
+
+func (s *objectStore) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	kind := s.kind(list)
+	var items []runtime.Object
+	for key, stored := range s.objects {
+		obj := stored.(client.Object)
+		if !strings.HasPrefix(key, kind+"/") || options.Namespace != "" && obj.GetNamespace() != options.Namespace {
+			continue
+		}
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labelSet(obj.GetLabels())) {
+			continue
+		}
+		items = append(items, stored.DeepCopyObject())
+	}
+	if unstructuredList, ok := list.(*unstructured.UnstructuredList); ok {
+		for i, item := range items {
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+			if err != nil {
+				return err
+			}
+			converted := &unstructured.Unstructured{Object: content}
+			converted.SetGroupVersionKind(unstructuredList.GroupVersionKind().GroupVersion().WithKind(kind))
+			items[i] = converted
+		}
+	}
+	return meta.SetList(list, items)
+}
+
+func (s *objectStore) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	s.objects[s.key(s.kind(obj), obj.GetNamespace(), obj.GetName())] = obj.DeepCopyObject()
+	return nil
+}
+
+func (s *objectStore) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	key := s.key(s.kind(obj), obj.GetNamespace(), obj.GetName())
+	if _, ok := s.objects[key]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: s.kind(obj)}, obj.GetName())
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+func withSharedNamespace(t *testing.T, namespace string) {
+	previous := status.SharedNamespace
+	status.SharedNamespace = namespace
+	t.Cleanup(func() { status.SharedNamespace = previous })
+}
+
+func TestSharedNamespaceClient(t *testing.T) {
+	withSharedNamespace(t, "participants")
+	ctx := context.Background()
+	store := newObjectStore()
+	c := newSharedNamespaceClient(store, "participants", "provisioner")
+
+	namespace := &unstructured.Unstructured{}
+	namespace.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})
+	namespace.SetName("acme")
+	namespace.SetLabels(map[string]string{status.ManagedByLabel: status.ManagedByValue})
+	if err := c.Patch(ctx, namespace, client.Apply); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.objects["ConfigMap/participants/acme"]; !ok {
+		t.Fatal("expected the namespace to be kept as ConfigMap in the shared namespace")
+	}
+	read := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "acme"}, read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Name != "acme" || read.Labels[status.ManagedByLabel] != status.ManagedByValue || read.Labels[sharedNamespaceLabel] != "" {
+		t.Errorf("unexpected namespace %+v", read.ObjectMeta)
+	}
+
+	for _, participant := range []string{"acme", "globex"} {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: participant}}
+		if err := c.Patch(ctx, deployment, client.Apply); err != nil {
+			t.Fatal(err)
+		}
+		if deployment.Name != "controlplane" || deployment.Namespace != participant {
+			t.Errorf("expected the caller's object to keep its name, got %s/%s", deployment.Namespace, deployment.Name)
+		}
+	}
+	stored, ok := store.objects["Deployment/participants/controlplane-acme"]
+	if !ok || stored.(*appsv1.Deployment).Labels[status.ParticipantLabel] != "acme" {
+		t.Fatalf("expected the deployment to be stored as controlplane-acme labelled with its participant, got %v", stored)
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace("acme")); err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments.Items) != 1 || deployments.Items[0].Name != "controlplane" || deployments.Items[0].Namespace != "acme" {
+		t.Errorf("expected the deployment of acme only, got %+v", deployments.Items)
+	}
+
+	// The provisioner's own namespace isn't mapped
+	if err := c.Patch(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "provisioner-quotas", Namespace: "provisioner"}}, client.Apply); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.objects["ConfigMap/provisioner/provisioner-quotas"]; !ok {
+		t.Error("expected objects in the provisioner's namespace to stay there")
+	}
+
+	if err := c.Delete(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "acme"}, &corev1.Namespace{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the namespace to be gone, got %v", err)
+	}
+	if _, ok := store.objects["Deployment/participants/controlplane-acme"]; ok {
+		t.Error("expected the participant's deployment to be deleted")
+	}
+	if _, ok := store.objects["Deployment/participants/controlplane-globex"]; !ok {
+		t.Error("expected the other participant's deployment to be kept")
+	}
+}
+
+func TestSharedNamespaceMutator(t *testing.T) {
+	withSharedNamespace(t, "participants")
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controlplane
+  namespace: acme
+spec:
+  selector:
+    matchLabels:
+      App: controlplane
+  template:
+    spec:
+      containers:
+        - name: controlplane
+          envFrom:
+            - configMapRef:
+                name: controlplane-config
+      volumes:
+        - name: participants-volume
+          configMap:
+            name: participants
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: controlplane-config
+  namespace: acme
+data:
+  EDC_DSP_CALLBACK_ADDRESS: "http://controlplane.acme.svc.cluster.local:8082/api/dsp"
+  EDC_ISSUER: "http://issuer.poc-issuer.svc.cluster.local:10016"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: controlplane
+  namespace: acme
+spec:
+  selector:
+    App: controlplane
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress-controlplane
+  namespace: acme
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /acme/cp
+            backend:
+              service:
+                name: controlplane
+`
+	mutate := sharedNamespaceMutator("acme")
+	objects := make(map[string]*unstructured.Unstructured)
+	for _, doc := range strings.Split(manifests, "---") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		if err := mutate(obj); err != nil {
+			t.Fatal(err)
+		}
+		objects[obj.GetKind()] = obj
+	}
+
+	deployment := objects["Deployment"].Object
+	if selector, _, _ := unstructured.NestedStringMap(deployment, "spec", "selector", "matchLabels"); selector[status.ParticipantLabel] != "acme" {
+		t.Errorf("expected the deployment to select the participant's pods, got %v", selector)
+	}
+	containers := nestedItems(deployment, "spec", "template", "spec", "containers")
+	if name, _, _ := unstructured.NestedString(nestedItems(containers[0], "envFrom")[0], "configMapRef", "name"); name != "controlplane-config-acme" {
+		t.Errorf("unexpected envFrom reference %s", name)
+	}
+	if name, _, _ := unstructured.NestedString(nestedItems(deployment, "spec", "template", "spec", "volumes")[0], "configMap", "name"); name != "participants-acme" {
+		t.Errorf("unexpected volume reference %s", name)
+	}
+
+	data, _, _ := unstructured.NestedStringMap(objects["ConfigMap"].Object, "data")
+	if data["EDC_DSP_CALLBACK_ADDRESS"] != "http://controlplane-acme.participants.svc.cluster.local:8082/api/dsp" {
+		t.Errorf("unexpected participant host %s", data["EDC_DSP_CALLBACK_ADDRESS"])
+	}
+	if data["EDC_ISSUER"] != "http://issuer.poc-issuer.svc.cluster.local:10016" {
+		t.Errorf("expected hosts of other namespaces to be kept, got %s", data["EDC_ISSUER"])
+	}
+
+	if selector, _, _ := unstructured.NestedStringMap(objects["Service"].Object, "spec", "selector"); selector[status.ParticipantLabel] != "acme" || selector["App"] != "controlplane" {
+		t.Errorf("unexpected service selector %v", selector)
+	}
+	paths := nestedItems(nestedItems(objects["Ingress"].Object, "spec", "rules")[0], "http", "paths")
+	if name, _, _ := unstructured.NestedString(paths[0], "backend", "service", "name"); name != "controlplane-acme" {
+		t.Errorf("unexpected ingress backend %s", name)
+	}
+}
+
+func TestValidateSharedNamespace(t *testing.T) {
+	if err := validateSharedNamespace(ParticipantDefinition{ParticipantName: strings.Repeat("a", 50), Mesh: &MeshOptions{}}); err != nil {
+		t.Errorf("expected no restrictions without shared namespace, got %v", err)
+	}
+	withSharedNamespace(t, "participants")
+	if err := validateSharedNamespace(ParticipantDefinition{ParticipantName: strings.Repeat("a", 50)}); err == nil {
+		t.Error("expected long names to be rejected")
+	}
+	if err := validateSharedNamespace(ParticipantDefinition{ParticipantName: "acme", Mesh: &MeshOptions{}}); err == nil {
+		t.Error("expected mesh settings to be rejected")
+	}
+	if err := validateSharedNamespace(ParticipantDefinition{ParticipantName: "acme"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if host := status.ServiceHost("identityhub", "acme"); host != "identityhub-acme.participants.svc.cluster.local" {
+		t.Errorf("unexpected service host %s", host)
+	}
+}
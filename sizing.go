@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// componentSize sets the resources of a component's container. Heap is the maximum Java heap of components running
+// on the JVM.
+type componentSize struct {
+	cpuRequest    string
+	cpuLimit      string
+	memoryRequest string
+	memoryLimit   string
+	heap          string
+}
+
+// sizingProfile sizes all components of a participant and the volume claim of its postgres data.
+type sizingProfile struct {
+	components      map[string]componentSize
+	postgresStorage string
+}
+
+// Sizing profiles selected by ParticipantDefinition.Size. The heap leaves a quarter of the memory limit to the
+// JVM's other memory. Participants without a profile keep the demo-grade settings of the templates: no limits and
+// postgres data that doesn't survive a restart.
+var sizingProfiles = map[string]sizingProfile{
+	"small": {
+		components: map[string]componentSize{
+			"controlplane": {"100m", "500m", "512Mi", "512Mi", "384m"},
+			"dataplane":    {"100m", "500m", "512Mi", "512Mi", "384m"},
+			"identityhub":  {"100m", "500m", "512Mi", "512Mi", "384m"},
+			"postgres":     {"100m", "500m", "256Mi", "256Mi", ""},
+			"vault":        {"50m", "250m", "128Mi", "128Mi", ""},
+		},
+		postgresStorage: "1Gi",
+	},
+	"medium": {
+		components: map[string]componentSize{
+			"controlplane": {"250m", "1", "1Gi", "1Gi", "768m"},
+			"dataplane":    {"250m", "1", "1Gi", "1Gi", "768m"},
+			"identityhub":  {"250m", "1", "1Gi", "1Gi", "768m"},
+			"postgres":     {"250m", "1", "512Mi", "512Mi", ""},
+			"vault":        {"100m", "500m", "256Mi", "256Mi", ""},
+		},
+		postgresStorage: "10Gi",
+	},
+	"large": {
+		components: map[string]componentSize{
+			"controlplane": {"500m", "2", "2Gi", "2Gi", "1536m"},
+			"dataplane":    {"500m", "2", "2Gi", "2Gi", "1536m"},
+			"identityhub":  {"500m", "2", "2Gi", "2Gi", "1536m"},
+			"postgres":     {"500m", "2", "1Gi", "1Gi", ""},
+			"vault":        {"250m", "1", "512Mi", "512Mi", ""},
+		},
+		postgresStorage: "50Gi",
+	},
+}
+
+// ConfigMaps holding the JVM options of the components, keyed by ConfigMap name
+var javaOptionsConfigMaps = map[string]string{"controlplane-config": "controlplane", "dataplane-config": "dataplane", "ih-config": "identityhub"}
+
+func validateSize(size string) error {
+	if _, ok := sizingProfiles[size]; !ok {
+		names := make([]string, 0, len(sizingProfiles))
+		for name := range sizingProfiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown size %q, must be one of %s", size, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// mutator sets the resources of the components' containers and their maximum heap.
+func (s sizingProfile) mutator() objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		switch obj.GetKind() {
+		case "Deployment":
+			size, ok := s.components[obj.GetName()]
+			if !ok {
+				return nil
+			}
+			return withResources(obj, size)
+		case "ConfigMap":
+			component, ok := javaOptionsConfigMaps[obj.GetName()]
+			if !ok || s.components[component].heap == "" {
+				return nil
+			}
+			heap := s.components[component].heap
+			options, _, _ := unstructured.NestedString(obj.Object, "data", "JAVA_TOOL_OPTIONS")
+			options = strings.TrimSpace(options + " -Xms" + heap + " -Xmx" + heap)
+			return unstructured.SetNestedField(obj.Object, options, "data", "JAVA_TOOL_OPTIONS")
+		}
+		return nil
+	}
+}
+
+// withResources sets the requests and limits of the deployment's container named after it.
+func withResources(obj *unstructured.Unstructured, size componentSize) error {
+	resources := map[string]any{
+		"requests": map[string]any{"cpu": size.cpuRequest, "memory": size.memoryRequest},
+		"limits":   map[string]any{"cpu": size.cpuLimit, "memory": size.memoryLimit},
+	}
+	return updateContainer(obj, obj.GetName(), func(container map[string]any) {
+		container["resources"] = resources
+	})
+}
+
+// updateContainer applies the update to the deployment's container of the given name.
+func updateContainer(obj *unstructured.Unstructured, name string, update func(container map[string]any)) error {
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if container, ok := container.(map[string]any); ok && container["name"] == name {
+			update(container)
+		}
+	}
+	return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSizingProfile(t *testing.T) {
+	definition := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", Size: "small"}
+	extraYaml, err := definition.extraManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := provisioningPlan{
+		definition: definition,
+		templates:  manifestSet{Connector: participantYaml, IdentityHub: identityhubYaml},
+		extraYaml:  extraYaml,
+		mutators:   definition.mutators(),
+	}
+	objects := map[string]*unstructured.Unstructured{}
+	_, err = plan.applyManifests(nil, context.Background(), func(_ client.Client, _ context.Context, object client.Object) error {
+		obj := object.(*unstructured.Unstructured)
+		objects[obj.GetKind()+"/"+obj.GetName()] = obj
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container := func(name string) map[string]any {
+		containers, _, _ := unstructured.NestedSlice(objects["Deployment/"+name].Object, "spec", "template", "spec", "containers")
+		return containers[0].(map[string]any)
+	}
+	limit, _, _ := unstructured.NestedString(container("controlplane"), "resources", "limits", "memory")
+	if limit != "512Mi" {
+		t.Errorf("expected the memory limit of the small profile, got %q", limit)
+	}
+	options, _, _ := unstructured.NestedString(objects["ConfigMap/ih-config"].Object, "data", "JAVA_TOOL_OPTIONS")
+	if !strings.HasPrefix(options, "-agentlib:jdwp") || !strings.HasSuffix(options, "-Xms384m -Xmx384m") {
+		t.Errorf("expected the heap to be added to the JVM options, got %q", options)
+	}
+
+	postgres := objects["Deployment/postgres"]
+	if strategy, _, _ := unstructured.NestedString(postgres.Object, "spec", "strategy", "type"); strategy != "Recreate" {
+		t.Errorf("expected postgres to be recreated, got %q", strategy)
+	}
+	volumes, _, _ := unstructured.NestedSlice(postgres.Object, "spec", "template", "spec", "volumes")
+	if claim, _, _ := unstructured.NestedString(volumes[len(volumes)-1].(map[string]any), "persistentVolumeClaim", "claimName"); claim != postgresDataClaim {
+		t.Errorf("expected the postgres data on the volume claim, got %v", volumes)
+	}
+	storage, _, _ := unstructured.NestedString(objects["PersistentVolumeClaim/"+postgresDataClaim].Object, "spec", "resources", "requests", "storage")
+	if storage != "1Gi" {
+		t.Errorf("expected a volume claim of 1Gi, got %q", storage)
+	}
+}
+
+func TestValidateSize(t *testing.T) {
+	if err := validateSize("medium"); err != nil {
+		t.Error(err)
+	}
+	if err := validateSize("huge"); err == nil || !strings.Contains(err.Error(), "large, medium, small") {
+		t.Errorf("expected the profiles to be listed, got %v", err)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How long a smoke test may take at most, negotiations and transfers are polled until then
+const smokeTestTimeout = 2 * time.Minute
+
+// How often the state of negotiations and transfers is polled
+var smokeTestPollInterval = 2 * time.Second
+
+// Steps of a smoke test, in order
+const (
+	smokeStepCatalog     = "catalog"
+	smokeStepNegotiation = "negotiation"
+	smokeStepTransfer    = "transfer"
+)
+
+var smokeTestSteps = []string{smokeStepCatalog, smokeStepNegotiation, smokeStepTransfer}
+
+const dspProtocol = "dataspace-protocol-http"
+
+// JSON-LD context of the management API requests, the odrl prefix is the one catalogs compact their policies with
+var smokeTestContext = map[string]any{"@vocab": "https://w3id.org/edc/v0.0.1/ns/", "odrl": "http://www.w3.org/ns/odrl/2/"}
+
+// smokeTestRequest selects the counterparty whose catalog the participant requests, the participant itself by
+// default, and optionally the asset that is negotiated.
+type smokeTestRequest struct {
+	Counterparty string `json:"counterparty,omitempty"`
+	AssetId      string `json:"assetId,omitempty"`
+}
+
+// smokeTestStep is the outcome of one step of a smoke test.
+type smokeTestStep struct {
+	Name string `json:"name"`
+	// State is COMPLETED, FAILED or SKIPPED after an earlier step failed
+	State    string `json:"state"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// smokeTestResult reports whether a participant can find, negotiate and transfer the data of a counterparty.
+type smokeTestResult struct {
+	Participant  string          `json:"participant"`
+	Counterparty string          `json:"counterparty"`
+	Passed       bool            `json:"passed"`
+	AssetId      string          `json:"assetId,omitempty"`
+	AgreementId  string          `json:"agreementId,omitempty"`
+	Steps        []smokeTestStep `json:"steps"`
+	StartedAt    time.Time       `json:"startedAt"`
+	FinishedAt   time.Time       `json:"finishedAt"`
+}
+
+// catalogOffer is a contract offer for a dataset of a catalog.
+type catalogOffer struct {
+	assetId string
+	policy  map[string]any
+}
+
+// smokeTest runs the participant's side of a data exchange with the provider through its management API: it requests
+// the provider's catalog via DSP, negotiates a contract for an offered dataset and starts a pull transfer, which is
+// terminated right away without pulling any data. Steps after a failed one are skipped.
+func smokeTest(ctx context.Context, consumer api.ManagementApi, provider ParticipantDefinition, assetId string) smokeTestResult {
+	result := smokeTestResult{Counterparty: provider.ParticipantName, StartedAt: time.Now().UTC()}
+	dspAddress := protocolEndpointUrl(provider)
+	var offer catalogOffer
+	steps := map[string]func() (string, error){
+		smokeStepCatalog: func() (string, error) {
+			var err error
+			if offer, err = requestOffer(ctx, consumer, dspAddress, provider.Did, assetId); err != nil {
+				return "", err
+			}
+			result.AssetId = offer.assetId
+			return fmt.Sprintf("%s offers %s", provider.ParticipantName, offer.assetId), nil
+		},
+		smokeStepNegotiation: func() (string, error) {
+			var err error
+			if result.AgreementId, err = negotiate(ctx, consumer, dspAddress, provider.Did, offer); err != nil {
+				return "", err
+			}
+			return "agreement " + result.AgreementId + " finalized", nil
+		},
+		smokeStepTransfer: func() (string, error) {
+			return transferDryRun(ctx, consumer, dspAddress, result.AgreementId)
+		},
+	}
+
+	failed := false
+	for _, name := range smokeTestSteps {
+		if failed {
+			result.Steps = append(result.Steps, smokeTestStep{Name: name, State: status.StepSkipped})
+			continue
+		}
+		started := time.Now()
+		message, err := steps[name]()
+		step := smokeTestStep{Name: name, State: status.StepCompleted, Message: message, Duration: time.Since(started).Round(time.Millisecond).String()}
+		if err != nil {
+			step.State, step.Message, failed = status.StepFailed, err.Error(), true
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	result.Passed = !failed
+	result.FinishedAt = time.Now().UTC()
+	return result
+}
+
+// requestOffer requests the provider's catalog and returns the first offer for the asset, for any asset if none is
+// given.
+func requestOffer(ctx context.Context, consumer api.ManagementApi, dspAddress string, providerDid string, assetId string) (catalogOffer, error) {
+	offers, err := requestCatalog(ctx, consumer, dspAddress, providerDid)
+	if err != nil {
+		return catalogOffer{}, err
+	}
+	for _, offer := range offers {
+		if assetId == "" || offer.assetId == assetId {
+			return offer, nil
+		}
+	}
+	if assetId != "" {
+		return catalogOffer{}, fmt.Errorf("the catalog has no offer for asset %s", assetId)
+	}
+	return catalogOffer{}, fmt.Errorf("the catalog offers no datasets to the participant")
+}
+
+// requestCatalog requests the catalog of the provider at the DSP address and returns its offers.
+func requestCatalog(ctx context.Context, consumer api.ManagementApi, dspAddress string, providerDid string) ([]catalogOffer, error) {
+	request, err := json.Marshal(map[string]any{
+		"@context":            smokeTestContext,
+		"@type":               "CatalogRequest",
+		"counterPartyAddress": dspAddress,
+		"counterPartyId":      providerDid,
+		"protocol":            dspProtocol,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := consumer.RequestCatalog(ctx, string(request))
+	if err != nil {
+		return nil, err
+	}
+	return catalogOffers(response)
+}
+
+// catalogOffers returns the offers of a compacted DCAT catalog. Single datasets and policies aren't wrapped in arrays.
+func catalogOffers(catalog string) ([]catalogOffer, error) {
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(catalog), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %w", err)
+	}
+	var offers []catalogOffer
+	for _, dataset := range jsonLdValues(parsed["dcat:dataset"]) {
+		assetId, _ := dataset["@id"].(string)
+		for _, policy := range jsonLdValues(dataset["odrl:hasPolicy"]) {
+			if _, ok := policy["@id"].(string); ok {
+				offers = append(offers, catalogOffer{assetId: assetId, policy: policy})
+			}
+		}
+	}
+	return offers, nil
+}
+
+func jsonLdValues(value any) []map[string]any {
+	switch value := value.(type) {
+	case map[string]any:
+		return []map[string]any{value}
+	case []any:
+		values := make([]map[string]any, 0, len(value))
+		for _, item := range value {
+			if object, ok := item.(map[string]any); ok {
+				values = append(values, object)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// negotiate negotiates a contract for the offer and returns the ID of the finalized agreement.
+func negotiate(ctx context.Context, consumer api.ManagementApi, dspAddress string, providerDid string, offer catalogOffer) (string, error) {
+	policy := make(map[string]any, len(offer.policy)+3)
+	for key, value := range offer.policy {
+		policy[key] = value
+	}
+	policy["@type"] = "odrl:Offer"
+	policy["odrl:assigner"] = map[string]any{"@id": providerDid}
+	policy["odrl:target"] = map[string]any{"@id": offer.assetId}
+	request, err := json.Marshal(map[string]any{
+		"@context":            smokeTestContext,
+		"@type":               "ContractRequest",
+		"counterPartyAddress": dspAddress,
+		"protocol":            dspProtocol,
+		"policy":              policy,
+	})
+	if err != nil {
+		return "", err
+	}
+	id, err := initiated(consumer.InitiateNegotiation(ctx, string(request)))
+	if err != nil {
+		return "", err
+	}
+	negotiation, err := awaitState(ctx, "negotiation "+id, func() (string, error) { return consumer.GetNegotiation(ctx, id) }, "FINALIZED")
+	if err != nil {
+		return "", err
+	}
+	agreementId, _ := negotiation["contractAgreementId"].(string)
+	return agreementId, nil
+}
+
+// transferDryRun starts a pull transfer of the agreement's asset and terminates it once it started. The data isn't
+// pulled, so nothing is transferred.
+func transferDryRun(ctx context.Context, consumer api.ManagementApi, dspAddress string, agreementId string) (string, error) {
+	request, err := json.Marshal(map[string]any{
+		"@context":            smokeTestContext,
+		"@type":               "TransferRequest",
+		"counterPartyAddress": dspAddress,
+		"protocol":            dspProtocol,
+		"contractId":          agreementId,
+		"transferType":        "HttpData-PULL",
+	})
+	if err != nil {
+		return "", err
+	}
+	id, err := initiated(consumer.InitiateTransfer(ctx, string(request)))
+	if err != nil {
+		return "", err
+	}
+	if _, err := awaitState(ctx, "transfer "+id, func() (string, error) { return consumer.GetTransferProcess(ctx, id) }, "STARTED"); err != nil {
+		return "", err
+	}
+	terminate, err := json.Marshal(map[string]any{"@context": smokeTestContext, "@type": "TerminateTransfer", "reason": "smoke test"})
+	if err != nil {
+		return "", err
+	}
+	if err := consumer.TerminateTransfer(ctx, id, string(terminate)); err != nil {
+		return "", fmt.Errorf("transfer %s started but terminating it failed: %w", id, err)
+	}
+	return fmt.Sprintf("transfer %s started and terminated without pulling data", id), nil
+}
+
+// initiated returns the ID of the negotiation or transfer process a management API call created.
+func initiated(response string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Id string `json:"@id"`
+	}
+	if err := json.Unmarshal([]byte(response), &created); err != nil || created.Id == "" {
+		return "", fmt.Errorf("the connector returned no ID: %s", response)
+	}
+	return created.Id, nil
+}
+
+// awaitState polls the negotiation or transfer process until it reaches the state, and fails when it is terminated
+// or the context is done.
+func awaitState(ctx context.Context, subject string, get func() (string, error), state string) (map[string]any, error) {
+	ticker := time.NewTicker(smokeTestPollInterval)
+	defer ticker.Stop()
+	current := ""
+	for {
+		response, err := get()
+		if err != nil {
+			return nil, err
+		}
+		var process map[string]any
+		if err := json.Unmarshal([]byte(response), &process); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", subject, err)
+		}
+		current, _ = process["state"].(string)
+		switch current {
+		case state:
+			return process, nil
+		case "TERMINATED":
+			detail, _ := process["errorDetail"].(string)
+			return nil, fmt.Errorf("%s was terminated: %s", subject, detail)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s is still %s: %w", subject, current, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// smokeTestDefinition returns the definition the participant was seeded with, which its DID and routes are taken from.
+func smokeTestDefinition(kubeClient client.Client, ctx context.Context, name string) (ParticipantDefinition, error) {
+	state, err := loadSeedingState(kubeClient, ctx, name)
+	if err != nil {
+		return ParticipantDefinition{}, err
+	}
+	if state.definition.ParticipantName == "" || state.definition.Did == "" {
+		return ParticipantDefinition{}, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("participant %s wasn't seeded by the provisioner", name))
+	}
+	return state.definition, nil
+}
+
+// runSmokeTest validates a provisioned participant end to end by exchanging data with a counterparty, the participant
+// itself unless the body or ?counterparty names another one in the caller's scope. The outcome of each step is
+// reported with 200, whether the test passed or not.
+func runSmokeTest(kubeClient client.Client, ctx context.Context, clients seedingClients, parser payloadParser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request smokeTestRequest
+		if len(c.Body()) > 0 {
+			if err := parser.parse(c, &request); err != nil {
+				return err
+			}
+		}
+		name := c.Params("participantName")
+		counterparty := c.Query("counterparty", request.Counterparty)
+		if counterparty == "" {
+			counterparty = name
+		}
+		if errs := validation.IsDNS1123Label(counterparty); len(errs) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid counterparty: "+errs[0])
+		}
+		if ok, err := inScope(kubeClient, ctx, tenantOf(c), counterparty); err != nil {
+			return err
+		} else if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "counterparty not found")
+		}
+
+		consumer, err := smokeTestDefinition(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		provider := consumer
+		if counterparty != name {
+			if provider, err = smokeTestDefinition(kubeClient, ctx, counterparty); err != nil {
+				return err
+			}
+		}
+		creds, err := loadCredentials(kubeClient, ctx, name)
+		if err != nil {
+			return err
+		}
+		testCtx, cancel := context.WithTimeout(c.UserContext(), smokeTestTimeout)
+		defer cancel()
+		mgmtApi := managementApi(consumer, clients.forParticipant(consumer).withTrace(c.UserContext()), creds)
+		result := smokeTest(testCtx, mgmtApi, provider, request.AssetId)
+		result.Participant = name
+		fmt.Printf("Smoke test of %s against %s passed: %t\n", name, counterparty, result.Passed)
+		return c.JSON(result)
+	}
+}
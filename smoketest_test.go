@@ -0,0 +1,92 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"aruba-provisioner/api/status"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSmokeTest(t *testing.T) {
+	smokeTestPollInterval = 10 * time.Millisecond
+	var mu sync.Mutex
+	var requests []string
+	var negotiationPolls int
+	var negotiation map[string]any
+	catalog := `{"@id":"catalog","dcat:dataset":[{"@id":"asset-1","odrl:hasPolicy":{"@id":"offer-1","odrl:permission":[]}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /catalog/request":
+			_, _ = w.Write([]byte(catalog))
+		case "POST /contractnegotiations":
+			_ = json.Unmarshal(body, &negotiation)
+			_, _ = w.Write([]byte(`{"@id":"negotiation-1"}`))
+		case "GET /contractnegotiations/negotiation-1":
+			negotiationPolls++
+			if negotiationPolls < 2 {
+				_, _ = w.Write([]byte(`{"state":"REQUESTED"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"state":"FINALIZED","contractAgreementId":"agreement-1"}`))
+		case "POST /transferprocesses":
+			_, _ = w.Write([]byte(`{"@id":"transfer-1"}`))
+		case "GET /transferprocesses/transfer-1":
+			_, _ = w.Write([]byte(`{"state":"STARTED"}`))
+		case "POST /transferprocesses/transfer-1/terminate":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	consumer := &api.ApiClient{BaseUrl: server.URL}
+	provider := ParticipantDefinition{ParticipantName: "provider", Did: "did:web:provider"}
+
+	result := smokeTest(context.Background(), consumer, provider, "")
+	if !result.Passed || result.AssetId != "asset-1" || result.AgreementId != "agreement-1" {
+		t.Fatalf("expected the smoke test to pass, got %+v", result)
+	}
+	for _, step := range result.Steps {
+		if step.State != status.StepCompleted {
+			t.Errorf("expected step %s to complete, got %+v", step.Name, step)
+		}
+	}
+	policy, _ := negotiation["policy"].(map[string]any)
+	if policy["@id"] != "offer-1" || policy["odrl:assigner"].(map[string]any)["@id"] != "did:web:provider" {
+		t.Errorf("unexpected negotiated policy %v", policy)
+	}
+	if !slices.Contains(requests, "POST /transferprocesses/transfer-1/terminate") {
+		t.Errorf("expected the transfer to be terminated, requests %v", requests)
+	}
+
+	catalog = `{"@id":"catalog","dcat:dataset":[]}`
+	result = smokeTest(context.Background(), consumer, provider, "")
+	if result.Passed || result.Steps[0].State != status.StepFailed || !strings.Contains(result.Steps[0].Message, "no datasets") {
+		t.Fatalf("expected the catalog step to fail, got %+v", result)
+	}
+	if result.Steps[1].State != status.StepSkipped || result.Steps[2].State != status.StepSkipped {
+		t.Errorf("expected the steps after the failed one to be skipped, got %+v", result.Steps)
+	}
+}
+
+func TestCatalogOffersOfSingleDataset(t *testing.T) {
+	offers, err := catalogOffers(`{"dcat:dataset":{"@id":"asset-1","odrl:hasPolicy":[{"@id":"offer-1"},{"@id":"offer-2"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 2 || offers[1].assetId != "asset-1" || offers[1].policy["@id"] != "offer-2" {
+		t.Errorf("unexpected offers %+v", offers)
+	}
+}
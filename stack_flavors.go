@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Annotation recording the flavor a participant was provisioned with
+const flavorAnnotation = "aruba-provisioner/flavor"
+
+// FlavorConfig is a named participant stack, e.g. one with an external postgres or a minimal one for development,
+// selected by ParticipantDefinition.Flavor.
+type FlavorConfig struct {
+	// Manifests is the directory, URL, configmap:<namespace>/<name> or helm:<chart> the participants of the flavor are
+	// rendered from, like --manifests. The embedded manifests are used if empty.
+	Manifests string `json:"manifests,omitempty"`
+	// SeedDir holds the seed resources and presigned credentials of the participants of the flavor, like --seed-dir.
+	// The embedded demo resources are used if empty.
+	SeedDir string `json:"seedDir,omitempty"`
+}
+
+// flavorConfigs holds the flavors loaded from the file given with --flavors, keyed by name.
+var flavorConfigs map[string]FlavorConfig
+
+// loadFlavors reads the flavors from a YAML file keyed by flavor name.
+func loadFlavors(path string) (map[string]FlavorConfig, error) {
+	flavors := make(map[string]FlavorConfig)
+	if path == "" {
+		return flavors, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read flavors: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &flavors); err != nil {
+		return nil, fmt.Errorf("parse flavors: %w", err)
+	}
+	for name := range flavors {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("flavor %s: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	return flavors, nil
+}
+
+// seedDirOf returns the seed directory of the participant's flavor, --seed-dir for participants without flavor.
+func seedDirOf(definition ParticipantDefinition) string {
+	if definition.Flavor == "" {
+		return seedDir
+	}
+	return flavorConfigs[definition.Flavor].SeedDir
+}
+
+// stackFlavors serves the manifest set of every flavor, the manifests of --manifests to participants without flavor.
+type stackFlavors struct {
+	defaults *manifestStore
+	named    map[string]*manifestStore
+}
+
+func newStackFlavors(defaults *manifestStore, configs map[string]FlavorConfig, c client.Client) *stackFlavors {
+	named := make(map[string]*manifestStore, len(configs))
+	for name, config := range configs {
+		store := newManifestStore(config.Manifests, c)
+		store.helm = defaults.helm
+		named[name] = store
+	}
+	return &stackFlavors{defaults: defaults, named: named}
+}
+
+func (f *stackFlavors) names() []string {
+	return slices.Sorted(maps.Keys(f.named))
+}
+
+// manifests returns the manifest set of the flavor, rejecting unknown flavors with a 400.
+func (f *stackFlavors) manifests(flavor string) (manifestSet, error) {
+	if flavor == "" {
+		return f.defaults.get(), nil
+	}
+	store, ok := f.named[flavor]
+	if !ok {
+		message := fmt.Sprintf("unknown flavor %q", flavor)
+		if len(f.named) > 0 {
+			message += ", must be one of " + strings.Join(f.names(), ", ")
+		}
+		return manifestSet{}, &validationError{Fields: []fieldError{{"flavor", message}}}
+	}
+	return store.get(), nil
+}
+
+// reload reloads the manifests of every flavor. Flavors whose manifests fail to load keep their current ones, the
+// errors of all of them are returned.
+func (f *stackFlavors) reload(ctx context.Context) (manifestSet, map[string]manifestSet, error) {
+	defaults, err := f.defaults.reload(ctx)
+	errs := []error{err}
+	flavors := make(map[string]manifestSet, len(f.named))
+	for _, name := range f.names() {
+		set, err := f.named[name].reload(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("flavor %s: %w", name, err))
+			continue
+		}
+		flavors[name] = set
+	}
+	return defaults, flavors, errors.Join(errs...)
+}
+
+// flavorMutator records the participant's flavor on its namespace.
+func flavorMutator(flavor string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, map[string]string{flavorAnnotation: flavor})
+		}
+		return nil
+	}
+}
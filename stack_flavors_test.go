@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStackFlavors(t *testing.T) {
+	dir := t.TempDir()
+	minimal := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: minimal\n  namespace: ${PARTICIPANT_NAME}\n"
+	seeds := filepath.Join(dir, "seeds")
+	files := map[string]string{
+		"flavors.yaml":                 "minimal-dev:\n  manifests: " + filepath.Join(dir, "minimal") + "\n  seedDir: " + seeds + "\n",
+		"minimal/" + connectorManifest: minimal,
+		"seeds/asset.json":             `{"@id": "asset-dev", "@type": "Asset", "dataAddress": {"type": "HttpData"}}`,
+		"seeds/alice.membership.jwt":   "eyJhbGciOiJFUzI1NiJ9.e30.c2ln",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configs, err := loadFlavors(filepath.Join(dir, "flavors.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(configs map[string]FlavorConfig) { flavorConfigs = configs }(flavorConfigs)
+	flavorConfigs = configs
+	flavors := newStackFlavors(newManifestStore("", nil), configs, nil)
+	if err := warmUpTemplates(context.Background(), flavors); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := flavors.manifests("minimal-dev")
+	if err != nil || set.Connector != minimal || set.IdentityHub != identityhubYaml {
+		t.Errorf("expected the manifests of the flavor, got %v", err)
+	}
+	if set, _ := flavors.manifests(""); set.Connector != participantYaml {
+		t.Error("expected participants without flavor to get the default manifests")
+	}
+	if _, err := flavors.manifests("postgres-external"); err == nil || !strings.Contains(err.Error(), "minimal-dev") {
+		t.Errorf("expected the unknown flavor to be rejected, got %v", err)
+	}
+
+	alice := ParticipantDefinition{ParticipantName: "alice", Flavor: "minimal-dev"}
+	catalog, err := connectorCatalog(alice)
+	if err != nil || len(catalog.assets) != 1 || !strings.Contains(catalog.assets[0], "asset-dev") {
+		t.Errorf("expected the seed resources of the flavor, got %v %v", catalog.assets, err)
+	}
+	if credentials, err := presignedCredentials(alice); err != nil || len(credentials) != 1 {
+		t.Errorf("expected the presigned credential of the flavor, got %v %v", credentials, err)
+	}
+	if catalog, _ := connectorCatalog(ParticipantDefinition{ParticipantName: "bob"}); len(catalog.assets) != 2 {
+		t.Errorf("expected the embedded seed resources without flavor, got %d", len(catalog.assets))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "flavors.yaml"), []byte("Minimal_Dev: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFlavors(filepath.Join(dir, "flavors.yaml")); err == nil {
+		t.Error("expected a flavor name that isn't a DNS label to be rejected")
+	}
+}
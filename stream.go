@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Streamed participant statuses are reloaded at this interval, and whenever one of the participant's deployments
+// changes, so seeding progress shows up without deployment changes
+const statusStreamInterval = 2 * time.Second
+
+// streamJson pushes the loaded value as a server-sent event whenever it changes, until the client disconnects or
+// the context is cancelled. The value is loaded at the interval and, when subscribe is given, as soon as the
+// subscription reports a change. The loader runs outside the request lifecycle, so it must not capture the fiber.Ctx.
+func streamJson(c *fiber.Ctx, ctx context.Context, interval time.Duration, subscribe func() (<-chan struct{}, func()), load func() (any, error)) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var changes <-chan struct{}
+		if subscribe != nil {
+			var unsubscribe func()
+			changes, unsubscribe = subscribe()
+			defer unsubscribe()
+		}
+
+		var last []byte
+		for {
+			value, err := load()
+			if err != nil {
+				value = fiber.Map{"error": err.Error()}
+			}
+			payload, err := json.Marshal(value)
+			if err != nil {
+				return
+			}
+			if bytes.Equal(payload, last) {
+				_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+			} else {
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", payload)
+				last = payload
+			}
+			if err := w.Flush(); err != nil {
+				// client went away
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-changes:
+			}
+		}
+	})
+	return nil
+}
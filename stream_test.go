@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStreamJson(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	subscribed, unsubscribed := false, false
+	subscribe := func() (<-chan struct{}, func()) {
+		subscribed = true
+		return make(chan struct{}), func() { unsubscribed = true }
+	}
+	app := fiber.New()
+	app.Get("/stream", func(c *fiber.Ctx) error {
+		return streamJson(c, ctx, time.Hour, subscribe, func() (any, error) {
+			return fiber.Map{"status": "READY"}, nil
+		})
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/stream", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+		t.Errorf("unexpected content type %s", resp.Header.Get(fiber.HeaderContentType))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `data: {"status":"READY"}`) {
+		t.Errorf("expected the status as an event, got %q", body)
+	}
+	if !subscribed || !unsubscribed {
+		t.Error("expected the stream to subscribe to changes and unsubscribe when it ends")
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Placeholders substituted in the manifest templates when a participant is rendered
+var manifestPlaceholders = []string{"PARTICIPANT_NAME", "PARTICIPANT_ID"}
+
+// Placeholders substituted in the participant context created in the identity hub
+var participantContextPlaceholders = []string{"PARTICIPANT_NAME", "PARTICIPANT_DID", "CREDENTIAL_SERVICE_URL", "PROTOCOL_ENDPOINT_URL"}
+
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// Kinds of the templates in a template report
+const (
+	templateKindManifest = "manifest"
+	templateKindChart    = "chart"
+	templateKindSeed     = "seed"
+)
+
+// templateInfo describes a manifest template or seed resource participants are provisioned with.
+type templateInfo struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Source   string `json:"source"`
+	Checksum string `json:"checksum,omitempty"`
+	// Documents is the number of Kubernetes objects of a manifest
+	Documents    int      `json:"documents,omitempty"`
+	Placeholders []string `json:"placeholders,omitempty"`
+	Problems     []string `json:"problems,omitempty"`
+}
+
+// templateReport describes the loaded template set and the problems found in it.
+type templateReport struct {
+	Flavor    string         `json:"flavor,omitempty"`
+	Source    string         `json:"source"`
+	LoadedAt  time.Time      `json:"loadedAt"`
+	Valid     bool           `json:"valid"`
+	Templates []templateInfo `json:"templates"`
+}
+
+// err lists the problems of all templates, nil if there are none.
+func (r templateReport) err() error {
+	var problems []string
+	for _, template := range r.Templates {
+		for _, problem := range template.Problems {
+			problems = append(problems, fmt.Sprintf("%s %s: %s", template.Kind, template.Name, problem))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d problems in the templates:\n  %s", len(problems), strings.Join(problems, "\n  "))
+}
+
+func templateChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// placeholdersOf returns the placeholders used in the template, sorted.
+func placeholdersOf(content string) []string {
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkPlaceholders rejects placeholders that aren't substituted, which would end up verbatim in the rendered objects.
+func checkPlaceholders(content string, known []string) error {
+	var unknown []string
+	for _, name := range placeholdersOf(content) {
+		if !slices.Contains(known, name) {
+			unknown = append(unknown, "${"+name+"}")
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown placeholders %s, expected %s", strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// validateManifestTemplate checks that a manifest template only uses known placeholders and renders Kubernetes
+// objects.
+func validateManifestTemplate(manifest string) error {
+	if err := checkPlaceholders(manifest, manifestPlaceholders); err != nil {
+		return err
+	}
+	return validateManifest(manifest)
+}
+
+func inspectManifest(name string, source string, manifest string) templateInfo {
+	info := templateInfo{Name: name, Kind: templateKindManifest, Source: source, Checksum: templateChecksum(manifest), Placeholders: placeholdersOf(manifest)}
+	if err := validateManifestTemplate(manifest); err != nil {
+		info.Problems = append(info.Problems, err.Error())
+	}
+	for _, doc := range strings.Split(manifest, "---") {
+		if strings.TrimSpace(doc) != "" {
+			info.Documents++
+		}
+	}
+	return info
+}
+
+// inspectParticipantContext checks that the participant context is valid JSON once its placeholders are substituted.
+func inspectParticipantContext() templateInfo {
+	info := templateInfo{Name: "participant.json", Kind: templateKindSeed, Source: "embedded", Checksum: templateChecksum(participantJson), Placeholders: placeholdersOf(participantJson)}
+	if err := checkPlaceholders(participantJson, participantContextPlaceholders); err != nil {
+		info.Problems = append(info.Problems, err.Error())
+	}
+	rendered := placeholderPattern.ReplaceAllString(participantJson, "sample")
+	if !json.Valid([]byte(rendered)) {
+		info.Problems = append(info.Problems, "not valid JSON")
+	}
+	return info
+}
+
+// inspectSeedResource checks the fields the management API requires of a seed resource of the JSON-LD type.
+func inspectSeedResource(seedType string, source string, body string) templateInfo {
+	info := templateInfo{Kind: templateKindSeed, Source: source, Checksum: templateChecksum(body)}
+	var entity map[string]any
+	if err := json.Unmarshal([]byte(body), &entity); err != nil {
+		info.Name = seedType
+		info.Problems = append(info.Problems, "not a JSON object: "+err.Error())
+		return info
+	}
+	id, _ := entity["@id"].(string)
+	info.Name = seedType + " " + id
+	if id == "" {
+		info.Problems = append(info.Problems, "missing @id")
+	}
+	if entityType, _ := entity["@type"].(string); strings.TrimPrefix(entityType, "edc:") != seedType {
+		info.Problems = append(info.Problems, fmt.Sprintf("@type %q, expected %s", entityType, seedType))
+	}
+	var required []string
+	switch seedType {
+	case seedTypeAsset:
+		required = []string{"dataAddress"}
+	case seedTypePolicyDefinition:
+		required = []string{"policy"}
+	case seedTypeContractDefinition:
+		required = []string{"accessPolicyId", "contractPolicyId"}
+	}
+	for _, field := range required {
+		if value, ok := entity[field]; !ok || value == "" || value == nil {
+			info.Problems = append(info.Problems, "missing "+field)
+		}
+	}
+	return info
+}
+
+// inspectSeedCatalog checks the default seed resources, those of the seed directory dir replacing the embedded ones
+// of their type.
+func inspectSeedCatalog(dir string) []templateInfo {
+	catalog := embeddedCatalog()
+	var loaded seedCatalog
+	if dir != "" {
+		var err error
+		if loaded, err = loadSeedDir(dir); err != nil {
+			return []templateInfo{{Name: dir, Kind: templateKindSeed, Source: dir, Problems: []string{err.Error()}}}
+		}
+	}
+	var infos []templateInfo
+	for _, resources := range []struct {
+		seedType string
+		embedded []string
+		loaded   []string
+	}{
+		{seedTypeAsset, catalog.assets, loaded.assets},
+		{seedTypePolicyDefinition, catalog.policies, loaded.policies},
+		{seedTypeContractDefinition, catalog.contractDefinitions, loaded.contractDefinitions},
+	} {
+		bodies, source := resources.embedded, "embedded"
+		if len(resources.loaded) > 0 {
+			bodies, source = resources.loaded, dir
+		}
+		for _, body := range bodies {
+			infos = append(infos, inspectSeedResource(resources.seedType, source, body))
+		}
+	}
+	return infos
+}
+
+// inspectTemplates validates the manifest templates of the set, those of the issuer services and federated catalogs,
+// the participant context and the default seed resources of the seed directory. Charts are checked when they are
+// loaded, by rendering them for a sample participant.
+func inspectTemplates(set manifestSet, seeds string) templateReport {
+	report := templateReport{Source: set.Source, LoadedAt: set.LoadedAt}
+	if set.Chart != nil {
+		report.Templates = append(report.Templates, templateInfo{Name: set.Chart.chart, Kind: templateKindChart, Source: set.Source})
+	} else {
+		report.Templates = append(report.Templates,
+			inspectManifest(connectorManifest, set.Source, set.Connector),
+			inspectManifest(identityhubManifest, set.Source, set.IdentityHub))
+	}
+	report.Templates = append(report.Templates,
+		inspectManifest("issuerservice.yaml", "embedded", issuerServiceYaml),
+		inspectManifest("federatedcatalog.yaml", "embedded", federatedCatalogYaml),
+		inspectParticipantContext())
+	report.Templates = append(report.Templates, inspectSeedCatalog(seeds)...)
+	report.Valid = report.err() == nil
+	return report
+}
+
+// inspectFlavor validates the templates of the flavor, those of the default stack if it is empty.
+func inspectFlavor(flavors *stackFlavors, flavor string) (templateReport, error) {
+	set, err := flavors.manifests(flavor)
+	if err != nil {
+		return templateReport{}, err
+	}
+	report := inspectTemplates(set, seedDirOf(ParticipantDefinition{Flavor: flavor}))
+	report.Flavor = flavor
+	return report, nil
+}
+
+// getTemplates reports the loaded template set of the default stack, or of the flavor given with ?flavor, where each
+// template comes from, its checksum and its problems. Seed resources are read again, so changes to the seed directory
+// show up without a restart.
+func getTemplates(flavors *stackFlavors) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report, err := inspectFlavor(flavors, c.Query("flavor"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(report)
+	}
+}
+
+// warmUpTemplates loads and validates the templates of all flavors at startup, so broken templates stop the
+// provisioner before it accepts participants rather than failing their provisioning.
+func warmUpTemplates(ctx context.Context, flavors *stackFlavors) error {
+	if _, _, err := flavors.reload(ctx); err != nil {
+		return err
+	}
+	var errs []error
+	for _, flavor := range append([]string{""}, flavors.names()...) {
+		report, err := inspectFlavor(flavors, flavor)
+		if err == nil {
+			err = report.err()
+		}
+		if err != nil && flavor != "" {
+			err = fmt.Errorf("flavor %s: %w", flavor, err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("Validated %d templates from %s\n", len(report.Templates), report.Source)
+	}
+	return errors.Join(errs...)
+}
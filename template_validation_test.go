@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedTemplatesAreValid(t *testing.T) {
+	report := inspectTemplates(newManifestStore("", nil).get(), "")
+	if err := report.err(); err != nil || !report.Valid {
+		t.Fatalf("expected the embedded templates to be valid, got %v", err)
+	}
+	connector := report.Templates[0]
+	if connector.Name != connectorManifest || connector.Source != "embedded" || connector.Checksum != templateChecksum(participantYaml) || connector.Documents == 0 {
+		t.Errorf("unexpected connector template %+v", connector)
+	}
+	if strings.Join(connector.Placeholders, ",") != "PARTICIPANT_ID,PARTICIPANT_NAME" {
+		t.Errorf("unexpected placeholders %v", connector.Placeholders)
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	dir := t.TempDir()
+	connector := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${PARTICIPANT_NAME}-${PARTICIPANT_REGION}\n"
+	if err := os.WriteFile(filepath.Join(dir, connectorManifest), []byte(connector), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := warmUpTemplates(context.Background(), newStackFlavors(newManifestStore(dir, nil), nil, nil)); err == nil || !strings.Contains(err.Error(), "${PARTICIPANT_REGION}") {
+		t.Errorf("expected the unknown placeholder to be reported, got %v", err)
+	}
+
+	seeds := t.TempDir()
+	asset := `{"@id": "asset-9", "@type": "Asset", "properties": {}}`
+	if err := os.WriteFile(filepath.Join(seeds, "asset.json"), []byte(asset), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	report := inspectTemplates(newManifestStore("", nil).get(), seeds)
+	err := report.err()
+	if report.Valid || err == nil || !strings.Contains(err.Error(), "seed Asset asset-9: missing dataAddress") {
+		t.Errorf("expected the asset without data address to be reported, got %v", err)
+	}
+	for _, template := range report.Templates {
+		if template.Kind == templateKindSeed && strings.HasPrefix(template.Name, seedTypePolicyDefinition) && template.Source != "embedded" {
+			t.Errorf("expected the policies to stay embedded, got %+v", template)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Namespace label recording the tenant that provisioned a participant
+const tenantLabel = "aruba-provisioner/tenant"
+
+// tenantOf returns the tenant the caller's credentials are scoped to, empty for unscoped callers.
+func tenantOf(c *fiber.Ctx) string {
+	if caller, ok := c.Locals(principalKey).(*principal); ok {
+		return caller.Tenant
+	}
+	return ""
+}
+
+// tenantSelector narrows a listing of managed namespaces to those of the tenant.
+func tenantSelector(tenant string) client.MatchingLabels {
+	selector := client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}
+	if tenant != "" {
+		selector[tenantLabel] = tenant
+	}
+	return selector
+}
+
+// ownerOf returns the tenant that provisioned the participant, empty if it was provisioned by an unscoped caller.
+func ownerOf(c client.Client, ctx context.Context, name string) (string, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return namespace.Labels[tenantLabel], nil
+}
+
+// inScope reports whether the participant belongs to the tenant. Participants that don't exist are in every scope,
+// so tenants can create them. Unscoped callers can access all participants.
+func inScope(c client.Client, ctx context.Context, tenant string, name string) (bool, error) {
+	if tenant == "" {
+		return true, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return namespace.Labels[tenantLabel] == tenant, nil
+}
+
+// claimParticipant rejects provisioning a participant into a system namespace, into a namespace the provisioner
+// doesn't manage unless it adopts it, or that already exists under another tenant.
+func claimParticipant(c client.Client, ctx context.Context, tenant string, name string, adopt bool) error {
+	namespace := conflictingResource{Kind: "Namespace", Name: name}
+	if status.IsSystemNamespace(name) {
+		return &conflictError{code: codeNameReserved, resource: namespace, message: fmt.Sprintf("%s is a system namespace and can't be a participant", name)}
+	}
+	if adopt {
+		if err := claimAdoptedNamespace(c, ctx, name); err != nil {
+			return err
+		}
+	}
+	existing := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, existing); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && !adopt && existing.Labels[status.ManagedByLabel] != status.ManagedByValue {
+		return &conflictError{code: codeNameConflict, resource: namespace, message: fmt.Sprintf("namespace %s already exists and isn't a participant", name)}
+	}
+	ok, err := inScope(c, ctx, tenant, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("participant %s already exists", name))
+	}
+	return nil
+}
+
+// requireScope rejects requests for participants of other tenants as if the participant didn't exist.
+func requireScope(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ok, err := inScope(kubeClient, ctx, tenantOf(c), c.Params("participantName"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "participant not found")
+		}
+		return c.Next()
+	}
+}
+
+// filterScope drops the participants outside the tenant's scope.
+func filterScope(c client.Client, ctx context.Context, tenant string, names []string) ([]string, error) {
+	if tenant == "" {
+		return names, nil
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, tenantSelector(tenant)); err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		owned[namespace.Name] = true
+	}
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		if owned[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// tenantMutator labels the participant namespace with the tenant that provisioned it.
+func tenantMutator(tenant string) objectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if tenant == "" || obj.GetKind() != "Namespace" {
+			return nil
+		}
+		addLabels(obj, map[string]string{tenantLabel: tenant})
+		return nil
+	}
+}
+
+// requireJobScope hides the jobs of participants outside the caller's scope.
+func requireJobScope(kubeClient client.Client, ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job := jobs.get(c.Params("id"))
+		if job == nil {
+			return fiber.NewError(fiber.StatusNotFound, "no such job")
+		}
+		job.mu.Lock()
+		participant := job.Participant
+		job.mu.Unlock()
+		ok, err := inScope(kubeClient, ctx, tenantOf(c), participant)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "no such job")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aruba-provisioner/api/status"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// namespaceClient is an in-memory client serving Namespace reads for unit tests.
+type namespaceClient struct {
+	client.Client
+	namespaces map[string]*corev1.Namespace
+}
+
+func newNamespaceClient(namespaces ...*corev1.Namespace) *namespaceClient {
+	c := &namespaceClient{namespaces: make(map[string]*corev1.Namespace)}
+	for _, namespace := range namespaces {
+		c.namespaces[namespace.Name] = namespace
+	}
+	return c
+}
+
+func (c *namespaceClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	namespace, ok := c.namespaces[key.Name]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, key.Name)
+	}
+	namespace.DeepCopyInto(obj.(*corev1.Namespace))
+	return nil
+}
+
+func (c *namespaceClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	namespaces := list.(*corev1.NamespaceList)
+	for _, namespace := range c.namespaces {
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labelSet(namespace.Labels)) {
+			continue
+		}
+		namespaces.Items = append(namespaces.Items, *namespace.DeepCopy())
+	}
+	return nil
+}
+
+func tenantNamespace(name string, tenant string) *corev1.Namespace {
+	labels := map[string]string{status.ManagedByLabel: status.ManagedByValue}
+	if tenant != "" {
+		labels[tenantLabel] = tenant
+	}
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestTenantOfCredentials(t *testing.T) {
+	issuer, sign := newTestIssuer(t)
+	auth := newApiAuth("static-key,acme:acme-key", "admin-key", issuer.URL, "", "tenant", "")
+	app := fiber.New()
+	app.Use(auth.middleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(tenantOf(c))
+	})
+
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantTenant string
+	}{
+		{"unscoped key", map[string]string{"Authorization": "Bearer static-key"}, fiber.StatusOK, ""},
+		{"scoped key", map[string]string{"Authorization": "Bearer acme-key"}, fiber.StatusOK, "acme"},
+		{"tenant name is not a key", map[string]string{"Authorization": "Bearer acme"}, fiber.StatusUnauthorized, ""},
+		{"admin key", map[string]string{"x-api-key": "admin-key"}, fiber.StatusOK, ""},
+		{"token with tenant", map[string]string{"Authorization": "Bearer " + sign(map[string]any{"iss": issuer.URL, "exp": exp, "tenant": "globex"})}, fiber.StatusOK, "globex"},
+		{"token without tenant", map[string]string{"Authorization": "Bearer " + sign(map[string]any{"iss": issuer.URL, "exp": exp})}, fiber.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest("GET", "/", nil)
+		for k, v := range tt.headers {
+			request.Header.Set(k, v)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, response.StatusCode, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != fiber.StatusOK {
+			continue
+		}
+		body := make([]byte, 64)
+		n, _ := response.Body.Read(body)
+		if got := string(body[:n]); got != tt.wantTenant {
+			t.Errorf("%s: tenant = %q, want %q", tt.name, got, tt.wantTenant)
+		}
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	c := newNamespaceClient(tenantNamespace("acme-edc", "acme"), tenantNamespace("globex-edc", "globex"), tenantNamespace("legacy", ""))
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals(principalKey, &principal{Subject: "api-key", Tenant: ctx.Get("x-tenant")})
+		return ctx.Next()
+	})
+	app.Get("/:participantName", requireScope(c, context.Background()), func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		tenant      string
+		participant string
+		want        int
+	}{
+		{"acme", "acme-edc", fiber.StatusOK},
+		{"acme", "globex-edc", fiber.StatusNotFound},
+		{"acme", "legacy", fiber.StatusNotFound},
+		{"acme", "unknown", fiber.StatusOK},
+		{"", "globex-edc", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest("GET", "/"+tt.participant, nil)
+		request.Header.Set("x-tenant", tt.tenant)
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tt.want {
+			t.Errorf("tenant %q, participant %s: status = %d, want %d", tt.tenant, tt.participant, response.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestFilterScope(t *testing.T) {
+	c := newNamespaceClient(tenantNamespace("acme-edc", "acme"), tenantNamespace("globex-edc", "globex"))
+	names := []string{"acme-edc", "globex-edc"}
+
+	filtered, err := filterScope(c, context.Background(), "acme", names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || filtered[0] != "acme-edc" {
+		t.Errorf("filtered = %v, want only acme-edc", filtered)
+	}
+	all, err := filterScope(c, context.Background(), "", names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("unscoped callers see %d participants, want 2", len(all))
+	}
+}
+
+func TestClaimParticipant(t *testing.T) {
+	c := newNamespaceClient(tenantNamespace("acme-edc", "acme"), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}})
+	tests := []struct {
+		name     string
+		tenant   string
+		wantCode string
+	}{
+		{"new-edc", "acme", ""},
+		{"acme-edc", "acme", ""},
+		{"acme-edc", "globex", statusCode(fiber.StatusConflict)},
+		{"monitoring", "", codeNameConflict},
+		{"kube-system", "", codeNameReserved},
+		{"default", "", codeNameReserved},
+	}
+	for _, tt := range tests {
+		err := claimParticipant(c, context.Background(), tt.tenant, tt.name, false)
+		if tt.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		p := problemOf(err)
+		if p.Status != fiber.StatusConflict || p.Code != tt.wantCode {
+			t.Errorf("%s: got %d %s, want 409 %s", tt.name, p.Status, p.Code, tt.wantCode)
+		}
+		if tt.wantCode != statusCode(fiber.StatusConflict) && (p.Conflict == nil || p.Conflict.Kind != "Namespace" || p.Conflict.Name != tt.name) {
+			t.Errorf("%s: conflicting resource = %+v", tt.name, p.Conflict)
+		}
+	}
+}
+
+func TestTenantMutator(t *testing.T) {
+	namespace := &unstructured.Unstructured{}
+	namespace.SetKind("Namespace")
+	namespace.SetLabels(map[string]string{status.ManagedByLabel: status.ManagedByValue})
+	deployment := &unstructured.Unstructured{}
+	deployment.SetKind("Deployment")
+
+	for _, obj := range []*unstructured.Unstructured{namespace, deployment} {
+		if err := tenantMutator("acme")(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := namespace.GetLabels()[tenantLabel]; got != "acme" {
+		t.Errorf("namespace tenant = %q, want acme", got)
+	}
+	if namespace.GetLabels()[status.ManagedByLabel] != status.ManagedByValue {
+		t.Error("existing labels were dropped")
+	}
+	if _, ok := deployment.GetLabels()[tenantLabel]; ok {
+		t.Error("only the namespace is labelled with the tenant")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The server certificate is reloaded at this interval, so renewed certificates are served without a restart
+var certificateReloadInterval = time.Minute
+
+// certificateSource returns the PEM encoded certificate chain and private key the API is served with.
+type certificateSource func(ctx context.Context) ([]byte, []byte, error)
+
+func fileCertificates(certFile string, keyFile string) certificateSource {
+	return func(context.Context) ([]byte, []byte, error) {
+		cert, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cert, key, nil
+	}
+}
+
+// secretCertificates reads the certificate from a kubernetes.io/tls Secret, e.g. one maintained by cert-manager.
+func secretCertificates(c client.Client, key client.ObjectKey) certificateSource {
+	return func(ctx context.Context) ([]byte, []byte, error) {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			return nil, nil, err
+		}
+		return secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], nil
+	}
+}
+
+// secretKey parses a Secret given as namespace/name, or as name in the default namespace.
+func secretKey(secret string, defaultNamespace string) client.ObjectKey {
+	if namespace, name, ok := strings.Cut(secret, "/"); ok {
+		return client.ObjectKey{Namespace: namespace, Name: name}
+	}
+	return client.ObjectKey{Namespace: defaultNamespace, Name: secret}
+}
+
+// certificateReloader serves the latest certificate of its source. A certificate that fails to load is logged and the
+// previous one kept.
+type certificateReloader struct {
+	source  certificateSource
+	current atomic.Pointer[tls.Certificate]
+	// loaded is the certificate and key last parsed, unchanged ones aren't parsed again
+	loaded []byte
+}
+
+func newCertificateReloader(ctx context.Context, source certificateSource) (*certificateReloader, error) {
+	r := &certificateReloader{source: source}
+	if err := r.reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certificateReloader) reload(ctx context.Context) error {
+	cert, key, err := r.source(ctx)
+	if err != nil {
+		return err
+	}
+	loaded := append(append([]byte{}, cert...), key...)
+	if bytes.Equal(loaded, r.loaded) {
+		return nil
+	}
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return err
+	}
+	r.current.Store(&certificate)
+	r.loaded = loaded
+	return nil
+}
+
+// watch reloads the certificate until the context is cancelled.
+func (r *certificateReloader) watch(ctx context.Context) {
+	ticker := time.NewTicker(certificateReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reload(ctx); err != nil {
+				fmt.Printf("reloading the TLS certificate failed, serving the previous one: %v\n", err)
+			}
+		}
+	}
+}
+
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current.Load(), nil
+}
+
+// serverTlsConfig returns the TLS settings of the API server. With a client CA, client certificates issued by it
+// authenticate callers, and are required if requireClientCert is set.
+func serverTlsConfig(certificates *certificateReloader, clientCaFile string, requireClientCert bool) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certificates.getCertificate}
+	if clientCaFile == "" {
+		if requireClientCert {
+			return nil, errors.New("requiring client certificates needs a client CA")
+		}
+		return config, nil
+	}
+	pem, err := os.ReadFile(clientCaFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", clientCaFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if requireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// listenTls serves the app over TLS with the certificate of the source, reloading it until ctx is cancelled.
+func listenTls(ctx context.Context, app *fiber.App, address string, source certificateSource, clientCaFile string, requireClientCert bool) error {
+	certificates, err := newCertificateReloader(ctx, source)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	config, err := serverTlsConfig(certificates, clientCaFile, requireClientCert)
+	if err != nil {
+		return err
+	}
+	go certificates.watch(ctx)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Serving the API over TLS on", address)
+	return app.Listener(tls.NewListener(listener, config))
+}
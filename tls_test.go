@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testCertificate is a certificate with its key, signed by parent or self-signed if parent is nil.
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	// keyPem is the PEM encoded private key
+	keyPem []byte
+}
+
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		cert:   cert,
+		key:    key,
+		pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.pem, c.keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write := func(c *testCertificate) {
+		if err := os.WriteFile(certFile, c.pem, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(keyFile, c.keyPem, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := newTestCertificate(t, "first", nil)
+	write(first)
+	reloader, err := newCertificateReloader(context.Background(), fileCertificates(certFile, keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewed := newTestCertificate(t, "renewed", nil)
+	write(renewed)
+	if err := reloader.reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	served, _ := reloader.getCertificate(nil)
+	if leaf, _ := x509.ParseCertificate(served.Certificate[0]); leaf.Subject.CommonName != "renewed" {
+		t.Errorf("expected the renewed certificate, got %s", leaf.Subject.CommonName)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.reload(context.Background()); err == nil {
+		t.Error("expected the broken key to be reported")
+	}
+	if current, _ := reloader.getCertificate(nil); current != served {
+		t.Error("a broken certificate replaced the served one")
+	}
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "provisioner", ca)
+	client := newTestCertificate(t, "alice", ca)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, require := range []bool{false, true} {
+		reloader, err := newCertificateReloader(context.Background(), func(context.Context) ([]byte, []byte, error) {
+			return server.pem, server.keyPem, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := serverTlsConfig(reloader, caFile, require)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app := fiber.New()
+		app.Use((&apiAuth{clientCertificates: true}).middleware())
+		app.Get("/whoami", func(c *fiber.Ctx) error {
+			return c.SendString(c.Locals(principalKey).(*principal).Subject)
+		})
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() { _ = app.Listener(tls.NewListener(listener, config)) }()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.cert)
+		get := func(certificates ...tls.Certificate) (*http.Response, error) {
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+			return httpClient.Get("https://" + listener.Addr().String() + "/whoami")
+		}
+
+		response, err := get(client.tlsCertificate(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if string(body) != "cert:alice" {
+			t.Errorf("expected the client certificate to authenticate alice, got %d %s", response.StatusCode, body)
+		}
+
+		response, err = get()
+		if require {
+			if err == nil {
+				response.Body.Close()
+				t.Error("expected connections without client certificate to be rejected")
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		} else {
+			response.Body.Close()
+			if response.StatusCode != http.StatusUnauthorized {
+				t.Errorf("expected 401 without client certificate, got %d", response.StatusCode)
+			}
+		}
+		_ = app.Shutdown()
+	}
+}
+
+func TestServerTlsConfigRequiresClientCa(t *testing.T) {
+	if _, err := serverTlsConfig(&certificateReloader{}, "", true); err == nil {
+		t.Error("expected requiring client certificates without CA to fail")
+	}
+}
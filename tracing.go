@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of spans as defined by OTLP
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+const (
+	// Finished spans are sent to the collector this often
+	traceExportInterval = 5 * time.Second
+	// Spans beyond this many waiting for export are dropped
+	maxQueuedSpans = 4096
+)
+
+// traces exports the spans of the provisioner, nil when tracing is disabled
+var traces *tracer
+
+// spanContext identifies the current span of a context, the parent of spans started from it.
+type spanContext struct {
+	traceId [16]byte
+	spanId  [8]byte
+}
+
+type spanContextKey struct{}
+
+// span is an operation of a trace, exported in the OTLP format once finished.
+type span struct {
+	spanContext
+	parentId   [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        error
+}
+
+// startSpan starts a span as child of the span in the context, or as the root of a new trace. The span must be
+// finished. Without tracing configured, it returns a nil span whose methods do nothing.
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *span) {
+	if traces == nil {
+		return ctx, nil
+	}
+	s := &span{name: name, kind: kind, start: time.Now(), attributes: make(map[string]string)}
+	if parent, ok := ctx.Value(spanContextKey{}).(spanContext); ok {
+		s.traceId = parent.traceId
+		s.parentId = parent.spanId
+	} else {
+		_, _ = rand.Read(s.traceId[:])
+	}
+	_, _ = rand.Read(s.spanId[:])
+	return context.WithValue(ctx, spanContextKey{}, s.spanContext), s
+}
+
+func (s *span) set(key string, value string) {
+	if s != nil {
+		s.attributes[key] = value
+	}
+}
+
+// finish ends the span, marking it failed when err is set, and queues it for export.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	traces.enqueue(s)
+}
+
+// withSpanOf returns ctx carrying the current span of from, to continue the trace of a request in background work
+// that must not be cancelled with the request.
+func withSpanOf(ctx context.Context, from context.Context) context.Context {
+	if parent, ok := from.Value(spanContextKey{}).(spanContext); ok {
+		return context.WithValue(ctx, spanContextKey{}, parent)
+	}
+	return ctx
+}
+
+// traceparent formats the W3C trace context header of the current span of the context.
+func traceparent(ctx context.Context) (string, bool) {
+	current, ok := ctx.Value(spanContextKey{}).(spanContext)
+	if !ok {
+		return "", false
+	}
+	return "00-" + hex.EncodeToString(current.traceId[:]) + "-" + hex.EncodeToString(current.spanId[:]) + "-01", true
+}
+
+// withTraceparent continues the trace of a W3C trace context header, invalid headers are ignored.
+func withTraceparent(ctx context.Context, header string) context.Context {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return ctx
+	}
+	var remote spanContext
+	traceId, err1 := hex.DecodeString(parts[1])
+	spanId, err2 := hex.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || len(traceId) != len(remote.traceId) || len(spanId) != len(remote.spanId) {
+		return ctx
+	}
+	copy(remote.traceId[:], traceId)
+	copy(remote.spanId[:], spanId)
+	return context.WithValue(ctx, spanContextKey{}, remote)
+}
+
+// tracer batches finished spans and sends them to an OTLP/HTTP collector such as Jaeger or Tempo.
+type tracer struct {
+	endpoint   string
+	service    string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	queue   []*span
+	dropped int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newTracer starts exporting spans to the collector at endpoint, e.g. http://tempo:4318. It returns nil without an
+// endpoint.
+func newTracer(endpoint string, service string) *tracer {
+	if endpoint == "" {
+		return nil
+	}
+	t := &tracer{
+		endpoint:   strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:    service,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go t.exportLoop()
+	fmt.Println("Exporting traces to", t.endpoint)
+	return t
+}
+
+func (t *tracer) enqueue(s *span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, s)
+}
+
+func (t *tracer) exportLoop() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(traceExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			t.flush()
+			return
+		case <-ticker.C:
+			t.flush()
+		}
+	}
+}
+
+// shutdown exports the remaining spans and stops the exporter.
+func (t *tracer) shutdown() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	<-t.stopped
+}
+
+func (t *tracer) flush() {
+	t.mu.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		fmt.Printf("Dropped %d spans, the trace collector can't keep up\n", dropped)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	body, err := json.Marshal(t.export(spans))
+	if err != nil {
+		fmt.Println("Encoding spans failed:", err)
+		return
+	}
+	response, err := t.httpClient.Post(t.endpoint, fiber.MIMEApplicationJSON, bytes.NewReader(body))
+	if err != nil {
+		fmt.Println("Exporting spans failed:", err)
+		return
+	}
+	_ = response.Body.Close()
+	if response.StatusCode >= 300 {
+		fmt.Println("Exporting spans failed:", response.Status)
+	}
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// export converts the spans into an OTLP/JSON ExportTraceServiceRequest.
+func (t *tracer) export(spans []*span) fiber.Map {
+	converted := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		out := otlpSpan{
+			TraceId:           hex.EncodeToString(s.traceId[:]),
+			SpanId:            hex.EncodeToString(s.spanId[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attributes),
+		}
+		if s.parentId != [8]byte{} {
+			out.ParentSpanId = hex.EncodeToString(s.parentId[:])
+		}
+		if s.err != nil {
+			// STATUS_CODE_ERROR
+			out.Status.Code = 2
+			out.Status.Message = s.err.Error()
+		}
+		converted[i] = out
+	}
+	return fiber.Map{"resourceSpans": []fiber.Map{{
+		"resource":   fiber.Map{"attributes": otlpAttributes(map[string]string{"service.name": t.service})},
+		"scopeSpans": []fiber.Map{{"scope": fiber.Map{"name": "aruba-provisioner"}, "spans": converted}},
+	}}}
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	converted := make([]otlpAttribute, 0, len(attributes))
+	for key, value := range attributes {
+		attribute := otlpAttribute{Key: key}
+		attribute.Value.StringValue = value
+		converted = append(converted, attribute)
+	}
+	return converted
+}
+
+// traceRequests records a server span for every request, continuing the trace of a traceparent header. Handlers find
+// the span in the request's user context.
+func traceRequests() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if traces == nil {
+			return c.Next()
+		}
+		ctx, s := startSpan(withTraceparent(c.UserContext(), c.Get("traceparent")), c.Method(), spanKindServer)
+		c.SetUserContext(ctx)
+		err := c.Next()
+		s.name = c.Method() + " " + c.Route().Path
+		s.set("http.request.method", c.Method())
+		s.set("http.route", c.Route().Path)
+		code := c.Response().StatusCode()
+		if err != nil {
+			code = problemOf(err).Status
+		}
+		s.set("http.response.status_code", strconv.Itoa(code))
+		s.finish(err)
+		return err
+	}
+}
+
+// tracingTransport records a client span for every request and propagates the trace to the server. Requests made
+// without a traced context continue the trace of parent.
+type tracingTransport struct {
+	parent context.Context
+	next   http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	if _, ok := ctx.Value(spanContextKey{}).(spanContext); !ok && t.parent != nil {
+		ctx = withSpanOf(ctx, t.parent)
+	}
+	ctx, s := startSpan(ctx, request.Method, spanKindClient)
+	s.set("http.request.method", request.Method)
+	s.set("server.address", request.URL.Host)
+	s.set("url.path", request.URL.Path)
+	if header, ok := traceparent(ctx); ok {
+		request = request.Clone(ctx)
+		request.Header.Set("traceparent", header)
+	}
+	response, err := t.next.RoundTrip(request)
+	if err == nil {
+		s.set("http.response.status_code", strconv.Itoa(response.StatusCode))
+		if response.StatusCode >= 500 {
+			s.finish(fmt.Errorf("%s", response.Status))
+			return response, err
+		}
+	}
+	s.finish(err)
+	return response, err
+}
+
+// tracedClient records a client span for every call to the Kubernetes API.
+type tracedClient struct {
+	client.WithWatch
+}
+
+func traceKubeCall(ctx context.Context, verb string, obj runtime.Object, namespace string, name string) (context.Context, *span) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+	ctx, s := startSpan(ctx, "kube "+verb+" "+kind, spanKindClient)
+	s.set("k8s.namespace.name", namespace)
+	if name != "" {
+		s.set("k8s.object.name", name)
+	}
+	return ctx, s
+}
+
+func (c tracedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, s := traceKubeCall(ctx, "get", obj, key.Namespace, key.Name)
+	err := c.WithWatch.Get(ctx, key, obj, opts...)
+	// lookups of objects that don't exist are expected
+	s.finish(client.IgnoreNotFound(err))
+	return err
+}
+
+func (c tracedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, s := traceKubeCall(ctx, "list", list, (&client.ListOptions{}).ApplyOptions(opts).Namespace, "")
+	err := c.WithWatch.List(ctx, list, opts...)
+	s.finish(err)
+	return err
+}
+
+func (c tracedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, s := traceKubeCall(ctx, "create", obj, obj.GetNamespace(), obj.GetName())
+	err := c.WithWatch.Create(ctx, obj, opts...)
+	s.finish(err)
+	return err
+}
+
+func (c tracedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, s := traceKubeCall(ctx, "update", obj, obj.GetNamespace(), obj.GetName())
+	err := c.WithWatch.Update(ctx, obj, opts...)
+	s.finish(err)
+	return err
+}
+
+func (c tracedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, s := traceKubeCall(ctx, "patch", obj, obj.GetNamespace(), obj.GetName())
+	err := c.WithWatch.Patch(ctx, obj, patch, opts...)
+	s.finish(err)
+	return err
+}
+
+func (c tracedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, s := traceKubeCall(ctx, "delete", obj, obj.GetNamespace(), obj.GetName())
+	err := c.WithWatch.Delete(ctx, obj, opts...)
+	s.finish(err)
+	return err
+}
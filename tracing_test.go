@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collector is an OTLP/HTTP endpoint keeping the spans it receives.
+type collector struct {
+	mu    sync.Mutex
+	spans []otlpSpan
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&request) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resource := range request.ResourceSpans {
+		for _, scope := range resource.ScopeSpans {
+			c.spans = append(c.spans, scope.Spans...)
+		}
+	}
+}
+
+func (c *collector) byName() map[string]otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spans := make(map[string]otlpSpan)
+	for _, s := range c.spans {
+		spans[s.Name] = s
+	}
+	return spans
+}
+
+func enableTracing(t *testing.T) *collector {
+	spans := &collector{}
+	server := httptest.NewServer(spans)
+	t.Cleanup(server.Close)
+	traces = newTracer(server.URL, "test")
+	t.Cleanup(func() { traces = nil })
+	return spans
+}
+
+func TestJobTrace(t *testing.T) {
+	spans := enableTracing(t)
+	var requests []string
+	component := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("traceparent"))
+	}))
+	defer component.Close()
+
+	job, err := jobs.create(context.Background(), "trace-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := withTraceparent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	_ = job.execute(ctx, []jobStep{
+		{phaseApply, func(context.Context) error { return nil }},
+		{phaseSeeding, func(ctx context.Context) error {
+			// seeding requests carry no context of their own
+			httpClient := seedingClients{}.withTrace(ctx).client(targetManagement)
+			response, err := httpClient.Get(component.URL)
+			if err != nil {
+				return err
+			}
+			_ = response.Body.Close()
+			return errors.New("asset rejected")
+		}},
+	})
+	traces.shutdown()
+
+	got := spans.byName()
+	jobSpan, apply, seeding, request := got["job"], got[phaseApply], got[phaseSeeding], got["GET"]
+	if jobSpan.TraceId != "0af7651916cd43dd8448eb211c80319c" || jobSpan.ParentSpanId != "b7ad6b7169203331" {
+		t.Errorf("job span %+v doesn't continue the request's trace", jobSpan)
+	}
+	if apply.ParentSpanId != jobSpan.SpanId || seeding.ParentSpanId != jobSpan.SpanId {
+		t.Error("phases are not children of the job span")
+	}
+	if request.ParentSpanId != seeding.SpanId || request.Kind != spanKindClient {
+		t.Errorf("seeding request span %+v is not a client span of the seeding phase", request)
+	}
+	if seeding.Status.Code != 2 || seeding.Status.Message != "asset rejected" {
+		t.Errorf("failed phase status = %+v", seeding.Status)
+	}
+	if len(requests) != 1 || !strings.Contains(requests[0], request.SpanId) {
+		t.Errorf("traceparent sent = %v, want the request span %s", requests, request.SpanId)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	traces = &tracer{}
+	defer func() { traces = nil }()
+	header := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx, s := startSpan(withTraceparent(context.Background(), header), "child", spanKindInternal)
+	got, ok := traceparent(ctx)
+	if !ok || !strings.HasPrefix(got, "00-0af7651916cd43dd8448eb211c80319c-") || strings.Contains(got, "b7ad6b7169203331") {
+		t.Errorf("traceparent = %q, want the trace continued with a new span", got)
+	}
+	if s.parentId != [8]byte{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31} {
+		t.Errorf("parent = %x", s.parentId)
+	}
+	for _, invalid := range []string{"", "01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "00-xyz-b7ad6b7169203331-01"} {
+		if _, ok := traceparent(withTraceparent(context.Background(), invalid)); ok {
+			t.Errorf("invalid header %q accepted", invalid)
+		}
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	ctx, s := startSpan(context.Background(), "noop", spanKindInternal)
+	s.set("key", "value")
+	s.finish(nil)
+	if _, ok := traceparent(ctx); ok || s != nil {
+		t.Error("spans recorded without tracing configured")
+	}
+}
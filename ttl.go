@@ -0,0 +1,110 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Interval the namespaces of ephemeral participants are checked for lapsed lifetimes at
+const expiryCheckInterval = time.Minute
+
+// parseLifetime parses the lifetime of an ephemeral participant, a Go duration like 90m or 8h, or a number of days
+// like 2d.
+func parseLifetime(value string) (time.Duration, error) {
+	lifetime, err := time.ParseDuration(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var count int
+		count, err = strconv.Atoi(days)
+		lifetime = time.Duration(count) * 24 * time.Hour
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid lifetime %q, expected e.g. 90m, 8h or 2d", value)
+	}
+	if lifetime <= 0 {
+		return 0, errors.New("the lifetime must be positive")
+	}
+	return lifetime, nil
+}
+
+// expiryMutator records the expiry of the participant on its namespace. Re-applying a participant keeps its expiry
+// unless the definition asks for another lifetime, which then counts from now.
+func expiryMutator(c client.Client, ctx context.Context, participant string, expiresAfter string, lifetime time.Duration) (objectMutator, error) {
+	expiresAt := time.Now().Add(lifetime)
+	namespace := &corev1.Namespace{}
+	err := c.Get(ctx, client.ObjectKey{Name: participant}, namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if existing := status.ExpiryOf(namespace); existing != nil && namespace.Annotations[status.ExpiresAfterAnnotation] == expiresAfter {
+		expiresAt = *existing
+	}
+	annotations := map[string]string{
+		status.ExpiresAfterAnnotation: expiresAfter,
+		status.ExpiresAtAnnotation:    expiresAt.UTC().Format(time.RFC3339),
+	}
+	return func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() == "Namespace" {
+			addAnnotations(obj, annotations)
+		}
+		return nil
+	}, nil
+}
+
+// expireParticipants deletes the ephemeral participants whose lifetime lapsed and the participants whose grace period
+// in the recycle bin lapsed, checking at the interval until the context is cancelled. With several replicas only the
+// leader deletes them.
+func (p *provisioner) expireParticipants(ctx context.Context, interval time.Duration, leading func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !leading() {
+			continue
+		}
+		for _, name := range p.expiredParticipants(ctx) {
+			fmt.Println("Lifetime of", name, "lapsed, deleting it")
+			job, err := p.startDeletion(ctx, name, nil)
+			if err != nil {
+				fmt.Printf("Deleting expired participant %s failed: %v\n", name, err)
+				continue
+			}
+			p.announce(eventParticipantExpired, name, map[string]string{"jobId": job.Id})
+		}
+		p.emptyRecycleBin(ctx)
+	}
+}
+
+// expiredParticipants returns the participants whose lifetime lapsed and that aren't being deleted already.
+func (p *provisioner) expiredParticipants(ctx context.Context) []string {
+	namespaces := &corev1.NamespaceList{}
+	if err := p.kubeClient.List(ctx, namespaces, client.MatchingLabels{status.ManagedByLabel: status.ManagedByValue}); err != nil {
+		fmt.Println("Listing managed namespaces failed:", err)
+		return nil
+	}
+	var expired []string
+	for _, namespace := range namespaces.Items {
+		expiresAt := status.ExpiryOf(&namespace)
+		if expiresAt == nil || time.Now().Before(*expiresAt) || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		if jobs.running(namespace.Name) || p.statusChecker.InOperation(ctx, namespace.Name) {
+			continue
+		}
+		expired = append(expired, namespace.Name)
+	}
+	return expired
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"aruba-provisioner/api/status"
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseLifetime(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"90m": 90 * time.Minute, "8h": 8 * time.Hour, "2d": 48 * time.Hour} {
+		if lifetime, err := parseLifetime(value); err != nil || lifetime != expected {
+			t.Errorf("%s: expected %s, got %s, %v", value, expected, lifetime, err)
+		}
+	}
+	for _, value := range []string{"", "soon", "1.5d", "-1h", "0s"} {
+		if _, err := parseLifetime(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	lapsed := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	later := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	managed := func(name string, annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations,
+			Labels: map[string]string{status.ManagedByLabel: status.ManagedByValue}}}
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	kube := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		managed("workshop", map[string]string{status.ExpiresAfterAnnotation: "2h", status.ExpiresAtAnnotation: lapsed}),
+		managed("ci", map[string]string{status.ExpiresAfterAnnotation: "2h", status.ExpiresAtAnnotation: later}),
+		managed("production", nil),
+	).Build()
+	participants := &provisioner{kubeClient: kube, statusChecker: status.NewStatusChecker(context.Background(), kube, 0)}
+
+	if expired := participants.expiredParticipants(context.Background()); len(expired) != 1 || expired[0] != "workshop" {
+		t.Errorf("expected only the workshop participant to be expired, got %v", expired)
+	}
+
+	expiryOf := func(expiresAfter string) string {
+		lifetime, err := parseLifetime(expiresAfter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mutate, err := expiryMutator(kube, context.Background(), "ci", expiresAfter, lifetime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		namespace := &unstructured.Unstructured{Object: map[string]any{"kind": "Namespace"}}
+		if err := mutate(namespace); err != nil {
+			t.Fatal(err)
+		}
+		return namespace.GetAnnotations()[status.ExpiresAtAnnotation]
+	}
+	if expiresAt := expiryOf("2h"); expiresAt != later {
+		t.Errorf("expected re-applying to keep the expiry %s, got %s", later, expiresAt)
+	}
+	if expiresAt := expiryOf("1d"); expiresAt == later {
+		t.Error("expected a new lifetime to replace the expiry")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Longest time spent resolving the ingress host of a participant
+const hostLookupTimeout = 5 * time.Second
+
+// lookupHost resolves host names, replaced in tests
+var lookupHost = net.DefaultResolver.LookupHost
+
+// fieldError describes why the value of a field of a request was rejected.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationError is returned for requests with invalid fields and answered with a 400 listing all of them.
+type validationError struct {
+	Fields []fieldError `json:"fields"`
+}
+
+func (e *validationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "invalid participant definition: " + strings.Join(messages, "; ")
+}
+
+// validate checks the fields identifying the participant before anything is applied: the name becomes the namespace
+// and has to be a DNS-1123 label, the DID has to be a well-formed did:web or did:key and the ingress host, taken from
+// the dataspace config if not set, has to resolve. The seed bundle of the catalog is loaded, so its entities are
+// validated as well.
+func (p *ParticipantDefinition) validate(ctx context.Context, dataspaces map[string]DataspaceConfig) error {
+	var fields []fieldError
+	if p.ParticipantName == "" {
+		fields = append(fields, fieldError{"participantName", "required"})
+	} else if errs := validation.IsDNS1123Label(p.ParticipantName); len(errs) > 0 {
+		fields = append(fields, fieldError{"participantName", strings.Join(errs, ", ")})
+	}
+	if p.Did == "" {
+		fields = append(fields, fieldError{"did", "required"})
+	} else if err := validateDid(p.Did); err != nil {
+		fields = append(fields, fieldError{"did", err.Error()})
+	}
+	if err := resolveIngressHost(p, dataspaces); err != nil {
+		fields = append(fields, fieldError{"kubeHost", err.Error()})
+	} else if err := validateResolvable(ctx, p.KubernetesIngressHost); err != nil {
+		fields = append(fields, fieldError{"kubeHost", err.Error()})
+	}
+	if err := p.Seed.validate(); err != nil {
+		fields = append(fields, fieldError{"seed.steps", err.Error()})
+	}
+	if err := p.Catalog.fetchSource(ctx); err != nil {
+		fields = append(fields, fieldError{"catalog.source", err.Error()})
+	}
+	fields = append(fields, p.Catalog.validate(p.ContractDefinitions)...)
+	if len(fields) > 0 {
+		return &validationError{Fields: fields}
+	}
+	return nil
+}
+
+// validateDid checks that the DID is a structurally valid did:web or did:key.
+func validateDid(did string) error {
+	switch {
+	case strings.HasPrefix(did, "did:web:"):
+		return validateDidWeb(strings.TrimPrefix(did, "did:web:"))
+	case strings.HasPrefix(did, "did:key:"):
+		return validateDidKey(strings.TrimPrefix(did, "did:key:"))
+	default:
+		return errors.New("must be a did:web or did:key DID")
+	}
+}
+
+var didPathSegment = regexp.MustCompile(`^([A-Za-z0-9._~-]|%[0-9A-Fa-f]{2})+$`)
+
+// validateDidWeb checks the domain, with an optional percent encoded port, and the path segments of a did:web.
+func validateDidWeb(id string) error {
+	segments := strings.Split(id, ":")
+	host, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return fmt.Errorf("invalid domain %q", segments[0])
+	}
+	if name, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || len(validation.IsValidPortNum(n)) > 0 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		host = name
+	}
+	if net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(strings.ToLower(host))) > 0 {
+		return fmt.Errorf("invalid domain %q", host)
+	}
+	for _, segment := range segments[1:] {
+		if !didPathSegment.MatchString(segment) {
+			return fmt.Errorf("invalid path segment %q", segment)
+		}
+	}
+	return nil
+}
+
+// Public key lengths of the multicodec key types used with did:key, keyed by their varint encoded codec
+var didKeyTypes = map[string]int{
+	"\xed\x01": 32, // ed25519-pub
+	"\xec\x01": 32, // x25519-pub
+	"\xe7\x01": 33, // secp256k1-pub, compressed
+	"\x80\x24": 33, // p256-pub, compressed
+	"\x81\x24": 49, // p384-pub, compressed
+}
+
+// validateDidKey checks that the method specific identifier of a did:key is a base58btc multibase encoded public key
+// of a known type.
+func validateDidKey(id string) error {
+	encoded, ok := strings.CutPrefix(id, "z")
+	if !ok {
+		return errors.New("did:key must be base58btc multibase encoded")
+	}
+	decoded, err := decodeBase58(encoded)
+	if err != nil {
+		return err
+	}
+	if len(decoded) < 2 {
+		return errors.New("did:key too short")
+	}
+	length, ok := didKeyTypes[string(decoded[:2])]
+	if !ok {
+		return errors.New("did:key of unsupported key type")
+	}
+	if len(decoded)-2 != length {
+		return fmt.Errorf("did:key with %d byte key, want %d", len(decoded)-2, length)
+	}
+	return nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func decodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty base58 value")
+	}
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for _, r := range s {
+		digit := strings.IndexRune(base58Alphabet, r)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+	// leading ones encode leading zero bytes
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
+
+// validateResolvable checks that the host of the ingress URL resolves.
+func validateResolvable(ctx context.Context, ingressUrl string) error {
+	parsed, err := url.Parse(ingressUrl)
+	if err != nil || parsed.Hostname() == "" {
+		return fmt.Errorf("invalid ingress host %q", ingressUrl)
+	}
+	host := parsed.Hostname()
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+	defer cancel()
+	if _, err := lookupHost(lookupCtx, host); err != nil {
+		return fmt.Errorf("host %q does not resolve", host)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestValidateDid(t *testing.T) {
+	tests := []struct {
+		did     string
+		wantErr bool
+	}{
+		{"did:web:acme.example.com", false},
+		{"did:web:localhost%3A8443:participants:acme", false},
+		{"did:web:acme", false},
+		{"did:web:acme_corp.example.com", true},
+		{"did:web:acme.example.com:", true},
+		{"did:web:localhost%3A99999", true},
+		{"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK", false},
+		{"did:key:zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169", false},
+		{"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2do", true},
+		{"did:key:f6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK", true},
+		{"did:key:z0OIl", true},
+		{"did:example:123", true},
+		{"acme", true},
+	}
+	for _, tt := range tests {
+		if err := validateDid(tt.did); (err != nil) != tt.wantErr {
+			t.Errorf("validateDid(%q) = %v, wantErr %v", tt.did, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host == "ingress.test" {
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	t.Cleanup(func() { lookupHost = net.DefaultResolver.LookupHost })
+
+	valid := ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme.example.com", KubernetesIngressHost: "ingress.test"}
+	if err := valid.validate(context.Background(), nil); err != nil {
+		t.Fatalf("valid definition rejected: %v", err)
+	}
+	if valid.KubernetesIngressHost != "http://ingress.test" {
+		t.Errorf("ingress host = %q, want it resolved to a URL", valid.KubernetesIngressHost)
+	}
+	if err := (&ParticipantDefinition{ParticipantName: "acme", Did: "did:web:acme", KubernetesIngressHost: "10.1.2.3:8080"}).validate(context.Background(), nil); err != nil {
+		t.Errorf("IP addresses need no lookup: %v", err)
+	}
+
+	invalid := ParticipantDefinition{ParticipantName: "Acme_Corp", Did: "did:example:acme", KubernetesIngressHost: "unknown.test"}
+	err := invalid.validate(context.Background(), nil)
+	var validationErr *validationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want a validationError", err)
+	}
+	var fields []string
+	for _, field := range validationErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	if len(fields) != 3 || fields[0] != "participantName" || fields[1] != "did" || fields[2] != "kubeHost" {
+		t.Errorf("fields = %v, want participantName, did and kubeHost", fields)
+	}
+}
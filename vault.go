@@ -0,0 +1,290 @@
+package main
+
+import (
+	"aruba-provisioner/api"
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Secret in the participant namespace holding the AppRole credentials and the token of the participant's components
+const vaultCredentialsName = "vault-credentials"
+
+// Keys of the vault credentials Secret
+const (
+	vaultTokenKey     = "token"
+	vaultRoleIdKey    = "role-id"
+	vaultSecretIdKey  = "secret-id"
+	vaultDataspaceKey = "dataspace"
+)
+
+// Tokens of the participants' components are periodic, the components renew them long before the period passes
+const vaultTokenPeriod = "768h"
+
+// Components reading secrets from the participant's vault, by the name of their Deployment and ConfigMap
+var vaultComponents = map[string]string{"controlplane": "controlplane-config", "dataplane": "dataplane-config", "identityhub": "ih-config"}
+
+// VaultConfig configures a HashiCorp Vault outside the participant namespaces keeping the connector secrets, in place
+// of the development Vault per participant whose secrets don't survive restarts. Each participant gets a policy
+// limited to its folder of the KV engine and an AppRole with that policy; its components use a token of the role.
+type VaultConfig struct {
+	// Url is the address the provisioner and the participants' components reach the Vault at, no external Vault is
+	// used when empty
+	Url string `json:"url,omitempty"`
+	// Token authenticates the provisioner, it needs to manage policies, AppRoles, mounts and namespaces
+	Token string `json:"token,omitempty"`
+	// Namespace is the Vault Enterprise namespace participants are set up in, the root namespace when empty
+	Namespace string `json:"namespace,omitempty"`
+	// ParticipantNamespaces sets every participant up in a child namespace of its own (Vault Enterprise)
+	ParticipantNamespaces bool `json:"participantNamespaces,omitempty"`
+	// Mount is the path of the KV v2 engine keeping the secrets
+	Mount string `json:"mount,omitempty"`
+	// Disabled keeps the development Vault per participant, e.g. for demo dataspaces
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// defaultVault is set with --vault-url, --vault-token, --vault-namespace, --vault-participant-namespaces and
+// --vault-mount.
+var defaultVault = VaultConfig{Mount: "secret"}
+
+// vaultFor returns the vault settings of the dataspace, falling back to the defaults for settings it doesn't set.
+func vaultFor(dataspace DataspaceConfig) VaultConfig {
+	vault := defaultVault
+	if dataspace.Vault == nil {
+		return vault
+	}
+	if dataspace.Vault.Url != "" {
+		vault.Url = dataspace.Vault.Url
+	}
+	if dataspace.Vault.Token != "" {
+		vault.Token = dataspace.Vault.Token
+	}
+	if dataspace.Vault.Namespace != "" {
+		vault.Namespace = dataspace.Vault.Namespace
+	}
+	if dataspace.Vault.Mount != "" {
+		vault.Mount = dataspace.Vault.Mount
+	}
+	vault.ParticipantNamespaces = vault.ParticipantNamespaces || dataspace.Vault.ParticipantNamespaces
+	vault.Disabled = vault.Disabled || dataspace.Vault.Disabled
+	return vault
+}
+
+func (v VaultConfig) enabled() bool {
+	return v.Url != "" && !v.Disabled
+}
+
+// namespaceOf returns the Vault namespace of the participant's policy, role and secrets.
+func (v VaultConfig) namespaceOf(participant string) string {
+	if v.ParticipantNamespaces {
+		return path.Join(v.Namespace, participant)
+	}
+	return v.Namespace
+}
+
+// policy grants access to the participant's folder of the KV engine only.
+func (v VaultConfig) policy(participant string) string {
+	return fmt.Sprintf(`path "%[1]s/data/%[2]s/*" {
+  capabilities = ["create", "read", "update", "delete", "list"]
+}
+path "%[1]s/metadata/%[2]s/*" {
+  capabilities = ["read", "list", "delete"]
+}
+`, v.Mount, participant)
+}
+
+// roleName is the name of the participant's policy and AppRole.
+func roleName(participant string) string {
+	return "participant-" + participant
+}
+
+func (v VaultConfig) client(clients seedingClients) *api.ApiClient {
+	return &api.ApiClient{
+		BaseUrl:    v.Url,
+		ApiKey:     v.Token,
+		HttpClient: clients.client(targetVault),
+		Retry:      api.DefaultRetry,
+	}
+}
+
+// vaultMutator points the components at the external Vault: the settings of their ConfigMaps select the Vault, the
+// participant's namespace and folder, and the token is read from the vault credentials Secret. The development
+// Vault, its Service and its ingress path are dropped.
+func (v VaultConfig) mutator(participant string) objectMutator {
+	secretPath := "/v1/" + v.Mount
+	if namespace := v.namespaceOf(participant); namespace != "" {
+		// Vault accepts the namespace as prefix of the request path
+		secretPath = "/v1/" + namespace + "/" + v.Mount
+	}
+	return func(obj *unstructured.Unstructured) error {
+		switch obj.GetKind() {
+		case "Deployment", "Service":
+			if obj.GetName() == "vault" {
+				return errSkipObject
+			}
+			if _, ok := vaultComponents[obj.GetName()]; ok && obj.GetKind() == "Deployment" {
+				return withVaultToken(obj)
+			}
+		case "ConfigMap":
+			for _, configMap := range vaultComponents {
+				if obj.GetName() != configMap {
+					continue
+				}
+				unstructured.RemoveNestedField(obj.Object, "data", "EDC_VAULT_HASHICORP_TOKEN")
+				for setting, value := range map[string]string{
+					"EDC_VAULT_HASHICORP_URL":             v.Url,
+					"EDC_VAULT_HASHICORP_API_SECRET_PATH": secretPath,
+					"EDC_VAULT_HASHICORP_FOLDER":          participant,
+				} {
+					if err := unstructured.SetNestedField(obj.Object, value, "data", setting); err != nil {
+						return err
+					}
+				}
+			}
+		case "Ingress":
+			return withoutVaultPaths(obj)
+		}
+		return nil
+	}
+}
+
+// withVaultToken passes the token of the vault credentials Secret to every container of the deployment.
+func withVaultToken(obj *unstructured.Unstructured) error {
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return err
+	}
+	for i := range containers {
+		container, ok := containers[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		env, _ := container["env"].([]any)
+		container["env"] = append(env, map[string]any{
+			"name": "EDC_VAULT_HASHICORP_TOKEN",
+			"valueFrom": map[string]any{
+				"secretKeyRef": map[string]any{"name": vaultCredentialsName, "key": vaultTokenKey},
+			},
+		})
+	}
+	return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+}
+
+// withoutVaultPaths removes the ingress paths routed to the development Vault.
+func withoutVaultPaths(obj *unstructured.Unstructured) error {
+	rules, _, err := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		rule, ok := rules[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		kept := make([]any, 0, len(paths))
+		for _, p := range paths {
+			if service, _, _ := unstructured.NestedString(p.(map[string]any), "backend", "service", "name"); service == "vault" {
+				continue
+			}
+			kept = append(kept, p)
+		}
+		if err := unstructured.SetNestedSlice(rule, kept, "http", "paths"); err != nil {
+			return err
+		}
+	}
+	return unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules")
+}
+
+// setupParticipantVault creates the participant's namespace, if it gets one, policy and AppRole in the Vault and
+// stores the credentials of the role and a token issued for it in the participant namespace. Credentials stored by
+// an earlier run are kept, so re-applying a participant doesn't pile up secret IDs and tokens.
+func setupParticipantVault(c client.Client, ctx context.Context, vault VaultConfig, definition ParticipantDefinition, clients seedingClients) error {
+	participant := definition.ParticipantName
+	existing := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: participant, Name: vaultCredentialsName}, existing)
+	if err == nil && len(existing.Data[vaultTokenKey]) > 0 {
+		return nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	v := vault.client(clients)
+	namespace := vault.namespaceOf(participant)
+	if vault.ParticipantNamespaces {
+		if err := v.CreateVaultNamespace(ctx, vault.Namespace, participant); err != nil {
+			return fmt.Errorf("create vault namespace: %w", err)
+		}
+	}
+	if err := v.EnableKvEngine(ctx, namespace, vault.Mount); err != nil {
+		return fmt.Errorf("enable kv engine: %w", err)
+	}
+	if err := v.EnableAppRoleAuth(ctx, namespace); err != nil {
+		return fmt.Errorf("enable approle auth: %w", err)
+	}
+	role := roleName(participant)
+	if err := v.PutVaultPolicy(ctx, namespace, role, vault.policy(participant)); err != nil {
+		return fmt.Errorf("create vault policy: %w", err)
+	}
+	if err := v.PutAppRole(ctx, namespace, role, api.AppRole{Policies: []string{role}, TokenPeriod: vaultTokenPeriod}); err != nil {
+		return fmt.Errorf("create approle: %w", err)
+	}
+	roleId, secretId, err := v.AppRoleCredentials(ctx, namespace, role)
+	if err != nil {
+		return fmt.Errorf("read approle credentials: %w", err)
+	}
+	token, err := v.AppRoleLogin(ctx, namespace, roleId, secretId)
+	if err != nil {
+		return fmt.Errorf("approle login: %w", err)
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: vaultCredentialsName, Namespace: participant},
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			vaultTokenKey:     []byte(token),
+			vaultRoleIdKey:    []byte(roleId),
+			vaultSecretIdKey:  []byte(secretId),
+			vaultDataspaceKey: []byte(dataspaceOf(definition)),
+		},
+	}
+	return applyResource(c, ctx, secret)
+}
+
+// removeParticipantVault deletes what setupParticipantVault created in the Vault, including the participant's
+// secrets. Participants without vault credentials were provisioned with the development Vault and are skipped.
+func removeParticipantVault(c client.Client, ctx context.Context, participant string, dataspaces map[string]DataspaceConfig, clients seedingClients) error {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: participant, Name: vaultCredentialsName}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	vault := vaultFor(dataspaces[string(secret.Data[vaultDataspaceKey])])
+	if !vault.enabled() {
+		fmt.Printf("Vault of %s is no longer configured, its policy, role and secrets are left in place\n", participant)
+		return nil
+	}
+	v := vault.client(clients)
+	if vault.ParticipantNamespaces {
+		return v.DeleteVaultNamespace(ctx, vault.Namespace, participant)
+	}
+	role := roleName(participant)
+	if err := v.DeleteAppRole(ctx, vault.Namespace, role); err != nil {
+		return err
+	}
+	if err := v.DeleteVaultPolicy(ctx, vault.Namespace, role); err != nil {
+		return err
+	}
+	return v.DeleteSecretsIn(ctx, vault.Namespace, vault.Mount, participant)
+}
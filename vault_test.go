@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyingSecretClient stores the Secrets applied with server-side apply.
+type applyingSecretClient struct {
+	*secretClient
+}
+
+func (c applyingSecretClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	c.secrets[client.ObjectKeyFromObject(obj)] = obj.(*corev1.Secret).DeepCopy()
+	return nil
+}
+
+// fakeVault records the requests it receives as "METHOD namespace path" and answers the AppRole requests.
+type fakeVault struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]string
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	vault := &fakeVault{bodies: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		encoded, _ := json.Marshal(body)
+		request := r.Method + " " + r.Header.Get("X-Vault-Namespace") + " " + r.URL.EscapedPath()
+		vault.mu.Lock()
+		vault.requests = append(vault.requests, request)
+		vault.bodies[request] = string(encoded)
+		vault.mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/sys/namespaces/"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/v1/sys/auth/approle":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["path is already in use at approle/"]}`))
+		case strings.HasSuffix(r.URL.Path, "/role-id"):
+			_, _ = w.Write([]byte(`{"data":{"role_id":"role-1"}}`))
+		case strings.HasSuffix(r.URL.Path, "/secret-id"):
+			_, _ = w.Write([]byte(`{"data":{"secret_id":"secret-1"}}`))
+		case r.URL.Path == "/v1/auth/approle/login":
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"hvs.participant"}}`))
+		case r.URL.Query().Get("list") == "true":
+			_, _ = w.Write([]byte(`{"data":{"keys":["sts-client-secret","nested/"]}}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return vault, server
+}
+
+func (v *fakeVault) received(request string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, r := range v.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetupParticipantVault(t *testing.T) {
+	fake, server := newFakeVault(t)
+	vault := VaultConfig{Url: server.URL, Token: "admin", Namespace: "dataspaces", ParticipantNamespaces: true, Mount: "secret"}
+	kube := applyingSecretClient{newSecretClient()}
+	definition := ParticipantDefinition{ParticipantName: "alice", Dataspace: "staging"}
+
+	if err := setupParticipantVault(kube, context.Background(), vault, definition, seedingClients{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"POST dataspaces /v1/sys/namespaces/alice",
+		"POST dataspaces/alice /v1/sys/mounts/secret",
+		"PUT dataspaces/alice /v1/sys/policies/acl/participant-alice",
+		"POST dataspaces/alice /v1/auth/approle/role/participant-alice",
+		"POST dataspaces/alice /v1/auth/approle/login",
+	} {
+		if !fake.received(expected) {
+			t.Errorf("expected %s, got %v", expected, fake.requests)
+		}
+	}
+	if policy := fake.bodies["PUT dataspaces/alice /v1/sys/policies/acl/participant-alice"]; !strings.Contains(policy, `secret/data/alice/*`) {
+		t.Errorf("expected the policy to be limited to the participant's folder, got %s", policy)
+	}
+	secret := kube.secrets[client.ObjectKey{Namespace: "alice", Name: vaultCredentialsName}]
+	if secret == nil || string(secret.Data[vaultTokenKey]) != "hvs.participant" || string(secret.Data[vaultDataspaceKey]) != "staging" {
+		t.Fatalf("expected the credentials to be stored in the participant namespace, got %v", secret)
+	}
+
+	// re-applying keeps the stored credentials
+	fake.requests = nil
+	if err := setupParticipantVault(kube, context.Background(), vault, definition, seedingClients{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("expected no vault requests for a participant set up before, got %v", fake.requests)
+	}
+
+	// deletion removes the participant's namespace
+	previous := defaultVault
+	t.Cleanup(func() { defaultVault = previous })
+	defaultVault = VaultConfig{}
+	dataspaces := map[string]DataspaceConfig{"staging": {Vault: &vault}}
+	if err := removeParticipantVault(kube, context.Background(), "alice", dataspaces, seedingClients{}); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.received("DELETE dataspaces /v1/sys/namespaces/alice") {
+		t.Errorf("expected the participant namespace to be deleted, got %v", fake.requests)
+	}
+}
+
+func TestRemoveParticipantVaultSecrets(t *testing.T) {
+	fake, server := newFakeVault(t)
+	previous := defaultVault
+	t.Cleanup(func() { defaultVault = previous })
+	defaultVault = VaultConfig{Url: server.URL, Token: "admin", Mount: "connectors"}
+	kube := applyingSecretClient{newSecretClient()}
+	kube.secrets[client.ObjectKey{Namespace: "alice", Name: vaultCredentialsName}] = &corev1.Secret{Data: map[string][]byte{vaultDataspaceKey: []byte(defaultDataspace)}}
+
+	if err := removeParticipantVault(kube, context.Background(), "alice", nil, seedingClients{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"DELETE  /v1/auth/approle/role/participant-alice",
+		"DELETE  /v1/sys/policies/acl/participant-alice",
+		"DELETE  /v1/connectors/metadata/alice/sts-client-secret",
+	} {
+		if !fake.received(expected) {
+			t.Errorf("expected %s, got %v", expected, fake.requests)
+		}
+	}
+
+	fake.requests = nil
+	if err := removeParticipantVault(kube, context.Background(), "bob", nil, seedingClients{}); err != nil || len(fake.requests) != 0 {
+		t.Errorf("expected participants with the development vault to be skipped, got %v %v", err, fake.requests)
+	}
+}
+
+func TestVaultMutator(t *testing.T) {
+	vault := VaultConfig{Url: "https://vault.example.com", Token: "admin", Namespace: "dataspaces", Mount: "secret"}
+	mutator := vault.mutator("alice")
+
+	config := parseObject(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: controlplane-config\ndata:\n  EDC_VAULT_HASHICORP_URL: http://vault.alice.svc.cluster.local:8200\n  EDC_VAULT_HASHICORP_TOKEN: root\n")
+	if err := mutator(config); err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(config.Object, "data")
+	if data["EDC_VAULT_HASHICORP_URL"] != vault.Url || data["EDC_VAULT_HASHICORP_API_SECRET_PATH"] != "/v1/dataspaces/secret" ||
+		data["EDC_VAULT_HASHICORP_FOLDER"] != "alice" || data["EDC_VAULT_HASHICORP_TOKEN"] != "" {
+		t.Errorf("unexpected vault settings %v", data)
+	}
+
+	deployment := parseObject(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: controlplane\nspec:\n  template:\n    spec:\n      containers:\n        - name: controlplane\n")
+	if err := mutator(deployment); err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	env, _ := containers[0].(map[string]any)["env"].([]any)
+	if len(env) != 1 || env[0].(map[string]any)["name"] != "EDC_VAULT_HASHICORP_TOKEN" {
+		t.Errorf("expected the token to be read from the credentials secret, got %v", env)
+	}
+
+	if err := mutator(parseObject(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: vault\n")); err != errSkipObject {
+		t.Errorf("expected the development vault to be skipped, got %v", err)
+	}
+	ingress := parseObject(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ingress-controlplane
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /alice/cp
+            backend: {service: {name: controlplane}}
+          - path: /alice/vault
+            backend: {service: {name: vault}}
+`)
+	if err := mutator(ingress); err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if paths, _, _ := unstructured.NestedSlice(rules[0].(map[string]any), "http", "paths"); len(paths) != 1 {
+		t.Errorf("expected the vault path to be removed, got %v", paths)
+	}
+}
+
+func TestExternalVaultSecretStore(t *testing.T) {
+	fake, server := newFakeVault(t)
+	dataspace := DataspaceConfig{Vault: &VaultConfig{Url: server.URL, Token: "admin"}}
+	store := secretStoreFor(dataspace, nil, ParticipantDefinition{ParticipantName: "alice"}, seedingClients{})
+	if err := store.put(context.Background(), "did:web:alice-sts-client-secret", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.received("POST  /v1/secret/data/alice/did:web:alice-sts-client-secret") {
+		t.Errorf("expected the secret in the participant's folder, got %v", fake.requests)
+	}
+}